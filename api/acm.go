@@ -0,0 +1,77 @@
+package api
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type acmInterface interface {
+	DescribeCertificate(arn string) (*Certificate, error)
+	ListCertificates() ([]*Certificate, error)
+}
+
+type Certificate struct {
+	CertificateArn string
+	DomainName     string
+}
+
+type acmClient struct {
+	*jsonClient
+}
+
+func newACMClient(p client.ConfigProvider) *acmClient {
+	return &acmClient{newJSONClient(p, "acm", "CertificateManager", "1.1")}
+}
+
+func (c *acmClient) DescribeCertificate(arn string) (*Certificate, error) {
+	var resp struct {
+		Certificate *Certificate
+	}
+	err := c.Call("DescribeCertificate", &struct{ CertificateArn string }{arn}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Certificate, nil
+}
+
+func (c *acmClient) ListCertificates() ([]*Certificate, error) {
+	var certs []*Certificate
+	var input struct {
+		NextToken string `json:",omitempty"`
+	}
+
+	for {
+		var resp struct {
+			CertificateSummaryList []*Certificate
+			NextToken              string
+		}
+		if err := c.Call("ListCertificates", &input, &resp); err != nil {
+			return nil, err
+		}
+		certs = append(certs, resp.CertificateSummaryList...)
+		if resp.NextToken == "" {
+			return certs, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// FindMatchingCertificate finds a certificate for the same domain as a certificate in
+// another region, returning false if there isn't one
+func FindMatchingCertificate(from, to acmInterface, arn string) (string, bool, error) {
+	cert, err := from.DescribeCertificate(arn)
+	if err != nil {
+		return "", false, err
+	}
+
+	certs, err := to.ListCertificates()
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, c := range certs {
+		if c.DomainName == cert.DomainName {
+			return c.CertificateArn, true, nil
+		}
+	}
+	return "", false, nil
+}
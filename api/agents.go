@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// recommendedAMIParameter describes the current ECS optimized AMI, including the agent
+// version it ships with
+const recommendedAMIParameter = "/aws/service/ecs/optimized-ami/amazon-linux-2/recommended"
+
+var (
+	// ErrAgentUpToDate is returned when updating an agent that's already the latest version
+	ErrAgentUpToDate = errors.New("The agent is already the latest version")
+
+	// ErrAgentUpdateUnsupported is returned when updating an agent that can't be updated in
+	// place, like those on ECS optimized AMIs, which need their instances replaced
+	ErrAgentUpdateUnsupported = errors.New("The agent can't be updated in place")
+)
+
+// AgentStatus is the versions of the ECS agent and docker on a container instance
+type AgentStatus struct {
+	ContainerInstanceArn string
+	InstanceID           string
+	AgentVersion         string
+	DockerVersion        string
+	UpdateStatus         string
+	Connected            bool
+}
+
+// AgentStatuses returns the agent versions of container instances
+func AgentStatuses(instances []*ecs.ContainerInstance) []AgentStatus {
+	statuses := []AgentStatus{}
+	for _, ci := range instances {
+		s := AgentStatus{
+			ContainerInstanceArn: aws.StringValue(ci.ContainerInstanceArn),
+			InstanceID:           aws.StringValue(ci.Ec2InstanceId),
+			UpdateStatus:         aws.StringValue(ci.AgentUpdateStatus),
+			Connected:            aws.BoolValue(ci.AgentConnected),
+		}
+		if ci.VersionInfo != nil {
+			s.AgentVersion = strings.TrimPrefix(aws.StringValue(ci.VersionInfo.AgentVersion), "v")
+			s.DockerVersion = strings.TrimPrefix(aws.StringValue(ci.VersionInfo.DockerVersion), "DockerVersion: ")
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// LatestAgentVersion returns the version of the agent on the current ECS optimized AMI
+func LatestAgentVersion(svc ssmInterface) (string, error) {
+	value, err := svc.GetParameter(recommendedAMIParameter, false)
+	if err != nil {
+		return "", err
+	}
+	var ami struct {
+		AgentVersion string `json:"ecs_agent_version"`
+	}
+	if err = json.Unmarshal([]byte(value), &ami); err != nil {
+		return "", err
+	}
+	return ami.AgentVersion, nil
+}
+
+// NewestAgentVersion returns the newest agent version of container instances
+func NewestAgentVersion(statuses []AgentStatus) string {
+	newest := ""
+	for _, s := range statuses {
+		if CompareVersions(s.AgentVersion, newest) > 0 {
+			newest = s.AgentVersion
+		}
+	}
+	return newest
+}
+
+// CompareVersions compares dotted version numbers like 1.51.0, returning -1, 0 or 1
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// UpdateAgent starts an update of the agent on a container instance to the latest version
+func UpdateAgent(svc ECSAPI, cluster, containerInstanceArn string) error {
+	_, err := svc.UpdateContainerAgent(&ecs.UpdateContainerAgentInput{
+		Cluster:           aws.String(cluster),
+		ContainerInstance: aws.String(containerInstanceArn),
+	})
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "NoUpdateAvailableException":
+			return ErrAgentUpToDate
+		case "MissingVersionException", "InvalidParameterException":
+			return ErrAgentUpdateUnsupported
+		}
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+package api
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.51.0", "1.51.0", 0},
+		{"1.9.0", "1.10.0", -1},
+		{"1.51.1", "1.51", 1},
+		{"", "1.0.0", -1},
+	} {
+		if got := CompareVersions(tc.a, tc.b); got != tc.expected {
+			t.Errorf("Expected CompareVersions(%q, %q) to be %d, got %d", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Package apitest has in-memory fakes of the aws apis that ecsy uses, so that code built on
+// api.Services can be tested without an aws account
+package apitest
+
+import "github.com/lox/ecsy/api"
+
+// Fake is a set of in-memory services, only cloudformation, ecs, ec2 and cloudwatch are
+// faked and the other services are left nil
+type Fake struct {
+	CloudFormation *CloudFormation
+	ECS            *ECS
+	EC2            *EC2
+	CloudWatch     *CloudWatch
+}
+
+// New returns fakes with no stacks, services or instances
+func New() *Fake {
+	return &Fake{
+		CloudFormation: NewCloudFormation(),
+		ECS:            NewECS(),
+		EC2:            NewEC2(),
+		CloudWatch:     NewCloudWatch(),
+	}
+}
+
+// Services returns api.Services backed by the fakes
+func (f *Fake) Services() api.Services {
+	return api.Services{
+		Cloudformation: f.CloudFormation,
+		ECS:            f.ECS,
+		EC2:            f.EC2,
+		CloudWatch:     f.CloudWatch,
+	}
+}
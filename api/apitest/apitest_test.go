@@ -0,0 +1,466 @@
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+)
+
+const template = `Parameters:
+    Size:
+        Type: String
+
+Resources:
+    Queue:
+        Type: AWS::SQS::Queue
+`
+
+func TestCreateAndUpdateStack(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	fake.CloudFormation.SetOutputs("llamas", map[string]string{"QueueUrl": "https://sqs/llamas"})
+
+	ctx := api.StackOptions{Params: map[string]string{"Size": "small"}}
+	if err := api.CreateStack(svc.Cloudformation, "llamas", template, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	err := api.PollUntilCreated(context.Background(), svc.Cloudformation, "llamas", func(e *cloudformation.StackEvent) {
+		events = append(events, aws.StringValue(e.ResourceStatus))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1] != cloudformation.StackStatusCreateComplete {
+		t.Fatalf("Expected the stack to be created, got events %v", events)
+	}
+
+	outputs, err := api.StackOutputs(svc.Cloudformation, "llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs["QueueUrl"] != "https://sqs/llamas" {
+		t.Fatalf("Expected the stack's outputs, got %v", outputs)
+	}
+
+	if err = api.UpdateStack(svc.Cloudformation, "llamas", template, ctx); err != api.ErrNoStackUpdates {
+		t.Fatalf("Expected no updates to be performed, got %v", err)
+	}
+
+	stacks, err := api.FindStacksByName(svc.Cloudformation, "llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = api.UpdateStackParameters(svc.Cloudformation, stacks[0], map[string]string{"Size": "large"}); err != nil {
+		t.Fatal(err)
+	}
+	if params := fake.CloudFormation.Parameters("llamas"); params["Size"] != "large" {
+		t.Fatalf("Expected Size to be updated, got %v", params)
+	}
+	if body, _ := fake.CloudFormation.Template("llamas"); body != template {
+		t.Fatalf("Expected the previous template to be kept, got %q", body)
+	}
+}
+
+func TestCreateAndUpdateStackWithNamedRole(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	named := `Parameters:
+    Size:
+        Type: String
+
+Resources:
+    Role:
+        Type: AWS::IAM::Role
+        Properties:
+            RoleName: !Sub "deploy-${Size}"
+`
+
+	ctx := api.StackOptions{Params: map[string]string{"Size": "small"}}
+	if err := api.CreateStack(svc.Cloudformation, "llamas", named, ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Params["Size"] = "large"
+	if err := api.UpdateStack(svc.Cloudformation, "llamas", named, ctx); err != nil {
+		t.Fatal(err)
+	}
+	stacks, err := api.FindStacksByName(svc.Cloudformation, "llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = api.UpdateStackParameters(svc.Cloudformation, stacks[0], map[string]string{"Size": "medium"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStackPolicyProtectsStatefulResources(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+
+	body := template + `    Bucket:
+        Type: AWS::S3::Bucket
+`
+	if err := api.CreateStack(svc.Cloudformation, "llamas", body, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var policy struct {
+		Statement []struct {
+			Effect   string
+			Resource interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(fake.CloudFormation.StackPolicy("llamas")), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Statement) != 2 || policy.Statement[1].Effect != "Deny" ||
+		fmt.Sprint(policy.Statement[1].Resource) != "[LogicalResourceId/Bucket]" {
+		t.Fatalf("Expected the bucket to be protected, got %+v", policy)
+	}
+
+	if err := api.UpdateStack(svc.Cloudformation, "llamas", template, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if p := fake.CloudFormation.StackPolicy("llamas"); strings.Contains(p, "Deny") {
+		t.Fatalf("Expected nothing to be protected without the bucket, got %s", p)
+	}
+}
+
+func TestDeleteStack(t *testing.T) {
+	svc := New().Services()
+
+	if err := api.CreateStack(svc.Cloudformation, "llamas", template, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DeleteStack(svc.Cloudformation, "llamas"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.PollUntilDeleted(context.Background(), svc.Cloudformation, "llamas", func(*cloudformation.StackEvent) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.StackOutputs(svc.Cloudformation, "llamas"); err == nil {
+		t.Fatal("Expected the deleted stack not to exist")
+	}
+}
+
+func TestDeployTaskDefinition(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	fake.ECS.AddService("cluster", "web", "web:1", 2)
+
+	resp, err := svc.ECS.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("web"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("web"), Image: aws.String("nginx")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arn := aws.StringValue(resp.TaskDefinition.TaskDefinitionArn)
+
+	_, err = svc.ECS.UpdateService(&ecs.UpdateServiceInput{
+		Cluster:        aws.String("cluster"),
+		Service:        aws.String("web"),
+		TaskDefinition: aws.String(arn),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events int
+	err = api.PollUntilTaskDeployed(context.Background(), svc.ECS, "cluster", "web", arn, func(*ecs.ServiceEvent) {
+		events++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events != 1 {
+		t.Fatalf("Expected the service's steady state event, got %d events", events)
+	}
+}
+
+func TestApplyStackAndDeploy(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	fake.CloudFormation.SetOutputs("cluster-web", map[string]string{
+		"StackType":  "ecs-former::ecs-service",
+		"ECSCluster": "cluster",
+		"ECSService": "web",
+		"TaskFamily": "web",
+	})
+
+	opts := api.StackOptions{Params: map[string]string{"Size": "small"}}
+	for i := 0; i < 2; i++ {
+		if err := api.ApplyStack(context.Background(), svc.Cloudformation, "cluster-web", template, opts, func(*cloudformation.StackEvent) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := svc.ECS.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("web"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("web"), Image: aws.String("nginx:v1")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.ECS.AddService("cluster", "web", aws.StringValue(resp.TaskDefinition.TaskDefinitionArn), 2)
+
+	result, err := api.Deploy(context.Background(), svc, api.DeployOptions{
+		Cluster: "cluster",
+		Service: "web",
+		Images:  map[string]string{"web": ":v2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Images["web"] != "nginx:v2" || !strings.HasSuffix(result.TaskDefinitionArn, "web:2") {
+		t.Fatalf("Expected web:2 with nginx:v2 to be deployed, got %+v", result)
+	}
+	if image := aws.StringValue(resp.TaskDefinition.ContainerDefinitions[0].Image); image != "nginx:v1" {
+		t.Fatalf("Expected the previous task definition to be unchanged, got %s", image)
+	}
+}
+
+func TestFindStacksAcrossPages(t *testing.T) {
+	fake := New()
+	fake.CloudFormation.PageSize = 2
+	svc := api.WithStackIndex(fake.Services())
+
+	createService := func(name string) {
+		fake.CloudFormation.SetOutputs(name, map[string]string{
+			"StackType":  "ecs-former::ecs-service",
+			"ECSCluster": "cluster",
+			"TaskFamily": name,
+		})
+		opts := api.StackOptions{Params: map[string]string{"Size": "small", "ECSCluster": "cluster"}}
+		if err := api.CreateStack(svc.Cloudformation, name, template, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		createService(fmt.Sprintf("service%d", i))
+	}
+
+	stacks, err := api.FindServiceStacks(svc.Cloudformation, "cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stacks) != 5 {
+		t.Fatalf("Expected the stacks on every page to be found, got %d", len(stacks))
+	}
+	if cluster := api.StackTags(stacks[0])[api.TagCluster]; cluster != "cluster" {
+		t.Fatalf("Expected the stack to be tagged with its cluster, got %q", cluster)
+	}
+
+	// creating a stack drops the index, so the new stack is found
+	createService("service5")
+	if _, err = api.FindServiceStack(svc.Cloudformation, "cluster", "service5"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookupClusterStack(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+
+	stack, err := api.LookupClusterStack(svc.Cloudformation, "cluster")
+	if err != nil || stack != nil {
+		t.Fatalf("Expected no stack for a cluster that doesn't exist, got %v, %v", stack, err)
+	}
+	if _, err = api.FindClusterStack(svc.Cloudformation, "cluster"); err == nil {
+		t.Fatal("Expected finding a cluster that doesn't exist to fail")
+	}
+
+	fake.CloudFormation.SetOutputs("ecs-cluster-cluster", map[string]string{
+		"StackType":  "ecs-former::ecs-stack",
+		"ECSCluster": "cluster",
+	})
+	opts := api.StackOptions{Params: map[string]string{"Size": "small"}}
+	if err = api.CreateStack(svc.Cloudformation, "ecs-cluster-cluster", template, opts); err != nil {
+		t.Fatal(err)
+	}
+	if stack, err = api.LookupClusterStack(svc.Cloudformation, "cluster"); err != nil || stack == nil {
+		t.Fatalf("Expected the cluster's stack, got %v, %v", stack, err)
+	}
+}
+
+// throttledEvents fails the first poll of stack events after its first page
+type throttledEvents struct {
+	api.CFNAPI
+	throttled bool
+}
+
+func (c *throttledEvents) DescribeStackEventsPages(input *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error {
+	if c.throttled {
+		return c.CFNAPI.DescribeStackEventsPages(input, fn)
+	}
+	c.throttled = true
+	c.CFNAPI.DescribeStackEventsPages(input, func(page *cloudformation.DescribeStackEventsOutput, last bool) bool {
+		fn(page, false)
+		return false
+	})
+	return awserr.New("Throttling", "Rate exceeded", nil)
+}
+
+func TestPollUntilCreatedRetriesThrottling(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	if err := api.CreateStack(svc.Cloudformation, "llamas", template, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	err := api.PollUntilCreated(context.Background(), &throttledEvents{CFNAPI: svc.Cloudformation}, "llamas", func(e *cloudformation.StackEvent) {
+		events = append(events, aws.StringValue(e.ResourceStatus))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected each event once after the poll was retried, got %v", events)
+	}
+}
+
+func TestUpdateStackAttachesToUpdateInProgress(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	opts := api.StackOptions{Params: map[string]string{"Size": "small"}, Attach: true}
+	if err := api.CreateStack(svc.Cloudformation, "llamas", template, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	// attaching to an update of the same template and parameters is quiet
+	fake.CloudFormation.StartUpdate("llamas", template, opts.Params)
+	if err := api.UpdateStack(svc.Cloudformation, "llamas", template, opts); err != nil {
+		t.Fatalf("Expected to attach to the update in progress, got %v", err)
+	}
+	if strings.Contains(logged.String(), "Warning") {
+		t.Fatalf("Expected no warning, got %q", logged.String())
+	}
+	fake.CloudFormation.FinishUpdate("llamas")
+
+	// but the changes of this update aren't applied by the one in progress
+	fake.CloudFormation.StartUpdate("llamas", template, opts.Params)
+	opts.Params = map[string]string{"Size": "large"}
+	if err := api.UpdateStack(svc.Cloudformation, "llamas", template, opts); err != nil {
+		t.Fatalf("Expected to attach to the update in progress, got %v", err)
+	}
+	if !strings.Contains(logged.String(), "Warning: attached to the UPDATE_IN_PROGRESS") {
+		t.Fatalf("Expected a warning that the update wasn't applied, got %q", logged.String())
+	}
+
+	opts.Attach = false
+	if err := api.UpdateStack(svc.Cloudformation, "llamas", template, opts); err == nil {
+		t.Fatalf("Expected updating a stack with an update in progress to fail without attaching")
+	}
+}
+
+func TestPollUntilCreatedStartsAtLatestOperation(t *testing.T) {
+	fake := New()
+	fake.CloudFormation.PageSize = 1
+	svc := fake.Services()
+	if err := api.CreateStack(svc.Cloudformation, "llamas", template, api.StackOptions{Params: map[string]string{"Size": "small"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.UpdateStack(svc.Cloudformation, "llamas", template, api.StackOptions{Params: map[string]string{"Size": "large"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	err := api.PollUntilCreated(context.Background(), svc.Cloudformation, "llamas", func(e *cloudformation.StackEvent) {
+		events = append(events, aws.StringValue(e.ResourceStatus))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(events, ",") != "UPDATE_IN_PROGRESS,UPDATE_COMPLETE" {
+		t.Fatalf("Expected only the events of the update, got %v", events)
+	}
+}
+
+// failingLookups fails describing stacks, either when listing them all or when describing
+// one by name
+type failingLookups struct {
+	api.CFNAPI
+	byName bool
+}
+
+func (c *failingLookups) DescribeStacksPages(input *cloudformation.DescribeStacksInput, fn func(*cloudformation.DescribeStacksOutput, bool) bool) error {
+	if (input != nil && input.StackName != nil) == c.byName {
+		return awserr.New("Throttling", "Rate exceeded", nil)
+	}
+	return c.CFNAPI.DescribeStacksPages(input, fn)
+}
+
+func (c *failingLookups) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	if (input != nil && input.StackName != nil) == c.byName {
+		return nil, awserr.New("Throttling", "Rate exceeded", nil)
+	}
+	return c.CFNAPI.DescribeStacks(input)
+}
+
+func TestLookupNetworkStack(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	fake.CloudFormation.SetOutputs("example-network", map[string]string{"VpcId": "vpc-1234"})
+	if err := api.CreateStack(svc.Cloudformation, "example-network", template, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	network, err := api.LookupNetworkStack(svc.Cloudformation, "example")
+	if err != nil || network == nil || network.VpcId != "vpc-1234" {
+		t.Fatalf("Expected the network stack's outputs, got %+v, %v", network, err)
+	}
+	if network, err = api.LookupNetworkStack(svc.Cloudformation, "missing"); err != nil || network != nil {
+		t.Fatalf("Expected no network stack for a missing cluster, got %+v, %v", network, err)
+	}
+	if network, err = api.LookupNetworkStack(&failingLookups{CFNAPI: svc.Cloudformation, byName: true}, "example"); err == nil {
+		t.Fatalf("Expected a failed lookup to fail rather than be taken as missing, got %+v", network)
+	}
+}
+
+func TestFindAllStacksForClusterFailsOnPartialResults(t *testing.T) {
+	fake := New()
+	svc := fake.Services()
+	fake.CloudFormation.SetOutputs("ecs-example-cluster", map[string]string{"StackType": "ecs-former::ecs-stack", "ECSCluster": "example"})
+	for _, name := range []string{"ecs-example-cluster", "example-network"} {
+		if err := api.CreateStack(svc.Cloudformation, name, template, api.StackOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, "example")
+	if err != nil || len(stacks) != 2 {
+		t.Fatalf("Expected the cluster and network stacks, got %d, %v", len(stacks), err)
+	}
+	if stacks, err = api.FindAllStacksForCluster(svc.Cloudformation, "missing"); err != nil || len(stacks) != 0 {
+		t.Fatalf("Expected no stacks for a missing cluster, got %d, %v", len(stacks), err)
+	}
+
+	for _, byName := range []bool{false, true} {
+		stacks, err := api.FindAllStacksForCluster(&failingLookups{CFNAPI: svc.Cloudformation, byName: byName}, "example")
+		if err == nil {
+			t.Errorf("Expected a failed lookup (by name: %v) to fail, got %d stacks", byName, len(stacks))
+		}
+	}
+}
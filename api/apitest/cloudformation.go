@@ -0,0 +1,502 @@
+package apitest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// CloudFormation is an in-memory cloudformation that implements api.CFNAPI. Stacks finish
+// creating and updating as soon as they are started, with the outputs set by SetOutputs.
+type CloudFormation struct {
+	// PageSize splits the stacks and events that are described into pages of this many,
+	// like cloudformation does, everything is in one page when it's zero
+	PageSize int
+
+	mu         sync.Mutex
+	stacks     map[string]*fakeStack
+	outputs    map[string]map[string]string
+	changeSets map[string]*cloudformation.CreateChangeSetInput
+	clock      time.Time
+}
+
+type fakeStack struct {
+	stack  *cloudformation.Stack
+	body   string
+	policy string
+	events []*cloudformation.StackEvent
+}
+
+func NewCloudFormation() *CloudFormation {
+	return &CloudFormation{
+		stacks:     map[string]*fakeStack{},
+		outputs:    map[string]map[string]string{},
+		changeSets: map[string]*cloudformation.CreateChangeSetInput{},
+		clock:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// SetOutputs sets the outputs of a stack, which are applied when it's created or updated
+func (c *CloudFormation) SetOutputs(stackName string, outputs map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.outputs[stackName] = outputs
+	if s, ok := c.stacks[stackName]; ok {
+		s.stack.Outputs = c.stackOutputs(stackName)
+	}
+}
+
+// Template returns the template a stack was last created or updated with
+func (c *CloudFormation) Template(stackName string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.stacks[stackName]
+	if !ok {
+		return "", false
+	}
+	return s.body, true
+}
+
+// StackPolicy returns the stack policy a stack was last created, updated or set with
+func (c *CloudFormation) StackPolicy(stackName string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.stacks[stackName]; ok {
+		return s.policy
+	}
+	return ""
+}
+
+// StartUpdate starts updating a stack to a template and parameters without finishing, as
+// if another command were updating it, until FinishUpdate is called
+func (c *CloudFormation) StartUpdate(stackName string, body string, params map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.stacks[stackName]
+	if !ok {
+		return
+	}
+	s.body = body
+	s.stack.Parameters = []*cloudformation.Parameter{}
+	for k, v := range params {
+		s.stack.Parameters = append(s.stack.Parameters, &cloudformation.Parameter{
+			ParameterKey:   aws.String(k),
+			ParameterValue: aws.String(v),
+		})
+	}
+	c.setStatus(s, cloudformation.StackStatusUpdateInProgress)
+}
+
+// FinishUpdate completes an update started by StartUpdate
+func (c *CloudFormation) FinishUpdate(stackName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.stacks[stackName]; ok {
+		c.setStatus(s, cloudformation.StackStatusUpdateComplete)
+	}
+}
+
+// Parameters returns the parameters a stack was last created or updated with
+func (c *CloudFormation) Parameters(stackName string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	params := map[string]string{}
+	if s, ok := c.stacks[stackName]; ok {
+		for _, p := range s.stack.Parameters {
+			params[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+		}
+	}
+	return params
+}
+
+func (c *CloudFormation) stackOutputs(stackName string) []*cloudformation.Output {
+	keys := []string{}
+	for k := range c.outputs[stackName] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	outputs := []*cloudformation.Output{}
+	for _, k := range keys {
+		outputs = append(outputs, &cloudformation.Output{
+			OutputKey:   aws.String(k),
+			OutputValue: aws.String(c.outputs[stackName][k]),
+		})
+	}
+	return outputs
+}
+
+// setStatus records a stack's status along with an event for it, newest first like the api
+func (c *CloudFormation) setStatus(s *fakeStack, status string) {
+	c.clock = c.clock.Add(time.Second)
+	s.stack.StackStatus = aws.String(status)
+	s.events = append([]*cloudformation.StackEvent{{
+		EventId:           aws.String(fmt.Sprintf("%s-%d", aws.StringValue(s.stack.StackName), len(s.events)+1)),
+		StackName:         s.stack.StackName,
+		LogicalResourceId: s.stack.StackName,
+		ResourceType:      aws.String("AWS::CloudFormation::Stack"),
+		ResourceStatus:    aws.String(status),
+		Timestamp:         aws.Time(c.clock),
+	}}, s.events...)
+}
+
+func stackNotFound(name string) error {
+	return awserr.New("ValidationError", fmt.Sprintf("Stack with id %s does not exist", name), nil)
+}
+
+// lookup returns a stack that hasn't been deleted
+func (c *CloudFormation) lookup(name string) (*fakeStack, error) {
+	s, ok := c.stacks[name]
+	if !ok || aws.StringValue(s.stack.StackStatus) == cloudformation.StackStatusDeleteComplete {
+		return nil, stackNotFound(name)
+	}
+	return s, nil
+}
+
+// resolveParams applies parameters that use their previous values
+func resolveParams(previous, params []*cloudformation.Parameter) []*cloudformation.Parameter {
+	values := map[string]string{}
+	for _, p := range previous {
+		values[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+	}
+
+	resolved := []*cloudformation.Parameter{}
+	for _, p := range params {
+		value := aws.StringValue(p.ParameterValue)
+		if aws.BoolValue(p.UsePreviousValue) {
+			value = values[aws.StringValue(p.ParameterKey)]
+		}
+		resolved = append(resolved, &cloudformation.Parameter{
+			ParameterKey:   p.ParameterKey,
+			ParameterValue: aws.String(value),
+		})
+	}
+	sort.Slice(resolved, func(i, j int) bool {
+		return aws.StringValue(resolved[i].ParameterKey) < aws.StringValue(resolved[j].ParameterKey)
+	})
+	return resolved
+}
+
+func sameParams(a, b []*cloudformation.Parameter) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if aws.StringValue(a[i].ParameterKey) != aws.StringValue(b[i].ParameterKey) ||
+			aws.StringValue(a[i].ParameterValue) != aws.StringValue(b[i].ParameterValue) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CloudFormation) CreateStack(input *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(input.StackName)
+	if _, err := c.lookup(name); err == nil {
+		return nil, awserr.New("AlreadyExistsException", fmt.Sprintf("Stack [%s] already exists", name), nil)
+	}
+
+	if err := checkCapabilities(aws.StringValue(input.TemplateBody), input.Capabilities); err != nil {
+		return nil, err
+	}
+
+	id := fmt.Sprintf("arn:aws:cloudformation:us-east-1:123456789012:stack/%s/%d", name, len(c.stacks)+1)
+	s := &fakeStack{
+		stack: &cloudformation.Stack{
+			StackId:      aws.String(id),
+			StackName:    aws.String(name),
+			Parameters:   resolveParams(nil, input.Parameters),
+			Tags:         input.Tags,
+			Outputs:      c.stackOutputs(name),
+			CreationTime: aws.Time(c.clock),
+		},
+		body:   aws.StringValue(input.TemplateBody),
+		policy: aws.StringValue(input.StackPolicyBody),
+	}
+	c.stacks[name] = s
+	c.setStatus(s, cloudformation.StackStatusCreateInProgress)
+	c.setStatus(s, cloudformation.StackStatusCreateComplete)
+	return &cloudformation.CreateStackOutput{StackId: aws.String(id)}, nil
+}
+
+func (c *CloudFormation) UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.lookup(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	if status := aws.StringValue(s.stack.StackStatus); strings.HasSuffix(status, "_IN_PROGRESS") {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("Stack:%s is in %s state and can not be updated.",
+			aws.StringValue(s.stack.StackId), status), nil)
+	}
+	body := aws.StringValue(input.TemplateBody)
+	if aws.BoolValue(input.UsePreviousTemplate) {
+		body = s.body
+	}
+	if err := checkCapabilities(body, input.Capabilities); err != nil {
+		return nil, err
+	}
+
+	if !c.update(s, body, input.Parameters, input.Tags) {
+		return nil, awserr.New("ValidationError", "No updates are to be performed.", nil)
+	}
+	if input.StackPolicyBody != nil {
+		s.policy = aws.StringValue(input.StackPolicyBody)
+	}
+	return &cloudformation.UpdateStackOutput{StackId: s.stack.StackId}, nil
+}
+
+// namedIAMResource matches the properties that name IAM resources in a template
+var namedIAMResource = regexp.MustCompile(`(?m)^\s+(RoleName|UserName|GroupName|ManagedPolicyName):`)
+
+// checkCapabilities fails like cloudformation when a template that names IAM resources
+// isn't acknowledged with CAPABILITY_NAMED_IAM
+func checkCapabilities(body string, capabilities []*string) error {
+	if !namedIAMResource.MatchString(body) {
+		return nil
+	}
+	for _, c := range capabilities {
+		if aws.StringValue(c) == "CAPABILITY_NAMED_IAM" {
+			return nil
+		}
+	}
+	return awserr.New("InsufficientCapabilitiesException", "Requires capabilities : [CAPABILITY_NAMED_IAM]", nil)
+}
+
+func (c *CloudFormation) SetStackPolicy(input *cloudformation.SetStackPolicyInput) (*cloudformation.SetStackPolicyOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.lookup(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	s.policy = aws.StringValue(input.StackPolicyBody)
+	return &cloudformation.SetStackPolicyOutput{}, nil
+}
+
+// update applies a template and parameters to a stack, returning false without changing
+// anything if they are the same
+func (c *CloudFormation) update(s *fakeStack, body string, params []*cloudformation.Parameter, tags []*cloudformation.Tag) bool {
+	resolved := resolveParams(s.stack.Parameters, params)
+	if body == s.body && sameParams(resolved, s.stack.Parameters) {
+		return false
+	}
+	s.body = body
+	s.stack.Parameters = resolved
+	s.stack.Tags = tags
+	s.stack.Outputs = c.stackOutputs(aws.StringValue(s.stack.StackName))
+	c.setStatus(s, cloudformation.StackStatusUpdateInProgress)
+	c.setStatus(s, cloudformation.StackStatusUpdateComplete)
+	return true
+}
+
+func (c *CloudFormation) DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, err := c.lookup(aws.StringValue(input.StackName)); err == nil {
+		c.setStatus(s, cloudformation.StackStatusDeleteInProgress)
+		c.setStatus(s, cloudformation.StackStatusDeleteComplete)
+	}
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func (c *CloudFormation) CancelUpdateStack(input *cloudformation.CancelUpdateStackInput) (*cloudformation.CancelUpdateStackOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.lookup(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	// updates finish as soon as they start, so only those started by StartUpdate can be cancelled
+	if aws.StringValue(s.stack.StackStatus) != cloudformation.StackStatusUpdateInProgress {
+		return nil, awserr.New("ValidationError", "CancelUpdateStack cannot be called from current stack status", nil)
+	}
+	c.setStatus(s, cloudformation.StackStatusUpdateRollbackInProgress)
+	c.setStatus(s, cloudformation.StackStatusUpdateRollbackComplete)
+	return &cloudformation.CancelUpdateStackOutput{}, nil
+}
+
+func (c *CloudFormation) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if input != nil && input.StackName != nil {
+		s, err := c.lookup(aws.StringValue(input.StackName))
+		if err != nil {
+			return nil, err
+		}
+		return &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{s.stack}}, nil
+	}
+
+	names := []string{}
+	for name := range c.stacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	output := &cloudformation.DescribeStacksOutput{}
+	for _, name := range names {
+		if s, err := c.lookup(name); err == nil {
+			output.Stacks = append(output.Stacks, s.stack)
+		}
+	}
+	return output, nil
+}
+
+func (c *CloudFormation) DescribeStacksPages(input *cloudformation.DescribeStacksInput, fn func(*cloudformation.DescribeStacksOutput, bool) bool) error {
+	output, err := c.DescribeStacks(input)
+	if err != nil {
+		return err
+	}
+	for start := 0; ; start += c.PageSize {
+		end := len(output.Stacks)
+		if c.PageSize > 0 && start+c.PageSize < end {
+			end = start + c.PageSize
+		}
+		last := end == len(output.Stacks)
+		if !fn(&cloudformation.DescribeStacksOutput{Stacks: output.Stacks[start:end]}, last) || last {
+			return nil
+		}
+	}
+}
+
+func (c *CloudFormation) DescribeStackEventsPages(input *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error {
+	c.mu.Lock()
+	s, ok := c.stacks[aws.StringValue(input.StackName)]
+	var events []*cloudformation.StackEvent
+	if ok {
+		events = append(events, s.events...)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return stackNotFound(aws.StringValue(input.StackName))
+	}
+	for start := 0; ; start += c.PageSize {
+		end := len(events)
+		if c.PageSize > 0 && start+c.PageSize < end {
+			end = start + c.PageSize
+		}
+		last := end == len(events)
+		if !fn(&cloudformation.DescribeStackEventsOutput{StackEvents: events[start:end]}, last) || last {
+			return nil
+		}
+	}
+}
+
+func (c *CloudFormation) GetTemplate(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.lookup(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	return &cloudformation.GetTemplateOutput{TemplateBody: aws.String(s.body)}, nil
+}
+
+func (c *CloudFormation) DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.lookup(aws.StringValue(input.StackName)); err != nil {
+		return nil, err
+	}
+	return &cloudformation.DescribeStackResourcesOutput{}, nil
+}
+
+func changeSetKey(stackName, name *string) string {
+	return aws.StringValue(stackName) + "/" + aws.StringValue(name)
+}
+
+func (c *CloudFormation) CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.lookup(aws.StringValue(input.StackName)); err != nil {
+		return nil, err
+	}
+	c.changeSets[changeSetKey(input.StackName, input.ChangeSetName)] = input
+	return &cloudformation.CreateChangeSetOutput{}, nil
+}
+
+// DescribeChangeSet reports a change set that changes the template or parameters as a
+// modification of the stack itself, as the fake doesn't model resources
+func (c *CloudFormation) DescribeChangeSet(input *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs, ok := c.changeSets[changeSetKey(input.StackName, input.ChangeSetName)]
+	if !ok {
+		return nil, awserr.New("ChangeSetNotFound", "ChangeSet does not exist", nil)
+	}
+	s, err := c.lookup(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.StringValue(cs.TemplateBody) == s.body && sameParams(resolveParams(s.stack.Parameters, cs.Parameters), s.stack.Parameters) {
+		return &cloudformation.DescribeChangeSetOutput{
+			Status:       aws.String(cloudformation.ChangeSetStatusFailed),
+			StatusReason: aws.String("The submitted information didn't contain changes."),
+		}, nil
+	}
+	return &cloudformation.DescribeChangeSetOutput{
+		Status: aws.String(cloudformation.ChangeSetStatusCreateComplete),
+		Changes: []*cloudformation.Change{{
+			Type: aws.String("Resource"),
+			ResourceChange: &cloudformation.ResourceChange{
+				Action:            aws.String(cloudformation.ChangeActionModify),
+				LogicalResourceId: s.stack.StackName,
+				ResourceType:      aws.String("AWS::CloudFormation::Stack"),
+			},
+		}},
+	}, nil
+}
+
+func (c *CloudFormation) ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := changeSetKey(input.StackName, input.ChangeSetName)
+	cs, ok := c.changeSets[key]
+	if !ok {
+		return nil, awserr.New("ChangeSetNotFound", "ChangeSet does not exist", nil)
+	}
+	s, err := c.lookup(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	c.update(s, aws.StringValue(cs.TemplateBody), cs.Parameters, cs.Tags)
+	delete(c.changeSets, key)
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+func (c *CloudFormation) DeleteChangeSet(input *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.changeSets, changeSetKey(input.StackName, input.ChangeSetName))
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
@@ -0,0 +1,42 @@
+package apitest
+
+import (
+	"sync"
+
+	"github.com/lox/ecsy/api"
+)
+
+// CloudWatch is an in-memory cloudwatch with the alarms set on it, it has no metrics
+type CloudWatch struct {
+	mu     sync.Mutex
+	alarms map[string]api.Alarm
+}
+
+func NewCloudWatch() *CloudWatch {
+	return &CloudWatch{alarms: map[string]api.Alarm{}}
+}
+
+// SetAlarm adds an alarm or changes its state
+func (c *CloudWatch) SetAlarm(alarm api.Alarm) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.alarms[alarm.Name] = alarm
+}
+
+func (c *CloudWatch) GetMetricStatistics(input *api.MetricStatisticsInput) ([]api.Datapoint, error) {
+	return nil, nil
+}
+
+func (c *CloudWatch) DescribeAlarms(names []string) ([]api.Alarm, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	alarms := []api.Alarm{}
+	for _, name := range names {
+		if alarm, ok := c.alarms[name]; ok {
+			alarms = append(alarms, alarm)
+		}
+	}
+	return alarms, nil
+}
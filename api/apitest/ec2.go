@@ -0,0 +1,266 @@
+package apitest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lox/ecsy/api"
+)
+
+// EC2 is an in-memory ec2 that implements api.EC2API, with the security groups and instances
+// added to it
+type EC2 struct {
+	mu             sync.Mutex
+	securityGroups map[string]api.SecurityGroup
+	instances      map[string]api.Instance
+	keyPairs       []string
+	offerings      []string
+	vpcs           []string
+	addresses      []string
+	zones          []string
+	zoneOfferings  map[string][]string
+	subnets        map[string]api.Subnet
+	images         []string
+	events         []api.InstanceEvent
+	imageDates     map[string]time.Time
+}
+
+func NewEC2() *EC2 {
+	return &EC2{
+		securityGroups: map[string]api.SecurityGroup{},
+		instances:      map[string]api.Instance{},
+		zoneOfferings:  map[string][]string{},
+		subnets:        map[string]api.Subnet{},
+		imageDates:     map[string]time.Time{},
+	}
+}
+
+// AddSecurityGroup adds or replaces a security group
+func (e *EC2) AddSecurityGroup(group api.SecurityGroup) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.securityGroups[group.ID] = group
+}
+
+// AddKeyPair adds a key pair
+func (e *EC2) AddKeyPair(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.keyPairs = append(e.keyPairs, name)
+	sort.Strings(e.keyPairs)
+}
+
+// AddInstanceTypeOfferings adds instance types to those offered in the region
+func (e *EC2) AddInstanceTypeOfferings(instanceTypes ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.offerings = append(e.offerings, instanceTypes...)
+	sort.Strings(e.offerings)
+}
+
+// AddAvailabilityZone adds an available zone, offering instance types in it
+func (e *EC2) AddAvailabilityZone(zone string, instanceTypes ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.zones = append(e.zones, zone)
+	sort.Strings(e.zones)
+	for _, t := range instanceTypes {
+		e.zoneOfferings[t] = append(e.zoneOfferings[t], zone)
+		sort.Strings(e.zoneOfferings[t])
+	}
+}
+
+// AddSubnet adds or replaces a subnet
+func (e *EC2) AddSubnet(subnet api.Subnet) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.subnets[subnet.ID] = subnet
+}
+
+// AddImage adds an available image
+func (e *EC2) AddImage(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.images = append(e.images, id)
+}
+
+// AddImageCreated adds an available image that was created at a time
+func (e *EC2) AddImageCreated(id string, created time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.images = append(e.images, id)
+	e.imageDates[id] = created
+}
+
+// AddVpc adds a VPC
+func (e *EC2) AddVpc(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.vpcs = append(e.vpcs, id)
+}
+
+// AddAddress allocates an elastic ip
+func (e *EC2) AddAddress(ip string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.addresses = append(e.addresses, ip)
+}
+
+// AddInstanceEvent schedules maintenance for an instance
+func (e *EC2) AddInstanceEvent(event api.InstanceEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.events = append(e.events, event)
+}
+
+// AddInstance adds or replaces an instance
+func (e *EC2) AddInstance(instance api.Instance) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.instances[instance.ID] = instance
+}
+
+func (e *EC2) DescribeSecurityGroups(groupIds []string) ([]api.SecurityGroup, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	groups := []api.SecurityGroup{}
+	for _, id := range groupIds {
+		group, ok := e.securityGroups[id]
+		if !ok {
+			return nil, fmt.Errorf("InvalidGroup.NotFound: The security group '%s' does not exist", id)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (e *EC2) DescribeInstances(instanceIds []string) ([]api.Instance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instances := []api.Instance{}
+	for _, id := range instanceIds {
+		instance, ok := e.instances[id]
+		if !ok {
+			return nil, fmt.Errorf("InvalidInstanceID.NotFound: The instance ID '%s' does not exist", id)
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (e *EC2) DescribeKeyPairs() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string{}, e.keyPairs...), nil
+}
+
+func (e *EC2) DescribeInstanceTypeOfferings() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string{}, e.offerings...), nil
+}
+
+func (e *EC2) DescribeVpcs() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string{}, e.vpcs...), nil
+}
+
+func (e *EC2) DescribeAddresses() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string{}, e.addresses...), nil
+}
+
+func (e *EC2) DescribeInstanceTypeZones(instanceType string) ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string{}, e.zoneOfferings[instanceType]...), nil
+}
+
+func (e *EC2) DescribeAvailabilityZones() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string{}, e.zones...), nil
+}
+
+func (e *EC2) DescribeSubnets(subnetIds []string) ([]api.Subnet, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	subnets := []api.Subnet{}
+	for _, id := range subnetIds {
+		subnet, ok := e.subnets[id]
+		if !ok {
+			return nil, fmt.Errorf("InvalidSubnetID.NotFound: The subnet ID '%s' does not exist", id)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+func (e *EC2) DescribeImages(imageIds []string) ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	found := []string{}
+	for _, id := range imageIds {
+		for _, image := range e.images {
+			if image == id {
+				found = append(found, id)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (e *EC2) DescribeInstanceEvents(instanceIds []string) ([]api.InstanceEvent, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	events := []api.InstanceEvent{}
+	for _, event := range e.events {
+		for _, id := range instanceIds {
+			if event.InstanceID == id {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+func (e *EC2) DescribeImageCreationDates(imageIds []string) (map[string]time.Time, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	dates := map[string]time.Time{}
+	for _, id := range imageIds {
+		for _, image := range e.images {
+			if image == id {
+				dates[id] = e.imageDates[id]
+			}
+		}
+	}
+	return dates, nil
+}
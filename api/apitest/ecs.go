@@ -0,0 +1,331 @@
+package apitest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const arnPrefix = "arn:aws:ecs:us-east-1:123456789012:"
+
+// ECS is an in-memory ecs that implements api.ECSAPI. Services deploy a new task definition
+// as soon as they are updated, and tasks stop as soon as they have run.
+type ECS struct {
+	mu              sync.Mutex
+	clusters        map[string]*ecs.Cluster
+	services        map[string]*ecs.Service
+	taskDefinitions map[string]*ecs.TaskDefinition
+	revisions       map[string]int64
+	tasks           map[string]*ecs.Task
+	instances       map[string]*ecs.ContainerInstance
+	clock           time.Time
+}
+
+func NewECS() *ECS {
+	return &ECS{
+		clusters:        map[string]*ecs.Cluster{},
+		services:        map[string]*ecs.Service{},
+		taskDefinitions: map[string]*ecs.TaskDefinition{},
+		revisions:       map[string]int64{},
+		tasks:           map[string]*ecs.Task{},
+		instances:       map[string]*ecs.ContainerInstance{},
+		clock:           time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// AddService adds a running service to a cluster, as if its stack had created it
+func (e *ECS) AddService(cluster, name, taskDefinition string, desired int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.services[cluster+"/"+name] = &ecs.Service{
+		ServiceName:    aws.String(name),
+		ServiceArn:     aws.String(arnPrefix + "service/" + cluster + "/" + name),
+		ClusterArn:     aws.String(arnPrefix + "cluster/" + cluster),
+		TaskDefinition: aws.String(taskDefinition),
+		Status:         aws.String("ACTIVE"),
+		DesiredCount:   aws.Int64(desired),
+		RunningCount:   aws.Int64(desired),
+		Deployments: []*ecs.Deployment{{
+			Status:         aws.String("PRIMARY"),
+			TaskDefinition: aws.String(taskDefinition),
+			DesiredCount:   aws.Int64(desired),
+			RunningCount:   aws.Int64(desired),
+		}},
+	}
+}
+
+// AddContainerInstance adds an instance to a cluster
+func (e *ECS) AddContainerInstance(cluster string, instance *ecs.ContainerInstance) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.instances[cluster+"/"+aws.StringValue(instance.ContainerInstanceArn)] = instance
+}
+
+// clusterName accepts either the name or the arn of a cluster, like the api
+func clusterName(cluster *string) string {
+	name := aws.StringValue(cluster)
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		name = name[idx+1:]
+	}
+	if name == "" {
+		return "default"
+	}
+	return name
+}
+
+func (e *ECS) CreateCluster(input *ecs.CreateClusterInput) (*ecs.CreateClusterOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	name := clusterName(input.ClusterName)
+	if _, ok := e.clusters[name]; !ok {
+		e.clusters[name] = &ecs.Cluster{
+			ClusterName: aws.String(name),
+			ClusterArn:  aws.String(arnPrefix + "cluster/" + name),
+			Status:      aws.String("ACTIVE"),
+		}
+	}
+	return &ecs.CreateClusterOutput{Cluster: e.clusters[name]}, nil
+}
+
+func (e *ECS) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	family := aws.StringValue(input.Family)
+	e.revisions[family]++
+	arn := fmt.Sprintf("%stask-definition/%s:%d", arnPrefix, family, e.revisions[family])
+
+	td := &ecs.TaskDefinition{
+		TaskDefinitionArn:    aws.String(arn),
+		Family:               input.Family,
+		Revision:             aws.Int64(e.revisions[family]),
+		Status:               aws.String("ACTIVE"),
+		ContainerDefinitions: input.ContainerDefinitions,
+		NetworkMode:          input.NetworkMode,
+		TaskRoleArn:          input.TaskRoleArn,
+		Volumes:              input.Volumes,
+	}
+	e.taskDefinitions[arn] = td
+	e.taskDefinitions[family] = td
+	return &ecs.RegisterTaskDefinitionOutput{TaskDefinition: td}, nil
+}
+
+func (e *ECS) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	td, ok := e.taskDefinitions[aws.StringValue(input.TaskDefinition)]
+	if !ok {
+		return nil, fmt.Errorf("ClientException: Unable to describe task definition %s", aws.StringValue(input.TaskDefinition))
+	}
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: td}, nil
+}
+
+func (e *ECS) DescribeServices(input *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cluster := clusterName(input.Cluster)
+	output := &ecs.DescribeServicesOutput{}
+	for _, name := range input.Services {
+		if s, ok := e.services[cluster+"/"+aws.StringValue(name)]; ok {
+			output.Services = append(output.Services, s)
+		} else {
+			output.Failures = append(output.Failures, &ecs.Failure{
+				Arn:    name,
+				Reason: aws.String("MISSING"),
+			})
+		}
+	}
+	return output, nil
+}
+
+// UpdateService replaces a service's deployment straight away, recording an event for it
+func (e *ECS) UpdateService(input *ecs.UpdateServiceInput) (*ecs.UpdateServiceOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, ok := e.services[clusterName(input.Cluster)+"/"+aws.StringValue(input.Service)]
+	if !ok {
+		return nil, fmt.Errorf("ServiceNotFoundException: Service not found.")
+	}
+	if input.TaskDefinition != nil {
+		s.TaskDefinition = input.TaskDefinition
+	}
+	if input.DesiredCount != nil {
+		s.DesiredCount = input.DesiredCount
+	}
+	s.RunningCount = s.DesiredCount
+	s.Deployments = []*ecs.Deployment{{
+		Status:         aws.String("PRIMARY"),
+		TaskDefinition: s.TaskDefinition,
+		DesiredCount:   s.DesiredCount,
+		RunningCount:   s.DesiredCount,
+	}}
+
+	// events are only shown if they are newer than when polling started
+	e.clock = time.Now()
+	s.Events = append([]*ecs.ServiceEvent{{
+		Id:        aws.String(fmt.Sprintf("%d", len(s.Events)+1)),
+		CreatedAt: aws.Time(e.clock),
+		Message:   aws.String(fmt.Sprintf("(service %s) has reached a steady state.", aws.StringValue(s.ServiceName))),
+	}}, s.Events...)
+	return &ecs.UpdateServiceOutput{Service: s}, nil
+}
+
+func (e *ECS) ListServicesPages(input *ecs.ListServicesInput, fn func(*ecs.ListServicesOutput, bool) bool) error {
+	e.mu.Lock()
+	prefix := clusterName(input.Cluster) + "/"
+	output := &ecs.ListServicesOutput{}
+	for key, s := range e.services {
+		if strings.HasPrefix(key, prefix) {
+			output.ServiceArns = append(output.ServiceArns, s.ServiceArn)
+		}
+	}
+	e.mu.Unlock()
+
+	fn(output, true)
+	return nil
+}
+
+// RunTask starts tasks that have already stopped, with their containers exiting cleanly
+func (e *ECS) RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	td, ok := e.taskDefinitions[aws.StringValue(input.TaskDefinition)]
+	if !ok {
+		return nil, fmt.Errorf("ClientException: TaskDefinition not found.")
+	}
+
+	count := aws.Int64Value(input.Count)
+	if count == 0 {
+		count = 1
+	}
+
+	output := &ecs.RunTaskOutput{}
+	for i := int64(0); i < count; i++ {
+		task := &ecs.Task{
+			TaskArn:           aws.String(fmt.Sprintf("%stask/%d", arnPrefix, len(e.tasks)+1)),
+			ClusterArn:        aws.String(arnPrefix + "cluster/" + clusterName(input.Cluster)),
+			TaskDefinitionArn: td.TaskDefinitionArn,
+			StartedBy:         input.StartedBy,
+			LastStatus:        aws.String("STOPPED"),
+			DesiredStatus:     aws.String("STOPPED"),
+		}
+		for _, c := range td.ContainerDefinitions {
+			task.Containers = append(task.Containers, &ecs.Container{
+				Name:       c.Name,
+				LastStatus: aws.String("STOPPED"),
+				ExitCode:   aws.Int64(0),
+			})
+		}
+		e.tasks[aws.StringValue(task.TaskArn)] = task
+		output.Tasks = append(output.Tasks, task)
+	}
+	return output, nil
+}
+
+func (e *ECS) DescribeTasks(input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	output := &ecs.DescribeTasksOutput{}
+	for _, arn := range input.Tasks {
+		if task, ok := e.tasks[aws.StringValue(arn)]; ok {
+			output.Tasks = append(output.Tasks, task)
+		} else {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: arn, Reason: aws.String("MISSING")})
+		}
+	}
+	return output, nil
+}
+
+// StopTask stops a task straight away
+func (e *ECS) StopTask(input *ecs.StopTaskInput) (*ecs.StopTaskOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	task, ok := e.tasks[aws.StringValue(input.Task)]
+	if !ok {
+		return nil, fmt.Errorf("InvalidParameterException: The referenced task was not found.")
+	}
+	task.LastStatus = aws.String("STOPPED")
+	task.DesiredStatus = aws.String("STOPPED")
+	task.StoppedReason = input.Reason
+	return &ecs.StopTaskOutput{Task: task}, nil
+}
+
+func (e *ECS) WaitUntilTasksStopped(input *ecs.DescribeTasksInput) error {
+	return nil
+}
+
+func (e *ECS) ListTasksPages(input *ecs.ListTasksInput, fn func(*ecs.ListTasksOutput, bool) bool) error {
+	e.mu.Lock()
+	cluster := arnPrefix + "cluster/" + clusterName(input.Cluster)
+	output := &ecs.ListTasksOutput{}
+	for arn, task := range e.tasks {
+		if aws.StringValue(task.ClusterArn) == cluster {
+			output.TaskArns = append(output.TaskArns, aws.String(arn))
+		}
+	}
+	e.mu.Unlock()
+
+	fn(output, true)
+	return nil
+}
+
+func (e *ECS) ListContainerInstancesPages(input *ecs.ListContainerInstancesInput, fn func(*ecs.ListContainerInstancesOutput, bool) bool) error {
+	e.mu.Lock()
+	prefix := clusterName(input.Cluster) + "/"
+	output := &ecs.ListContainerInstancesOutput{}
+	for key, instance := range e.instances {
+		if strings.HasPrefix(key, prefix) {
+			output.ContainerInstanceArns = append(output.ContainerInstanceArns, instance.ContainerInstanceArn)
+		}
+	}
+	e.mu.Unlock()
+
+	fn(output, true)
+	return nil
+}
+
+func (e *ECS) DescribeContainerInstances(input *ecs.DescribeContainerInstancesInput) (*ecs.DescribeContainerInstancesOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cluster := clusterName(input.Cluster)
+	output := &ecs.DescribeContainerInstancesOutput{}
+	for _, arn := range input.ContainerInstances {
+		if instance, ok := e.instances[cluster+"/"+aws.StringValue(arn)]; ok {
+			output.ContainerInstances = append(output.ContainerInstances, instance)
+		} else {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: arn, Reason: aws.String("MISSING")})
+		}
+	}
+	return output, nil
+}
+
+// UpdateContainerAgent marks the agent of a container instance as updating, instances without
+// version info are treated like those on ECS optimized AMIs, which can't be updated in place
+func (e *ECS) UpdateContainerAgent(input *ecs.UpdateContainerAgentInput) (*ecs.UpdateContainerAgentOutput, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instance, ok := e.instances[clusterName(input.Cluster)+"/"+aws.StringValue(input.ContainerInstance)]
+	if !ok {
+		return nil, awserr.New("InvalidParameterException", "Container instance not found", nil)
+	} else if instance.VersionInfo == nil {
+		return nil, awserr.New("MissingVersionException", "Agent version info is missing", nil)
+	}
+	instance.AgentUpdateStatus = aws.String(ecs.AgentUpdateStatusPending)
+	return &ecs.UpdateContainerAgentOutput{ContainerInstance: instance}, nil
+}
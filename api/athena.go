@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type athenaInterface interface {
+	StartQueryExecution(query, database, outputLocation string) (string, error)
+	GetQueryExecution(id string) (QueryExecution, error)
+	GetQueryResults(id string) ([][]string, error)
+}
+
+// QueryExecution is the state of an athena query
+type QueryExecution struct {
+	ID     string
+	State  string
+	Reason string
+}
+
+type athenaClient struct {
+	*jsonClient
+}
+
+func newAthenaClient(p client.ConfigProvider) *athenaClient {
+	return &athenaClient{newJSONClient(p, "athena", "AmazonAthena", "1.1")}
+}
+
+func (c *athenaClient) StartQueryExecution(query, database, outputLocation string) (string, error) {
+	type queryContext struct {
+		Database string `json:"Database"`
+	}
+	type resultConfiguration struct {
+		OutputLocation string `json:"OutputLocation"`
+	}
+	input := struct {
+		QueryString           string              `json:"QueryString"`
+		QueryExecutionContext *queryContext       `json:"QueryExecutionContext,omitempty"`
+		ResultConfiguration   resultConfiguration `json:"ResultConfiguration"`
+	}{
+		QueryString:         query,
+		ResultConfiguration: resultConfiguration{outputLocation},
+	}
+	if database != "" {
+		input.QueryExecutionContext = &queryContext{database}
+	}
+
+	var resp struct {
+		QueryExecutionID string `json:"QueryExecutionId"`
+	}
+	err := c.Call("StartQueryExecution", &input, &resp)
+	return resp.QueryExecutionID, err
+}
+
+func (c *athenaClient) GetQueryExecution(id string) (QueryExecution, error) {
+	var resp struct {
+		QueryExecution struct {
+			Status struct {
+				State             string `json:"State"`
+				StateChangeReason string `json:"StateChangeReason"`
+			} `json:"Status"`
+		} `json:"QueryExecution"`
+	}
+	err := c.Call("GetQueryExecution", &struct {
+		QueryExecutionID string `json:"QueryExecutionId"`
+	}{id}, &resp)
+	return QueryExecution{
+		ID:     id,
+		State:  resp.QueryExecution.Status.State,
+		Reason: resp.QueryExecution.Status.StateChangeReason,
+	}, err
+}
+
+// GetQueryResults returns the rows of a query's results, the first row is the column names
+func (c *athenaClient) GetQueryResults(id string) ([][]string, error) {
+	rows := [][]string{}
+	var token string
+	for {
+		var resp struct {
+			ResultSet struct {
+				Rows []struct {
+					Data []struct {
+						VarCharValue string `json:"VarCharValue"`
+					} `json:"Data"`
+				} `json:"Rows"`
+			} `json:"ResultSet"`
+			NextToken string `json:"NextToken"`
+		}
+		err := c.Call("GetQueryResults", &struct {
+			QueryExecutionID string `json:"QueryExecutionId"`
+			NextToken        string `json:"NextToken,omitempty"`
+		}{id, token}, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range resp.ResultSet.Rows {
+			values := []string{}
+			for _, d := range row.Data {
+				values = append(values, d.VarCharValue)
+			}
+			rows = append(rows, values)
+		}
+		if token = resp.NextToken; token == "" {
+			return rows, nil
+		}
+	}
+}
+
+// RunQuery runs an athena query and waits for it to finish, returning the rows of its results
+func RunQuery(ctx context.Context, svc athenaInterface, query, database, outputLocation string) ([][]string, error) {
+	id, err := svc.StartQueryExecution(query, database, outputLocation)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		q, err := svc.GetQueryExecution(id)
+		if err != nil {
+			return nil, err
+		}
+		switch q.State {
+		case "SUCCEEDED":
+			return svc.GetQueryResults(id)
+		case "FAILED", "CANCELLED":
+			return nil, fmt.Errorf("Query %s %s: %s", id, q.State, q.Reason)
+		}
+		if err = Sleep(ctx, time.Second, "query "+id, "it was "+q.State); err != nil {
+			return nil, err
+		}
+	}
+}
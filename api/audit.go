@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// AuditTableName is the dynamodb table in each account and region that ecsy appends a record
+// of every command that changes something to, created by `bootstrap`
+const AuditTableName = "ecsy-audit"
+
+// auditAccountKey is the partition of records for commands that aren't run on a cluster
+const auditAccountKey = "-"
+
+// AuditRecord is who ran a command, when, with which arguments and what it created or updated
+type AuditRecord struct {
+	Cluster    string
+	RecordedAt time.Time
+	Caller     string
+	Owner      string
+	Command    string
+	Args       []string
+	Resources  []string
+	Error      string
+}
+
+func (r AuditRecord) item() (map[string]AttributeValue, error) {
+	args, err := json.Marshal(r.Args)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := json.Marshal(r.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := r.Cluster
+	if cluster == "" {
+		cluster = auditAccountKey
+	}
+	item := map[string]AttributeValue{
+		"Cluster":    {S: aws.String(cluster)},
+		"RecordedAt": {S: aws.String(r.RecordedAt.UTC().Format(time.RFC3339Nano))},
+		"Caller":     {S: aws.String(r.Caller)},
+		"Owner":      {S: aws.String(r.Owner)},
+		"Command":    {S: aws.String(r.Command)},
+		"Args":       {S: aws.String(string(args))},
+		"Resources":  {S: aws.String(string(resources))},
+	}
+	if r.Error != "" {
+		item["Error"] = AttributeValue{S: aws.String(r.Error)}
+	}
+	return item, nil
+}
+
+func auditRecordFromItem(item map[string]AttributeValue) AuditRecord {
+	str := func(key string) string {
+		return aws.StringValue(item[key].S)
+	}
+
+	r := AuditRecord{
+		Cluster: str("Cluster"),
+		Caller:  str("Caller"),
+		Owner:   str("Owner"),
+		Command: str("Command"),
+		Error:   str("Error"),
+	}
+	if r.Cluster == auditAccountKey {
+		r.Cluster = ""
+	}
+	r.RecordedAt, _ = time.Parse(time.RFC3339Nano, str("RecordedAt"))
+	json.Unmarshal([]byte(str("Args")), &r.Args)
+	json.Unmarshal([]byte(str("Resources")), &r.Resources)
+	return r
+}
+
+// WriteAuditRecord appends a record to the audit table, records are never overwritten
+func WriteAuditRecord(svc dynamodbInterface, r AuditRecord) error {
+	item, err := r.item()
+	if err != nil {
+		return err
+	}
+	err = svc.PutItem(&PutItemInput{
+		TableName:           AuditTableName,
+		Item:                item,
+		ConditionExpression: "attribute_not_exists(RecordedAt)",
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ResourceNotFoundException" {
+		return ErrTableNotFound
+	}
+	return err
+}
+
+// ListAuditRecords returns the records for a cluster since a time, newest first, or those
+// for commands that aren't run on a cluster if it's empty
+func ListAuditRecords(svc dynamodbInterface, cluster string, since time.Time, limit int) ([]AuditRecord, error) {
+	if cluster == "" {
+		cluster = auditAccountKey
+	}
+	items, err := svc.Query(&QueryInput{
+		TableName:              AuditTableName,
+		KeyConditionExpression: "Cluster = :cluster AND RecordedAt >= :since",
+		ExpressionAttributeValues: map[string]AttributeValue{
+			":cluster": {S: aws.String(cluster)},
+			":since":   {S: aws.String(since.UTC().Format(time.RFC3339Nano))},
+		},
+		Limit: int64(limit),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ResourceNotFoundException" {
+		return nil, ErrTableNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	records := []AuditRecord{}
+	for _, item := range items {
+		records = append(records, auditRecordFromItem(item))
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// CreateAuditTable creates the audit table, billed per request
+func CreateAuditTable(svc dynamodbInterface) error {
+	return svc.CreateTable(&CreateTableInput{
+		TableName: AuditTableName,
+		KeySchema: []KeySchemaElement{
+			{AttributeName: "Cluster", KeyType: "HASH"},
+			{AttributeName: "RecordedAt", KeyType: "RANGE"},
+		},
+		AttributeDefinitions: []AttributeDefinition{
+			{AttributeName: "Cluster", AttributeType: "S"},
+			{AttributeName: "RecordedAt", AttributeType: "S"},
+		},
+		BillingMode: "PAY_PER_REQUEST",
+	})
+}
+
+func (r AuditRecord) String() string {
+	s := fmt.Sprintf("%s  %s  ecsy %s", r.RecordedAt.Local().Format("2006-01-02 15:04:05"), r.Caller, r.Command)
+	if r.Error != "" {
+		s += "  failed: " + r.Error
+	}
+	return s
+}
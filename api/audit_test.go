@@ -0,0 +1,28 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditRecordRoundTrips(t *testing.T) {
+	r := AuditRecord{
+		RecordedAt: time.Date(2017, 3, 1, 10, 30, 0, 0, time.UTC),
+		Caller:     "arn:aws:iam::123456789012:user/lachlan",
+		Owner:      "lachlan@build-1",
+		Command:    "bootstrap",
+		Args:       []string{"bootstrap", "--env", "production"},
+		Resources:  []string{},
+	}
+	item, err := r.item()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster := *item["Cluster"].S; cluster != auditAccountKey {
+		t.Fatalf("Expected account records under %q, got %q", auditAccountKey, cluster)
+	}
+	if got := auditRecordFromItem(item); !reflect.DeepEqual(got, r) {
+		t.Fatalf("Expected %#v, got %#v", r, got)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type autoscalingInterface interface {
+	StartInstanceRefresh(group string, prefs InstanceRefreshPreferences) (string, error)
+	DescribeInstanceRefresh(group, id string) (InstanceRefresh, error)
+	TerminateInstance(instanceId string) error
+}
+
+// InstanceRefreshPreferences control how an instance refresh replaces instances
+type InstanceRefreshPreferences struct {
+	MinHealthyPercentage  int
+	InstanceWarmup        int
+	CheckpointPercentages []int
+	CheckpointDelay       int
+}
+
+// InstanceRefresh is the progress of an instance refresh of an autoscaling group
+type InstanceRefresh struct {
+	ID                 string `xml:"InstanceRefreshId"`
+	Status             string `xml:"Status"`
+	StatusReason       string `xml:"StatusReason"`
+	PercentageComplete int    `xml:"PercentageComplete"`
+	InstancesToUpdate  int    `xml:"InstancesToUpdate"`
+}
+
+// Instance refresh statuses that it doesn't leave
+const (
+	InstanceRefreshSuccessful = "Successful"
+	InstanceRefreshFailed     = "Failed"
+	InstanceRefreshCancelled  = "Cancelled"
+)
+
+type autoscalingClient struct {
+	*queryClient
+}
+
+func newAutoscalingClient(p client.ConfigProvider) *autoscalingClient {
+	return &autoscalingClient{newQueryClient(p, "autoscaling", "2011-01-01")}
+}
+
+func (c *autoscalingClient) StartInstanceRefresh(group string, prefs InstanceRefreshPreferences) (string, error) {
+	params := url.Values{
+		"AutoScalingGroupName":             {group},
+		"Strategy":                         {"Rolling"},
+		"Preferences.MinHealthyPercentage": {strconv.Itoa(prefs.MinHealthyPercentage)},
+	}
+	if prefs.InstanceWarmup > 0 {
+		params.Set("Preferences.InstanceWarmup", strconv.Itoa(prefs.InstanceWarmup))
+	}
+	for idx, p := range prefs.CheckpointPercentages {
+		params.Set(fmt.Sprintf("Preferences.CheckpointPercentages.member.%d", idx+1), strconv.Itoa(p))
+	}
+	if len(prefs.CheckpointPercentages) > 0 {
+		params.Set("Preferences.CheckpointDelay", strconv.Itoa(prefs.CheckpointDelay))
+	}
+
+	var resp struct {
+		ID string `xml:"StartInstanceRefreshResult>InstanceRefreshId"`
+	}
+	err := c.Call("StartInstanceRefresh", params, &resp)
+	return resp.ID, err
+}
+
+func (c *autoscalingClient) DescribeInstanceRefresh(group, id string) (InstanceRefresh, error) {
+	var resp struct {
+		Refreshes []InstanceRefresh `xml:"DescribeInstanceRefreshesResult>InstanceRefreshes>member"`
+	}
+	err := c.Call("DescribeInstanceRefreshes", url.Values{
+		"AutoScalingGroupName":        {group},
+		"InstanceRefreshIds.member.1": {id},
+	}, &resp)
+	if err != nil {
+		return InstanceRefresh{}, err
+	} else if len(resp.Refreshes) == 0 {
+		return InstanceRefresh{}, fmt.Errorf("No instance refresh %s for %s", id, group)
+	}
+	return resp.Refreshes[0], nil
+}
+
+// TerminateInstance terminates an instance of an autoscaling group, which launches another
+// to replace it
+func (c *autoscalingClient) TerminateInstance(instanceId string) error {
+	return c.Call("TerminateInstanceInAutoScalingGroup", url.Values{
+		"InstanceId":                     {instanceId},
+		"ShouldDecrementDesiredCapacity": {"false"},
+	}, nil)
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// The vendored aws-sdk-go only ships a handful of services, so the rest are called
+// via these minimal clients that reuse the sdk's signing, retries and error handling
+// but marshal requests with encoding/json and encoding/xml.
+
+type queryClient struct {
+	*client.Client
+}
+
+func newQueryClient(p client.ConfigProvider, serviceName, apiVersion string) *queryClient {
+	c := p.ClientConfig(serviceName)
+	qc := &queryClient{
+		Client: client.New(*c.Config, metadata.ClientInfo{
+			ServiceName:   serviceName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    apiVersion,
+		}, c.Handlers),
+	}
+
+	qc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	qc.Handlers.Build.PushBack(buildQuery)
+	qc.Handlers.Unmarshal.PushBack(unmarshalXML)
+	qc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	qc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+	return qc
+}
+
+// Call invokes an action with the provided query params, decoding the xml response into out
+func (c *queryClient) Call(action string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	req := c.NewRequest(&request.Operation{
+		Name:       action,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, &params, out)
+	return req.Send()
+}
+
+func buildQuery(r *request.Request) {
+	body := url.Values{
+		"Action":  {r.Operation.Name},
+		"Version": {r.ClientInfo.APIVersion},
+	}
+	for k, v := range *r.Params.(*url.Values) {
+		body[k] = v
+	}
+
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	r.SetBufferBody([]byte(body.Encode()))
+}
+
+func unmarshalXML(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	if r.Data == nil {
+		return
+	}
+	if err := xml.NewDecoder(r.HTTPResponse.Body).Decode(r.Data); err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding query response", err)
+	}
+}
+
+type jsonClient struct {
+	*client.Client
+}
+
+func newJSONClient(p client.ConfigProvider, serviceName, targetPrefix, jsonVersion string, cfgs ...*aws.Config) *jsonClient {
+	c := p.ClientConfig(serviceName, cfgs...)
+	jc := &jsonClient{
+		Client: client.New(*c.Config, metadata.ClientInfo{
+			ServiceName:   serviceName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			JSONVersion:   jsonVersion,
+			TargetPrefix:  targetPrefix,
+		}, c.Handlers),
+	}
+
+	jc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	jc.Handlers.Build.PushBack(buildJSON)
+	jc.Handlers.Unmarshal.PushBack(unmarshalJSON)
+	jc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	jc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+	return jc
+}
+
+// Call invokes an operation with a pointer to a json encodable input, decoding the response into out
+func (c *jsonClient) Call(operation string, in, out interface{}) error {
+	if in == nil {
+		in = &struct{}{}
+	}
+	req := c.NewRequest(&request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, in, out)
+	return req.Send()
+}
+
+func buildJSON(r *request.Request) {
+	b, err := json.Marshal(r.Params)
+	if err != nil {
+		r.Error = awserr.New("SerializationError", "failed encoding json request", err)
+		return
+	}
+
+	r.HTTPRequest.Header.Set("X-Amz-Target", r.ClientInfo.TargetPrefix+"."+r.Operation.Name)
+	r.HTTPRequest.Header.Set("Content-Type", "application/x-amz-json-"+r.ClientInfo.JSONVersion)
+	r.SetBufferBody(b)
+}
+
+func unmarshalJSON(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+	if r.Data == nil {
+		return
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.HTTPResponse.Body); err != nil {
+		r.Error = awserr.New("SerializationError", "failed reading json response", err)
+		return
+	}
+	if buf.Len() == 0 {
+		return
+	}
+	if err := json.Unmarshal(buf.Bytes(), r.Data); err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding json response", err)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+const backupVersion = 1
+
+// ClusterBackup captures everything needed to recreate a cluster and its services
+type ClusterBackup struct {
+	Version         int                   `json:"version"`
+	Cluster         string                `json:"cluster"`
+	Region          string                `json:"region"`
+	ExportedAt      time.Time             `json:"exported_at"`
+	Stacks          []StackBackup         `json:"stacks"`
+	TaskDefinitions []*ecs.TaskDefinition `json:"task_definitions"`
+}
+
+type StackBackup struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type,omitempty"`
+	Template   string            `json:"template"`
+	Parameters map[string]string `json:"parameters"`
+	Outputs    map[string]string `json:"outputs"`
+}
+
+// stack types in the order they need to be created in
+var stackTypeOrder = []string{
+	"",
+	"ecs-former::ecs-stack",
+	"ecs-former::ecs-service",
+}
+
+func stackTypeRank(t string) int {
+	for idx, st := range stackTypeOrder {
+		if st == t {
+			return idx
+		}
+	}
+	return len(stackTypeOrder)
+}
+
+// ExportCluster reads the templates and parameters of all of a cluster's stacks and the
+// task definitions its services use
+func ExportCluster(cfn CFNAPI, ecsSvc ECSAPI, cluster, region string) (*ClusterBackup, error) {
+	stacks, err := FindAllStacksForCluster(cfn, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &ClusterBackup{
+		Version:    backupVersion,
+		Cluster:    cluster,
+		Region:     region,
+		ExportedAt: time.Now(),
+	}
+
+	for _, stack := range stacks {
+		resp, err := cfn.GetTemplate(&cloudformation.GetTemplateInput{
+			StackName: stack.StackName,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sb := StackBackup{
+			Name:       *stack.StackName,
+			Template:   *resp.TemplateBody,
+			Parameters: map[string]string{},
+			Outputs:    StackOutputMap(stack),
+		}
+		sb.Type = sb.Outputs["StackType"]
+		for _, param := range stack.Parameters {
+			sb.Parameters[*param.ParameterKey] = aws.StringValue(param.ParameterValue)
+		}
+
+		if taskDef, ok := sb.Parameters["TaskDefinition"]; ok && taskDef != "" {
+			resp, err := ecsSvc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+				TaskDefinition: aws.String(taskDef),
+			})
+			if err != nil {
+				return nil, err
+			}
+			backup.TaskDefinitions = append(backup.TaskDefinitions, resp.TaskDefinition)
+		}
+
+		backup.Stacks = append(backup.Stacks, sb)
+	}
+
+	sort.SliceStable(backup.Stacks, func(i, j int) bool {
+		return stackTypeRank(backup.Stacks[i].Type) < stackTypeRank(backup.Stacks[j].Type)
+	})
+
+	return backup, nil
+}
+
+// TaskDefinition finds an exported task definition by arn
+func (b *ClusterBackup) TaskDefinition(arn string) (*ecs.TaskDefinition, error) {
+	for _, td := range b.TaskDefinitions {
+		if aws.StringValue(td.TaskDefinitionArn) == arn {
+			return td, nil
+		}
+	}
+	return nil, fmt.Errorf("No task definition %s in backup", arn)
+}
+
+// RegisterTaskDefinitionInput converts an existing task definition back into an input for
+// registering it again
+func RegisterTaskDefinitionInput(td *ecs.TaskDefinition) *ecs.RegisterTaskDefinitionInput {
+	return &ecs.RegisterTaskDefinitionInput{
+		Family:               td.Family,
+		ContainerDefinitions: td.ContainerDefinitions,
+		NetworkMode:          td.NetworkMode,
+		TaskRoleArn:          td.TaskRoleArn,
+		Volumes:              td.Volumes,
+	}
+}
+
+// Replacements maps values from one environment to their equivalents in another, for
+// instance the vpc id of the original network stack to the vpc id of its copy
+type Replacements map[string]string
+
+// AddOutputs records the outputs of a recreated stack as replacements for the originals
+func (r Replacements) AddOutputs(before, after map[string]string) {
+	for k, v := range before {
+		if nv, ok := after[k]; ok && v != "" && nv != v {
+			r[v] = nv
+		}
+	}
+}
+
+// Apply replaces a value if it was wholly a value from the original environment
+func (r Replacements) Apply(v string) string {
+	if nv, ok := r[v]; ok {
+		return nv
+	}
+	return v
+}
+
+// ApplyTaskDefinition rewrites a task definition's log configuration for a new region
+func (r Replacements) ApplyTaskDefinition(input *ecs.RegisterTaskDefinitionInput, region string) {
+	for _, def := range input.ContainerDefinitions {
+		if def.LogConfiguration == nil {
+			continue
+		}
+		for k, v := range def.LogConfiguration.Options {
+			if k == "awslogs-region" {
+				def.LogConfiguration.Options[k] = aws.String(region)
+			} else if v != nil {
+				def.LogConfiguration.Options[k] = aws.String(r.Apply(*v))
+			}
+		}
+	}
+}
+
+// RegionSpecific returns whether a value looks like an arn from a particular region
+func RegionSpecific(v, region string) bool {
+	return strings.HasPrefix(v, "arn:") && strings.Contains(v, ":"+region+":")
+}
+
+// TranslateECRImage rewrites an image in an ecr registry to the same repository in another region
+func TranslateECRImage(image, from, to string) (string, bool) {
+	registry := ".dkr.ecr." + from + ".amazonaws.com/"
+	if !strings.Contains(image, registry) {
+		return image, false
+	}
+	return strings.Replace(image, registry, ".dkr.ecr."+to+".amazonaws.com/", 1), true
+}
+
+// TemplateHasAMI returns whether a template that maps regions to AMIs has one for region,
+// templates without a mapping are assumed not to need one
+func TemplateHasAMI(template, region string) bool {
+	if !strings.Contains(template, "AWSRegionToAMI") {
+		return true
+	}
+	return regexp.MustCompile(`(?m)^\s+"?` + regexp.QuoteMeta(region) + `"?\s*:\s*\{\s*"?AMIID`).MatchString(template)
+}
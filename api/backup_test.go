@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestReplacementsTranslateTaskDefinitionLogs(t *testing.T) {
+	r := Replacements{}
+	r.AddOutputs(
+		map[string]string{"LogGroupName": "ecs-example-cluster", "ECSCluster": "example"},
+		map[string]string{"LogGroupName": "ecs-example-cluster-B", "ECSCluster": "example"},
+	)
+
+	input := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions: []*ecs.ContainerDefinition{{
+			LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: aws.String("awslogs"),
+				Options: map[string]*string{
+					"awslogs-group":  aws.String("ecs-example-cluster"),
+					"awslogs-region": aws.String("us-east-1"),
+				},
+			},
+		}},
+	}
+	r.ApplyTaskDefinition(input, "eu-west-1")
+
+	opts := input.ContainerDefinitions[0].LogConfiguration.Options
+	if *opts["awslogs-group"] != "ecs-example-cluster-B" || *opts["awslogs-region"] != "eu-west-1" {
+		t.Fatalf("Unexpected log options %v", opts)
+	}
+	if _, ok := r["example"]; ok {
+		t.Fatalf("Unchanged outputs shouldn't be replaced")
+	}
+}
+
+func TestTemplateHasAMI(t *testing.T) {
+	template := "Mappings:\n    AWSRegionToAMI:\n        us-east-1: { AMIID: ami-1924770e }\n"
+
+	if !TemplateHasAMI(template, "us-east-1") {
+		t.Fatalf("Expected us-east-1 to have an AMI")
+	}
+	if TemplateHasAMI(template, "sa-east-1") {
+		t.Fatalf("Expected sa-east-1 not to have an AMI")
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Placement is where a running task of a service is
+type Placement struct {
+	Service          string
+	TaskArn          string
+	InstanceID       string
+	AvailabilityZone string
+}
+
+// ServicePlacements returns where the running tasks of a cluster's services are, and the
+// availability zones the cluster has instances in
+func ServicePlacements(svc ECSAPI, cluster string) ([]Placement, []string, error) {
+	instances, err := ListContainerInstances(svc, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	type location struct{ id, zone string }
+	locations := map[string]location{}
+	zones := []string{}
+	for _, ci := range instances {
+		l := location{id: aws.StringValue(ci.Ec2InstanceId)}
+		for _, a := range ci.Attributes {
+			if aws.StringValue(a.Name) == "ecs.availability-zone" {
+				l.zone = aws.StringValue(a.Value)
+			}
+		}
+		locations[aws.StringValue(ci.ContainerInstanceArn)] = l
+		if aws.StringValue(ci.Status) == ContainerInstanceActive && l.zone != "" && !containsName(zones, l.zone) {
+			zones = append(zones, l.zone)
+		}
+	}
+	sort.Strings(zones)
+
+	services, err := ListServices(svc, cluster)
+	if err != nil {
+		return nil, nil, err
+	}
+	placements := []Placement{}
+	for _, service := range services {
+		name := aws.StringValue(service.ServiceName)
+		tasks, err := ListServiceTasks(svc, cluster, name, ecs.DesiredStatusRunning)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range tasks {
+			l := locations[aws.StringValue(t.ContainerInstanceArn)]
+			placements = append(placements, Placement{
+				Service:          name,
+				TaskArn:          aws.StringValue(t.TaskArn),
+				InstanceID:       l.id,
+				AvailabilityZone: l.zone,
+			})
+		}
+	}
+	return placements, zones, nil
+}
+
+// ZoneCounts returns the number of tasks in each zone, including zones without any
+func ZoneCounts(placements []Placement, zones []string) map[string]int {
+	counts := map[string]int{}
+	for _, z := range zones {
+		counts[z] = 0
+	}
+	for _, p := range placements {
+		counts[p.AvailabilityZone]++
+	}
+	return counts
+}
+
+// Imbalance is the difference between the zones with the most and fewest tasks
+func Imbalance(counts map[string]int) int {
+	if len(counts) == 0 {
+		return 0
+	}
+	min, max := -1, 0
+	for _, n := range counts {
+		if min == -1 || n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return max - min
+}
+
+// TasksToMove returns the tasks of a service to stop so that its scheduler, spreading tasks
+// across zones, starts their replacements in the zones with the fewest. Tasks are taken from
+// the busiest instance of the busiest zone first.
+func TasksToMove(placements []Placement, zones []string) []Placement {
+	counts := ZoneCounts(placements, zones)
+	remaining := append([]Placement{}, placements...)
+
+	moves := []Placement{}
+	for Imbalance(counts) > 1 {
+		busiest, quietest := "", ""
+		for _, z := range sortedZones(counts) {
+			if busiest == "" || counts[z] > counts[busiest] {
+				busiest = z
+			}
+			if quietest == "" || counts[z] < counts[quietest] {
+				quietest = z
+			}
+		}
+
+		perInstance := map[string]int{}
+		for _, p := range remaining {
+			if p.AvailabilityZone == busiest {
+				perInstance[p.InstanceID]++
+			}
+		}
+		pick := -1
+		for idx, p := range remaining {
+			if p.AvailabilityZone == busiest && (pick == -1 || perInstance[p.InstanceID] > perInstance[remaining[pick].InstanceID]) {
+				pick = idx
+			}
+		}
+		moves = append(moves, remaining[pick])
+		remaining = append(remaining[:pick], remaining[pick+1:]...)
+		counts[busiest]--
+		counts[quietest]++
+	}
+	return moves
+}
+
+func sortedZones(counts map[string]int) []string {
+	zones := []string{}
+	for z := range counts {
+		zones = append(zones, z)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// StopTask stops a task, giving the reason it was stopped
+func StopTask(svc ECSAPI, cluster, taskArn, reason string) error {
+	_, err := svc.StopTask(&ecs.StopTaskInput{
+		Cluster: aws.String(cluster),
+		Task:    aws.String(taskArn),
+		Reason:  aws.String(reason),
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+package api
+
+import "testing"
+
+func TestTasksToMoveEvensOutZones(t *testing.T) {
+	zones := []string{"us-east-1a", "us-east-1b", "us-east-1c"}
+	placements := []Placement{
+		{TaskArn: "1", InstanceID: "i-1", AvailabilityZone: "us-east-1a"},
+		{TaskArn: "2", InstanceID: "i-1", AvailabilityZone: "us-east-1a"},
+		{TaskArn: "3", InstanceID: "i-2", AvailabilityZone: "us-east-1a"},
+		{TaskArn: "4", InstanceID: "i-1", AvailabilityZone: "us-east-1a"},
+		{TaskArn: "5", InstanceID: "i-3", AvailabilityZone: "us-east-1b"},
+	}
+
+	moves := TasksToMove(placements, zones)
+	if len(moves) != 2 {
+		t.Fatalf("Expected 2 tasks to move, got %v", moves)
+	}
+	for _, m := range moves {
+		if m.InstanceID != "i-1" {
+			t.Errorf("Expected tasks to move from the busiest instance, got %s", m.InstanceID)
+		}
+	}
+	if n := len(TasksToMove(placements[:3], zones[:2])); n != 1 {
+		t.Errorf("Expected 1 task to move, got %d", n)
+	}
+	if n := len(TasksToMove(placements[3:], zones[:2])); n != 0 {
+		t.Errorf("Expected balanced zones to need no moves, got %d", n)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/yaml.v2"
+)
+
+// ChangeSet is a preview of an update to a stack, which can be executed or deleted
+type ChangeSet struct {
+	Name      string
+	StackName string
+	Changes   []*cloudformation.ResourceChange
+}
+
+// PreviewStackUpdate creates a change set that updates a stack to a new template, keeping
+// the previous values of parameters unless they are overridden. A nil change set means
+// the update wouldn't change anything.
+func PreviewStackUpdate(ctx context.Context, svc CFNAPI, stack *cloudformation.Stack, body string, overrides map[string]string) (*ChangeSet, error) {
+	if err := LintTemplate(body); err != nil {
+		return nil, err
+	}
+
+	var tpl struct {
+		Parameters map[string]interface{} `yaml:"Parameters"`
+	}
+	if err := yaml.Unmarshal([]byte(body), &tpl); err != nil {
+		return nil, err
+	}
+
+	previous := map[string]bool{}
+	for _, p := range stack.Parameters {
+		previous[aws.StringValue(p.ParameterKey)] = true
+	}
+
+	params := []*cloudformation.Parameter{}
+	for key := range tpl.Parameters {
+		if v, ok := overrides[key]; ok {
+			params = append(params, &cloudformation.Parameter{ParameterKey: aws.String(key), ParameterValue: aws.String(v)})
+		} else if previous[key] {
+			params = append(params, &cloudformation.Parameter{ParameterKey: aws.String(key), UsePreviousValue: aws.Bool(true)})
+		}
+	}
+
+	cs := &ChangeSet{
+		Name:      fmt.Sprintf("ecsy-%d", time.Now().Unix()),
+		StackName: aws.StringValue(stack.StackName),
+	}
+	_, err := svc.CreateChangeSet(&cloudformation.CreateChangeSetInput{
+		StackName:     stack.StackName,
+		ChangeSetName: aws.String(cs.Name),
+		Capabilities:  stackCapabilities(),
+		Parameters:    params,
+		Tags:          stackTags(body, overrides, StackTags(stack)),
+		TemplateBody:  aws.String(body),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := svc.DescribeChangeSet(&cloudformation.DescribeChangeSetInput{
+			StackName:     stack.StackName,
+			ChangeSetName: aws.String(cs.Name),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch aws.StringValue(resp.Status) {
+		case cloudformation.ChangeSetStatusCreateComplete:
+			for _, change := range resp.Changes {
+				cs.Changes = append(cs.Changes, change.ResourceChange)
+			}
+			return cs, nil
+		case cloudformation.ChangeSetStatusFailed:
+			reason := aws.StringValue(resp.StatusReason)
+			if err := DeleteChangeSet(svc, cs); err != nil {
+				return nil, err
+			}
+			if strings.Contains(reason, "didn't contain changes") || strings.Contains(reason, ErrNoStackUpdates.Error()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("Failed to create change set for %s: %s", cs.StackName, reason)
+		}
+		if err = Sleep(ctx, 2*time.Second, "change set "+cs.Name, "it was "+aws.StringValue(resp.Status)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ExecuteChangeSet starts updating a stack with a change set
+func ExecuteChangeSet(svc CFNAPI, cs *ChangeSet) error {
+	_, err := svc.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
+		StackName:     aws.String(cs.StackName),
+		ChangeSetName: aws.String(cs.Name),
+	})
+	return err
+}
+
+// DeleteChangeSet removes a change set that won't be executed
+func DeleteChangeSet(svc CFNAPI, cs *ChangeSet) error {
+	_, err := svc.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		StackName:     aws.String(cs.StackName),
+		ChangeSetName: aws.String(cs.Name),
+	})
+	return err
+}
+
+// FormatResourceChange describes a change to a resource in a change set
+func FormatResourceChange(c *cloudformation.ResourceChange) string {
+	s := fmt.Sprintf("%-7s %s (%s)", aws.StringValue(c.Action), aws.StringValue(c.LogicalResourceId), aws.StringValue(c.ResourceType))
+	if r := aws.StringValue(c.Replacement); r == "True" || r == "Conditional" {
+		s += " replacement: " + strings.ToLower(r)
+	}
+	return s
+}
@@ -1,22 +1,38 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/fatih/color"
+	"github.com/lox/ecsy/failure"
+	"github.com/lox/ecsy/templates"
 )
 
-type cfnInterface interface {
+// CFNAPI is the subset of the cloudformation api that ecsy uses, which the fakes in
+// api/apitest implement for tests
+type CFNAPI interface {
 	DescribeStacksPages(*cloudformation.DescribeStacksInput, func(*cloudformation.DescribeStacksOutput, bool) bool) error
 	DescribeStackEventsPages(*cloudformation.DescribeStackEventsInput, func(*cloudformation.DescribeStackEventsOutput, bool) bool) error
 	DescribeStacks(*cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
 	CreateStack(*cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error)
+	UpdateStack(*cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error)
 	DeleteStack(*cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error)
+	CancelUpdateStack(*cloudformation.CancelUpdateStackInput) (*cloudformation.CancelUpdateStackOutput, error)
+	GetTemplate(*cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error)
+	DescribeStackResources(*cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error)
+	CreateChangeSet(*cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error)
+	DescribeChangeSet(*cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error)
+	ExecuteChangeSet(*cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error)
+	DeleteChangeSet(*cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error)
+	SetStackPolicy(*cloudformation.SetStackPolicyInput) (*cloudformation.SetStackPolicyOutput, error)
 }
 
 type stackOutputMap map[string]string
@@ -56,10 +72,25 @@ func GetStackOutputByKey(stack *cloudformation.Stack, key string) (string, bool)
 	return "", false
 }
 
+func GetStackParameterByKey(stack *cloudformation.Stack, key string) (string, bool) {
+	for _, param := range stack.Parameters {
+		if *param.ParameterKey == key {
+			return *param.ParameterValue, true
+		}
+	}
+	return "", false
+}
+
 var ErrNoStacksFound = errors.New("No matching stacks found")
 
-func FindStacksByOutputs(svc cfnInterface, match map[string]string) ([]*cloudformation.Stack, error) {
-	stacks, err := findAllActiveStacks(svc)
+func FindStacksByOutputs(svc CFNAPI, match map[string]string) ([]*cloudformation.Stack, error) {
+	var stacks []*cloudformation.Stack
+	var err error
+	if index := indexedStacks(svc); index != nil && match["ECSCluster"] != "" {
+		stacks, err = index.clusterStacks(match["ECSCluster"])
+	} else {
+		stacks, err = findAllActiveStacks(svc)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -73,19 +104,21 @@ func FindStacksByOutputs(svc cfnInterface, match map[string]string) ([]*cloudfor
 	return filteredStacks, nil
 }
 
-func findAllActiveStacks(svc cfnInterface) (stacks []*cloudformation.Stack, err error) {
+func findAllActiveStacks(svc CFNAPI) (stacks []*cloudformation.Stack, err error) {
 	err = svc.DescribeStacksPages(nil, func(page *cloudformation.DescribeStacksOutput, last bool) bool {
 		for _, s := range page.Stacks {
 			if *s.StackStatus != "DELETE_COMPLETE" {
 				stacks = append(stacks, s)
 			}
 		}
-		return last
+		return !last
 	})
 	return
 }
 
-func FindStacksByName(svc cfnInterface, stackName string) (stacks []*cloudformation.Stack, err error) {
+// FindStacksByName returns the stack with a name or id, or none if it doesn't exist. Other
+// errors, like throttling or missing permissions, are returned.
+func FindStacksByName(svc CFNAPI, stackName string) (stacks []*cloudformation.Stack, err error) {
 	filter := &cloudformation.DescribeStacksInput{
 		StackName: &stackName,
 	}
@@ -96,19 +129,114 @@ func FindStacksByName(svc cfnInterface, stackName string) (stacks []*cloudformat
 				stacks = append(stacks, s)
 			}
 		}
-		return last
+		return !last
 	})
+	if isStackNotFound(err) {
+		return nil, nil
+	}
 	return
 }
 
-type CreateStackContext struct {
+// isStackNotFound returns whether describing a stack failed because it doesn't exist
+func isStackNotFound(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == "ValidationError" && strings.HasSuffix(awsErr.Message(), "does not exist")
+}
+
+// StackOptions are how a stack is created or updated
+type StackOptions struct {
 	Params          map[string]string
 	DisableRollback bool
+	// Attach makes creating or updating a stack that already has an operation in progress
+	// a no-op, so that a command that was interrupted can be rerun and poll it again
+	Attach bool
+	// AllowReplacement lets an update replace or delete the resources that the stack policy
+	// protects, see ProtectedResourceTypes
+	AllowReplacement bool
+}
+
+// CreateStackContext is the previous name of StackOptions.
+//
+// Deprecated: use StackOptions.
+type CreateStackContext = StackOptions
+
+// attachableStackStatuses are the statuses of stacks with an operation in progress that
+// PollUntilCreated follows to completion
+var attachableStackStatuses = map[string]bool{
+	cloudformation.StackStatusCreateInProgress:                        true,
+	cloudformation.StackStatusRollbackInProgress:                      true,
+	cloudformation.StackStatusUpdateInProgress:                        true,
+	cloudformation.StackStatusUpdateCompleteCleanupInProgress:         true,
+	cloudformation.StackStatusUpdateRollbackInProgress:                true,
+	cloudformation.StackStatusUpdateRollbackCompleteCleanupInProgress: true,
+}
+
+// IsStackInProgress returns whether a stack has a create or update in progress that can
+// be attached to
+func IsStackInProgress(stack *cloudformation.Stack) bool {
+	return stack != nil && attachableStackStatuses[aws.StringValue(stack.StackStatus)]
+}
+
+// attachToStack returns whether a stack that failed to be created or updated has an
+// operation in progress to attach to instead. The operation in progress is left to finish
+// as it is, so attaching warns if it isn't applying the same template and parameters.
+func attachToStack(svc CFNAPI, name string, body string, opts StackOptions, err error) bool {
+	if !opts.Attach || err == nil {
+		return false
+	}
+	stacks, findErr := FindStacksByName(svc, name)
+	if findErr != nil || len(stacks) == 0 || !IsStackInProgress(stacks[0]) {
+		return false
+	}
+	deployed, templateErr := DeployedTemplate(svc, name)
+	if templateErr != nil || deployed != body || !sameStackParams(stacks[0], opts.Params) {
+		log.Printf("Warning: attached to the %s already in progress on %s, which may not have the changes being applied. Rerun once it finishes to apply them",
+			aws.StringValue(stacks[0].StackStatus), name)
+	}
+	return true
 }
 
-func CreateStack(svc cfnInterface, name string, body string, ctx CreateStackContext) error {
+// sameStackParams returns whether a stack has the values of params. Values that can't be
+// compared, such as NoEcho parameters and parameter store references, are assumed to match.
+func sameStackParams(stack *cloudformation.Stack, params map[string]string) bool {
+	values := map[string]string{}
+	for _, p := range stack.Parameters {
+		values[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+	}
+	for k, v := range params {
+		if values[k] != v && values[k] != "****" && !IsParameterReference(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// LintTemplate checks a template before it's deployed, failing on errors that cloudformation
+// would reject it for and logging warnings
+func LintTemplate(body string) error {
+	issues := templates.Lint(body)
+	for _, issue := range issues {
+		if issue.Level == templates.LintWarning {
+			log.Printf("Template warning %s: %s", issue.Rule, issue.Message)
+		}
+	}
+	if errs := templates.LintErrors(issues); len(errs) > 0 {
+		messages := []string{}
+		for _, issue := range errs {
+			messages = append(messages, fmt.Sprintf("%s %s", issue.Rule, issue.Message))
+		}
+		return failure.Errorf(failure.Validation, "Template has %d errors: %s", len(errs), strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+func CreateStack(svc CFNAPI, name string, body string, opts StackOptions) error {
+	if err := LintTemplate(body); err != nil {
+		return err
+	}
+
 	paramsSlice := []*cloudformation.Parameter{}
-	for k, v := range ctx.Params {
+	for k, v := range opts.Params {
 		paramsSlice = append(paramsSlice, &cloudformation.Parameter{
 			ParameterKey:   aws.String(k),
 			ParameterValue: aws.String(v),
@@ -116,21 +244,131 @@ func CreateStack(svc cfnInterface, name string, body string, ctx CreateStackCont
 	}
 
 	_, err := svc.CreateStack(&cloudformation.CreateStackInput{
-		StackName: aws.String(name),
-		Capabilities: []*string{
-			aws.String("CAPABILITY_IAM"),
-		},
-		DisableRollback: aws.Bool(ctx.DisableRollback),
+		StackName:       aws.String(name),
+		Capabilities:    stackCapabilities(),
+		DisableRollback: aws.Bool(opts.DisableRollback),
 		Parameters:      paramsSlice,
+		StackPolicyBody: aws.String(StackPolicy(body)),
+		Tags:            stackTags(body, opts.Params, nil),
 		TemplateBody:    aws.String(body),
 	})
+	if attachToStack(svc, name, body, opts, err) {
+		return nil
+	}
+	return err
+}
+
+// stackCapabilities acknowledge that a template creates IAM resources, including ones that
+// it names, like a role with a RoleName, which cloudformation otherwise refuses
+func stackCapabilities() []*string {
+	return aws.StringSlice([]string{"CAPABILITY_IAM", "CAPABILITY_NAMED_IAM"})
+}
+
+var ErrNoStackUpdates = errors.New("No updates are to be performed")
+
+// ApplyStack creates a stack, or updates it if it already exists, calling f with each of
+// its events until the create or update completes or ctx ends. A stack that is already up
+// to date isn't an error.
+func ApplyStack(ctx context.Context, svc CFNAPI, name string, body string, opts StackOptions, f func(e *cloudformation.StackEvent)) error {
+	existing, err := FindStacksByName(svc, name)
 	if err != nil {
 		return err
 	}
-	return nil
+	if len(existing) == 0 {
+		err = CreateStack(svc, name, body, opts)
+	} else {
+		err = UpdateStack(svc, name, body, opts)
+	}
+	if err == ErrNoStackUpdates {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return PollUntilCreated(ctx, svc, name, f)
+}
+
+func UpdateStack(svc CFNAPI, name string, body string, opts StackOptions) error {
+	if err := LintTemplate(body); err != nil {
+		return err
+	}
+
+	paramsSlice := []*cloudformation.Parameter{}
+	for k, v := range opts.Params {
+		paramsSlice = append(paramsSlice, &cloudformation.Parameter{
+			ParameterKey:   aws.String(k),
+			ParameterValue: aws.String(v),
+		})
+	}
+
+	existing, err := existingStackTags(svc, name)
+	if err != nil {
+		return err
+	}
+
+	input := &cloudformation.UpdateStackInput{
+		StackName:       aws.String(name),
+		Capabilities:    stackCapabilities(),
+		Parameters:      paramsSlice,
+		StackPolicyBody: aws.String(StackPolicy(body)),
+		Tags:            stackTags(body, opts.Params, existing),
+		TemplateBody:    aws.String(body),
+	}
+	if opts.AllowReplacement {
+		input.StackPolicyDuringUpdateBody = aws.String(allowAllStackPolicy)
+	}
+
+	_, err = svc.UpdateStack(input)
+	if err != nil && strings.Contains(err.Error(), ErrNoStackUpdates.Error()) {
+		return ErrNoStackUpdates
+	} else if attachToStack(svc, name, body, opts, err) {
+		return nil
+	}
+	return err
+}
+
+// UpdateStackParameters updates some of the parameters of a stack, keeping its deployed
+// template and the previous values of its other parameters
+func UpdateStackParameters(svc CFNAPI, stack *cloudformation.Stack, params map[string]string) error {
+	body, err := DeployedTemplate(svc, *stack.StackName)
+	if err != nil {
+		return err
+	}
+
+	existing, err := existingStackTags(svc, *stack.StackName)
+	if err != nil {
+		return err
+	}
+
+	paramsSlice := []*cloudformation.Parameter{}
+	for _, param := range stack.Parameters {
+		if _, ok := params[*param.ParameterKey]; !ok {
+			paramsSlice = append(paramsSlice, &cloudformation.Parameter{
+				ParameterKey:     param.ParameterKey,
+				UsePreviousValue: aws.Bool(true),
+			})
+		}
+	}
+	for k, v := range params {
+		paramsSlice = append(paramsSlice, &cloudformation.Parameter{
+			ParameterKey:   aws.String(k),
+			ParameterValue: aws.String(v),
+		})
+	}
+
+	_, err = svc.UpdateStack(&cloudformation.UpdateStackInput{
+		StackName:           stack.StackName,
+		Capabilities:        stackCapabilities(),
+		Parameters:          paramsSlice,
+		Tags:                stackTags(body, params, existing),
+		UsePreviousTemplate: aws.Bool(true),
+	})
+	if err != nil && strings.Contains(err.Error(), ErrNoStackUpdates.Error()) {
+		return ErrNoStackUpdates
+	}
+	return err
 }
 
-func DeleteStack(svc cfnInterface, name string) error {
+func DeleteStack(svc CFNAPI, name string) error {
 	_, err := svc.DeleteStack(&cloudformation.DeleteStackInput{
 		StackName: &name,
 	})
@@ -138,7 +376,15 @@ func DeleteStack(svc cfnInterface, name string) error {
 	return err
 }
 
-func StackOutputs(svc cfnInterface, name string) (stackOutputMap, error) {
+// CancelUpdateStack cancels an update that is in progress, which rolls the stack back
+func CancelUpdateStack(svc CFNAPI, name string) error {
+	_, err := svc.CancelUpdateStack(&cloudformation.CancelUpdateStackInput{
+		StackName: aws.String(name),
+	})
+	return err
+}
+
+func StackOutputs(svc CFNAPI, name string) (stackOutputMap, error) {
 	resp, err := svc.DescribeStacks(&cloudformation.DescribeStacksInput{
 		StackName: aws.String(name),
 	})
@@ -155,31 +401,65 @@ func StackOutputs(svc cfnInterface, name string) (stackOutputMap, error) {
 	return outputs, nil
 }
 
-func PollUntilCreated(svc cfnInterface, stackName string, f func(e *cloudformation.StackEvent)) error {
-	return PollStackEventsUntil(svc, stackName, isCreateUpdateComplete, f)
+// StackResources returns the resources in a stack
+func StackResources(svc CFNAPI, name string) ([]*cloudformation.StackResource, error) {
+	resp, err := svc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.StackResources, nil
 }
 
-func PollUntilDeleted(svc cfnInterface, stackName string, f func(e *cloudformation.StackEvent)) error {
-	return PollStackEventsUntil(svc, stackName, isDeleteComplete, f)
+func PollUntilCreated(ctx context.Context, svc CFNAPI, stackName string, f func(e *cloudformation.StackEvent)) error {
+	return PollStackEventsUntil(ctx, svc, stackName, isCreateUpdateComplete, f)
 }
 
-func PollStackEventsUntil(svc cfnInterface, stackName string, terminalCondition EventChecker, f func(e *cloudformation.StackEvent)) error {
-	lastSeen := time.Time{}
+func PollUntilDeleted(ctx context.Context, svc CFNAPI, stackName string, f func(e *cloudformation.StackEvent)) error {
+	return PollStackEventsUntil(ctx, svc, stackName, isDeleteComplete, f)
+}
+
+// Stack events are polled every stackPollInterval whilst they're changing, backing off to
+// stackPollMaxInterval whilst they aren't
+var (
+	stackPollInterval    = 1 * time.Second
+	stackPollMaxInterval = 10 * time.Second
+)
+
+// maxPollErrors is how many polls in a row can fail with throttling or transient errors
+// before polling gives up
+const maxPollErrors = 5
+
+// PollStackEventsUntil calls f with each new event of a stack until the terminal condition
+// is met, or the context ends. Events are tracked by id, so each is only fetched and passed
+// to f once, even when a poll is retried after a transient error. Retries wait for the poll
+// interval, a Retry-After from the api isn't honoured.
+func PollStackEventsUntil(ctx context.Context, svc CFNAPI, stackName string, terminalCondition EventChecker, f func(e *cloudformation.StackEvent)) error {
+	seen := map[string]bool{}
+	interval := stackPollInterval
+	var lastEvent string
+	var failures int
 
 	for {
-		events, err := allStackEvents(svc, stackName, lastSeen)
+		events, err := newStackEvents(svc, stackName, seen)
 		if err != nil {
-			return err
+			if failures++; failures > maxPollErrors || !isTransientError(err) {
+				return err
+			}
+			log.Printf("Failed to poll stack %s, retrying: %v", stackName, err)
+		} else {
+			failures = 0
 		}
 
 		for i := len(events) - 1; i >= 0; i-- {
-			if events[i].Timestamp.After(lastSeen) {
-				f(events[i])
-				lastSeen = *events[i].Timestamp
-			}
+			seen[stackEventKey(events[i])] = true
+			f(events[i])
 		}
 
 		if len(events) > 0 {
+			interval = stackPollInterval
+			lastEvent = fmt.Sprintf("its last event was %s %s", aws.StringValue(events[0].LogicalResourceId), aws.StringValue(events[0].ResourceStatus))
 			t, err := terminalCondition(stackName, events[0])
 			if err != nil {
 				return err
@@ -187,27 +467,100 @@ func PollStackEventsUntil(svc cfnInterface, stackName string, terminalCondition
 			if t {
 				break
 			}
+		} else if interval *= 2; interval > stackPollMaxInterval {
+			interval = stackPollMaxInterval
 		}
 
-		time.Sleep(1 * time.Second)
+		if err := Sleep(ctx, interval, "stack "+stackName, lastEvent); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-func allStackEvents(svc cfnInterface, stackName string, after time.Time) (events []*cloudformation.StackEvent, err error) {
+// operationStartStatuses are the statuses of the stack event that starts an operation
+var operationStartStatuses = map[string]bool{
+	cloudformation.StackStatusCreateInProgress: true,
+	cloudformation.StackStatusUpdateInProgress: true,
+	cloudformation.StackStatusDeleteInProgress: true,
+	"IMPORT_IN_PROGRESS":                       true,
+}
+
+// isOperationStart returns whether an event is the stack starting an operation, rather than
+// one of its resources or nested stacks
+func isOperationStart(event *cloudformation.StackEvent) bool {
+	return aws.StringValue(event.ResourceType) == "AWS::CloudFormation::Stack" &&
+		aws.StringValue(event.LogicalResourceId) == aws.StringValue(event.StackName) &&
+		operationStartStatuses[aws.StringValue(event.ResourceStatus)]
+}
+
+// newStackEvents returns the events of a stack that haven't been seen, newest first. Paging
+// stops at the first event that has been seen, as new events are only added in front, and
+// before anything has been seen it stops at the event that started the latest operation
+// rather than paging through the stack's whole history.
+func newStackEvents(svc CFNAPI, stackName string, seen map[string]bool) (events []*cloudformation.StackEvent, err error) {
 	params := &cloudformation.DescribeStackEventsInput{
 		StackName: aws.String(stackName),
 	}
 
+	first := len(seen) == 0
 	err = svc.DescribeStackEventsPages(params, func(page *cloudformation.DescribeStackEventsOutput, last bool) bool {
 		for _, event := range page.StackEvents {
+			if seen[stackEventKey(event)] {
+				return false
+			}
+			events = append(events, event)
+			if first && isOperationStart(event) {
+				return false
+			}
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// stackEventKey identifies an event by its id, or by what happened when it has none
+func stackEventKey(event *cloudformation.StackEvent) string {
+	if event.EventId != nil {
+		return *event.EventId
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", aws.StringValue(event.LogicalResourceId), aws.StringValue(event.ResourceStatus),
+		aws.StringValue(event.ResourceStatusReason), aws.TimeValue(event.Timestamp).Format(time.RFC3339Nano))
+}
+
+// isTransientError returns whether an api call failed in a way that's likely to succeed if
+// it's retried, like throttling or a network error
+func isTransientError(err error) bool {
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() >= 500 {
+		return true
+	}
+	if awsErr, ok := err.(awserr.Error); ok {
+		switch awsErr.Code() {
+		case "Throttling", "ThrottlingException", "RequestLimitExceeded", "RequestError", "RequestTimeout":
+			return true
+		}
+	}
+	return false
+}
+
+func allStackEvents(svc CFNAPI, stackName string, after time.Time) (events []*cloudformation.StackEvent, err error) {
+	params := &cloudformation.DescribeStackEventsInput{
+		StackName: aws.String(stackName),
+	}
+
+	err = svc.DescribeStackEventsPages(params, func(page *cloudformation.DescribeStackEventsOutput, last bool) bool {
+		for _, event := range page.StackEvents {
+			// events are newest first, so the rest have been seen already
 			if !event.Timestamp.After(after) {
-				return true
+				return false
 			}
 			events = append(events, event)
 		}
-		return last
+		return !last
 	})
 
 	return
@@ -219,18 +572,22 @@ func isCreateUpdateComplete(stackName string, ev *cloudformation.StackEvent) (bo
 	if *ev.LogicalResourceId == stackName {
 		switch *ev.ResourceStatus {
 		case cloudformation.ResourceStatusUpdateComplete,
-			cloudformation.ResourceStatusCreateComplete,
-			cloudformation.ResourceStatusUpdateFailed,
+			cloudformation.ResourceStatusCreateComplete:
+			var err error
+			if ev.ResourceStatusReason != nil {
+				err = errors.New(*ev.ResourceStatusReason)
+			}
+			return true, err
+		case cloudformation.ResourceStatusUpdateFailed,
 			cloudformation.ResourceStatusCreateFailed,
 			cloudformation.StackStatusRollbackComplete,
 			cloudformation.StackStatusRollbackFailed,
 			cloudformation.StackStatusUpdateRollbackComplete,
 			cloudformation.StackStatusUpdateRollbackFailed:
-			var err error
 			if ev.ResourceStatusReason != nil {
-				err = errors.New(*ev.ResourceStatusReason)
+				return true, failure.Errorf(failure.StackRollback, "%s", *ev.ResourceStatusReason)
 			}
-			return true, err
+			return true, failure.Errorf(failure.StackRollback, "Stack %s is %s", stackName, *ev.ResourceStatus)
 		}
 	}
 	return false, nil
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+// lookupRetryInterval is how long a throttled lookup waits before it's retried, doubling
+// each time, as cloudtrail only allows a couple of lookups a second in each account
+var lookupRetryInterval = time.Second
+
+type cloudtrailInterface interface {
+	LookupEvents(source string, start, end time.Time) ([]TrailEvent, error)
+}
+
+// TrailEvent is an API call recorded by cloudtrail
+type TrailEvent struct {
+	Name      string
+	Time      time.Time
+	Principal string
+	ReadOnly  bool
+
+	// RequestParameters are the parameters the API was called with
+	RequestParameters map[string]interface{}
+}
+
+// Parameter returns a string parameter of the call, with arns shortened to the name they end with
+func (e TrailEvent) Parameter(key string) string {
+	s, _ := e.RequestParameters[key].(string)
+	if strings.HasPrefix(s, "arn:") {
+		parts := strings.Split(s, "/")
+		if len(parts) > 2 && strings.HasSuffix(parts[0], ":stack") {
+			return parts[1]
+		}
+		return parts[len(parts)-1]
+	}
+	return s
+}
+
+type cloudtrailClient struct {
+	*jsonClient
+}
+
+func newCloudtrailClient(p client.ConfigProvider) *cloudtrailClient {
+	return &cloudtrailClient{newJSONClient(p, "cloudtrail", "com.amazonaws.cloudtrail.v20131101.CloudTrail_20131101", "1.1")}
+}
+
+// LookupEvents returns the calls to the APIs of a service, like ecs.amazonaws.com, between two times
+func (c *cloudtrailClient) LookupEvents(source string, start, end time.Time) ([]TrailEvent, error) {
+	type lookupAttribute struct {
+		AttributeKey   string `json:"AttributeKey"`
+		AttributeValue string `json:"AttributeValue"`
+	}
+	input := struct {
+		LookupAttributes []lookupAttribute `json:"LookupAttributes"`
+		StartTime        float64           `json:"StartTime"`
+		EndTime          float64           `json:"EndTime"`
+		MaxResults       int               `json:"MaxResults"`
+		NextToken        string            `json:"NextToken,omitempty"`
+	}{
+		LookupAttributes: []lookupAttribute{{"EventSource", source}},
+		StartTime:        float64(start.Unix()),
+		EndTime:          float64(end.Unix()),
+		MaxResults:       50,
+	}
+
+	events := []TrailEvent{}
+	for {
+		var resp struct {
+			Events []struct {
+				EventName       string  `json:"EventName"`
+				EventTime       float64 `json:"EventTime"`
+				Username        string  `json:"Username"`
+				CloudTrailEvent string  `json:"CloudTrailEvent"`
+			} `json:"Events"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := c.lookupPage(&input, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, e := range resp.Events {
+			var detail struct {
+				UserIdentity struct {
+					Arn string `json:"arn"`
+				} `json:"userIdentity"`
+				ReadOnly          bool                   `json:"readOnly"`
+				RequestParameters map[string]interface{} `json:"requestParameters"`
+			}
+			json.Unmarshal([]byte(e.CloudTrailEvent), &detail)
+
+			sec, frac := math.Modf(e.EventTime)
+			event := TrailEvent{
+				Name:              e.EventName,
+				Time:              time.Unix(int64(sec), int64(frac*1e9)),
+				Principal:         detail.UserIdentity.Arn,
+				ReadOnly:          detail.ReadOnly,
+				RequestParameters: detail.RequestParameters,
+			}
+			if event.Principal == "" {
+				event.Principal = e.Username
+			}
+			events = append(events, event)
+		}
+
+		if resp.NextToken == "" {
+			return events, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// lookupPage looks up a page of events, retrying throttling and other transient errors that
+// paging through a long history runs into
+func (c *cloudtrailClient) lookupPage(in, out interface{}) error {
+	interval := lookupRetryInterval
+	for failures := 1; ; failures++ {
+		err := c.Call("LookupEvents", in, out)
+		if err == nil || failures > maxPollErrors || !isTransientError(err) {
+			return err
+		}
+		log.Printf("Failed to look up cloudtrail events, retrying: %v", err)
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestLookupEventsRetriesThrottling(t *testing.T) {
+	defer func(interval time.Duration) { lookupRetryInterval = interval }(lookupRetryInterval)
+	lookupRetryInterval = time.Millisecond
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			fmt.Fprint(w, `{"Events":[{"EventName":"UpdateService","EventTime":1760432400,"Username":"deployer"}],"NextToken":"page2"}`)
+		case 2, 3:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"ThrottlingException","message":"Rate exceeded"}`)
+		default:
+			fmt.Fprint(w, `{"Events":[{"EventName":"RegisterTaskDefinition","EventTime":1760432460,"Username":"deployer"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	c := newCloudtrailClient(session.New(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(server.URL),
+		Credentials: credentials.NewStaticCredentials("AKIA", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+
+	events, err := c.LookupEvents("ecs.amazonaws.com", time.Unix(1760432000, 0), time.Unix(1760433000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Name != "UpdateService" || events[1].Name != "RegisterTaskDefinition" {
+		t.Fatalf("Expected both pages of events, got %#v", events)
+	}
+	if requests != 4 {
+		t.Fatalf("Expected the throttled page to be retried twice, got %d requests", requests)
+	}
+}
+
+func TestLookupEventsGivesUp(t *testing.T) {
+	defer func(interval time.Duration) { lookupRetryInterval = interval }(lookupRetryInterval)
+	lookupRetryInterval = time.Millisecond
+
+	for code, attempts := range map[string]int{"ThrottlingException": maxPollErrors + 1, "InvalidTimeRangeException": 1} {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"__type":%q,"message":"failed"}`, code)
+		}))
+
+		c := newCloudtrailClient(session.New(&aws.Config{
+			Region:      aws.String("us-east-1"),
+			Endpoint:    aws.String(server.URL),
+			Credentials: credentials.NewStaticCredentials("AKIA", "secret", ""),
+			MaxRetries:  aws.Int(0),
+		}))
+		if _, err := c.LookupEvents("ecs.amazonaws.com", time.Now().Add(-time.Hour), time.Now()); err == nil {
+			t.Errorf("Expected %s to fail the lookup", code)
+		}
+		if requests != attempts {
+			t.Errorf("Expected %s to be tried %d times, got %d", code, attempts, requests)
+		}
+		server.Close()
+	}
+}
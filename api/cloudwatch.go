@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type cloudwatchInterface interface {
+	GetMetricStatistics(input *MetricStatisticsInput) ([]Datapoint, error)
+	DescribeAlarms(names []string) ([]Alarm, error)
+}
+
+// Alarm is the state of a cloudwatch metric alarm
+type Alarm struct {
+	Name   string `xml:"AlarmName"`
+	State  string `xml:"StateValue"`
+	Reason string `xml:"StateReason"`
+}
+
+// AlarmStateAlarm is the state of an alarm that is firing
+const AlarmStateAlarm = "ALARM"
+
+type MetricStatisticsInput struct {
+	Namespace  string
+	MetricName string
+	Dimensions map[string]string
+	Statistic  string
+	Period     time.Duration
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+type Datapoint struct {
+	Timestamp time.Time `xml:"Timestamp"`
+	Average   float64   `xml:"Average"`
+	Maximum   float64   `xml:"Maximum"`
+	Minimum   float64   `xml:"Minimum"`
+	Sum       float64   `xml:"Sum"`
+	Unit      string    `xml:"Unit"`
+}
+
+type cloudwatchClient struct {
+	*queryClient
+}
+
+func newCloudwatchClient(p client.ConfigProvider) *cloudwatchClient {
+	return &cloudwatchClient{newQueryClient(p, "monitoring", "2010-08-01")}
+}
+
+func (c *cloudwatchClient) GetMetricStatistics(input *MetricStatisticsInput) ([]Datapoint, error) {
+	params := url.Values{
+		"Namespace":           {input.Namespace},
+		"MetricName":          {input.MetricName},
+		"Statistics.member.1": {input.Statistic},
+		"Period":              {strconv.Itoa(int(input.Period.Seconds()))},
+		"StartTime":           {input.StartTime.UTC().Format(time.RFC3339)},
+		"EndTime":             {input.EndTime.UTC().Format(time.RFC3339)},
+	}
+
+	for idx, k := range sortedKeys(input.Dimensions) {
+		params.Set(fmt.Sprintf("Dimensions.member.%d.Name", idx+1), k)
+		params.Set(fmt.Sprintf("Dimensions.member.%d.Value", idx+1), input.Dimensions[k])
+	}
+
+	var resp struct {
+		Datapoints []Datapoint `xml:"GetMetricStatisticsResult>Datapoints>member"`
+	}
+	if err := c.Call("GetMetricStatistics", params, &resp); err != nil {
+		return nil, err
+	}
+
+	sort.Sort(byTimestamp(resp.Datapoints))
+	return resp.Datapoints, nil
+}
+
+func (c *cloudwatchClient) DescribeAlarms(names []string) ([]Alarm, error) {
+	params := url.Values{}
+	for idx, name := range names {
+		params.Set(fmt.Sprintf("AlarmNames.member.%d", idx+1), name)
+	}
+
+	var resp struct {
+		Alarms []Alarm `xml:"DescribeAlarmsResult>MetricAlarms>member"`
+	}
+	if err := c.Call("DescribeAlarms", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Alarms, nil
+}
+
+type byTimestamp []Datapoint
+
+func (b byTimestamp) Len() int           { return len(b) }
+func (b byTimestamp) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byTimestamp) Less(i, j int) bool { return b[i].Timestamp.Before(b[j].Timestamp) }
+
+var ErrNoDatapoints = errors.New("No datapoints found")
+
+// LatestAverage returns the most recent average of a metric over the last few periods
+func LatestAverage(svc cloudwatchInterface, namespace, metric string, dimensions map[string]string) (float64, error) {
+	now := time.Now()
+	points, err := svc.GetMetricStatistics(&MetricStatisticsInput{
+		Namespace:  namespace,
+		MetricName: metric,
+		Dimensions: dimensions,
+		Statistic:  "Average",
+		Period:     time.Minute,
+		StartTime:  now.Add(-5 * time.Minute),
+		EndTime:    now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 {
+		return 0, ErrNoDatapoints
+	}
+	return points[len(points)-1].Average, nil
+}
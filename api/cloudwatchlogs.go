@@ -1,10 +1,68 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
 	logs "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/lox/ecsy/failure"
 )
 
 type cloudwatchLogsInterface interface {
 	DescribeLogStreamsPages(input *logs.DescribeLogStreamsInput, fn func(p *logs.DescribeLogStreamsOutput, lastPage bool) (shouldContinue bool)) error
 	FilterLogEventsPages(input *logs.FilterLogEventsInput, fn func(p *logs.FilterLogEventsOutput, lastPage bool) (shouldContinue bool)) error
+	GetLogEvents(input *logs.GetLogEventsInput) (*logs.GetLogEventsOutput, error)
+}
+
+type TaskUtilization struct {
+	TaskId         string  `json:"TaskId"`
+	CpuUtilized    float64 `json:"CpuUtilized"`
+	CpuReserved    float64 `json:"CpuReserved"`
+	MemoryUtilized float64 `json:"MemoryUtilized"`
+	MemoryReserved float64 `json:"MemoryReserved"`
+	Timestamp      int64   `json:"Timestamp"`
+}
+
+// LogRetentionDays are the retention periods cloudwatch logs accepts for a log group
+var LogRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// ValidateLogRetention returns an error if cloudwatch logs doesn't accept a retention period
+func ValidateLogRetention(days int) error {
+	for _, d := range LogRetentionDays {
+		if d == days {
+			return nil
+		}
+	}
+	return failure.Errorf(failure.Validation, "Log retention of %d days isn't supported, use one of %v", days, LogRetentionDays)
+}
+
+// ContainerInsightsLogGroup returns the log group that container insights writes performance events to
+func ContainerInsightsLogGroup(cluster string) string {
+	return fmt.Sprintf("/aws/ecs/containerinsights/%s/performance", cluster)
+}
+
+// FindTaskUtilization returns the most recent container insights utilization for each task in a cluster
+func FindTaskUtilization(svc cloudwatchLogsInterface, cluster string, since time.Time) (map[string]TaskUtilization, error) {
+	tasks := map[string]TaskUtilization{}
+
+	err := svc.FilterLogEventsPages(&logs.FilterLogEventsInput{
+		LogGroupName:  aws.String(ContainerInsightsLogGroup(cluster)),
+		FilterPattern: aws.String(`{ $.Type = "Task" }`),
+		StartTime:     aws.Int64(since.UnixNano() / int64(time.Millisecond)),
+	}, func(page *logs.FilterLogEventsOutput, lastPage bool) bool {
+		for _, event := range page.Events {
+			var u TaskUtilization
+			if err := json.Unmarshal([]byte(*event.Message), &u); err != nil {
+				continue
+			}
+			if existing, ok := tasks[u.TaskId]; !ok || existing.Timestamp < u.Timestamp {
+				tasks[u.TaskId] = u
+			}
+		}
+		return !lastPage
+	})
+
+	return tasks, err
 }
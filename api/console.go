@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// federationEndpoint is where sign-in tokens for the console are exchanged, overridden in tests
+var federationEndpoint = "https://signin.aws.amazon.com/federation"
+
+// federationPolicy doesn't restrict a federation token beyond the caller's own permissions,
+// which it's always limited to
+const federationPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`
+
+// ConsoleClusterURL is the console page of a cluster's services
+func ConsoleClusterURL(region, cluster string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/v2/clusters/%s/services?region=%s",
+		region, url.PathEscape(cluster), region)
+}
+
+// ConsoleServiceURL is the console page of a service, from its name or arn
+func ConsoleServiceURL(region, cluster, service string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/v2/clusters/%s/services/%s/health?region=%s",
+		region, url.PathEscape(cluster), url.PathEscape(lastARNSegment(service)), region)
+}
+
+// ConsoleTaskURL is the console page of a task, from its id or arn
+func ConsoleTaskURL(region, cluster, task string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ecs/v2/clusters/%s/tasks/%s/configuration?region=%s",
+		region, url.PathEscape(cluster), url.PathEscape(lastARNSegment(task)), region)
+}
+
+// ConsoleStackURL is the console page of a stack, from its id
+func ConsoleStackURL(region, stackID string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/cloudformation/home?region=%s#/stacks/stackinfo?stackId=%s",
+		region, region, url.QueryEscape(stackID))
+}
+
+func lastARNSegment(s string) string {
+	return s[strings.LastIndex(s, "/")+1:]
+}
+
+// ConsoleOptions configure the session a console sign-in url starts
+type ConsoleOptions struct {
+	// RoleArn is a role to assume for the session, otherwise the caller's own credentials
+	// are used
+	RoleArn string
+	// Duration is how long the session lasts, between 15 minutes and 12 hours
+	Duration time.Duration
+}
+
+// ConsoleSigninURL returns a url that signs in to the console and opens destination. The
+// session uses temporary credentials, from assuming a role, the caller's credentials if they
+// are already temporary, or otherwise a federation token.
+func ConsoleSigninURL(svc Services, destination string, opts ConsoleOptions) (string, error) {
+	if opts.Duration == 0 {
+		opts.Duration = time.Hour
+	}
+	duration := aws.Int64(int64(opts.Duration / time.Second))
+
+	creds, err := svc.Credentials.Get()
+	if err != nil {
+		return "", err
+	}
+	session := map[string]string{
+		"sessionId":    creds.AccessKeyID,
+		"sessionKey":   creds.SecretAccessKey,
+		"sessionToken": creds.SessionToken,
+	}
+
+	params := url.Values{"Action": {"getSigninToken"}}
+	var temporary *sts.Credentials
+	if opts.RoleArn != "" {
+		resp, err := svc.STS.AssumeRole(&sts.AssumeRoleInput{
+			RoleArn:         aws.String(opts.RoleArn),
+			RoleSessionName: aws.String(fmt.Sprintf("ecsy-console-%d", time.Now().Unix())),
+			DurationSeconds: duration,
+		})
+		if err != nil {
+			return "", err
+		}
+		temporary = resp.Credentials
+	} else if creds.SessionToken == "" {
+		resp, err := svc.STS.GetFederationToken(&sts.GetFederationTokenInput{
+			Name:            aws.String("ecsy-console"),
+			Policy:          aws.String(federationPolicy),
+			DurationSeconds: duration,
+		})
+		if err != nil {
+			return "", err
+		}
+		temporary = resp.Credentials
+		// sessions from assumed roles last as long as their credentials, only federation
+		// tokens can set a duration
+		params.Set("SessionDuration", fmt.Sprintf("%d", *duration))
+	}
+
+	if temporary != nil {
+		session["sessionId"] = aws.StringValue(temporary.AccessKeyId)
+		session["sessionKey"] = aws.StringValue(temporary.SecretAccessKey)
+		session["sessionToken"] = aws.StringValue(temporary.SessionToken)
+	}
+	b, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	params.Set("Session", string(b))
+
+	resp, err := http.Get(federationEndpoint + "?" + params.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed to get a console sign-in token: %s", resp.Status)
+	}
+
+	var token struct {
+		SigninToken string
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	return federationEndpoint + "?" + url.Values{
+		"Action":      {"login"},
+		"Issuer":      {"ecsy"},
+		"Destination": {destination},
+		"SigninToken": {token.SigninToken},
+	}.Encode(), nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type fakeFederationSTS struct {
+	stsInterface
+	federated bool
+}
+
+func (f *fakeFederationSTS) GetFederationToken(*sts.GetFederationTokenInput) (*sts.GetFederationTokenOutput, error) {
+	f.federated = true
+	return &sts.GetFederationTokenOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("ASIA"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("federated"),
+	}}, nil
+}
+
+func TestConsoleSigninURL(t *testing.T) {
+	var session map[string]string
+	var duration string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.Unmarshal([]byte(r.URL.Query().Get("Session")), &session)
+		duration = r.URL.Query().Get("SessionDuration")
+		w.Write([]byte(`{"SigninToken":"llamas"}`))
+	}))
+	defer server.Close()
+
+	endpoint := federationEndpoint
+	federationEndpoint = server.URL
+	defer func() { federationEndpoint = endpoint }()
+
+	fake := &fakeFederationSTS{}
+	svc := Services{
+		STS:         fake,
+		Credentials: credentials.NewStaticCredentials("AKIA", "secret", ""),
+	}
+
+	destination := ConsoleServiceURL("us-east-1", "prod", "arn:aws:ecs:us-east-1:123456789012:service/prod/web")
+	if !strings.HasSuffix(destination, "/clusters/prod/services/web/health?region=us-east-1") {
+		t.Fatalf("Unexpected service url %s", destination)
+	}
+
+	signin, err := ConsoleSigninURL(svc, destination, ConsoleOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fake.federated || session["sessionToken"] != "federated" || duration != "3600" {
+		t.Fatalf("Expected long lived keys to be exchanged for a federation token, got %v", session)
+	}
+
+	u, err := url.Parse(signin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := u.Query(); q.Get("SigninToken") != "llamas" || q.Get("Destination") != destination {
+		t.Fatalf("Unexpected sign-in url %s", signin)
+	}
+
+	// credentials that are already temporary are used as they are
+	fake.federated = false
+	svc.Credentials = credentials.NewStaticCredentials("ASIA", "secret", "token")
+	if _, err = ConsoleSigninURL(svc, destination, ConsoleOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.federated || session["sessionToken"] != "token" || duration != "" {
+		t.Fatalf("Expected the session's own credentials to be used, got %v", session)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Container instance statuses that can be set, instances that are draining get no new
+// tasks and have their service tasks replaced elsewhere
+const (
+	ContainerInstanceActive   = "ACTIVE"
+	ContainerInstanceDraining = "DRAINING"
+)
+
+// The vendored ecs client predates draining and attributes, so they're called directly
+type containerInstanceInterface interface {
+	UpdateContainerInstancesState(cluster string, arns []string, status string) error
+	PutAttribute(cluster, arn, name, value string) error
+	DeleteAttribute(cluster, arn, name string) error
+}
+
+type containerInstanceClient struct {
+	*jsonClient
+}
+
+func newContainerInstanceClient(p client.ConfigProvider) *containerInstanceClient {
+	return &containerInstanceClient{newJSONClient(p, "ecs", "AmazonEC2ContainerServiceV20141113", "1.1")}
+}
+
+func (c *containerInstanceClient) UpdateContainerInstancesState(cluster string, arns []string, status string) error {
+	return c.Call("UpdateContainerInstancesState", &struct {
+		Cluster            string   `json:"cluster"`
+		ContainerInstances []string `json:"containerInstances"`
+		Status             string   `json:"status"`
+	}{cluster, arns, status}, nil)
+}
+
+type containerInstanceAttribute struct {
+	Name       string `json:"name"`
+	Value      string `json:"value,omitempty"`
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+}
+
+// PutAttribute sets a custom attribute on a container instance, which placement constraints
+// can match on
+func (c *containerInstanceClient) PutAttribute(cluster, arn, name, value string) error {
+	return c.Call("PutAttributes", &struct {
+		Cluster    string                       `json:"cluster"`
+		Attributes []containerInstanceAttribute `json:"attributes"`
+	}{cluster, []containerInstanceAttribute{{name, value, "container-instance", arn}}}, nil)
+}
+
+func (c *containerInstanceClient) DeleteAttribute(cluster, arn, name string) error {
+	return c.Call("DeleteAttributes", &struct {
+		Cluster    string                       `json:"cluster"`
+		Attributes []containerInstanceAttribute `json:"attributes"`
+	}{cluster, []containerInstanceAttribute{{Name: name, TargetType: "container-instance", TargetID: arn}}}, nil)
+}
+
+// PollUntilDrained waits until container instances have no running tasks, calling f with
+// the number of tasks still running on them whenever that changes
+func PollUntilDrained(ctx context.Context, svc ECSAPI, cluster string, arns []string, f func(running int64)) error {
+	last := int64(-1)
+	for {
+		resp, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(cluster),
+			ContainerInstances: aws.StringSlice(arns),
+		})
+		if err != nil {
+			return err
+		}
+
+		var running int64
+		for _, ci := range resp.ContainerInstances {
+			running += aws.Int64Value(ci.RunningTasksCount)
+		}
+		if running != last {
+			f(running)
+			last = running
+		}
+		if running == 0 {
+			return nil
+		}
+
+		if err := Sleep(ctx, 5*time.Second, "instances to drain", fmt.Sprintf("%d tasks were still running", running)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+// Cost allocation tags that ecsy puts on clusters, services and what they run, so that
+// spend can be broken down by them once they are activated
+const (
+	TagCostCluster = "ecsy:cluster"
+	TagCostService = "ecsy:service"
+)
+
+type costsInterface interface {
+	CostsByTag(start, end time.Time, filterKey, filterValue, groupKey string) ([]TagCost, error)
+	CostAllocationTags(keys ...string) (map[string]string, error)
+	ActivateCostAllocationTags(keys ...string) error
+}
+
+// TagCost is the spend on resources with a value of a tag, the value is empty for
+// resources without the tag
+type TagCost struct {
+	Value  string
+	Amount float64
+	Unit   string
+}
+
+type costsClient struct {
+	*jsonClient
+}
+
+// cost explorer only has an endpoint in us-east-1
+func newCostsClient(p client.ConfigProvider) *costsClient {
+	return &costsClient{newJSONClient(p, "ce", "AWSInsightsIndexService", "1.1",
+		&aws.Config{Region: aws.String("us-east-1")})}
+}
+
+// CostsByTag returns the unblended cost of resources with a tag value between two dates,
+// grouped by the values of another tag
+func (c *costsClient) CostsByTag(start, end time.Time, filterKey, filterValue, groupKey string) ([]TagCost, error) {
+	type tagValues struct {
+		Key    string   `json:"Key"`
+		Values []string `json:"Values"`
+	}
+	type groupDefinition struct {
+		Type string `json:"Type"`
+		Key  string `json:"Key"`
+	}
+	input := struct {
+		TimePeriod struct {
+			Start string `json:"Start"`
+			End   string `json:"End"`
+		} `json:"TimePeriod"`
+		Granularity   string                 `json:"Granularity"`
+		Metrics       []string               `json:"Metrics"`
+		Filter        map[string]interface{} `json:"Filter"`
+		GroupBy       []groupDefinition      `json:"GroupBy"`
+		NextPageToken string                 `json:"NextPageToken,omitempty"`
+	}{
+		Granularity: "MONTHLY",
+		Metrics:     []string{"UnblendedCost"},
+		Filter:      map[string]interface{}{"Tags": tagValues{filterKey, []string{filterValue}}},
+		GroupBy:     []groupDefinition{{"TAG", groupKey}},
+	}
+	input.TimePeriod.Start = start.Format("2006-01-02")
+	input.TimePeriod.End = end.Format("2006-01-02")
+
+	totals := map[string]*TagCost{}
+	values := []string{}
+	for {
+		var resp struct {
+			ResultsByTime []struct {
+				Groups []struct {
+					Keys    []string `json:"Keys"`
+					Metrics map[string]struct {
+						Amount string `json:"Amount"`
+						Unit   string `json:"Unit"`
+					} `json:"Metrics"`
+				} `json:"Groups"`
+			} `json:"ResultsByTime"`
+			NextPageToken string `json:"NextPageToken"`
+		}
+		if err := c.Call("GetCostAndUsage", &input, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, result := range resp.ResultsByTime {
+			for _, g := range result.Groups {
+				// group keys look like ecsy:service$web, with nothing after the $ when untagged
+				value := ""
+				if len(g.Keys) > 0 {
+					value = g.Keys[0][strings.Index(g.Keys[0], "$")+1:]
+				}
+				metric := g.Metrics["UnblendedCost"]
+				amount, err := strconv.ParseFloat(metric.Amount, 64)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := totals[value]; !ok {
+					totals[value] = &TagCost{Value: value, Unit: metric.Unit}
+					values = append(values, value)
+				}
+				totals[value].Amount += amount
+			}
+		}
+
+		if resp.NextPageToken == "" {
+			break
+		}
+		input.NextPageToken = resp.NextPageToken
+	}
+
+	costs := []TagCost{}
+	for _, value := range values {
+		costs = append(costs, *totals[value])
+	}
+	return costs, nil
+}
+
+// CostAllocationTags returns the status of cost allocation tags, Active or Inactive, tags
+// that billing hasn't seen on any resource yet are left out
+func (c *costsClient) CostAllocationTags(keys ...string) (map[string]string, error) {
+	var resp struct {
+		CostAllocationTags []struct {
+			TagKey string `json:"TagKey"`
+			Status string `json:"Status"`
+		} `json:"CostAllocationTags"`
+	}
+	err := c.Call("ListCostAllocationTags", &struct {
+		TagKeys []string `json:"TagKeys"`
+	}{keys}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	statuses := map[string]string{}
+	for _, t := range resp.CostAllocationTags {
+		statuses[t.TagKey] = t.Status
+	}
+	return statuses, nil
+}
+
+// ActivateCostAllocationTags makes billing break down spend by tags from now on
+func (c *costsClient) ActivateCostAllocationTags(keys ...string) error {
+	type tagStatus struct {
+		TagKey string `json:"TagKey"`
+		Status string `json:"Status"`
+	}
+	statuses := []tagStatus{}
+	for _, key := range keys {
+		statuses = append(statuses, tagStatus{key, "Active"})
+	}
+	return c.Call("UpdateCostAllocationTagsStatus", &struct {
+		CostAllocationTagsStatus []tagStatus `json:"CostAllocationTagsStatus"`
+	}{statuses}, nil)
+}
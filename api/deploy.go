@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DeployOptions select the service a task definition is deployed to
+type DeployOptions struct {
+	Cluster string
+	// Service is the task family of an ecsy service, which its stack is found by
+	Service string
+	// TaskDefinition is a family:revision or arn to deploy, by default the service's
+	// current task definition is registered again with Images applied to it
+	TaskDefinition string
+	// Images are container names to an image, or just a tag like :v2 for the current image
+	Images map[string]string
+	// ImageTag is a tag for the images of the containers that aren't in Images
+	ImageTag string
+	// DesiredCount changes the number of tasks the service runs, when it's set
+	DesiredCount int64
+	// OnEvent is called with each of the service's events until the deploy finishes
+	OnEvent func(e *ecs.ServiceEvent)
+}
+
+// DeployResult is the task definition a service was deployed with
+type DeployResult struct {
+	TaskDefinitionArn string
+	Images            map[string]string
+	// URL is the service's load balancer, if it has one
+	URL string
+}
+
+// Deploy updates an ecsy service to a task definition and waits until its tasks are
+// running, or ctx ends. It's the core of `ecsy deploy` without compose files, hooks,
+// locks or notifications, for tools that embed ecsy.
+func Deploy(ctx context.Context, svc Services, opts DeployOptions) (*DeployResult, error) {
+	stack, err := FindServiceStack(svc.Cloudformation, opts.Cluster, opts.Service)
+	if err != nil {
+		return nil, err
+	}
+	outputs := StackOutputMap(stack)
+
+	td, err := deployTaskDefinition(svc.ECS, outputs["ECSCluster"], outputs["ECSService"], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &ecs.UpdateServiceInput{
+		Service:        aws.String(outputs["ECSService"]),
+		Cluster:        aws.String(outputs["ECSCluster"]),
+		TaskDefinition: td.TaskDefinitionArn,
+	}
+	if opts.DesiredCount > 0 {
+		update.DesiredCount = aws.Int64(opts.DesiredCount)
+	}
+	if _, err = svc.ECS.UpdateService(update); err != nil {
+		return nil, err
+	}
+
+	f := opts.OnEvent
+	if f == nil {
+		f = func(*ecs.ServiceEvent) {}
+	}
+	err = PollUntilTaskDeployed(ctx, svc.ECS, outputs["ECSCluster"], outputs["ECSService"], *td.TaskDefinitionArn, f)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeployResult{
+		TaskDefinitionArn: *td.TaskDefinitionArn,
+		Images:            map[string]string{},
+		URL:               outputs["ECSLoadBalancer"],
+	}
+	for _, def := range td.ContainerDefinitions {
+		result.Images[*def.Name] = aws.StringValue(def.Image)
+	}
+	return result, nil
+}
+
+// deployTaskDefinition returns the task definition to deploy, registering a new revision
+// when images are changed
+func deployTaskDefinition(svc ECSAPI, cluster, service string, opts DeployOptions) (*ecs.TaskDefinition, error) {
+	taskDefinition := opts.TaskDefinition
+	if taskDefinition == "" {
+		current, err := DescribeService(svc, cluster, service)
+		if err != nil {
+			return nil, err
+		}
+		taskDefinition = aws.StringValue(current.TaskDefinition)
+	}
+
+	resp, err := svc.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		return nil, err
+	}
+	images := map[string]string{}
+	for name, image := range opts.Images {
+		images[name] = image
+	}
+	if opts.ImageTag != "" {
+		for _, def := range resp.TaskDefinition.ContainerDefinitions {
+			if _, ok := images[*def.Name]; !ok {
+				images[*def.Name] = ":" + opts.ImageTag
+			}
+		}
+	}
+	if len(images) == 0 {
+		return resp.TaskDefinition, nil
+	}
+
+	// images are updated in copies, so that the described task definition isn't changed
+	input := RegisterTaskDefinitionInput(resp.TaskDefinition)
+	input.ContainerDefinitions = []*ecs.ContainerDefinition{}
+	for _, def := range resp.TaskDefinition.ContainerDefinitions {
+		copied := *def
+		input.ContainerDefinitions = append(input.ContainerDefinitions, &copied)
+	}
+	if err = UpdateContainerImages(input.ContainerDefinitions, images); err != nil {
+		return nil, fmt.Errorf("Failed to update images of %s: %v", taskDefinition, err)
+	}
+	registered, err := svc.RegisterTaskDefinition(input)
+	if err != nil {
+		return nil, err
+	}
+	return registered.TaskDefinition, nil
+}
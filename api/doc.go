@@ -0,0 +1,29 @@
+// Package api is the library that ecsy's commands are built on, for tools that embed
+// ecsy's logic rather than shelling out to it.
+//
+// Services holds the aws clients, from DefaultServices for the default credentials and
+// region, EnvironmentServices for a region and role, or apitest for in-memory fakes:
+//
+//	svc := api.DefaultServices
+//	network, err := api.FindNetworkStack(svc.Cloudformation, "production")
+//
+// Stacks are created and updated from templates with StackOptions, and ApplyStack does
+// either and waits for it to finish:
+//
+//	err := api.ApplyStack(ctx, svc.Cloudformation, "production-network", templates.NetworkStack(), api.StackOptions{
+//		Params: map[string]string{"VpcEndpoints": "s3"},
+//	}, func(e *cloudformation.StackEvent) { log.Println(*e.ResourceStatus) })
+//
+// Deploy updates a service's images, or deploys a task definition registered elsewhere:
+//
+//	result, err := api.Deploy(ctx, svc, api.DeployOptions{
+//		Cluster: "production",
+//		Service: "web",
+//		Images:  map[string]string{"web": ":v2"},
+//	})
+//
+// Functions that wait, like Deploy, ApplyStack, PollUntilCreated and PollUntilTaskDeployed,
+// stop when their context ends. The exported functions and types of this package are kept
+// compatible between releases, with anything that changes left in place and marked
+// Deprecated first.
+package api
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type dynamodbInterface interface {
+	PutItem(input *PutItemInput) error
+	GetItem(input *GetItemInput) (map[string]AttributeValue, error)
+	UpdateItem(input *UpdateItemInput) error
+	DeleteItem(input *DeleteItemInput) error
+	Query(input *QueryInput) ([]map[string]AttributeValue, error)
+	CreateTable(input *CreateTableInput) error
+	DescribeTable(table string) (string, error)
+}
+
+// AttributeValue is a dynamodb attribute, only strings and numbers are supported
+type AttributeValue struct {
+	S *string `json:",omitempty"`
+	N *string `json:",omitempty"`
+}
+
+type PutItemInput struct {
+	TableName                 string
+	Item                      map[string]AttributeValue
+	ConditionExpression       string                    `json:",omitempty"`
+	ExpressionAttributeValues map[string]AttributeValue `json:",omitempty"`
+}
+
+type GetItemInput struct {
+	TableName      string
+	Key            map[string]AttributeValue
+	ConsistentRead bool
+}
+
+type UpdateItemInput struct {
+	TableName                 string
+	Key                       map[string]AttributeValue
+	UpdateExpression          string
+	ConditionExpression       string                    `json:",omitempty"`
+	ExpressionAttributeValues map[string]AttributeValue `json:",omitempty"`
+}
+
+type DeleteItemInput struct {
+	TableName                 string
+	Key                       map[string]AttributeValue
+	ConditionExpression       string                    `json:",omitempty"`
+	ExpressionAttributeValues map[string]AttributeValue `json:",omitempty"`
+}
+
+type QueryInput struct {
+	TableName                 string
+	KeyConditionExpression    string
+	ExpressionAttributeValues map[string]AttributeValue `json:",omitempty"`
+	ScanIndexForward          bool
+	Limit                     int64 `json:",omitempty"`
+}
+
+type KeySchemaElement struct {
+	AttributeName string
+	KeyType       string
+}
+
+type AttributeDefinition struct {
+	AttributeName string
+	AttributeType string
+}
+
+type CreateTableInput struct {
+	TableName            string
+	KeySchema            []KeySchemaElement
+	AttributeDefinitions []AttributeDefinition
+	BillingMode          string
+}
+
+// ErrTableNotFound is returned when a dynamodb table doesn't exist
+var ErrTableNotFound = errors.New("Table not found")
+
+type dynamodbClient struct {
+	*jsonClient
+}
+
+func newDynamoDBClient(p client.ConfigProvider) *dynamodbClient {
+	return &dynamodbClient{newJSONClient(p, "dynamodb", "DynamoDB_20120810", "1.0")}
+}
+
+func (c *dynamodbClient) PutItem(input *PutItemInput) error {
+	return c.Call("PutItem", input, nil)
+}
+
+func (c *dynamodbClient) GetItem(input *GetItemInput) (map[string]AttributeValue, error) {
+	var resp struct {
+		Item map[string]AttributeValue
+	}
+	if err := c.Call("GetItem", input, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Item, nil
+}
+
+func (c *dynamodbClient) UpdateItem(input *UpdateItemInput) error {
+	return c.Call("UpdateItem", input, nil)
+}
+
+func (c *dynamodbClient) DeleteItem(input *DeleteItemInput) error {
+	return c.Call("DeleteItem", input, nil)
+}
+
+// Query returns the items matching a key condition, following pages until the limit, if
+// any, is reached
+func (c *dynamodbClient) Query(input *QueryInput) ([]map[string]AttributeValue, error) {
+	items := []map[string]AttributeValue{}
+	params := struct {
+		*QueryInput
+		ExclusiveStartKey map[string]AttributeValue `json:",omitempty"`
+	}{QueryInput: input}
+	for {
+		var resp struct {
+			Items            []map[string]AttributeValue
+			LastEvaluatedKey map[string]AttributeValue
+		}
+		if err := c.Call("Query", params, &resp); err != nil {
+			return nil, err
+		}
+		items = append(items, resp.Items...)
+		if len(resp.LastEvaluatedKey) == 0 || (input.Limit > 0 && int64(len(items)) >= input.Limit) {
+			return items, nil
+		}
+		params.ExclusiveStartKey = resp.LastEvaluatedKey
+	}
+}
+
+func (c *dynamodbClient) CreateTable(input *CreateTableInput) error {
+	return c.Call("CreateTable", input, nil)
+}
+
+// DescribeTable returns the status of a table
+func (c *dynamodbClient) DescribeTable(table string) (string, error) {
+	var resp struct {
+		Table struct {
+			TableStatus string
+		}
+	}
+	err := c.Call("DescribeTable", map[string]string{"TableName": table}, &resp)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ResourceNotFoundException" {
+		return "", ErrTableNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return resp.Table.TableStatus, nil
+}
@@ -0,0 +1,408 @@
+package api
+
+import (
+	"encoding/xml"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// EC2API is the subset of the ec2 api that ecsy uses
+type EC2API interface {
+	DescribeSecurityGroups(groupIds []string) ([]SecurityGroup, error)
+	DescribeInstances(instanceIds []string) ([]Instance, error)
+	DescribeKeyPairs() ([]string, error)
+	DescribeInstanceTypeOfferings() ([]string, error)
+	DescribeInstanceTypeZones(instanceType string) ([]string, error)
+	DescribeAvailabilityZones() ([]string, error)
+	DescribeSubnets(subnetIds []string) ([]Subnet, error)
+	DescribeImages(imageIds []string) ([]string, error)
+	DescribeVpcs() ([]string, error)
+	DescribeAddresses() ([]string, error)
+	DescribeInstanceEvents(instanceIds []string) ([]InstanceEvent, error)
+	DescribeImageCreationDates(imageIds []string) (map[string]time.Time, error)
+}
+
+// Instance is an EC2 instance and where it can be reached
+type Instance struct {
+	ID               string
+	State            string
+	AvailabilityZone string
+	PrivateIP        string
+	PublicIP         string
+}
+
+// InstanceEvent is maintenance that AWS has scheduled for an instance, like its retirement
+// or a reboot
+type InstanceEvent struct {
+	InstanceID  string
+	Code        string
+	Description string
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+// Subnet is a subnet of a VPC and the availability zone it's in
+type Subnet struct {
+	ID               string
+	AvailabilityZone string
+}
+
+// SecurityGroup is an EC2 security group and its ingress rules
+type SecurityGroup struct {
+	ID          string
+	Name        string
+	Description string
+	Ingress     []SecurityGroupRule
+}
+
+// SecurityGroupRule is a single source allowed to access a port range
+type SecurityGroupRule struct {
+	Protocol      string
+	FromPort      int
+	ToPort        int
+	CidrIP        string
+	SourceGroupID string
+}
+
+type ec2Client struct {
+	*queryClient
+}
+
+func newEC2Client(p client.ConfigProvider) *ec2Client {
+	qc := newQueryClient(p, "ec2", "2016-11-15")
+	qc.Handlers.UnmarshalError.Clear()
+	qc.Handlers.UnmarshalError.PushBack(unmarshalEC2Error)
+	return &ec2Client{qc}
+}
+
+// ec2 uses a variant of the query protocol with a different error document
+func unmarshalEC2Error(r *request.Request) {
+	defer r.HTTPResponse.Body.Close()
+
+	var resp struct {
+		Code      string `xml:"Errors>Error>Code"`
+		Message   string `xml:"Errors>Error>Message"`
+		RequestID string `xml:"RequestID"`
+	}
+	if err := xml.NewDecoder(r.HTTPResponse.Body).Decode(&resp); err != nil {
+		r.Error = awserr.New("SerializationError", "failed decoding ec2 error response", err)
+		return
+	}
+	r.Error = awserr.NewRequestFailure(awserr.New(resp.Code, resp.Message, nil),
+		r.HTTPResponse.StatusCode, resp.RequestID)
+}
+
+func (c *ec2Client) DescribeSecurityGroups(groupIds []string) ([]SecurityGroup, error) {
+	var resp struct {
+		Groups []struct {
+			GroupId     string `xml:"groupId"`
+			GroupName   string `xml:"groupName"`
+			Description string `xml:"groupDescription"`
+			Permissions []struct {
+				Protocol string   `xml:"ipProtocol"`
+				FromPort string   `xml:"fromPort"`
+				ToPort   string   `xml:"toPort"`
+				Groups   []string `xml:"groups>item>groupId"`
+				Ranges   []string `xml:"ipRanges>item>cidrIp"`
+			} `xml:"ipPermissions>item"`
+		} `xml:"securityGroupInfo>item"`
+	}
+
+	params := url.Values{}
+	for idx, id := range groupIds {
+		params.Set("GroupId."+strconv.Itoa(idx+1), id)
+	}
+	if err := c.Call("DescribeSecurityGroups", params, &resp); err != nil {
+		return nil, err
+	}
+
+	groups := []SecurityGroup{}
+	for _, g := range resp.Groups {
+		sg := SecurityGroup{
+			ID:          g.GroupId,
+			Name:        g.GroupName,
+			Description: g.Description,
+		}
+		for _, p := range g.Permissions {
+			from, _ := strconv.Atoi(p.FromPort)
+			to, _ := strconv.Atoi(p.ToPort)
+			for _, cidr := range p.Ranges {
+				sg.Ingress = append(sg.Ingress, SecurityGroupRule{
+					Protocol: p.Protocol, FromPort: from, ToPort: to, CidrIP: cidr,
+				})
+			}
+			for _, group := range p.Groups {
+				sg.Ingress = append(sg.Ingress, SecurityGroupRule{
+					Protocol: p.Protocol, FromPort: from, ToPort: to, SourceGroupID: group,
+				})
+			}
+		}
+		groups = append(groups, sg)
+	}
+	return groups, nil
+}
+
+func (c *ec2Client) DescribeInstances(instanceIds []string) ([]Instance, error) {
+	var resp struct {
+		Instances []struct {
+			InstanceId string `xml:"instanceId"`
+			State      string `xml:"instanceState>name"`
+			Zone       string `xml:"placement>availabilityZone"`
+			PrivateIP  string `xml:"privateIpAddress"`
+			PublicIP   string `xml:"ipAddress"`
+		} `xml:"reservationSet>item>instancesSet>item"`
+	}
+
+	params := url.Values{}
+	for idx, id := range instanceIds {
+		params.Set("InstanceId."+strconv.Itoa(idx+1), id)
+	}
+	if err := c.Call("DescribeInstances", params, &resp); err != nil {
+		return nil, err
+	}
+
+	instances := []Instance{}
+	for _, i := range resp.Instances {
+		instances = append(instances, Instance{
+			ID:               i.InstanceId,
+			State:            i.State,
+			AvailabilityZone: i.Zone,
+			PrivateIP:        i.PrivateIP,
+			PublicIP:         i.PublicIP,
+		})
+	}
+	return instances, nil
+}
+
+// DescribeKeyPairs returns the names of the key pairs in the region
+func (c *ec2Client) DescribeKeyPairs() ([]string, error) {
+	var resp struct {
+		Names []string `xml:"keySet>item>keyName"`
+	}
+	if err := c.Call("DescribeKeyPairs", nil, &resp); err != nil {
+		return nil, err
+	}
+	sort.Strings(resp.Names)
+	return resp.Names, nil
+}
+
+// DescribeInstanceTypeOfferings returns the names of the instance types offered in the region
+func (c *ec2Client) DescribeInstanceTypeOfferings() ([]string, error) {
+	offerings, err := c.instanceTypeOfferings(url.Values{"LocationType": {"region"}})
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, o := range offerings {
+		names = append(names, o.InstanceType)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// DescribeInstanceTypeZones returns the availability zones an instance type is offered in
+func (c *ec2Client) DescribeInstanceTypeZones(instanceType string) ([]string, error) {
+	offerings, err := c.instanceTypeOfferings(url.Values{
+		"LocationType":     {"availability-zone"},
+		"Filter.1.Name":    {"instance-type"},
+		"Filter.1.Value.1": {instanceType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	zones := []string{}
+	for _, o := range offerings {
+		zones = append(zones, o.Location)
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+type instanceTypeOffering struct {
+	InstanceType string `xml:"instanceType"`
+	Location     string `xml:"location"`
+}
+
+func (c *ec2Client) instanceTypeOfferings(params url.Values) ([]instanceTypeOffering, error) {
+	params.Set("MaxResults", "1000")
+	offerings := []instanceTypeOffering{}
+	for {
+		var resp struct {
+			Offerings []instanceTypeOffering `xml:"instanceTypeOfferingSet>item"`
+			NextToken string                 `xml:"nextToken"`
+		}
+		if err := c.Call("DescribeInstanceTypeOfferings", params, &resp); err != nil {
+			return nil, err
+		}
+		offerings = append(offerings, resp.Offerings...)
+		if resp.NextToken == "" {
+			return offerings, nil
+		}
+		params.Set("NextToken", resp.NextToken)
+	}
+}
+
+// DescribeAvailabilityZones returns the names of the available zones in the region, in the
+// order that Fn::GetAZs lists them
+func (c *ec2Client) DescribeAvailabilityZones() ([]string, error) {
+	var resp struct {
+		Zones []string `xml:"availabilityZoneInfo>item>zoneName"`
+	}
+	err := c.Call("DescribeAvailabilityZones", url.Values{
+		"Filter.1.Name":    {"state"},
+		"Filter.1.Value.1": {"available"},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(resp.Zones)
+	return resp.Zones, nil
+}
+
+func (c *ec2Client) DescribeSubnets(subnetIds []string) ([]Subnet, error) {
+	var resp struct {
+		Subnets []struct {
+			SubnetId string `xml:"subnetId"`
+			Zone     string `xml:"availabilityZone"`
+		} `xml:"subnetSet>item"`
+	}
+
+	params := url.Values{}
+	for idx, id := range subnetIds {
+		params.Set("SubnetId."+strconv.Itoa(idx+1), id)
+	}
+	if err := c.Call("DescribeSubnets", params, &resp); err != nil {
+		return nil, err
+	}
+
+	subnets := []Subnet{}
+	for _, s := range resp.Subnets {
+		subnets = append(subnets, Subnet{ID: s.SubnetId, AvailabilityZone: s.Zone})
+	}
+	return subnets, nil
+}
+
+// DescribeImages returns which of the images are available in the region, images that don't
+// exist are left out rather than failing
+func (c *ec2Client) DescribeImages(imageIds []string) ([]string, error) {
+	var resp struct {
+		IDs []string `xml:"imagesSet>item>imageId"`
+	}
+
+	params := url.Values{
+		"Filter.1.Name":    {"image-id"},
+		"Filter.2.Name":    {"state"},
+		"Filter.2.Value.1": {"available"},
+	}
+	for idx, id := range imageIds {
+		params.Set("Filter.1.Value."+strconv.Itoa(idx+1), id)
+	}
+	if err := c.Call("DescribeImages", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IDs, nil
+}
+
+// DescribeImageCreationDates returns when images were created, images that don't exist
+// are left out
+func (c *ec2Client) DescribeImageCreationDates(imageIds []string) (map[string]time.Time, error) {
+	var resp struct {
+		Images []struct {
+			ID           string    `xml:"imageId"`
+			CreationDate time.Time `xml:"creationDate"`
+		} `xml:"imagesSet>item"`
+	}
+
+	params := url.Values{}
+	for idx, id := range imageIds {
+		params.Set("ImageId."+strconv.Itoa(idx+1), id)
+	}
+	if err := c.Call("DescribeImages", params, &resp); err != nil {
+		return nil, err
+	}
+
+	dates := map[string]time.Time{}
+	for _, image := range resp.Images {
+		dates[image.ID] = image.CreationDate
+	}
+	return dates, nil
+}
+
+// DescribeVpcs returns the ids of the VPCs in the region
+func (c *ec2Client) DescribeVpcs() ([]string, error) {
+	var resp struct {
+		IDs []string `xml:"vpcSet>item>vpcId"`
+	}
+	if err := c.Call("DescribeVpcs", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IDs, nil
+}
+
+// DescribeAddresses returns the public ips of the elastic ips allocated in the region
+func (c *ec2Client) DescribeAddresses() ([]string, error) {
+	var resp struct {
+		IPs []string `xml:"addressesSet>item>publicIp"`
+	}
+	if err := c.Call("DescribeAddresses", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.IPs, nil
+}
+
+// DescribeInstanceEvents returns the scheduled events of instances that haven't completed
+// or been canceled
+func (c *ec2Client) DescribeInstanceEvents(instanceIds []string) ([]InstanceEvent, error) {
+	events := []InstanceEvent{}
+
+	// DescribeInstanceStatus accepts at most 100 instances at a time
+	for len(instanceIds) > 0 {
+		n := len(instanceIds)
+		if n > 100 {
+			n = 100
+		}
+		var resp struct {
+			Statuses []struct {
+				InstanceId string `xml:"instanceId"`
+				Events     []struct {
+					Code        string    `xml:"code"`
+					Description string    `xml:"description"`
+					NotBefore   time.Time `xml:"notBefore"`
+					NotAfter    time.Time `xml:"notAfter"`
+				} `xml:"eventsSet>item"`
+			} `xml:"instanceStatusSet>item"`
+		}
+
+		params := url.Values{"IncludeAllInstances": {"true"}}
+		for idx, id := range instanceIds[:n] {
+			params.Set("InstanceId."+strconv.Itoa(idx+1), id)
+		}
+		if err := c.Call("DescribeInstanceStatus", params, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, s := range resp.Statuses {
+			for _, e := range s.Events {
+				if strings.HasPrefix(e.Description, "[Completed]") || strings.HasPrefix(e.Description, "[Canceled]") {
+					continue
+				}
+				events = append(events, InstanceEvent{
+					InstanceID:  s.InstanceId,
+					Code:        e.Code,
+					Description: e.Description,
+					NotBefore:   e.NotBefore,
+					NotAfter:    e.NotAfter,
+				})
+			}
+		}
+		instanceIds = instanceIds[n:]
+	}
+	return events, nil
+}
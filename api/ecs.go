@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,7 +14,8 @@ import (
 
 const ECS_POLL_INTERVAL = 1 * time.Second
 
-type ecsInterface interface {
+// ECSAPI is the subset of the ecs api that ecsy uses
+type ECSAPI interface {
 	DescribeServices(*ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error)
 	CreateCluster(*ecs.CreateClusterInput) (*ecs.CreateClusterOutput, error)
 	RegisterTaskDefinition(*ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error)
@@ -21,6 +23,13 @@ type ecsInterface interface {
 	RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error)
 	DescribeTasks(input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error)
 	WaitUntilTasksStopped(input *ecs.DescribeTasksInput) error
+	ListServicesPages(input *ecs.ListServicesInput, fn func(p *ecs.ListServicesOutput, lastPage bool) (shouldContinue bool)) error
+	DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error)
+	ListTasksPages(input *ecs.ListTasksInput, fn func(p *ecs.ListTasksOutput, lastPage bool) (shouldContinue bool)) error
+	ListContainerInstancesPages(input *ecs.ListContainerInstancesInput, fn func(p *ecs.ListContainerInstancesOutput, lastPage bool) (shouldContinue bool)) error
+	DescribeContainerInstances(input *ecs.DescribeContainerInstancesInput) (*ecs.DescribeContainerInstancesOutput, error)
+	UpdateContainerAgent(input *ecs.UpdateContainerAgentInput) (*ecs.UpdateContainerAgentOutput, error)
+	StopTask(input *ecs.StopTaskInput) (*ecs.StopTaskOutput, error)
 }
 
 func UpdateContainerImages(defs []*ecs.ContainerDefinition, images map[string]string) error {
@@ -67,7 +76,7 @@ func (d *dockerImageName) String() string {
 	return fmt.Sprintf("%s:%s", d.Image, d.Tag)
 }
 
-func getService(svc ecsInterface, cluster, service string) (*ecs.Service, error) {
+func getService(svc ECSAPI, cluster, service string) (*ecs.Service, error) {
 	resp, err := svc.DescribeServices(&ecs.DescribeServicesInput{
 		Services: []*string{aws.String(service)},
 		Cluster:  aws.String(cluster),
@@ -87,10 +96,27 @@ func getService(svc ecsInterface, cluster, service string) (*ecs.Service, error)
 	return resp.Services[0], nil
 }
 
-func PollUntilTaskDeployed(svc ecsInterface, cluster string, service string, task string, f func(e *ecs.ServiceEvent)) error {
+// DescribeService returns a single service in a cluster
+func DescribeService(svc ECSAPI, cluster, service string) (*ecs.Service, error) {
+	return getService(svc, cluster, service)
+}
+
+func PollUntilTaskDeployed(ctx context.Context, svc ECSAPI, cluster string, service string, task string, f func(e *ecs.ServiceEvent)) error {
+	return PollUntilTaskDeployedOrStopped(ctx, svc, cluster, service, task, f, nil)
+}
+
+// PollUntilTaskDeployedOrStopped waits for a task definition to be deployed like PollUntilTaskDeployed,
+// calling stop between polls and giving up with the error it returns, if any
+func PollUntilTaskDeployedOrStopped(ctx context.Context, svc ECSAPI, cluster string, service string, task string, f func(e *ecs.ServiceEvent), stop func() error) error {
 	lastSeen := time.Now().Add(-1 * time.Minute)
 
 	for {
+		if stop != nil {
+			if err := stop(); err != nil {
+				return err
+			}
+		}
+
 		service, err := getService(svc, cluster, service)
 		if err != nil {
 			return err
@@ -108,10 +134,46 @@ func PollUntilTaskDeployed(svc ecsInterface, cluster string, service string, tas
 			return nil
 		}
 
-		time.Sleep(ECS_POLL_INTERVAL)
+		if err = Sleep(ctx, ECS_POLL_INTERVAL, "service "+*service.ServiceName+" to deploy "+task, serviceState(service)); err != nil {
+			return err
+		}
 	}
 }
 
+// PollUntilServiceStable waits until a service has a single deployment running its desired count
+func PollUntilServiceStable(ctx context.Context, svc ECSAPI, cluster string, service string, f func(e *ecs.ServiceEvent)) error {
+	lastSeen := time.Now().Add(-1 * time.Minute)
+
+	for {
+		service, err := getService(svc, cluster, service)
+		if err != nil {
+			return err
+		}
+
+		for i := len(service.Events) - 1; i >= 0; i-- {
+			event := service.Events[i]
+			if event.CreatedAt.After(lastSeen) {
+				f(event)
+				lastSeen = *event.CreatedAt
+			}
+		}
+
+		if len(service.Deployments) == 1 && *service.RunningCount == *service.DesiredCount {
+			return nil
+		}
+
+		if err = Sleep(ctx, ECS_POLL_INTERVAL, "service "+*service.ServiceName+" to be stable", serviceState(service)); err != nil {
+			return err
+		}
+	}
+}
+
+// serviceState describes the deployments of a service, for when waiting for it gives up
+func serviceState(service *ecs.Service) string {
+	return fmt.Sprintf("it has %d deployments and %d of %d tasks running", len(service.Deployments),
+		aws.Int64Value(service.RunningCount), aws.Int64Value(service.DesiredCount))
+}
+
 func ExposedPorts(taskDef *ecs.TaskDefinition) map[string][]*ecs.PortMapping {
 	mappings := map[string][]*ecs.PortMapping{}
 
@@ -129,3 +191,129 @@ func ExposedPorts(taskDef *ecs.TaskDefinition) map[string][]*ecs.PortMapping {
 
 	return mappings
 }
+
+// ListServices returns all the services in a cluster
+func ListServices(svc ECSAPI, cluster string) ([]*ecs.Service, error) {
+	arns := []*string{}
+	err := svc.ListServicesPages(&ecs.ListServicesInput{
+		Cluster: aws.String(cluster),
+	}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+		arns = append(arns, page.ServiceArns...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := []*ecs.Service{}
+
+	// DescribeServices accepts at most 10 services at a time
+	for len(arns) > 0 {
+		n := len(arns)
+		if n > 10 {
+			n = 10
+		}
+		resp, err := svc.DescribeServices(&ecs.DescribeServicesInput{
+			Cluster:  aws.String(cluster),
+			Services: arns[:n],
+		})
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, resp.Services...)
+		arns = arns[n:]
+	}
+
+	return services, nil
+}
+
+// ListServiceTasks returns the tasks for a service with the given desired status
+func ListServiceTasks(svc ECSAPI, cluster, service, desiredStatus string) ([]*ecs.Task, error) {
+	arns := []*string{}
+	err := svc.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(service),
+		DesiredStatus: aws.String(desiredStatus),
+	}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		arns = append(arns, page.TaskArns...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	return describeTasks(svc, cluster, arns)
+}
+
+// ListInstanceTasks returns the running tasks on a container instance
+func ListInstanceTasks(svc ECSAPI, cluster, containerInstance string) ([]*ecs.Task, error) {
+	arns := []*string{}
+	err := svc.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:           aws.String(cluster),
+		ContainerInstance: aws.String(containerInstance),
+	}, func(page *ecs.ListTasksOutput, lastPage bool) bool {
+		arns = append(arns, page.TaskArns...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+	return describeTasks(svc, cluster, arns)
+}
+
+func describeTasks(svc ECSAPI, cluster string, arns []*string) ([]*ecs.Task, error) {
+	tasks := []*ecs.Task{}
+
+	// DescribeTasks accepts at most 100 tasks at a time
+	for len(arns) > 0 {
+		n := len(arns)
+		if n > 100 {
+			n = 100
+		}
+		resp, err := svc.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(cluster),
+			Tasks:   arns[:n],
+		})
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, resp.Tasks...)
+		arns = arns[n:]
+	}
+
+	return tasks, nil
+}
+
+// ListContainerInstances returns the container instances registered with a cluster
+func ListContainerInstances(svc ECSAPI, cluster string) ([]*ecs.ContainerInstance, error) {
+	arns := []*string{}
+	err := svc.ListContainerInstancesPages(&ecs.ListContainerInstancesInput{
+		Cluster: aws.String(cluster),
+	}, func(page *ecs.ListContainerInstancesOutput, lastPage bool) bool {
+		arns = append(arns, page.ContainerInstanceArns...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instances := []*ecs.ContainerInstance{}
+
+	// DescribeContainerInstances accepts at most 100 instances at a time
+	for len(arns) > 0 {
+		n := len(arns)
+		if n > 100 {
+			n = 100
+		}
+		resp, err := svc.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(cluster),
+			ContainerInstances: arns[:n],
+		})
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.ContainerInstances...)
+		arns = arns[n:]
+	}
+
+	return instances, nil
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 )
@@ -15,43 +16,85 @@ type NetworkOutputs struct {
 	Subnet3Private string
 }
 
-func FindClusterStack(svc cfnInterface, clusterName string) (*cloudformation.Stack, error) {
-	clusterStacks, err := FindStacksByOutputs(svc, map[string]string{
-		"StackType":  "ecs-former::ecs-stack",
-		"ECSCluster": clusterName,
-	})
+func FindClusterStack(svc CFNAPI, clusterName string) (*cloudformation.Stack, error) {
+	stack, err := LookupClusterStack(svc, clusterName)
 	if err != nil {
 		return nil, err
 	}
-	if len(clusterStacks) == 0 {
+	if stack == nil {
 		return nil, fmt.Errorf(
 			"Failed to find a cloudformation stack for cluster %q",
 			clusterName,
 		)
 	}
-	return clusterStacks[0], nil
+	return stack, nil
 }
 
-func FindServiceStack(svc cfnInterface, clusterName, taskFamily string) (*cloudformation.Stack, error) {
-	serviceStacks, err := FindStacksByOutputs(svc, map[string]string{
-		"StackType":  "ecs-former::ecs-service",
+// LookupClusterStack returns the stack for a cluster, or nil if it doesn't have one
+func LookupClusterStack(svc CFNAPI, clusterName string) (*cloudformation.Stack, error) {
+	clusterStacks, err := FindStacksByOutputs(svc, map[string]string{
+		"StackType":  "ecs-former::ecs-stack",
 		"ECSCluster": clusterName,
-		"TaskFamily": taskFamily,
+	})
+	if err != nil || len(clusterStacks) == 0 {
+		return nil, err
+	}
+	return clusterStacks[0], nil
+}
+
+// ClusterNames returns the names of the clusters that have cluster stacks
+func ClusterNames(svc CFNAPI) ([]string, error) {
+	stacks, err := FindStacksByOutputs(svc, map[string]string{
+		"StackType": "ecs-former::ecs-stack",
 	})
 	if err != nil {
 		return nil, err
 	}
-	if len(serviceStacks) == 0 {
+
+	names := []string{}
+	for _, stack := range stacks {
+		names = append(names, StackOutputMap(stack)["ECSCluster"])
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func FindServiceStack(svc CFNAPI, clusterName, taskFamily string) (*cloudformation.Stack, error) {
+	stack, err := LookupServiceStack(svc, clusterName, taskFamily)
+	if err != nil {
+		return nil, err
+	}
+	if stack == nil {
 		return nil, fmt.Errorf(
 			"Failed to find a cloudformation stack matching task %q, cluster %q",
 			taskFamily,
 			clusterName,
 		)
 	}
+	return stack, nil
+}
+
+// LookupServiceStack returns the stack for a service, or nil if it doesn't have one
+func LookupServiceStack(svc CFNAPI, clusterName, taskFamily string) (*cloudformation.Stack, error) {
+	serviceStacks, err := FindStacksByOutputs(svc, map[string]string{
+		"StackType":  "ecs-former::ecs-service",
+		"ECSCluster": clusterName,
+		"TaskFamily": taskFamily,
+	})
+	if err != nil || len(serviceStacks) == 0 {
+		return nil, err
+	}
 	return serviceStacks[0], nil
 }
 
-func FindNetworkStack(svc cfnInterface, clusterName string) (NetworkOutputs, error) {
+func FindServiceStacks(svc CFNAPI, clusterName string) ([]*cloudformation.Stack, error) {
+	return FindStacksByOutputs(svc, map[string]string{
+		"StackType":  "ecs-former::ecs-service",
+		"ECSCluster": clusterName,
+	})
+}
+
+func FindNetworkStack(svc CFNAPI, clusterName string) (NetworkOutputs, error) {
 	stackName := clusterName + "-network"
 
 	outputs, err := StackOutputs(svc, stackName)
@@ -69,15 +112,50 @@ func FindNetworkStack(svc cfnInterface, clusterName string) (NetworkOutputs, err
 	}, nil
 }
 
-func FindAllStacksForCluster(svc cfnInterface, clusterName string) ([]*cloudformation.Stack, error) {
+// LookupNetworkStack returns the network stack of a cluster, or nil if it doesn't have one.
+// Other errors, like throttling or missing permissions, are returned.
+func LookupNetworkStack(svc CFNAPI, clusterName string) (*NetworkOutputs, error) {
+	network, err := FindNetworkStack(svc, clusterName)
+	if isStackNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &network, nil
+}
+
+// FindAllStacksForCluster returns the stacks of a cluster and its network stack, failing
+// rather than returning some of them if either lookup fails
+func FindAllStacksForCluster(svc CFNAPI, clusterName string) ([]*cloudformation.Stack, error) {
 	stacks, err := FindStacksByOutputs(svc, map[string]string{
 		"ECSCluster": clusterName,
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	networkStack, err := FindStacksByName(svc, clusterName+"-network")
-	if err == nil {
-		stacks = append(stacks, networkStack...)
+	if err != nil {
+		return nil, err
 	}
 
-	return stacks, nil
+	return append(stacks, networkStack...), nil
+}
+
+// FindManagedStacks returns the stacks that ecsy created, by their tags or StackType output,
+// including those that failed to be created and have no outputs
+func FindManagedStacks(svc CFNAPI) ([]*cloudformation.Stack, error) {
+	stacks, err := findAllActiveStacks(svc)
+	if err != nil {
+		return nil, err
+	}
+	managed := []*cloudformation.Stack{}
+	for _, stack := range stacks {
+		_, tagged := StackTags(stack)[TagVersion]
+		_, typed := GetStackOutputByKey(stack, "StackType")
+		if tagged || typed {
+			managed = append(managed, stack)
+		}
+	}
+	return managed, nil
 }
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type elbInterface interface {
+	DescribeInstanceHealth(loadBalancerName string) ([]TargetHealth, error)
+	DescribeTargetHealth(targetGroupArn string) ([]TargetHealth, error)
+	DescribeLoadBalancers() ([]string, error)
+}
+
+// TargetHealth is the health of an instance behind a classic ELB or a target in a target group
+type TargetHealth struct {
+	ID          string
+	Port        string
+	State       string
+	Reason      string
+	Description string
+}
+
+type elbClient struct {
+	classic *queryClient
+	v2      *queryClient
+}
+
+func newELBClient(p client.ConfigProvider) *elbClient {
+	return &elbClient{
+		classic: newQueryClient(p, "elasticloadbalancing", "2012-06-01"),
+		v2:      newQueryClient(p, "elasticloadbalancing", "2015-12-01"),
+	}
+}
+
+func (c *elbClient) DescribeInstanceHealth(loadBalancerName string) ([]TargetHealth, error) {
+	var resp struct {
+		InstanceStates []struct {
+			InstanceId  string `xml:"InstanceId"`
+			State       string `xml:"State"`
+			ReasonCode  string `xml:"ReasonCode"`
+			Description string `xml:"Description"`
+		} `xml:"DescribeInstanceHealthResult>InstanceStates>member"`
+	}
+	err := c.classic.Call("DescribeInstanceHealth", url.Values{
+		"LoadBalancerName": {loadBalancerName},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	health := []TargetHealth{}
+	for _, s := range resp.InstanceStates {
+		health = append(health, TargetHealth{
+			ID:          s.InstanceId,
+			State:       s.State,
+			Reason:      s.ReasonCode,
+			Description: s.Description,
+		})
+	}
+	return health, nil
+}
+
+func (c *elbClient) DescribeTargetHealth(targetGroupArn string) ([]TargetHealth, error) {
+	var resp struct {
+		Descriptions []struct {
+			Id          string `xml:"Target>Id"`
+			Port        string `xml:"Target>Port"`
+			State       string `xml:"TargetHealth>State"`
+			Reason      string `xml:"TargetHealth>Reason"`
+			Description string `xml:"TargetHealth>Description"`
+		} `xml:"DescribeTargetHealthResult>TargetHealthDescriptions>member"`
+	}
+	err := c.v2.Call("DescribeTargetHealth", url.Values{
+		"TargetGroupArn": {targetGroupArn},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	health := []TargetHealth{}
+	for _, d := range resp.Descriptions {
+		health = append(health, TargetHealth{
+			ID:          d.Id,
+			Port:        d.Port,
+			State:       d.State,
+			Reason:      d.Reason,
+			Description: d.Description,
+		})
+	}
+	return health, nil
+}
+
+// DescribeLoadBalancers returns the names of the classic load balancers in the region
+func (c *elbClient) DescribeLoadBalancers() ([]string, error) {
+	names := []string{}
+	params := url.Values{}
+	for {
+		var resp struct {
+			Names      []string `xml:"DescribeLoadBalancersResult>LoadBalancerDescriptions>member>LoadBalancerName"`
+			NextMarker string   `xml:"DescribeLoadBalancersResult>NextMarker"`
+		}
+		if err := c.classic.Call("DescribeLoadBalancers", params, &resp); err != nil {
+			return nil, err
+		}
+		names = append(names, resp.Names...)
+		if resp.NextMarker == "" {
+			return names, nil
+		}
+		params.Set("Marker", resp.NextMarker)
+	}
+}
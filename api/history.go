@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// correlationWindow is how long after an API call changes are attributed to whoever made it
+const correlationWindow = 15 * time.Minute
+
+// Change is something that changed in a cluster, from stack events, cloudtrail, service
+// deployments or the audit log
+type Change struct {
+	Time        time.Time
+	Source      string
+	Principal   string
+	Resource    string
+	Description string
+}
+
+func (c Change) String() string {
+	principal := c.Principal
+	if principal == "" {
+		principal = "-"
+	}
+	return fmt.Sprintf("%s  %-14s %-20s %s: %s",
+		c.Time.Local().Format("2006-01-02 15:04:05"), c.Source, c.Resource, principal, c.Description)
+}
+
+// StackChanges returns the completed and failed changes to stacks and their resources since a time
+func StackChanges(svc CFNAPI, stacks []*cloudformation.Stack, since time.Time) ([]Change, error) {
+	changes := []Change{}
+	for _, stack := range stacks {
+		name := aws.StringValue(stack.StackName)
+		events, err := allStackEvents(svc, name, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			status := aws.StringValue(e.ResourceStatus)
+			if aws.StringValue(e.LogicalResourceId) != name && strings.HasSuffix(status, "_IN_PROGRESS") {
+				continue
+			}
+			changes = append(changes, Change{
+				Time:        aws.TimeValue(e.Timestamp),
+				Source:      "cloudformation",
+				Resource:    name,
+				Description: FormatStackEvent(e),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// TrailChanges returns the calls that changed a cluster's services or stacks since a time
+func TrailChanges(svc cloudtrailInterface, cluster string, stackNames []string, since time.Time) ([]Change, error) {
+	changes := []Change{}
+
+	events, err := svc.LookupEvents("ecs.amazonaws.com", since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if e.ReadOnly || e.Parameter("cluster") != cluster {
+			continue
+		}
+		resource := e.Parameter("service")
+		if resource == "" {
+			resource = cluster
+		}
+		changes = append(changes, Change{
+			Time:        e.Time,
+			Source:      "cloudtrail",
+			Principal:   e.Principal,
+			Resource:    resource,
+			Description: e.Name,
+		})
+	}
+
+	events, err = svc.LookupEvents("cloudformation.amazonaws.com", since, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		name := e.Parameter("stackName")
+		if e.ReadOnly || !containsName(stackNames, name) {
+			continue
+		}
+		changes = append(changes, Change{
+			Time:        e.Time,
+			Source:      "cloudtrail",
+			Principal:   e.Principal,
+			Resource:    name,
+			Description: e.Name,
+		})
+	}
+	return changes, nil
+}
+
+// DeploymentChanges returns the deployments of a cluster's services that started since a time
+func DeploymentChanges(svc ECSAPI, cluster string, since time.Time) ([]Change, error) {
+	services, err := ListServices(svc, cluster)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	for _, service := range services {
+		for _, d := range service.Deployments {
+			if !aws.TimeValue(d.CreatedAt).After(since) {
+				continue
+			}
+			changes = append(changes, Change{
+				Time:     aws.TimeValue(d.CreatedAt),
+				Source:   "deployment",
+				Resource: aws.StringValue(service.ServiceName),
+				Description: fmt.Sprintf("%s %s, %d of %d running",
+					strings.ToLower(aws.StringValue(d.Status)), lastARNSegment(aws.StringValue(d.TaskDefinition)),
+					aws.Int64Value(d.RunningCount), aws.Int64Value(d.DesiredCount)),
+			})
+		}
+	}
+	return changes, nil
+}
+
+// AuditChanges returns the ecsy commands run on a cluster since a time, none if there's no audit table
+func AuditChanges(svc dynamodbInterface, cluster string, since time.Time) ([]Change, error) {
+	records, err := ListAuditRecords(svc, cluster, since, 0)
+	if err == ErrTableNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	for _, r := range records {
+		description := strings.Join(r.Args, " ")
+		if r.Error != "" {
+			description += " (failed: " + r.Error + ")"
+		}
+		changes = append(changes, Change{
+			Time:        r.RecordedAt,
+			Source:      "ecsy",
+			Principal:   r.Caller,
+			Resource:    cluster,
+			Description: description,
+		})
+	}
+	return changes, nil
+}
+
+// Timeline sorts changes oldest first and attributes those without a principal, like stack
+// events and deployments, to whoever last made a call that changed the same resource
+func Timeline(changes []Change) []Change {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Time.Before(changes[j].Time)
+	})
+
+	last := map[string]Change{}
+	for i, c := range changes {
+		if c.Principal != "" {
+			last[c.Resource] = c
+			continue
+		}
+		if cause, ok := last[c.Resource]; ok && c.Time.Sub(cause.Time) <= correlationWindow {
+			changes[i].Principal = cause.Principal
+		}
+	}
+	return changes
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
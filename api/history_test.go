@@ -0,0 +1,42 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimelineAttributesChangesToCalls(t *testing.T) {
+	start := time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC)
+	changes := Timeline([]Change{
+		{Time: start.Add(2 * time.Minute), Source: "deployment", Resource: "web"},
+		{Time: start.Add(time.Hour), Source: "deployment", Resource: "web"},
+		{Time: start.Add(time.Minute), Source: "cloudtrail", Resource: "web", Principal: "arn:aws:iam::123456789012:user/lachlan"},
+		{Time: start.Add(3 * time.Minute), Source: "cloudformation", Resource: "example-worker"},
+	})
+
+	if changes[0].Source != "cloudtrail" {
+		t.Fatalf("Expected changes oldest first, got %v", changes)
+	}
+	if changes[1].Principal != "arn:aws:iam::123456789012:user/lachlan" {
+		t.Fatalf("Expected the deployment to be attributed to the call before it, got %q", changes[1].Principal)
+	}
+	if changes[2].Principal != "" {
+		t.Fatalf("Expected a change to another resource to be unattributed, got %q", changes[2].Principal)
+	}
+	if changes[3].Principal != "" {
+		t.Fatalf("Expected a change long after the call to be unattributed, got %q", changes[3].Principal)
+	}
+}
+
+func TestTrailEventParameterShortensArns(t *testing.T) {
+	e := TrailEvent{RequestParameters: map[string]interface{}{
+		"cluster":   "arn:aws:ecs:us-east-1:123456789012:cluster/example",
+		"service":   "arn:aws:ecs:us-east-1:123456789012:service/example/web",
+		"stackName": "arn:aws:cloudformation:us-east-1:123456789012:stack/example-web/1a2b3c",
+	}}
+	for key, expected := range map[string]string{"cluster": "example", "service": "web", "stackName": "example-web"} {
+		if got := e.Parameter(key); got != expected {
+			t.Errorf("Expected %s to be %q, got %q", key, expected, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type iamInterface interface {
+	GetRole(name string) (*Role, error)
+	CreateRole(name, assumeRolePolicy string) (*Role, error)
+	AttachRolePolicy(roleName, policyArn string) error
+	CreateServiceLinkedRole(serviceName string) error
+	SimulatePrincipalPolicy(principalArn string, actions []string) (map[string]string, error)
+}
+
+var ErrNoSuchEntity = errors.New("No such IAM entity")
+
+type Role struct {
+	Name string `xml:"RoleName"`
+	Arn  string `xml:"Arn"`
+}
+
+type iamClient struct {
+	*queryClient
+}
+
+func newIAMClient(p client.ConfigProvider) *iamClient {
+	return &iamClient{newQueryClient(p, "iam", "2010-05-08")}
+}
+
+func (c *iamClient) GetRole(name string) (*Role, error) {
+	var resp struct {
+		Role Role `xml:"GetRoleResult>Role"`
+	}
+	err := c.Call("GetRole", url.Values{"RoleName": {name}}, &resp)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchEntity" {
+		return nil, ErrNoSuchEntity
+	} else if err != nil {
+		return nil, err
+	}
+	return &resp.Role, nil
+}
+
+func (c *iamClient) CreateRole(name, assumeRolePolicy string) (*Role, error) {
+	var resp struct {
+		Role Role `xml:"CreateRoleResult>Role"`
+	}
+	err := c.Call("CreateRole", url.Values{
+		"RoleName":                 {name},
+		"AssumeRolePolicyDocument": {assumeRolePolicy},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Role, nil
+}
+
+func (c *iamClient) AttachRolePolicy(roleName, policyArn string) error {
+	return c.Call("AttachRolePolicy", url.Values{
+		"RoleName":  {roleName},
+		"PolicyArn": {policyArn},
+	}, nil)
+}
+
+func (c *iamClient) CreateServiceLinkedRole(serviceName string) error {
+	return c.Call("CreateServiceLinkedRole", url.Values{
+		"AWSServiceName": {serviceName},
+	}, nil)
+}
+
+// SimulatePrincipalPolicy returns the decision for each action, one of allowed, explicitDeny or implicitDeny
+func (c *iamClient) SimulatePrincipalPolicy(principalArn string, actions []string) (map[string]string, error) {
+	decisions := map[string]string{}
+	params := url.Values{"PolicySourceArn": {principalArn}}
+	addMembers(params, "ActionNames", actions)
+
+	for {
+		var resp struct {
+			Results []struct {
+				Action   string `xml:"EvalActionName"`
+				Decision string `xml:"EvalDecision"`
+			} `xml:"SimulatePrincipalPolicyResult>EvaluationResults>member"`
+			IsTruncated bool   `xml:"SimulatePrincipalPolicyResult>IsTruncated"`
+			Marker      string `xml:"SimulatePrincipalPolicyResult>Marker"`
+		}
+		if err := c.Call("SimulatePrincipalPolicy", params, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Results {
+			decisions[r.Action] = r.Decision
+		}
+		if !resp.IsTruncated {
+			return decisions, nil
+		}
+		params.Set("Marker", resp.Marker)
+	}
+}
+
+// PrincipalArn converts the arn returned by sts for an assumed role session into the arn
+// of the role itself, which is what policy simulation expects
+func PrincipalArn(callerArn string) string {
+	parts := strings.Split(callerArn, ":")
+	if len(parts) != 6 || parts[2] != "sts" || !strings.HasPrefix(parts[5], "assumed-role/") {
+		return callerArn
+	}
+	role := strings.Split(strings.TrimPrefix(parts[5], "assumed-role/"), "/")[0]
+	return "arn:" + parts[1] + ":iam::" + parts[4] + ":role/" + role
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type instanceConnectInterface interface {
+	SendSSHPublicKey(instanceID, osUser, availabilityZone, publicKey string) error
+}
+
+type instanceConnectClient struct {
+	*jsonClient
+}
+
+func newInstanceConnectClient(p client.ConfigProvider) *instanceConnectClient {
+	return &instanceConnectClient{newJSONClient(p, "ec2-instance-connect", "AWSEC2InstanceConnectService", "1.1")}
+}
+
+// SendSSHPublicKey pushes a public key to an instance, which accepts it for the next 60 seconds
+func (c *instanceConnectClient) SendSSHPublicKey(instanceID, osUser, availabilityZone, publicKey string) error {
+	var resp struct {
+		Success bool `json:"Success"`
+	}
+	return c.Call("SendSSHPublicKey", &struct {
+		InstanceID       string `json:"InstanceId"`
+		InstanceOSUser   string `json:"InstanceOSUser"`
+		AvailabilityZone string `json:"AvailabilityZone,omitempty"`
+		SSHPublicKey     string `json:"SSHPublicKey"`
+	}{instanceID, osUser, availabilityZone, publicKey}, &resp)
+}
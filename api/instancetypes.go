@@ -0,0 +1,223 @@
+package api
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lox/ecsy/failure"
+)
+
+// InstanceResources are the cpu units and memory in MiB an instance type provides for tasks
+type InstanceResources struct {
+	CPU    int64
+	Memory int64
+}
+
+// instanceTypes are the instance types ecsy knows the resources of, ECS gives each vCPU 1024 cpu units
+var instanceTypes = map[string]InstanceResources{
+	"t2.nano":     {1024, 512},
+	"t2.micro":    {1024, 1024},
+	"t2.small":    {1024, 2048},
+	"t2.medium":   {2048, 4096},
+	"t2.large":    {2048, 8192},
+	"m3.medium":   {1024, 3840},
+	"m3.large":    {2048, 7680},
+	"m3.xlarge":   {4096, 15360},
+	"m3.2xlarge":  {8192, 30720},
+	"m4.large":    {2048, 8192},
+	"m4.xlarge":   {4096, 16384},
+	"m4.2xlarge":  {8192, 32768},
+	"m4.4xlarge":  {16384, 65536},
+	"m4.10xlarge": {40960, 163840},
+	"c4.large":    {2048, 3840},
+	"c4.xlarge":   {4096, 7680},
+	"c4.2xlarge":  {8192, 15360},
+	"c4.4xlarge":  {16384, 30720},
+	"c4.8xlarge":  {36864, 61440},
+	"c3.large":    {2048, 3840},
+	"c3.xlarge":   {4096, 7680},
+	"c3.2xlarge":  {8192, 15360},
+	"c3.4xlarge":  {16384, 30720},
+	"c3.8xlarge":  {32768, 61440},
+	"r3.large":    {2048, 15616},
+	"r3.xlarge":   {4096, 31232},
+	"r3.2xlarge":  {8192, 62464},
+	"r3.4xlarge":  {16384, 124928},
+	"r3.8xlarge":  {32768, 249856},
+	"i2.xlarge":   {4096, 31232},
+	"i2.2xlarge":  {8192, 62464},
+	"i2.4xlarge":  {16384, 124928},
+	"i2.8xlarge":  {32768, 249856},
+	"t3.nano":     {2048, 512},
+	"t3.micro":    {2048, 1024},
+	"t3.small":    {2048, 2048},
+	"t3.medium":   {2048, 4096},
+	"t3.large":    {2048, 8192},
+	"t3.xlarge":   {4096, 16384},
+	"t3.2xlarge":  {8192, 32768},
+	"t3a.nano":    {2048, 512},
+	"t3a.micro":   {2048, 1024},
+	"t3a.small":   {2048, 2048},
+	"t3a.medium":  {2048, 4096},
+	"t3a.large":   {2048, 8192},
+	"t3a.xlarge":  {4096, 16384},
+	"t3a.2xlarge": {8192, 32768},
+	"t4g.nano":    {2048, 512},
+	"t4g.micro":   {2048, 1024},
+	"t4g.small":   {2048, 2048},
+	"t4g.medium":  {2048, 4096},
+	"t4g.large":   {2048, 8192},
+	"t4g.xlarge":  {4096, 16384},
+	"t4g.2xlarge": {8192, 32768},
+	"m5.large":    {2048, 8192},
+	"m5.xlarge":   {4096, 16384},
+	"m5.2xlarge":  {8192, 32768},
+	"m5.4xlarge":  {16384, 65536},
+	"m5.8xlarge":  {32768, 131072},
+	"m6i.large":   {2048, 8192},
+	"m6i.xlarge":  {4096, 16384},
+	"m6i.2xlarge": {8192, 32768},
+	"m6i.4xlarge": {16384, 65536},
+	"m6i.8xlarge": {32768, 131072},
+	"m6g.large":   {2048, 8192},
+	"m6g.xlarge":  {4096, 16384},
+	"m6g.2xlarge": {8192, 32768},
+	"m6g.4xlarge": {16384, 65536},
+	"m6g.8xlarge": {32768, 131072},
+	"c5.large":    {2048, 4096},
+	"c5.xlarge":   {4096, 8192},
+	"c5.2xlarge":  {8192, 16384},
+	"c5.4xlarge":  {16384, 32768},
+	"c6i.large":   {2048, 4096},
+	"c6i.xlarge":  {4096, 8192},
+	"c6i.2xlarge": {8192, 16384},
+	"c6i.4xlarge": {16384, 32768},
+	"c6g.large":   {2048, 4096},
+	"c6g.xlarge":  {4096, 8192},
+	"c6g.2xlarge": {8192, 16384},
+	"c6g.4xlarge": {16384, 32768},
+	"r5.large":    {2048, 16384},
+	"r5.xlarge":   {4096, 32768},
+	"r5.2xlarge":  {8192, 65536},
+	"r5.4xlarge":  {16384, 131072},
+	"r6i.large":   {2048, 16384},
+	"r6i.xlarge":  {4096, 32768},
+	"r6i.2xlarge": {8192, 65536},
+	"r6i.4xlarge": {16384, 131072},
+	"r6g.large":   {2048, 16384},
+	"r6g.xlarge":  {4096, 32768},
+	"r6g.2xlarge": {8192, 65536},
+	"r6g.4xlarge": {16384, 131072},
+}
+
+// InstanceTypeResources returns the resources of an instance type, or false if it isn't known
+func InstanceTypeResources(instanceType string) (InstanceResources, bool) {
+	r, ok := instanceTypes[instanceType]
+	return r, ok
+}
+
+// previousGenerations are the instance families that AWS no longer lists as current generation
+var previousGenerations = map[string]bool{
+	"t1": true, "m1": true, "m2": true, "m3": true, "c1": true, "c3": true, "r3": true, "i2": true,
+}
+
+func instanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// IsBurstable returns whether an instance type runs on cpu credits, which production
+// workloads can exhaust
+func IsBurstable(instanceType string) bool {
+	return strings.HasPrefix(instanceFamily(instanceType), "t")
+}
+
+// ValidateInstanceType checks that the main instances of a cluster can be an instance type:
+// one of the current generation, that runs the cluster's x86_64 ECS optimized AMI, and that's
+// offered in the region unless offered is empty
+func ValidateInstanceType(instanceType string, offered []string) error {
+	if _, ok := instanceTypes[instanceType]; !ok {
+		return failure.Errorf(failure.Validation, "Unknown instance type %s, use --recommend to pick one", instanceType)
+	}
+	if previousGenerations[instanceFamily(instanceType)] {
+		return failure.Errorf(failure.Validation, "%s is a previous generation instance type, use --recommend to pick a current one", instanceType)
+	}
+	if InstanceArchitecture(instanceType) != "amd64" {
+		return failure.Errorf(failure.Validation, "%s is an arm64 instance type, which needs an instance group with an arm64 AMI", instanceType)
+	}
+	if len(offered) > 0 && !offeredIn(instanceType, offered) {
+		return failure.Errorf(failure.Validation, "%s isn't offered in this region", instanceType)
+	}
+	return nil
+}
+
+func offeredIn(instanceType string, offered []string) bool {
+	for _, o := range offered {
+		if o == instanceType {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceTypes returns the instance types the main instances of a cluster can be, sorted
+// by name
+func InstanceTypes() []string {
+	names := []string{}
+	for name := range instanceTypes {
+		if ValidateInstanceType(name, nil) == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RecommendInstanceTypes returns the instance types that give count instances at least cpu
+// units and memory MiB between them, those with the least to spare and then the newest first.
+// Burstable types are left out unless burstable is set.
+func RecommendInstanceTypes(cpu, memory int64, count int, offered []string, burstable bool) []string {
+	if count < 1 {
+		count = 1
+	}
+	perCPU := (cpu + int64(count) - 1) / int64(count)
+	perMemory := (memory + int64(count) - 1) / int64(count)
+
+	fits := []string{}
+	for _, name := range InstanceTypes() {
+		r := instanceTypes[name]
+		if r.CPU < perCPU || r.Memory < perMemory || (IsBurstable(name) && !burstable) {
+			continue
+		}
+		if len(offered) > 0 && !offeredIn(name, offered) {
+			continue
+		}
+		fits = append(fits, name)
+	}
+
+	sort.SliceStable(fits, func(i, j int) bool {
+		a, b := instanceTypes[fits[i]], instanceTypes[fits[j]]
+		if a.CPU != b.CPU {
+			return a.CPU < b.CPU
+		} else if a.Memory != b.Memory {
+			return a.Memory < b.Memory
+		} else if IsBurstable(fits[i]) != IsBurstable(fits[j]) {
+			return !IsBurstable(fits[i])
+		}
+		// newer generations cost less for the same resources
+		return instanceGeneration(fits[i]) > instanceGeneration(fits[j])
+	})
+	return fits
+}
+
+// instanceGeneration is the number in an instance type's family, like 6 for m6i.large
+func instanceGeneration(instanceType string) int {
+	generation := 0
+	for _, r := range instanceFamily(instanceType) {
+		if r >= '0' && r <= '9' {
+			generation = generation*10 + int(r-'0')
+		} else if generation > 0 {
+			break
+		}
+	}
+	return generation
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateInstanceType(t *testing.T) {
+	offered := []string{"m5.large", "t3.micro", "m6g.large"}
+	for instanceType, valid := range map[string]bool{
+		"m5.large":  true,
+		"t3.micro":  true,
+		"m6i.large": false,
+		"m3.medium": false,
+		"m6g.large": false,
+		"x9.huge":   false,
+	} {
+		if err := ValidateInstanceType(instanceType, offered); (err == nil) != valid {
+			t.Fatalf("Expected %s valid to be %v, got %v", instanceType, valid, err)
+		}
+	}
+	if err := ValidateInstanceType("m6i.large", nil); err != nil {
+		t.Fatalf("Expected types to be valid when offerings aren't known, got %v", err)
+	}
+}
+
+func TestRecommendInstanceTypes(t *testing.T) {
+	offered := []string{"t3.large", "m5.large", "m5.xlarge", "c5.xlarge", "r5.large"}
+
+	// 8 vcpus and 32 GiB over 4 instances is 2 vcpus and 8 GiB each
+	fits := RecommendInstanceTypes(8*1024, 32*1024, 4, offered, false)
+	if expected := []string{"m5.large", "r5.large", "c5.xlarge", "m5.xlarge"}; !reflect.DeepEqual(fits, expected) {
+		t.Fatalf("Expected %v, got %v", expected, fits)
+	}
+
+	fits = RecommendInstanceTypes(8*1024, 32*1024, 4, offered, true)
+	if fits[0] != "m5.large" || len(fits) != 5 {
+		t.Fatalf("Expected burstable types to fit too, got %v", fits)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// LockHeldError is returned when a lock is already held by someone else
+type LockHeldError struct {
+	Key      string
+	Owner    string
+	Acquired time.Time
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("%s is locked by %s since %s, use --force-unlock if it is stale",
+		e.Key, e.Owner, e.Acquired.Format(time.RFC3339))
+}
+
+// AcquireLock takes a lock on key in a dynamodb table, expiring after ttl so that a
+// crashed deploy doesn't hold it forever
+func AcquireLock(svc dynamodbInterface, table, key, owner string, ttl time.Duration) error {
+	now := time.Now()
+
+	err := svc.PutItem(&PutItemInput{
+		TableName: table,
+		Item: map[string]AttributeValue{
+			"LockKey":    {S: aws.String(key)},
+			"LockOwner":  {S: aws.String(owner)},
+			"AcquiredAt": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+			"ExpiresAt":  {N: aws.String(strconv.FormatInt(now.Add(ttl).Unix(), 10))},
+		},
+		ConditionExpression: "attribute_not_exists(LockKey) OR ExpiresAt < :now",
+		ExpressionAttributeValues: map[string]AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ConditionalCheckFailedException" {
+		item, getErr := svc.GetItem(&GetItemInput{
+			TableName:      table,
+			Key:            map[string]AttributeValue{"LockKey": {S: aws.String(key)}},
+			ConsistentRead: true,
+		})
+		if getErr != nil {
+			return getErr
+		}
+		held := &LockHeldError{Key: key}
+		if v := item["LockOwner"].S; v != nil {
+			held.Owner = *v
+		}
+		if v := item["AcquiredAt"].N; v != nil {
+			if secs, err := strconv.ParseInt(*v, 10, 64); err == nil {
+				held.Acquired = time.Unix(secs, 0)
+			}
+		}
+		return held
+	}
+	return err
+}
+
+// ReleaseLock releases a lock, provided that it's still held by owner
+func ReleaseLock(svc dynamodbInterface, table, key, owner string) error {
+	err := svc.DeleteItem(&DeleteItemInput{
+		TableName:           table,
+		Key:                 map[string]AttributeValue{"LockKey": {S: aws.String(key)}},
+		ConditionExpression: "LockOwner = :owner",
+		ExpressionAttributeValues: map[string]AttributeValue{
+			":owner": {S: aws.String(owner)},
+		},
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ConditionalCheckFailedException" {
+		return fmt.Errorf("Lock on %s is no longer held by %s", key, owner)
+	}
+	return err
+}
+
+// RenewLock pushes back when a lock expires to ttl from now, provided that it's still held
+// by owner, so that a lock outlives its ttl whilst its owner is running
+func RenewLock(svc dynamodbInterface, table, key, owner string, ttl time.Duration) error {
+	err := svc.UpdateItem(&UpdateItemInput{
+		TableName:           table,
+		Key:                 map[string]AttributeValue{"LockKey": {S: aws.String(key)}},
+		UpdateExpression:    "SET ExpiresAt = :expires",
+		ConditionExpression: "LockOwner = :owner",
+		ExpressionAttributeValues: map[string]AttributeValue{
+			":owner":   {S: aws.String(owner)},
+			":expires": {N: aws.String(strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))},
+		},
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ConditionalCheckFailedException" {
+		return fmt.Errorf("Lock on %s is no longer held by %s", key, owner)
+	}
+	return err
+}
+
+// ForceUnlock removes a lock regardless of who holds it
+func ForceUnlock(svc dynamodbInterface, table, key string) error {
+	return svc.DeleteItem(&DeleteItemInput{
+		TableName: table,
+		Key:       map[string]AttributeValue{"LockKey": {S: aws.String(key)}},
+	})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// lockTable holds a single lock, checking the owner of updates to it like the condition
+// expressions do
+type lockTable struct {
+	dynamodbInterface
+	item map[string]AttributeValue
+}
+
+func (t *lockTable) UpdateItem(input *UpdateItemInput) error {
+	if t.item == nil || aws.StringValue(t.item["LockOwner"].S) != aws.StringValue(input.ExpressionAttributeValues[":owner"].S) {
+		return awserr.New("ConditionalCheckFailedException", "The conditional request failed", nil)
+	}
+	t.item["ExpiresAt"] = input.ExpressionAttributeValues[":expires"]
+	return nil
+}
+
+func TestRenewLock(t *testing.T) {
+	table := &lockTable{item: map[string]AttributeValue{
+		"LockKey":   {S: aws.String("cluster/web")},
+		"LockOwner": {S: aws.String("deployer")},
+		"ExpiresAt": {N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))},
+	}}
+
+	if err := RenewLock(table, "locks", "cluster/web", "deployer", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	expires, err := strconv.ParseInt(aws.StringValue(table.item["ExpiresAt"].N), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(time.Unix(expires, 0)) < 59*time.Minute {
+		t.Fatalf("Expected the lock to expire in an hour, got %v", time.Unix(expires, 0))
+	}
+
+	if err = RenewLock(table, "locks", "cluster/web", "someone else", time.Hour); err == nil {
+		t.Fatal("Expected renewing a lock held by someone else to fail")
+	}
+}
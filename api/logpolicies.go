@@ -0,0 +1,41 @@
+package api
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+// The vendored cloudwatchlogs client predates resource policies
+type logPolicyInterface interface {
+	DescribeResourcePolicies() ([]LogResourcePolicy, error)
+	PutResourcePolicy(name, document string) error
+}
+
+type LogResourcePolicy struct {
+	PolicyName     string `json:"policyName"`
+	PolicyDocument string `json:"policyDocument"`
+}
+
+type logPolicyClient struct {
+	*jsonClient
+}
+
+func newLogPolicyClient(p client.ConfigProvider) *logPolicyClient {
+	return &logPolicyClient{newJSONClient(p, "logs", "Logs_20140328", "1.1")}
+}
+
+func (c *logPolicyClient) DescribeResourcePolicies() ([]LogResourcePolicy, error) {
+	var resp struct {
+		ResourcePolicies []LogResourcePolicy `json:"resourcePolicies"`
+	}
+	if err := c.Call("DescribeResourcePolicies", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.ResourcePolicies, nil
+}
+
+func (c *logPolicyClient) PutResourcePolicy(name, document string) error {
+	return c.Call("PutResourcePolicy", &LogResourcePolicy{
+		PolicyName:     name,
+		PolicyDocument: document,
+	}, nil)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// ClusterOverview summarizes the health of a cluster, for checking many clusters at once
+type ClusterOverview struct {
+	Cluster           string
+	Instances         int
+	ActiveInstances   int
+	Services          int
+	UnhealthyServices []string
+	PendingTasks      int64
+	OldestAMI         time.Time
+}
+
+// SummarizeCluster returns the overview of a cluster, its instances and services
+func SummarizeCluster(svc ECSAPI, ec2 EC2API, cluster string) (ClusterOverview, error) {
+	o := ClusterOverview{Cluster: cluster}
+
+	instances, err := ListContainerInstances(svc, cluster)
+	if err != nil {
+		return o, err
+	}
+	amis := []string{}
+	for _, ci := range instances {
+		o.Instances++
+		if aws.StringValue(ci.Status) == ContainerInstanceActive && aws.BoolValue(ci.AgentConnected) {
+			o.ActiveInstances++
+		}
+		for _, a := range ci.Attributes {
+			if aws.StringValue(a.Name) == "ecs.ami-id" && !containsName(amis, aws.StringValue(a.Value)) {
+				amis = append(amis, aws.StringValue(a.Value))
+			}
+		}
+	}
+	if len(amis) > 0 {
+		dates, err := ec2.DescribeImageCreationDates(amis)
+		if err != nil {
+			return o, err
+		}
+		for _, created := range dates {
+			if o.OldestAMI.IsZero() || created.Before(o.OldestAMI) {
+				o.OldestAMI = created
+			}
+		}
+	}
+
+	services, err := ListServices(svc, cluster)
+	if err != nil {
+		return o, err
+	}
+	for _, s := range services {
+		o.Services++
+		o.PendingTasks += aws.Int64Value(s.PendingCount)
+		if aws.Int64Value(s.RunningCount) < aws.Int64Value(s.DesiredCount) {
+			o.UnhealthyServices = append(o.UnhealthyServices, aws.StringValue(s.ServiceName))
+		}
+	}
+	return o, nil
+}
+
+// IsStackFailed returns whether a stack's last operation failed or was rolled back
+func IsStackFailed(stack *cloudformation.Stack) bool {
+	status := aws.StringValue(stack.StackStatus)
+	return strings.HasSuffix(status, "_FAILED") || strings.HasSuffix(status, "ROLLBACK_COMPLETE")
+}
+
+// FailedStacks returns the stacks ecsy manages whose last operation failed
+func FailedStacks(svc CFNAPI) ([]*cloudformation.Stack, error) {
+	stacks, err := FindManagedStacks(svc)
+	if err != nil {
+		return nil, err
+	}
+	failed := []*cloudformation.Stack{}
+	for _, stack := range stacks {
+		if IsStackFailed(stack) {
+			failed = append(failed, stack)
+		}
+	}
+	return failed, nil
+}
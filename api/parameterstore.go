@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/templates"
+)
+
+// Parameter store references, like ssm:/myapp/prod/url, or ssm-secure:/myapp/prod/token for
+// SecureString parameters that are decrypted
+const (
+	ParameterPrefix       = "ssm:"
+	SecureParameterPrefix = "ssm-secure:"
+)
+
+// IsParameterReference returns whether a value refers to a parameter store parameter
+func IsParameterReference(value string) bool {
+	return strings.HasPrefix(value, ParameterPrefix) || strings.HasPrefix(value, SecureParameterPrefix)
+}
+
+// ParameterResolver resolves parameter store references, looking each parameter up once
+type ParameterResolver struct {
+	svc    ssmInterface
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func NewParameterResolver(svc ssmInterface) *ParameterResolver {
+	return &ParameterResolver{svc: svc, values: map[string]string{}}
+}
+
+// Resolve returns the value of a parameter store reference, other values are returned as is
+func (r *ParameterResolver) Resolve(value string) (string, error) {
+	if !IsParameterReference(value) {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if resolved, ok := r.values[value]; ok {
+		return resolved, nil
+	}
+
+	decrypt := strings.HasPrefix(value, SecureParameterPrefix)
+	name := strings.TrimPrefix(strings.TrimPrefix(value, SecureParameterPrefix), ParameterPrefix)
+	resolved, err := r.svc.GetParameter(name, decrypt)
+	if err != nil {
+		return "", fmt.Errorf("Failed to resolve %s: %v", value, err)
+	}
+	r.values[value] = resolved
+	return resolved, nil
+}
+
+// resolveStackParameters resolves the references in stack parameters. Decrypted values
+// are only allowed in parameters that the template marks NoEcho, as any other parameter
+// shows its value to anyone that can describe the stack.
+func (r *ParameterResolver) resolveStackParameters(params []*cloudformation.Parameter, template func() (string, error)) ([]*cloudformation.Parameter, error) {
+	var noEcho map[string]bool
+	resolved := make([]*cloudformation.Parameter, len(params))
+	for idx, p := range params {
+		copied := *p
+		if p.ParameterValue != nil {
+			if strings.HasPrefix(*p.ParameterValue, SecureParameterPrefix) {
+				if noEcho == nil {
+					body, err := template()
+					if err != nil {
+						return nil, err
+					}
+					noEcho = templates.NoEchoParameters(body)
+				}
+				if !noEcho[aws.StringValue(p.ParameterKey)] {
+					return nil, fmt.Errorf("Parameter %s refers to %s, but isn't NoEcho so its value would be shown in the stack",
+						aws.StringValue(p.ParameterKey), *p.ParameterValue)
+				}
+			}
+			value, err := r.Resolve(*p.ParameterValue)
+			if err != nil {
+				return nil, fmt.Errorf("Parameter %s: %v", aws.StringValue(p.ParameterKey), err)
+			}
+			copied.ParameterValue = aws.String(value)
+		}
+		resolved[idx] = &copied
+	}
+	return resolved, nil
+}
+
+// parameterStoreCfn resolves parameter store references in the parameters of stacks
+// before they are created or updated
+type parameterStoreCfn struct {
+	CFNAPI
+	resolver *ParameterResolver
+}
+
+// template returns a function that returns the template a stack is being changed to
+func (c *parameterStoreCfn) template(stackName, body *string, usePrevious *bool) func() (string, error) {
+	return func() (string, error) {
+		if aws.BoolValue(usePrevious) {
+			return DeployedTemplate(c.CFNAPI, aws.StringValue(stackName))
+		}
+		return aws.StringValue(body), nil
+	}
+}
+
+func (c *parameterStoreCfn) CreateStack(input *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	copied := *input
+	params, err := c.resolver.resolveStackParameters(input.Parameters, c.template(input.StackName, input.TemplateBody, nil))
+	if err != nil {
+		return nil, err
+	}
+	copied.Parameters = params
+	return c.CFNAPI.CreateStack(&copied)
+}
+
+func (c *parameterStoreCfn) UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	copied := *input
+	params, err := c.resolver.resolveStackParameters(input.Parameters, c.template(input.StackName, input.TemplateBody, input.UsePreviousTemplate))
+	if err != nil {
+		return nil, err
+	}
+	copied.Parameters = params
+	return c.CFNAPI.UpdateStack(&copied)
+}
+
+func (c *parameterStoreCfn) CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+	copied := *input
+	params, err := c.resolver.resolveStackParameters(input.Parameters, c.template(input.StackName, input.TemplateBody, input.UsePreviousTemplate))
+	if err != nil {
+		return nil, err
+	}
+	copied.Parameters = params
+	return c.CFNAPI.CreateChangeSet(&copied)
+}
+
+// WithParameterStore returns services that resolve parameter store references in stack
+// parameters with the resolver
+func WithParameterStore(svc Services, resolver *ParameterResolver) Services {
+	if _, ok := svc.Cloudformation.(*parameterStoreCfn); !ok {
+		svc.Cloudformation = &parameterStoreCfn{svc.Cloudformation, resolver}
+	}
+	return svc
+}
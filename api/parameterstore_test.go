@@ -0,0 +1,49 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+type parameterStore map[string]string
+
+func (p parameterStore) SendCommand(instanceIds []string, commands []string) (string, error) {
+	return "", nil
+}
+
+func (p parameterStore) GetCommandInvocation(commandID, instanceID string) (CommandInvocation, error) {
+	return CommandInvocation{}, nil
+}
+
+func (p parameterStore) GetParameter(name string, decrypt bool) (string, error) {
+	return p[name], nil
+}
+
+func TestResolveStackParametersRequiresNoEchoForSecrets(t *testing.T) {
+	template := func() (string, error) {
+		return "Parameters:\n  Token:\n    Type: String\n    NoEcho: true\n  Url:\n    Type: String\n", nil
+	}
+	r := NewParameterResolver(parameterStore{"/app/token": "secret", "/app/url": "https://example.com"})
+
+	params, err := r.resolveStackParameters([]*cloudformation.Parameter{
+		{ParameterKey: aws.String("Token"), ParameterValue: aws.String("ssm-secure:/app/token")},
+		{ParameterKey: aws.String("Url"), ParameterValue: aws.String("ssm:/app/url")},
+	}, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := aws.StringValue(params[0].ParameterValue); v != "secret" {
+		t.Fatalf("Expected the token to be resolved, got %q", v)
+	}
+	if v := aws.StringValue(params[1].ParameterValue); v != "https://example.com" {
+		t.Fatalf("Expected the url to be resolved, got %q", v)
+	}
+
+	if _, err := r.resolveStackParameters([]*cloudformation.Parameter{
+		{ParameterKey: aws.String("Url"), ParameterValue: aws.String("ssm-secure:/app/token")},
+	}, template); err == nil {
+		t.Fatalf("Expected a decrypted value in a parameter that isn't NoEcho to be refused")
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+// The vendored cloudformation client predates termination protection, so it's called directly
+type terminationProtectionInterface interface {
+	TerminationProtected(stackName string) (bool, error)
+	SetTerminationProtection(stackName string, enabled bool) error
+}
+
+type terminationProtectionClient struct {
+	*queryClient
+}
+
+func newTerminationProtectionClient(p client.ConfigProvider) *terminationProtectionClient {
+	return &terminationProtectionClient{newQueryClient(p, "cloudformation", "2010-05-15")}
+}
+
+// TerminationProtected returns whether a stack can't be deleted until its termination
+// protection is turned off
+func (c *terminationProtectionClient) TerminationProtected(stackName string) (bool, error) {
+	var resp struct {
+		Stacks []struct {
+			EnableTerminationProtection bool `xml:"EnableTerminationProtection"`
+		} `xml:"DescribeStacksResult>Stacks>member"`
+	}
+	if err := c.Call("DescribeStacks", url.Values{"StackName": {stackName}}, &resp); err != nil {
+		return false, err
+	}
+	return len(resp.Stacks) > 0 && resp.Stacks[0].EnableTerminationProtection, nil
+}
+
+func (c *terminationProtectionClient) SetTerminationProtection(stackName string, enabled bool) error {
+	return c.Call("UpdateTerminationProtection", url.Values{
+		"StackName":                   {stackName},
+		"EnableTerminationProtection": {strconv.FormatBool(enabled)},
+	}, nil)
+}
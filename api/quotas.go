@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type quotasInterface interface {
+	GetServiceQuota(serviceCode, quotaCode string) (float64, error)
+	RequestServiceQuotaIncrease(serviceCode, quotaCode string, desired float64) (string, error)
+}
+
+type quotasClient struct {
+	*jsonClient
+}
+
+func newQuotasClient(p client.ConfigProvider) *quotasClient {
+	return &quotasClient{newJSONClient(p, "servicequotas", "ServiceQuotasV20190624", "1.1")}
+}
+
+type quotaInput struct {
+	ServiceCode string
+	QuotaCode   string
+}
+
+// GetServiceQuota returns the value of a quota in the account, falling back to the default
+// for quotas that have never been changed
+func (c *quotasClient) GetServiceQuota(serviceCode, quotaCode string) (float64, error) {
+	var resp struct {
+		Quota struct {
+			Value float64
+		}
+	}
+	err := c.Call("GetServiceQuota", &quotaInput{serviceCode, quotaCode}, &resp)
+	if e, ok := err.(awserr.Error); ok && e.Code() == "NoSuchResourceException" {
+		err = c.Call("GetAWSDefaultServiceQuota", &quotaInput{serviceCode, quotaCode}, &resp)
+	}
+	return resp.Quota.Value, err
+}
+
+// RequestServiceQuotaIncrease asks for a quota to be raised, returning the id of the request
+func (c *quotasClient) RequestServiceQuotaIncrease(serviceCode, quotaCode string, desired float64) (string, error) {
+	var resp struct {
+		RequestedQuota struct {
+			Id string
+		}
+	}
+	err := c.Call("RequestServiceQuotaIncrease", &struct {
+		ServiceCode  string
+		QuotaCode    string
+		DesiredValue float64
+	}{serviceCode, quotaCode, desired}, &resp)
+	return resp.RequestedQuota.Id, err
+}
+
+// Quota is a service quota that creating something would use more of
+type Quota struct {
+	Name        string
+	ServiceCode string
+	QuotaCode   string
+	// Used is how much of the quota is already used, Needed how much more will be
+	Used   float64
+	Needed float64
+	// Value is the quota in the account, set by CheckQuotas
+	Value float64
+}
+
+func (q Quota) String() string {
+	return fmt.Sprintf("%s (%g used of %g, %g more needed)", q.Name, q.Used, q.Value, q.Needed)
+}
+
+// Exceeded returns whether the quota is too low for what's needed
+func (q Quota) Exceeded() bool {
+	return q.Used+q.Needed > q.Value
+}
+
+// Quotas that ecsy checks before creating clusters and services
+var (
+	VPCsQuota         = Quota{Name: "VPCs per Region", ServiceCode: "vpc", QuotaCode: "L-F678F1CE"}
+	ElasticIPsQuota   = Quota{Name: "EC2-VPC Elastic IPs", ServiceCode: "ec2", QuotaCode: "L-0263D0A3"}
+	LoadBalancerQuota = Quota{Name: "Classic Load Balancers per Region", ServiceCode: "elasticloadbalancing", QuotaCode: "L-E9E9831D"}
+	TasksQuota        = Quota{Name: "Tasks per service", ServiceCode: "ecs", QuotaCode: "L-9EF96962"}
+)
+
+// CheckQuotas looks up the value of each quota, returning those that would be exceeded
+func CheckQuotas(svc quotasInterface, quotas []Quota) ([]Quota, error) {
+	exceeded := []Quota{}
+	for _, q := range quotas {
+		value, err := svc.GetServiceQuota(q.ServiceCode, q.QuotaCode)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get quota %s: %v", q.Name, err)
+		}
+		q.Value = value
+		if q.Exceeded() {
+			exceeded = append(exceeded, q)
+		}
+	}
+	return exceeded, nil
+}
@@ -0,0 +1,29 @@
+package api
+
+import "testing"
+
+type fakeQuotas map[string]float64
+
+func (f fakeQuotas) GetServiceQuota(serviceCode, quotaCode string) (float64, error) {
+	return f[serviceCode+"/"+quotaCode], nil
+}
+
+func (f fakeQuotas) RequestServiceQuotaIncrease(serviceCode, quotaCode string, desired float64) (string, error) {
+	return "request", nil
+}
+
+func TestCheckQuotas(t *testing.T) {
+	quotas := fakeQuotas{"vpc/L-F678F1CE": 5, "ec2/L-0263D0A3": 5}
+
+	vpcs, eips := VPCsQuota, ElasticIPsQuota
+	vpcs.Used, vpcs.Needed = 5, 1
+	eips.Used, eips.Needed = 4, 1
+
+	exceeded, err := CheckQuotas(quotas, []Quota{vpcs, eips})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exceeded) != 1 || exceeded[0].Name != VPCsQuota.Name || exceeded[0].Value != 5 {
+		t.Fatalf("Expected only the vpc quota to be exceeded, got %v", exceeded)
+	}
+}
@@ -0,0 +1,571 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+// ErrImageNotFound is returned when a tag or digest doesn't exist in a repository
+var ErrImageNotFound = errors.New("Image not found")
+
+// ErrImageNotVisible is returned when a registry won't show whether an image exists
+// without credentials, such as for a private repository on Docker Hub
+var ErrImageNotVisible = errors.New("Image can't be seen without credentials")
+
+type registryInterface interface {
+	DescribeImage(ref ImageRef) (*Image, error)
+	ListImages(ref ImageRef) ([]*Image, error)
+	Architectures(ref ImageRef) ([]string, error)
+	ScanFindings(ref ImageRef) (*ScanFindings, error)
+}
+
+// ScanFindings are the vulnerabilities found by ECR image scanning
+type ScanFindings struct {
+	Status   string
+	Counts   map[string]int
+	Findings []Finding
+}
+
+// Finding is a vulnerability in a package in an image
+type Finding struct {
+	Name     string
+	Severity string
+	Package  string
+	URI      string
+}
+
+// Severities of findings, from most to least severe
+var Severities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL", "UNDEFINED"}
+
+// AtLeast returns the findings that are at least as severe as a severity
+func (f *ScanFindings) AtLeast(severity string) []Finding {
+	rank := map[string]int{}
+	for i, s := range Severities {
+		rank[s] = i
+	}
+	matched := []Finding{}
+	for _, finding := range f.Findings {
+		if r, ok := rank[finding.Severity]; ok && r <= rank[strings.ToUpper(severity)] {
+			matched = append(matched, finding)
+		}
+	}
+	return matched
+}
+
+// Image is an image pushed to a repository in ECR or Docker Hub
+type Image struct {
+	Tags     []string
+	Digest   string
+	PushedAt time.Time
+}
+
+// ImageRef is a parsed docker image name, such as nginx:1.21 or
+// 123456789012.dkr.ecr.us-east-1.amazonaws.com/app@sha256:...
+type ImageRef struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+var ecrRegistry = regexp.MustCompile(`^(\d{12})\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com(\.cn)?$`)
+
+// ParseImageRef splits an image name into its registry, repository, tag and digest
+func ParseImageRef(image string) ImageRef {
+	var ref ImageRef
+	if i := strings.Index(image, "@"); i != -1 {
+		image, ref.Digest = image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i != -1 && !strings.Contains(image[i:], "/") {
+		image, ref.Tag = image[:i], image[i+1:]
+	}
+	if i := strings.Index(image, "/"); i != -1 {
+		if host := image[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry, image = host, image[i+1:]
+		}
+	}
+	ref.Repository = image
+	return ref
+}
+
+// String returns the image name, with the digest if it's pinned to one
+func (r ImageRef) String() string {
+	s := r.Repository
+	if r.Registry != "" {
+		s = r.Registry + "/" + s
+	}
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Pinned returns the image name pinned to a digest, dropping the tag
+func (r ImageRef) Pinned(digest string) string {
+	r.Tag, r.Digest = "", digest
+	return r.String()
+}
+
+// ECR is whether the image is in an ECR repository
+func (r ImageRef) ECR() bool {
+	return ecrRegistry.MatchString(r.Registry)
+}
+
+// Resolvable is whether the image is in a registry that images can be looked up in
+func (r ImageRef) Resolvable() bool {
+	return r.Registry == "" || r.Registry == "docker.io" || ecrRegistry.MatchString(r.Registry)
+}
+
+// LatestSemverTag returns the highest of the tags in the form 1.2.3 or v1.2.3, ignoring
+// pre-releases and tags that aren't versions
+func LatestSemverTag(tags []string) (string, bool) {
+	type version struct {
+		tag   string
+		parts [3]int
+	}
+	versions := []version{}
+
+	for _, tag := range tags {
+		pieces := strings.Split(strings.TrimPrefix(tag, "v"), ".")
+		if len(pieces) != 3 {
+			continue
+		}
+		v := version{tag: tag}
+		valid := true
+		for i, piece := range pieces {
+			n, err := strconv.Atoi(piece)
+			if err != nil || n < 0 {
+				valid = false
+				break
+			}
+			v.parts[i] = n
+		}
+		if valid {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return "", false
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		for k := 0; k < 3; k++ {
+			if versions[i].parts[k] != versions[j].parts[k] {
+				return versions[i].parts[k] > versions[j].parts[k]
+			}
+		}
+		return versions[i].tag < versions[j].tag
+	})
+	return versions[0].tag, true
+}
+
+// registryClient looks up images in ECR, in whichever region the repository is in, or
+// in public repositories on Docker Hub
+type registryClient struct {
+	p    client.ConfigProvider
+	http *http.Client
+	hub  string
+
+	mu  sync.Mutex
+	ecr map[string]*jsonClient
+}
+
+func newRegistryClient(p client.ConfigProvider) *registryClient {
+	return &registryClient{
+		p:    p,
+		http: &http.Client{Timeout: 30 * time.Second},
+		hub:  "https://hub.docker.com",
+		ecr:  map[string]*jsonClient{},
+	}
+}
+
+func (c *registryClient) ecrClient(region string) *jsonClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.ecr[region]; !ok {
+		c.ecr[region] = newJSONClient(c.p, "ecr", "AmazonEC2ContainerRegistry_V20150921", "1.1",
+			&aws.Config{Region: aws.String(region)})
+	}
+	return c.ecr[region]
+}
+
+type ecrImageDetail struct {
+	ImageDigest   string    `json:"imageDigest"`
+	ImageTags     []string  `json:"imageTags"`
+	ImagePushedAt epochTime `json:"imagePushedAt"`
+}
+
+func (d ecrImageDetail) image() *Image {
+	return &Image{Tags: d.ImageTags, Digest: d.ImageDigest, PushedAt: d.ImagePushedAt.Time()}
+}
+
+type ecrImageID struct {
+	ImageTag    string `json:"imageTag,omitempty"`
+	ImageDigest string `json:"imageDigest,omitempty"`
+}
+
+type ecrDescribeImagesInput struct {
+	RegistryID     string       `json:"registryId"`
+	RepositoryName string       `json:"repositoryName"`
+	ImageIDs       []ecrImageID `json:"imageIds,omitempty"`
+	Filter         *struct {
+		TagStatus string `json:"tagStatus"`
+	} `json:"filter,omitempty"`
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+type ecrDescribeImagesOutput struct {
+	ImageDetails []ecrImageDetail `json:"imageDetails"`
+	NextToken    string           `json:"nextToken"`
+}
+
+func (c *registryClient) DescribeImage(ref ImageRef) (*Image, error) {
+	if m := ecrRegistry.FindStringSubmatch(ref.Registry); m != nil {
+		var resp ecrDescribeImagesOutput
+		err := c.ecrClient(m[2]).Call("DescribeImages", &ecrDescribeImagesInput{
+			RegistryID:     m[1],
+			RepositoryName: ref.Repository,
+			ImageIDs:       []ecrImageID{{ImageTag: imageTag(ref), ImageDigest: ref.Digest}},
+		}, &resp)
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ImageNotFoundException" {
+			return nil, ErrImageNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		if len(resp.ImageDetails) == 0 {
+			return nil, ErrImageNotFound
+		}
+		return resp.ImageDetails[0].image(), nil
+	}
+
+	if ref.Registry != "" && ref.Registry != "docker.io" {
+		return nil, fmt.Errorf("Can't look up images in registry %s", ref.Registry)
+	}
+	if ref.Digest != "" {
+		images, err := c.ListImages(ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			if image.Digest == ref.Digest {
+				return image, nil
+			}
+		}
+		return nil, ErrImageNotFound
+	}
+
+	var tag dockerHubTag
+	if err := c.dockerHub(ref, "/tags/"+url.PathEscape(imageTag(ref))+"/", &tag); err != nil {
+		return nil, err
+	}
+	return tag.image(), nil
+}
+
+func (c *registryClient) ListImages(ref ImageRef) ([]*Image, error) {
+	images := []*Image{}
+
+	if m := ecrRegistry.FindStringSubmatch(ref.Registry); m != nil {
+		input := &ecrDescribeImagesInput{RegistryID: m[1], RepositoryName: ref.Repository}
+		input.Filter = &struct {
+			TagStatus string `json:"tagStatus"`
+		}{"TAGGED"}
+
+		for {
+			var resp ecrDescribeImagesOutput
+			if err := c.ecrClient(m[2]).Call("DescribeImages", input, &resp); err != nil {
+				return nil, err
+			}
+			for _, d := range resp.ImageDetails {
+				images = append(images, d.image())
+			}
+			if resp.NextToken == "" {
+				return images, nil
+			}
+			input.NextToken = resp.NextToken
+		}
+	}
+
+	if ref.Registry != "" && ref.Registry != "docker.io" {
+		return nil, fmt.Errorf("Can't look up images in registry %s", ref.Registry)
+	}
+
+	var resp struct {
+		Results []dockerHubTag `json:"results"`
+	}
+	if err := c.dockerHub(ref, "/tags/?page_size=100&ordering=last_updated", &resp); err != nil {
+		return nil, err
+	}
+	for _, tag := range resp.Results {
+		images = append(images, tag.image())
+	}
+	return images, nil
+}
+
+type dockerHubTag struct {
+	Name        string    `json:"name"`
+	Digest      string    `json:"digest"`
+	LastUpdated time.Time `json:"last_updated"`
+	Images      []struct {
+		Architecture string `json:"architecture"`
+	} `json:"images"`
+}
+
+func (t dockerHubTag) image() *Image {
+	return &Image{Tags: []string{t.Name}, Digest: t.Digest, PushedAt: t.LastUpdated}
+}
+
+// Architectures returns the cpu architectures that an image can run on, from the platforms
+// of a multi-arch image or the config of a single one
+func (c *registryClient) Architectures(ref ImageRef) ([]string, error) {
+	m := ecrRegistry.FindStringSubmatch(ref.Registry)
+	if m == nil {
+		if ref.Registry != "" && ref.Registry != "docker.io" {
+			return nil, fmt.Errorf("Can't look up images in registry %s", ref.Registry)
+		}
+		var tag dockerHubTag
+		if err := c.dockerHub(ref, "/tags/"+url.PathEscape(imageTag(ref))+"/", &tag); err != nil {
+			return nil, err
+		}
+		archs := []string{}
+		for _, image := range tag.Images {
+			archs = append(archs, image.Architecture)
+		}
+		return archs, nil
+	}
+
+	ecr := c.ecrClient(m[2])
+	var resp struct {
+		Images []struct {
+			ImageManifest string `json:"imageManifest"`
+		} `json:"images"`
+	}
+	err := ecr.Call("BatchGetImage", &struct {
+		RegistryID         string       `json:"registryId"`
+		RepositoryName     string       `json:"repositoryName"`
+		ImageIDs           []ecrImageID `json:"imageIds"`
+		AcceptedMediaTypes []string     `json:"acceptedMediaTypes"`
+	}{m[1], ref.Repository, []ecrImageID{{ImageTag: imageTag(ref), ImageDigest: ref.Digest}}, manifestMediaTypes}, &resp)
+	if err != nil {
+		return nil, err
+	} else if len(resp.Images) == 0 {
+		return nil, ErrImageNotFound
+	}
+
+	var manifest struct {
+		Manifests []struct {
+			Platform struct {
+				Architecture string `json:"architecture"`
+			} `json:"platform"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err = json.Unmarshal([]byte(resp.Images[0].ImageManifest), &manifest); err != nil {
+		return nil, err
+	}
+
+	archs := []string{}
+	for _, m := range manifest.Manifests {
+		archs = append(archs, m.Platform.Architecture)
+	}
+	if len(archs) > 0 || manifest.Config.Digest == "" {
+		return archs, nil
+	}
+
+	// single images only record their architecture in the config blob
+	var layer struct {
+		DownloadURL string `json:"downloadUrl"`
+	}
+	err = ecr.Call("GetDownloadUrlForLayer", &struct {
+		RegistryID     string `json:"registryId"`
+		RepositoryName string `json:"repositoryName"`
+		LayerDigest    string `json:"layerDigest"`
+	}{m[1], ref.Repository, manifest.Config.Digest}, &layer)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := c.http.Get(layer.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Body.Close()
+
+	var config struct {
+		Architecture string `json:"architecture"`
+	}
+	if err = json.NewDecoder(blob.Body).Decode(&config); err != nil {
+		return nil, err
+	}
+	return []string{config.Architecture}, nil
+}
+
+// ScanFindings returns the findings of the latest scan of an image in ECR, from basic
+// scanning or the active findings of enhanced scanning with Inspector
+func (c *registryClient) ScanFindings(ref ImageRef) (*ScanFindings, error) {
+	m := ecrRegistry.FindStringSubmatch(ref.Registry)
+	if m == nil {
+		return nil, fmt.Errorf("Only images in ECR can be scanned, not %s", ref)
+	}
+
+	input := &struct {
+		RegistryID     string     `json:"registryId"`
+		RepositoryName string     `json:"repositoryName"`
+		ImageID        ecrImageID `json:"imageId"`
+		NextToken      string     `json:"nextToken,omitempty"`
+	}{m[1], ref.Repository, ecrImageID{ImageTag: imageTag(ref), ImageDigest: ref.Digest}, ""}
+
+	findings := &ScanFindings{Counts: map[string]int{}}
+	for {
+		var resp struct {
+			ImageScanStatus struct {
+				Status string `json:"status"`
+			} `json:"imageScanStatus"`
+			ImageScanFindings struct {
+				Findings []struct {
+					Name       string `json:"name"`
+					Severity   string `json:"severity"`
+					URI        string `json:"uri"`
+					Attributes []struct {
+						Key   string `json:"key"`
+						Value string `json:"value"`
+					} `json:"attributes"`
+				} `json:"findings"`
+				EnhancedFindings []struct {
+					Severity                    string `json:"severity"`
+					Status                      string `json:"status"`
+					PackageVulnerabilityDetails struct {
+						VulnerabilityID    string `json:"vulnerabilityId"`
+						SourceURL          string `json:"sourceUrl"`
+						VulnerablePackages []struct {
+							Name string `json:"name"`
+						} `json:"vulnerablePackages"`
+					} `json:"packageVulnerabilityDetails"`
+				} `json:"enhancedFindings"`
+			} `json:"imageScanFindings"`
+			NextToken string `json:"nextToken"`
+		}
+
+		err := c.ecrClient(m[2]).Call("DescribeImageScanFindings", input, &resp)
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "ScanNotFoundException" {
+			findings.Status = "NOT_SCANNED"
+			return findings, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		findings.Status = resp.ImageScanStatus.Status
+		for _, f := range resp.ImageScanFindings.Findings {
+			finding := Finding{Name: f.Name, Severity: f.Severity, URI: f.URI}
+			for _, attr := range f.Attributes {
+				if attr.Key == "package_name" {
+					finding.Package = attr.Value
+				}
+			}
+			findings.Findings = append(findings.Findings, finding)
+			findings.Counts[f.Severity]++
+		}
+		for _, f := range resp.ImageScanFindings.EnhancedFindings {
+			if f.Status != "ACTIVE" {
+				continue
+			}
+			finding := Finding{
+				Name:     f.PackageVulnerabilityDetails.VulnerabilityID,
+				Severity: f.Severity,
+				URI:      f.PackageVulnerabilityDetails.SourceURL,
+			}
+			if packages := f.PackageVulnerabilityDetails.VulnerablePackages; len(packages) > 0 {
+				finding.Package = packages[0].Name
+			}
+			findings.Findings = append(findings.Findings, finding)
+			findings.Counts[f.Severity]++
+		}
+
+		if resp.NextToken == "" {
+			return findings, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// InstanceArchitecture returns the cpu architecture of an ec2 instance type, graviton
+// instances like m6g.large or t4g.micro are arm64
+func InstanceArchitecture(instanceType string) string {
+	family := strings.SplitN(instanceType, ".", 2)[0]
+	if family == "a1" {
+		return "arm64"
+	}
+	for i := 1; i < len(family); i++ {
+		if family[i-1] >= '0' && family[i-1] <= '9' {
+			if strings.HasPrefix(family[i:], "g") {
+				return "arm64"
+			}
+			break
+		}
+	}
+	return "amd64"
+}
+
+func (c *registryClient) dockerHub(ref ImageRef, path string, out interface{}) error {
+	repository := ref.Repository
+	if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	resp, err := c.http.Get(c.hub + "/v2/repositories/" + repository + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrImageNotVisible
+	case http.StatusNotFound:
+		// private repositories are hidden from anonymous requests, so a missing tag is
+		// only certain if the repository itself can be seen
+		if path == "/" {
+			return ErrImageNotVisible
+		}
+		var repo struct{}
+		if err := c.dockerHub(ref, "/", &repo); err != nil {
+			return err
+		}
+		return ErrImageNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Docker Hub responded with %s for %s", resp.Status, repository)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func imageTag(ref ImageRef) string {
+	if ref.Tag == "" && ref.Digest == "" {
+		return "latest"
+	}
+	return ref.Tag
+}
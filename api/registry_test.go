@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseImageRef(t *testing.T) {
+	for image, expected := range map[string]ImageRef{
+		"nginx":                 {Repository: "nginx"},
+		"nginx:1.21":            {Repository: "nginx", Tag: "1.21"},
+		"localhost:5000/app:v1": {Registry: "localhost:5000", Repository: "app", Tag: "v1"},
+		"lox/app@sha256:abc":    {Repository: "lox/app", Digest: "sha256:abc"},
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app:v2": {
+			Registry: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Repository: "team/app", Tag: "v2"},
+	} {
+		ref := ParseImageRef(image)
+		if ref != expected {
+			t.Fatalf("Expected %s to parse as %#v, got %#v", image, expected, ref)
+		}
+		if ref.String() != image {
+			t.Fatalf("Expected %#v to format as %s, got %s", ref, image, ref.String())
+		}
+	}
+}
+
+func TestLatestSemverTag(t *testing.T) {
+	tag, ok := LatestSemverTag([]string{"latest", "v1.9.0", "v1.10.0", "1.10.0-rc1", "v1.2.30"})
+	if !ok || tag != "v1.10.0" {
+		t.Fatalf("Expected v1.10.0, got %q", tag)
+	}
+
+	if _, ok := LatestSemverTag([]string{"latest", "main"}); ok {
+		t.Fatalf("Expected no version tags")
+	}
+}
+
+func TestInstanceArchitecture(t *testing.T) {
+	for instanceType, expected := range map[string]string{
+		"t3.micro":   "amd64",
+		"m6g.large":  "arm64",
+		"c6gn.large": "arm64",
+		"a1.medium":  "arm64",
+		"g4dn.large": "amd64",
+		"m5a.large":  "amd64",
+	} {
+		if arch := InstanceArchitecture(instanceType); arch != expected {
+			t.Fatalf("Expected %s to be %s, got %s", instanceType, expected, arch)
+		}
+	}
+}
+
+func TestDockerHubPrivateRepositories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/repositories/lox/public/":
+			w.Write([]byte(`{}`))
+		case "/v2/repositories/lox/public/tags/v1/":
+			w.Write([]byte(`{"images":[{"digest":"sha256:abc","architecture":"amd64"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := &registryClient{http: server.Client(), hub: server.URL}
+	for image, expected := range map[string]error{
+		"lox/public:v1":  nil,
+		"lox/public:v2":  ErrImageNotFound,
+		"lox/private:v1": ErrImageNotVisible,
+	} {
+		if _, err := c.DescribeImage(ParseImageRef(image)); err != expected {
+			t.Fatalf("Expected %v describing %s, got %v", expected, image, err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/lox/ecsy/failure"
+)
+
+// Assumed role credentials are cached between invocations so that a role that needs MFA
+// only prompts for a code once an hour. They are kept in the macOS keychain or the secret
+// service on linux where available, otherwise encrypted in ~/.ecsy/credentials.
+
+// roleCredentialsDuration is how long cached role credentials last, the default maximum
+// session duration of a role
+const roleCredentialsDuration = time.Hour
+
+// MFATokenPrompt asks for the current code of an MFA device
+var MFATokenPrompt = func(serial string) (string, error) {
+	fmt.Fprintf(os.Stderr, "Enter MFA code for %s: ", serial)
+	code, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSpace(code), nil
+}
+
+// cachedRoleCredentials are the credentials of an assumed role as they are cached
+type cachedRoleCredentials struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+// cachingRoleProvider assumes a role, reusing the credentials cached by an earlier invocation
+// until they are close to expiring
+type cachingRoleProvider struct {
+	credentials.Expiry
+	sts       stsAssumer
+	store     credentialStore
+	roleArn   string
+	mfaSerial string
+}
+
+type stsAssumer interface {
+	AssumeRole(*sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+}
+
+// NewCachedRoleCredentials returns credentials for a role assumed with the credentials of
+// p, prompting for an MFA code with MFATokenPrompt if mfaSerial is set
+func NewCachedRoleCredentials(p client.ConfigProvider, roleArn, mfaSerial string) *credentials.Credentials {
+	return credentials.NewCredentials(&cachingRoleProvider{
+		sts:       sts.New(p),
+		store:     defaultCredentialStore(),
+		roleArn:   roleArn,
+		mfaSerial: mfaSerial,
+	})
+}
+
+// cacheKey identifies credentials by the role and the MFA device used to assume it
+func (r *cachingRoleProvider) cacheKey() string {
+	sum := sha256.Sum256([]byte(r.roleArn + "|" + r.mfaSerial))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *cachingRoleProvider) Retrieve() (credentials.Value, error) {
+	if b, err := r.store.Load(r.cacheKey()); err == nil {
+		var cached cachedRoleCredentials
+		if json.Unmarshal(b, &cached) == nil && time.Now().Add(5*time.Minute).Before(cached.Expiration) {
+			return r.value(cached), nil
+		}
+	}
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(r.roleArn),
+		RoleSessionName: aws.String(fmt.Sprintf("ecsy-%d", time.Now().Unix())),
+		DurationSeconds: aws.Int64(int64(roleCredentialsDuration / time.Second)),
+	}
+	if r.mfaSerial != "" {
+		code, err := MFATokenPrompt(r.mfaSerial)
+		if err != nil {
+			return credentials.Value{}, err
+		} else if code == "" {
+			return credentials.Value{}, failure.Errorf(failure.Auth, "An MFA code is needed to assume %s", r.roleArn)
+		}
+		input.SerialNumber = aws.String(r.mfaSerial)
+		input.TokenCode = aws.String(code)
+	}
+
+	resp, err := r.sts.AssumeRole(input)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	cached := cachedRoleCredentials{
+		AccessKeyID:     aws.StringValue(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(resp.Credentials.SessionToken),
+		Expiration:      aws.TimeValue(resp.Credentials.Expiration),
+	}
+	if b, err := json.Marshal(cached); err == nil {
+		// failing to cache only means assuming the role again next time
+		_ = r.store.Save(r.cacheKey(), b)
+	}
+	return r.value(cached), nil
+}
+
+func (r *cachingRoleProvider) value(c cachedRoleCredentials) credentials.Value {
+	r.SetExpiration(c.Expiration, 5*time.Minute)
+	return credentials.Value{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		ProviderName:    "CachedAssumeRoleProvider",
+	}
+}
+
+// sharedConfigRole returns the role and MFA device of a profile that assumes a role with
+// the credentials of a source_profile, which the vendored sdk can't do with MFA
+func sharedConfigRole(name string) (roleArn, mfaSerial, sourceProfile string, err error) {
+	_, section, err := profileSection(name)
+	if err != nil || section == nil {
+		return "", "", "", err
+	}
+	return section.Key("role_arn").String(), section.Key("mfa_serial").String(),
+		section.Key("source_profile").String(), nil
+}
+
+var errCredentialNotFound = errors.New("No cached credentials")
+
+// credentialStore keeps secrets between invocations
+type credentialStore interface {
+	Load(key string) ([]byte, error)
+	Save(key string, value []byte) error
+}
+
+func defaultCredentialStore() credentialStore {
+	if runtime.GOOS == "darwin" {
+		if _, err := exec.LookPath("security"); err == nil {
+			return keychainStore{}
+		}
+	} else if runtime.GOOS == "linux" && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		if _, err := exec.LookPath("secret-tool"); err == nil {
+			return secretServiceStore{}
+		}
+	}
+	home, _ := os.UserHomeDir()
+	return fileStore{dir: filepath.Join(home, ".ecsy", "credentials")}
+}
+
+// keychainStore keeps secrets in the macOS login keychain
+type keychainStore struct{}
+
+func (keychainStore) Load(key string) ([]byte, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", "ecsy", "-a", key, "-w").Output()
+	if err != nil {
+		return nil, errCredentialNotFound
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func (keychainStore) Save(key string, value []byte) error {
+	// the secret is written to security's interactive mode, hex encoded, rather than passed
+	// as an argument that other users could read from the process list
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s ecsy -a %q -X %s\n", key, hex.EncodeToString(value)))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return err
+	} else if strings.Contains(string(out), "security: ") {
+		return fmt.Errorf("Failed to save credentials to the keychain: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// secretServiceStore keeps secrets in the desktop keyring on linux, via secret-tool
+type secretServiceStore struct{}
+
+func (secretServiceStore) Load(key string) ([]byte, error) {
+	out, err := exec.Command("secret-tool", "lookup", "service", "ecsy", "account", key).Output()
+	if err != nil || len(out) == 0 {
+		return nil, errCredentialNotFound
+	}
+	return bytes.TrimSpace(out), nil
+}
+
+func (secretServiceStore) Save(key string, value []byte) error {
+	cmd := exec.Command("secret-tool", "store", "--label=ecsy credentials", "service", "ecsy", "account", key)
+	cmd.Stdin = bytes.NewReader(value)
+	return cmd.Run()
+}
+
+// fileStore keeps secrets in files readable only by the user, encrypted with AES-GCM under
+// a key kept alongside them, so that copies of the files alone aren't usable
+type fileStore struct {
+	dir string
+}
+
+func (s fileStore) key() ([]byte, error) {
+	path := filepath.Join(s.dir, ".key")
+	key, err := ioutil.ReadFile(path)
+	if err == nil && len(key) == 32 {
+		return key, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, err
+	}
+	return key, ioutil.WriteFile(path, key, 0600)
+}
+
+func (s fileStore) gcm() (cipher.AEAD, error) {
+	key, err := s.key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s fileStore) Load(key string) ([]byte, error) {
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, key))
+	if os.IsNotExist(err) {
+		return nil, errCredentialNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errCredentialNotFound
+	}
+	return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], []byte(key))
+}
+
+func (s fileStore) Save(key string, value []byte) error {
+	gcm, err := s.gcm()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.dir, key), gcm.Seal(nonce, nonce, value, []byte(key)), 0600)
+}
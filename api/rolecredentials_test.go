@@ -0,0 +1,141 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+type fakeAssumer struct {
+	calls int
+	input *sts.AssumeRoleInput
+}
+
+func (f *fakeAssumer) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.calls++
+	f.input = input
+	return &sts.AssumeRoleOutput{Credentials: &sts.Credentials{
+		AccessKeyId:     aws.String("AKIA"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("session"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestCachedRoleCredentialsPromptOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	prompts := 0
+	prompt := MFATokenPrompt
+	MFATokenPrompt = func(serial string) (string, error) {
+		prompts++
+		return "123456", nil
+	}
+	defer func() { MFATokenPrompt = prompt }()
+
+	assumer := &fakeAssumer{}
+	store := fileStore{dir: dir}
+
+	// each invocation of ecsy has its own provider, sharing only the store
+	for i := 0; i < 2; i++ {
+		provider := &cachingRoleProvider{
+			sts:       assumer,
+			store:     store,
+			roleArn:   "arn:aws:iam::123456789012:role/deploy",
+			mfaSerial: "arn:aws:iam::123456789012:mfa/deployer",
+		}
+		creds, err := provider.Retrieve()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if creds.SessionToken != "session" {
+			t.Fatalf("Expected the role's credentials, got %#v", creds)
+		}
+	}
+
+	if prompts != 1 || assumer.calls != 1 {
+		t.Fatalf("Expected to prompt and assume the role once, got %d prompts and %d calls", prompts, assumer.calls)
+	}
+	if aws.StringValue(assumer.input.TokenCode) != "123456" {
+		t.Fatalf("Expected the MFA code to be used, got %v", assumer.input)
+	}
+}
+
+func TestFileStoreEncrypts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := fileStore{dir: dir}
+	if err = store.Save("key", []byte("llamas")); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := ioutil.ReadFile(dir + "/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) == "llamas" {
+		t.Fatal("Expected the stored value to be encrypted")
+	}
+
+	value, err := store.Load("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(value) != "llamas" {
+		t.Fatalf("Expected llamas, got %q", value)
+	}
+
+	if _, err = store.Load("missing"); err != errCredentialNotFound {
+		t.Fatalf("Expected a missing value not to be found, got %v", err)
+	}
+}
+
+func TestNewSessionFailsOnSourceProfileCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "config")
+	err = ioutil.WriteFile(config, []byte(`[profile a]
+role_arn = arn:aws:iam::123456789012:role/a
+source_profile = b
+
+[profile b]
+role_arn = arn:aws:iam::123456789012:role/b
+source_profile = a
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home, file, keys := os.Getenv("HOME"), os.Getenv("AWS_CONFIG_FILE"), os.Getenv("AWS_ACCESS_KEY_ID")
+	os.Setenv("HOME", dir)
+	os.Setenv("AWS_CONFIG_FILE", config)
+	os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer func() {
+		os.Setenv("HOME", home)
+		os.Setenv("AWS_CONFIG_FILE", file)
+		os.Setenv("AWS_ACCESS_KEY_ID", keys)
+	}()
+
+	if _, err = newSession(session.Options{Profile: "a"}); err == nil || !strings.Contains(err.Error(), "leads back") {
+		t.Fatalf("Expected the source_profile cycle to fail, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type secretsInterface interface {
+	GetSecretValue(id string) (string, error)
+}
+
+type secretsClient struct {
+	*jsonClient
+}
+
+func newSecretsClient(p client.ConfigProvider) *secretsClient {
+	return &secretsClient{newJSONClient(p, "secretsmanager", "secretsmanager", "1.1")}
+}
+
+func (c *secretsClient) GetSecretValue(id string) (string, error) {
+	var resp struct {
+		SecretString string
+	}
+	err := c.Call("GetSecretValue", &struct{ SecretId string }{id}, &resp)
+	return resp.SecretString, err
+}
+
+// TaskSecret is a container environment variable that ECS sets from parameter store or
+// secrets manager, which the vendored sdk predates
+type TaskSecret struct {
+	Name      string `json:"name"`
+	ValueFrom string `json:"valueFrom"`
+}
+
+// ResolveTaskSecret returns the value a task secret would have in a container, from a
+// parameter name or arn, or a secrets manager arn optionally followed by a json key
+func ResolveTaskSecret(ssm ssmInterface, secrets secretsInterface, s TaskSecret) (string, error) {
+	if !strings.HasPrefix(s.ValueFrom, "arn:") || strings.Contains(s.ValueFrom, ":ssm:") {
+		return ssm.GetParameter(s.ValueFrom, true)
+	}
+
+	// arn:aws:secretsmanager:region:account:secret:name:json-key:version-stage:version-id
+	pieces := strings.Split(s.ValueFrom, ":")
+	if len(pieces) < 7 {
+		return "", fmt.Errorf("Secret %s has an invalid arn %s", s.Name, s.ValueFrom)
+	}
+	value, err := secrets.GetSecretValue(strings.Join(pieces[:7], ":"))
+	if err != nil || len(pieces) < 8 || pieces[7] == "" {
+		return value, err
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("Secret %s isn't json with a %s key", s.Name, pieces[7])
+	}
+	field, ok := fields[pieces[7]]
+	if !ok {
+		return "", fmt.Errorf("Secret %s has no %s key", s.Name, pieces[7])
+	}
+	return fmt.Sprint(field), nil
+}
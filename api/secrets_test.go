@@ -0,0 +1,49 @@
+package api
+
+import "testing"
+
+type fakeParameters map[string]string
+
+func (f fakeParameters) SendCommand(instanceIds []string, commands []string) (string, error) {
+	return "", nil
+}
+
+func (f fakeParameters) GetCommandInvocation(commandID, instanceID string) (CommandInvocation, error) {
+	return CommandInvocation{}, nil
+}
+
+func (f fakeParameters) GetParameter(name string, decrypt bool) (string, error) {
+	return f[name], nil
+}
+
+func (f fakeParameters) GetSecretValue(id string) (string, error) {
+	return f[id], nil
+}
+
+func TestResolveTaskSecret(t *testing.T) {
+	const secretArn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db-AbCdEf"
+	values := fakeParameters{
+		"/myapp/token": "token",
+		"arn:aws:ssm:us-east-1:123456789012:parameter/myapp/url": "https://example.com",
+		secretArn: `{"username":"app","password":"hunter2"}`,
+	}
+
+	for valueFrom, expected := range map[string]string{
+		"/myapp/token": "token",
+		"arn:aws:ssm:us-east-1:123456789012:parameter/myapp/url": "https://example.com",
+		secretArn:                 `{"username":"app","password":"hunter2"}`,
+		secretArn + ":password::": "hunter2",
+	} {
+		value, err := ResolveTaskSecret(values, values, TaskSecret{Name: "SECRET", ValueFrom: valueFrom})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != expected {
+			t.Fatalf("Expected %s to resolve to %q, got %q", valueFrom, expected, value)
+		}
+	}
+
+	if _, err := ResolveTaskSecret(values, values, TaskSecret{Name: "SECRET", ValueFrom: secretArn + ":missing::"}); err == nil {
+		t.Fatalf("Expected an error for a missing json key")
+	}
+}
@@ -1,31 +1,195 @@
 package api
 
 import (
+	"fmt"
 	"log"
+	"os"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 var DefaultServices Services
 
 type Services struct {
-	Cloudformation cfnInterface
-	ECS            ecsInterface
-	Logs           cloudwatchLogsInterface
+	Cloudformation  CFNAPI
+	ECS             ECSAPI
+	Logs            cloudwatchLogsInterface
+	CloudWatch      cloudwatchInterface
+	ELB             elbInterface
+	SNS             snsInterface
+	DynamoDB        dynamodbInterface
+	ACM             acmInterface
+	STS             stsInterface
+	StackSets       stackSetInterface
+	IAM             iamInterface
+	LogPolicies     logPolicyInterface
+	EC2             EC2API
+	StepFunctions   stepFunctionsInterface
+	Registry        registryInterface
+	Athena          athenaInterface
+	SSM             ssmInterface
+	InstanceConnect instanceConnectInterface
+	Autoscaling     autoscalingInterface
+	TaskDefinitions taskDefinitionInterface
+	Secrets         secretsInterface
+	Quotas          quotasInterface
+	Protection      terminationProtectionInterface
+	CloudTrail      cloudtrailInterface
+	Costs           costsInterface
+	Instances       containerInstanceInterface
+
+	// Credentials and Region are those the clients were created with
+	Credentials *credentials.Credentials
+	Region      string
+}
+
+type stsInterface interface {
+	GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+	AssumeRole(*sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error)
+	GetFederationToken(*sts.GetFederationTokenInput) (*sts.GetFederationTokenOutput, error)
 }
 
 func init() {
-	sess, err := session.NewSessionWithOptions(session.Options{
+	sess, err := newSession(session.Options{
 		SharedConfigState: session.SharedConfigEnable,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	DefaultServices.Cloudformation = cloudformation.New(sess)
-	DefaultServices.ECS = ecs.New(sess)
-	DefaultServices.Logs = cloudwatchlogs.New(sess)
+	DefaultServices = NewServices(sess)
+}
+
+// NewServices creates clients for all the services ecsy uses
+func NewServices(p client.ConfigProvider) Services {
+	cfg := p.ClientConfig("sts").Config
+	return Services{
+		Cloudformation:  cloudformation.New(p),
+		ECS:             ecs.New(p),
+		Logs:            cloudwatchlogs.New(p),
+		CloudWatch:      newCloudwatchClient(p),
+		ELB:             newELBClient(p),
+		SNS:             newSNSClient(p),
+		DynamoDB:        newDynamoDBClient(p),
+		ACM:             newACMClient(p),
+		STS:             sts.New(p),
+		StackSets:       newStackSetClient(p),
+		IAM:             newIAMClient(p),
+		LogPolicies:     newLogPolicyClient(p),
+		EC2:             newEC2Client(p),
+		StepFunctions:   newStepFunctionsClient(p),
+		Registry:        newRegistryClient(p),
+		Athena:          newAthenaClient(p),
+		SSM:             newSSMClient(p),
+		InstanceConnect: newInstanceConnectClient(p),
+		Autoscaling:     newAutoscalingClient(p),
+		TaskDefinitions: newTaskDefinitionClient(p),
+		Secrets:         newSecretsClient(p),
+		Quotas:          newQuotasClient(p),
+		Protection:      newTerminationProtectionClient(p),
+		CloudTrail:      newCloudtrailClient(p),
+		Costs:           newCostsClient(p),
+		Instances:       newContainerInstanceClient(p),
+		Credentials:     cfg.Credentials,
+		Region:          aws.StringValue(cfg.Region),
+	}
+}
+
+// newSession creates a session from the shared config, unless keys are set in the environment
+// getting credentials from IAM Identity Center for SSO profiles and caching the credentials
+// of profiles that assume a role
+func newSession(opts session.Options) (*session.Session, error) {
+	return newProfileSession(opts, map[string]bool{})
+}
+
+// newProfileSession creates a session for a profile, failing if its source_profile leads
+// back to a profile that's already been visited rather than recursing forever
+func newProfileSession(opts session.Options, visited map[string]bool) (*session.Session, error) {
+	sess, err := session.NewSessionWithOptions(opts)
+	if err != nil || os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return sess, err
+	}
+
+	name := opts.Profile
+	if name == "" {
+		name = ProfileName()
+	}
+
+	profile, err := LoadSSOProfile(name)
+	if err != nil {
+		return nil, err
+	} else if profile != nil {
+		return sess.Copy(&aws.Config{Credentials: NewSSOCredentials(profile)}), nil
+	}
+
+	roleArn, mfaSerial, source, err := sharedConfigRole(name)
+	if err != nil || roleArn == "" || source == "" || source == name {
+		return sess, err
+	}
+	visited[name] = true
+	if visited[source] {
+		return nil, fmt.Errorf("Profile %s has a source_profile of %s, which leads back to it", name, source)
+	}
+	opts.Profile = source
+	sourceSess, err := newProfileSession(opts, visited)
+	if err != nil {
+		return nil, err
+	}
+	return sess.Copy(&aws.Config{
+		Credentials: NewCachedRoleCredentials(sourceSess, roleArn, mfaSerial),
+	}), nil
+}
+
+// RegionServices creates clients for services in a different region to the default
+func RegionServices(region string) (Services, error) {
+	return EnvironmentServices(region, "", "")
+}
+
+// EnvironmentServices creates clients for services in a region, optionally assuming a
+// role first, for instance to deploy to a production account, with an MFA code if the
+// role needs one. Empty values fall back to the default session.
+func EnvironmentServices(region, roleArn, mfaSerial string) (Services, error) {
+	opts := session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}
+	if region != "" {
+		opts.Config.Region = aws.String(region)
+	}
+
+	sess, err := newSession(opts)
+	if err != nil {
+		return Services{}, err
+	}
+
+	if roleArn != "" {
+		sess = sess.Copy(&aws.Config{
+			Credentials: NewCachedRoleCredentials(sess, roleArn, mfaSerial),
+		})
+	}
+	return NewServices(sess), nil
+}
+
+// AccountID returns the id of the account that the services are using
+func AccountID(svc stsInterface) (string, error) {
+	resp, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return *resp.Account, nil
+}
+
+// CallerArn returns the arn of the identity that the services are using
+func CallerArn(svc stsInterface) (string, error) {
+	resp, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return *resp.Arn, nil
 }
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type snsInterface interface {
+	Publish(topicArn, subject, message string, attributes map[string]string) error
+}
+
+type snsClient struct {
+	*queryClient
+}
+
+func newSNSClient(p client.ConfigProvider) *snsClient {
+	return &snsClient{newQueryClient(p, "sns", "2010-03-31")}
+}
+
+func (c *snsClient) Publish(topicArn, subject, message string, attributes map[string]string) error {
+	params := url.Values{
+		"TopicArn": {topicArn},
+		"Message":  {message},
+	}
+	if subject != "" {
+		params.Set("Subject", subject)
+	}
+
+	idx := 1
+	for _, k := range sortedKeys(attributes) {
+		prefix := "MessageAttributes.entry." + strconv.Itoa(idx)
+		params.Set(prefix+".Name", k)
+		params.Set(prefix+".Value.DataType", "String")
+		params.Set(prefix+".Value.StringValue", attributes[k])
+		idx++
+	}
+
+	return c.Call("Publish", params, nil)
+}
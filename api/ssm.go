@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type ssmInterface interface {
+	SendCommand(instanceIds []string, commands []string) (string, error)
+	GetCommandInvocation(commandID, instanceID string) (CommandInvocation, error)
+	GetParameter(name string, decrypt bool) (string, error)
+}
+
+// CommandInvocation is the result of running a command on an instance with SSM
+type CommandInvocation struct {
+	InstanceID string
+	Status     string
+	Output     string
+	Error      string
+}
+
+type ssmClient struct {
+	*jsonClient
+}
+
+func newSSMClient(p client.ConfigProvider) *ssmClient {
+	return &ssmClient{newJSONClient(p, "ssm", "AmazonSSM", "1.1")}
+}
+
+// SendCommand runs shell commands on instances with the AWS-RunShellScript document
+func (c *ssmClient) SendCommand(instanceIds []string, commands []string) (string, error) {
+	var resp struct {
+		Command struct {
+			CommandID string `json:"CommandId"`
+		} `json:"Command"`
+	}
+	err := c.Call("SendCommand", &struct {
+		InstanceIds  []string            `json:"InstanceIds"`
+		DocumentName string              `json:"DocumentName"`
+		Parameters   map[string][]string `json:"Parameters"`
+	}{
+		InstanceIds:  instanceIds,
+		DocumentName: "AWS-RunShellScript",
+		Parameters:   map[string][]string{"commands": commands},
+	}, &resp)
+	return resp.Command.CommandID, err
+}
+
+func (c *ssmClient) GetCommandInvocation(commandID, instanceID string) (CommandInvocation, error) {
+	var resp struct {
+		Status                string `json:"Status"`
+		StandardOutputContent string `json:"StandardOutputContent"`
+		StandardErrorContent  string `json:"StandardErrorContent"`
+	}
+	err := c.Call("GetCommandInvocation", &struct {
+		CommandID  string `json:"CommandId"`
+		InstanceID string `json:"InstanceId"`
+	}{commandID, instanceID}, &resp)
+	return CommandInvocation{
+		InstanceID: instanceID,
+		Status:     resp.Status,
+		Output:     resp.StandardOutputContent,
+		Error:      resp.StandardErrorContent,
+	}, err
+}
+
+// GetParameter returns the value of a parameter store parameter, decrypting SecureString
+// parameters if asked to
+func (c *ssmClient) GetParameter(name string, decrypt bool) (string, error) {
+	var resp struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	err := c.Call("GetParameter", &struct {
+		Name           string `json:"Name"`
+		WithDecryption bool   `json:"WithDecryption"`
+	}{name, decrypt}, &resp)
+	return resp.Parameter.Value, err
+}
+
+// RunShellCommand runs shell commands on instances and waits for them to finish, returning
+// the result on each instance
+func RunShellCommand(ctx context.Context, svc ssmInterface, instanceIds []string, commands []string) ([]CommandInvocation, error) {
+	id, err := svc.SendCommand(instanceIds, commands)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []CommandInvocation{}
+	for _, instance := range instanceIds {
+		status := "it hasn't started"
+		for {
+			if err := Sleep(ctx, 2*time.Second, fmt.Sprintf("command %s on %s", id, instance), status); err != nil {
+				return nil, err
+			}
+
+			inv, err := svc.GetCommandInvocation(id, instance)
+			if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "InvocationDoesNotExist" {
+				// invocations take a moment to appear after the command is sent
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("Failed to get the result of %s on %s: %v", id, instance, err)
+			}
+			if inv.Status != "Pending" && inv.Status != "InProgress" && inv.Status != "Delayed" {
+				results = append(results, inv)
+				break
+			}
+			status = "it was " + inv.Status
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/go-ini/ini"
+	"github.com/lox/ecsy/failure"
+)
+
+// The vendored aws-sdk-go predates IAM Identity Center, so SSO profiles are handled here.
+// Tokens are cached in ~/.aws/sso/cache in the same format as the aws cli, so logging in
+// with either works for both.
+
+// SSOProfile is a profile in the shared config that gets credentials from IAM Identity Center
+type SSOProfile struct {
+	Name      string
+	Session   string
+	StartURL  string
+	Region    string
+	AccountID string
+	RoleName  string
+}
+
+// ssoEndpoint returns the url of an IAM Identity Center api, overridden in tests
+var ssoEndpoint = func(service, region string) string {
+	return fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+}
+
+var ssoHTTP = &http.Client{Timeout: 30 * time.Second}
+
+// ProfileName returns the profile from the environment that sessions use
+func ProfileName() string {
+	if p := os.Getenv("AWS_PROFILE"); p != "" {
+		return p
+	}
+	if p := os.Getenv("AWS_DEFAULT_PROFILE"); p != "" {
+		return p
+	}
+	return "default"
+}
+
+func sharedConfigFile() string {
+	if f := os.Getenv("AWS_CONFIG_FILE"); f != "" {
+		return f
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "config")
+}
+
+// profileSection reads a profile from the shared config, returning nil if there isn't one
+func profileSection(name string) (*ini.File, *ini.Section, error) {
+	f, err := ini.Load(sharedConfigFile())
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	section, err := f.GetSection("profile " + name)
+	if err != nil && name == "default" {
+		section, err = f.GetSection("default")
+	}
+	if err != nil {
+		return f, nil, nil
+	}
+	return f, section, nil
+}
+
+// LoadSSOProfile reads a profile from the shared config, returning nil if it doesn't use
+// SSO. Both sso-session sections and the older sso_start_url keys are supported.
+func LoadSSOProfile(name string) (*SSOProfile, error) {
+	f, section, err := profileSection(name)
+	if err != nil || section == nil {
+		return nil, err
+	}
+
+	p := &SSOProfile{
+		Name:      name,
+		Session:   section.Key("sso_session").String(),
+		StartURL:  section.Key("sso_start_url").String(),
+		Region:    section.Key("sso_region").String(),
+		AccountID: section.Key("sso_account_id").String(),
+		RoleName:  section.Key("sso_role_name").String(),
+	}
+	if p.Session != "" {
+		s, err := f.GetSection("sso-session " + p.Session)
+		if err != nil {
+			return nil, fmt.Errorf("Profile %s refers to sso-session %s, which isn't in %s", name, p.Session, sharedConfigFile())
+		}
+		p.StartURL = s.Key("sso_start_url").String()
+		p.Region = s.Key("sso_region").String()
+	}
+
+	if p.StartURL == "" {
+		return nil, nil
+	} else if p.Region == "" {
+		return nil, fmt.Errorf("Profile %s has no sso_region", name)
+	}
+	return p, nil
+}
+
+// ssoToken is an access token cached by the aws cli's format
+type ssoToken struct {
+	StartURL     string    `json:"startUrl"`
+	Region       string    `json:"region"`
+	AccessToken  string    `json:"accessToken"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	ClientID     string    `json:"clientId,omitempty"`
+	ClientSecret string    `json:"clientSecret,omitempty"`
+}
+
+func (t *ssoToken) valid() bool {
+	return t.AccessToken != "" && time.Now().Add(time.Minute).Before(t.ExpiresAt)
+}
+
+// tokenCacheFile is named after the sso-session, or the start url for older profiles
+func (p *SSOProfile) tokenCacheFile() string {
+	key := p.StartURL
+	if p.Session != "" {
+		key = p.Session
+	}
+	sum := sha1.Sum([]byte(key))
+
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json")
+}
+
+func (p *SSOProfile) cachedToken() (*ssoToken, error) {
+	b, err := ioutil.ReadFile(p.tokenCacheFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var token ssoToken
+	if err = json.Unmarshal(b, &token); err != nil {
+		return nil, fmt.Errorf("Failed to read cached SSO token %s: %v", p.tokenCacheFile(), err)
+	}
+	return &token, nil
+}
+
+func (p *SSOProfile) cacheToken(token *ssoToken) error {
+	if err := os.MkdirAll(filepath.Dir(p.tokenCacheFile()), 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.tokenCacheFile(), b, 0600)
+}
+
+// LoggedIn returns when the cached token for the profile expires, if it hasn't yet
+func (p *SSOProfile) LoggedIn() (time.Time, bool) {
+	token, err := p.cachedToken()
+	if err != nil || token == nil || !token.valid() {
+		return time.Time{}, false
+	}
+	return token.ExpiresAt, true
+}
+
+// ssoError is an error response from the oidc or portal apis
+type ssoError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+	Message     string `json:"message"`
+}
+
+func (e *ssoError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	} else if e.Message != "" {
+		return e.Code + ": " + e.Message
+	}
+	return e.Code
+}
+
+// FailureKind treats every SSO failure as a credentials problem
+func (e *ssoError) FailureKind() failure.Kind {
+	return failure.Auth
+}
+
+func ssoCall(req *http.Request, out interface{}) error {
+	resp, err := ssoHTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := &ssoError{}
+		if json.NewDecoder(resp.Body).Decode(e) != nil || e.Code == "" {
+			e.Code = resp.Status
+		}
+		return e
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (p *SSOProfile) oidc(path string, in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", ssoEndpoint("oidc", p.Region)+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return ssoCall(req, out)
+}
+
+// DeviceAuthorization is what the user needs to approve a login in their browser
+type DeviceAuthorization struct {
+	UserCode                string `json:"userCode"`
+	VerificationURIComplete string `json:"verificationUriComplete"`
+	DeviceCode              string `json:"deviceCode"`
+	Interval                int    `json:"interval"`
+	ExpiresIn               int    `json:"expiresIn"`
+}
+
+// Login runs the device authorization flow, calling prompt with where to approve it and
+// caching the token once it has been
+func (p *SSOProfile) Login(ctx context.Context, prompt func(DeviceAuthorization)) error {
+	var client struct {
+		ClientID     string `json:"clientId"`
+		ClientSecret string `json:"clientSecret"`
+	}
+	err := p.oidc("/client/register", map[string]string{
+		"clientName": "ecsy",
+		"clientType": "public",
+	}, &client)
+	if err != nil {
+		return err
+	}
+
+	var auth DeviceAuthorization
+	err = p.oidc("/device_authorization", map[string]string{
+		"clientId":     client.ClientID,
+		"clientSecret": client.ClientSecret,
+		"startUrl":     p.StartURL,
+	}, &auth)
+	if err != nil {
+		return err
+	}
+	prompt(auth)
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+	defer cancel()
+
+	for {
+		if err = Sleep(ctx, interval, "the login to be approved", ""); err != nil {
+			return err
+		}
+
+		var resp struct {
+			AccessToken string `json:"accessToken"`
+			ExpiresIn   int    `json:"expiresIn"`
+		}
+		err = p.oidc("/token", map[string]string{
+			"clientId":     client.ClientID,
+			"clientSecret": client.ClientSecret,
+			"grantType":    "urn:ietf:params:oauth:grant-type:device_code",
+			"deviceCode":   auth.DeviceCode,
+		}, &resp)
+		if e, ok := err.(*ssoError); ok && e.Code == "authorization_pending" {
+			continue
+		} else if ok && e.Code == "slow_down" {
+			interval += 5 * time.Second
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		return p.cacheToken(&ssoToken{
+			StartURL:     p.StartURL,
+			Region:       p.Region,
+			AccessToken:  resp.AccessToken,
+			ExpiresAt:    time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second).UTC(),
+			ClientID:     client.ClientID,
+			ClientSecret: client.ClientSecret,
+		})
+	}
+}
+
+// ssoProvider gets role credentials for a profile with its cached token
+type ssoProvider struct {
+	credentials.Expiry
+	profile *SSOProfile
+}
+
+// NewSSOCredentials returns credentials for the account and role of an SSO profile, which
+// fail with a hint to run `ecsy login` once the cached token has expired
+func NewSSOCredentials(p *SSOProfile) *credentials.Credentials {
+	return credentials.NewCredentials(&ssoProvider{profile: p})
+}
+
+func (s *ssoProvider) Retrieve() (credentials.Value, error) {
+	p := s.profile
+	if p.AccountID == "" || p.RoleName == "" {
+		return credentials.Value{}, failure.Errorf(failure.Auth, "Profile %s needs sso_account_id and sso_role_name", p.Name)
+	}
+
+	token, err := p.cachedToken()
+	if err != nil {
+		return credentials.Value{}, err
+	} else if token == nil || !token.valid() {
+		return credentials.Value{}, failure.Errorf(failure.Auth,
+			"The SSO session for profile %s has expired, run `ecsy login --profile %s`", p.Name, p.Name)
+	}
+
+	req, err := http.NewRequest("GET", ssoEndpoint("portal.sso", p.Region)+"/federation/credentials?"+url.Values{
+		"account_id": {p.AccountID},
+		"role_name":  {p.RoleName},
+	}.Encode(), nil)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	req.Header.Set("x-amz-sso_bearer_token", token.AccessToken)
+
+	var resp struct {
+		RoleCredentials struct {
+			AccessKeyID     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      int64  `json:"expiration"`
+		} `json:"roleCredentials"`
+	}
+	if err = ssoCall(req, &resp); err != nil {
+		return credentials.Value{}, err
+	}
+
+	creds := resp.RoleCredentials
+	s.SetExpiration(time.Unix(0, creds.Expiration*int64(time.Millisecond)), time.Minute)
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    "SSOProvider",
+	}, nil
+}
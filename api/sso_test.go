@@ -0,0 +1,114 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lox/ecsy/failure"
+)
+
+const ssoConfig = `[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 111111111111
+sso_role_name = Admin
+
+[profile dev]
+sso_session = acme
+sso_account_id = 222222222222
+sso_role_name = Developer
+region = eu-west-1
+
+[sso-session acme]
+sso_start_url = https://acme.awsapps.com/start
+sso_region = eu-west-1
+
+[profile keys]
+region = us-east-1
+`
+
+func withSSOConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy-sso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "config"), []byte(ssoConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	home, config := os.Getenv("HOME"), os.Getenv("AWS_CONFIG_FILE")
+	os.Setenv("HOME", dir)
+	os.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Cleanup(func() {
+		os.Setenv("HOME", home)
+		os.Setenv("AWS_CONFIG_FILE", config)
+		os.RemoveAll(dir)
+	})
+}
+
+func TestLoadSSOProfile(t *testing.T) {
+	withSSOConfig(t)
+
+	p, err := LoadSSOProfile("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StartURL != "https://acme.awsapps.com/start" || p.Region != "eu-west-1" || p.RoleName != "Developer" {
+		t.Fatalf("Expected the sso-session to be used, got %#v", p)
+	}
+
+	if p, err = LoadSSOProfile("legacy"); err != nil || p.StartURL != "https://legacy.awsapps.com/start" {
+		t.Fatalf("Expected the legacy start url, got %#v, %v", p, err)
+	}
+
+	if p, err = LoadSSOProfile("keys"); err != nil || p != nil {
+		t.Fatalf("Expected no SSO profile for keys, got %#v, %v", p, err)
+	}
+}
+
+func TestSSOCredentials(t *testing.T) {
+	withSSOConfig(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-amz-sso_bearer_token") != "token" || r.URL.Query().Get("role_name") != "Developer" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Session token not found or invalid"}`))
+			return
+		}
+		w.Write([]byte(`{"roleCredentials":{"accessKeyId":"AKIA","secretAccessKey":"secret","sessionToken":"session","expiration":4102444800000}}`))
+	}))
+	defer server.Close()
+
+	endpoint := ssoEndpoint
+	ssoEndpoint = func(service, region string) string { return server.URL }
+	defer func() { ssoEndpoint = endpoint }()
+
+	p, err := LoadSSOProfile("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewSSOCredentials(p).Get(); failure.KindOf(err) != failure.Auth {
+		t.Fatalf("Expected an auth failure before logging in, got %v", err)
+	}
+
+	if err = p.cacheToken(&ssoToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.LoggedIn(); !ok {
+		t.Fatal("Expected to be logged in with the cached token")
+	}
+
+	creds, err := NewSSOCredentials(p).Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "AKIA" || creds.SessionToken != "session" {
+		t.Fatalf("Expected the role credentials, got %#v", creds)
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// stackIndexTTL is how long a stack index is used for before the stacks are listed again,
+// so that long running commands see stacks that other commands changed
+var stackIndexTTL = 30 * time.Second
+
+// stackIndex is the active stacks of an account, by the cluster they belong to and name
+type stackIndex struct {
+	stacks    []*cloudformation.Stack
+	byCluster map[string][]*cloudformation.Stack
+	byName    map[string]*cloudformation.Stack
+	loadedAt  time.Time
+}
+
+func newStackIndex(stacks []*cloudformation.Stack) *stackIndex {
+	idx := &stackIndex{
+		stacks:    stacks,
+		byCluster: map[string][]*cloudformation.Stack{},
+		byName:    map[string]*cloudformation.Stack{},
+		loadedAt:  time.Now(),
+	}
+	for _, stack := range stacks {
+		if cluster := StackCluster(stack); cluster != "" {
+			idx.byCluster[cluster] = append(idx.byCluster[cluster], stack)
+		}
+		idx.byName[aws.StringValue(stack.StackName)] = stack
+		idx.byName[aws.StringValue(stack.StackId)] = stack
+	}
+	return idx
+}
+
+// StackCluster returns the cluster a stack belongs to, from its ECSCluster output or the
+// cluster it was tagged with when it was created
+func StackCluster(stack *cloudformation.Stack) string {
+	if cluster, ok := GetStackOutputByKey(stack, "ECSCluster"); ok {
+		return cluster
+	}
+	return StackTags(stack)[TagCluster]
+}
+
+// stackIndexCfn lists the stacks of an account once and looks them up from an index,
+// rather than paging through every stack for each lookup. Concurrent lookups share the
+// same listing, and the index is dropped whenever a stack is changed or polled.
+type stackIndexCfn struct {
+	CFNAPI
+	mu         sync.Mutex
+	index      *stackIndex
+	loading    chan struct{}
+	generation int
+}
+
+// WithStackIndex returns services that look up stacks from an index of the account's
+// stacks, which suits commands that find many stacks in accounts with hundreds of them
+func WithStackIndex(svc Services) Services {
+	if indexedStacks(svc.Cloudformation) == nil {
+		svc.Cloudformation = &stackIndexCfn{CFNAPI: svc.Cloudformation}
+	}
+	return svc
+}
+
+// indexedStacks returns the stack index that svc looks up stacks with, if it has one
+func indexedStacks(svc CFNAPI) *stackIndexCfn {
+	for {
+		switch c := svc.(type) {
+		case *stackIndexCfn:
+			return c
+		case *parameterStoreCfn:
+			svc = c.CFNAPI
+		default:
+			return nil
+		}
+	}
+}
+
+// load returns the index, listing the stacks if it's missing or expired. Callers that
+// need it whilst it's being listed wait for that listing rather than starting another.
+func (c *stackIndexCfn) load() (*stackIndex, error) {
+	c.mu.Lock()
+	for {
+		if c.index != nil && time.Since(c.index.loadedAt) < stackIndexTTL {
+			idx := c.index
+			c.mu.Unlock()
+			return idx, nil
+		}
+		if c.loading == nil {
+			break
+		}
+		loading := c.loading
+		c.mu.Unlock()
+		<-loading
+		c.mu.Lock()
+	}
+	loading := make(chan struct{})
+	c.loading = loading
+	generation := c.generation
+	c.mu.Unlock()
+
+	stacks, err := findAllActiveStacks(c.CFNAPI)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loading = nil
+	close(loading)
+	if err != nil {
+		return nil, err
+	}
+	idx := newStackIndex(stacks)
+	// a stack that changed whilst listing might be stale, so the listing isn't kept
+	if generation == c.generation {
+		c.index = idx
+	}
+	return idx, nil
+}
+
+// cachedStack returns a stack by name or id if the index has been loaded and hasn't
+// expired, without listing stacks
+func (c *stackIndexCfn) cachedStack(name *string) *cloudformation.Stack {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.index != nil && time.Since(c.index.loadedAt) < stackIndexTTL {
+		return c.index.byName[aws.StringValue(name)]
+	}
+	return nil
+}
+
+func (c *stackIndexCfn) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.index = nil
+	c.generation++
+}
+
+// clusterStacks returns the active stacks that belong to a cluster
+func (c *stackIndexCfn) clusterStacks(cluster string) ([]*cloudformation.Stack, error) {
+	idx, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return idx.byCluster[cluster], nil
+}
+
+func (c *stackIndexCfn) DescribeStacksPages(input *cloudformation.DescribeStacksInput, fn func(*cloudformation.DescribeStacksOutput, bool) bool) error {
+	if input == nil || input.StackName == nil {
+		idx, err := c.load()
+		if err != nil {
+			return err
+		}
+		fn(&cloudformation.DescribeStacksOutput{Stacks: idx.stacks}, true)
+		return nil
+	}
+	if stack := c.cachedStack(input.StackName); stack != nil {
+		fn(&cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{stack}}, true)
+		return nil
+	}
+	return c.CFNAPI.DescribeStacksPages(input, fn)
+}
+
+func (c *stackIndexCfn) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	if input != nil && input.StackName != nil {
+		if stack := c.cachedStack(input.StackName); stack != nil {
+			return &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{stack}}, nil
+		}
+	}
+	return c.CFNAPI.DescribeStacks(input)
+}
+
+func (c *stackIndexCfn) DescribeStackEventsPages(input *cloudformation.DescribeStackEventsInput, fn func(*cloudformation.DescribeStackEventsOutput, bool) bool) error {
+	c.invalidate()
+	return c.CFNAPI.DescribeStackEventsPages(input, fn)
+}
+
+func (c *stackIndexCfn) CreateStack(input *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	defer c.invalidate()
+	return c.CFNAPI.CreateStack(input)
+}
+
+func (c *stackIndexCfn) UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	defer c.invalidate()
+	return c.CFNAPI.UpdateStack(input)
+}
+
+func (c *stackIndexCfn) DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	defer c.invalidate()
+	return c.CFNAPI.DeleteStack(input)
+}
+
+func (c *stackIndexCfn) CancelUpdateStack(input *cloudformation.CancelUpdateStackInput) (*cloudformation.CancelUpdateStackOutput, error) {
+	defer c.invalidate()
+	return c.CFNAPI.CancelUpdateStack(input)
+}
+
+func (c *stackIndexCfn) ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
+	defer c.invalidate()
+	return c.CFNAPI.ExecuteChangeSet(input)
+}
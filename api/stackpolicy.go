@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/yaml.v2"
+)
+
+// ProtectedResourceTypes are the types of resources that hold data or have DNS pointed at
+// them, which the stack policy of a stack prevents from being replaced or deleted
+var ProtectedResourceTypes = map[string]bool{
+	"AWS::RDS::DBInstance":                      true,
+	"AWS::RDS::DBCluster":                       true,
+	"AWS::ElastiCache::ReplicationGroup":        true,
+	"AWS::EFS::FileSystem":                      true,
+	"AWS::DynamoDB::Table":                      true,
+	"AWS::S3::Bucket":                           true,
+	"AWS::SecretsManager::Secret":               true,
+	"AWS::ElasticLoadBalancing::LoadBalancer":   true,
+	"AWS::ElasticLoadBalancingV2::LoadBalancer": true,
+	"AWS::CloudFront::Distribution":             true,
+}
+
+// allowAllStackPolicy allows every update, it overrides the stack policy for a single
+// update when replacements are allowed
+const allowAllStackPolicy = `{"Statement":[{"Effect":"Allow","Action":"Update:*","Principal":"*","Resource":"*"}]}`
+
+type stackPolicyStatement struct {
+	Effect    string
+	Action    interface{}
+	Principal string
+	Resource  interface{}
+}
+
+// ProtectedResources returns the logical ids of the resources in a template that its stack
+// policy protects
+func ProtectedResources(body string) []string {
+	var tpl struct {
+		Resources map[string]struct {
+			Type string `yaml:"Type"`
+		} `yaml:"Resources"`
+	}
+	if err := yaml.Unmarshal([]byte(body), &tpl); err != nil {
+		return nil
+	}
+
+	protected := []string{}
+	for id, r := range tpl.Resources {
+		if ProtectedResourceTypes[r.Type] {
+			protected = append(protected, id)
+		}
+	}
+	sort.Strings(protected)
+	return protected
+}
+
+// StackPolicy returns the stack policy for a template, which allows every update except
+// replacing or deleting its protected resources
+func StackPolicy(body string) string {
+	statements := []stackPolicyStatement{
+		{Effect: "Allow", Action: "Update:*", Principal: "*", Resource: "*"},
+	}
+	if protected := ProtectedResources(body); len(protected) > 0 {
+		resources := []string{}
+		for _, id := range protected {
+			resources = append(resources, "LogicalResourceId/"+id)
+		}
+		statements = append(statements, stackPolicyStatement{
+			Effect: "Deny", Action: []string{"Update:Replace", "Update:Delete"}, Principal: "*", Resource: resources,
+		})
+	}
+
+	b, _ := json.Marshal(map[string]interface{}{"Statement": statements})
+	return string(b)
+}
+
+// SetStackPolicy replaces the stack policy of a stack
+func SetStackPolicy(svc CFNAPI, stackName, policy string) error {
+	_, err := svc.SetStackPolicy(&cloudformation.SetStackPolicyInput{
+		StackName:       aws.String(stackName),
+		StackPolicyBody: aws.String(policy),
+	})
+	return err
+}
+
+// AllowReplacements lets the next update of a stack replace or delete its protected resources,
+// until the stack policy is set again
+func AllowReplacements(svc CFNAPI, stackName string) error {
+	return SetStackPolicy(svc, stackName, allowAllStackPolicy)
+}
+
+// ProtectedChanges returns the changes in a change set that replace or remove a resource
+// of a protected type
+func ProtectedChanges(cs *ChangeSet) []*cloudformation.ResourceChange {
+	changes := []*cloudformation.ResourceChange{}
+	for _, c := range cs.Changes {
+		if !ProtectedResourceTypes[aws.StringValue(c.ResourceType)] {
+			continue
+		}
+		if r := aws.StringValue(c.Replacement); aws.StringValue(c.Action) == cloudformation.ChangeActionRemove || r == "True" || r == "Conditional" {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+// The vendored cloudformation client predates stack sets, so they are called directly
+type stackSetInterface interface {
+	DescribeStackSet(name string) (*StackSet, error)
+	CreateStackSet(input *StackSetInput) error
+	UpdateStackSet(input *StackSetInput) (string, error)
+	CreateStackInstances(name string, targets StackSetTargets) (string, error)
+	DescribeStackSetOperation(name, operationID string) (*StackSetOperation, error)
+	ListStackInstances(name string) ([]StackInstance, error)
+}
+
+var ErrStackSetNotFound = errors.New("Stack set not found")
+
+type StackSet struct {
+	Name            string `xml:"StackSetName"`
+	Status          string `xml:"Status"`
+	PermissionModel string `xml:"PermissionModel"`
+}
+
+type StackSetInput struct {
+	Name         string
+	TemplateBody string
+	Parameters   map[string]string
+	// ServiceManaged deploys to organizational units via AWS Organizations rather than
+	// to accounts that have the stack set administration roles
+	ServiceManaged bool
+}
+
+// StackSetTargets are the accounts or organizational units and regions to deploy stack instances to
+type StackSetTargets struct {
+	Accounts            []string
+	OrganizationalUnits []string
+	Regions             []string
+}
+
+type StackSetOperation struct {
+	ID           string `xml:"OperationId"`
+	Action       string `xml:"Action"`
+	Status       string `xml:"Status"`
+	StatusReason string `xml:"StatusReason"`
+}
+
+type StackInstance struct {
+	Account            string `xml:"Account"`
+	Region             string `xml:"Region"`
+	OrganizationalUnit string `xml:"OrganizationalUnitId"`
+	Status             string `xml:"Status"`
+	DetailedStatus     string `xml:"StackInstanceStatus>DetailedStatus"`
+	StatusReason       string `xml:"StatusReason"`
+}
+
+type stackSetClient struct {
+	*queryClient
+}
+
+func newStackSetClient(p client.ConfigProvider) *stackSetClient {
+	return &stackSetClient{newQueryClient(p, "cloudformation", "2010-05-15")}
+}
+
+func (c *stackSetClient) DescribeStackSet(name string) (*StackSet, error) {
+	var resp struct {
+		StackSet StackSet `xml:"DescribeStackSetResult>StackSet"`
+	}
+	err := c.Call("DescribeStackSet", url.Values{"StackSetName": {name}}, &resp)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "StackSetNotFoundException" {
+		return nil, ErrStackSetNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &resp.StackSet, nil
+}
+
+func (c *stackSetClient) stackSetParams(input *StackSetInput) url.Values {
+	params := url.Values{
+		"StackSetName":          {input.Name},
+		"TemplateBody":          {input.TemplateBody},
+		"Capabilities.member.1": {"CAPABILITY_NAMED_IAM"},
+	}
+	for idx, k := range sortedKeys(input.Parameters) {
+		prefix := "Parameters.member." + strconv.Itoa(idx+1)
+		params.Set(prefix+".ParameterKey", k)
+		params.Set(prefix+".ParameterValue", input.Parameters[k])
+	}
+	if input.ServiceManaged {
+		params.Set("PermissionModel", "SERVICE_MANAGED")
+		params.Set("AutoDeployment.Enabled", "true")
+		params.Set("AutoDeployment.RetainStacksOnAccountRemoval", "false")
+	}
+	return params
+}
+
+func (c *stackSetClient) CreateStackSet(input *StackSetInput) error {
+	return c.Call("CreateStackSet", c.stackSetParams(input), nil)
+}
+
+func (c *stackSetClient) UpdateStackSet(input *StackSetInput) (string, error) {
+	var resp struct {
+		OperationID string `xml:"UpdateStackSetResult>OperationId"`
+	}
+	if err := c.Call("UpdateStackSet", c.stackSetParams(input), &resp); err != nil {
+		return "", err
+	}
+	return resp.OperationID, nil
+}
+
+func (c *stackSetClient) CreateStackInstances(name string, targets StackSetTargets) (string, error) {
+	params := url.Values{"StackSetName": {name}}
+	addMembers(params, "Regions", targets.Regions)
+	if len(targets.OrganizationalUnits) > 0 {
+		addMembers(params, "DeploymentTargets.OrganizationalUnitIds", targets.OrganizationalUnits)
+	} else {
+		addMembers(params, "Accounts", targets.Accounts)
+	}
+
+	var resp struct {
+		OperationID string `xml:"CreateStackInstancesResult>OperationId"`
+	}
+	if err := c.Call("CreateStackInstances", params, &resp); err != nil {
+		return "", err
+	}
+	return resp.OperationID, nil
+}
+
+func (c *stackSetClient) DescribeStackSetOperation(name, operationID string) (*StackSetOperation, error) {
+	var resp struct {
+		Operation StackSetOperation `xml:"DescribeStackSetOperationResult>StackSetOperation"`
+	}
+	err := c.Call("DescribeStackSetOperation", url.Values{
+		"StackSetName": {name},
+		"OperationId":  {operationID},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Operation, nil
+}
+
+func (c *stackSetClient) ListStackInstances(name string) ([]StackInstance, error) {
+	instances := []StackInstance{}
+	params := url.Values{"StackSetName": {name}}
+
+	for {
+		var resp struct {
+			Summaries []StackInstance `xml:"ListStackInstancesResult>Summaries>member"`
+			NextToken string          `xml:"ListStackInstancesResult>NextToken"`
+		}
+		if err := c.Call("ListStackInstances", params, &resp); err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.Summaries...)
+		if resp.NextToken == "" {
+			return instances, nil
+		}
+		params.Set("NextToken", resp.NextToken)
+	}
+}
+
+func addMembers(params url.Values, name string, values []string) {
+	for idx, v := range values {
+		params.Set(name+".member."+strconv.Itoa(idx+1), v)
+	}
+}
+
+// PollStackSetOperation waits for a stack set operation to finish
+func PollStackSetOperation(ctx context.Context, svc stackSetInterface, name, operationID string) error {
+	for {
+		op, err := svc.DescribeStackSetOperation(name, operationID)
+		if err != nil {
+			return err
+		}
+
+		switch op.Status {
+		case "SUCCEEDED":
+			return nil
+		case "FAILED", "STOPPED":
+			return fmt.Errorf("Stack set operation %s %s: %s", operationID, op.Status, op.StatusReason)
+		}
+
+		if err = Sleep(ctx, 5*time.Second, "stack set operation "+operationID, "it was "+op.Status); err != nil {
+			return err
+		}
+	}
+}
+
+// MissingStackInstances returns the regions and accounts in targets that don't yet have
+// stack instances. Organizational units are always returned with the missing regions,
+// as the accounts within them are managed by automatic deployment.
+func MissingStackInstances(existing []StackInstance, targets StackSetTargets) StackSetTargets {
+	have := map[string]bool{}
+	haveRegion := map[string]bool{}
+	for _, i := range existing {
+		have[i.Account+"/"+i.Region] = true
+		haveRegion[i.Region] = true
+	}
+
+	missing := StackSetTargets{OrganizationalUnits: targets.OrganizationalUnits}
+	regions := map[string]bool{}
+	accounts := map[string]bool{}
+
+	for _, region := range targets.Regions {
+		if len(targets.OrganizationalUnits) > 0 && !haveRegion[region] {
+			regions[region] = true
+		}
+		for _, account := range targets.Accounts {
+			if !have[account+"/"+region] {
+				regions[region] = true
+				accounts[account] = true
+			}
+		}
+	}
+
+	for _, region := range targets.Regions {
+		if regions[region] {
+			missing.Regions = append(missing.Regions, region)
+		}
+	}
+	for _, account := range targets.Accounts {
+		if accounts[account] {
+			missing.Accounts = append(missing.Accounts, account)
+		}
+	}
+	return missing
+}
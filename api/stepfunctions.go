@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+)
+
+type stepFunctionsInterface interface {
+	StartExecution(stateMachineArn, input string) (string, error)
+	DescribeExecution(executionArn string) (Execution, error)
+	ListExecutions(stateMachineArn string, max int) ([]Execution, error)
+}
+
+// Execution is a run of a step functions state machine
+type Execution struct {
+	ExecutionArn string
+	Name         string
+	Status       string
+	StartDate    time.Time
+	StopDate     time.Time
+	Output       string
+	Error        string
+	Cause        string
+}
+
+// step functions returns timestamps as fractional epoch seconds
+type epochTime float64
+
+func (t epochTime) Time() time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(float64(t)*float64(time.Second)))
+}
+
+type sfnExecution struct {
+	ExecutionArn string    `json:"executionArn"`
+	Name         string    `json:"name"`
+	Status       string    `json:"status"`
+	StartDate    epochTime `json:"startDate"`
+	StopDate     epochTime `json:"stopDate"`
+	Output       string    `json:"output"`
+	Error        string    `json:"error"`
+	Cause        string    `json:"cause"`
+}
+
+func (e sfnExecution) execution() Execution {
+	return Execution{
+		ExecutionArn: e.ExecutionArn,
+		Name:         e.Name,
+		Status:       e.Status,
+		StartDate:    e.StartDate.Time(),
+		StopDate:     e.StopDate.Time(),
+		Output:       e.Output,
+		Error:        e.Error,
+		Cause:        e.Cause,
+	}
+}
+
+type stepFunctionsClient struct {
+	*jsonClient
+}
+
+func newStepFunctionsClient(p client.ConfigProvider) *stepFunctionsClient {
+	return &stepFunctionsClient{newJSONClient(p, "states", "AWSStepFunctions", "1.0")}
+}
+
+func (c *stepFunctionsClient) StartExecution(stateMachineArn, input string) (string, error) {
+	if input == "" {
+		input = "{}"
+	}
+	var resp struct {
+		ExecutionArn string `json:"executionArn"`
+	}
+	err := c.Call("StartExecution", &struct {
+		StateMachineArn string `json:"stateMachineArn"`
+		Input           string `json:"input"`
+	}{stateMachineArn, input}, &resp)
+	return resp.ExecutionArn, err
+}
+
+func (c *stepFunctionsClient) DescribeExecution(executionArn string) (Execution, error) {
+	var resp sfnExecution
+	err := c.Call("DescribeExecution", &struct {
+		ExecutionArn string `json:"executionArn"`
+	}{executionArn}, &resp)
+	return resp.execution(), err
+}
+
+func (c *stepFunctionsClient) ListExecutions(stateMachineArn string, max int) ([]Execution, error) {
+	var resp struct {
+		Executions []sfnExecution `json:"executions"`
+	}
+	err := c.Call("ListExecutions", &struct {
+		StateMachineArn string `json:"stateMachineArn"`
+		MaxResults      int    `json:"maxResults"`
+	}{stateMachineArn, max}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	executions := []Execution{}
+	for _, e := range resp.Executions {
+		executions = append(executions, e.execution())
+	}
+	return executions, nil
+}
+
+// PollExecution waits for an execution to finish, returning its final state
+func PollExecution(ctx context.Context, svc stepFunctionsInterface, executionArn string) (Execution, error) {
+	for {
+		e, err := svc.DescribeExecution(executionArn)
+		if err != nil || e.Status != "RUNNING" {
+			return e, err
+		}
+		if err = Sleep(ctx, 10*time.Second, "execution "+executionArn, "it was RUNNING"); err != nil {
+			return e, err
+		}
+	}
+}
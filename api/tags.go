@@ -0,0 +1,152 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// ToolVersion is the version of ecsy recorded on the stacks it creates and updates
+var ToolVersion = "dev"
+
+// ToolCommand is the ecsy command being run, like "jobs deploy", which is recorded on the
+// stacks it creates and updates
+var ToolCommand string
+
+// Tags that ecsy records on stacks, so it's possible to tell which version created a stack
+const (
+	TagVersion          = "ecsy:version"
+	TagTemplateChecksum = "ecsy:template-sha256"
+	TagCommand          = "ecsy:command"
+	TagCluster          = "ecsy:cluster"
+)
+
+// maximum length of a tag value in cloudformation
+const maxTagValue = 256
+
+// TemplateChecksum returns the sha256 of a template body
+func TemplateChecksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// StackTags returns the tags of a stack as a map
+func StackTags(stack *cloudformation.Stack) map[string]string {
+	tags := map[string]string{}
+	for _, t := range stack.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags
+}
+
+// stackTags are the tags for creating or updating a stack with a template and parameters,
+// keeping any existing tags that ecsy doesn't manage. Stacks with an ECSCluster parameter
+// are tagged with it, so they're found for the cluster even without outputs.
+func stackTags(body string, params map[string]string, existing map[string]string) []*cloudformation.Tag {
+	tags := map[string]string{}
+	for k, v := range existing {
+		tags[k] = v
+	}
+	if cluster := params["ECSCluster"]; cluster != "" {
+		tags[TagCluster] = cluster
+	}
+	tags[TagVersion] = ToolVersion
+	tags[TagTemplateChecksum] = TemplateChecksum(body)
+	if command := invocation(os.Args[1:]); command != "" {
+		tags[TagCommand] = command
+	}
+
+	result := []*cloudformation.Tag{}
+	for _, k := range sortedKeys(tags) {
+		result = append(result, &cloudformation.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return result
+}
+
+// existingStackTags returns the tags on a stack that is being updated
+func existingStackTags(svc CFNAPI, name string) (map[string]string, error) {
+	resp, err := svc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Stacks) == 0 {
+		return map[string]string{}, nil
+	}
+	return StackTags(resp.Stacks[0]), nil
+}
+
+// invocation describes the command ecsy was run with by its name and the names of the flags
+// it was given. Flag values aren't included, as they can be secrets and would change the
+// tags on every update. It's truncated to fit in a tag.
+func invocation(args []string) string {
+	words := []string{}
+	if ToolCommand != "" {
+		words = append(words, ToolCommand)
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		} else if strings.HasPrefix(arg, "--") {
+			words = append(words, strings.SplitN(arg, "=", 2)[0])
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			words = append(words, arg[:2])
+		}
+	}
+	return truncate(strings.Join(words, " "), maxTagValue)
+}
+
+// truncate shortens s to at most n bytes, without splitting a multi byte character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// safeFlagValues are the flags that RedactArgs keeps the values of, which say what a command
+// ran against rather than carry secrets
+var safeFlagValues = map[string]bool{
+	"cluster": true,
+	"service": true,
+	"env":     true,
+	"stack":   true,
+	"region":  true,
+}
+
+// RedactArgs returns the flags of a command line without their values, other than those in
+// safeFlagValues. Arguments that aren't flags are dropped, as they can't be told apart from
+// the values of flags.
+func RedactArgs(args []string) []string {
+	redacted := []string{}
+	keepNext := false
+	for _, arg := range args {
+		switch {
+		case arg == "--":
+			return redacted
+		case strings.HasPrefix(arg, "--"):
+			name := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+			keepNext = len(name) == 1 && safeFlagValues[name[0]]
+			if len(name) == 2 && safeFlagValues[name[0]] {
+				redacted = append(redacted, arg)
+			} else {
+				redacted = append(redacted, "--"+name[0])
+			}
+		case keepNext:
+			redacted = append(redacted, arg)
+			keepNext = false
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			redacted = append(redacted, arg[:2])
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInvocationOnlyHasFlagNames(t *testing.T) {
+	ToolCommand = "create-db"
+	defer func() { ToolCommand = "" }()
+
+	s := invocation([]string{"create-db", "--cluster", "example", "--master-password", "hunter2", "--datadog-key=abc", "-s", "web"})
+	if s != "create-db --cluster --master-password --datadog-key -s" {
+		t.Fatalf("Unexpected invocation %q", s)
+	}
+}
+
+func TestTruncateKeepsCharactersWhole(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"llamas", 10, "llamas"},
+		{"llamas", 3, "lla"},
+		{"naïve", 3, "na"},
+		{"naïve", 4, "naï"},
+	} {
+		if got := truncate(tc.s, tc.n); got != tc.expected {
+			t.Errorf("truncate(%q, %d) = %q, expected %q", tc.s, tc.n, got, tc.expected)
+		}
+	}
+}
+
+func TestRedactArgsKeepsSafeValues(t *testing.T) {
+	args := RedactArgs([]string{"deploy", "--cluster", "example", "--service=web", "--pagerduty-key", "abc", "--opsgenie-key=def", "-s", "web", "--", "--cluster"})
+	expected := []string{"--cluster", "example", "--service=web", "--pagerduty-key", "--opsgenie-key", "-s"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected %v, got %v", expected, args)
+	}
+}
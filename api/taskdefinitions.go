@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+var invalidPortNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// PortNames are names for the port mappings of a task's containers, keyed by container
+// name and then container port. Service connect refers to ports by name, which the vendored
+// sdk predates.
+type PortNames map[string]map[int64]string
+
+// PortName returns the name ecsy gives a container's port mapping, like "web-8080"
+func PortName(container string, port int64) string {
+	name := invalidPortNameChars.ReplaceAllString(strings.ToLower(container), "-")
+	return strings.TrimLeft(fmt.Sprintf("%s-%d", name, port), "-_")
+}
+
+// ContainerFields are fields of container definitions that the vendored sdk predates, like
+// systemControls and linuxParameters, keyed by container name and then the field's json name
+type ContainerFields map[string]map[string]interface{}
+
+type taskDefinitionInterface interface {
+	RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput, names PortNames, fields ContainerFields) (*ecs.RegisterTaskDefinitionOutput, error)
+	RegisterRawTaskDefinition(body []byte) (*ecs.RegisterTaskDefinitionOutput, error)
+	DescribeTaskDefinitionSecrets(taskDefinition string) (map[string][]TaskSecret, error)
+	DescribeTaskDefinitionRoles(taskDefinition string) (TaskRoles, error)
+}
+
+// TaskRoles are the roles that a task definition's tasks run with. The vendored sdk
+// predates execution roles.
+type TaskRoles struct {
+	TaskRoleArn      string `json:"taskRoleArn"`
+	ExecutionRoleArn string `json:"executionRoleArn"`
+}
+
+// readOnlyTaskDefinitionFields are set by ECS when a task definition is registered, so are
+// dropped from raw task definitions, such as the output of `aws ecs describe-task-definition`
+var readOnlyTaskDefinitionFields = []string{
+	"taskDefinitionArn", "revision", "status", "requiresAttributes", "compatibilities",
+	"registeredAt", "registeredBy", "deregisteredAt",
+}
+
+type taskDefinitionClient struct {
+	*jsonClient
+}
+
+func newTaskDefinitionClient(p client.ConfigProvider) *taskDefinitionClient {
+	return &taskDefinitionClient{newJSONClient(p, "ecs", "AmazonEC2ContainerServiceV20141113", "1.1")}
+}
+
+// RegisterTaskDefinition registers a task definition with its port mappings named and the
+// containers' extra fields. The input is marshalled like the sdk does and the names and
+// fields are added to the result.
+func (c *taskDefinitionClient) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput, names PortNames, containerFields ContainerFields) (*ecs.RegisterTaskDefinitionOutput, error) {
+	fields, err := taskDefinitionFields(input, names, containerFields)
+	if err != nil {
+		return nil, err
+	}
+	return c.register(fields)
+}
+
+// TaskDefinitionJSON returns the json that registering a task definition sends to the ECS
+// api, with its port mappings named and the containers' extra fields
+func TaskDefinitionJSON(input *ecs.RegisterTaskDefinitionInput, names PortNames, containerFields ContainerFields) ([]byte, error) {
+	fields, err := taskDefinitionFields(input, names, containerFields)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// RawTaskDefinitionJSON returns the json that registering a raw task definition sends to
+// the ECS api
+func RawTaskDefinitionJSON(body []byte) ([]byte, error) {
+	fields, err := rawTaskDefinitionFields(body)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+func taskDefinitionFields(input *ecs.RegisterTaskDefinitionInput, names PortNames, containerFields ContainerFields) (map[string]interface{}, error) {
+	b, err := jsonutil.BuildJSON(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	defs, _ := fields["containerDefinitions"].([]interface{})
+	for _, d := range defs {
+		def, _ := d.(map[string]interface{})
+		container, _ := def["name"].(string)
+		for field, value := range containerFields[container] {
+			def[field] = value
+		}
+		mappings, _ := def["portMappings"].([]interface{})
+		for _, m := range mappings {
+			mapping, _ := m.(map[string]interface{})
+			port, _ := mapping["containerPort"].(float64)
+			if name, ok := names[container][int64(port)]; ok {
+				mapping["name"] = name
+			}
+		}
+	}
+	return fields, nil
+}
+
+// RegisterRawTaskDefinition registers a task definition from the json that the ECS api
+// takes, passing through any fields that the vendored sdk doesn't know about
+func (c *taskDefinitionClient) RegisterRawTaskDefinition(body []byte) (*ecs.RegisterTaskDefinitionOutput, error) {
+	fields, err := rawTaskDefinitionFields(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.register(fields)
+}
+
+// ParseRawTaskDefinition parses the json of a raw task definition into the fields the
+// vendored sdk knows about, for describing what it changes
+func ParseRawTaskDefinition(body []byte) (*ecs.RegisterTaskDefinitionInput, error) {
+	fields, err := rawTaskDefinitionFields(body)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	input := &ecs.RegisterTaskDefinitionInput{}
+	return input, jsonutil.UnmarshalJSON(input, bytes.NewReader(b))
+}
+
+// rawTaskDefinitionFields decodes a raw task definition and drops the fields that ECS sets
+func rawTaskDefinitionFields(body []byte) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("Failed to parse task definition: %v", err)
+	}
+	if wrapped, ok := fields["taskDefinition"].(map[string]interface{}); ok {
+		fields = wrapped
+	}
+	for _, field := range readOnlyTaskDefinitionFields {
+		delete(fields, field)
+	}
+	if family, _ := fields["family"].(string); family == "" {
+		return nil, fmt.Errorf("Task definition has no family")
+	}
+	return fields, nil
+}
+
+func (c *taskDefinitionClient) register(fields map[string]interface{}) (*ecs.RegisterTaskDefinitionOutput, error) {
+	var resp struct {
+		TaskDefinition json.RawMessage `json:"taskDefinition"`
+	}
+	if err := c.Call("RegisterTaskDefinition", &fields, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.TaskDefinition) == 0 {
+		return nil, fmt.Errorf("No task definition registered for %v", fields["family"])
+	}
+
+	taskDefinition := &ecs.TaskDefinition{}
+	if err := jsonutil.UnmarshalJSON(taskDefinition, bytes.NewReader(resp.TaskDefinition)); err != nil {
+		return nil, err
+	}
+	return &ecs.RegisterTaskDefinitionOutput{TaskDefinition: taskDefinition}, nil
+}
+
+// DescribeTaskDefinitionSecrets returns the secrets of each of a task definition's containers
+func (c *taskDefinitionClient) DescribeTaskDefinitionSecrets(taskDefinition string) (map[string][]TaskSecret, error) {
+	var resp struct {
+		TaskDefinition struct {
+			ContainerDefinitions []struct {
+				Name    string       `json:"name"`
+				Secrets []TaskSecret `json:"secrets"`
+			} `json:"containerDefinitions"`
+		} `json:"taskDefinition"`
+	}
+	err := c.Call("DescribeTaskDefinition", &struct {
+		TaskDefinition string `json:"taskDefinition"`
+	}{taskDefinition}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := map[string][]TaskSecret{}
+	for _, def := range resp.TaskDefinition.ContainerDefinitions {
+		if len(def.Secrets) > 0 {
+			secrets[def.Name] = def.Secrets
+		}
+	}
+	return secrets, nil
+}
+
+// DescribeTaskDefinitionRoles returns the task and execution roles of a task definition
+func (c *taskDefinitionClient) DescribeTaskDefinitionRoles(taskDefinition string) (TaskRoles, error) {
+	var resp struct {
+		TaskDefinition TaskRoles `json:"taskDefinition"`
+	}
+	err := c.Call("DescribeTaskDefinition", &struct {
+		TaskDefinition string `json:"taskDefinition"`
+	}{taskDefinition}, &resp)
+	return resp.TaskDefinition, err
+}
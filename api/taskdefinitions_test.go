@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestTaskDefinitionJSON(t *testing.T) {
+	input := &ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("proxy"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("haproxy"), PortMappings: []*ecs.PortMapping{{ContainerPort: aws.Int64(80)}}},
+			{Name: aws.String("web")},
+		},
+	}
+	controls := []interface{}{map[string]interface{}{"namespace": "net.core.somaxconn", "value": "1024"}}
+
+	b, err := TaskDefinitionJSON(input, PortNames{"haproxy": {80: "haproxy-80"}}, ContainerFields{"haproxy": {"systemControls": controls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields struct {
+		ContainerDefinitions []map[string]interface{} `json:"containerDefinitions"`
+	}
+	if err = json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	haproxy, web := fields.ContainerDefinitions[0], fields.ContainerDefinitions[1]
+	if !reflect.DeepEqual(haproxy["systemControls"], controls) {
+		t.Errorf("Expected the container's system controls, got %v", haproxy["systemControls"])
+	}
+	if mapping := haproxy["portMappings"].([]interface{})[0].(map[string]interface{}); mapping["name"] != "haproxy-80" {
+		t.Errorf("Expected the port mapping to be named, got %v", mapping)
+	}
+	if _, ok := web["systemControls"]; ok {
+		t.Errorf("Expected only haproxy to have system controls, got %v", web)
+	}
+}
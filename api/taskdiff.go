@@ -0,0 +1,157 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DiffTaskDefinitions describes how a task definition would change when registering next,
+// ignoring the defaults that ECS fills in. No changes means registering it is a no-op.
+func DiffTaskDefinitions(current, next *ecs.RegisterTaskDefinitionInput) []string {
+	changes := []string{}
+	changed := func(what, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", what, quoteOrNone(from), quoteOrNone(to)))
+		}
+	}
+
+	changed("task role", aws.StringValue(current.TaskRoleArn), aws.StringValue(next.TaskRoleArn))
+	changed("network mode", aws.StringValue(current.NetworkMode), aws.StringValue(next.NetworkMode))
+	changed("volumes", volumeNames(current.Volumes), volumeNames(next.Volumes))
+
+	containers := map[string]*ecs.ContainerDefinition{}
+	for _, def := range current.ContainerDefinitions {
+		containers[aws.StringValue(def.Name)] = def
+	}
+
+	for _, def := range next.ContainerDefinitions {
+		name := aws.StringValue(def.Name)
+		before, ok := containers[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("container %s: added", name))
+			continue
+		}
+		delete(containers, name)
+
+		for _, field := range containerFields {
+			changed(name+" "+field.Name, field.Value(before), field.Value(def))
+		}
+
+		env, nextEnv := environmentMap(before.Environment), environmentMap(def.Environment)
+		for _, key := range unionKeys(env, nextEnv) {
+			if sensitiveName.MatchString(key) && env[key] != nextEnv[key] {
+				changes = append(changes, fmt.Sprintf("%s env %s: %s → %s", name, key, redacted(env[key]), redacted(nextEnv[key])))
+				continue
+			}
+			changed(name+" env "+key, env[key], nextEnv[key])
+		}
+	}
+
+	removed := []string{}
+	for name := range containers {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, fmt.Sprintf("container %s: removed", name))
+	}
+
+	return changes
+}
+
+var containerFields = []struct {
+	Name  string
+	Value func(*ecs.ContainerDefinition) string
+}{
+	{"image", func(d *ecs.ContainerDefinition) string { return aws.StringValue(d.Image) }},
+	{"command", func(d *ecs.ContainerDefinition) string { return strings.Join(aws.StringValueSlice(d.Command), " ") }},
+	{"entrypoint", func(d *ecs.ContainerDefinition) string { return strings.Join(aws.StringValueSlice(d.EntryPoint), " ") }},
+	{"cpu", func(d *ecs.ContainerDefinition) string { return int64OrEmpty(d.Cpu) }},
+	{"memory", func(d *ecs.ContainerDefinition) string { return int64OrEmpty(d.Memory) }},
+	{"memory reservation", func(d *ecs.ContainerDefinition) string { return int64OrEmpty(d.MemoryReservation) }},
+	{"essential", func(d *ecs.ContainerDefinition) string { return fmt.Sprintf("%t", d.Essential == nil || *d.Essential) }},
+	{"ports", func(d *ecs.ContainerDefinition) string { return portMappings(d.PortMappings) }},
+	{"links", func(d *ecs.ContainerDefinition) string { return strings.Join(aws.StringValueSlice(d.Links), ",") }},
+	{"user", func(d *ecs.ContainerDefinition) string { return aws.StringValue(d.User) }},
+	{"privileged", func(d *ecs.ContainerDefinition) string { return fmt.Sprintf("%t", aws.BoolValue(d.Privileged)) }},
+	{"read-only root filesystem", func(d *ecs.ContainerDefinition) string {
+		return fmt.Sprintf("%t", aws.BoolValue(d.ReadonlyRootFilesystem))
+	}},
+	{"log driver", func(d *ecs.ContainerDefinition) string {
+		if d.LogConfiguration == nil {
+			return ""
+		}
+		return aws.StringValue(d.LogConfiguration.LogDriver)
+	}},
+}
+
+// sensitiveName matches environment variables that are likely to hold secrets, which
+// are redacted from diffs
+var sensitiveName = regexp.MustCompile(`(?i)(secret|passw(or)?d|token|credential|private|api_?key|access_?key)`)
+
+func redacted(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return "(redacted)"
+}
+
+func quoteOrNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+func int64OrEmpty(v *int64) string {
+	if v == nil || *v == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", *v)
+}
+
+func volumeNames(volumes []*ecs.Volume) string {
+	names := []string{}
+	for _, v := range volumes {
+		names = append(names, aws.StringValue(v.Name))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func portMappings(mappings []*ecs.PortMapping) string {
+	ports := []string{}
+	for _, m := range mappings {
+		protocol := aws.StringValue(m.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		ports = append(ports, fmt.Sprintf("%d:%d/%s", aws.Int64Value(m.HostPort), aws.Int64Value(m.ContainerPort), protocol))
+	}
+	sort.Strings(ports)
+	return strings.Join(ports, ",")
+}
+
+func environmentMap(env []*ecs.KeyValuePair) map[string]string {
+	m := map[string]string{}
+	for _, kv := range env {
+		m[aws.StringValue(kv.Name)] = aws.StringValue(kv.Value)
+	}
+	return m
+}
+
+func unionKeys(a, b map[string]string) []string {
+	keys := sortedKeys(a)
+	for _, k := range sortedKeys(b) {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
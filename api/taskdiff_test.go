@@ -0,0 +1,46 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestDiffTaskDefinitions(t *testing.T) {
+	current := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1"), Cpu: aws.Int64(0), Essential: aws.Bool(true),
+				Environment: []*ecs.KeyValuePair{
+					{Name: aws.String("LEVEL"), Value: aws.String("debug")},
+					{Name: aws.String("DB_PASSWORD"), Value: aws.String("hunter2")},
+				}},
+			{Name: aws.String("sidecar"), Image: aws.String("sidecar:latest")},
+		},
+	}
+	next := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v2"),
+				Environment: []*ecs.KeyValuePair{
+					{Name: aws.String("LEVEL"), Value: aws.String("info")},
+					{Name: aws.String("DB_PASSWORD"), Value: aws.String("hunter3")},
+				}},
+		},
+	}
+
+	changes := DiffTaskDefinitions(current, next)
+	expected := []string{
+		`app image: "app:v1" → "app:v2"`,
+		`app env DB_PASSWORD: (redacted) → (redacted)`,
+		`app env LEVEL: "debug" → "info"`,
+		`container sidecar: removed`,
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Unexpected changes %#v", changes)
+	}
+
+	if changes := DiffTaskDefinitions(next, next); len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %#v", changes)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	logs "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Diagnosis is a summary of why a task stopped
+type Diagnosis struct {
+	StopCode   string
+	Causes     []string
+	Containers []ContainerDiagnosis
+}
+
+type ContainerDiagnosis struct {
+	Name     string
+	ExitCode *int64
+	Reason   string
+	OOM      bool
+}
+
+// DiagnoseStoppedTask classifies the stopped reason of a task and its containers
+func DiagnoseStoppedTask(task *ecs.Task) Diagnosis {
+	d := Diagnosis{StopCode: stopCode(aws.StringValue(task.StoppedReason))}
+
+	for _, container := range task.Containers {
+		cd := ContainerDiagnosis{
+			Name:     aws.StringValue(container.Name),
+			ExitCode: container.ExitCode,
+			Reason:   aws.StringValue(container.Reason),
+		}
+
+		switch {
+		case strings.Contains(cd.Reason, "OutOfMemoryError"),
+			cd.ExitCode != nil && *cd.ExitCode == 137 && d.StopCode == "EssentialContainerExited":
+			cd.OOM = true
+			d.Causes = append(d.Causes, fmt.Sprintf("Container %s ran out of memory", cd.Name))
+		case strings.Contains(cd.Reason, "CannotPullContainerError"):
+			d.Causes = append(d.Causes, fmt.Sprintf("Container %s image could not be pulled", cd.Name))
+		case strings.Contains(cd.Reason, "CannotStartContainerError"),
+			strings.Contains(cd.Reason, "CannotCreateContainerError"):
+			d.Causes = append(d.Causes, fmt.Sprintf("Container %s could not be started", cd.Name))
+		case cd.ExitCode != nil && *cd.ExitCode != 0:
+			d.Causes = append(d.Causes, fmt.Sprintf("Container %s exited with %d", cd.Name, *cd.ExitCode))
+		}
+
+		d.Containers = append(d.Containers, cd)
+	}
+
+	if d.StopCode == "ELBHealthCheckFailed" {
+		d.Causes = append(d.Causes, "Task failed load balancer health checks")
+	}
+
+	return d
+}
+
+func stopCode(reason string) string {
+	switch {
+	case strings.HasPrefix(reason, "Essential container in task exited"):
+		return "EssentialContainerExited"
+	case strings.Contains(reason, "ELB health checks"):
+		return "ELBHealthCheckFailed"
+	case strings.HasPrefix(reason, "Scaling activity initiated"):
+		return "ServiceSchedulerInitiated"
+	case strings.HasPrefix(reason, "Task stopped by user"):
+		return "UserInitiated"
+	case strings.Contains(reason, "CannotPullContainerError"):
+		return "TaskFailedToStart"
+	case strings.Contains(reason, "Host EC2"), strings.Contains(reason, "terminated"):
+		return "HostTerminated"
+	case reason == "":
+		return "Unknown"
+	}
+	return "Other"
+}
+
+// TaskLogStream returns the awslogs group and stream for a container in a task
+func TaskLogStream(taskDef *ecs.TaskDefinition, task *ecs.Task, containerName string) (group, stream string, ok bool) {
+	for _, def := range taskDef.ContainerDefinitions {
+		if *def.Name != containerName || def.LogConfiguration == nil {
+			continue
+		}
+		if aws.StringValue(def.LogConfiguration.LogDriver) != "awslogs" {
+			return "", "", false
+		}
+
+		group = aws.StringValue(def.LogConfiguration.Options["awslogs-group"])
+		prefix := aws.StringValue(def.LogConfiguration.Options["awslogs-stream-prefix"])
+		if group == "" || prefix == "" {
+			return "", "", false
+		}
+		return group, fmt.Sprintf("%s/%s/%s", prefix, containerName, path.Base(*task.TaskArn)), true
+	}
+	return "", "", false
+}
+
+// TailLogStream returns the last lines of a log stream
+func TailLogStream(svc cloudwatchLogsInterface, group, stream string, lines int64) ([]string, error) {
+	resp, err := svc.GetLogEvents(&logs.GetLogEventsInput{
+		LogGroupName:  aws.String(group),
+		LogStreamName: aws.String(stream),
+		Limit:         aws.Int64(lines),
+		StartFromHead: aws.Bool(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []string{}
+	for _, event := range resp.Events {
+		messages = append(messages, *event.Message)
+	}
+	return messages, nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestDiagnoseOOMTask(t *testing.T) {
+	d := DiagnoseStoppedTask(&ecs.Task{
+		StoppedReason: aws.String("Essential container in task exited"),
+		Containers: []*ecs.Container{
+			{Name: aws.String("app"), ExitCode: aws.Int64(137), Reason: aws.String("OutOfMemoryError: Container killed due to memory usage")},
+		},
+	})
+
+	if d.StopCode != "EssentialContainerExited" {
+		t.Fatalf("Unexpected stop code %s", d.StopCode)
+	}
+	if !d.Containers[0].OOM {
+		t.Fatalf("Expected container to be detected as OOM")
+	}
+}
+
+func TestDiagnoseImagePullFailure(t *testing.T) {
+	d := DiagnoseStoppedTask(&ecs.Task{
+		StoppedReason: aws.String("CannotPullContainerError: API error (404): repository not found"),
+		Containers: []*ecs.Container{
+			{Name: aws.String("app"), Reason: aws.String("CannotPullContainerError: API error (404)")},
+		},
+	})
+
+	if d.StopCode != "TaskFailedToStart" {
+		t.Fatalf("Unexpected stop code %s", d.StopCode)
+	}
+	if len(d.Causes) != 1 || d.Causes[0] != "Container app image could not be pulled" {
+		t.Fatalf("Unexpected causes %#v", d.Causes)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"gopkg.in/yaml.v2"
+)
+
+// ResourceChange is how a resource differs between a deployed template and a new one
+type ResourceChange struct {
+	LogicalID  string
+	Type       string
+	Action     string
+	Properties []string
+}
+
+// Actions for resource changes
+const (
+	ResourceAdded    = "add"
+	ResourceRemoved  = "remove"
+	ResourceModified = "modify"
+)
+
+type templateResource struct {
+	Type       string                 `yaml:"Type"`
+	Condition  interface{}            `yaml:"Condition"`
+	DependsOn  interface{}            `yaml:"DependsOn"`
+	Properties map[string]interface{} `yaml:"Properties"`
+}
+
+// DiffTemplates compares the resources of two templates in yaml or json, listing the
+// properties of resources that changed. Intrinsic functions are compared by their values.
+func DiffTemplates(deployed, proposed string) ([]ResourceChange, error) {
+	var before, after struct {
+		Resources map[string]templateResource `yaml:"Resources"`
+	}
+	if err := yaml.Unmarshal([]byte(deployed), &before); err != nil {
+		return nil, fmt.Errorf("Failed to parse deployed template: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(proposed), &after); err != nil {
+		return nil, fmt.Errorf("Failed to parse template: %v", err)
+	}
+
+	ids := map[string]bool{}
+	for id := range before.Resources {
+		ids[id] = true
+	}
+	for id := range after.Resources {
+		ids[id] = true
+	}
+	sorted := []string{}
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	changes := []ResourceChange{}
+	for _, id := range sorted {
+		b, inBefore := before.Resources[id]
+		a, inAfter := after.Resources[id]
+
+		switch {
+		case !inBefore:
+			changes = append(changes, ResourceChange{LogicalID: id, Type: a.Type, Action: ResourceAdded})
+		case !inAfter:
+			changes = append(changes, ResourceChange{LogicalID: id, Type: b.Type, Action: ResourceRemoved})
+		default:
+			props := []string{}
+			if a.Type != b.Type {
+				props = append(props, "Type")
+			}
+			if !reflect.DeepEqual(a.Condition, b.Condition) {
+				props = append(props, "Condition")
+			}
+			if !reflect.DeepEqual(a.DependsOn, b.DependsOn) {
+				props = append(props, "DependsOn")
+			}
+			keys := map[string]bool{}
+			for k := range a.Properties {
+				keys[k] = true
+			}
+			for k := range b.Properties {
+				keys[k] = true
+			}
+			changed := []string{}
+			for k := range keys {
+				if !reflect.DeepEqual(a.Properties[k], b.Properties[k]) {
+					changed = append(changed, k)
+				}
+			}
+			sort.Strings(changed)
+			props = append(props, changed...)
+
+			if len(props) > 0 {
+				changes = append(changes, ResourceChange{LogicalID: id, Type: a.Type, Action: ResourceModified, Properties: props})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// DeployedTemplate returns the template body a stack was last created or updated with
+func DeployedTemplate(svc CFNAPI, stackName string) (string, error) {
+	resp, err := svc.GetTemplate(&cloudformation.GetTemplateInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(resp.TemplateBody), nil
+}
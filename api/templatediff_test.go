@@ -0,0 +1,36 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTemplates(t *testing.T) {
+	deployed := `
+Resources:
+  Queue:
+    Type: AWS::SQS::Queue
+    Properties:
+      VisibilityTimeout: 30
+  Topic:
+    Type: AWS::SNS::Topic
+`
+	proposed := `{"Resources": {
+  "Queue": {"Type": "AWS::SQS::Queue", "Properties": {"VisibilityTimeout": 60, "MessageRetentionPeriod": 3600}},
+  "Bucket": {"Type": "AWS::S3::Bucket"}
+}}`
+
+	changes, err := DiffTemplates(deployed, proposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ResourceChange{
+		{LogicalID: "Bucket", Type: "AWS::S3::Bucket", Action: ResourceAdded},
+		{LogicalID: "Queue", Type: "AWS::SQS::Queue", Action: ResourceModified, Properties: []string{"MessageRetentionPeriod", "VisibilityTimeout"}},
+		{LogicalID: "Topic", Type: "AWS::SNS::Topic", Action: ResourceRemoved},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Unexpected changes %#v", changes)
+	}
+}
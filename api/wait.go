@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitError is returned when a wait is given up on because its context ended, with the
+// last known state of what was being waited for, like "it has 2 deployments"
+type WaitError struct {
+	Err   error
+	What  string
+	State string
+}
+
+func (e *WaitError) Error() string {
+	verb := "Stopped waiting"
+	if e.Err == context.DeadlineExceeded {
+		verb = "Timed out waiting"
+	}
+	if e.State == "" {
+		return fmt.Sprintf("%s for %s", verb, e.What)
+	}
+	return fmt.Sprintf("%s for %s, %s", verb, e.What, e.State)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// Sleep waits between polls, returning a WaitError if the context ends first
+func Sleep(ctx context.Context, d time.Duration, what, state string) error {
+	select {
+	case <-ctx.Done():
+		return &WaitError{Err: ctx.Err(), What: what, State: state}
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lox/ecsy/failure"
+)
+
+func TestSleep(t *testing.T) {
+	if err := Sleep(context.Background(), time.Millisecond, "web", "it has 2 deployments"); err != nil {
+		t.Fatalf("Expected sleeping to finish, got %v", err)
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-expired.Done()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		ctx     context.Context
+		state   string
+		err     error
+		message string
+	}{
+		{expired, "it has 2 deployments", context.DeadlineExceeded, "Timed out waiting for web, it has 2 deployments"},
+		{expired, "", context.DeadlineExceeded, "Timed out waiting for web"},
+		{canceled, "it has 1 deployment", context.Canceled, "Stopped waiting for web, it has 1 deployment"},
+	} {
+		start := time.Now()
+		err := Sleep(tc.ctx, time.Hour, "web", tc.state)
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected an ended context to stop sleeping straight away")
+		}
+		if err == nil || err.Error() != tc.message {
+			t.Errorf("Expected %q, got %v", tc.message, err)
+			continue
+		}
+
+		var waitErr *WaitError
+		wrapped := fmt.Errorf("deploying: %w", err)
+		if !errors.As(wrapped, &waitErr) || waitErr.State != tc.state {
+			t.Errorf("Expected a WaitError with state %q, got %v", tc.state, wrapped)
+		}
+		if !errors.Is(wrapped, tc.err) {
+			t.Errorf("Expected %v to unwrap to %v", wrapped, tc.err)
+		}
+	}
+
+	if kind := failure.KindOf(&WaitError{Err: context.DeadlineExceeded, What: "web"}); kind != failure.Timeout {
+		t.Errorf("Expected a timed out wait to be a timeout, got %s", kind)
+	}
+}
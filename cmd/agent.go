@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// agentVersions returns the agent versions of a cluster's instances and the latest agent
+// version, that of the current ECS optimized AMI or else the newest in the cluster
+func agentVersions(svc api.Services, cluster string) ([]*ecs.ContainerInstance, []api.AgentStatus, string, error) {
+	instances, err := api.ListContainerInstances(svc.ECS, cluster)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	statuses := api.AgentStatuses(instances)
+
+	latest, err := api.LatestAgentVersion(svc.SSM)
+	if err != nil {
+		log.Printf("Not checking the latest agent version: %v", err)
+		latest = api.NewestAgentVersion(statuses)
+	}
+	return instances, statuses, latest, nil
+}
+
+func ConfigureAgentStatus(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+
+	cmd := app.Command("agent-status", "List the versions of the ECS agent and docker on a cluster's instances")
+	cmd.Flag("cluster", "The ECS cluster to list the instances of").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		_, statuses, latest, err := agentVersions(svc, cluster)
+		if err != nil {
+			return err
+		}
+		if len(statuses) == 0 {
+			log.Printf("No instances are registered with cluster %s", cluster)
+			return nil
+		}
+
+		outdated := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "INSTANCE\tAGENT\tDOCKER\tCONNECTED\tUPDATE\t")
+		for _, s := range statuses {
+			note := ""
+			if api.CompareVersions(s.AgentVersion, latest) < 0 {
+				note = "out of date"
+				outdated++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%s\n", s.InstanceID, s.AgentVersion, s.DockerVersion, s.Connected, s.UpdateStatus, note)
+		}
+		w.Flush()
+
+		log.Printf("%d instances, %d with an agent older than %s", len(statuses), outdated, latest)
+		if outdated > 0 {
+			log.Printf("Run `ecsy agent-update --cluster %s` to update them", cluster)
+		}
+		return nil
+	})
+}
+
+func ConfigureAgentUpdate(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+	var roll bool
+	var drainTimeout time.Duration
+
+	cmd := app.Command("agent-update", "Update the ECS agent on a cluster's instances that are out of date")
+	cmd.Flag("cluster", "The ECS cluster to update the instances of").
+		StringVar(&cluster)
+
+	cmd.Flag("roll", "Replace instances whose agent can't be updated in place, like those on ECS optimized AMIs, one at a time").
+		BoolVar(&roll)
+
+	cmd.Flag("drain-timeout", "How long to wait for an instance to drain before giving up on replacing it").
+		Default("15m").
+		DurationVar(&drainTimeout)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		instances, statuses, latest, err := agentVersions(svc, cluster)
+		if err != nil {
+			return err
+		}
+
+		unsupported := []*ecs.ContainerInstance{}
+		for idx, s := range statuses {
+			if api.CompareVersions(s.AgentVersion, latest) >= 0 {
+				continue
+			}
+			log.Printf("Updating the agent on %s from %s", s.InstanceID, s.AgentVersion)
+			switch err := api.UpdateAgent(svc.ECS, cluster, s.ContainerInstanceArn); err {
+			case nil:
+			case api.ErrAgentUpToDate:
+				log.Printf("%s already has the latest agent available to it", s.InstanceID)
+			case api.ErrAgentUpdateUnsupported:
+				log.Printf("%s can't update its agent in place", s.InstanceID)
+				unsupported = append(unsupported, instances[idx])
+			default:
+				return err
+			}
+		}
+
+		if len(unsupported) == 0 {
+			return nil
+		} else if !roll {
+			log.Printf("Run with --roll to replace %d instances, after `ecsy upgrade` if their AMI is out of date", len(unsupported))
+			return nil
+		}
+		for _, ci := range unsupported {
+			if err = replaceInstance(svc, cluster, ci, drainTimeout); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// approvalFlags make a command show what it would change and wait for approval before
+// changing anything, a manual gate for production deploys
+type approvalFlags struct {
+	Required bool
+}
+
+func (f *approvalFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("require-approval", "Show what would change and wait for it to be approved, ECSY_APPROVE=1 approves it without asking").
+		BoolVar(&f.Required)
+}
+
+// required returns whether approval is needed, from the flag or require_approval in the
+// config for the environment, which the flag can't turn off
+func (f approvalFlags) required(cfg *config.Config) bool {
+	return f.Required || cfg.RequireApproval
+}
+
+// approve waits for a change to be approved by typing yes, or by ECSY_APPROVE in pipelines
+// where there's no terminal to ask from
+func approve(what string) error {
+	if v, err := strconv.ParseBool(os.Getenv("ECSY_APPROVE")); err == nil && v {
+		log.Printf("Approved %s with ECSY_APPROVE", what)
+		return nil
+	}
+	if !interactive() {
+		return failure.Errorf(failure.Validation, "Approval is required for %s, set ECSY_APPROVE=1 to approve it when there's no terminal", what)
+	}
+
+	fmt.Fprintf(os.Stderr, "Approve %s? Type yes to approve: ", what)
+	line, _ := stdin.ReadString('\n')
+	if strings.TrimSpace(line) != "yes" {
+		return failure.Errorf(failure.Validation, "Stopping before %s, it wasn't approved", what)
+	}
+	return nil
+}
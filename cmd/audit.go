@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// auditedCommands change infrastructure or services, so are recorded in the audit log
+var auditedCommands = map[string]bool{
+	"bootstrap": true, "create-cluster": true, "delete-cluster": true, "unprotect": true, "upgrade": true,
+	"create-service": true, "deploy": true, "scale": true, "run-task": true, "register-taskdef": true,
+	"set-log-retention": true, "refresh-instances": true, "maintenance": true, "agent-update": true, "cordon": true, "uncordon": true, "rebalance": true, "create-db": true, "create-cache": true,
+	"budget": true, "import": true, "replicate": true, "stackset": true, "prometheus-config": true,
+	"keys sync": true, "sync run": true, "autodeploy enable": true, "autodeploy disable": true, "jobs deploy": true, "jobs run": true, "workflow deploy": true, "workflow run": true,
+}
+
+// audit collects the services, cluster and resources of the running command for its record
+var audit struct {
+	sync.Mutex
+	svc       *api.Services
+	cluster   string
+	resources []string
+}
+
+// auditServices records the services of the environment a command runs in, so that its
+// record is written to the environment's account
+func auditServices(svc api.Services) {
+	audit.Lock()
+	defer audit.Unlock()
+	audit.svc = &svc
+}
+
+func auditCluster(cluster string) {
+	audit.Lock()
+	defer audit.Unlock()
+	audit.cluster = cluster
+}
+
+// auditResource records the arn of a stack or task definition that a command created or updated
+func auditResource(arn string) {
+	audit.Lock()
+	defer audit.Unlock()
+	if !containsString(audit.resources, arn) {
+		audit.resources = append(audit.resources, arn)
+	}
+}
+
+// RecordAudit appends a record of a command that changes something to the audit log of the
+// account it ran in, accounts without an audit table aren't recorded
+func RecordAudit(command string, args []string, svc api.Services, err error) {
+	if !auditedCommands[command] {
+		return
+	}
+
+	audit.Lock()
+	defer audit.Unlock()
+	if audit.svc != nil {
+		svc = *audit.svc
+	}
+
+	r := api.AuditRecord{
+		Cluster:    audit.cluster,
+		RecordedAt: time.Now(),
+		Owner:      lockOwner(),
+		Command:    command,
+		Args:       api.RedactArgs(args),
+		Resources:  audit.resources,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if r.Caller, err = api.CallerArn(svc.STS); err != nil {
+		log.Printf("Not writing an audit record: %v", err)
+		return
+	}
+	if err = api.WriteAuditRecord(svc.DynamoDB, r); err != nil && err != api.ErrTableNotFound {
+		log.Printf("Failed to write an audit record: %v", err)
+	}
+}
+
+func ConfigureAudit(app *kingpin.Application, svc api.Services) {
+	audit := app.Command("audit", "Query the log of ecsy commands that changed infrastructure or services")
+
+	configureAuditList(audit, svc)
+}
+
+func configureAuditList(audit *kingpin.CmdClause, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+	var account bool
+	var since time.Duration
+	var limit int
+
+	cmd := audit.Command("list", "List who ran which commands on a cluster, newest first")
+	cmd.Flag("cluster", "The ECS cluster to list commands for").
+		StringVar(&cluster)
+
+	cmd.Flag("account", "List commands that weren't run on a cluster, like bootstrap, instead").
+		BoolVar(&account)
+
+	cmd.Flag("since", "How far back to list commands from").
+		Default("168h").
+		DurationVar(&since)
+
+	cmd.Flag("limit", "The most commands to list").
+		Default("50").
+		IntVar(&limit)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		if !account {
+			if cluster, err = resolveCluster(svc, cluster, cfg); err != nil {
+				return err
+			}
+		}
+
+		records, err := api.ListAuditRecords(svc.DynamoDB, cluster, time.Now().Add(-since), limit)
+		if err == api.ErrTableNotFound {
+			return fmt.Errorf("No audit table %s exists in %s, run `ecsy bootstrap` to create it", api.AuditTableName, svc.Region)
+		} else if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			log.Printf("No commands in the last %s", since)
+			return nil
+		}
+
+		for _, r := range records {
+			fmt.Println(r)
+			fmt.Printf("    %s\n", strings.Join(r.Args, " "))
+			fmt.Printf("    from %s\n", r.Owner)
+			for _, resource := range r.Resources {
+				fmt.Printf("    changed %s\n", resource)
+			}
+		}
+		return nil
+	})
+}
+
+// auditStack records the arn of a stack once it has finished changing
+func auditStack(svc api.Services, stackName string) {
+	if stacks, err := api.FindStacksByName(svc.Cloudformation, stackName); err == nil && len(stacks) > 0 {
+		auditResource(aws.StringValue(stacks[0].StackId))
+	} else {
+		auditResource(stackName)
+	}
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const autodeployStackType = "ecs-former::ecs-autodeploy"
+
+func autodeployStackName(cluster, service string) string {
+	return fmt.Sprintf("ecs-%s-%s-autodeploy", cluster, service)
+}
+
+func ConfigureAutodeploy(app *kingpin.Application, svc api.Services) {
+	autodeploy := app.Command("autodeploy", "Deploy images pushed to ECR to a service automatically")
+
+	configureAutodeployEnable(autodeploy, svc)
+	configureAutodeployDisable(autodeploy, svc)
+	configureAutodeployList(autodeploy, svc)
+}
+
+func configureAutodeployEnable(autodeploy *kingpin.CmdClause, svc api.Services) {
+	var env environmentFlags
+	var cluster, service, repository, tagPattern, container string
+
+	cmd := autodeploy.Command("enable", "Deploy pushes of matching tags to an ECR repository to a service")
+	cmd.Flag("cluster", "The ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service to deploy to").
+		Short('s').
+		Required().
+		StringVar(&service)
+
+	cmd.Flag("ecr-repo", "The name of the ECR repository to deploy pushes from").
+		Required().
+		StringVar(&repository)
+
+	cmd.Flag("tag-pattern", "Only deploy tags matching this pattern, * matches anything").
+		Default("*").
+		StringVar(&tagPattern)
+
+	cmd.Flag("container", "The container to deploy to, by default every container that runs an image from the repository").
+		StringVar(&container)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+		serviceStack, err := api.FindServiceStack(svc.Cloudformation, cluster, service)
+		if err != nil {
+			return err
+		}
+		outputs := api.StackOutputMap(serviceStack)
+
+		td, err := serviceTaskDefinition(svc, outputs["ECSCluster"], outputs["ECSService"])
+		if err != nil {
+			return err
+		}
+		containers := repositoryContainers(td, repository)
+		roles, err := svc.TaskDefinitions.DescribeTaskDefinitionRoles(aws.StringValue(td.TaskDefinitionArn))
+		if err != nil {
+			return err
+		}
+		if len(containers) == 0 {
+			return fmt.Errorf("No containers of %s run an image from %s", service, repository)
+		} else if container != "" && !containsString(containers, container) {
+			return fmt.Errorf("Container %s of %s doesn't run an image from %s", container, service, repository)
+		}
+
+		lockTable, _ := api.GetStackOutputByKey(clusterStack, "LockTableName")
+		ctx := api.StackOptions{
+			Params: map[string]string{
+				"ECSCluster":     outputs["ECSCluster"],
+				"ECSService":     outputs["ECSService"],
+				"TaskFamily":     service,
+				"RepositoryName": repository,
+				"TagPattern":     tagPattern,
+				"Container":      container,
+				"LockTableName":  lockTable,
+				// the function can only pass the roles the service runs with now, so it
+				// needs enabling again if they change
+				"TaskRoleArn":      roles.TaskRoleArn,
+				"ExecutionRoleArn": roles.ExecutionRoleArn,
+			},
+		}
+
+		timer := time.Now()
+		stackName := autodeployStackName(cluster, service)
+		log.Printf("Deploying pushes of %s:%s to %s", repository, tagPattern, service)
+
+		existing, err := api.FindStacksByName(svc.Cloudformation, stackName)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			log.Printf("Updating autodeploy cloudformation stack %s", stackName)
+			err = api.UpdateStack(svc.Cloudformation, stackName, templates.EcsAutodeploy(), ctx)
+			if err == api.ErrNoStackUpdates {
+				log.Printf("Autodeploy is already up to date")
+				return nil
+			}
+		} else {
+			log.Printf("Creating autodeploy cloudformation stack %s", stackName)
+			err = api.CreateStack(svc.Cloudformation, stackName, templates.EcsAutodeploy(), ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = waitForStack(svc, stackName); err != nil {
+			return err
+		}
+		log.Printf("Stack %s finished in %s", stackName, time.Now().Sub(timer).String())
+		return nil
+	})
+}
+
+// serviceTaskDefinition returns the task definition a service runs
+func serviceTaskDefinition(svc api.Services, cluster, service string) (*ecs.TaskDefinition, error) {
+	current, err := api.DescribeService(svc.ECS, cluster, service)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: current.TaskDefinition,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.TaskDefinition, nil
+}
+
+// repositoryContainers returns the containers of a task definition that run an image from
+// an ECR repository
+func repositoryContainers(td *ecs.TaskDefinition, repository string) []string {
+	containers := []string{}
+	for _, def := range td.ContainerDefinitions {
+		ref := api.ParseImageRef(aws.StringValue(def.Image))
+		if ref.ECR() && ref.Repository == repository {
+			containers = append(containers, aws.StringValue(def.Name))
+		}
+	}
+	return containers
+}
+
+func configureAutodeployDisable(autodeploy *kingpin.CmdClause, svc api.Services) {
+	var env environmentFlags
+	var cluster, service string
+
+	cmd := autodeploy.Command("disable", "Stop deploying pushes to a service")
+	cmd.Flag("cluster", "The ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service").
+		Short('s').
+		Required().
+		StringVar(&service)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stackName := autodeployStackName(cluster, service)
+		existing, err := api.FindStacksByName(svc.Cloudformation, stackName)
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			return fmt.Errorf("Autodeploy isn't enabled for %s", service)
+		}
+
+		log.Printf("Deleting autodeploy cloudformation stack %s", stackName)
+		if err = api.DeleteStack(svc.Cloudformation, stackName); err != nil {
+			return err
+		}
+		return api.PollUntilDeleted(commandContext(), svc.Cloudformation, stackName, printStackEvent)
+	})
+}
+
+func configureAutodeployList(autodeploy *kingpin.CmdClause, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+
+	cmd := autodeploy.Command("list", "List the services that pushes are deployed to")
+	cmd.Flag("cluster", "The ECS cluster to list").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stacks, err := api.FindStacksByOutputs(svc.Cloudformation, map[string]string{
+			"StackType":  autodeployStackType,
+			"ECSCluster": cluster,
+		})
+		if err != nil {
+			return err
+		}
+		if len(stacks) == 0 {
+			log.Printf("Autodeploy isn't enabled for any services on %s", cluster)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tREPOSITORY\tTAGS\tSTATUS")
+		for _, stack := range stacks {
+			outputs := api.StackOutputMap(stack)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", outputs["TaskFamily"], outputs["RepositoryName"],
+				outputs["TagPattern"], aws.StringValue(stack.StackStatus))
+		}
+		return w.Flush()
+	})
+}
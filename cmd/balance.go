@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// balanceFlags are shared by balance and rebalance
+type balanceFlags struct {
+	env      environmentFlags
+	cluster  string
+	services []string
+}
+
+func (f *balanceFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("cluster", "The ECS cluster the services are in").
+		StringVar(&f.cluster)
+
+	cmd.Flag("service", "Only include a service (repeatable), defaults to all of the cluster's services").
+		StringsVar(&f.services)
+
+	f.env.configure(cmd)
+}
+
+// load returns the placements of the tasks of the services, grouped by service
+func (f *balanceFlags) load(svc api.Services) (api.Services, string, map[string][]api.Placement, []string, error) {
+	cfg, svc, err := f.env.load(svc)
+	if err != nil {
+		return svc, "", nil, nil, err
+	}
+
+	cluster, err := resolveCluster(svc, f.cluster, cfg)
+	if err != nil {
+		return svc, "", nil, nil, err
+	}
+
+	placements, zones, err := api.ServicePlacements(svc.ECS, cluster)
+	if err != nil {
+		return svc, "", nil, nil, err
+	}
+	byService := map[string][]api.Placement{}
+	for _, p := range placements {
+		if len(f.services) == 0 || containsString(f.services, p.Service) {
+			byService[p.Service] = append(byService[p.Service], p)
+		}
+	}
+	return svc, cluster, byService, zones, nil
+}
+
+func ConfigureBalance(app *kingpin.Application, svc api.Services) {
+	var f balanceFlags
+
+	cmd := app.Command("balance", "Report how the tasks of a cluster's services are spread across availability zones and instances")
+	f.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		_, cluster, byService, zones, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+		if len(byService) == 0 {
+			log.Printf("No services in cluster %s have running tasks", cluster)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "SERVICE\t%s\tINSTANCES\tIMBALANCE\n", strings.Join(zones, "\t"))
+		unbalanced := 0
+		for _, name := range sortedServiceNames(byService) {
+			placements := byService[name]
+			counts := api.ZoneCounts(placements, zones)
+			columns := []string{}
+			for _, z := range zones {
+				columns = append(columns, fmt.Sprint(counts[z]))
+			}
+			imbalance := api.Imbalance(counts)
+			if imbalance > 1 {
+				unbalanced++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", name, strings.Join(columns, "\t"), instanceCounts(placements), imbalance)
+		}
+		w.Flush()
+
+		if unbalanced > 0 {
+			log.Printf("%d services are unbalanced across zones, run `ecsy rebalance --cluster %s` to even them out", unbalanced, cluster)
+		}
+		return nil
+	})
+}
+
+func ConfigureRebalance(app *kingpin.Application, svc api.Services) {
+	var f balanceFlags
+
+	cmd := app.Command("rebalance", "Even out services' tasks across availability zones, by stopping tasks one at a time for the scheduler to replace in the quietest zones")
+	f.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		svc, cluster, byService, zones, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		moved := 0
+		for _, name := range sortedServiceNames(byService) {
+			moves := api.TasksToMove(byService[name], zones)
+			if len(moves) == 0 {
+				continue
+			}
+			log.Printf("Moving %d tasks of %s", len(moves), name)
+			for _, m := range moves {
+				log.Printf("Stopping task %s on %s in %s", m.TaskArn, m.InstanceID, m.AvailabilityZone)
+				if err = api.StopTask(svc.ECS, cluster, m.TaskArn, "Rebalancing across availability zones with ecsy rebalance"); err != nil {
+					return err
+				}
+				if err = svc.ECS.WaitUntilTasksStopped(&ecs.DescribeTasksInput{
+					Cluster: aws.String(cluster),
+					Tasks:   []*string{aws.String(m.TaskArn)},
+				}); err != nil {
+					return err
+				}
+				err = api.PollUntilServiceStable(commandContext(), svc.ECS, cluster, name, func(e *ecs.ServiceEvent) {
+					log.Println(aws.StringValue(e.Message))
+				})
+				if err != nil {
+					return err
+				}
+				moved++
+			}
+		}
+
+		if moved == 0 {
+			log.Printf("The services of %s are balanced across zones", cluster)
+		}
+		return nil
+	})
+}
+
+func sortedServiceNames(byService map[string][]api.Placement) []string {
+	names := []string{}
+	for name := range byService {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// instanceCounts describes how many tasks are on each instance, like i-1:2 i-2:1
+func instanceCounts(placements []api.Placement) string {
+	counts := map[string]int{}
+	ids := []string{}
+	for _, p := range placements {
+		if counts[p.InstanceID] == 0 {
+			ids = append(ids, p.InstanceID)
+		}
+		counts[p.InstanceID]++
+	}
+	sort.Strings(ids)
+	parts := []string{}
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s:%d", id, counts[id]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/policy"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	taskExecutionRoleName    = "ecsTaskExecutionRole"
+	taskExecutionPolicyArn   = "arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy"
+	ecsServiceLinkedRole     = "AWSServiceRoleForECS"
+	logResourcePolicyName    = "ecsy"
+	taskExecutionTrustPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ecs-tasks.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+)
+
+func ConfigureBootstrap(app *kingpin.Application, svc api.Services) {
+	var dryRun bool
+
+	cmd := app.Command("bootstrap", "Create and validate the account level prerequisites that ecsy needs")
+	cmd.Flag("dry-run", "Only report missing prerequisites, don't create them").
+		BoolVar(&dryRun)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		accountID, err := api.AccountID(svc.STS)
+		if err != nil {
+			return err
+		}
+		log.Printf("Bootstrapping account %s", accountID)
+
+		missing := 0
+
+		if _, err = svc.IAM.GetRole(ecsServiceLinkedRole); err == api.ErrNoSuchEntity {
+			if dryRun {
+				log.Printf("Missing service linked role %s", ecsServiceLinkedRole)
+				missing++
+			} else {
+				log.Printf("Creating service linked role %s", ecsServiceLinkedRole)
+				if err = svc.IAM.CreateServiceLinkedRole("ecs.amazonaws.com"); err != nil {
+					return err
+				}
+			}
+		} else if err != nil {
+			return err
+		} else {
+			log.Printf("Service linked role %s exists", ecsServiceLinkedRole)
+		}
+
+		if _, err = svc.IAM.GetRole(taskExecutionRoleName); err == api.ErrNoSuchEntity {
+			if dryRun {
+				log.Printf("Missing task execution role %s", taskExecutionRoleName)
+				missing++
+			} else {
+				log.Printf("Creating task execution role %s", taskExecutionRoleName)
+				if _, err = svc.IAM.CreateRole(taskExecutionRoleName, taskExecutionTrustPolicy); err != nil {
+					return err
+				}
+				if err = svc.IAM.AttachRolePolicy(taskExecutionRoleName, taskExecutionPolicyArn); err != nil {
+					return err
+				}
+			}
+		} else if err != nil {
+			return err
+		} else {
+			log.Printf("Task execution role %s exists", taskExecutionRoleName)
+		}
+
+		policies, err := svc.LogPolicies.DescribeResourcePolicies()
+		if err != nil {
+			return err
+		}
+		if !hasLogResourcePolicy(policies) {
+			if dryRun {
+				log.Printf("Missing log resource policy %s", logResourcePolicyName)
+				missing++
+			} else {
+				log.Printf("Creating log resource policy %s", logResourcePolicyName)
+				if err = svc.LogPolicies.PutResourcePolicy(logResourcePolicyName, logResourcePolicy(accountID)); err != nil {
+					return err
+				}
+			}
+		} else {
+			log.Printf("Log resource policy %s exists", logResourcePolicyName)
+		}
+
+		if _, err = svc.DynamoDB.DescribeTable(api.AuditTableName); err == api.ErrTableNotFound {
+			if dryRun {
+				log.Printf("Missing audit table %s", api.AuditTableName)
+				missing++
+			} else {
+				log.Printf("Creating audit table %s", api.AuditTableName)
+				if err = api.CreateAuditTable(svc.DynamoDB); err != nil {
+					return err
+				}
+			}
+		} else if err != nil {
+			return err
+		} else {
+			log.Printf("Audit table %s exists", api.AuditTableName)
+		}
+
+		activateCostTags(svc, dryRun)
+
+		callerArn, err := api.CallerArn(svc.STS)
+		if err != nil {
+			return err
+		}
+
+		principal := api.PrincipalArn(callerArn)
+		log.Printf("Checking permissions of %s", principal)
+
+		actions := policy.Actions(policy.Commands()...)
+		decisions, err := svc.IAM.SimulatePrincipalPolicy(principal, actions)
+		if err != nil {
+			return err
+		}
+		for _, action := range actions {
+			if decision := decisions[action]; decision != "allowed" {
+				log.Printf("Missing permission %s (%s)", action, decision)
+				missing++
+			}
+		}
+
+		if missing > 0 {
+			return fmt.Errorf("%d prerequisites are missing", missing)
+		}
+
+		log.Printf("Account %s is ready for ecsy", accountID)
+		return nil
+	})
+}
+
+func hasLogResourcePolicy(policies []api.LogResourcePolicy) bool {
+	for _, p := range policies {
+		if p.PolicyName == logResourcePolicyName {
+			return true
+		}
+	}
+	return false
+}
+
+// logResourcePolicy lets aws services like eventbridge deliver to ecsy's log groups
+func logResourcePolicy(accountID string) string {
+	return fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow",`+
+		`"Principal":{"Service":["events.amazonaws.com","delivery.logs.amazonaws.com"]},`+
+		`"Action":["logs:CreateLogStream","logs:PutLogEvents"],`+
+		`"Resource":"arn:aws:logs:*:%s:log-group:ecs-*"}]}`, accountID)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func budgetStackName(cluster string) string {
+	return fmt.Sprintf("ecs-%s-budget", cluster)
+}
+
+func ConfigureBudget(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, email, topicArn string
+	var monthly int
+
+	cmd := app.Command("budget", "Create or update a monthly cost budget for a cluster, alerting at 80% and 100% of it")
+	cmd.Flag("cluster", "The ECS cluster to budget for").
+		StringVar(&cluster)
+
+	cmd.Flag("monthly", "The monthly limit in USD").
+		Required().
+		IntVar(&monthly)
+
+	cmd.Flag("email", "An email address to alert").
+		StringVar(&email)
+
+	cmd.Flag("topic-arn", "An sns topic to alert, defaults to the topic in config").
+		StringVar(&topicArn)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if monthly < 1 {
+			return fmt.Errorf("The monthly limit must be at least 1")
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		if clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster); err != nil {
+			return err
+		} else if clusterStack == nil {
+			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+		}
+
+		if topicArn == "" && cfg.Notifications.SNS != nil {
+			topicArn = cfg.Notifications.SNS.TopicArn
+		}
+		if email == "" && topicArn == "" {
+			return fmt.Errorf("A budget needs an --email or --topic-arn to alert")
+		}
+
+		ctx := api.StackOptions{
+			Params: map[string]string{
+				"ECSCluster":   cluster,
+				"MonthlyLimit": strconv.Itoa(monthly),
+				"Email":        email,
+				"TopicArn":     topicArn,
+			},
+		}
+
+		timer := time.Now()
+		stackName := budgetStackName(cluster)
+		log.Printf("Budgeting $%d a month for %s", monthly, cluster)
+
+		existing, err := api.FindStacksByName(svc.Cloudformation, stackName)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			log.Printf("Updating budget cloudformation stack %s", stackName)
+			err = api.UpdateStack(svc.Cloudformation, stackName, templates.EcsBudget(), ctx)
+			if err == api.ErrNoStackUpdates {
+				log.Printf("The budget is already up to date")
+				return nil
+			}
+		} else {
+			log.Printf("Creating budget cloudformation stack %s", stackName)
+			err = api.CreateStack(svc.Cloudformation, stackName, templates.EcsBudget(), ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = waitForStack(svc, stackName)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Stack %s finished in %s", stackName, time.Now().Sub(timer).String())
+		log.Printf("Costs are attributed by the ECSCluster tag, activate it as a cost allocation tag in billing if it isn't already")
+		return nil
+	})
+}
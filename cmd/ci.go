@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// annotateCI shows markdown in the CI build that ecsy is running in, if it's in one
+func annotateCI(context, style, markdown string) {
+	ci := notify.DetectCI()
+	if ci == nil {
+		return
+	}
+	if err := ci.Annotate(context, style, markdown); err != nil {
+		log.Printf("Failed to annotate the build: %v", err)
+	}
+}
+
+// changesMarkdown renders the changes a deploy would make as a list under a heading
+func changesMarkdown(heading string, changes []string) string {
+	if len(changes) == 0 {
+		return fmt.Sprintf("**%s would make no changes**\n\n", heading)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s would make %d changes**\n\n", heading, len(changes))
+	for _, change := range changes {
+		fmt.Fprintf(&b, "- %s\n", change)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func ConfigureCI(app *kingpin.Application, svc api.Services) {
+	ci := app.Command("ci", "Helpers for running ecsy in Buildkite and GitHub Actions")
+
+	configureCIEmitMetadata(ci, svc)
+}
+
+func configureCIEmitMetadata(ci *kingpin.CmdClause, svc api.Services) {
+	var env environmentFlags
+	var cluster, service string
+
+	cmd := ci.Command("emit-metadata", "Set the task definition, images and url of a service as build meta-data or step outputs")
+	cmd.Flag("cluster", "The ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stack, err := api.FindServiceStack(svc.Cloudformation, cluster, service)
+		if err != nil {
+			return err
+		}
+		outputs := api.StackOutputMap(stack)
+
+		current, err := api.DescribeService(svc.ECS, outputs["ECSCluster"], outputs["ECSService"])
+		if err != nil {
+			return err
+		}
+		resp, err := svc.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: current.TaskDefinition,
+		})
+		if err != nil {
+			return err
+		}
+
+		metadata := map[string]string{
+			notify.MetadataKey(service, "task_definition"): aws.StringValue(current.TaskDefinition),
+		}
+		if url := outputs["ECSLoadBalancer"]; url != "" {
+			metadata[notify.MetadataKey(service, "url")] = url
+		}
+		for _, def := range resp.TaskDefinition.ContainerDefinitions {
+			metadata[notify.MetadataKey(service, "image_"+aws.StringValue(def.Name))] = aws.StringValue(def.Image)
+		}
+
+		keys := []string{}
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// outside of CI the metadata is printed, for eval or a .env file
+		target := notify.DetectCI()
+		for _, key := range keys {
+			if target == nil {
+				fmt.Printf("%s=%s\n", key, metadata[key])
+			} else if err = target.SetMetadata(key, metadata[key]); err != nil {
+				return err
+			} else {
+				log.Printf("Set %s to %s", key, metadata[key])
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lox/ecsy/api"
+)
+
+// companionStack is a stack of resources like a database that lives in the private
+// subnets of a cluster, alongside its services
+type companionStack struct {
+	Kind             string
+	Name             string
+	Cluster          string
+	StackName        string
+	Template         string
+	Params           map[string]string
+	OnExists         string
+	DisableRollback  bool
+	AllowReplacement bool
+}
+
+// createCompanionStack creates or updates a companion stack, filling in the network and
+// cluster parameters, and returns its outputs. Nil outputs are returned if it was skipped.
+func createCompanionStack(svc api.Services, c companionStack) (map[string]string, error) {
+	clusterStack, err := api.FindClusterStack(svc.Cloudformation, c.Cluster)
+	if err != nil {
+		return nil, err
+	} else if clusterStack == nil {
+		return nil, fmt.Errorf("No cluster exists for %q. Use `create-cluster`", c.Cluster)
+	}
+	clusterOutputs := api.StackOutputMap(clusterStack)
+
+	network, err := api.FindNetworkStack(svc.Cloudformation, c.Cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := api.FindStacksByName(svc.Cloudformation, c.StackName)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		switch c.OnExists {
+		case onExistsSkip:
+			log.Printf("A %s %s already exists on %s, skipping", c.Kind, c.Name, c.Cluster)
+			return nil, nil
+		case onExistsFail:
+			return nil, fmt.Errorf("A %s %q already exists for cluster %q. Use --on-exists=update to converge it",
+				c.Kind, c.Name, c.Cluster)
+		}
+	}
+
+	ctx := api.StackOptions{
+		Params: map[string]string{
+			"VpcId":               network.VpcId,
+			"VpcPrivateSubnet1Id": network.Subnet2Private,
+			"VpcPrivateSubnet2Id": network.Subnet3Private,
+			"ECSCluster":          c.Cluster,
+			"ECSSecurityGroup":    clusterOutputs["SecurityGroup"],
+			"KmsKeyArn":           clusterOutputs["KmsKeyArn"],
+		},
+		DisableRollback:  c.DisableRollback,
+		AllowReplacement: c.AllowReplacement,
+	}
+	for k, v := range c.Params {
+		ctx.Params[k] = v
+	}
+
+	timer := time.Now()
+	if len(existing) > 0 {
+		log.Printf("Updating %s cloudformation stack %s", c.Kind, c.StackName)
+		err = api.UpdateStack(svc.Cloudformation, c.StackName, c.Template, ctx)
+		if err == api.ErrNoStackUpdates {
+			log.Printf("The %s %s is already up to date", c.Kind, c.Name)
+			return api.StackOutputs(svc.Cloudformation, c.StackName)
+		}
+	} else {
+		log.Printf("Creating %s cloudformation stack %s", c.Kind, c.StackName)
+		err = api.CreateStack(svc.Cloudformation, c.StackName, c.Template, ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = waitForStack(svc, c.StackName)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Stack %s finished in %s", c.StackName, time.Now().Sub(timer).String())
+	return api.StackOutputs(svc.Cloudformation, c.StackName)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureConsole(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, service, task, stack string
+	var opts api.ConsoleOptions
+	var printOnly bool
+
+	cmd := app.Command("console", "Sign in to the AWS console at a cluster, service, task or stack")
+	cmd.Flag("cluster", "The ECS cluster to open").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The service or compose project to open").
+		Short('s').
+		StringVar(&service)
+
+	cmd.Flag("task", "The id or arn of a task to open").
+		StringVar(&task)
+
+	cmd.Flag("stack", "The name of a cloudformation stack to open").
+		StringVar(&stack)
+
+	cmd.Flag("role-arn", "A role to assume for the console session, rather than your own credentials").
+		StringVar(&opts.RoleArn)
+
+	cmd.Flag("duration", "How long the console session lasts, when it can be set").
+		Default("1h").
+		DurationVar(&opts.Duration)
+
+	cmd.Flag("print", "Print the sign-in url rather than opening it in a browser").
+		BoolVar(&printOnly)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if opts.Duration < 15*time.Minute || opts.Duration > 12*time.Hour {
+			return failure.Errorf(failure.Validation, "The --duration must be between 15m and 12h")
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		region := svc.Region
+		if region == "" {
+			region = resolveRegion(cfg)
+		}
+		if region == "" {
+			return failure.Errorf(failure.Validation, "A region is required, either in the config or with AWS_REGION")
+		}
+
+		var destination string
+		if stack != "" {
+			stacks, err := api.FindStacksByName(svc.Cloudformation, stack)
+			if err != nil {
+				return err
+			} else if len(stacks) == 0 {
+				return fmt.Errorf("No stack %s exists", stack)
+			}
+			destination = api.ConsoleStackURL(region, aws.StringValue(stacks[0].StackId))
+		} else {
+			cluster, err := resolveCluster(svc, cluster, cfg)
+			if err != nil {
+				return err
+			}
+			switch {
+			case task != "":
+				destination = api.ConsoleTaskURL(region, cluster, task)
+			case service != "":
+				serviceName, err := resolveServiceName(svc, cluster, service)
+				if err != nil {
+					return err
+				}
+				destination = api.ConsoleServiceURL(region, cluster, serviceName)
+			default:
+				destination = api.ConsoleClusterURL(region, cluster)
+			}
+		}
+
+		signin, err := api.ConsoleSigninURL(svc, destination, opts)
+		if err != nil {
+			return err
+		}
+
+		if !printOnly {
+			log.Printf("Opening %s", destination)
+			if err = openBrowser(signin); err == nil {
+				return nil
+			}
+			log.Printf("Couldn't open a browser (%v), open this url to sign in", err)
+		}
+		fmt.Println(signin)
+		return nil
+	})
+}
+
+// openBrowser opens a url with the desktop's default browser
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
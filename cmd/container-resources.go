@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// containerResourceFlags override the cpu and memory of containers from compose files, each
+// in the form container=value, or just a value for tasks with a single container
+type containerResourceFlags struct {
+	CPU               []string
+	Memory            []string
+	MemoryReservation []string
+}
+
+func (f *containerResourceFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("cpu", "The cpu units to reserve for a container, as container=units (repeatable)").
+		StringsVar(&f.CPU)
+
+	cmd.Flag("memory", "The hard memory limit in MiB for a container, as container=mib (repeatable)").
+		StringsVar(&f.Memory)
+
+	cmd.Flag("memory-reservation", "The soft memory limit in MiB for a container, as container=mib (repeatable)").
+		StringsVar(&f.MemoryReservation)
+}
+
+func (f containerResourceFlags) isSet() bool {
+	return len(f.CPU) > 0 || len(f.Memory) > 0 || len(f.MemoryReservation) > 0
+}
+
+// apply sets the resources of the containers in a task definition
+func (f containerResourceFlags) apply(defs []*ecs.ContainerDefinition) error {
+	for _, flag := range []struct {
+		Name   string
+		Values []string
+		Set    func(def *ecs.ContainerDefinition, v int64)
+	}{
+		{"cpu", f.CPU, func(def *ecs.ContainerDefinition, v int64) { def.Cpu = aws.Int64(v) }},
+		{"memory", f.Memory, func(def *ecs.ContainerDefinition, v int64) { def.Memory = aws.Int64(v) }},
+		{"memory-reservation", f.MemoryReservation, func(def *ecs.ContainerDefinition, v int64) { def.MemoryReservation = aws.Int64(v) }},
+	} {
+		for _, value := range flag.Values {
+			def, v, err := parseContainerResource(defs, value)
+			if err != nil {
+				return fmt.Errorf("Invalid --%s %q: %v", flag.Name, value, err)
+			}
+			log.Printf("Setting %s of container %s to %d", flag.Name, aws.StringValue(def.Name), v)
+			flag.Set(def, v)
+		}
+	}
+	return nil
+}
+
+func parseContainerResource(defs []*ecs.ContainerDefinition, value string) (*ecs.ContainerDefinition, int64, error) {
+	name, amount := "", value
+	if idx := strings.Index(value, "="); idx != -1 {
+		name, amount = value[:idx], value[idx+1:]
+	}
+
+	v, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil || v < 0 {
+		return nil, 0, fmt.Errorf("expected a whole number")
+	}
+
+	if name == "" {
+		if len(defs) != 1 {
+			return nil, 0, fmt.Errorf("the task has %d containers, use container=value", len(defs))
+		}
+		return defs[0], v, nil
+	}
+	for _, def := range defs {
+		if aws.StringValue(def.Name) == name {
+			return def, v, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("no container named %s", name)
+}
+
+// checkContainerResources verifies that the containers of a task, which are always placed
+// together, fit on a single instance of the cluster's instance type
+func checkContainerResources(defs []*ecs.ContainerDefinition, instanceType string) error {
+	available, ok := api.InstanceTypeResources(instanceType)
+	if !ok {
+		return nil
+	}
+
+	var cpu, memory int64
+	for _, def := range defs {
+		name := aws.StringValue(def.Name)
+		hard, soft := aws.Int64Value(def.Memory), aws.Int64Value(def.MemoryReservation)
+		if def.Memory != nil && hard < 4 {
+			return fmt.Errorf("Container %s has a memory limit of %d MiB, the minimum is 4 MiB", name, hard)
+		}
+		if def.Memory != nil && def.MemoryReservation != nil && soft > hard {
+			return fmt.Errorf("Container %s reserves %d MiB of memory, more than its limit of %d MiB", name, soft, hard)
+		}
+		cpu += aws.Int64Value(def.Cpu)
+		if def.MemoryReservation != nil {
+			memory += soft
+		} else {
+			memory += hard
+		}
+	}
+
+	if cpu > available.CPU {
+		return fmt.Errorf("The task reserves %d cpu units, more than the %d a %s instance has", cpu, available.CPU, instanceType)
+	}
+	if memory > available.Memory {
+		return fmt.Errorf("The task reserves %d MiB of memory, more than the %d MiB a %s instance has", memory, available.Memory, instanceType)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestParseContainerResource(t *testing.T) {
+	web := []*ecs.ContainerDefinition{{Name: aws.String("web")}}
+	sidecars := []*ecs.ContainerDefinition{{Name: aws.String("web")}, {Name: aws.String("proxy")}}
+
+	for _, tc := range []struct {
+		defs      []*ecs.ContainerDefinition
+		value     string
+		container string
+		amount    int64
+		err       bool
+	}{
+		{defs: web, value: "512", container: "web", amount: 512},
+		{defs: web, value: "web=256", container: "web", amount: 256},
+		{defs: sidecars, value: "proxy=128", container: "proxy", amount: 128},
+		{defs: sidecars, value: "web=0", container: "web", amount: 0},
+		{defs: sidecars, value: "512", err: true},
+		{defs: sidecars, value: "worker=512", err: true},
+		{defs: web, value: "web=-1", err: true},
+		{defs: web, value: "web=1.5", err: true},
+		{defs: web, value: "web=", err: true},
+		{defs: web, value: "", err: true},
+	} {
+		def, amount, err := parseContainerResource(tc.defs, tc.value)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %d", tc.value, amount)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %q to parse, got %v", tc.value, err)
+		} else if aws.StringValue(def.Name) != tc.container || amount != tc.amount {
+			t.Errorf("Expected %q to set %s to %d, got %s to %d", tc.value, tc.container, tc.amount, aws.StringValue(def.Name), amount)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// cordonFlags are shared by cordon and uncordon
+type cordonFlags struct {
+	env       environmentFlags
+	cluster   string
+	instances []string
+	attribute string
+}
+
+func (f *cordonFlags) configure(cmd *kingpin.CmdClause, attributeHelp string) {
+	cmd.Flag("cluster", "The ECS cluster the instances are in").
+		StringVar(&f.cluster)
+
+	cmd.Flag("instance", "The EC2 instance id or container instance arn of an instance (repeatable)").
+		Required().
+		StringsVar(&f.instances)
+
+	cmd.Flag("attribute", attributeHelp).
+		StringVar(&f.attribute)
+
+	f.env.configure(cmd)
+}
+
+// load returns the cluster and its container instances named by --instance
+func (f *cordonFlags) load(svc api.Services) (api.Services, string, []*ecs.ContainerInstance, error) {
+	cfg, svc, err := f.env.load(svc)
+	if err != nil {
+		return svc, "", nil, err
+	}
+
+	cluster, err := resolveCluster(svc, f.cluster, cfg)
+	if err != nil {
+		return svc, "", nil, err
+	}
+
+	all, err := api.ListContainerInstances(svc.ECS, cluster)
+	if err != nil {
+		return svc, "", nil, err
+	}
+	instances := []*ecs.ContainerInstance{}
+	for _, id := range f.instances {
+		var found *ecs.ContainerInstance
+		for _, ci := range all {
+			arn := aws.StringValue(ci.ContainerInstanceArn)
+			if aws.StringValue(ci.Ec2InstanceId) == id || arn == id || strings.HasSuffix(arn, "/"+id) {
+				found = ci
+			}
+		}
+		if found == nil {
+			return svc, "", nil, failure.Errorf(failure.Validation, "No instance %s is registered with cluster %s", id, cluster)
+		}
+		instances = append(instances, found)
+	}
+	return svc, cluster, instances, nil
+}
+
+func ConfigureCordon(app *kingpin.Application, svc api.Services) {
+	var f cordonFlags
+
+	cmd := app.Command("cordon", "Stop new tasks being placed on instances, their service tasks are moved to other instances")
+	f.configure(cmd, "A custom attribute like name=value to put on the instances while they're cordoned")
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		svc, cluster, instances, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		for _, ci := range instances {
+			id := aws.StringValue(ci.Ec2InstanceId)
+			arn := aws.StringValue(ci.ContainerInstanceArn)
+			if f.attribute != "" {
+				parts := strings.SplitN(f.attribute, "=", 2)
+				parts = append(parts, "")
+				log.Printf("Setting attribute %s on %s", f.attribute, id)
+				if err = svc.Instances.PutAttribute(cluster, arn, parts[0], parts[1]); err != nil {
+					return err
+				}
+			}
+			log.Printf("Cordoning %s, it has %d tasks running", id, aws.Int64Value(ci.RunningTasksCount))
+			if err = svc.Instances.UpdateContainerInstancesState(cluster, []string{arn}, api.ContainerInstanceDraining); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func ConfigureUncordon(app *kingpin.Application, svc api.Services) {
+	var f cordonFlags
+
+	cmd := app.Command("uncordon", "Let tasks be placed on cordoned instances again")
+	f.configure(cmd, "The name of a custom attribute to remove from the instances")
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		svc, cluster, instances, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		for _, ci := range instances {
+			id := aws.StringValue(ci.Ec2InstanceId)
+			arn := aws.StringValue(ci.ContainerInstanceArn)
+			log.Printf("Uncordoning %s", id)
+			if err = svc.Instances.UpdateContainerInstancesState(cluster, []string{arn}, api.ContainerInstanceActive); err != nil {
+				return err
+			}
+			if f.attribute != "" {
+				name := strings.SplitN(f.attribute, "=", 2)[0]
+				log.Printf("Removing attribute %s from %s", name, id)
+				if err = svc.Instances.DeleteAttribute(cluster, arn, name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
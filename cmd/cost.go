@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// costTags are the cost allocation tags that bootstrap activates
+var costTags = []string{api.TagCostCluster, api.TagCostService}
+
+func ConfigureCost(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, month string
+	var byService bool
+
+	cmd := app.Command("cost", "Report a cluster's spend for a month from Cost Explorer")
+	cmd.Flag("cluster", "The ECS cluster to report spend for").
+		StringVar(&cluster)
+
+	cmd.Flag("by-service", "Break down the spend by service").
+		BoolVar(&byService)
+
+	cmd.Flag("month", "The month to report, like 2017-02, defaults to last month").
+		StringVar(&month)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		start, err := costMonth(month, time.Now())
+		if err != nil {
+			return err
+		}
+		end := start.AddDate(0, 1, 0)
+
+		groupKey := api.TagCostCluster
+		if byService {
+			groupKey = api.TagCostService
+		}
+		costs, err := svc.Costs.CostsByTag(start, end, api.TagCostCluster, cluster, groupKey)
+		if err != nil {
+			return err
+		}
+		if len(costs) == 0 {
+			log.Printf("No spend is tagged %s=%s in %s, `ecsy bootstrap` activates the cost allocation tags",
+				api.TagCostCluster, cluster, start.Format("January 2006"))
+			return nil
+		}
+
+		sort.SliceStable(costs, func(i, j int) bool {
+			return costs[i].Amount > costs[j].Amount
+		})
+		log.Printf("Spend of cluster %s in %s", cluster, start.Format("January 2006"))
+		var total float64
+		for _, cost := range costs {
+			name := cost.Value
+			if !byService {
+				name = cluster
+			} else if name == "" {
+				// instances, their volumes and the rest of the cluster stack
+				name = "(cluster)"
+			}
+			fmt.Printf("%-40s %12.2f %s\n", name, cost.Amount, cost.Unit)
+			total += cost.Amount
+		}
+		if byService {
+			fmt.Printf("%-40s %12.2f %s\n", "total", total, costs[0].Unit)
+		}
+		return nil
+	})
+}
+
+// costMonth returns the start of a month like 2017-02, or of the month before now
+func costMonth(month string, now time.Time) (time.Time, error) {
+	if month == "" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0), nil
+	}
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, failure.Errorf(failure.Validation, "Invalid --month %q, expected a month like 2017-02", month)
+	}
+	return t, nil
+}
+
+// activateCostTags activates ecsy's cost allocation tags, which billing only knows about
+// once they've been on a resource for a day
+func activateCostTags(svc api.Services, dryRun bool) {
+	statuses, err := svc.Costs.CostAllocationTags(costTags...)
+	if err != nil {
+		log.Printf("Not checking cost allocation tags: %v", err)
+		return
+	}
+	inactive := []string{}
+	for _, key := range costTags {
+		switch statuses[key] {
+		case "Active":
+			log.Printf("Cost allocation tag %s is active", key)
+		case "":
+			log.Printf("Cost allocation tag %s isn't on any resources yet, rerun bootstrap a day after creating a cluster", key)
+		default:
+			inactive = append(inactive, key)
+		}
+	}
+	if len(inactive) == 0 {
+		return
+	}
+	for _, key := range inactive {
+		if dryRun {
+			log.Printf("Inactive cost allocation tag %s", key)
+		} else {
+			log.Printf("Activating cost allocation tag %s", key)
+		}
+	}
+	if !dryRun {
+		if err = svc.Costs.ActivateCostAllocationTags(inactive...); err != nil {
+			log.Printf("Failed to activate cost allocation tags: %v", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func cacheStackName(cluster, name string) string {
+	return fmt.Sprintf("ecs-%s-%s-cache", cluster, name)
+}
+
+func ConfigureCreateCache(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, name, engine, engineVersion, nodeType, onExists string
+	var nodes int
+	var transitEncryption, disableRollback, allowReplacement bool
+
+	cmd := app.Command("create-cache", "Create an ElastiCache replication group in the private subnets of a cluster")
+	cmd.Flag("cluster", "The name of the ECS cluster the cache is for").
+		StringVar(&cluster)
+
+	cmd.Flag("name", "The name of the cache, used to link services to it and prefix their environment").
+		Default("cache").
+		StringVar(&name)
+
+	cmd.Flag("engine", "The cache engine: redis or valkey").
+		Default("redis").
+		EnumVar(&engine, "redis", "valkey")
+
+	cmd.Flag("engine-version", "The engine version, defaults to the latest").
+		StringVar(&engineVersion)
+
+	cmd.Flag("node-type", "The node type of the cache nodes").
+		Default("cache.t3.micro").
+		StringVar(&nodeType)
+
+	cmd.Flag("nodes", "The number of nodes, more than one adds replicas with automatic failover").
+		Default("1").
+		IntVar(&nodes)
+
+	cmd.Flag("transit-encryption", "Require TLS for connections to the cache").
+		BoolVar(&transitEncryption)
+
+	cmd.Flag("disable-rollback", "Don't rollback created infrastructure if a failure occurs").
+		BoolVar(&disableRollback)
+
+	configureAllowReplacement(cmd, &allowReplacement)
+	env.configure(cmd)
+
+	configureOnExists(cmd, "cache", &onExists)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		outputs, err := createCompanionStack(svc, companionStack{
+			Kind:      "cache",
+			Name:      name,
+			Cluster:   cluster,
+			StackName: cacheStackName(cluster, name),
+			Template:  templates.EcsCache(),
+			Params: map[string]string{
+				"Engine":            engine,
+				"EngineVersion":     engineVersion,
+				"NodeType":          nodeType,
+				"Nodes":             strconv.Itoa(nodes),
+				"TransitEncryption": strconv.FormatBool(transitEncryption),
+			},
+			OnExists:         onExists,
+			DisableRollback:  disableRollback,
+			AllowReplacement: allowReplacement,
+		})
+		if err != nil || outputs == nil {
+			return err
+		}
+
+		log.Printf("Cache %s available at %s", name, outputs["URL"])
+		log.Printf("Link services with `create-service --cache %s`", name)
+		return nil
+	})
+}
@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/notify"
 	"github.com/lox/ecsy/templates"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -18,19 +23,44 @@ const (
 	stackDateFormat = "20060102-150405"
 )
 
+// What create commands do when the stack they would create already exists
+const (
+	onExistsSkip   = "skip"
+	onExistsUpdate = "update"
+	onExistsFail   = "fail"
+)
+
+func configureOnExists(cmd *kingpin.CmdClause, what string, onExists *string) {
+	cmd.Flag("on-exists", fmt.Sprintf("What to do if the %s already exists: skip, update or fail", what)).
+		Default(onExistsFail).
+		EnumVar(onExists, onExistsSkip, onExistsUpdate, onExistsFail)
+
+	cmd.Flag("if-not-exists", fmt.Sprintf("Only create the %s if it doesn't exist, same as --on-exists=skip", what)).
+		PreAction(func(c *kingpin.ParseContext) error {
+			*onExists = onExistsSkip
+			return nil
+		}).
+		Bool()
+}
+
 func clusterStackName(cluster string) string {
 	return fmt.Sprintf("ecs-%s-cluster", cluster)
 }
 
 func ConfigureCreateCluster(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
 	var cluster, keyName, instanceType, dockerUsername, dockerPassword, dockerEmail, authorizedKeys string
-	var datadogKey, logspoutTarget string
-	var instanceCount int
-	var disableRollback bool
+	var datadogKey, logspoutTarget, onExists, kmsKeyArn, vpcEndpoints, flowLogs string
+	var instanceCount, flowLogsInterval, logRetentionDays, totalVCPUs, totalMemory int
+	var recommend, disableRollback, allowReplacement, protect, hardened, noSSH, instanceConnect, disableDockerBridge, accessLogs bool
+	var ingress ingressFlags
+	var downtime downtimeFlags
+	var render renderFlags
+	var quota quotaFlags
+	var attributes map[string]string
 
 	cmd := app.Command("create-cluster", "Create an ECS cluster")
 	cmd.Flag("cluster", "The name of the ECS cluster to create").
-		Required().
 		StringVar(&cluster)
 
 	cmd.Flag("keyname", "The EC2 keypair to use for instance").
@@ -45,6 +75,15 @@ func ConfigureCreateCluster(app *kingpin.Application, svc api.Services) {
 		Default("3").
 		IntVar(&instanceCount)
 
+	cmd.Flag("recommend", "Print the instance type that fits --total-vcpus and --total-memory over --count instances, rather than creating the cluster").
+		BoolVar(&recommend)
+
+	cmd.Flag("total-vcpus", "The vcpus the cluster's instances need between them, for --recommend").
+		IntVar(&totalVCPUs)
+
+	cmd.Flag("total-memory", "The GiB of memory the cluster's instances need between them, for --recommend").
+		IntVar(&totalMemory)
+
 	cmd.Flag("docker-username", "The docker Username to use").
 		StringVar(&dockerUsername)
 
@@ -63,24 +102,217 @@ func ConfigureCreateCluster(app *kingpin.Application, svc api.Services) {
 	cmd.Flag("authorized-keys", "A URL to fetch a SSH authorized_keys file from.").
 		StringVar(&authorizedKeys)
 
+	cmd.Flag("kms-key-arn", "A KMS key to encrypt volumes, logs and tables with, defaults to AWS managed keys").
+		StringVar(&kmsKeyArn)
+
+	cmd.Flag("log-retention-days", "The number of days to keep the cluster's logs for").
+		Default("14").
+		IntVar(&logRetentionDays)
+
+	cmd.Flag("hardened", "Apply hardened defaults: no ssh keypair, instance ingress only from the cluster and access via SSM").
+		BoolVar(&hardened)
+
+	cmd.Flag("no-ssh", "Don't use an ssh keypair, access instances via SSM Session Manager with `ecsy ssh` instead").
+		BoolVar(&noSSH)
+
+	cmd.Flag("instance-connect", "Install EC2 Instance Connect, so `ecsy ssh` pushes a temporary key for each connection").
+		BoolVar(&instanceConnect)
+
+	cmd.Flag("attribute", "A custom ECS attribute for the cluster's instances, like pool=general, that placement constraints can target (repeatable)").
+		StringMapVar(&attributes)
+
+	cmd.Flag("disable-docker-bridge", "Disable the docker bridge network on instances, tasks must use host networking").
+		BoolVar(&disableDockerBridge)
+
+	ingress.configure(cmd, "cluster instances")
+	downtime.configure(cmd)
+
+	cmd.Flag("vpc-endpoints", fmt.Sprintf("Comma separated VPC endpoints to add to the network, any of %s", strings.Join(vpcEndpointNames(), ","))).
+		StringVar(&vpcEndpoints)
+
+	cmd.Flag("flow-logs", "Enable VPC flow logs to a generated cloudwatch log group or s3 bucket").
+		EnumVar(&flowLogs, "cloudwatch", "s3")
+
+	cmd.Flag("flow-logs-interval", "The seconds flow logs are aggregated over, 60 or 600").
+		Default("600").
+		IntVar(&flowLogsInterval)
+
+	cmd.Flag("access-logs", "Create a bucket for service load balancers to write access logs to, which can be queried with `query`").
+		BoolVar(&accessLogs)
+
 	cmd.Flag("disable-rollback", "Don't rollback created infrastructure if a failure occurs").
 		BoolVar(&disableRollback)
 
+	cmd.Flag("protect", "Turn on termination protection for the cluster and network stacks, defaults to protect in the config or whether the environment is production").
+		BoolVar(&protect)
+
+	configureAllowReplacement(cmd, &allowReplacement)
+	render.configure(cmd)
+	quota.configure(cmd)
+	env.configure(cmd)
+
+	configureOnExists(cmd, "cluster", &onExists)
+
 	cmd.Action(func(c *kingpin.ParseContext) error {
-		_, err := svc.ECS.CreateCluster(&ecs.CreateClusterInput{
-			ClusterName: aws.String(cluster),
-		})
+		env.Offline = render.enabled()
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+		if !flagGiven(c, "protect") {
+			protect = shouldProtect(cfg, env.Env)
+		}
+
+		if recommend {
+			return recommendInstanceType(svc, totalVCPUs, totalMemory, instanceCount, env.Env)
+		}
+
+		// a new cluster is named rather than picked from the existing ones, and the instances
+		// it's created with are asked about too
+		if cluster == "" && cfg.Cluster == "" && interactive() {
+			if cluster, err = prompt("--cluster", "The name of the ECS cluster to create", "", nil); err != nil {
+				return err
+			}
+			if !hardened && !noSSH {
+				if err = promptFlag(svc, c, "create-cluster", "keyname", "The EC2 keypair to use for instances", &keyName); err != nil {
+					return err
+				}
+			}
+			if err = promptFlag(svc, c, "create-cluster", "type", "The EC2 instance type to use", &instanceType); err != nil {
+				return err
+			}
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		var offered []string
+		if !render.enabled() {
+			offered = offeredInstanceTypes(svc)
+		}
+		if err = checkInstanceType(instanceType, offered, env.Env); err != nil {
+			return err
+		}
 
-		network, err := getOrCreateNetworkStack(cluster, disableRollback, svc)
+		downtimeParams, err := downtime.params()
+		if err != nil {
+			return err
+		}
+
+		instanceAttributes, err := instanceAttributesJSON(attributes)
+		if err != nil {
+			return err
+		}
+
+		if err = api.ValidateLogRetention(logRetentionDays); err != nil {
+			return err
+		}
+
+		// a rendered cluster is rendered as it would be created
+		var existing *cloudformation.Stack
+		if !render.enabled() {
+			if existing, err = api.LookupClusterStack(svc.Cloudformation, cluster); err != nil {
+				return err
+			}
+		}
+		// a stack left in progress by an interrupted run is attached to, rather than existing
+		resuming := api.IsStackInProgress(existing)
+		creating := existing == nil || aws.StringValue(existing.StackStatus) == cloudformation.StackStatusCreateInProgress
+		if resuming {
+			log.Printf("Cluster stack %s is already %s, resuming", *existing.StackName, *existing.StackStatus)
+		} else if existing != nil {
+			switch onExists {
+			case onExistsSkip:
+				log.Printf("Cluster %s already exists, skipping", cluster)
+				return nil
+			case onExistsFail:
+				return fmt.Errorf("A cluster already exists for %q. Use --on-exists=update to converge it", cluster)
+			}
+		}
+
+		if hardened {
+			log.Printf("Applying hardened defaults, instances will be accessible via SSM only")
+			keyName = ""
+		} else if noSSH {
+			log.Printf("Instances will be accessible via Session Manager rather than ssh")
+			keyName = ""
+		}
+
+		// the quotas a new network stack uses and what the instances need to launch are
+		// checked before anything is created
+		if !render.enabled() {
+			if err = preflightCluster(svc, cfg, cluster, keyName, instanceType); err != nil {
+				return err
+			}
+
+			quotas, err := clusterQuotas(svc, cluster)
+			if err != nil {
+				return err
+			}
+			if err = quota.check(svc, quotas); err != nil {
+				return err
+			}
+		}
+
+		networkOpts := networkOptions{
+			DisableRollback:  disableRollback,
+			VpcEndpoints:     splitList(vpcEndpoints),
+			FlowLogs:         flowLogs,
+			FlowLogsInterval: flowLogsInterval,
+		}
+
+		var network api.NetworkOutputs
+		if render.enabled() {
+			network, err = renderNetworkStack(cluster, networkOpts, render)
+		} else {
+			_, err = svc.ECS.CreateCluster(&ecs.CreateClusterInput{
+				ClusterName: aws.String(cluster),
+			})
+			if err != nil {
+				return err
+			}
+			network, err = getOrCreateNetworkStack(cluster, networkOpts, svc)
+		}
 		if err != nil {
 			return err
 		}
 
 		timer := time.Now()
 		stackName := clusterStackName(cluster)
-		log.Printf("Creating cloudformation stack %s", stackName)
+		if existing != nil {
+			stackName = *existing.StackName
+		}
+
+		rules, err := ingress.rules("10.0.0.0/16", "1-65535")
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			log.Printf("Allowing ingress %s", rule)
+		}
+		groups := []templates.InstanceGroup{}
+		for _, name := range sortedInstanceGroups(cfg) {
+			g := cfg.InstanceGroups[name]
+			log.Printf("Creating instance group %s of %d %s instances", name, g.Count, g.Type)
+			groups = append(groups, templates.InstanceGroup{
+				Name:         name,
+				InstanceType: g.Type,
+				ImageID:      g.AMI,
+				GPU:          g.GPU,
+				Desired:      g.Count,
+				Min:          g.Min,
+				Max:          g.Max,
+				Attributes:   g.Attributes,
+			})
+		}
+		template, err := templates.ClusterStack(rules, groups)
+		if err != nil {
+			return err
+		}
 
-		ctx := api.CreateStackContext{
+		ctx := api.StackOptions{
 			Params: map[string]string{
 				"VpcId":               network.VpcId,
 				"VpcPrivateSubnet1Id": network.Subnet2Private,
@@ -95,49 +327,153 @@ func ConfigureCreateCluster(app *kingpin.Application, svc api.Services) {
 				"LogspoutTarget":      logspoutTarget,
 				"DatadogApiKey":       datadogKey,
 				"AuthorizedUsersUrl":  authorizedKeys,
+				"KmsKeyArn":           kmsKeyArn,
+				"LogRetentionDays":    strconv.Itoa(logRetentionDays),
+				"Hardened":            strconv.FormatBool(hardened),
+				"SessionManager":      strconv.FormatBool(hardened || noSSH),
+				"InstanceConnect":     strconv.FormatBool(instanceConnect),
+				"DisableDockerBridge": strconv.FormatBool(disableDockerBridge),
+				"RestrictIngress":     strconv.FormatBool(ingress.restricted()),
+				"AccessLogs":          strconv.FormatBool(accessLogs),
+				"InstanceAttributes":  instanceAttributes,
 			},
-			DisableRollback: disableRollback,
+			DisableRollback:  disableRollback,
+			Attach:           true,
+			AllowReplacement: allowReplacement,
 		}
 
-		err = api.CreateStack(svc.Cloudformation, stackName, templates.EcsStack(), ctx)
-		if err != nil {
+		if downtime.isSet() {
+			log.Printf("Scaling instances to zero on %s and back up on %s", downtime.Down, downtime.Up)
+		}
+		for k, v := range downtimeParams {
+			ctx.Params[k] = v
+		}
+
+		if render.enabled() {
+			return render.stack(stackName, template, ctx)
+		}
+
+		if !creating {
+			log.Printf("Updating cloudformation stack %s", stackName)
+			err = api.UpdateStack(svc.Cloudformation, stackName, template, ctx)
+		} else {
+			log.Printf("Creating cloudformation stack %s", stackName)
+			err = api.CreateStack(svc.Cloudformation, stackName, template, ctx)
+		}
+		if err != nil && err != api.ErrNoStackUpdates {
 			return err
 		}
 
-		err = api.PollUntilCreated(svc.Cloudformation, stackName, func(event *cloudformation.StackEvent) {
-			log.Printf("%s\n", api.FormatStackEvent(event))
-		})
+		if protect {
+			if protectErr := protectStacks(svc, network.StackName, stackName); protectErr != nil {
+				return protectErr
+			}
+		}
+		if err == api.ErrNoStackUpdates {
+			log.Printf("Cluster %s is already up to date", cluster)
+			return nil
+		}
+
+		err = waitForStack(svc, stackName)
 		if err != nil {
 			return err
 		}
 
+		if !creating {
+			log.Printf("Cluster %s updated in %s\n\n", cluster, time.Now().Sub(timer).String())
+			return nil
+		}
+
+		notifiersFromConfig(svc, cfg).Notify(notify.Event{
+			Type:     notify.ClusterCreated,
+			Cluster:  cluster,
+			Duration: time.Now().Sub(timer),
+		})
+
 		log.Printf("Cluster %s created in %s\n\n", cluster, time.Now().Sub(timer).String())
 		return nil
 	})
 }
 
-func getOrCreateNetworkStack(clusterName string, disableRollback bool, svc api.Services) (api.NetworkOutputs, error) {
-	outputs, err := api.FindNetworkStack(svc.Cloudformation, clusterName)
-	if err == nil {
-		return outputs, nil
-	}
+// networkOptions are the optional features of the network stack for a cluster
+type networkOptions struct {
+	DisableRollback  bool
+	VpcEndpoints     []string
+	FlowLogs         string
+	FlowLogsInterval int
+}
 
-	timer := time.Now()
-	log.Printf("Creating Network Stack for %s", clusterName)
+// params returns the stack parameters for the options that have been set, so that
+// parameters of an existing stack can be carried forward for the rest
+func (o networkOptions) params() map[string]string {
+	params := map[string]string{}
+	if len(o.VpcEndpoints) > 0 {
+		params["VpcEndpoints"] = strings.Join(o.VpcEndpoints, ",")
+	}
+	if o.FlowLogs != "" {
+		params["FlowLogs"] = o.FlowLogs
+		params["FlowLogsInterval"] = strconv.Itoa(o.FlowLogsInterval)
+	}
+	return params
+}
 
-	ctx := api.CreateStackContext{
-		Params:          map[string]string{},
+func networkStackContext(params map[string]string, disableRollback bool) (string, api.StackOptions, error) {
+	tpl, err := templates.WithVpcEndpoints(templates.NetworkStack(), splitList(params["VpcEndpoints"]))
+	if err != nil {
+		return "", api.StackOptions{}, err
+	}
+	return tpl, api.StackOptions{
+		Params:          params,
 		DisableRollback: disableRollback,
+	}, nil
+}
+
+func getOrCreateNetworkStack(clusterName string, opts networkOptions, svc api.Services) (api.NetworkOutputs, error) {
+	params := opts.params()
+
+	existing, err := api.LookupNetworkStack(svc.Cloudformation, clusterName)
+	if err != nil {
+		return api.NetworkOutputs{}, err
+	}
+	outputs := api.NetworkOutputs{StackName: clusterName + "-network"}
+	if existing != nil {
+		outputs = *existing
+		stacks, err := api.FindStacksByName(svc.Cloudformation, outputs.StackName)
+		if err != nil {
+			return api.NetworkOutputs{}, err
+		}
+		if len(stacks) > 0 && api.IsStackInProgress(stacks[0]) {
+			log.Printf("Network Stack %s is already %s, resuming", outputs.StackName, *stacks[0].StackStatus)
+			if err = waitForStack(svc, outputs.StackName); err != nil {
+				return api.NetworkOutputs{}, err
+			}
+			if existing, err = api.LookupNetworkStack(svc.Cloudformation, clusterName); err != nil {
+				return api.NetworkOutputs{}, err
+			} else if existing == nil {
+				return api.NetworkOutputs{}, fmt.Errorf("Network Stack %s no longer exists after resuming it", outputs.StackName)
+			}
+			outputs = *existing
+		}
+		if len(params) == 0 {
+			return outputs, nil
+		}
+		return updateNetworkStack(clusterName, outputs, params, svc)
 	}
 
-	err = api.CreateStack(svc.Cloudformation, outputs.StackName, templates.NetworkStack(), ctx)
+	tpl, ctx, err := networkStackContext(params, opts.DisableRollback)
 	if err != nil {
 		return api.NetworkOutputs{}, err
 	}
 
-	err = api.PollUntilCreated(svc.Cloudformation, outputs.StackName, func(event *cloudformation.StackEvent) {
-		log.Printf("%s\n", api.FormatStackEvent(event))
-	})
+	timer := time.Now()
+	log.Printf("Creating Network Stack for %s", clusterName)
+
+	err = api.CreateStack(svc.Cloudformation, outputs.StackName, tpl, ctx)
+	if err != nil {
+		return api.NetworkOutputs{}, err
+	}
+
+	err = waitForStack(svc, outputs.StackName)
 	if err != nil {
 		return api.NetworkOutputs{}, err
 	}
@@ -150,3 +486,108 @@ func getOrCreateNetworkStack(clusterName string, disableRollback bool, svc api.S
 	log.Printf("%s created in %s\n\n", outputs.StackName, time.Now().Sub(timer).String())
 	return outputs, nil
 }
+
+// updateNetworkStack converges an existing network stack when network options are given,
+// keeping the options it was previously created with unless they are overridden
+func updateNetworkStack(clusterName string, outputs api.NetworkOutputs, params map[string]string, svc api.Services) (api.NetworkOutputs, error) {
+	if err := existingNetworkParams(svc, outputs.StackName, params); err != nil {
+		return api.NetworkOutputs{}, err
+	}
+
+	tpl, ctx, err := networkStackContext(params, false)
+	if err != nil {
+		return api.NetworkOutputs{}, err
+	}
+
+	timer := time.Now()
+	log.Printf("Updating Network Stack for %s", clusterName)
+
+	err = api.UpdateStack(svc.Cloudformation, outputs.StackName, tpl, ctx)
+	if err == api.ErrNoStackUpdates {
+		log.Printf("Network Stack %s is already up to date", outputs.StackName)
+		return outputs, nil
+	} else if err != nil {
+		return api.NetworkOutputs{}, err
+	}
+
+	err = waitForStack(svc, outputs.StackName)
+	if err != nil {
+		return api.NetworkOutputs{}, err
+	}
+
+	log.Printf("%s updated in %s\n\n", outputs.StackName, time.Now().Sub(timer).String())
+	return outputs, nil
+}
+
+// existingNetworkParams adds the parameters of an existing network stack that aren't overridden
+func existingNetworkParams(svc api.Services, stackName string, params map[string]string) error {
+	stacks, err := api.FindStacksByName(svc.Cloudformation, stackName)
+	if err != nil {
+		return err
+	}
+	for _, stack := range stacks {
+		for _, p := range stack.Parameters {
+			if _, exists := params[*p.ParameterKey]; !exists {
+				params[*p.ParameterKey] = aws.StringValue(p.ParameterValue)
+			}
+		}
+	}
+	return nil
+}
+
+// renderNetworkStack renders the network stack that create-cluster would create, returning
+// the placeholder outputs the cluster stack is rendered with
+func renderNetworkStack(clusterName string, opts networkOptions, render renderFlags) (api.NetworkOutputs, error) {
+	outputs := renderedNetwork(clusterName)
+	tpl, ctx, err := networkStackContext(opts.params(), opts.DisableRollback)
+	if err != nil {
+		return api.NetworkOutputs{}, err
+	}
+	return outputs, render.stack(outputs.StackName, tpl, ctx)
+}
+
+func vpcEndpointNames() []string {
+	names := []string{}
+	for name := range templates.VpcEndpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sortedInstanceGroups returns the names of the instance groups in config in order
+func sortedInstanceGroups(cfg *config.Config) []string {
+	names := []string{}
+	for name := range cfg.InstanceGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// instanceAttributesJSON returns the ECS attributes of the main group's instances in the
+// format the agent's ECS_INSTANCE_ATTRIBUTES takes
+func instanceAttributesJSON(attributes map[string]string) (string, error) {
+	all := map[string]string{templates.InstanceGroupAttribute: templates.DefaultInstanceGroup}
+	for k, v := range attributes {
+		if k == templates.InstanceGroupAttribute || strings.HasPrefix(k, "ecs.") {
+			return "", fmt.Errorf("Attribute %s is set by ecs or ecsy", k)
+		}
+		if err := config.ValidateAttribute(k, v); err != nil {
+			return "", fmt.Errorf("Attribute has an %v", err)
+		}
+		all[k] = v
+	}
+	b, err := json.Marshal(all)
+	return string(b), err
+}
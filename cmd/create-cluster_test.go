@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+)
+
+func TestInstanceAttributesJSON(t *testing.T) {
+	for _, tc := range []struct {
+		attributes map[string]string
+		json       string
+		err        bool
+	}{
+		{attributes: nil, json: `{"ecsy.instance-group":"default"}`},
+		{attributes: map[string]string{"tier": "db", "zone-group": "a/b"}, json: `{"ecsy.instance-group":"default","tier":"db","zone-group":"a/b"}`},
+		{attributes: map[string]string{"ecsy.instance-group": "web"}, err: true},
+		{attributes: map[string]string{"ecs.instance-type": "t2.micro"}, err: true},
+		{attributes: map[string]string{"tier": "db,cache"}, err: true},
+		{attributes: map[string]string{"tier": ""}, err: true},
+	} {
+		json, err := instanceAttributesJSON(tc.attributes)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Expected %v to be rejected, got %s", tc.attributes, json)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %v to be allowed, got %v", tc.attributes, err)
+		} else if json != tc.json {
+			t.Errorf("Expected %v to be %s, got %s", tc.attributes, tc.json, json)
+		}
+	}
+}
+
+// throttledStacks fails describing stacks by name with throttling
+type throttledStacks struct {
+	api.CFNAPI
+}
+
+func (c *throttledStacks) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	if input != nil && input.StackName != nil {
+		return nil, awserr.New("Throttling", "Rate exceeded", nil)
+	}
+	return c.CFNAPI.DescribeStacks(input)
+}
+
+func TestGetOrCreateNetworkStackReturnsLookupErrors(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	svc.Cloudformation = &throttledStacks{svc.Cloudformation}
+
+	if _, err := getOrCreateNetworkStack("example", networkOptions{}, svc); err == nil {
+		t.Fatalf("Expected a failed lookup of the network stack to fail")
+	}
+	if stacks, err := api.FindStacksByName(fake.CloudFormation, "example-network"); err != nil || len(stacks) != 0 {
+		t.Fatalf("Expected no network stack to be created when the lookup failed, got %d, %v", len(stacks), err)
+	}
+}
+
+func TestGetOrCreateNetworkStackUsesExisting(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	fake.CloudFormation.SetOutputs("example-network", map[string]string{"VpcId": "vpc-1234"})
+	if err := api.CreateStack(svc.Cloudformation, "example-network", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	network, err := getOrCreateNetworkStack("example", networkOptions{}, svc)
+	if err != nil || network.VpcId != "vpc-1234" {
+		t.Fatalf("Expected the existing network stack, got %+v, %v", network, err)
+	}
+}
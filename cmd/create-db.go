@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func dbStackName(cluster, name string) string {
+	return fmt.Sprintf("ecs-%s-%s-db", cluster, name)
+}
+
+func ConfigureCreateDB(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, name, engine, engineVersion, instanceClass, databaseName, onExists string
+	var storage int
+	var multiAZ, disableRollback, allowReplacement bool
+
+	cmd := app.Command("create-db", "Create an RDS database in the private subnets of a cluster")
+	cmd.Flag("cluster", "The name of the ECS cluster the database is for").
+		StringVar(&cluster)
+
+	cmd.Flag("name", "The name of the database, used to link services to it and prefix their environment").
+		Default("db").
+		StringVar(&name)
+
+	cmd.Flag("engine", "The database engine: postgres, mysql, aurora-postgresql or aurora-mysql").
+		Default("postgres").
+		EnumVar(&engine, "postgres", "mysql", "aurora-postgresql", "aurora-mysql")
+
+	cmd.Flag("engine-version", "The engine version, defaults to the latest").
+		StringVar(&engineVersion)
+
+	cmd.Flag("class", "The instance class of the database").
+		Default("db.t3.medium").
+		StringVar(&instanceClass)
+
+	cmd.Flag("storage", "The storage in GB for non-aurora engines").
+		Default("20").
+		IntVar(&storage)
+
+	cmd.Flag("database-name", "The name of the database to create in the engine").
+		Default("app").
+		StringVar(&databaseName)
+
+	cmd.Flag("multi-az", "Run a standby instance in another availability zone for non-aurora engines").
+		BoolVar(&multiAZ)
+
+	cmd.Flag("disable-rollback", "Don't rollback created infrastructure if a failure occurs").
+		BoolVar(&disableRollback)
+
+	configureAllowReplacement(cmd, &allowReplacement)
+	env.configure(cmd)
+
+	configureOnExists(cmd, "database", &onExists)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		outputs, err := createCompanionStack(svc, companionStack{
+			Kind:      "database",
+			Name:      name,
+			Cluster:   cluster,
+			StackName: dbStackName(cluster, name),
+			Template:  templates.EcsDatabase(),
+			Params: map[string]string{
+				"Engine":           engine,
+				"EngineVersion":    engineVersion,
+				"InstanceClass":    instanceClass,
+				"AllocatedStorage": strconv.Itoa(storage),
+				"DatabaseName":     databaseName,
+				"MultiAZ":          strconv.FormatBool(multiAZ),
+			},
+			OnExists:         onExists,
+			DisableRollback:  disableRollback,
+			AllowReplacement: allowReplacement,
+		})
+		if err != nil || outputs == nil {
+			return err
+		}
+
+		log.Printf("Database %s available at %s:%s", name, outputs["Host"], outputs["Port"])
+		log.Printf("Credentials are in secret %s, link services with `create-service --database %s`", outputs["SecretArn"], name)
+		return nil
+	})
+}
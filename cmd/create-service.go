@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -13,6 +17,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/lox/ecsy/api"
 	"github.com/lox/ecsy/compose"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/notify"
 	"github.com/lox/ecsy/templates"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -22,13 +28,20 @@ func serviceStackName(cluster, taskFamily string) string {
 }
 
 func ConfigureCreateService(app *kingpin.Application, svc api.Services) {
-	var cluster, projectName, healthCheck, certificateID string
-	var composeFiles []string
-	var disableRollback bool
+	var env environmentFlags
+	var cluster, projectName, healthCheck, certificateID, metricsPort, metricsPath, onExists, logKmsKeyArn, alarms string
+	var pagerDutyKey, opsgenieKey, namespace string
+	var composeFiles, databases, caches, constraints []string
+	var disableRollback, allowReplacement, assetsBucket, assetsCDN, noLoadBalancer, serviceConnect bool
+	var count, logRetentionDays int
+	var ingress ingressFlags
+	var resources containerResourceFlags
+	var render renderFlags
+	var quota quotaFlags
+	var waf wafFlags
 
 	cmd := app.Command("create-service", "Create an ECS service for your app")
 	cmd.Flag("cluster", "The name of the ECS cluster to use").
-		Required().
 		StringVar(&cluster)
 
 	cmd.Flag("project-name", "The name of the Compose project").
@@ -44,30 +57,133 @@ func ConfigureCreateService(app *kingpin.Application, svc api.Services) {
 		Default("").
 		StringVar(&certificateID)
 
+	cmd.Flag("constraint", "A placement constraint like attribute:ecsy.instance-group==gpu that instances must match to run the service's tasks (repeatable)").
+		StringsVar(&constraints)
+
+	cmd.Flag("count", "The number of tasks to run, defaults to the config or 1").
+		IntVar(&count)
+
+	cmd.Flag("metrics-port", "The host port the service exposes prometheus metrics on").
+		StringVar(&metricsPort)
+
+	cmd.Flag("metrics-path", "The path the service exposes prometheus metrics on").
+		Default("/metrics").
+		StringVar(&metricsPath)
+
 	cmd.Flag("file", "The paths to docker-compose files to convert to service definitions").
 		Short('f').
 		Default("docker-compose.yml").
 		ExistingFilesVar(&composeFiles)
 
+	resources.configure(cmd)
+
+	cmd.Flag("no-load-balancer", "Create a worker service that just keeps its tasks running, same as `type: worker` in config").
+		BoolVar(&noLoadBalancer)
+
+	ingress.configure(cmd, "service load balancer")
+	waf.configure(cmd)
+
+	cmd.Flag("service-connect", "Connect the service to others in the cluster with ECS service connect, reachable at http://<project-name>:<port>").
+		BoolVar(&serviceConnect)
+
+	cmd.Flag("namespace", "The cloud map namespace for service connect, created if it doesn't exist, defaults to the cluster name").
+		StringVar(&namespace)
+
+	cmd.Flag("database", "A database created with `create-db` to give tasks access to (repeatable)").
+		StringsVar(&databases)
+
+	cmd.Flag("cache", "A cache created with `create-cache` to pass connection details for (repeatable)").
+		StringsVar(&caches)
+
+	cmd.Flag("assets-bucket", "Create an s3 bucket for assets that tasks can access via $ASSETS_BUCKET").
+		BoolVar(&assetsBucket)
+
+	cmd.Flag("assets-cdn", "Serve the assets bucket with CloudFront at $ASSETS_URL").
+		BoolVar(&assetsCDN)
+
+	cmd.Flag("alarms", "Create a standard pack of alarms and a dashboard for the service, notifying the sns topic in config").
+		Default("none").
+		EnumVar(&alarms, "none", "default")
+
+	cmd.Flag("pagerduty-key", "A PagerDuty CloudWatch integration key to route the service's alarms to, implies --alarms default").
+		StringVar(&pagerDutyKey)
+
+	cmd.Flag("opsgenie-key", "An Opsgenie CloudWatch integration API key to route the service's alarms to, implies --alarms default").
+		StringVar(&opsgenieKey)
+
+	cmd.Flag("log-retention-days", "The number of days to keep the service's logs for").
+		Default("30").
+		IntVar(&logRetentionDays)
+
+	cmd.Flag("log-kms-key-arn", "A KMS key to encrypt the service's logs with, defaults to the cluster's key").
+		StringVar(&logKmsKeyArn)
+
 	cmd.Flag("disable-rollback", "Don't rollback created infrastructure if a failure occurs").
 		BoolVar(&disableRollback)
 
+	configureAllowReplacement(cmd, &allowReplacement)
+	render.configure(cmd)
+	quota.configure(cmd)
+	env.configure(cmd)
+
+	configureOnExists(cmd, "service", &onExists)
+
 	cmd.Action(func(c *kingpin.ParseContext) error {
-		log.Printf("Creating service %s on %s", projectName, cluster)
+		env.Offline = render.enabled()
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
 
-		clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		cluster, err := resolveCluster(svc, cluster, cfg)
 		if err != nil {
 			return err
 		}
-		if clusterStack == nil {
-			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`",
-				cluster)
+
+		if err = api.ValidateLogRetention(logRetentionDays); err != nil {
+			return err
+		}
+
+		log.Printf("Creating service %s on %s", projectName, cluster)
+
+		var clusterStack, existing *cloudformation.Stack
+		if render.enabled() {
+			// a rendered service is rendered as it would be created, on a cluster whose
+			// parameters aren't known
+			clusterStack = &cloudformation.Stack{StackName: aws.String(clusterStackName(cluster))}
+		} else {
+			if clusterStack, err = api.LookupClusterStack(svc.Cloudformation, cluster); err != nil {
+				return err
+			}
+			if clusterStack == nil {
+				return fmt.Errorf("No cluster exists for %q. Use `create-cluster`",
+					cluster)
+			}
+
+			// a stack left in progress by an interrupted run is attached to, rather than existing
+			if existing, err = api.LookupServiceStack(svc.Cloudformation, cluster, projectName); err != nil {
+				return err
+			}
+		}
+		creating := existing == nil || aws.StringValue(existing.StackStatus) == cloudformation.StackStatusCreateInProgress
+		if api.IsStackInProgress(existing) {
+			log.Printf("Service stack %s is already %s, resuming", *existing.StackName, *existing.StackStatus)
+		} else if existing != nil {
+			switch onExists {
+			case onExistsSkip:
+				log.Printf("Service %s already exists on %s, skipping", projectName, cluster)
+				return nil
+			case onExistsFail:
+				return fmt.Errorf("A service already exists for %q in cluster %q. Use `deploy` or --on-exists=update",
+					projectName, cluster)
+			}
 		}
 
-		stack, _ := api.FindServiceStack(svc.Cloudformation, cluster, projectName)
-		if stack != nil {
-			return fmt.Errorf("A service already exists for %q in cluster %q. Use `deploy` or `update-service`",
-				projectName, cluster)
+		if !render.enabled() {
+			loadBalancer := existing == nil && !noLoadBalancer && cfg.Type != config.ServiceTypeWorker
+			if err = quota.check(svc, serviceQuotas(svc, loadBalancer, desiredCount(count, cfg))); err != nil {
+				return err
+			}
 		}
 
 		log.Printf("Generating task definition from %v", composeFiles)
@@ -76,47 +192,103 @@ func ConfigureCreateService(app *kingpin.Application, svc api.Services) {
 			ProjectName:  projectName,
 		}
 
-		taskDefinitionInput, err := t.Transform()
+		taskDefinitionInput, containerFields, err := t.Transform()
 		if err != nil {
 			return err
 		}
 
-		clusterOutput, err := api.StackOutputs(svc.Cloudformation, *clusterStack.StackName)
-		if err != nil {
+		if err = resources.apply(taskDefinitionInput.ContainerDefinitions); err != nil {
 			return err
 		}
+		if instanceType, ok := api.GetStackParameterByKey(clusterStack, "InstanceType"); ok {
+			if err = checkContainerResources(taskDefinitionInput.ContainerDefinitions, instanceType); err != nil {
+				return err
+			}
+		}
 
-		if logGroup, exists := clusterOutput["LogGroupName"]; exists {
-			log.Printf("Setting tasks to use log group %s", logGroup)
+		var clusterOutput map[string]string
+		if render.enabled() {
+			clusterOutput = renderedOutputs(*clusterStack.StackName, templates.EcsStack())
+		} else if clusterOutput, err = api.StackOutputs(svc.Cloudformation, *clusterStack.StackName); err != nil {
+			return err
+		}
+		if logKmsKeyArn == "" {
+			logKmsKeyArn = clusterOutput["KmsKeyArn"]
+		}
 
-			for _, def := range taskDefinitionInput.ContainerDefinitions {
-				if def.LogConfiguration == nil {
-					def.LogConfiguration = &ecs.LogConfiguration{
-						LogDriver: aws.String("awslogs"),
-						Options: map[string]*string{
-							"awslogs-group":         aws.String(logGroup),
-							"awslogs-region":        aws.String(os.Getenv("AWS_REGION")),
-							"awslogs-stream-prefix": aws.String(projectName),
-						},
-					}
+		stackName := serviceStackName(cluster, *taskDefinitionInput.Family)
+		if existing != nil {
+			stackName = *existing.StackName
+		}
+
+		// the service stack creates a log group named after it before the service starts tasks
+		log.Printf("Setting tasks to use log group %s", stackName)
+
+		for _, def := range taskDefinitionInput.ContainerDefinitions {
+			if def.LogConfiguration == nil {
+				def.LogConfiguration = &ecs.LogConfiguration{
+					LogDriver: aws.String("awslogs"),
+					Options: map[string]*string{
+						"awslogs-group":         aws.String(stackName),
+						"awslogs-region":        aws.String(resolveRegion(cfg)),
+						"awslogs-stream-prefix": aws.String(projectName),
+					},
 				}
 			}
 		}
 
-		log.Printf("Registering a task for %s", projectName)
-		resp, err := svc.ECS.RegisterTaskDefinition(taskDefinitionInput)
-		if err != nil {
-			return err
+		var namespaceArn, portName string
+		var portNames api.PortNames
+		var connectPort int64
+		if serviceConnect {
+			if namespace == "" {
+				namespace = cluster
+			}
+			if namespaceArn, err = ensureNamespace(svc, cluster, namespace, render); err != nil {
+				return err
+			}
+
+			if container, port, ok := serviceConnectPort(taskDefinitionInput.ContainerDefinitions); ok {
+				portName, connectPort = api.PortName(container, port), port
+				portNames = api.PortNames{container: {port: portName}}
+				log.Printf("Connecting %s:%d to namespace %s as http://%s:%d", container, port, namespace, *taskDefinitionInput.Family, port)
+			} else {
+				log.Printf("No container maps a port, connecting to namespace %s as a client only", namespace)
+			}
+		} else if namespace != "" {
+			return fmt.Errorf("--namespace is only used with --service-connect")
 		}
-		log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
 
-		network, err := api.FindNetworkStack(svc.Cloudformation, cluster)
-		if err != nil {
-			return err
+		var resp *ecs.RegisterTaskDefinitionOutput
+		if render.enabled() {
+			if err = render.taskDefinition(taskDefinitionInput, portNames, containerFields); err != nil {
+				return err
+			}
+			resp = &ecs.RegisterTaskDefinitionOutput{TaskDefinition: &ecs.TaskDefinition{
+				Family:               taskDefinitionInput.Family,
+				TaskDefinitionArn:    aws.String(renderedValue("TaskDefinitionArn", *taskDefinitionInput.Family)),
+				ContainerDefinitions: taskDefinitionInput.ContainerDefinitions,
+			}}
+		} else {
+			log.Printf("Registering a task for %s", projectName)
+			resp, err = registerTaskDefinition(svc, taskDefinitionInput, portNames, containerFields)
+			if err != nil {
+				return err
+			}
+			log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
 		}
-		log.Printf("Found network stack %s", network.StackName)
 
-		ctx := api.CreateStackContext{
+		var network api.NetworkOutputs
+		if render.enabled() {
+			network = renderedNetwork(cluster)
+		} else {
+			if network, err = api.FindNetworkStack(svc.Cloudformation, cluster); err != nil {
+				return err
+			}
+			log.Printf("Found network stack %s", network.StackName)
+		}
+
+		ctx := api.StackOptions{
 			Params: map[string]string{
 				"VpcId":              network.VpcId,
 				"VpcPublicSubnet1Id": network.Subnet0Public,
@@ -126,55 +298,228 @@ func ConfigureCreateService(app *kingpin.Application, svc api.Services) {
 				"TaskFamily":         *resp.TaskDefinition.Family,
 				"TaskDefinition":     *resp.TaskDefinition.TaskDefinitionArn,
 				"SSLCertificateId":   certificateID,
+				"MetricsPort":        metricsPort,
+				"MetricsPath":        metricsPath,
+				"DesiredCount":       strconv.Itoa(desiredCount(count, cfg)),
+				"LogRetentionDays":   strconv.Itoa(logRetentionDays),
+				"LogKmsKeyArn":       logKmsKeyArn,
+				"AccessLogsBucket":   clusterOutput["AccessLogsBucket"],
 			},
-			DisableRollback: disableRollback,
+			DisableRollback:  disableRollback,
+			Attach:           true,
+			AllowReplacement: allowReplacement,
 		}
 
-		exposedPorts := api.ExposedPorts(resp.TaskDefinition)
+		ctx.Params["ServiceConnectNamespace"] = namespaceArn
+		ctx.Params["ServiceConnectPortName"] = portName
+		if portName != "" {
+			ctx.Params["ServiceConnectPort"] = strconv.FormatInt(connectPort, 10)
+		}
 
-		if len(exposedPorts) != 1 {
-			return fmt.Errorf("Task definition without exactly 1 host mapped port are not yet supported")
+		ctx.Params["PlacementConstraint"] = placementExpression(constraints)
+		if len(constraints) > 0 {
+			log.Printf("Placing tasks on instances matching %s", ctx.Params["PlacementConstraint"])
 		}
 
-		// for now this is a single value
-		for container, mappings := range exposedPorts {
-			for _, mapping := range mappings {
-				ctx.Params["ContainerName"] = container
-				ctx.Params["ContainerPort"] = strconv.FormatInt(*mapping.ContainerPort, 10)
-				ctx.Params["HealthCheckUrl"] = healthCheck
-				ctx.Params["ELBPort"] = strconv.FormatInt(*mapping.HostPort, 10)
+		// services follow the cluster's downtime, so their tasks aren't left pending
+		downtime, _ := api.GetStackParameterByKey(clusterStack, "DowntimeSchedule")
+		uptime, _ := api.GetStackParameterByKey(clusterStack, "UptimeSchedule")
+		if downtime != "" {
+			log.Printf("Scaling the service to zero on %s and back up on %s", downtime, uptime)
+		}
+		ctx.Params["DowntimeSchedule"] = downtime
+		ctx.Params["UptimeSchedule"] = uptime
+
+		var rules []templates.IngressRule
+
+		if noLoadBalancer || cfg.Type == config.ServiceTypeWorker {
+			if certificateID != "" || ingress.restricted() || waf.isSet() {
+				return fmt.Errorf("Worker services don't have a load balancer to configure certificates, ingress or WAF for")
+			}
+			log.Printf("Creating a worker service without a load balancer")
+			ctx.Params["LoadBalancer"] = "false"
+		} else {
+			exposedPorts := api.ExposedPorts(resp.TaskDefinition)
+
+			if len(exposedPorts) != 1 {
+				return fmt.Errorf("Task definition without exactly 1 host mapped port are not yet supported, use --no-load-balancer for workers")
+			}
+
+			// for now this is a single value
+			for container, mappings := range exposedPorts {
+				for _, mapping := range mappings {
+					ctx.Params["ContainerName"] = container
+					ctx.Params["ContainerPort"] = strconv.FormatInt(*mapping.ContainerPort, 10)
+					ctx.Params["HealthCheckUrl"] = healthCheck
+					ctx.Params["ELBPort"] = strconv.FormatInt(*mapping.HostPort, 10)
+				}
+			}
+
+			elbPorts := []string{ctx.Params["ELBPort"]}
+			if certificateID != "" {
+				elbPorts = append(elbPorts, "443")
+			}
+			rules, err = ingress.rules("0.0.0.0/0", elbPorts...)
+			if err != nil {
+				return err
+			}
+			for _, rule := range rules {
+				log.Printf("Allowing ingress %s", rule)
+			}
+
+			wafParams, err := waf.params(resolveRegion(cfg), ctx.Params["ELBPort"])
+			if err != nil {
+				return err
+			}
+			if waf.isSet() {
+				log.Printf("Serving the load balancer behind WAF from CloudFront")
+			}
+			for k, v := range wafParams {
+				ctx.Params[k] = v
 			}
 		}
+		ctx.Params["RestrictIngress"] = strconv.FormatBool(ingress.restricted())
+		ctx.Params["AssetsBucket"] = strconv.FormatBool(assetsBucket || assetsCDN)
+		ctx.Params["AssetsCDN"] = strconv.FormatBool(assetsCDN)
+
+		policies := []string{}
+		for _, name := range databases {
+			log.Printf("Giving tasks access to database %s", name)
+			if render.enabled() {
+				policies = append(policies, renderedValue("AccessPolicyArn", dbStackName(cluster, name)))
+				continue
+			}
+			dbOutputs, err := api.StackOutputs(svc.Cloudformation, dbStackName(cluster, name))
+			if err != nil {
+				return fmt.Errorf("No database %q exists for %q. Use `create-db`", name, cluster)
+			}
+			policies = append(policies, dbOutputs["AccessPolicyArn"])
+		}
+		ctx.Params["Databases"] = strings.Join(databases, ",")
+		ctx.Params["TaskPolicyArns"] = strings.Join(policies, ",")
 
-		timer := time.Now()
-		stackName := serviceStackName(cluster, *resp.TaskDefinition.Family)
+		for _, name := range caches {
+			if render.enabled() {
+				continue
+			}
+			if _, err := api.StackOutputs(svc.Cloudformation, cacheStackName(cluster, name)); err != nil {
+				return fmt.Errorf("No cache %q exists for %q. Use `create-cache`", name, cluster)
+			}
+		}
+		ctx.Params["Caches"] = strings.Join(caches, ",")
 
-		log.Printf("Creating service cloudformation stack %s", stackName)
+		template := templates.WithIngress(templates.EcsService(), "ELBSecurityGroup", rules)
 
-		err = api.CreateStack(svc.Cloudformation, stackName, templates.EcsService(), ctx)
-		if err != nil {
-			return err
+		for _, name := range cfg.Resources.Queues {
+			log.Printf("Creating queue %s", name)
+		}
+		for _, name := range cfg.Resources.Topics {
+			log.Printf("Creating topic %s", name)
 		}
+		ctx.Params["Queues"] = strings.Join(cfg.Resources.Queues, ",")
+		ctx.Params["Topics"] = strings.Join(cfg.Resources.Topics, ",")
+		template = templates.WithMessaging(template, cfg.Resources.Queues, cfg.Resources.Topics)
 
-		err = api.PollUntilCreated(svc.Cloudformation, stackName, func(event *cloudformation.StackEvent) {
-			log.Printf("%s\n", api.FormatStackEvent(event))
-		})
-		if err != nil {
-			return err
+		if pagerDutyKey != "" || opsgenieKey != "" {
+			alarms = "default"
 		}
 
-		stackOutputs, err := api.StackOutputs(svc.Cloudformation, stackName)
-		if err != nil {
-			return err
+		if alarms == "default" {
+			opts := templates.AlarmOptions{}
+			if ctx.Params["LoadBalancer"] != "false" {
+				opts.LoadBalancer = "HTTPLoadBalancer"
+				if certificateID != "" {
+					opts.LoadBalancer = "HTTPSLoadBalancer"
+				}
+			}
+			if cfg.Notifications.SNS != nil {
+				opts.TopicArn = cfg.Notifications.SNS.TopicArn
+			}
+			for _, i := range []struct{ Name, Endpoint string }{
+				{"PagerDuty", pagerDutyEndpoint(pagerDutyKey)},
+				{"Opsgenie", opsgenieEndpoint(opsgenieKey)},
+			} {
+				if i.Endpoint == "" {
+					continue
+				}
+				integration := templates.AlertIntegration{Name: i.Name}
+				log.Printf("Routing alarms to %s", i.Name)
+				opts.Integrations = append(opts.Integrations, integration)
+				ctx.Params[integration.Parameter()] = i.Endpoint
+			}
+			log.Printf("Creating alarms and a dashboard for the service")
+			template = templates.WithAlarms(template, opts)
 		}
 
-		var printer = func(e *ecs.ServiceEvent) {
-			log.Println(*e.Message)
+		if render.enabled() {
+			return render.stack(stackName, template, ctx)
 		}
 
-		log.Printf("Waiting for service to reach a steady state.")
-		err = api.PollUntilTaskDeployed(svc.ECS, cluster, stackOutputs["ECSService"], *resp.TaskDefinition.TaskDefinitionArn, printer)
-		if err != nil {
+		timer := time.Now()
+
+		// the stack update can change the service and its task definition, so it's locked
+		// against deploys and scaling of the service like they are against each other
+		var upToDate bool
+		var stackOutputs map[string]string
+		err = withServiceLock(commandContext(), svc, cluster, *taskDefinitionInput.Family, false, func(lockCtx context.Context) error {
+			var err error
+			if !creating {
+				log.Printf("Updating service cloudformation stack %s", stackName)
+				err = api.UpdateStack(svc.Cloudformation, stackName, template, ctx)
+			} else {
+				log.Printf("Creating service cloudformation stack %s", stackName)
+				err = api.CreateStack(svc.Cloudformation, stackName, template, ctx)
+			}
+			if err == api.ErrNoStackUpdates {
+				log.Printf("Service %s is already up to date", projectName)
+				upToDate = true
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = waitForStack(svc, stackName)
+			if err != nil {
+				return err
+			}
+
+			stackOutputs, err = api.StackOutputs(svc.Cloudformation, stackName)
+			if err != nil {
+				return err
+			}
+
+			resources, err := findServiceResources(svc, cluster, stackOutputs)
+			if err != nil {
+				return err
+			}
+			if err = lockCtx.Err(); err != nil {
+				return err
+			}
+			if resources.apply(taskDefinitionInput) {
+				log.Printf("Registering a task with the resources of %s", stackName)
+				resp, err = registerTaskDefinition(svc, taskDefinitionInput, portNames, containerFields)
+				if err != nil {
+					return err
+				}
+				log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+
+				_, err = svc.ECS.UpdateService(&ecs.UpdateServiceInput{
+					Service:        aws.String(stackOutputs["ECSService"]),
+					Cluster:        aws.String(cluster),
+					TaskDefinition: resp.TaskDefinition.TaskDefinitionArn,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			printer := serviceEventPrinter(cluster, stackOutputs["ECSService"])
+
+			log.Printf("Waiting for service to reach a steady state.")
+			return api.PollUntilTaskDeployed(lockCtx, svc.ECS, cluster, stackOutputs["ECSService"], *resp.TaskDefinition.TaskDefinitionArn, printer)
+		})
+		if err != nil || upToDate {
 			return err
 		}
 
@@ -183,8 +528,26 @@ func ConfigureCreateService(app *kingpin.Application, svc api.Services) {
 		// 	ui.Fatal(err)
 		// }
 
+		if !creating {
+			log.Printf("Service updated in %s", time.Now().Sub(timer).String())
+			return nil
+		}
+
+		notifiersFromConfig(svc, cfg).Notify(notify.Event{
+			Type:           notify.ServiceCreated,
+			Cluster:        cluster,
+			Service:        projectName,
+			TaskDefinition: *resp.TaskDefinition.TaskDefinitionArn,
+			Duration:       time.Now().Sub(timer),
+		})
+
 		log.Printf("Service created in %s", time.Now().Sub(timer).String())
-		log.Printf("Service available at %s", stackOutputs["ECSLoadBalancer"])
+		if url, ok := stackOutputs["ECSLoadBalancer"]; ok {
+			log.Printf("Service available at %s", url)
+		}
+		if url, ok := stackOutputs["WAFURL"]; ok {
+			log.Printf("Service available behind WAF at %s", url)
+		}
 		return nil
 	})
 }
@@ -196,3 +559,46 @@ func currentDirName() string {
 	}
 	return filepath.Base(cwd)
 }
+
+func desiredCount(count int, cfg *config.Config) int {
+	if count > 0 {
+		return count
+	}
+	if cfg.Count > 0 {
+		return cfg.Count
+	}
+	return 1
+}
+
+// pagerDutyEndpoint is where PagerDuty's CloudWatch integration receives sns notifications
+func pagerDutyEndpoint(key string) string {
+	if key == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://events.pagerduty.com/integration/%s/enqueue", url.PathEscape(key))
+}
+
+// opsgenieEndpoint is where Opsgenie's CloudWatch integration receives sns notifications
+func opsgenieEndpoint(key string) string {
+	if key == "" {
+		return ""
+	}
+	return "https://api.opsgenie.com/v1/json/cloudwatch?apiKey=" + url.QueryEscape(key)
+}
+
+var constraintOperator = regexp.MustCompile(`\s*(==|!=)\s*`)
+
+// placementExpression joins placement constraints into a single cluster query language
+// expression that instances must match all of
+func placementExpression(constraints []string) string {
+	exprs := []string{}
+	for _, c := range constraints {
+		exprs = append(exprs, constraintOperator.ReplaceAllString(strings.TrimSpace(c), " $1 "))
+	}
+	if len(exprs) > 1 {
+		for idx := range exprs {
+			exprs[idx] = "(" + exprs[idx] + ")"
+		}
+	}
+	return strings.Join(exprs, " and ")
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import "testing"
+
+func TestPlacementExpression(t *testing.T) {
+	for _, tc := range []struct {
+		constraints []string
+		expr        string
+	}{
+		{nil, ""},
+		{[]string{"attribute:tier==db"}, "attribute:tier == db"},
+		{[]string{"  attribute:ecs.instance-type  !=  t2.micro "}, "attribute:ecs.instance-type != t2.micro"},
+		{[]string{"attribute:tier == db", "attribute:ecs.availability-zone in [us-east-1a, us-east-1b]"},
+			"(attribute:tier == db) and (attribute:ecs.availability-zone in [us-east-1a, us-east-1b])"},
+	} {
+		if expr := placementExpression(tc.constraints); expr != tc.expr {
+			t.Errorf("Expected %q to be %q, got %q", tc.constraints, tc.expr, expr)
+		}
+	}
+}
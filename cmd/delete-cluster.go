@@ -18,6 +18,10 @@ func ConfigureDeleteCluster(app *kingpin.Application, svc api.Services) {
 		StringVar(&cluster)
 
 	cmd.Action(func(c *kingpin.ParseContext) error {
+		if err := checkUnprotected(svc, cluster); err != nil {
+			return err
+		}
+
 		fmt.Printf("Deleting cluster %s", cluster)
 		timer := time.Now()
 
@@ -32,9 +36,11 @@ func ConfigureDeleteCluster(app *kingpin.Application, svc api.Services) {
 				return err
 			}
 
-			err = api.PollUntilDeleted(svc.Cloudformation, *stack.StackName, func(event *cloudformation.StackEvent) {
+			err = api.PollUntilDeleted(commandContext(), svc.Cloudformation, *stack.StackName, func(event *cloudformation.StackEvent) {
 				fmt.Printf("%s\n", api.FormatStackEvent(event))
+				emitStackEvent(event)
 			})
+			emitStackResult(*stack.StackName, err)
 
 			fmt.Printf("Deleted stack %s\n", *stack.StackName)
 		}
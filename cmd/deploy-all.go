@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/failure"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/yaml.v2"
+)
+
+const healthCheckTimeout = 5 * time.Minute
+
+type allDeployOptions struct {
+	Cluster     string
+	ConfigFile  string
+	Env         string
+	Manifest    string
+	Region      string
+	TagStrategy string
+	ImageChecks imageCheckFlags
+	Template    config.TemplateData
+	PlanOnly    bool
+	Parallel    int
+	// Approval is required before the plan is applied
+	Approval bool
+}
+
+// servicePlan is a deploy of one of the services in config, and what it would change
+type servicePlan struct {
+	Name    string
+	Service config.Service
+	Options deployOptions
+	Changes []string
+}
+
+// deployAll plans deploys of the services in config and then applies the ones that change
+// the service, several at a time. Each service only starts once the services it depends on
+// have reached a steady state and passed their health checks.
+func deployAll(svc api.Services, cfg *config.Config, notifiers notify.Notifiers, forceUnlock bool, opts allDeployOptions) error {
+	order, err := cfg.DeployOrder()
+	if err != nil {
+		return err
+	}
+	if len(order) == 0 {
+		return fmt.Errorf("No services are defined in %s", opts.ConfigFile)
+	}
+
+	images, err := loadManifest(opts.Manifest)
+	if err != nil {
+		return err
+	}
+	for name := range images {
+		if _, ok := cfg.Services[name]; !ok {
+			return fmt.Errorf("Manifest has images for unknown service %q", name)
+		}
+	}
+
+	plans, err := planServices(svc, cfg, order, images, opts)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	log.Printf("Plan for %d services:", len(plans))
+	for _, p := range plans {
+		if len(p.Changes) == 0 {
+			log.Printf("  %s: no changes", p.Name)
+			continue
+		}
+		pending++
+		log.Printf("  %s: %d changes", p.Name, len(p.Changes))
+		for _, change := range p.Changes {
+			log.Printf("    %s", change)
+		}
+	}
+	if opts.PlanOnly {
+		var b strings.Builder
+		for _, p := range plans {
+			b.WriteString(changesMarkdown(fmt.Sprintf("Deploying `%s` to `%s`", p.Name, opts.Cluster), p.Changes))
+		}
+		annotateCI("ecsy-plan-"+opts.Cluster, notify.StyleInfo, b.String())
+	}
+	if opts.PlanOnly || pending == 0 {
+		return nil
+	}
+	if opts.Approval {
+		if err = approve(fmt.Sprintf("deploying %d services", pending)); err != nil {
+			return err
+		}
+	}
+
+	return applyPlans(svc, notifiers, forceUnlock, plans, pending, opts.Parallel)
+}
+
+func planServices(svc api.Services, cfg *config.Config, order []string, images map[string]map[string]string, opts allDeployOptions) ([]servicePlan, error) {
+	plans := []servicePlan{}
+	resolver := api.NewParameterResolver(svc.SSM)
+	for _, name := range order {
+		service := cfg.Services[name]
+		dir := serviceDir(opts.ConfigFile, name, service)
+
+		serviceCfg, err := loadServiceConfig(dir, opts.Env, opts.Template)
+		if err == nil {
+			err = serviceCfg.ResolveValues(resolver.Resolve)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load config for service %s: %v", name, err)
+		}
+
+		p := servicePlan{Name: name, Service: service, Options: deployOptions{
+			Cluster:      opts.Cluster,
+			ProjectName:  name,
+			ComposeFiles: []string{filepath.Join(dir, "docker-compose.yml")},
+			Images:       images[name],
+			Region:       opts.Region,
+			TagStrategy:  opts.TagStrategy,
+			ImageChecks:  opts.ImageChecks,
+			Config:       serviceCfg,
+		}}
+		if p.Options.Images == nil {
+			p.Options.Images = map[string]string{}
+		}
+
+		if p.Options.Prepared, err = prepareDeploy(svc, p.Options); err != nil {
+			return nil, fmt.Errorf("Failed to plan service %s: %v", name, err)
+		}
+		p.Changes = p.Options.Prepared.Changes(0)
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+// applyPlans deploys the plans that have changes with at most parallel running at once,
+// starting each when the services it depends on are done
+func applyPlans(svc api.Services, notifiers notify.Notifiers, forceUnlock bool, plans []servicePlan, pending, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	done := map[string]chan struct{}{}
+	for _, p := range plans {
+		done[p.Name] = make(chan struct{})
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := map[string]error{}
+	finished := 0
+	slots := make(chan struct{}, parallel)
+	timer := time.Now()
+
+	for _, p := range plans {
+		wg.Add(1)
+		go func(p servicePlan) {
+			defer wg.Done()
+			defer close(done[p.Name])
+
+			for _, dep := range p.Service.DependsOn {
+				<-done[dep]
+			}
+
+			mu.Lock()
+			for _, dep := range p.Service.DependsOn {
+				if _, ok := failed[dep]; ok {
+					failed[p.Name] = fmt.Errorf("Skipped, dependency %s failed", dep)
+				}
+			}
+			skip := failed[p.Name] != nil
+			mu.Unlock()
+
+			if skip || len(p.Changes) == 0 {
+				return
+			}
+
+			slots <- struct{}{}
+			err := applyPlan(svc, notifiers, forceUnlock, p)
+			<-slots
+
+			mu.Lock()
+			defer mu.Unlock()
+			finished++
+			if err != nil {
+				failed[p.Name] = err
+				log.Printf("[%d/%d] Failed to deploy %s: %v", finished, pending, p.Name, err)
+			} else {
+				log.Printf("[%d/%d] Deployed %s", finished, pending, p.Name)
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		names := []string{}
+		for _, p := range plans {
+			if err, ok := failed[p.Name]; ok {
+				names = append(names, p.Name)
+				log.Printf("%s: %v", p.Name, err)
+			}
+		}
+		return fmt.Errorf("Failed to deploy %d services: %s", len(names), strings.Join(names, ", "))
+	}
+
+	log.Printf("Deployed %d services in %s", pending, time.Now().Sub(timer).String())
+	return nil
+}
+
+func applyPlan(svc api.Services, notifiers notify.Notifiers, forceUnlock bool, p servicePlan) error {
+	if _, err := deployWithNotifications(svc, notifiers, forceUnlock, p.Options); err != nil {
+		return err
+	}
+	if p.Service.HealthURL != "" {
+		if err := waitForHealthy(p.Service.HealthURL, healthCheckTimeout); err != nil {
+			return fmt.Errorf("Service isn't healthy: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadManifest reads a yaml file of service names to the container images to deploy to
+// them, in the same form as the imagetags argument to deploy
+func loadManifest(path string) (map[string]map[string]string, error) {
+	images := map[string]map[string]string{}
+	if path == "" {
+		return images, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(b, &images); err != nil {
+		return nil, fmt.Errorf("Failed to parse manifest %s: %v", path, err)
+	}
+	return images, nil
+}
+
+// serviceDir is the directory with a service's compose file and config, relative to the
+// config that defines the service
+func serviceDir(configFile, name string, service config.Service) string {
+	path := service.Path
+	if path == "" {
+		path = name
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configFile), path)
+}
+
+func loadServiceConfig(dir, env string, data config.TemplateData) (*config.Config, error) {
+	cfg, err := config.LoadTemplate(filepath.Join(dir, config.DefaultFile), data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cfg.Environments[env]; !ok {
+		return cfg, nil
+	}
+	return cfg.ForEnvironment(env)
+}
+
+// waitForHealthy polls a url until it responds with a 2xx status
+func waitForHealthy(url string, timeout time.Duration) error {
+	log.Printf("Waiting for %s to be healthy", url)
+	client := &http.Client{Timeout: 10 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				log.Printf("%s is healthy", url)
+				return nil
+			}
+			err = fmt.Errorf("%s responded with %s", url, resp.Status)
+		}
+		if time.Now().After(deadline) {
+			return failure.Wrap(failure.DeployHealth, err)
+		}
+		if sleepErr := api.Sleep(commandContext(), 5*time.Second, url, err.Error()); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
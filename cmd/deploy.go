@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -11,16 +14,28 @@ import (
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/lox/ecsy/api"
 	"github.com/lox/ecsy/compose"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/notify"
+	"github.com/lox/ecsy/templates"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 func ConfigureDeploy(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
 	var cluster, projectName, imageTags string
 	var composeFiles []string
+	var github githubDeploymentFlags
+	var forceUnlock, all, planOnly bool
+	var manifest, tagStrategy, taskDefinition string
+	var parallel, count int
+	var imageChecks imageCheckFlags
+	var resources containerResourceFlags
+	var rollback rollbackFlags
+	var render renderFlags
+	var approval approvalFlags
 
 	cmd := app.Command("deploy", "Deploy updated task definitions to ECS")
 	cmd.Flag("cluster", "The ECS cluster to deploy to").
-		Required().
 		StringVar(&cluster)
 
 	cmd.Flag("project-name", "The name of the project").
@@ -31,7 +46,42 @@ func ConfigureDeploy(app *kingpin.Application, svc api.Services) {
 	cmd.Flag("file", "The docker-compose file to use").
 		Short('f').
 		Default("docker-compose.yml").
-		ExistingFilesVar(&composeFiles)
+		StringsVar(&composeFiles)
+
+	cmd.Flag("task-definition", "A task definition family:revision or arn registered outside of ecsy, such as with `register-taskdef`, or a json file to register, to deploy instead of compose files").
+		StringVar(&taskDefinition)
+
+	cmd.Flag("count", "The number of tasks to scale the service to, otherwise it keeps its current count").
+		IntVar(&count)
+
+	env.configure(cmd)
+
+	cmd.Flag("force-unlock", "Remove an existing deploy lock on the service before deploying").
+		BoolVar(&forceUnlock)
+
+	cmd.Flag("image-tag-strategy", "Resolve the tags of images from the git sha or the latest version, and pin them to digests").
+		EnumVar(&tagStrategy, tagStrategies...)
+
+	imageChecks.configure(cmd)
+	resources.configure(cmd)
+	rollback.configure(cmd)
+	render.configure(cmd)
+	approval.configure(cmd)
+
+	cmd.Flag("all", "Deploy each of the services in config, after the services they depend on").
+		BoolVar(&all)
+
+	cmd.Flag("manifest", "With --all, a yaml file of services to the images to deploy to them").
+		ExistingFileVar(&manifest)
+
+	cmd.Flag("plan", "With --all, only show which services would change").
+		BoolVar(&planOnly)
+
+	cmd.Flag("parallel", "With --all, the maximum number of services to deploy at once").
+		Default("4").
+		IntVar(&parallel)
+
+	github.configure(cmd)
 
 	cmd.Arg("imagetags", "Tags in the form image=tag to apply to the task").
 		StringVar(&imageTags)
@@ -42,91 +92,554 @@ func ConfigureDeploy(app *kingpin.Application, svc api.Services) {
 			return err
 		}
 
-		log.Printf("Generating task definition from %#v", composeFiles)
-		t := compose.Transformer{
-			ComposeFiles: composeFiles,
-			ProjectName:  projectName,
-		}
-
-		taskDefinitionInput, err := t.Transform()
+		env.Offline = render.enabled()
+		cfg, svc, err := env.load(svc)
 		if err != nil {
 			return err
 		}
 
-		clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		cluster, err := resolveCluster(svc, cluster, cfg)
 		if err != nil {
 			return err
-		} else if clusterStack == nil {
-			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`",
-				cluster)
-		}
-
-		if logGroup, exists := api.GetStackOutputByKey(clusterStack, "LogGroupName"); exists {
-			log.Printf("Setting tasks to use log group %s", logGroup)
-
-			for _, def := range taskDefinitionInput.ContainerDefinitions {
-				if def.LogConfiguration == nil {
-					def.LogConfiguration = &ecs.LogConfiguration{
-						LogDriver: aws.String("awslogs"),
-						Options: map[string]*string{
-							"awslogs-group":         aws.String(logGroup),
-							"awslogs-region":        aws.String(os.Getenv("AWS_REGION")),
-							"awslogs-stream-prefix": aws.String(projectName),
-						},
-					}
+		}
+
+		notifiers := notifiersFromConfig(svc, cfg)
+		if github.Enabled && !render.enabled() {
+			deployment, err := github.deployment(cluster)
+			if err != nil {
+				return err
+			}
+			notifiers = append(notifiers, deployment)
+		}
+
+		if taskDefinition == "" {
+			for _, file := range composeFiles {
+				if _, err := os.Stat(file); err != nil {
+					return fmt.Errorf("path '%s' does not exist", file)
 				}
 			}
+		} else if len(images) > 0 || tagStrategy != "" || resources.isSet() {
+			return fmt.Errorf("Images, tags and container resources can't be set for a task definition registered outside of ecsy")
+		} else if len(cfg.Hooks.PreDeploy) > 0 || len(cfg.Hooks.PostDeploy) > 0 || hasSmokeTestTasks(cfg) {
+			return fmt.Errorf("Deploy hooks and smoke test tasks run compose services, so can't be used with a task definition registered outside of ecsy")
 		}
 
-		log.Printf("Updating task definition for task %s", *taskDefinitionInput.Family)
-		err = api.UpdateContainerImages(taskDefinitionInput.ContainerDefinitions, images)
-		if err != nil {
-			return err
+		if all {
+			if render.enabled() {
+				return fmt.Errorf("Deploys to all services can't be rendered, use --plan instead")
+			}
+			if taskDefinition != "" {
+				return fmt.Errorf("A task definition can't be deployed to all services")
+			}
+			if len(images) > 0 {
+				return fmt.Errorf("Images can't be set for all services, use --manifest instead")
+			}
+			if len(rollback.Alarms) > 0 {
+				return fmt.Errorf("Rollback alarms can't be set for all services")
+			}
+			if resources.isSet() {
+				return fmt.Errorf("Container resources can't be set for all services, set them in each service's compose file")
+			}
+			if count > 0 {
+				return fmt.Errorf("A count can't be set for all services, use `scale` instead")
+			}
+			return deployAll(svc, cfg, notifiers, forceUnlock, allDeployOptions{
+				Cluster:     cluster,
+				ConfigFile:  env.ConfigFile,
+				Env:         env.Env,
+				Template:    env.templateData(),
+				Manifest:    manifest,
+				Region:      resolveRegion(cfg),
+				TagStrategy: tagStrategy,
+				ImageChecks: imageChecks,
+				PlanOnly:    planOnly,
+				Parallel:    parallel,
+				Approval:    approval.required(cfg),
+			})
+		}
+
+		opts := deployOptions{
+			Cluster:        cluster,
+			ProjectName:    projectName,
+			ComposeFiles:   composeFiles,
+			TaskDefinition: taskDefinition,
+			Template:       env.templateData(),
+			Images:         images,
+			Region:         resolveRegion(cfg),
+			TagStrategy:    tagStrategy,
+			ImageChecks:    imageChecks,
+			Resources:      resources,
+			Rollback:       rollback,
+			Count:          count,
+			Config:         cfg,
+		}
+		if render.enabled() {
+			return renderDeploy(render, opts)
+		}
+
+		if approval.required(cfg) {
+			if opts.Prepared, err = prepareDeploy(svc, opts); err != nil {
+				return err
+			}
+			changes := opts.Prepared.Changes(count)
+			log.Printf("Deploying %s to %s would make %d changes", projectName, cluster, len(changes))
+			for _, change := range changes {
+				log.Printf("  %s", change)
+			}
+			if err = approve(fmt.Sprintf("deploying %s", projectName)); err != nil {
+				return err
+			}
 		}
 
-		resp, err := svc.ECS.RegisterTaskDefinition(taskDefinitionInput)
+		_, err = deployWithNotifications(svc, notifiers, forceUnlock, opts)
+		return err
+	})
+}
+
+// deployWithNotifications deploys a service whilst holding its lock, notifying about
+// the start and the outcome of the deploy
+func deployWithNotifications(svc api.Services, notifiers notify.Notifiers, forceUnlock bool, opts deployOptions) (*deployResult, error) {
+	notifiers.Notify(notify.Event{
+		Type:    notify.DeployStarted,
+		Cluster: opts.Cluster,
+		Service: opts.ProjectName,
+		Images:  opts.Images,
+	})
+
+	timer := time.Now()
+	var result *deployResult
+	err := withServiceLock(commandContext(), svc, opts.Cluster, opts.ProjectName, forceUnlock, func(ctx context.Context) (err error) {
+		result, err = deployService(ctx, svc, opts)
+		return err
+	})
+	if err != nil {
+		event := notify.Event{
+			Type:     notify.DeployFailed,
+			Cluster:  opts.Cluster,
+			Service:  opts.ProjectName,
+			Images:   opts.Images,
+			Duration: time.Now().Sub(timer),
+			Error:    err.Error(),
+		}
+		var rolledBack *errRolledBack
+		if errors.As(err, &rolledBack) {
+			event.Type = notify.DeployRolledBack
+			event.TaskDefinition = rolledBack.Previous
+		}
+		notifiers.Notify(event)
+		return nil, err
+	}
+
+	notifiers.Notify(notify.Event{
+		Type:           notify.DeploySucceeded,
+		Cluster:        opts.Cluster,
+		Service:        opts.ProjectName,
+		Images:         result.Images,
+		TaskDefinition: result.TaskDefinitionArn,
+		URL:            result.URL,
+		Duration:       time.Now().Sub(timer),
+	})
+
+	auditResource(result.TaskDefinitionArn)
+	log.Printf("Deployed %s in %s", opts.ProjectName, time.Now().Sub(timer).String())
+	return result, nil
+}
+
+type deployOptions struct {
+	Cluster      string
+	ProjectName  string
+	ComposeFiles []string
+	// TaskDefinition is registered outside of ecsy, or a json file to register, and is
+	// deployed instead of compose files
+	TaskDefinition string
+	Template       config.TemplateData
+	Images         map[string]string
+	Region         string
+	TagStrategy    string
+	ImageChecks    imageCheckFlags
+	Resources      containerResourceFlags
+	Rollback       rollbackFlags
+	Config         *config.Config
+	Prepared       *preparedDeploy
+	// Count is the number of tasks to scale the service to, the service keeps its current
+	// count when it's zero so that deploys don't undo scaling
+	Count int
+}
+
+type deployResult struct {
+	TaskDefinitionArn string
+	Images            map[string]string
+	URL               string
+}
+
+// preparedDeploy is the task definition a deploy would register and the service it would update
+type preparedDeploy struct {
+	Input     *ecs.RegisterTaskDefinitionInput
+	PortNames api.PortNames
+	// ContainerFields are the fields of containers that the vendored sdk predates
+	ContainerFields api.ContainerFields
+	// Registered is a task definition registered outside of ecsy, deployed as is
+	Registered *ecs.TaskDefinition
+	// RawInput is the json of a task definition file, registered as is
+	RawInput []byte
+	Outputs  map[string]string
+	Service  *ecs.Service
+	Current  *ecs.TaskDefinition
+}
+
+// Changes describes how the deploy would change the service, scaling it to count if that's
+// set, no changes means deploying is a no-op
+func (p *preparedDeploy) Changes(count int) []string {
+	var changes []string
+	if p.Current != nil {
+		changes = api.DiffTaskDefinitions(api.RegisterTaskDefinitionInput(p.Current), p.Input)
+	} else {
+		changes = append(changes, "task definition: new")
+	}
+	if count > 0 && p.Service != nil && aws.Int64Value(p.Service.DesiredCount) != int64(count) {
+		changes = append(changes, fmt.Sprintf("desired count: %d → %d", aws.Int64Value(p.Service.DesiredCount), count))
+	}
+	return changes
+}
+
+// prepareDeploy generates the task definition for a deploy from compose files, along with
+// the current state of the service it would be deployed to
+func prepareDeploy(svc api.Services, opts deployOptions) (*preparedDeploy, error) {
+	if opts.TaskDefinition != "" {
+		return prepareRegisteredDeploy(svc, opts)
+	}
+
+	log.Printf("Generating task definition from %#v", opts.ComposeFiles)
+	t := compose.Transformer{
+		ComposeFiles: opts.ComposeFiles,
+		ProjectName:  opts.ProjectName,
+	}
+
+	taskDefinitionInput, containerFields, err := t.Transform()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterStack, err := api.FindClusterStack(svc.Cloudformation, opts.Cluster)
+	if err != nil {
+		return nil, err
+	} else if clusterStack == nil {
+		return nil, fmt.Errorf("No cluster exists for %q. Use `create-cluster`",
+			opts.Cluster)
+	}
+
+	log.Printf("Updating task definition for task %s", *taskDefinitionInput.Family)
+	err = api.UpdateContainerImages(taskDefinitionInput.ContainerDefinitions, opts.Images)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TagStrategy != "" {
+		err = resolveImageTags(svc, taskDefinitionInput.ContainerDefinitions, opts.TagStrategy,
+			filepath.Dir(opts.ComposeFiles[0]), opts.Images)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+	}
+
+	if err = opts.Resources.apply(taskDefinitionInput.ContainerDefinitions); err != nil {
+		return nil, err
+	}
+
+	arch := "amd64"
+	if instanceType, ok := api.GetStackParameterByKey(clusterStack, "InstanceType"); ok {
+		arch = api.InstanceArchitecture(instanceType)
+		if err = checkContainerResources(taskDefinitionInput.ContainerDefinitions, instanceType); err != nil {
+			return nil, err
+		}
+	}
+	if err = checkImages(svc, taskDefinitionInput.ContainerDefinitions, arch, opts.ImageChecks); err != nil {
+		return nil, err
+	}
+	if err = checkImageScans(svc, taskDefinitionInput.ContainerDefinitions, opts.ImageChecks); err != nil {
+		return nil, err
+	}
+
+	serviceStack, err := api.FindServiceStack(svc.Cloudformation, opts.Cluster, opts.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Found service stack %s", *serviceStack.StackName)
+
+	outputs := api.StackOutputMap(serviceStack)
 
-		serviceStack, err := api.FindServiceStack(svc.Cloudformation, cluster, projectName)
+	// services created before they had their own log group log to the cluster's
+	logGroup, exists := outputs["LogGroupName"]
+	if !exists {
+		logGroup, exists = api.GetStackOutputByKey(clusterStack, "LogGroupName")
+	}
+	if exists {
+		useLogGroup(taskDefinitionInput.ContainerDefinitions, logGroup, opts.Region, opts.ProjectName)
+	}
+
+	resources, err := findServiceResources(svc, opts.Cluster, outputs)
+	if err != nil {
+		return nil, err
+	}
+	resources.apply(taskDefinitionInput)
+
+	p := &preparedDeploy{
+		Input:           taskDefinitionInput,
+		PortNames:       servicePortNames(serviceStack, taskDefinitionInput.ContainerDefinitions),
+		ContainerFields: containerFields,
+		Outputs:         outputs,
+	}
+	return p, p.describeService(svc)
+}
+
+// prepareRegisteredDeploy looks up a task definition registered outside of ecsy, or renders
+// one from a json file, along with the current state of the service it would be deployed to
+func prepareRegisteredDeploy(svc api.Services, opts deployOptions) (*preparedDeploy, error) {
+	serviceStack, err := api.FindServiceStack(svc.Cloudformation, opts.Cluster, opts.ProjectName)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Found service stack %s", *serviceStack.StackName)
+
+	p := &preparedDeploy{Outputs: api.StackOutputMap(serviceStack)}
+
+	var family string
+	if _, err := os.Stat(opts.TaskDefinition); err == nil {
+		data := opts.Template
+		data.Cluster = opts.Cluster
+		if data.Outputs, err = clusterAndServiceOutputs(svc, opts.Cluster, p.Outputs); err != nil {
+			return nil, err
+		}
+
+		log.Printf("Rendering task definition from %s", opts.TaskDefinition)
+		if p.RawInput, err = renderTaskDefinitionFile(opts.TaskDefinition, data); err != nil {
+			return nil, err
+		}
+		if p.Input, err = api.ParseRawTaskDefinition(p.RawInput); err != nil {
+			return nil, err
+		}
+		family = aws.StringValue(p.Input.Family)
+	} else {
+		resp, err := svc.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: aws.String(opts.TaskDefinition),
+		})
 		if err != nil {
-			return err
+			return nil, err
 		}
-		log.Printf("Found service stack %s", *serviceStack.StackName)
+		log.Printf("Found task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+
+		p.Input = api.RegisterTaskDefinitionInput(resp.TaskDefinition)
+		p.Registered = resp.TaskDefinition
+		family = *resp.TaskDefinition.Family
+	}
 
-		outputs := api.StackOutputMap(serviceStack)
-		timer := time.Now()
+	if current := p.Outputs["TaskFamily"]; current != "" && current != family {
+		log.Printf("Task definition family %s differs from the service's %s", family, current)
+	}
+	return p, p.describeService(svc)
+}
 
-		log.Printf("Updating service %s with new task definition", *serviceStack.StackName)
-		_, err = svc.ECS.UpdateService(&ecs.UpdateServiceInput{
-			Service:        aws.String(outputs["ECSService"]),
-			Cluster:        aws.String(outputs["ECSCluster"]),
-			TaskDefinition: aws.String(*resp.TaskDefinition.TaskDefinitionArn),
+// clusterAndServiceOutputs merges the outputs of a cluster stack with a service's, for
+// placeholders in task definition files
+func clusterAndServiceOutputs(svc api.Services, cluster string, serviceOutputs map[string]string) (map[string]string, error) {
+	clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+	if err != nil {
+		return nil, err
+	} else if clusterStack == nil {
+		return nil, fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+	}
+
+	outputs := api.StackOutputMap(clusterStack)
+	for k, v := range serviceOutputs {
+		outputs[k] = v
+	}
+	return outputs, nil
+}
+
+// describeService finds the service a deploy updates and the task definition it's running
+func (p *preparedDeploy) describeService(svc api.Services) (err error) {
+	outputs := p.Outputs
+	if p.Service, err = api.DescribeService(svc.ECS, outputs["ECSCluster"], outputs["ECSService"]); err != nil {
+		return err
+	}
+	if p.Service.TaskDefinition != nil {
+		resp, err := svc.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: p.Service.TaskDefinition,
 		})
 		if err != nil {
 			return err
 		}
+		p.Current = resp.TaskDefinition
+	}
+	return nil
+}
+
+// useLogGroup sets containers without a log configuration to log to a log group
+func useLogGroup(defs []*ecs.ContainerDefinition, logGroup, region, prefix string) {
+	log.Printf("Setting tasks to use log group %s", logGroup)
+
+	for _, def := range defs {
+		if def.LogConfiguration == nil {
+			def.LogConfiguration = &ecs.LogConfiguration{
+				LogDriver: aws.String("awslogs"),
+				Options: map[string]*string{
+					"awslogs-group":         aws.String(logGroup),
+					"awslogs-region":        aws.String(region),
+					"awslogs-stream-prefix": aws.String(prefix),
+				},
+			}
+		}
+	}
+}
+
+// renderDeploy renders the task definition a deploy would register, without registering it
+// or updating the service. Nothing is looked up, so the outputs of the cluster and service
+// stacks are placeholders, and what's only known from the deployed service, like the
+// environment of its databases and the name of its service connect port, isn't rendered.
+func renderDeploy(render renderFlags, opts deployOptions) error {
+	if opts.TagStrategy != "" {
+		return fmt.Errorf("Tag strategies look up images in their registry, so can't be rendered. Set image tags instead")
+	}
+	outputs := renderedOutputs(serviceStackName(opts.Cluster, opts.ProjectName), templates.EcsService())
 
-		var printer = func(e *ecs.ServiceEvent) {
-			log.Println(*e.Message)
+	if opts.TaskDefinition != "" {
+		if _, err := os.Stat(opts.TaskDefinition); err != nil {
+			return fmt.Errorf("Task definition %s is already registered, so there is nothing to render", opts.TaskDefinition)
+		}
+		data := opts.Template
+		data.Cluster = opts.Cluster
+		data.Outputs = renderedOutputs(clusterStackName(opts.Cluster), templates.EcsStack())
+		for k, v := range outputs {
+			data.Outputs[k] = v
 		}
 
-		log.Printf("Waiting for service to reach a steady state.")
-		err = api.PollUntilTaskDeployed(svc.ECS, outputs["ECSCluster"], outputs["ECSService"], *resp.TaskDefinition.TaskDefinitionArn, printer)
+		log.Printf("Rendering task definition from %s", opts.TaskDefinition)
+		b, err := renderTaskDefinitionFile(opts.TaskDefinition, data)
+		if err != nil {
+			return err
+		}
+		input, err := api.ParseRawTaskDefinition(b)
 		if err != nil {
 			return err
 		}
+		return render.rawTaskDefinition(aws.StringValue(input.Family), b)
+	}
+
+	log.Printf("Generating task definition from %#v", opts.ComposeFiles)
+	t := compose.Transformer{
+		ComposeFiles: opts.ComposeFiles,
+		ProjectName:  opts.ProjectName,
+	}
+	input, containerFields, err := t.Transform()
+	if err != nil {
+		return err
+	}
+	if err = api.UpdateContainerImages(input.ContainerDefinitions, opts.Images); err != nil {
+		return err
+	}
+	if err = opts.Resources.apply(input.ContainerDefinitions); err != nil {
+		return err
+	}
+	useLogGroup(input.ContainerDefinitions, outputs["LogGroupName"], opts.Region, opts.ProjectName)
+	serviceResources{TaskRoleArn: outputs["TaskRoleArn"]}.apply(input)
 
-		// ui.Printf("Waiting for service to stabilize")
-		// if err = apient.WaitUntilServicesStable(input.ClusterName, serviceOutputs["ECSService"]); err != nil {
-		// 	ui.Fatal(err)
-		// }
+	return render.taskDefinition(input, nil, containerFields)
+}
 
-		log.Printf("Deployed in %s", time.Now().Sub(timer).String())
-		return nil
-	})
+// deployService registers a new task definition from compose files and updates the
+// service to use it, running any hooks and waiting for the service to reach a steady state
+// until ctx is done
+func deployService(ctx context.Context, svc api.Services, opts deployOptions) (*deployResult, error) {
+	p := opts.Prepared
+	if p == nil {
+		var err error
+		if p, err = prepareDeploy(svc, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	hookTask := onceOffTask{
+		Cluster:      opts.Cluster,
+		ProjectName:  opts.ProjectName,
+		ComposeFiles: opts.ComposeFiles,
+		Images:       opts.Images,
+	}
+
+	alarms, err := newAlarmWatcher(svc, opts.Rollback.Alarms)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := runHooks(svc, "pre-deploy", opts.Config.Hooks.PreDeploy, hookTask); err != nil {
+		return nil, err
+	}
+
+	resp := &ecs.RegisterTaskDefinitionOutput{TaskDefinition: p.Registered}
+	if p.Registered == nil {
+		if p.RawInput != nil {
+			resp, err = svc.TaskDefinitions.RegisterRawTaskDefinition(p.RawInput)
+		} else {
+			resp, err = registerTaskDefinition(svc, p.Input, p.PortNames, p.ContainerFields)
+		}
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+	}
+
+	result := &deployResult{
+		TaskDefinitionArn: *resp.TaskDefinition.TaskDefinitionArn,
+		Images:            map[string]string{},
+	}
+	for _, def := range resp.TaskDefinition.ContainerDefinitions {
+		result.Images[*def.Name] = aws.StringValue(def.Image)
+	}
+
+	outputs := p.Outputs
+	result.URL = outputs["ECSLoadBalancer"]
+
+	log.Printf("Updating service %s with new task definition", outputs["ECSService"])
+	update := &ecs.UpdateServiceInput{
+		Service:        aws.String(outputs["ECSService"]),
+		Cluster:        aws.String(outputs["ECSCluster"]),
+		TaskDefinition: aws.String(*resp.TaskDefinition.TaskDefinitionArn),
+	}
+	if opts.Count > 0 {
+		log.Printf("Setting desired count to %d", opts.Count)
+		update.DesiredCount = aws.Int64(int64(opts.Count))
+	}
+
+	_, err = svc.ECS.UpdateService(update)
+	if err != nil {
+		return nil, err
+	}
+
+	printer := serviceEventPrinter(outputs["ECSCluster"], outputs["ECSService"])
+
+	log.Printf("Waiting for service to reach a steady state.")
+	err = api.PollUntilTaskDeployedOrStopped(ctx, svc.ECS, outputs["ECSCluster"], outputs["ECSService"], *resp.TaskDefinition.TaskDefinitionArn, printer, alarms.check)
+	if err == nil {
+		err = alarms.watch(ctx, opts.Rollback.Watch)
+	}
+	if err == nil {
+		err = runSmokeTests(svc, opts.Config.SmokeTests, result.URL, hookTask)
+	}
+	if shouldRollback(err) && p.Service.TaskDefinition != nil {
+		return nil, rollbackService(ctx, svc, outputs["ECSCluster"], opts.ProjectName, outputs["ECSService"], *p.Service.TaskDefinition, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	// ui.Printf("Waiting for service to stabilize")
+	// if err = apient.WaitUntilServicesStable(input.ClusterName, serviceOutputs["ECSService"]); err != nil {
+	// 	ui.Fatal(err)
+	// }
+
+	if err = runHooks(svc, "post-deploy", opts.Config.Hooks.PostDeploy, hookTask); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
 func parseImageMap(s string) (map[string]string, error) {
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+)
+
+func TestPreparedDeployChangesCount(t *testing.T) {
+	current := &ecs.TaskDefinition{
+		Family: aws.String("web"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{
+			{Name: aws.String("web"), Image: aws.String("web:v1")},
+		},
+	}
+	p := &preparedDeploy{
+		Input:   api.RegisterTaskDefinitionInput(current),
+		Service: &ecs.Service{DesiredCount: aws.Int64(4)},
+		Current: current,
+	}
+
+	for count, expected := range map[int][]string{
+		0: nil,
+		4: nil,
+		6: {"desired count: 4 → 6"},
+	} {
+		changes := p.Changes(count)
+		if len(changes) != len(expected) || (len(expected) > 0 && changes[0] != expected[0]) {
+			t.Errorf("Expected a count of %d to change %v, got %v", count, expected, changes)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/compose"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureDiff(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, projectName string
+	var composeFiles, images []string
+
+	cmd := app.Command("diff", "Show how a deploy would change the task definition a service is running")
+	cmd.Flag("cluster", "The ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service").
+		Short('p').
+		Default(currentDirName()).
+		StringVar(&projectName)
+
+	cmd.Flag("file", "The docker-compose file to use").
+		Short('f').
+		Default("docker-compose.yml").
+		ExistingFilesVar(&composeFiles)
+
+	cmd.Flag("image", "An image to deploy, either container=image or an image that replaces containers from the same repository").
+		StringsVar(&images)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		imageMap, err := containerImages(composeFiles, projectName, images)
+		if err != nil {
+			return err
+		}
+
+		p, err := prepareDeploy(svc, deployOptions{
+			Cluster:      cluster,
+			ProjectName:  projectName,
+			ComposeFiles: composeFiles,
+			Images:       imageMap,
+			Region:       resolveRegion(cfg),
+			ImageChecks:  imageCheckFlags{Skip: true},
+			Config:       cfg,
+		})
+		if err != nil {
+			return err
+		}
+
+		if p.Current != nil {
+			log.Printf("Comparing to %s:%d", aws.StringValue(p.Current.Family), aws.Int64Value(p.Current.Revision))
+		}
+
+		changes := p.Changes(0)
+		annotateCI("ecsy-diff-"+projectName, notify.StyleInfo, changesMarkdown(
+			fmt.Sprintf("Deploying `%s` to `%s`", projectName, cluster), changes))
+		if len(changes) == 0 {
+			log.Printf("No changes")
+			return nil
+		}
+		for _, change := range changes {
+			log.Printf("  %s", change)
+		}
+		log.Printf("%d changes", len(changes))
+		return nil
+	})
+}
+
+// containerImages maps images given as container=image or as an image to the containers
+// that they replace, matching bare images to containers using the same repository
+func containerImages(composeFiles []string, projectName string, images []string) (map[string]string, error) {
+	m := map[string]string{}
+	bare := []string{}
+	for _, image := range images {
+		if pieces := strings.SplitN(image, "=", 2); len(pieces) == 2 {
+			m[pieces[0]] = pieces[1]
+		} else {
+			bare = append(bare, image)
+		}
+	}
+	if len(bare) == 0 {
+		return m, nil
+	}
+
+	t := compose.Transformer{
+		ComposeFiles: composeFiles,
+		ProjectName:  projectName,
+	}
+	input, _, err := t.Transform()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range bare {
+		ref := api.ParseImageRef(image)
+		matched := false
+		for _, def := range input.ContainerDefinitions {
+			current := api.ParseImageRef(aws.StringValue(def.Image))
+			if current.Registry == ref.Registry && current.Repository == ref.Repository {
+				m[aws.StringValue(def.Name)] = image
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("No container uses an image from %s", ref.Repository)
+		}
+	}
+	return m, nil
+}
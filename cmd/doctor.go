@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/policy"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// endpointServices are the services whose regional endpoints ecsy needs to reach
+var endpointServices = []string{
+	"sts", "cloudformation", "ecs", "ec2", "logs", "monitoring", "elasticloadbalancing",
+	"autoscaling", "ssm", "api.ecr",
+}
+
+// doctor reports the outcome of each check, counting those that failed
+type doctor struct {
+	failed int
+}
+
+func (d *doctor) pass(check, format string, args ...interface{}) {
+	log.Printf("PASS  %-12s %s", check, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) warn(check, hint, format string, args ...interface{}) {
+	log.Printf("WARN  %-12s %s", check, fmt.Sprintf(format, args...))
+	if hint != "" {
+		log.Printf("      %-12s %s", "", hint)
+	}
+}
+
+func (d *doctor) fail(check, hint, format string, args ...interface{}) {
+	d.failed++
+	log.Printf("FAIL  %-12s %s", check, fmt.Sprintf(format, args...))
+	if hint != "" {
+		log.Printf("      %-12s %s", "", hint)
+	}
+}
+
+func ConfigureDoctor(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+
+	cmd := app.Command("doctor", "Check credentials, permissions, prerequisites, connectivity and stacks, with hints to fix what's wrong")
+	cmd.Flag("cluster", "Only check the stacks of a cluster, defaults to the config or every ecsy stack in the region").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		d := &doctor{}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			d.fail("config", "Check --config and --env, and the credentials of the environment's role", "%v", err)
+			return fmt.Errorf("Found %d problems", d.failed)
+		}
+		if cluster == "" {
+			cluster = cfg.Cluster
+		}
+
+		if svc.Region == "" {
+			d.fail("region", "Set AWS_REGION, region in ecsy.yml or region in the profile of ~/.aws/config", "No region is configured")
+			return fmt.Errorf("Found %d problems", d.failed)
+		}
+		d.pass("region", "%s", svc.Region)
+		d.checkEndpoints(svc.Region)
+
+		callerArn, err := api.CallerArn(svc.STS)
+		if err != nil {
+			d.fail("credentials", credentialsHint(), "%v", err)
+			return fmt.Errorf("Found %d problems", d.failed)
+		}
+		d.pass("credentials", "%s", callerArn)
+
+		d.checkPermissions(svc, callerArn)
+		d.checkPrerequisites(svc)
+		d.checkStacks(svc, cluster)
+
+		if d.failed > 0 {
+			return fmt.Errorf("Found %d problems", d.failed)
+		}
+		log.Printf("Everything looks good")
+		return nil
+	})
+}
+
+func credentialsHint() string {
+	if p, err := api.LoadSSOProfile(api.ProfileName()); err == nil && p != nil {
+		return fmt.Sprintf("Run `ecsy login --profile %s`", p.Name)
+	}
+	return "Set AWS_PROFILE to a profile in ~/.aws/config, or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY"
+}
+
+func (d *doctor) checkPermissions(svc api.Services, callerArn string) {
+	actions := policy.Actions(policy.Commands()...)
+	decisions, err := svc.IAM.SimulatePrincipalPolicy(api.PrincipalArn(callerArn), actions)
+	if err != nil {
+		d.warn("permissions", "Allow iam:SimulatePrincipalPolicy to check them", "Can't simulate the caller's policies: %v", err)
+		return
+	}
+
+	denied := []string{}
+	for _, action := range actions {
+		if decisions[action] != "allowed" {
+			denied = append(denied, action)
+		}
+	}
+	if len(denied) == 0 {
+		d.pass("permissions", "All %d actions that ecsy uses are allowed", len(actions))
+		return
+	}
+	shown := denied
+	if len(shown) > 5 {
+		shown = shown[:5]
+	}
+	d.fail("permissions", "Attach the policy from `ecsy iam-policy`, optionally for only the commands you use",
+		"%d actions are denied, including %s", len(denied), strings.Join(shown, ", "))
+}
+
+func (d *doctor) checkPrerequisites(svc api.Services) {
+	for _, role := range []string{ecsServiceLinkedRole, taskExecutionRoleName} {
+		if _, err := svc.IAM.GetRole(role); err == api.ErrNoSuchEntity {
+			d.fail("roles", "Run `ecsy bootstrap` to create it", "Role %s doesn't exist", role)
+		} else if err != nil {
+			d.warn("roles", "", "Can't check role %s: %v", role, err)
+		} else {
+			d.pass("roles", "Role %s exists", role)
+		}
+	}
+
+	policies, err := svc.LogPolicies.DescribeResourcePolicies()
+	if err != nil {
+		d.warn("roles", "", "Can't check the log resource policy: %v", err)
+	} else if !hasLogResourcePolicy(policies) {
+		d.fail("roles", "Run `ecsy bootstrap` to create it", "Log resource policy %s doesn't exist", logResourcePolicyName)
+	} else {
+		d.pass("roles", "Log resource policy %s exists", logResourcePolicyName)
+	}
+}
+
+// checkEndpoints makes a request to each endpoint, any response at all means it can be reached
+func (d *doctor) checkEndpoints(region string) {
+	endpoints := []string{"https://iam.amazonaws.com"}
+	for _, service := range endpointServices {
+		endpoints = append(endpoints, fmt.Sprintf("https://%s.%s.amazonaws.com", service, region))
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	errs := make([]error, len(endpoints))
+	var wg sync.WaitGroup
+	for idx, endpoint := range endpoints {
+		wg.Add(1)
+		go func(idx int, endpoint string) {
+			defer wg.Done()
+			resp, err := client.Get(endpoint)
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs[idx] = err
+		}(idx, endpoint)
+	}
+	wg.Wait()
+
+	unreachable := 0
+	for idx, endpoint := range endpoints {
+		if errs[idx] != nil {
+			unreachable++
+			d.fail("connectivity", "Check proxies (HTTPS_PROXY), firewalls or VPC endpoints for it", "Can't reach %s: %v", endpoint, errs[idx])
+		}
+	}
+	if unreachable == 0 {
+		d.pass("connectivity", "Reached %d endpoints", len(endpoints))
+	}
+}
+
+// checkStacks looks for ecsy stacks that failed, are stuck, have templates older than this
+// version's, or are missing the stacks they depend on
+func (d *doctor) checkStacks(svc api.Services, cluster string) {
+	stacks, err := api.FindManagedStacks(svc.Cloudformation)
+	if err != nil {
+		d.fail("stacks", "", "Can't list stacks: %v", err)
+		return
+	}
+
+	names, clusters := map[string]bool{}, map[string]bool{}
+	for _, stack := range stacks {
+		names[aws.StringValue(stack.StackName)] = true
+		if stackType(stack) == "ecs-former::ecs-stack" {
+			c, _ := api.GetStackOutputByKey(stack, "ECSCluster")
+			clusters[c] = true
+		}
+	}
+
+	failed, checked := d.failed, 0
+	for _, stack := range stacks {
+		name := aws.StringValue(stack.StackName)
+		stackCluster, _ := api.GetStackOutputByKey(stack, "ECSCluster")
+		if cluster != "" && stackCluster != cluster && name != cluster+"-network" &&
+			!strings.HasPrefix(name, "ecs-"+cluster+"-") {
+			continue
+		}
+		checked++
+
+		status := aws.StringValue(stack.StackStatus)
+		switch {
+		case status == cloudformation.StackStatusRollbackComplete || strings.HasSuffix(status, "_FAILED"):
+			d.fail("stacks", stackStatusHint(status, name), "%s is %s: %s", name, status, aws.StringValue(stack.StackStatusReason))
+			continue
+		case strings.HasSuffix(status, "_IN_PROGRESS"):
+			d.warn("stacks", fmt.Sprintf("Follow it with `ecsy poll-stack --stack %s`", name), "%s is %s", name, status)
+			continue
+		}
+
+		st := stackType(stack)
+		if st == networkStackType {
+			stackCluster = strings.TrimSuffix(name, "-network")
+		}
+		if network := stackCluster + "-network"; st == "ecs-former::ecs-stack" && !names[network] {
+			// network stacks from before ecsy tagged stacks aren't managed ones
+			if existing, err := api.FindStacksByName(svc.Cloudformation, network); err != nil {
+				d.warn("stacks", "", "Failed to find network stack %s: %v", network, err)
+			} else if len(existing) == 0 {
+				d.fail("stacks", "Rerun `ecsy create-cluster` to recreate it", "Cluster %s has no network stack %s", stackCluster, network)
+			}
+		}
+		if st == "ecs-former::ecs-service" && !clusters[stackCluster] {
+			d.fail("stacks", fmt.Sprintf("Delete %s or recreate the cluster", name), "Service stack %s is for cluster %s, which doesn't exist", name, stackCluster)
+		}
+
+		if tpl, ok := embeddedTemplates[st]; ok {
+			deployed, err := api.DeployedTemplate(svc.Cloudformation, name)
+			if err != nil {
+				d.warn("stacks", "", "Can't get the template of %s: %v", name, err)
+			} else if from, to := templates.TemplateVersion(deployed), templates.TemplateVersion(tpl()); from < to {
+				d.warn("stacks", fmt.Sprintf("Run `ecsy upgrade --cluster %s` to preview the upgrade", stackCluster),
+					"%s is on template version %d, this ecsy has %d", name, from, to)
+			}
+		}
+	}
+	if d.failed == failed {
+		d.pass("stacks", "Checked %d stacks", checked)
+	}
+}
+
+func stackStatusHint(status, name string) string {
+	switch status {
+	case cloudformation.StackStatusRollbackComplete, cloudformation.StackStatusRollbackFailed, cloudformation.StackStatusCreateFailed:
+		return fmt.Sprintf("It was never created, delete %s and create it again", name)
+	case cloudformation.StackStatusUpdateRollbackFailed:
+		return fmt.Sprintf("Continue the rollback with `aws cloudformation continue-update-rollback --stack-name %s`", name)
+	case cloudformation.StackStatusDeleteFailed:
+		return fmt.Sprintf("Delete %s again, retaining the resources that failed to delete", name)
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	cronExpression = regexp.MustCompile(`^cron\((.+)\)$`)
+	cronDayNumber  = regexp.MustCompile(`[0-9]+`)
+)
+
+// downtimeFlags are the schedules that a cluster is scaled to zero and back up on
+type downtimeFlags struct {
+	Down string
+	Up   string
+}
+
+func (f *downtimeFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("schedule-downtime", "A cron expression in UTC, like \"cron(0 20 ? * MON-FRI *)\", for when to scale the cluster and its services to zero").
+		StringVar(&f.Down)
+
+	cmd.Flag("schedule-uptime", "A cron expression in UTC, like \"cron(0 7 ? * MON-FRI *)\", for when to scale the cluster and its services back up").
+		StringVar(&f.Up)
+}
+
+func (f *downtimeFlags) isSet() bool {
+	return f.Down != "" || f.Up != ""
+}
+
+// params returns the cluster stack parameters for the schedules, the cron expressions for
+// services to scale on and their equivalent recurrences for the autoscaling group
+func (f *downtimeFlags) params() (map[string]string, error) {
+	if f.isSet() && (f.Down == "" || f.Up == "") {
+		return nil, fmt.Errorf("Both --schedule-downtime and --schedule-uptime are needed to schedule downtime")
+	}
+
+	params := map[string]string{
+		"DowntimeSchedule":   f.Down,
+		"UptimeSchedule":     f.Up,
+		"DowntimeRecurrence": "",
+		"UptimeRecurrence":   "",
+	}
+	if !f.isSet() {
+		return params, nil
+	}
+
+	var err error
+	if params["DowntimeRecurrence"], err = cronRecurrence(f.Down); err != nil {
+		return nil, err
+	}
+	if params["UptimeRecurrence"], err = cronRecurrence(f.Up); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// cronRecurrence converts a cron expression like scheduled events and application autoscaling
+// use into the unix cron format that autoscaling group scheduled actions use
+func cronRecurrence(expr string) (string, error) {
+	m := cronExpression.FindStringSubmatch(strings.TrimSpace(expr))
+	if m == nil {
+		return "", fmt.Errorf("Schedule %q isn't a cron expression like \"cron(0 20 ? * MON-FRI *)\"", expr)
+	}
+
+	fields := strings.Fields(m[1])
+	if len(fields) != 6 {
+		return "", fmt.Errorf("Schedule %q needs 6 fields: minutes, hours, day of month, month, day of week and year", expr)
+	}
+	if fields[5] != "*" {
+		return "", fmt.Errorf("Schedule %q can't be limited to particular years", expr)
+	}
+	for _, field := range fields[:5] {
+		if strings.ContainsAny(field, "LW#") {
+			return "", fmt.Errorf("Schedule %q uses L, W or #, which autoscaling groups don't support", expr)
+		}
+	}
+
+	for idx := range fields {
+		if fields[idx] == "?" {
+			fields[idx] = "*"
+		}
+	}
+
+	// days of the week are numbered from 1 for sunday rather than 0, leaving any step alone
+	days := strings.SplitN(fields[4], "/", 2)
+	days[0] = cronDayNumber.ReplaceAllStringFunc(days[0], func(n string) string {
+		day, _ := strconv.Atoi(n)
+		return strconv.Itoa(day - 1)
+	})
+	fields[4] = strings.Join(days, "/")
+
+	return strings.Join(fields[:5], " "), nil
+}
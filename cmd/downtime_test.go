@@ -0,0 +1,35 @@
+package cmd
+
+import "testing"
+
+func TestCronRecurrence(t *testing.T) {
+	for _, tc := range []struct {
+		expr       string
+		recurrence string
+		err        bool
+	}{
+		{expr: "cron(0 20 ? * MON-FRI *)", recurrence: "0 20 * * MON-FRI"},
+		{expr: " cron(30 7 ? * 2-6 *) ", recurrence: "30 7 * * 1-5"},
+		{expr: "cron(0 */4 1 * ? *)", recurrence: "0 */4 1 * *"},
+		{expr: "cron(0 6 ? * 2/2 *)", recurrence: "0 6 * * 1/2"},
+		{expr: "cron(0 6 ? * 1,7 *)", recurrence: "0 6 * * 0,6"},
+		{expr: "0 20 * * MON-FRI", err: true},
+		{expr: "cron(0 20 * *)", err: true},
+		{expr: "cron(0 20 ? * MON-FRI 2027)", err: true},
+		{expr: "cron(0 20 L * ? *)", err: true},
+		{expr: "cron(0 20 ? * 6#3 *)", err: true},
+	} {
+		recurrence, err := cronRecurrence(tc.expr)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %q", tc.expr, recurrence)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected %q to convert, got %v", tc.expr, err)
+		} else if recurrence != tc.recurrence {
+			t.Errorf("Expected %q to convert to %q, got %q", tc.expr, tc.recurrence, recurrence)
+		}
+	}
+}
@@ -23,12 +23,16 @@ func ConfigureDumpTaskDefinition(app *kingpin.Application, svc api.Services) {
 			ComposeFiles: composeFiles,
 		}
 
-		taskDefinitionInput, err := t.Transform()
+		taskDefinitionInput, containerFields, err := t.Transform()
 		if err != nil {
 			return err
 		}
 
-		log.Println(taskDefinitionInput.String())
+		b, err := api.TaskDefinitionJSON(taskDefinitionInput, nil, containerFields)
+		if err != nil {
+			return err
+		}
+		log.Println(string(b))
 
 		return nil
 	})
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/eject"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureEject(app *kingpin.Application, svc api.Services) {
+	var cluster, format, dir string
+
+	cmd := app.Command("eject", "Generate terraform or cdk code that adopts a cluster's stacks")
+	cmd.Flag("cluster", "The name of the ECS cluster to eject").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Flag("format", "The format to generate, either terraform or cdk").
+		Default("terraform").
+		EnumVar(&format, "terraform", "cdk")
+
+	cmd.Flag("dir", "The directory to write generated code to").
+		Default("ecsy-eject").
+		StringVar(&dir)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		backup, err := api.ExportCluster(svc.Cloudformation, svc.ECS, cluster, os.Getenv("AWS_REGION"))
+		if err != nil {
+			return err
+		}
+
+		stacks := []eject.Stack{}
+		for _, sb := range backup.Stacks {
+			resources, err := api.StackResources(svc.Cloudformation, sb.Name)
+			if err != nil {
+				return err
+			}
+
+			s := eject.Stack{
+				Name:       sb.Name,
+				Template:   sb.Template,
+				Parameters: sb.Parameters,
+			}
+			for _, r := range resources {
+				s.Resources = append(s.Resources, eject.Resource{
+					LogicalID:  *r.LogicalResourceId,
+					Type:       *r.ResourceType,
+					PhysicalID: aws.StringValue(r.PhysicalResourceId),
+				})
+			}
+			stacks = append(stacks, s)
+		}
+
+		var files map[string][]byte
+		var steps, unsupported []string
+
+		switch format {
+		case "terraform":
+			files, unsupported = eject.Terraform(stacks)
+			steps = eject.TerraformSteps(stacks)
+		case "cdk":
+			var secrets []string
+			files, secrets = eject.CDK(stacks)
+			steps = eject.CDKSteps(stacks)
+			for _, s := range secrets {
+				log.Printf("Warning: parameter %s is NoEcho, so its value can't be read back and must be passed to cdk deploy", s)
+			}
+		}
+
+		names := []string{}
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(path, files[name], 0644); err != nil {
+				return err
+			}
+			log.Printf("Wrote %s", path)
+		}
+
+		for _, u := range unsupported {
+			log.Printf("Warning: %s can't be imported and must be migrated by hand", u)
+		}
+
+		fmt.Printf("\nTo finish migrating %s to %s, from %s:\n\n", cluster, format, dir)
+		for idx, step := range steps {
+			fmt.Printf("  %d. %s\n", idx+1, step)
+		}
+		return nil
+	})
+}
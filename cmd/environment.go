@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// environmentFlags selects the config file and the environment within it to use
+type environmentFlags struct {
+	ConfigFile string
+	Env        string
+	ImageTag   string
+	Vars       map[string]string
+	// Offline loads the config without calling AWS, for commands that only render
+	Offline bool
+}
+
+func (e *environmentFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("config", "The ecsy config file to use").
+		Default(config.DefaultFile).
+		StringVar(&e.ConfigFile)
+
+	cmd.Flag("env", "The environment from the config file to use").
+		Envar("ECSY_ENV").
+		StringVar(&e.Env)
+
+	cmd.Flag("image-tag", "The image tag for {{ .ImageTag }} placeholders in config and task definition files").
+		Envar("ECSY_IMAGE_TAG").
+		StringVar(&e.ImageTag)
+
+	cmd.Flag("var", "A KEY=VALUE for {{ .Vars.KEY }} placeholders in config and task definition files (repeatable)").
+		StringMapVar(&e.Vars)
+}
+
+// templateData is what placeholders in config and task definition files are resolved from,
+// along with the outputs of stacks where they are known
+func (e *environmentFlags) templateData() config.TemplateData {
+	return config.TemplateData{
+		ImageTag: e.ImageTag,
+		Vars:     e.Vars,
+		Env:      config.Environ(),
+	}
+}
+
+// load reads the config with the selected environment applied, returning services for
+// the environment's region and role
+func (e *environmentFlags) load(svc api.Services) (*config.Config, api.Services, error) {
+	cfg, err := config.LoadTemplate(e.ConfigFile, e.templateData())
+	if err != nil {
+		return nil, svc, err
+	}
+
+	cfg, err = cfg.ForEnvironment(e.Env)
+	if err != nil {
+		return nil, svc, err
+	}
+	if e.Env != "" {
+		log.Printf("Using environment %s", e.Env)
+	}
+
+	// parameter store references are left unresolved, so no secrets end up rendered
+	if e.Offline {
+		return cfg, svc, nil
+	}
+
+	if cfg.Region != "" || cfg.RoleArn != "" {
+		if svc, err = api.EnvironmentServices(cfg.Region, cfg.RoleArn, cfg.MFASerial); err != nil {
+			return nil, svc, err
+		}
+	}
+
+	if cfg.AccountID != "" {
+		accountID, err := api.AccountID(svc.STS)
+		if err != nil {
+			return nil, svc, err
+		}
+		if accountID != cfg.AccountID {
+			return nil, svc, failure.Errorf(failure.Auth, "Environment %q is in account %s, but credentials are for %s",
+				e.Env, cfg.AccountID, accountID)
+		}
+	}
+
+	// stacks are looked up from one listing of the account's stacks
+	svc = api.WithStackIndex(svc)
+
+	// parameter store references in config and stack parameters are resolved with the
+	// environment's credentials
+	resolver := api.NewParameterResolver(svc.SSM)
+	if err = cfg.ResolveValues(resolver.Resolve); err != nil {
+		return nil, svc, err
+	}
+	svc = api.WithParameterStore(svc, resolver)
+
+	auditServices(svc)
+	return cfg, svc, nil
+}
+
+// resolveCluster returns the cluster from the command line, falling back to the config, and
+// then to picking one of the clusters when run from a terminal
+func resolveCluster(svc api.Services, cluster string, cfg *config.Config) (string, error) {
+	if cluster == "" {
+		cluster = cfg.Cluster
+	}
+	if cluster == "" && interactive() {
+		var err error
+		if cluster, err = prompt("--cluster", "The name of the ECS cluster", "", choicesFor(svc, "", "cluster")); err != nil {
+			return "", err
+		}
+	}
+	if cluster != "" {
+		auditCluster(cluster)
+		return cluster, nil
+	}
+	return "", failure.Errorf(failure.Validation, "A cluster is required, either with --cluster or in %s", config.DefaultFile)
+}
+
+// resolveRegion returns the region tasks will run in, for configuring logging
+func resolveRegion(cfg *config.Config) string {
+	if cfg.Region != "" {
+		return cfg.Region
+	}
+	return os.Getenv("AWS_REGION")
+}
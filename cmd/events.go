@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/failure"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Types of the events in the --events-json stream, besides the notify events that are
+// written as they're sent to notifiers
+const (
+	eventCommandStarted   = "command_started"
+	eventCommandCompleted = "command_completed"
+	eventStackStarted     = "stack_started"
+	eventStackResource    = "stack_resource"
+	eventStackCompleted   = "stack_completed"
+	eventStackFailed      = "stack_failed"
+	eventServiceEvent     = "service_event"
+)
+
+// jsonEvent is a line of the --events-json stream
+type jsonEvent struct {
+	Type         string    `json:"type"`
+	Time         time.Time `json:"time"`
+	Command      string    `json:"command,omitempty"`
+	Cluster      string    `json:"cluster,omitempty"`
+	Service      string    `json:"service,omitempty"`
+	Stack        string    `json:"stack,omitempty"`
+	Resource     string    `json:"resource,omitempty"`
+	ResourceType string    `json:"resource_type,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Message      string    `json:"message,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ExitCode     *int      `json:"exit_code,omitempty"`
+}
+
+// eventStream writes newline delimited json events for wrappers and UIs to follow a
+// command by, it does nothing unless --events-json is given
+type eventStream struct {
+	sync.Mutex
+	w       io.WriteCloser
+	command string
+}
+
+var (
+	eventsPath string
+	events     = &eventStream{}
+)
+
+// ConfigureEvents adds the global --events-json flag
+func ConfigureEvents(app *kingpin.Application) {
+	app.Flag("events-json", "Write newline delimited json events about each phase of the command to a file or fifo, like /dev/fd/3").
+		Envar("ECSY_EVENTS_JSON").
+		StringVar(&eventsPath)
+
+	app.PreAction(func(c *kingpin.ParseContext) error {
+		if eventsPath == "" {
+			return nil
+		}
+		w, err := openEvents(eventsPath)
+		if err != nil {
+			return err
+		}
+		events.w = w
+		if c.SelectedCommand != nil {
+			events.command = c.SelectedCommand.FullCommand()
+		}
+		events.emit(jsonEvent{Type: eventCommandStarted, Command: events.command})
+		return nil
+	})
+}
+
+// openEvents opens the file that events are written to. Commands print their output to
+// stdout and log to stderr, so the events can't share either without corrupting them.
+func openEvents(path string) (io.WriteCloser, error) {
+	if path == "-" || path == "/dev/stdout" || path == "/dev/stderr" {
+		return nil, failure.Errorf(failure.Validation,
+			"The --events-json stream can't share stdout or stderr with the command's output, give it a file or a file descriptor like /dev/fd/3")
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// FinishEvents writes the result of the command and closes the --events-json stream
+func FinishEvents(err error) {
+	events.Lock()
+	defer events.Unlock()
+	if events.w == nil {
+		return
+	}
+
+	code := failure.ExitCode(err)
+	e := jsonEvent{Type: eventCommandCompleted, Command: events.command, ExitCode: &code}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	events.write(e)
+	events.w.Close()
+	events.w = nil
+}
+
+func (s *eventStream) enabled() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.w != nil
+}
+
+func (s *eventStream) emit(e jsonEvent) {
+	s.Lock()
+	defer s.Unlock()
+	if s.w != nil {
+		s.write(e)
+	}
+}
+
+func (s *eventStream) write(v interface{}) {
+	if e, ok := v.(jsonEvent); ok && e.Time.IsZero() {
+		e.Time = time.Now()
+		v = e
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to write event: %v", err)
+		return
+	}
+	if _, err = s.w.Write(append(b, '\n')); err != nil {
+		log.Printf("Failed to write event: %v", err)
+	}
+}
+
+// Notify writes the lifecycle events that are sent to notifiers, like deploy_succeeded
+func (s *eventStream) Notify(e notify.Event) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.w != nil {
+		s.write(e)
+	}
+	return nil
+}
+
+// emitStackEvent writes a resource status change of a stack
+func emitStackEvent(event *cloudformation.StackEvent) {
+	events.emit(jsonEvent{
+		Type:         eventStackResource,
+		Time:         aws.TimeValue(event.Timestamp),
+		Stack:        aws.StringValue(event.StackName),
+		Resource:     aws.StringValue(event.LogicalResourceId),
+		ResourceType: aws.StringValue(event.ResourceType),
+		Status:       aws.StringValue(event.ResourceStatus),
+		Reason:       aws.StringValue(event.ResourceStatusReason),
+	})
+}
+
+// emitStackResult writes how a stack operation that was waited for finished
+func emitStackResult(stackName string, err error) {
+	if err != nil {
+		events.emit(jsonEvent{Type: eventStackFailed, Stack: stackName, Error: err.Error()})
+		return
+	}
+	events.emit(jsonEvent{Type: eventStackCompleted, Stack: stackName})
+}
+
+// serviceEventPrinter logs the events of a service whilst waiting for a deploy, writing
+// them to the --events-json stream too
+func serviceEventPrinter(cluster, service string) func(e *ecs.ServiceEvent) {
+	return func(e *ecs.ServiceEvent) {
+		log.Println(*e.Message)
+		events.emit(jsonEvent{
+			Type:    eventServiceEvent,
+			Time:    aws.TimeValue(e.CreatedAt),
+			Cluster: cluster,
+			Service: service,
+			Message: aws.StringValue(e.Message),
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/failure"
+	"github.com/lox/ecsy/notify"
+)
+
+type eventBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *eventBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+// withEvents points the --events-json stream at a buffer whilst a test runs
+func withEvents(t *testing.T) *eventBuffer {
+	buf := &eventBuffer{}
+	events.w, events.command = buf, "deploy"
+	t.Cleanup(func() { events.w, events.command = nil, "" })
+	return buf
+}
+
+// decodeEvents returns each line that was written as a map of its json fields
+func decodeEvents(t *testing.T, buf *eventBuffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Expected each line to be json, got %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestEventShapes(t *testing.T) {
+	buf := withEvents(t)
+	at := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+
+	emitStackEvent(&cloudformation.StackEvent{
+		Timestamp:            aws.Time(at),
+		StackName:            aws.String("example-web"),
+		LogicalResourceId:    aws.String("TaskDefinition"),
+		ResourceType:         aws.String("AWS::ECS::TaskDefinition"),
+		ResourceStatus:       aws.String("UPDATE_COMPLETE"),
+		ResourceStatusReason: aws.String(""),
+	})
+	emitStackResult("example-web", errors.New("rolled back"))
+	emitStackResult("example-web", nil)
+	serviceEventPrinter("example", "web")(&ecs.ServiceEvent{
+		CreatedAt: aws.Time(at),
+		Message:   aws.String("(service web) has reached a steady state."),
+	})
+	events.Notify(notify.Event{Type: "deploy_succeeded", Cluster: "example", Service: "web", Duration: 90 * time.Second, Time: at})
+	FinishEvents(failure.Errorf(failure.Timeout, "Timed out"))
+
+	lines := decodeEvents(t, buf)
+	for _, line := range lines {
+		if _, ok := line["time"].(string); !ok {
+			t.Errorf("Expected every event to have a time, got %v", line)
+		}
+		delete(line, "time")
+	}
+
+	expected := []map[string]interface{}{
+		{"type": "stack_resource", "stack": "example-web", "resource": "TaskDefinition", "resource_type": "AWS::ECS::TaskDefinition", "status": "UPDATE_COMPLETE"},
+		{"type": "stack_failed", "stack": "example-web", "error": "rolled back"},
+		{"type": "stack_completed", "stack": "example-web"},
+		{"type": "service_event", "cluster": "example", "service": "web", "message": "(service web) has reached a steady state."},
+		{"type": "deploy_succeeded", "cluster": "example", "service": "web", "duration_seconds": float64(90)},
+		{"type": "command_completed", "command": "deploy", "error": "Timed out", "exit_code": float64(4)},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Expected events:\n%v\ngot:\n%v", expected, lines)
+	}
+	if !buf.closed || events.enabled() {
+		t.Errorf("Expected the stream to be closed once the command finished")
+	}
+}
+
+func TestEventTimes(t *testing.T) {
+	buf := withEvents(t)
+	at := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+
+	events.emit(jsonEvent{Type: eventStackStarted, Stack: "example-web", Time: at})
+	events.emit(jsonEvent{Type: eventCommandStarted, Command: "deploy"})
+
+	var first, second jsonEvent
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events, got %q", buf.String())
+	}
+	if err := json.Unmarshal(lines[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(lines[1], &second); err != nil {
+		t.Fatal(err)
+	}
+	if !first.Time.Equal(at) {
+		t.Errorf("Expected the time an event happened to be kept, got %v", first.Time)
+	}
+	if second.Time.IsZero() || second.ExitCode != nil {
+		t.Errorf("Expected an event without a time to be stamped with now and no exit code, got %+v", second)
+	}
+}
+
+func TestEventsWithoutStream(t *testing.T) {
+	events.emit(jsonEvent{Type: eventCommandStarted})
+	FinishEvents(nil)
+	if events.enabled() {
+		t.Fatalf("Expected no stream without --events-json")
+	}
+}
+
+func TestOpenEvents(t *testing.T) {
+	for _, path := range []string{"-", "/dev/stdout", "/dev/stderr"} {
+		if _, err := openEvents(path); failure.KindOf(err) != failure.Validation {
+			t.Errorf("Expected %q to be rejected as it's shared with the command's output, got %v", path, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "events")
+	w, err := openEvents(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
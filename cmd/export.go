@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureExport(app *kingpin.Application, svc api.Services) {
+	var cluster string
+
+	cmd := app.Command("export", "Export a cluster's stacks and task definitions as json")
+	cmd.Flag("cluster", "The name of the ECS cluster to export").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		backup, err := api.ExportCluster(svc.Cloudformation, svc.ECS, cluster, os.Getenv("AWS_REGION"))
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Exported %d stacks and %d task definitions for %s",
+			len(backup.Stacks), len(backup.TaskDefinitions), cluster)
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(backup)
+	})
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureGetURL(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, service, path string
+	var waitHealthy bool
+
+	cmd := app.Command("get-url", "Print the public url of a service's load balancer")
+	cmd.Flag("cluster", "The name of the ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service or compose project").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	cmd.Flag("wait-healthy", "Wait until the service responds to its health check with a 2xx status, for smoke tests, for up to --timeout or 5m").
+		BoolVar(&waitHealthy)
+
+	cmd.Flag("path", "The path to check with --wait-healthy, defaults to the load balancer's health check").
+		StringVar(&path)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stack, err := api.FindServiceStack(svc.Cloudformation, cluster, service)
+		if err != nil {
+			return err
+		}
+
+		url, ok := api.GetStackOutputByKey(stack, "ECSLoadBalancer")
+		if !ok {
+			return fmt.Errorf("Service %s on %s is a worker without a load balancer", service, cluster)
+		}
+
+		if waitHealthy {
+			timeout := 5 * time.Minute
+			if waitTimeout > 0 {
+				timeout = waitTimeout
+			}
+			if path == "" {
+				path, _ = api.GetStackParameterByKey(stack, "HealthCheckUrl")
+			}
+			if err = waitForHealthy(strings.TrimSuffix(url, "/")+"/"+strings.TrimPrefix(path, "/"), timeout); err != nil {
+				return err
+			}
+		}
+
+		fmt.Println(url)
+		return nil
+	})
+}
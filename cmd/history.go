@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureHistory(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+	var since time.Duration
+
+	cmd := app.Command("history", "Show a timeline of what changed in a cluster and who changed it")
+	cmd.Flag("cluster", "The ECS cluster to show changes to").
+		StringVar(&cluster)
+
+	cmd.Flag("since", "How far back to show changes from").
+		Default("24h").
+		DurationVar(&since)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+		stackNames := []string{}
+		for _, stack := range stacks {
+			stackNames = append(stackNames, aws.StringValue(stack.StackName))
+		}
+
+		from := time.Now().Add(-since)
+		changes, err := api.StackChanges(svc.Cloudformation, stacks, from)
+		if err != nil {
+			return err
+		}
+
+		if deployments, err := api.DeploymentChanges(svc.ECS, cluster, from); err != nil {
+			log.Printf("Not including deployments: %v", err)
+		} else {
+			changes = append(changes, deployments...)
+		}
+
+		if calls, err := api.TrailChanges(svc.CloudTrail, cluster, stackNames, from); err != nil {
+			log.Printf("Not including cloudtrail events: %v", err)
+		} else {
+			changes = append(changes, calls...)
+		}
+
+		if commands, err := api.AuditChanges(svc.DynamoDB, cluster, from); err != nil {
+			log.Printf("Not including the audit log: %v", err)
+		} else {
+			changes = append(changes, commands...)
+		}
+
+		if len(changes) == 0 {
+			log.Printf("Nothing changed in cluster %s in the last %s", cluster, since)
+			return nil
+		}
+		for _, change := range api.Timeline(changes) {
+			fmt.Println(change)
+		}
+		return nil
+	})
+}
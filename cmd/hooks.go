@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+)
+
+// runHooks runs each hook as a once-off task, stopping at the first that fails
+func runHooks(svc api.Services, stage string, hooks []config.Hook, base onceOffTask) error {
+	for _, hook := range hooks {
+		t := base
+		t.Service = hook.Service
+		t.Commands = hook.Command
+
+		log.Printf("Running %s hook %s", stage, hook)
+		exitCode, err := runOnceOffTask(svc, t)
+		if err != nil {
+			return fmt.Errorf("The %s hook %s failed: %v", stage, hook, err)
+		}
+		if exitCode != 0 {
+			return fmt.Errorf("The %s hook %s failed with exit code %d", stage, hook, exitCode)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/policy"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureIAMPolicy(app *kingpin.Application, svc api.Services) {
+	var commands []string
+	var prefix string
+
+	cmd := app.Command("iam-policy", "Print the least privilege IAM policy needed to run ecsy commands")
+	cmd.Arg("commands", "The commands to allow, defaults to all of them").
+		EnumsVar(&commands, policy.Commands()...)
+
+	cmd.Flag("cluster-prefix", "Only allow access to clusters with names starting with this prefix").
+		StringVar(&prefix)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if len(commands) == 0 {
+			commands = policy.Commands()
+		}
+
+		doc, err := policy.ForCommands(policy.Scope{ClusterPrefix: prefix}, commands...)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	})
+}
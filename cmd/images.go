@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Image tag strategies pick the tag to deploy for each container's image
+const (
+	tagStrategyGitSHA       = "git-sha"
+	tagStrategyLatestSemver = "latest-semver"
+	tagStrategyDigest       = "digest"
+)
+
+var tagStrategies = []string{tagStrategyGitSHA, tagStrategyLatestSemver, tagStrategyDigest}
+
+// resolveImageTags sets the tag of each container's image using a strategy, then pins it
+// to the digest of that tag so the task definition always runs the same image. Containers
+// with images set explicitly keep their tag but are still pinned.
+func resolveImageTags(svc api.Services, defs []*ecs.ContainerDefinition, strategy, gitDir string, explicit map[string]string) error {
+	var sha string
+	if strategy == tagStrategyGitSHA {
+		out, err := exec.Command("git", "-C", gitDir, "rev-parse", "HEAD").Output()
+		if err != nil {
+			return fmt.Errorf("Failed to find the git sha of %s: %v", gitDir, err)
+		}
+		sha = strings.TrimSpace(string(out))
+	}
+
+	for _, def := range defs {
+		name := aws.StringValue(def.Name)
+		ref := api.ParseImageRef(aws.StringValue(def.Image))
+		if !ref.Resolvable() {
+			log.Printf("Not pinning %s, images in %s can't be looked up", ref, ref.Registry)
+			continue
+		}
+
+		var image *api.Image
+		var err error
+
+		_, isExplicit := explicit[name]
+		switch {
+		case ref.Digest != "":
+			continue
+		case isExplicit || strategy == tagStrategyDigest:
+			image, err = svc.Registry.DescribeImage(ref)
+		case strategy == tagStrategyGitSHA:
+			// images are commonly tagged with either the full or the short sha
+			for _, tag := range []string{sha, sha[:7]} {
+				ref.Tag = tag
+				if image, err = svc.Registry.DescribeImage(ref); err != api.ErrImageNotFound {
+					break
+				}
+			}
+		case strategy == tagStrategyLatestSemver:
+			image, err = latestSemverImage(svc, &ref)
+		}
+		if err == api.ErrImageNotFound {
+			return fmt.Errorf("No image %s exists for container %s", ref, name)
+		} else if err != nil {
+			return err
+		}
+
+		log.Printf("Pinning container %s to %s (%s)", name, ref, image.Digest)
+		def.Image = aws.String(ref.Pinned(image.Digest))
+	}
+	return nil
+}
+
+func latestSemverImage(svc api.Services, ref *api.ImageRef) (*api.Image, error) {
+	images, err := svc.Registry.ListImages(*ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	byTag := map[string]*api.Image{}
+	for _, image := range images {
+		for _, tag := range image.Tags {
+			tags = append(tags, tag)
+			byTag[tag] = image
+		}
+	}
+
+	tag, ok := api.LatestSemverTag(tags)
+	if !ok {
+		return nil, fmt.Errorf("No version tags exist for %s", ref.Repository)
+	}
+	ref.Tag = tag
+	return byTag[tag], nil
+}
+
+// imageCheckFlags control the checks made on images before they are deployed
+type imageCheckFlags struct {
+	Skip        bool
+	WarnAgeDays int
+	ScanGate    string
+}
+
+func (f *imageCheckFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("skip-image-check", "Don't check that images exist and run on the cluster's architecture before deploying").
+		BoolVar(&f.Skip)
+
+	cmd.Flag("warn-image-age", "Warn about images that were pushed more than this many days ago").
+		Default("30").
+		IntVar(&f.WarnAgeDays)
+
+	cmd.Flag("scan-gate", "Refuse to deploy images in ECR with scan findings of this severity or worse").
+		EnumVar(&f.ScanGate, "critical", "high", "medium", "low")
+}
+
+// checkImages verifies that each container's image has been pushed and can run on the
+// cluster's architecture, catching a tag that was never pushed before the deploy starts
+func checkImages(svc api.Services, defs []*ecs.ContainerDefinition, arch string, f imageCheckFlags) error {
+	if f.Skip {
+		return nil
+	}
+
+	for _, def := range defs {
+		name := aws.StringValue(def.Name)
+		ref := api.ParseImageRef(aws.StringValue(def.Image))
+		if !ref.Resolvable() {
+			log.Printf("Not checking %s, images in %s can't be looked up", ref, ref.Registry)
+			continue
+		}
+
+		image, err := svc.Registry.DescribeImage(ref)
+		if err == api.ErrImageNotVisible {
+			log.Printf("Warning: not checking image %s for container %s, it can't be seen without credentials", ref, name)
+			continue
+		} else if err == api.ErrImageNotFound {
+			return fmt.Errorf("Image %s for container %s doesn't exist, has it been pushed?", ref, name)
+		} else if err != nil {
+			return fmt.Errorf("Failed to check image %s for container %s: %v", ref, name, err)
+		}
+
+		if age := time.Now().Sub(image.PushedAt); f.WarnAgeDays > 0 && !image.PushedAt.IsZero() && age > time.Duration(f.WarnAgeDays)*24*time.Hour {
+			log.Printf("Warning: image %s for container %s was pushed %d days ago", ref, name, int(age.Hours()/24))
+		}
+
+		archs, err := svc.Registry.Architectures(ref)
+		if err != nil {
+			log.Printf("Warning: failed to find the architecture of %s: %v", ref, err)
+			continue
+		}
+		if len(archs) > 0 && !containsString(archs, arch) {
+			return fmt.Errorf("Image %s for container %s is built for %s, but the cluster runs %s",
+				ref, name, strings.Join(archs, ","), arch)
+		}
+	}
+	return nil
+}
+
+// checkImageScans refuses to deploy images with scan findings at least as severe as the
+// scan gate, printing a summary of the findings. Images outside of ECR can't be scanned.
+func checkImageScans(svc api.Services, defs []*ecs.ContainerDefinition, f imageCheckFlags) error {
+	if f.ScanGate == "" {
+		return nil
+	}
+
+	failed := []string{}
+	for _, def := range defs {
+		name := aws.StringValue(def.Name)
+		ref := api.ParseImageRef(aws.StringValue(def.Image))
+		if !ref.ECR() {
+			log.Printf("Warning: image %s for container %s isn't in ECR and can't be scanned", ref, name)
+			continue
+		}
+
+		scan, err := svc.Registry.ScanFindings(ref)
+		if err != nil {
+			return fmt.Errorf("Failed to get scan findings for %s: %v", ref, err)
+		}
+		if scan.Status != "COMPLETE" && scan.Status != "ACTIVE" {
+			return fmt.Errorf("Image %s for container %s has no completed scan (%s)", ref, name, scan.Status)
+		}
+
+		counts := []string{}
+		for _, severity := range api.Severities {
+			if n := scan.Counts[severity]; n > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", n, strings.ToLower(severity)))
+			}
+		}
+		if len(counts) == 0 {
+			counts = append(counts, "no findings")
+		}
+		log.Printf("Image %s for container %s has %s", ref, name, strings.Join(counts, ", "))
+
+		blocking := scan.AtLeast(f.ScanGate)
+		for _, finding := range blocking {
+			log.Printf("  %-8s %-20s %-20s %s", finding.Severity, finding.Name, finding.Package, finding.URI)
+		}
+		if len(blocking) > 0 {
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("Images for %s have %s or worse scan findings", strings.Join(failed, ", "), f.ScanGate)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
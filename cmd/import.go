@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureImport(app *kingpin.Application, svc api.Services) {
+	var file, region string
+
+	cmd := app.Command("import", "Recreate a cluster from an export, optionally in another region")
+	cmd.Arg("file", "The file written by `ecsy export`").
+		Required().
+		ExistingFileVar(&file)
+
+	cmd.Flag("region", "The region to recreate the cluster in").
+		Default(os.Getenv("AWS_REGION")).
+		StringVar(&region)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		var backup api.ClusterBackup
+		if err = json.NewDecoder(f).Decode(&backup); err != nil {
+			return fmt.Errorf("Failed to parse %s: %v", file, err)
+		}
+
+		target := svc
+		if region != "" && region != os.Getenv("AWS_REGION") {
+			if target, err = api.RegionServices(region); err != nil {
+				return err
+			}
+		}
+
+		timer := time.Now()
+		warnings, err := restoreCluster(target, &backup, region, api.Replacements{})
+		for _, w := range warnings {
+			log.Printf("Warning: %s", w)
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Cluster %s imported into %s in %s", backup.Cluster, region, time.Now().Sub(timer).String())
+		return nil
+	})
+}
+
+// restoreCluster recreates the stacks in a backup in order, replacing references to the
+// original stacks' outputs and task definitions with those of their copies. Values that
+// can't be translated are returned as warnings.
+func restoreCluster(svc api.Services, backup *api.ClusterBackup, region string, r api.Replacements) ([]string, error) {
+	warnings := []string{}
+
+	_, err := svc.ECS.CreateCluster(&ecs.CreateClusterInput{
+		ClusterName: aws.String(backup.Cluster),
+	})
+	if err != nil {
+		return warnings, err
+	}
+
+	for _, stack := range backup.Stacks {
+		existing, err := api.FindStacksByName(svc.Cloudformation, stack.Name)
+		if err != nil {
+			return warnings, err
+		}
+		if len(existing) > 0 {
+			log.Printf("Stack %s already exists, skipping", stack.Name)
+			r.AddOutputs(stack.Outputs, api.StackOutputMap(existing[0]))
+			continue
+		}
+
+		if arn, ok := stack.Parameters["TaskDefinition"]; ok && arn != "" {
+			newArn, taskWarnings, err := restoreTaskDefinition(svc, backup, arn, region, r)
+			warnings = append(warnings, taskWarnings...)
+			if err != nil {
+				return warnings, err
+			}
+			r[arn] = newArn
+		}
+
+		ctx := api.StackOptions{Params: map[string]string{}}
+		for k, v := range stack.Parameters {
+			nv := r.Apply(v)
+			if strings.Trim(v, "*") == "" && v != "" {
+				warnings = append(warnings, fmt.Sprintf("Parameter %s of %s is masked and was left empty", k, stack.Name))
+				nv = ""
+			} else if api.RegionSpecific(nv, backup.Region) {
+				warnings = append(warnings, fmt.Sprintf("Parameter %s of %s refers to %s in %s", k, stack.Name, nv, backup.Region))
+			}
+			ctx.Params[k] = nv
+		}
+
+		log.Printf("Creating cloudformation stack %s", stack.Name)
+		if err := api.CreateStack(svc.Cloudformation, stack.Name, stack.Template, ctx); err != nil {
+			return warnings, err
+		}
+
+		err = waitForStack(svc, stack.Name)
+		if err != nil {
+			return warnings, err
+		}
+
+		outputs, err := api.StackOutputs(svc.Cloudformation, stack.Name)
+		if err != nil {
+			return warnings, err
+		}
+		r.AddOutputs(stack.Outputs, outputs)
+	}
+
+	return warnings, nil
+}
+
+func restoreTaskDefinition(svc api.Services, backup *api.ClusterBackup, arn, region string, r api.Replacements) (string, []string, error) {
+	warnings := []string{}
+
+	td, err := backup.TaskDefinition(arn)
+	if err != nil {
+		return "", warnings, err
+	}
+
+	input := api.RegisterTaskDefinitionInput(td)
+	r.ApplyTaskDefinition(input, region)
+
+	for _, def := range input.ContainerDefinitions {
+		def.Image = aws.String(r.Apply(aws.StringValue(def.Image)))
+		if strings.Contains(aws.StringValue(def.Image), ".ecr."+backup.Region+".") {
+			warnings = append(warnings, fmt.Sprintf("Container %s of %s uses image %s from %s",
+				aws.StringValue(def.Name), aws.StringValue(td.Family), *def.Image, backup.Region))
+		}
+	}
+
+	resp, err := svc.ECS.RegisterTaskDefinition(input)
+	if err != nil {
+		return "", warnings, err
+	}
+	log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+
+	return *resp.TaskDefinition.TaskDefinitionArn, warnings, nil
+}
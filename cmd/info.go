@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureInfo(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+
+	cmd := app.Command("info", "Show which version of ecsy and its templates created a cluster's stacks, and how")
+	cmd.Flag("cluster", "The ECS cluster to show").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("This is ecsy %s", api.ToolVersion)
+		for _, stack := range stacks {
+			name := aws.StringValue(stack.StackName)
+			tags := api.StackTags(stack)
+
+			deployed, err := api.DeployedTemplate(svc.Cloudformation, name)
+			if err != nil {
+				return err
+			}
+
+			version := tags[api.TagVersion]
+			if version == "" {
+				version = "unknown"
+			}
+			updated := stack.CreationTime
+			if stack.LastUpdatedTime != nil {
+				updated = stack.LastUpdatedTime
+			}
+
+			log.Printf("%s (%s)", name, aws.StringValue(stack.StackStatus))
+			log.Printf("  Type:             %s", stackType(stack))
+			log.Printf("  Ecsy version:     %s", version)
+			log.Printf("  Template version: %d", templates.TemplateVersion(deployed))
+			log.Printf("  Template sha256:  %s", api.TemplateChecksum(deployed))
+			if tpl, ok := embeddedTemplates[stackType(stack)]; ok && api.TemplateChecksum(tpl()) == api.TemplateChecksum(deployed) {
+				log.Printf("                    matches this version's embedded template")
+			}
+			if command := tags[api.TagCommand]; command != "" {
+				log.Printf("  Command:          ecsy %s", command)
+			}
+			log.Printf("  Last updated:     %s", aws.TimeValue(updated).Format("2006-01-02 15:04:05 MST"))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+)
+
+// offeredInstanceTypes returns the instance types offered in the region, or none if they
+// can't be listed, in which case instance types aren't checked against the region
+func offeredInstanceTypes(svc api.Services) []string {
+	offered, err := svc.EC2.DescribeInstanceTypeOfferings()
+	if err != nil {
+		log.Printf("Failed to list the instance types offered in the region, not checking them: %v", err)
+		return nil
+	}
+	return offered
+}
+
+// isProduction guesses whether an environment from the config is production from its name
+func isProduction(env string) bool {
+	return strings.Contains(strings.ToLower(env), "prod")
+}
+
+// checkInstanceType validates the instance type of a cluster's main instances against those
+// offered in the region, if they're known, warning about burstable types in production
+func checkInstanceType(instanceType string, offered []string, env string) error {
+	if err := api.ValidateInstanceType(instanceType, offered); err != nil {
+		return err
+	}
+	if api.IsBurstable(instanceType) && isProduction(env) {
+		log.Printf("Warning: %s is a burstable instance type, which can run out of cpu credits under sustained load in %s",
+			instanceType, env)
+	}
+	return nil
+}
+
+// recommendInstanceType prints the instance type that best fits total vcpus and memory in
+// GiB spread over count instances, logging the alternatives
+func recommendInstanceType(svc api.Services, vcpus, memory, count int, env string) error {
+	if vcpus <= 0 && memory <= 0 {
+		return failure.Errorf(failure.Validation, "--recommend needs --total-vcpus or --total-memory")
+	}
+
+	fits := api.RecommendInstanceTypes(int64(vcpus)*1024, int64(memory)*1024, count,
+		offeredInstanceTypes(svc), !isProduction(env))
+	if len(fits) == 0 {
+		return failure.Errorf(failure.Validation, "No instance type fits %d vcpus and %d GiB over %d instances, try a larger --count",
+			vcpus, memory, count)
+	}
+
+	log.Printf("Recommended %d %s instances for %d vcpus and %d GiB of memory", count, fits[0], vcpus, memory)
+	if len(fits) > 1 {
+		alternatives := fits[1:]
+		if len(alternatives) > 3 {
+			alternatives = alternatives[:3]
+		}
+		log.Printf("Alternatives are %s", strings.Join(alternatives, ", "))
+	}
+	fmt.Println(fits[0])
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+)
+
+// errDetached is returned when a stack operation is left running after an interrupt
+var errDetached = errors.New("Detached from the stack operation, it's still in progress")
+
+func printStackEvent(event *cloudformation.StackEvent) {
+	log.Printf("%s\n", api.FormatStackEvent(event))
+	emitStackEvent(event)
+}
+
+// waitForStack polls a stack until the create or update that was started finishes. Ctrl-C
+// offers to cancel the operation, rolling back an update or deleting a half created stack,
+// or to detach and leave it running. A second Ctrl-C exits as usual.
+func waitForStack(svc api.Services, stackName string) error {
+	defer auditStack(svc, stackName)
+
+	events.emit(jsonEvent{Type: eventStackStarted, Stack: stackName})
+	err := waitForStackOrInterrupt(svc, stackName)
+	emitStackResult(stackName, err)
+	return err
+}
+
+func waitForStackOrInterrupt(svc api.Services, stackName string) error {
+	ctx, cancel := context.WithCancel(commandContext())
+	defer cancel()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := api.PollUntilCreated(ctx, svc.Cloudformation, stackName, printStackEvent)
+	if !errors.Is(err, context.Canceled) || commandContext().Err() != nil {
+		return err
+	}
+	signal.Stop(interrupts)
+
+	return interruptedStack(svc, stackName)
+}
+
+// interruptedStack asks what to do with a stack operation that was interrupted
+func interruptedStack(svc api.Services, stackName string) error {
+	stacks, err := api.FindStacksByName(svc.Cloudformation, stackName)
+	if err != nil {
+		return err
+	} else if len(stacks) == 0 {
+		return fmt.Errorf("Stack %s no longer exists", stackName)
+	}
+
+	status := aws.StringValue(stacks[0].StackStatus)
+	var action string
+	switch status {
+	case cloudformation.StackStatusCreateInProgress:
+		action = "delete the stack"
+	case cloudformation.StackStatusUpdateInProgress:
+		action = "cancel the update"
+	default:
+		return fmt.Errorf("Interrupted whilst stack %s is %s", stackName, status)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nStack %s is %s. Do you want to %s, or detach and leave it running? [cancel/DETACH] ",
+		stackName, status, action)
+	answer, _ := stdin.ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "c" && a != "cancel" {
+		log.Printf("Leaving %s running, follow it with `ecsy poll-stack --stack %s`", stackName, stackName)
+		return errDetached
+	}
+
+	if status == cloudformation.StackStatusCreateInProgress {
+		log.Printf("Deleting stack %s", stackName)
+		if err = api.DeleteStack(svc.Cloudformation, stackName); err != nil {
+			return err
+		}
+		if err = api.PollUntilDeleted(commandContext(), svc.Cloudformation, stackName, printStackEvent); err != nil {
+			return err
+		}
+		return fmt.Errorf("Interrupted, deleted the partially created stack %s", stackName)
+	}
+
+	log.Printf("Cancelling the update of %s", stackName)
+	if err = api.CancelUpdateStack(svc.Cloudformation, stackName); err != nil {
+		return err
+	}
+	err = api.PollUntilCreated(commandContext(), svc.Cloudformation, stackName, printStackEvent)
+	if err != nil && failure.KindOf(err) != failure.StackRollback {
+		return err
+	}
+	return fmt.Errorf("Interrupted, cancelled the update of %s and rolled it back", stackName)
+}
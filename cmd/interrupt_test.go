@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+)
+
+const testTemplate = `Resources:
+    Queue:
+        Type: AWS::SQS::Queue
+`
+
+func withStdin(t *testing.T, input string) {
+	previous := stdin
+	stdin = bufio.NewReader(strings.NewReader(input))
+	t.Cleanup(func() { stdin = previous })
+}
+
+func TestWaitForStack(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	if err := api.CreateStack(svc.Cloudformation, "llamas", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForStack(svc, "llamas"); err != nil {
+		t.Fatalf("Expected the created stack to be waited for, got %v", err)
+	}
+	if err := waitForStack(svc, "alpacas"); err == nil {
+		t.Fatalf("Expected waiting for a missing stack to fail")
+	}
+}
+
+func TestInterruptedStackDetaches(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	if err := api.CreateStack(svc.Cloudformation, "llamas", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fake.CloudFormation.StartUpdate("llamas", testTemplate, nil)
+
+	withStdin(t, "\n")
+	if err := interruptedStack(svc, "llamas"); err != errDetached {
+		t.Fatalf("Expected to detach from the update by default, got %v", err)
+	}
+}
+
+func TestInterruptedStackCancelsUpdate(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	if err := api.CreateStack(svc.Cloudformation, "llamas", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fake.CloudFormation.StartUpdate("llamas", testTemplate, nil)
+
+	withStdin(t, "cancel\n")
+	err := interruptedStack(svc, "llamas")
+	if err == nil || !strings.Contains(err.Error(), "cancelled the update of llamas") {
+		t.Fatalf("Expected the update to be cancelled, got %v", err)
+	}
+
+	stacks, err := api.FindStacksByName(svc.Cloudformation, "llamas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := *stacks[0].StackStatus; status != "UPDATE_ROLLBACK_COMPLETE" {
+		t.Fatalf("Expected the update to be rolled back, got %s", status)
+	}
+}
+
+func TestInterruptedStackThatFinished(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	if err := api.CreateStack(svc.Cloudformation, "llamas", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := interruptedStack(svc, "llamas"); err == nil || err == errDetached {
+		t.Fatalf("Expected an error for a stack with nothing in progress, got %v", err)
+	}
+	if err := interruptedStack(svc, "alpacas"); err == nil {
+		t.Fatalf("Expected an error for a missing stack")
+	}
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func jobsStackName(cluster, projectName string) string {
+	return fmt.Sprintf("ecs-%s-%s-jobs", cluster, projectName)
+}
+
+// jobsFlags are the flags shared by the jobs and workflow commands
+type jobsFlags struct {
+	env          environmentFlags
+	Cluster      string
+	ProjectName  string
+	ComposeFiles []string
+}
+
+func (f *jobsFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("cluster", "The ECS cluster the jobs run on").
+		StringVar(&f.Cluster)
+
+	cmd.Flag("project-name", "The name of the project").
+		Short('p').
+		Default(currentDirName()).
+		StringVar(&f.ProjectName)
+
+	f.env.configure(cmd)
+}
+
+func (f *jobsFlags) load(svc api.Services) (*config.Config, api.Services, string, error) {
+	cfg, svc, err := f.env.load(svc)
+	if err != nil {
+		return nil, svc, "", err
+	}
+
+	cluster, err := resolveCluster(svc, f.Cluster, cfg)
+	return cfg, svc, cluster, err
+}
+
+// stateMachine returns the state machine arn for a deployed job
+func (f *jobsFlags) stateMachine(svc api.Services, cluster, name string) (string, error) {
+	return findStateMachine(svc, jobsStackName(cluster, f.ProjectName),
+		templates.JobStateMachineOutput(name), "job", name, f.ProjectName)
+}
+
+func findStateMachine(svc api.Services, stackName, output, kind, name, projectName string) (string, error) {
+	outputs, err := api.StackOutputs(svc.Cloudformation, stackName)
+	if err != nil {
+		return "", fmt.Errorf("No %ss are deployed for %q. Use `%s deploy`", kind, projectName, kind)
+	}
+	arn, ok := outputs[output]
+	if !ok {
+		return "", fmt.Errorf("No %s %q is deployed for %q", kind, name, projectName)
+	}
+	return arn, nil
+}
+
+// deployStateMachinesStack creates or updates a stack of state machines, waiting for it to finish
+func deployStateMachinesStack(svc api.Services, stackName, tpl, kind string) error {
+	ctx := api.StackOptions{Params: map[string]string{}}
+
+	existing, err := api.FindStacksByName(svc.Cloudformation, stackName)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		log.Printf("Updating %s cloudformation stack %s", kind, stackName)
+		err = api.UpdateStack(svc.Cloudformation, stackName, tpl, ctx)
+		if err == api.ErrNoStackUpdates {
+			log.Printf("Stack %s is already up to date", stackName)
+			return nil
+		}
+	} else {
+		log.Printf("Creating %s cloudformation stack %s", kind, stackName)
+		err = api.CreateStack(svc.Cloudformation, stackName, tpl, ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return waitForStack(svc, stackName)
+}
+
+// runStateMachine starts an execution of a state machine and optionally waits for it to succeed
+func runStateMachine(svc api.Services, arn, kind, name string, wait bool) error {
+	executionArn, err := svc.StepFunctions.StartExecution(arn, "")
+	if err != nil {
+		return err
+	}
+	log.Printf("Started %s %s as %s", kind, name, executionArn)
+	if !wait {
+		return nil
+	}
+
+	execution, err := api.PollExecution(commandContext(), svc.StepFunctions, executionArn)
+	if err != nil {
+		return err
+	}
+	if execution.Status != "SUCCEEDED" {
+		return fmt.Errorf("The %s %s %s: %s %s", kind, name, execution.Status, execution.Error, execution.Cause)
+	}
+
+	log.Printf("The %s %s succeeded in %s", kind, name, execution.StopDate.Sub(execution.StartDate).String())
+	return nil
+}
+
+// printExecutions prints the start time, status and duration of state machine executions
+func printExecutions(executions []api.Execution) {
+	for _, e := range executions {
+		duration := "running"
+		if !e.StopDate.IsZero() {
+			duration = e.StopDate.Sub(e.StartDate).String()
+		}
+		log.Printf("%s %-10s %-12s %s", e.StartDate.Format(time.RFC3339), e.Status, duration, e.Name)
+	}
+}
+
+func ConfigureJobs(app *kingpin.Application, svc api.Services) {
+	jobs := app.Command("jobs", "Manage scheduled and on demand jobs defined in config")
+
+	configureJobsDeploy(jobs, svc)
+	configureJobsRun(jobs, svc)
+	configureJobsList(jobs, svc)
+	configureJobsHistory(jobs, svc)
+}
+
+func configureJobsDeploy(jobs *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+	var images string
+
+	cmd := jobs.Command("deploy", "Register tasks for jobs and create or update their state machines and schedules")
+	f.configure(cmd)
+
+	cmd.Flag("file", "The docker-compose file to use").
+		Short('f').
+		Default("docker-compose.yml").
+		ExistingFilesVar(&f.ComposeFiles)
+
+	cmd.Arg("imagetags", "Tags in the form image=tag to apply to the tasks").
+		StringVar(&images)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		imageMap, err := parseImageMap(images)
+		if err != nil {
+			return err
+		}
+
+		cfg, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Jobs) == 0 {
+			return fmt.Errorf("No jobs are defined in %s", f.env.ConfigFile)
+		}
+
+		jobs := []templates.Job{}
+		for _, name := range sortedJobNames(cfg.Jobs) {
+			job := cfg.Jobs[name]
+			timeout, _ := job.TimeoutDuration()
+
+			taskDef, _, err := registerOnceOffTask(svc, onceOffTask{
+				Cluster:      cluster,
+				ProjectName:  f.ProjectName,
+				Family:       fmt.Sprintf("%s_%s_job", f.ProjectName, name),
+				Service:      job.Service,
+				ComposeFiles: f.ComposeFiles,
+				Images:       imageMap,
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("Registered task definition %s:%d for job %s", *taskDef.Family, *taskDef.Revision, name)
+
+			jobs = append(jobs, templates.Job{
+				Name:           name,
+				TaskDefinition: *taskDef.TaskDefinitionArn,
+				Container:      job.Service,
+				Command:        job.Command,
+				Schedule:       job.Schedule,
+				Timeout:        timeout,
+				Retries:        job.Retries,
+				NoOverlap:      job.NoOverlap,
+			})
+		}
+
+		tpl, err := templates.JobsStack(cluster, jobs)
+		if err != nil {
+			return err
+		}
+
+		timer := time.Now()
+		if err = deployStateMachinesStack(svc, jobsStackName(cluster, f.ProjectName), tpl, "jobs"); err != nil {
+			return err
+		}
+
+		log.Printf("Deployed %d jobs in %s", len(jobs), time.Now().Sub(timer).String())
+		return nil
+	})
+}
+
+func configureJobsRun(jobs *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+	var name string
+	var noWait bool
+
+	cmd := jobs.Command("run", "Start a deployed job now, waiting for it to finish")
+	f.configure(cmd)
+
+	cmd.Flag("no-wait", "Don't wait for the job to finish").
+		BoolVar(&noWait)
+
+	cmd.Arg("job", "The name of the job to run").
+		Required().
+		StringVar(&name)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		_, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		arn, err := f.stateMachine(svc, cluster, name)
+		if err != nil {
+			return err
+		}
+
+		return runStateMachine(svc, arn, "job", name, !noWait)
+	})
+}
+
+func configureJobsList(jobs *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+
+	cmd := jobs.Command("list", "List the jobs in config and how they last ran")
+	f.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range sortedJobNames(cfg.Jobs) {
+			job := cfg.Jobs[name]
+			schedule := job.Schedule
+			if schedule == "" {
+				schedule = "on demand"
+			}
+
+			last := "not deployed"
+			if arn, err := f.stateMachine(svc, cluster, name); err == nil {
+				last = "never run"
+				executions, err := svc.StepFunctions.ListExecutions(arn, 1)
+				if err != nil {
+					return err
+				}
+				if len(executions) > 0 {
+					last = fmt.Sprintf("%s %s", executions[0].Status, executions[0].StartDate.Format(time.RFC3339))
+				}
+			}
+
+			log.Printf("%-20s %-30s %s", name, schedule, last)
+		}
+		return nil
+	})
+}
+
+func configureJobsHistory(jobs *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+	var name string
+	var count int
+
+	cmd := jobs.Command("history", "Show recent runs of a job")
+	f.configure(cmd)
+
+	cmd.Flag("count", "The number of runs to show").
+		Default("10").
+		IntVar(&count)
+
+	cmd.Arg("job", "The name of the job").
+		Required().
+		StringVar(&name)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		_, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		arn, err := f.stateMachine(svc, cluster, name)
+		if err != nil {
+			return err
+		}
+
+		executions, err := svc.StepFunctions.ListExecutions(arn, count)
+		if err != nil {
+			return err
+		}
+
+		printExecutions(executions)
+		return nil
+	})
+}
+
+func sortedJobNames(jobs map[string]config.Job) []string {
+	names := []string{}
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
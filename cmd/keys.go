@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureKeys(app *kingpin.Application, svc api.Services) {
+	keys := app.Command("keys", "Manage the ssh keys that can access a cluster's instances")
+
+	configureKeysSync(keys, svc)
+}
+
+func configureKeysSync(keys *kingpin.CmdClause, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+
+	cmd := keys.Command("sync", "Sync authorized_keys on all of a cluster's instances now, rather than waiting for the next sync")
+	cmd.Flag("cluster", "The ECS cluster to sync the keys of").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		} else if stack == nil {
+			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+		}
+
+		if url, _ := api.GetStackParameterByKey(stack, "AuthorizedUsersUrl"); url == "" {
+			return fmt.Errorf("Cluster %s doesn't sync keys, create it with --authorized-keys", cluster)
+		}
+
+		// older clusters sync keys hourly with a script that SSM can't reach
+		deployed, err := api.DeployedTemplate(svc.Cloudformation, *stack.StackName)
+		if err != nil {
+			return err
+		} else if templates.TemplateVersion(deployed) < 6 {
+			return fmt.Errorf("Cluster %s was created before keys could be synced on demand, use `upgrade` first", cluster)
+		}
+
+		instances, err := api.ListContainerInstances(svc.ECS, cluster)
+		if err != nil {
+			return err
+		}
+		ids := []string{}
+		for _, i := range instances {
+			ids = append(ids, aws.StringValue(i.Ec2InstanceId))
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("Cluster %s has no instances", cluster)
+		}
+
+		log.Printf("Syncing authorized_keys on %d instances", len(ids))
+		results, err := api.RunShellCommand(commandContext(), svc.SSM, ids, []string{"/usr/local/bin/sync-authorized-keys"})
+		if err != nil {
+			return err
+		}
+
+		var failed int
+		for _, r := range results {
+			if r.Status == "Success" {
+				log.Printf("%s: synced", r.InstanceID)
+				continue
+			}
+			failed++
+			log.Printf("%s: %s %s", r.InstanceID, r.Status, strings.TrimSpace(r.Error))
+		}
+		if failed > 0 {
+			return fmt.Errorf("Failed to sync keys on %d of %d instances", failed, len(results))
+		}
+		return nil
+	})
+}
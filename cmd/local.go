@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v2"
+)
+
+const localComposeFile = "docker-compose.ecsy-local.yml"
+
+// localCompose is a compose file that runs a task definition's containers locally
+type localCompose struct {
+	Version  string                         `yaml:"version"`
+	Services map[string]localComposeService `yaml:"services"`
+}
+
+type localComposeService struct {
+	Image       string            `yaml:"image"`
+	Entrypoint  []string          `yaml:"entrypoint,omitempty"`
+	Command     []string          `yaml:"command,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	WorkingDir  string            `yaml:"working_dir,omitempty"`
+	User        string            `yaml:"user,omitempty"`
+	Privileged  bool              `yaml:"privileged,omitempty"`
+	ReadOnly    bool              `yaml:"read_only,omitempty"`
+	MemLimit    string            `yaml:"mem_limit,omitempty"`
+}
+
+// localFlags select the service whose task definition is run locally
+type localFlags struct {
+	env     environmentFlags
+	Cluster string
+	Service string
+}
+
+func (f *localFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("cluster", "The name of the ECS cluster the service is in").
+		StringVar(&f.Cluster)
+
+	cmd.Flag("service", "The name of the service or compose project").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&f.Service)
+
+	f.env.configure(cmd)
+}
+
+func ConfigureLocal(app *kingpin.Application, svc api.Services) {
+	local := app.Command("local", "Run a service's task definition locally with docker compose")
+
+	configureLocalGenerate(local, svc)
+	configureLocalUp(local, svc)
+}
+
+func configureLocalGenerate(local *kingpin.CmdClause, svc api.Services) {
+	var flags localFlags
+	var output string
+
+	cmd := local.Command("generate", "Write a compose file that runs the containers of a service's current task definition")
+	flags.configure(cmd)
+
+	cmd.Flag("output", "The file to write, defaults to stdout").
+		Short('o').
+		StringVar(&output)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		b, err := generateLocalCompose(svc, flags)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			_, err = os.Stdout.Write(b)
+			return err
+		}
+		return writeLocalCompose(output, b)
+	})
+}
+
+func configureLocalUp(local *kingpin.CmdClause, svc api.Services) {
+	var flags localFlags
+	var output string
+
+	cmd := local.Command("up", "Run the containers of a service's current task definition with docker compose")
+	flags.configure(cmd)
+
+	cmd.Flag("output", "The compose file to write and run").
+		Short('o').
+		Default(localComposeFile).
+		StringVar(&output)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		b, err := generateLocalCompose(svc, flags)
+		if err != nil {
+			return err
+		}
+		if err = writeLocalCompose(output, b); err != nil {
+			return err
+		}
+
+		command := exec.Command("docker", "compose", "-f", output, "-p", flags.Service, "up")
+		command.Stdin = os.Stdin
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+		return command.Run()
+	})
+}
+
+// writeLocalCompose writes a compose file that only the user can read, as it has the task's
+// secrets in it, and adds it to .gitignore if it's in a git repository that doesn't ignore it
+func writeLocalCompose(output string, b []byte) error {
+	log.Printf("Writing %s", output)
+	if err := ioutil.WriteFile(output, b, 0600); err != nil {
+		return err
+	}
+
+	command := exec.Command("git", "check-ignore", "-q", filepath.Base(output))
+	command.Dir = filepath.Dir(output)
+	err := command.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		// it's either ignored already, or not in a git repository
+		return nil
+	}
+
+	gitignore := filepath.Join(filepath.Dir(output), ".gitignore")
+	log.Printf("Adding %s to %s so that its secrets aren't committed", filepath.Base(output), gitignore)
+	f, err := os.OpenFile(gitignore, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entry := "/" + filepath.Base(output) + "\n"
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		entry = "\n" + entry
+	}
+	_, err = f.WriteString(entry)
+	return err
+}
+
+// composeEscape escapes the $ of a value, which compose would otherwise interpolate
+func composeEscape(s string) string {
+	return strings.Replace(s, "$", "$$", -1)
+}
+
+func composeEscapeAll(values []string) []string {
+	escaped := []string{}
+	for _, s := range values {
+		escaped = append(escaped, composeEscape(s))
+	}
+	return escaped
+}
+
+// generateLocalCompose converts the task definition a service is running into a compose
+// file, with its secrets resolved into the environment
+func generateLocalCompose(svc api.Services, flags localFlags) ([]byte, error) {
+	cfg, svc, err := flags.env.load(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := resolveCluster(svc, flags.Cluster, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName, err := resolveServiceName(svc, cluster, flags.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := api.DescribeService(svc.ECS, cluster, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := svc.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: service.TaskDefinition,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Generating a compose file from %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+
+	secrets, err := svc.TaskDefinitions.DescribeTaskDefinitionSecrets(*resp.TaskDefinition.TaskDefinitionArn)
+	if err != nil {
+		return nil, err
+	}
+
+	file := localCompose{Version: "3", Services: map[string]localComposeService{}}
+	for _, def := range resp.TaskDefinition.ContainerDefinitions {
+		s := localComposeService{
+			Image:       aws.StringValue(def.Image),
+			Entrypoint:  composeEscapeAll(aws.StringValueSlice(def.EntryPoint)),
+			Command:     composeEscapeAll(aws.StringValueSlice(def.Command)),
+			Environment: map[string]string{},
+			WorkingDir:  aws.StringValue(def.WorkingDirectory),
+			User:        aws.StringValue(def.User),
+			Privileged:  aws.BoolValue(def.Privileged),
+			ReadOnly:    aws.BoolValue(def.ReadonlyRootFilesystem),
+		}
+		if def.Memory != nil {
+			s.MemLimit = fmt.Sprintf("%dm", *def.Memory)
+		}
+		for _, kv := range def.Environment {
+			s.Environment[aws.StringValue(kv.Name)] = composeEscape(aws.StringValue(kv.Value))
+		}
+
+		// dynamic host ports are mapped to the same port locally
+		for _, mapping := range def.PortMappings {
+			hostPort := aws.Int64Value(mapping.HostPort)
+			if hostPort == 0 {
+				hostPort = aws.Int64Value(mapping.ContainerPort)
+			}
+			s.Ports = append(s.Ports, fmt.Sprintf("%d:%d", hostPort, aws.Int64Value(mapping.ContainerPort)))
+		}
+
+		for _, secret := range secrets[aws.StringValue(def.Name)] {
+			value, err := api.ResolveTaskSecret(svc.SSM, svc.Secrets, secret)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("Warning: secret %s of %s is written into the compose file's environment", secret.Name, *def.Name)
+			s.Environment[secret.Name] = composeEscape(value)
+		}
+
+		if len(def.MountPoints) > 0 {
+			log.Printf("Warning: the volumes of %s aren't mounted locally", *def.Name)
+		}
+		file.Services[aws.StringValue(def.Name)] = s
+	}
+
+	return yaml.Marshal(file)
+}
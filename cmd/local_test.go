@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComposeEscape(t *testing.T) {
+	for value, expected := range map[string]string{
+		"plain":       "plain",
+		"pa$$word":    "pa$$$$word",
+		"${HOME}/bin": "$${HOME}/bin",
+		"cost is $5":  "cost is $$5",
+		"":            "",
+	} {
+		if escaped := composeEscape(value); escaped != expected {
+			t.Fatalf("Expected %q to escape to %q, got %q", value, expected, escaped)
+		}
+	}
+}
+
+func TestWriteLocalComposeIgnoresIt(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "ecsy-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err = exec.Command("git", "init", "-q", dir).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, localComposeFile)
+	for i := 0; i < 2; i++ {
+		if err = writeLocalCompose(output, []byte("services: {}\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the compose file to only be readable by the user, got %v", info.Mode().Perm())
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "/"+localComposeFile); n != 1 {
+		t.Fatalf("Expected the compose file to be ignored once, got %q", b)
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/lox/ecsy/api"
+)
+
+// serviceLockTTL is how long a lock lasts after it was last renewed, so a lock left
+// behind by a killed deploy expires soon after
+const serviceLockTTL = 15 * time.Minute
+
+// serviceLockRenewal is how often a lock is renewed whilst it's held
+var serviceLockRenewal = 5 * time.Minute
+
+// heldLock is the context key of the lock that an operation runs under
+type heldLock struct{}
+
+// lockOwner describes who holds a lock, so a blocked deploy can say who to talk to
+func lockOwner() string {
+	hostname, _ := os.Hostname()
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	owner := fmt.Sprintf("%s@%s:%d", username, hostname, os.Getpid())
+	if runID := os.Getenv("GITHUB_RUN_ID"); runID != "" {
+		owner += " (github run " + runID + ")"
+	} else if buildURL := os.Getenv("BUILDKITE_BUILD_URL"); buildURL != "" {
+		owner += " (" + buildURL + ")"
+	}
+	return owner
+}
+
+// withServiceLock runs fn whilst holding the cluster's lock for a service, keyed by the
+// cluster and the service's task family, renewing the lock for as long as fn runs. The
+// context fn is given is cancelled if the lock can't be renewed, so that fn stops rather
+// than carrying on unlocked, and that error is returned. Running under a lock that ctx
+// already holds runs fn straight away. Clusters created before the lock table existed are
+// run without a lock.
+func withServiceLock(ctx context.Context, svc api.Services, cluster, service string, forceUnlock bool, fn func(ctx context.Context) error) error {
+	key := fmt.Sprintf("%s/%s", cluster, service)
+	if held, _ := ctx.Value(heldLock{}).(string); held == key {
+		return fn(ctx)
+	}
+
+	clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+	if err != nil {
+		return err
+	} else if clusterStack == nil {
+		return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+	}
+
+	table, ok := api.GetStackOutputByKey(clusterStack, "LockTableName")
+	if !ok {
+		log.Printf("Cluster %s has no lock table, not locking %s", cluster, service)
+		return fn(ctx)
+	}
+
+	if forceUnlock {
+		log.Printf("Forcibly removing lock on %s", key)
+		if err = api.ForceUnlock(svc.DynamoDB, table, key); err != nil {
+			return err
+		}
+	}
+
+	owner := lockOwner()
+	if err = api.AcquireLock(svc.DynamoDB, table, key, owner, serviceLockTTL); err != nil {
+		return err
+	}
+	log.Printf("Acquired lock on %s", key)
+
+	defer func() {
+		if err := api.ReleaseLock(svc.DynamoDB, table, key, owner); err != nil {
+			log.Printf("Failed to release lock on %s: %v", key, err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, heldLock{}, key))
+	lost := make(chan error, 1)
+	go func() {
+		lost <- renewServiceLock(ctx, svc, table, key, owner, cancel)
+	}()
+
+	err = fn(ctx)
+	cancel()
+	if lostErr := <-lost; lostErr != nil {
+		return lostErr
+	}
+	return err
+}
+
+// renewServiceLock renews a lock every serviceLockRenewal until ctx is done, cancelling
+// the operation that holds it if it can't be renewed
+func renewServiceLock(ctx context.Context, svc api.Services, table, key, owner string, cancel func()) error {
+	ticker := time.NewTicker(serviceLockRenewal)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := api.RenewLock(svc.DynamoDB, table, key, owner, serviceLockTTL); err != nil {
+				cancel()
+				return fmt.Errorf("Stopped as the lock on %s couldn't be renewed: %v", key, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+)
+
+// lockTable is a lock table that checks who holds each lock like the condition expressions
+// do, failing renewals with renewErr if it's set
+type lockTable struct {
+	mu       sync.Mutex
+	owners   map[string]string
+	renewErr error
+}
+
+func (t *lockTable) PutItem(input *api.PutItemInput) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := aws.StringValue(input.Item["LockKey"].S)
+	if _, held := t.owners[key]; held {
+		return awserr.New("ConditionalCheckFailedException", "The conditional request failed", nil)
+	}
+	t.owners[key] = aws.StringValue(input.Item["LockOwner"].S)
+	return nil
+}
+
+func (t *lockTable) GetItem(input *api.GetItemInput) (map[string]api.AttributeValue, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	owner := t.owners[aws.StringValue(input.Key["LockKey"].S)]
+	return map[string]api.AttributeValue{"LockOwner": {S: aws.String(owner)}}, nil
+}
+
+func (t *lockTable) UpdateItem(input *api.UpdateItemInput) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.renewErr
+}
+
+func (t *lockTable) DeleteItem(input *api.DeleteItemInput) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.owners, aws.StringValue(input.Key["LockKey"].S))
+	return nil
+}
+
+func (t *lockTable) Query(input *api.QueryInput) ([]map[string]api.AttributeValue, error) {
+	return nil, nil
+}
+
+func (t *lockTable) CreateTable(input *api.CreateTableInput) error {
+	return nil
+}
+
+func (t *lockTable) DescribeTable(table string) (string, error) {
+	return "ACTIVE", nil
+}
+
+// lockedServices returns services with a cluster stack named example that has a lock table
+func lockedServices(t *testing.T, fake *apitest.Fake) (api.Services, *lockTable) {
+	fake.CloudFormation.SetOutputs("ecs-example-cluster", map[string]string{
+		"StackType":     "ecs-former::ecs-stack",
+		"ECSCluster":    "example",
+		"LockTableName": "example-locks",
+	})
+	if err := api.CreateStack(fake.CloudFormation, "ecs-example-cluster", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	table := &lockTable{owners: map[string]string{}}
+	svc := fake.Services()
+	svc.DynamoDB = table
+	return svc, table
+}
+
+func TestServiceLockStopsWhenRenewalFails(t *testing.T) {
+	defer func(renewal time.Duration) { serviceLockRenewal = renewal }(serviceLockRenewal)
+	serviceLockRenewal = 10 * time.Millisecond
+
+	svc, table := lockedServices(t, apitest.New())
+	table.renewErr = errors.New("throttled")
+
+	err := withServiceLock(context.Background(), svc, "example", "web", false, func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("Expected the operation to be stopped")
+		}
+	})
+	if err == nil || !strings.Contains(err.Error(), "couldn't be renewed: throttled") {
+		t.Fatalf("Expected the failed renewal to stop the operation, got %v", err)
+	}
+	if len(table.owners) != 0 {
+		t.Fatalf("Expected the lock to be released, got %v", table.owners)
+	}
+}
+
+func TestServiceLockHeldByContext(t *testing.T) {
+	svc, table := lockedServices(t, apitest.New())
+
+	err := withServiceLock(context.Background(), svc, "example", "web", false, func(ctx context.Context) error {
+		var held *api.LockHeldError
+		if err := withServiceLock(context.Background(), svc, "example", "web", false, func(context.Context) error { return nil }); !errors.As(err, &held) {
+			t.Errorf("Expected another operation to be blocked by the lock, got %v", err)
+		}
+		return withServiceLock(ctx, svc, "example", "web", false, func(context.Context) error { return nil })
+	})
+	if err != nil {
+		t.Fatalf("Expected an operation to run under the lock it already holds, got %v", err)
+	}
+	if len(table.owners) != 0 {
+		t.Fatalf("Expected the lock to be released, got %v", table.owners)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureLogin(app *kingpin.Application) {
+	var profileName string
+	var force bool
+
+	cmd := app.Command("login", "Log in to IAM Identity Center (AWS SSO) for a profile in ~/.aws/config")
+
+	cmd.Flag("profile", "The SSO profile to log in with").
+		Envar("AWS_PROFILE").
+		Default("default").
+		StringVar(&profileName)
+
+	cmd.Flag("force", "Log in again even if the cached token hasn't expired").
+		BoolVar(&force)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		profile, err := api.LoadSSOProfile(profileName)
+		if err != nil {
+			return err
+		} else if profile == nil {
+			return failure.Errorf(failure.Validation, "Profile %s doesn't use SSO, it needs sso_session or sso_start_url", profileName)
+		}
+
+		if expires, ok := profile.LoggedIn(); ok && !force {
+			log.Printf("Already logged in to %s until %s", profile.StartURL, expires.Local().Format(time.RFC1123))
+			return nil
+		}
+
+		log.Printf("Logging in to %s", profile.StartURL)
+		err = profile.Login(commandContext(), func(auth api.DeviceAuthorization) {
+			fmt.Fprintf(os.Stderr, "\nApprove the login in your browser at:\n\n    %s\n\nand check the code is %s\n\n",
+				auth.VerificationURIComplete, auth.UserCode)
+		})
+		if err != nil {
+			return failure.Wrap(failure.Auth, err)
+		}
+
+		expires, _ := profile.LoggedIn()
+		log.Printf("Logged in until %s", expires.Local().Format(time.RFC1123))
+		return nil
+	})
+}
@@ -137,5 +137,4 @@ func (lw *logWatcher) Watch(ctx context.Context) error {
 			return ctx.Err()
 		}
 	}
-	return nil
 }
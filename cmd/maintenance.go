@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureMaintenance(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+	var within time.Duration
+	var replace bool
+	var drainTimeout time.Duration
+
+	cmd := app.Command("maintenance", "List the retirements and maintenance AWS has scheduled for a cluster's instances, and replace them ahead of time")
+	cmd.Flag("cluster", "The ECS cluster to check the instances of").
+		StringVar(&cluster)
+
+	cmd.Flag("within", "Only list maintenance scheduled to start within this long").
+		Default("336h").
+		DurationVar(&within)
+
+	cmd.Flag("replace", "Drain the affected instances one at a time and replace them, without asking").
+		BoolVar(&replace)
+
+	cmd.Flag("drain-timeout", "How long to wait for an instance to drain before giving up on replacing it").
+		Default("15m").
+		DurationVar(&drainTimeout)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		instances, err := api.ListContainerInstances(svc.ECS, cluster)
+		if err != nil {
+			return err
+		}
+		byID := map[string]*ecs.ContainerInstance{}
+		ids := []string{}
+		for _, ci := range instances {
+			id := aws.StringValue(ci.Ec2InstanceId)
+			byID[id] = ci
+			ids = append(ids, id)
+		}
+
+		events, err := svc.EC2.DescribeInstanceEvents(ids)
+		if err != nil {
+			return err
+		}
+		affected := []*ecs.ContainerInstance{}
+		for _, e := range scheduledEvents(events, time.Now().Add(within)) {
+			ci := byID[e.InstanceID]
+			log.Printf("%s %s from %s: %s", e.InstanceID, e.Code, e.NotBefore.Local().Format("2006-01-02 15:04"), e.Description)
+
+			tasks, err := api.ListInstanceTasks(svc.ECS, cluster, aws.StringValue(ci.ContainerInstanceArn))
+			if err != nil {
+				return err
+			}
+			for _, t := range tasks {
+				arn := aws.StringValue(t.TaskDefinitionArn)
+				if startedByService(t) {
+					log.Printf("    runs %s", arn[strings.LastIndex(arn, "/")+1:])
+				} else {
+					log.Printf("    runs %s, which isn't part of a service and won't be moved by draining", arn[strings.LastIndex(arn, "/")+1:])
+				}
+			}
+			if !containsInstance(affected, ci) {
+				affected = append(affected, ci)
+			}
+		}
+		if len(affected) == 0 {
+			log.Printf("No maintenance is scheduled for the instances of %s in the next %s", cluster, within)
+			return nil
+		}
+
+		if !replace {
+			if !interactive() {
+				log.Printf("Run with --replace to drain and replace %d instances", len(affected))
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Drain and replace %d instances now? [y/N]: ", len(affected))
+			line, _ := stdin.ReadString('\n')
+			if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "y") {
+				return nil
+			}
+		}
+
+		for _, ci := range affected {
+			if err = replaceInstance(svc, cluster, ci, drainTimeout); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scheduledEvents returns the events that start before a time, soonest first
+func scheduledEvents(events []api.InstanceEvent, before time.Time) []api.InstanceEvent {
+	scheduled := []api.InstanceEvent{}
+	for _, e := range events {
+		if e.NotBefore.Before(before) {
+			scheduled = append(scheduled, e)
+		}
+	}
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		return scheduled[i].NotBefore.Before(scheduled[j].NotBefore)
+	})
+	return scheduled
+}
+
+// replaceInstance drains a container instance so its service tasks are started elsewhere,
+// then terminates it so its autoscaling group launches a replacement. Draining doesn't stop
+// tasks that were run outside of a service, so it's given up on after a timeout rather than
+// terminating them.
+func replaceInstance(svc api.Services, cluster string, ci *ecs.ContainerInstance, drainTimeout time.Duration) error {
+	id := aws.StringValue(ci.Ec2InstanceId)
+	arn := aws.StringValue(ci.ContainerInstanceArn)
+
+	log.Printf("Draining %s", id)
+	if err := svc.Instances.UpdateContainerInstancesState(cluster, []string{arn}, api.ContainerInstanceDraining); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(commandContext(), drainTimeout)
+	defer cancel()
+	err := api.PollUntilDrained(ctx, svc.ECS, cluster, []string{arn}, func(running int64) {
+		log.Printf("%s has %d tasks running", id, running)
+	})
+	var waitErr *api.WaitError
+	if errors.As(err, &waitErr) && ctx.Err() == context.DeadlineExceeded && commandContext().Err() == nil {
+		return fmt.Errorf("%s didn't drain within %s, stop any tasks on it that aren't part of a service and try again: %w", id, drainTimeout, err)
+	} else if err != nil {
+		return err
+	}
+
+	log.Printf("Terminating %s, its autoscaling group will replace it", id)
+	return svc.Autoscaling.TerminateInstance(id)
+}
+
+// startedByService returns whether a task was started by a service's scheduler, which
+// starts it again elsewhere when its instance is drained
+func startedByService(t *ecs.Task) bool {
+	return strings.HasPrefix(aws.StringValue(t.StartedBy), "ecs-svc/")
+}
+
+func containsInstance(instances []*ecs.ContainerInstance, ci *ecs.ContainerInstance) bool {
+	for _, i := range instances {
+		if i == ci {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+)
+
+func TestScheduledEvents(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	retire := api.InstanceEvent{InstanceID: "i-1", Code: "instance-retirement", NotBefore: now.Add(72 * time.Hour)}
+	reboot := api.InstanceEvent{InstanceID: "i-2", Code: "system-reboot", NotBefore: now.Add(24 * time.Hour)}
+	later := api.InstanceEvent{InstanceID: "i-3", Code: "system-maintenance", NotBefore: now.Add(30 * 24 * time.Hour)}
+
+	for _, tc := range []struct {
+		events   []api.InstanceEvent
+		before   time.Time
+		expected []string
+	}{
+		{nil, now, []string{}},
+		{[]api.InstanceEvent{retire, reboot, later}, now.Add(14 * 24 * time.Hour), []string{"i-2", "i-1"}},
+		{[]api.InstanceEvent{retire, reboot, later}, now.Add(48 * time.Hour), []string{"i-2"}},
+		{[]api.InstanceEvent{later, retire}, now.Add(60 * 24 * time.Hour), []string{"i-1", "i-3"}},
+		{[]api.InstanceEvent{retire}, retire.NotBefore, []string{}},
+	} {
+		ids := []string{}
+		for _, e := range scheduledEvents(tc.events, tc.before) {
+			ids = append(ids, e.InstanceID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.expected, ",") {
+			t.Errorf("Expected events before %v to be %v, got %v", tc.before, tc.expected, ids)
+		}
+	}
+}
+
+type drainingInstances struct {
+	states map[string]string
+}
+
+func (d *drainingInstances) UpdateContainerInstancesState(cluster string, arns []string, status string) error {
+	for _, arn := range arns {
+		d.states[arn] = status
+	}
+	return nil
+}
+
+func (d *drainingInstances) PutAttribute(cluster, arn, name, value string) error { return nil }
+func (d *drainingInstances) DeleteAttribute(cluster, arn, name string) error     { return nil }
+
+type terminatingGroup struct {
+	terminated []string
+}
+
+func (g *terminatingGroup) StartInstanceRefresh(group string, prefs api.InstanceRefreshPreferences) (string, error) {
+	return "", nil
+}
+
+func (g *terminatingGroup) DescribeInstanceRefresh(group, id string) (api.InstanceRefresh, error) {
+	return api.InstanceRefresh{}, nil
+}
+
+func (g *terminatingGroup) TerminateInstance(instanceID string) error {
+	g.terminated = append(g.terminated, instanceID)
+	return nil
+}
+
+func TestReplaceInstanceDrainTimeout(t *testing.T) {
+	fakeECS := apitest.NewECS()
+	drained := &ecs.ContainerInstance{
+		ContainerInstanceArn: aws.String("arn:drained"),
+		Ec2InstanceId:        aws.String("i-drained"),
+		RunningTasksCount:    aws.Int64(0),
+	}
+	standalone := &ecs.ContainerInstance{
+		ContainerInstanceArn: aws.String("arn:standalone"),
+		Ec2InstanceId:        aws.String("i-standalone"),
+		RunningTasksCount:    aws.Int64(1),
+	}
+	fakeECS.AddContainerInstance("example", drained)
+	fakeECS.AddContainerInstance("example", standalone)
+
+	instances := &drainingInstances{states: map[string]string{}}
+	group := &terminatingGroup{}
+	svc := api.Services{ECS: fakeECS, Instances: instances, Autoscaling: group}
+
+	if err := replaceInstance(svc, "example", drained, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	err := replaceInstance(svc, "example", standalone, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "i-standalone didn't drain within 10ms") {
+		t.Fatalf("Expected replacing an instance that doesn't drain to time out, got %v", err)
+	}
+
+	if strings.Join(group.terminated, ",") != "i-drained" {
+		t.Errorf("Expected only the drained instance to be terminated, got %v", group.terminated)
+	}
+	if instances.states["arn:standalone"] != api.ContainerInstanceDraining {
+		t.Errorf("Expected the instance to be draining, got %q", instances.states["arn:standalone"])
+	}
+}
+
+func TestStartedByService(t *testing.T) {
+	for startedBy, expected := range map[string]bool{
+		"ecs-svc/1234567890123456789": true,
+		"ecsy":                        false,
+		"":                            false,
+	} {
+		if actual := startedByService(&ecs.Task{StartedBy: aws.String(startedBy)}); actual != expected {
+			t.Errorf("Expected a task started by %q to be from a service: %v, got %v", startedBy, expected, actual)
+		}
+	}
+}
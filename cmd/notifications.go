@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func notifiersFromConfig(svc api.Services, cfg *config.Config) notify.Notifiers {
+	notifiers := notify.Notifiers{}
+
+	if slack := cfg.Notifications.Slack; slack != nil && slack.WebhookURL != "" {
+		notifiers = append(notifiers, &notify.Slack{
+			WebhookURL: slack.WebhookURL,
+			Channel:    slack.Channel,
+			Username:   slack.Username,
+		})
+	}
+
+	if webhook := cfg.Notifications.Webhook; webhook != nil && webhook.URL != "" {
+		notifiers = append(notifiers, &notify.Webhook{
+			URL:     webhook.URL,
+			Headers: webhook.Headers,
+		})
+	}
+
+	if sns := cfg.Notifications.SNS; sns != nil && sns.TopicArn != "" {
+		notifiers = append(notifiers, &notify.SNS{
+			TopicArn:  sns.TopicArn,
+			Publisher: svc.SNS,
+		})
+	}
+
+	// builds in github actions and buildkite show deploys as annotations
+	if ci := notify.DetectCI(); ci != nil {
+		notifiers = append(notifiers, ci)
+	}
+
+	if events.enabled() {
+		notifiers = append(notifiers, events)
+	}
+
+	return notifiers
+}
+
+// githubDeploymentFlags configures tracking a deploy as a GitHub Deployment, defaulting
+// to the environment variables that GitHub Actions provides
+type githubDeploymentFlags struct {
+	Enabled     bool
+	APIURL      string
+	Token       string
+	Repository  string
+	Ref         string
+	Environment string
+}
+
+func (g *githubDeploymentFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("github-deployment", "Create a GitHub deployment and update its status through the rollout").
+		BoolVar(&g.Enabled)
+
+	cmd.Flag("github-token", "The GitHub token to create deployments with").
+		Envar("GITHUB_TOKEN").
+		StringVar(&g.Token)
+
+	cmd.Flag("github-repo", "The GitHub repository in the form owner/repo").
+		Envar("GITHUB_REPOSITORY").
+		StringVar(&g.Repository)
+
+	cmd.Flag("github-ref", "The git ref being deployed").
+		Envar("GITHUB_SHA").
+		StringVar(&g.Ref)
+
+	cmd.Flag("github-environment", "The GitHub environment name, defaults to the cluster").
+		StringVar(&g.Environment)
+
+	cmd.Flag("github-api-url", "The GitHub api to use").
+		Envar("GITHUB_API_URL").
+		Default("https://api.github.com").
+		StringVar(&g.APIURL)
+}
+
+func (g *githubDeploymentFlags) deployment(cluster string) (*notify.GitHubDeployment, error) {
+	if g.Token == "" || g.Repository == "" || g.Ref == "" {
+		return nil, fmt.Errorf("GitHub deployments require --github-token, --github-repo and --github-ref")
+	}
+
+	environment := g.Environment
+	if environment == "" {
+		environment = cluster
+	}
+
+	var logURL string
+	if server, runID := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_RUN_ID"); server != "" && runID != "" {
+		logURL = fmt.Sprintf("%s/%s/actions/runs/%s", server, g.Repository, runID)
+	}
+
+	return &notify.GitHubDeployment{
+		APIURL:      g.APIURL,
+		Token:       g.Token,
+		Repository:  g.Repository,
+		Ref:         g.Ref,
+		Environment: environment,
+		LogURL:      logURL,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureOverview(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var notifyResult bool
+
+	cmd := app.Command("overview", "Summarize the health of every cluster in the account, for a morning check")
+	cmd.Flag("notify", "Send the overview to the notifications configured for the environment, like slack").
+		BoolVar(&notifyResult)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		clusters, err := api.ClusterNames(svc.Cloudformation)
+		if err != nil {
+			return err
+		}
+
+		var out bytes.Buffer
+		problems := []string{}
+		w := tabwriter.NewWriter(&out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "CLUSTER\tINSTANCES\tSERVICES\tUNHEALTHY\tPENDING\tOLDEST AMI")
+		for _, cluster := range clusters {
+			o, err := api.SummarizeCluster(svc.ECS, svc.EC2, cluster)
+			if err != nil {
+				return err
+			}
+			amiAge := "-"
+			if !o.OldestAMI.IsZero() {
+				amiAge = fmt.Sprintf("%d days", int(time.Since(o.OldestAMI).Hours()/24))
+			}
+			unhealthy := "-"
+			if len(o.UnhealthyServices) > 0 {
+				unhealthy = strings.Join(o.UnhealthyServices, ", ")
+				problems = append(problems, fmt.Sprintf("%s has unhealthy services %s", cluster, unhealthy))
+			}
+			if o.ActiveInstances < o.Instances {
+				problems = append(problems, fmt.Sprintf("%s has %d inactive instances", cluster, o.Instances-o.ActiveInstances))
+			}
+			fmt.Fprintf(w, "%s\t%d/%d\t%d\t%s\t%d\t%s\n", cluster, o.ActiveInstances, o.Instances, o.Services, unhealthy, o.PendingTasks, amiAge)
+		}
+		w.Flush()
+
+		failed, err := api.FailedStacks(svc.Cloudformation)
+		if err != nil {
+			return err
+		}
+		if len(failed) > 0 {
+			fmt.Fprintln(&out, "\nFailed stacks:")
+			for _, stack := range failed {
+				fmt.Fprintf(&out, "  %s %s\n", aws.StringValue(stack.StackName), aws.StringValue(stack.StackStatus))
+				problems = append(problems, fmt.Sprintf("stack %s is %s", aws.StringValue(stack.StackName), aws.StringValue(stack.StackStatus)))
+			}
+		}
+
+		os.Stdout.Write(out.Bytes())
+		if len(problems) == 0 {
+			log.Printf("%d clusters, all healthy", len(clusters))
+		} else {
+			log.Printf("%d clusters, %d problems", len(clusters), len(problems))
+		}
+
+		if notifyResult {
+			notifiersFromConfig(svc, cfg).Notify(notify.Event{
+				Type:    notify.OverviewReported,
+				Summary: out.String(),
+				Error:   strings.Join(problems, "\n"),
+			})
+		}
+		return nil
+	})
+}
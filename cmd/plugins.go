@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// pluginPrefix is what executables on the PATH are named to be run as ecsy commands
+const pluginPrefix = "ecsy-"
+
+// RunPlugin runs ecsy-<command> from the PATH when the command isn't one of ecsy's own,
+// returning whether a plugin was run and the code it exited with. Plugins are passed the
+// rest of the arguments, and the cluster, region and cluster stack outputs in ECSY_*
+// environment variables.
+func RunPlugin(app *kingpin.Application, args []string, svc api.Services) (bool, int) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || args[0] == "help" {
+		return false, 0
+	}
+	if app.GetCommand(args[0]) != nil {
+		return false, 0
+	}
+	path, err := exec.LookPath(pluginPrefix + args[0])
+	if err != nil {
+		return false, 0
+	}
+
+	c := exec.Command(path, args[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), pluginEnviron(args[1:], svc)...)
+
+	if err = c.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return true, exitErr.ExitCode()
+		}
+		log.Printf("Failed to run %s: %v", path, err)
+		return true, 1
+	}
+	return true, 0
+}
+
+// pluginEnviron is the context a plugin is run with, from the --config, --env and --cluster
+// flags it was given, falling back to the config file
+func pluginEnviron(args []string, svc api.Services) []string {
+	e := environmentFlags{
+		ConfigFile: pluginFlag(args, "config", config.DefaultFile),
+		Env:        pluginFlag(args, "env", os.Getenv("ECSY_ENV")),
+	}
+	environ := []string{}
+	if exe, err := os.Executable(); err == nil {
+		environ = append(environ, "ECSY_BIN="+exe)
+	}
+
+	cfg, svc, err := e.load(svc)
+	if err != nil {
+		log.Printf("Not including the environment: %v", err)
+		return environ
+	}
+	if e.Env != "" {
+		environ = append(environ, "ECSY_ENV="+e.Env)
+	}
+
+	region := svc.Region
+	if cfg.Region != "" {
+		region = cfg.Region
+	}
+	if region != "" {
+		environ = append(environ, "ECSY_REGION="+region, "AWS_REGION="+region)
+	}
+
+	// plugins use the same credentials, so that roles assumed for the environment apply
+	if cfg.RoleArn != "" && svc.Credentials != nil {
+		creds, err := svc.Credentials.Get()
+		if err != nil {
+			log.Printf("Not including credentials: %v", err)
+		} else {
+			environ = append(environ,
+				"AWS_ACCESS_KEY_ID="+creds.AccessKeyID,
+				"AWS_SECRET_ACCESS_KEY="+creds.SecretAccessKey,
+				"AWS_SESSION_TOKEN="+creds.SessionToken,
+			)
+		}
+	}
+
+	cluster := pluginFlag(args, "cluster", os.Getenv("ECSY_CLUSTER"))
+	if cluster == "" {
+		cluster = cfg.Cluster
+	}
+	if cluster == "" {
+		return environ
+	}
+	environ = append(environ, "ECSY_CLUSTER="+cluster)
+
+	stack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+	if err != nil {
+		log.Printf("Not including stack outputs: %v", err)
+		return environ
+	}
+	environ = append(environ, "ECSY_STACK="+*stack.StackName)
+	for key, value := range api.StackOutputMap(stack) {
+		environ = append(environ, pluginOutputVar(key)+"="+value)
+	}
+	return environ
+}
+
+// pluginFlag returns the value of a --name flag in args, in either the --name value or
+// --name=value forms, without removing it
+func pluginFlag(args []string, name, fallback string) string {
+	flag := "--" + name
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(arg, flag+"=") {
+			return strings.TrimPrefix(arg, flag+"=")
+		}
+	}
+	return fallback
+}
+
+// pluginOutputVar is the environment variable a stack output is passed in, like
+// ECSY_OUTPUT_VPC_ID for VpcId
+func pluginOutputVar(key string) string {
+	var b strings.Builder
+	b.WriteString("ECSY_OUTPUT_")
+	for i, r := range key {
+		if i > 0 && r >= 'A' && r <= 'Z' && !(key[i-1] >= 'A' && key[i-1] <= 'Z') {
+			b.WriteRune('_')
+		}
+		b.WriteString(strings.ToUpper(string(r)))
+	}
+	return b.String()
+}
+
+// plugins returns the names of the ecsy-<command> executables on the PATH, with the first
+// one found for each name
+func plugins() map[string]string {
+	found := map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := f.Name()
+			if !strings.HasPrefix(name, pluginPrefix) || f.IsDir() || f.Mode()&0111 == 0 {
+				continue
+			}
+			command := strings.TrimPrefix(name, pluginPrefix)
+			if _, ok := found[command]; !ok {
+				found[command] = filepath.Join(dir, name)
+			}
+		}
+	}
+	return found
+}
+
+func ConfigurePlugins(app *kingpin.Application) {
+	cmd := app.Command("plugins", "List the ecsy-<command> plugins on the PATH")
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		found := plugins()
+		commands := []string{}
+		for command := range found {
+			commands = append(commands, command)
+		}
+		sort.Strings(commands)
+
+		for _, command := range commands {
+			if app.GetCommand(command) != nil {
+				log.Printf("Plugin %s is hidden by the ecsy %s command", found[command], command)
+				continue
+			}
+			fmt.Printf("%s\t%s\n", command, found[command])
+		}
+		if len(commands) == 0 {
+			log.Printf("No %s<command> plugins found on the PATH", pluginPrefix)
+		}
+		return nil
+	})
+}
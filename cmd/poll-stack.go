@@ -19,9 +19,11 @@ func ConfigurePollStack(app *kingpin.Application, svc api.Services) {
 		StringVar(&stackName)
 
 	cmd.Action(func(c *kingpin.ParseContext) error {
-		err := api.PollUntilCreated(svc.Cloudformation, stackName, func(event *cloudformation.StackEvent) {
+		err := api.PollUntilCreated(commandContext(), svc.Cloudformation, stackName, func(event *cloudformation.StackEvent) {
 			log.Printf("%s\n", api.FormatStackEvent(event))
+			emitStackEvent(event)
 		})
+		emitStackResult(stackName, err)
 		if err != nil {
 			return err
 		}
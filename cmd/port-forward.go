@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigurePortForward(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, service, container, ports string
+
+	cmd := app.Command("port-forward", "Forward a local port to a container of a running task with a Session Manager session to its instance")
+	cmd.Flag("cluster", "The name of the ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service or compose project").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	cmd.Flag("container", "The container to forward to, defaults to whichever maps the port").
+		StringVar(&container)
+
+	env.configure(cmd)
+
+	cmd.Arg("ports", "The local port and the container port to forward to, like 8080:80").
+		Required().
+		StringVar(&ports)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		localPort, containerPort, err := parsePortPair(ports)
+		if err != nil {
+			return err
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		} else if stack == nil {
+			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+		} else if !usesSessionManager(stack) {
+			return fmt.Errorf("Cluster %s doesn't use Session Manager, update it with `create-cluster --no-ssh`", cluster)
+		}
+
+		serviceName, err := resolveServiceName(svc, cluster, service)
+		if err != nil {
+			return err
+		}
+
+		tasks, err := api.ListServiceTasks(svc.ECS, cluster, serviceName, "RUNNING")
+		if err != nil {
+			return err
+		}
+
+		task, hostPort, err := findHostPort(tasks, container, containerPort)
+		if err != nil {
+			return fmt.Errorf("Service %s: %v", serviceName, err)
+		}
+
+		resp, err := svc.ECS.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(cluster),
+			ContainerInstances: []*string{task.ContainerInstanceArn},
+		})
+		if err != nil {
+			return err
+		} else if len(resp.ContainerInstances) == 0 {
+			return fmt.Errorf("Task %s has no container instance", aws.StringValue(task.TaskArn))
+		}
+		instanceID := aws.StringValue(resp.ContainerInstances[0].Ec2InstanceId)
+
+		log.Printf("Forwarding localhost:%d to port %d of task %s on %s", localPort, containerPort,
+			aws.StringValue(task.TaskArn), instanceID)
+
+		command := exec.Command("aws", "ssm", "start-session",
+			"--target", instanceID,
+			"--document-name", "AWS-StartPortForwardingSession",
+			"--parameters", fmt.Sprintf("portNumber=%d,localPortNumber=%d", hostPort, localPort),
+			"--region", resolveRegion(cfg))
+		command.Stdin = os.Stdin
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+		return command.Run()
+	})
+}
+
+// parsePortPair parses a local and remote port like 8080:80, or a single port for both
+func parsePortPair(s string) (int64, int64, error) {
+	pieces := strings.SplitN(s, ":", 2)
+	if len(pieces) == 1 {
+		pieces = append(pieces, pieces[0])
+	}
+
+	var ports [2]int64
+	for idx, p := range pieces {
+		port, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || port < 1 || port > 65535 {
+			return 0, 0, fmt.Errorf("Failed to parse ports %q, expected local:remote like 8080:80", s)
+		}
+		ports[idx] = port
+	}
+	return ports[0], ports[1], nil
+}
+
+// findHostPort returns the first task with a container that binds the container port to a
+// host port, which is what the instance forwards to
+func findHostPort(tasks []*ecs.Task, container string, containerPort int64) (*ecs.Task, int64, error) {
+	if len(tasks) == 0 {
+		return nil, 0, fmt.Errorf("no tasks are running")
+	}
+	for _, task := range tasks {
+		for _, c := range task.Containers {
+			if container != "" && aws.StringValue(c.Name) != container {
+				continue
+			}
+			for _, binding := range c.NetworkBindings {
+				if aws.Int64Value(binding.ContainerPort) == containerPort && aws.Int64Value(binding.HostPort) != 0 {
+					return task, aws.Int64Value(binding.HostPort), nil
+				}
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("no running task maps container port %d to a host port", containerPort)
+}
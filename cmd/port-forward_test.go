@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func TestParsePortPair(t *testing.T) {
+	for _, tc := range []struct {
+		s             string
+		local, remote int64
+		err           bool
+	}{
+		{s: "8080:80", local: 8080, remote: 80},
+		{s: "5432", local: 5432, remote: 5432},
+		{s: "1:65535", local: 1, remote: 65535},
+		{s: "", err: true},
+		{s: "http", err: true},
+		{s: "8080:", err: true},
+		{s: "0:80", err: true},
+		{s: "8080:65536", err: true},
+		{s: "8080:80:90", err: true},
+	} {
+		local, remote, err := parsePortPair(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %d:%d", tc.s, local, remote)
+			}
+			continue
+		}
+		if err != nil || local != tc.local || remote != tc.remote {
+			t.Errorf("Expected %q to be %d:%d, got %d:%d (%v)", tc.s, tc.local, tc.remote, local, remote, err)
+		}
+	}
+}
+
+func TestFindHostPort(t *testing.T) {
+	container := func(name string, bindings ...[2]int64) *ecs.Container {
+		c := &ecs.Container{Name: aws.String(name)}
+		for _, b := range bindings {
+			c.NetworkBindings = append(c.NetworkBindings, &ecs.NetworkBinding{
+				ContainerPort: aws.Int64(b[0]),
+				HostPort:      aws.Int64(b[1]),
+			})
+		}
+		return c
+	}
+	pending := &ecs.Task{TaskArn: aws.String("pending"), Containers: []*ecs.Container{container("web", [2]int64{80, 0})}}
+	web := &ecs.Task{TaskArn: aws.String("web"), Containers: []*ecs.Container{
+		container("proxy", [2]int64{80, 32001}),
+		container("web", [2]int64{80, 32002}, [2]int64{9000, 32003}),
+	}}
+
+	for _, tc := range []struct {
+		tasks     []*ecs.Task
+		container string
+		port      int64
+		task      string
+		hostPort  int64
+		err       bool
+	}{
+		{tasks: nil, port: 80, err: true},
+		{tasks: []*ecs.Task{pending}, port: 80, err: true},
+		{tasks: []*ecs.Task{pending, web}, port: 80, task: "web", hostPort: 32001},
+		{tasks: []*ecs.Task{pending, web}, container: "web", port: 80, task: "web", hostPort: 32002},
+		{tasks: []*ecs.Task{web}, port: 9000, task: "web", hostPort: 32003},
+		{tasks: []*ecs.Task{web}, container: "proxy", port: 9000, err: true},
+		{tasks: []*ecs.Task{web}, container: "worker", port: 80, err: true},
+	} {
+		task, hostPort, err := findHostPort(tc.tasks, tc.container, tc.port)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Expected no host port for %q port %d, got %d", tc.container, tc.port, hostPort)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected a host port for %q port %d, got %v", tc.container, tc.port, err)
+		} else if aws.StringValue(task.TaskArn) != tc.task || hostPort != tc.hostPort {
+			t.Errorf("Expected %q port %d to be %s:%d, got %s:%d", tc.container, tc.port, tc.task, tc.hostPort, aws.StringValue(task.TaskArn), hostPort)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/failure"
+	"github.com/lox/ecsy/templates"
+)
+
+// preflightCluster checks that a cluster's instances can launch before any stacks are created,
+// so that a missing keypair, AMI or instance type fails in seconds rather than in a rollback.
+// Checks that can't be made, such as without permissions to describe what they need, are
+// warned about.
+func preflightCluster(svc api.Services, cfg *config.Config, cluster, keyName, instanceType string) error {
+	if keyName != "" {
+		if err := preflightKeyPair(svc, keyName); err != nil {
+			return err
+		}
+	}
+
+	images := []string{}
+	if ami, ok := templates.RegionAMI(templates.EcsStack(), svc.Region); ok {
+		images = append(images, ami)
+	} else if svc.Region != "" {
+		return failure.Errorf(failure.Validation, "The cluster template has no ECS optimized AMI for %s", svc.Region)
+	}
+
+	instanceTypes := []string{instanceType}
+	for _, name := range sortedInstanceGroups(cfg) {
+		g := cfg.InstanceGroups[name]
+		if g.AMI != "" {
+			images = append(images, g.AMI)
+		}
+		if !containsString(instanceTypes, g.Type) {
+			instanceTypes = append(instanceTypes, g.Type)
+		}
+	}
+	if err := preflightImages(svc, images); err != nil {
+		return err
+	}
+
+	zones, err := clusterZones(svc, cluster)
+	if err != nil {
+		log.Printf("Not checking instance types are offered in the cluster's availability zones: %v", err)
+		return nil
+	}
+	for _, instanceType := range instanceTypes {
+		offered, err := svc.EC2.DescribeInstanceTypeZones(instanceType)
+		if err != nil {
+			log.Printf("Not checking %s is offered in the cluster's availability zones: %v", instanceType, err)
+			continue
+		}
+		missing := []string{}
+		for _, zone := range zones {
+			if !containsString(offered, zone) {
+				missing = append(missing, zone)
+			}
+		}
+		if len(missing) > 0 {
+			return failure.Errorf(failure.Validation, "%s isn't offered in %s, which the cluster's instances run in",
+				instanceType, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+func preflightKeyPair(svc api.Services, keyName string) error {
+	keyPairs, err := svc.EC2.DescribeKeyPairs()
+	if err != nil {
+		log.Printf("Not checking keypair %s exists: %v", keyName, err)
+		return nil
+	}
+	if !containsString(keyPairs, keyName) {
+		return failure.Errorf(failure.Validation, "Keypair %s doesn't exist in %s, create it, pass another --keyname or use --no-ssh",
+			keyName, svc.Region)
+	}
+	return nil
+}
+
+func preflightImages(svc api.Services, images []string) error {
+	if len(images) == 0 {
+		return nil
+	}
+	available, err := svc.EC2.DescribeImages(images)
+	if err != nil {
+		log.Printf("Not checking AMIs are available: %v", err)
+		return nil
+	}
+	for _, image := range images {
+		if !containsString(available, image) {
+			return failure.Errorf(failure.Validation, "AMI %s isn't available in %s", image, svc.Region)
+		}
+	}
+	return nil
+}
+
+// clusterZones returns the availability zones of a cluster's private subnets, which its
+// instances run in, or the zones the network stack would create them in if it doesn't exist
+func clusterZones(svc api.Services, cluster string) ([]string, error) {
+	network, err := api.LookupNetworkStack(svc.Cloudformation, cluster)
+	if err != nil {
+		return nil, err
+	} else if network != nil {
+		subnets, err := svc.EC2.DescribeSubnets([]string{network.Subnet2Private, network.Subnet3Private})
+		if err != nil {
+			return nil, err
+		}
+		zones := []string{}
+		for _, subnet := range subnets {
+			zones = append(zones, subnet.AvailabilityZone)
+		}
+		return zones, nil
+	}
+
+	zones, err := svc.EC2.DescribeAvailabilityZones()
+	if err != nil {
+		return nil, err
+	}
+	// the private subnets are in the first two zones that Fn::GetAZs returns
+	if len(zones) > 2 {
+		zones = zones[:2]
+	}
+	return zones, nil
+}
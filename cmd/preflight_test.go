@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lox/ecsy/api/apitest"
+)
+
+func TestClusterZonesReturnsLookupErrors(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	for _, zone := range []string{"us-east-1a", "us-east-1b", "us-east-1c"} {
+		fake.EC2.AddAvailabilityZone(zone)
+	}
+
+	zones, err := clusterZones(svc, "example")
+	if err != nil || !reflect.DeepEqual(zones, []string{"us-east-1a", "us-east-1b"}) {
+		t.Fatalf("Expected the first two zones for a cluster without a network stack, got %v, %v", zones, err)
+	}
+
+	svc.Cloudformation = &throttledStacks{svc.Cloudformation}
+	if zones, err = clusterZones(svc, "example"); err == nil {
+		t.Fatalf("Expected a failed lookup of the network stack to fail, got %v", zones)
+	}
+}
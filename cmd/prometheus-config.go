@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/prometheus"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func prometheusAgentStackName(cluster string) string {
+	return fmt.Sprintf("ecs-%s-prometheus-agent", cluster)
+}
+
+func ConfigurePrometheusConfig(app *kingpin.Application, svc api.Services) {
+	var cluster, remoteWriteURL, agentImage string
+	var agent, deployAgent bool
+
+	cmd := app.Command("prometheus-config", "Generate prometheus scrape config for services exposing metrics")
+	cmd.Flag("cluster", "The name of the ECS cluster to discover services in").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Flag("agent", "Generate config for an agent scraping the instance it runs on").
+		BoolVar(&agent)
+
+	cmd.Flag("deploy-agent", "Deploy a prometheus agent as a daemon service on the cluster").
+		BoolVar(&deployAgent)
+
+	cmd.Flag("remote-write-url", "The prometheus remote write endpoint for the agent to push to").
+		StringVar(&remoteWriteURL)
+
+	cmd.Flag("agent-image", "The prometheus compatible image to run as the agent").
+		Default("prom/prometheus:v2.45.0").
+		StringVar(&agentImage)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		stacks, err := api.FindServiceStacks(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+
+		targets := []prometheus.Target{}
+		for _, stack := range stacks {
+			outputs := api.StackOutputMap(stack)
+			if value, exists := outputs["MetricsPort"]; exists {
+				port, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("Stack %s has an invalid MetricsPort %q", aws.StringValue(stack.StackName), value)
+				}
+				targets = append(targets, prometheus.Target{
+					Service: outputs["TaskFamily"],
+					Port:    port,
+					Path:    outputs["MetricsPath"],
+				})
+			}
+		}
+
+		if len(targets) == 0 {
+			return fmt.Errorf("No services in cluster %q expose a metrics port. Use `create-service --metrics-port`",
+				cluster)
+		}
+
+		if !agent && !deployAgent {
+			b, err := prometheus.EC2ServiceDiscovery(cluster, os.Getenv("AWS_REGION"), targets)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s", b)
+			return nil
+		}
+
+		b, err := prometheus.AgentConfig(cluster, targets, remoteWriteURL)
+		if err != nil {
+			return err
+		}
+
+		if !deployAgent {
+			fmt.Printf("%s", b)
+			return nil
+		}
+
+		if remoteWriteURL == "" {
+			return fmt.Errorf("A --remote-write-url is required to deploy an agent")
+		}
+
+		clusterStack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+
+		ctx := api.StackOptions{
+			Params: map[string]string{
+				"ECSCluster":       cluster,
+				"AgentImage":       agentImage,
+				"PrometheusConfig": string(b),
+				"LogGroupName":     api.StackOutputMap(clusterStack)["LogGroupName"],
+			},
+		}
+
+		stackName := prometheusAgentStackName(cluster)
+		existing, err := api.FindStacksByName(svc.Cloudformation, stackName)
+		if err != nil {
+			return err
+		}
+
+		if len(existing) > 0 {
+			log.Printf("Updating prometheus agent stack %s", stackName)
+			err = api.UpdateStack(svc.Cloudformation, stackName, templates.PrometheusAgent(), ctx)
+			if err == api.ErrNoStackUpdates {
+				log.Printf("Prometheus agent is already up to date")
+				return nil
+			}
+		} else {
+			log.Printf("Creating prometheus agent stack %s", stackName)
+			err = api.CreateStack(svc.Cloudformation, stackName, templates.PrometheusAgent(), ctx)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = waitForStack(svc, stackName)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Prometheus agent scraping %d services on %s", len(targets), cluster)
+		return nil
+	})
+}
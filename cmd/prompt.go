@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lox/ecsy/api"
+	"github.com/mattn/go-isatty"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// noInput stops commands prompting for missing values, for scripts run from a terminal
+var noInput bool
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// flagChoices list the values to pick from when prompting for a flag, by the flag's name or
+// the command and flag, like "create-cluster type"
+var flagChoices = map[string]func(svc api.Services) ([]string, error){
+	"cluster": func(svc api.Services) ([]string, error) {
+		return api.ClusterNames(svc.Cloudformation)
+	},
+	"keyname": func(svc api.Services) ([]string, error) {
+		return svc.EC2.DescribeKeyPairs()
+	},
+	"create-cluster type": func(svc api.Services) ([]string, error) {
+		return api.RecommendInstanceTypes(0, 0, 1, offeredInstanceTypes(svc), true), nil
+	},
+}
+
+// ConfigurePrompts adds the global --no-input flag
+func ConfigurePrompts(app *kingpin.Application) {
+	app.Flag("no-input", "Fail rather than prompt for missing values when run from a terminal").
+		Envar("ECSY_NO_INPUT").
+		BoolVar(&noInput)
+}
+
+// interactive returns whether missing values can be prompted for
+func interactive() bool {
+	if noInput || os.Getenv("CI") != "" {
+		return false
+	}
+	return isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stderr.Fd())
+}
+
+// PromptRequired prompts for the required flags and arguments of the command in args that
+// are missing, returning args with them added. Args are returned as they are if they can't
+// be parsed or prompting isn't possible, so that the usual errors are reported.
+func PromptRequired(app *kingpin.Application, args []string, svc api.Services) []string {
+	c, err := app.ParseContext(args)
+	if err != nil || c.SelectedCommand == nil {
+		return args
+	}
+
+	given := map[string]bool{}
+	for _, element := range c.Elements {
+		switch clause := element.Clause.(type) {
+		case *kingpin.FlagClause:
+			given["--"+clause.Model().Name] = true
+		case *kingpin.ArgClause:
+			given[clause.Model().Name] = true
+		}
+	}
+	if given["--no-input"] || given["--help"] {
+		return args
+	}
+	if v, err := strconv.ParseBool(os.Getenv("ECSY_NO_INPUT")); err == nil && v {
+		return args
+	}
+	if !interactive() {
+		return args
+	}
+
+	command := c.SelectedCommand
+	model := command.Model()
+	for _, flag := range model.Flags {
+		if !flag.Required || given["--"+flag.Name] || command.GetFlag(flag.Name).HasEnvarValue() {
+			continue
+		}
+		value, err := prompt("--"+flag.Name, flag.Help, "", choicesFor(svc, model.FullCommand, flag.Name))
+		if err != nil {
+			return args
+		}
+		args = append(args, "--"+flag.Name+"="+value)
+	}
+	for _, arg := range model.Args {
+		if !arg.Required || given[arg.Name] || command.GetArg(arg.Name).HasEnvarValue() {
+			continue
+		}
+		value, err := prompt(arg.Name, arg.Help, "", nil)
+		if err != nil {
+			return args
+		}
+		args = append(args, value)
+	}
+	return args
+}
+
+// choicesFor returns the values to pick a flag from, or none if they can't be listed
+func choicesFor(svc api.Services, command, flag string) []string {
+	list, ok := flagChoices[command+" "+flag]
+	if !ok {
+		list, ok = flagChoices[flag]
+	}
+	if !ok {
+		return nil
+	}
+	choices, err := list(svc)
+	if err != nil {
+		return nil
+	}
+	return choices
+}
+
+var errNoAnswer = errors.New("No value was entered")
+
+// prompt asks for a value on stderr, falling back to def if nothing is entered. A value
+// must be one of the choices if there are any, which can also be picked by number.
+func prompt(name, help, def string, choices []string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", name, help)
+	for idx, choice := range choices {
+		fmt.Fprintf(os.Stderr, "  %d) %s\n", idx+1, choice)
+	}
+
+	for {
+		if def != "" {
+			fmt.Fprintf(os.Stderr, "%s [%s]: ", name, def)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: ", name)
+		}
+
+		line, err := stdin.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if err != nil && answer == "" {
+			fmt.Fprintln(os.Stderr)
+			return "", errNoAnswer
+		}
+
+		if answer == "" {
+			answer = def
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(choices) {
+			answer = choices[n-1]
+		}
+
+		switch {
+		case answer == "":
+			fmt.Fprintf(os.Stderr, "A value is required\n")
+		case len(choices) > 0 && !containsString(choices, answer):
+			fmt.Fprintf(os.Stderr, "%s isn't one of the choices\n", answer)
+		default:
+			return answer, nil
+		}
+	}
+}
+
+// promptFlag prompts for a flag that wasn't given if the command is interactive, otherwise
+// keeping its current value
+func promptFlag(svc api.Services, c *kingpin.ParseContext, command, flag, help string, value *string) error {
+	if !interactive() || flagGiven(c, flag) {
+		return nil
+	}
+	answer, err := prompt("--"+flag, help, *value, choicesFor(svc, command, flag))
+	if err != nil {
+		return err
+	}
+	*value = answer
+	return nil
+}
+
+// flagGiven returns whether a flag was on the command line
+func flagGiven(c *kingpin.ParseContext, name string) bool {
+	for _, element := range c.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok && flag.Model().Name == name {
+			return true
+		}
+	}
+	return false
+}
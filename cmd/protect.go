@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// shouldProtect returns whether the stacks of an environment get termination protection,
+// from the config or else whether the environment is production
+func shouldProtect(cfg *config.Config, env string) bool {
+	if cfg.Protect != nil {
+		return *cfg.Protect
+	}
+	return isProduction(env)
+}
+
+// protectStacks turns on termination protection for stacks that don't have it
+func protectStacks(svc api.Services, stackNames ...string) error {
+	for _, name := range stackNames {
+		protected, err := svc.Protection.TerminationProtected(name)
+		if err != nil {
+			return err
+		}
+		if protected {
+			continue
+		}
+		log.Printf("Turning on termination protection for %s", name)
+		if err = svc.Protection.SetTerminationProtection(name, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// protectedStacks returns the stacks of a cluster that have termination protection
+func protectedStacks(svc api.Services, cluster string) ([]string, error) {
+	stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, cluster)
+	if err != nil {
+		return nil, err
+	}
+	protected := []string{}
+	for _, stack := range stacks {
+		name := aws.StringValue(stack.StackName)
+		if ok, err := svc.Protection.TerminationProtected(name); err != nil {
+			return nil, err
+		} else if ok {
+			protected = append(protected, name)
+		}
+	}
+	return protected, nil
+}
+
+func ConfigureUnprotect(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+
+	cmd := app.Command("unprotect", "Turn off termination protection for a cluster's stacks, so they can be deleted")
+	cmd.Flag("cluster", "The ECS cluster to unprotect").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		protected, err := protectedStacks(svc, cluster)
+		if err != nil {
+			return err
+		}
+		if len(protected) == 0 {
+			log.Printf("No stacks of cluster %s have termination protection", cluster)
+			return nil
+		}
+		for _, name := range protected {
+			log.Printf("Turning off termination protection for %s", name)
+			if err = svc.Protection.SetTerminationProtection(name, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkUnprotected fails if any of a cluster's stacks have termination protection, which
+// would fail their deletion part way through
+func checkUnprotected(svc api.Services, cluster string) error {
+	protected, err := protectedStacks(svc, cluster)
+	if err != nil {
+		log.Printf("Not checking termination protection: %v", err)
+		return nil
+	}
+	if len(protected) > 0 {
+		return failure.Errorf(failure.Validation, "%s have termination protection, run `ecsy unprotect --cluster %s` first",
+			strings.Join(protected, ", "), cluster)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// the table definitions for logs that can be queried, with the location of the logs substituted
+const accessLogsTable = `CREATE EXTERNAL TABLE IF NOT EXISTS %s (
+  request_timestamp string,
+  elb_name string,
+  request_ip string,
+  request_port int,
+  backend_ip string,
+  backend_port int,
+  request_processing_time double,
+  backend_processing_time double,
+  client_response_time double,
+  elb_response_code string,
+  backend_response_code string,
+  received_bytes bigint,
+  sent_bytes bigint,
+  request_verb string,
+  url string,
+  protocol string,
+  user_agent string,
+  ssl_cipher string,
+  ssl_protocol string
+)
+ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.RegexSerDe'
+WITH SERDEPROPERTIES (
+  'serialization.format' = '1',
+  'input.regex' = '([^ ]*) ([^ ]*) ([^ ]*):([0-9]*) ([^ ]*)[:-]([0-9]*) ([-.0-9]*) ([-.0-9]*) ([-.0-9]*) (|[-0-9]*) (-|[-0-9]*) ([-0-9]*) ([-0-9]*) \"([^ ]*) ([^ ]*) (- |[^ ]*)\" (\"[^\"]*\") ([A-Z0-9-]+) ([A-Za-z0-9.-]*)$'
+)
+LOCATION '%s'`
+
+const flowLogsTable = `CREATE EXTERNAL TABLE IF NOT EXISTS %s (
+  version int,
+  account_id string,
+  interface_id string,
+  srcaddr string,
+  dstaddr string,
+  srcport int,
+  dstport int,
+  protocol bigint,
+  packets bigint,
+  bytes bigint,
+  start bigint,
+  ` + "`end`" + ` bigint,
+  action string,
+  log_status string
+)
+ROW FORMAT DELIMITED FIELDS TERMINATED BY ' '
+LOCATION '%s'
+TBLPROPERTIES ("skip.header.line.count"="1")`
+
+var athenaNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// athenaName converts a name to one athena accepts for databases and tables
+func athenaName(parts ...string) string {
+	return athenaNameChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "_")), "_")
+}
+
+func ConfigureQuery(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, source, service, query string
+
+	cmd := app.Command("query", "Query load balancer access logs or VPC flow logs with SQL via Athena")
+	cmd.Flag("cluster", "The ECS cluster the logs belong to").
+		StringVar(&cluster)
+
+	cmd.Flag("source", "The logs to query").
+		Default("access-logs").
+		EnumVar(&source, "access-logs", "flow-logs")
+
+	cmd.Flag("service", "The service to query the access logs of").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	env.configure(cmd)
+
+	cmd.Arg("sql", "The query to run, with {table} replaced by the table of logs").
+		Required().
+		StringVar(&query)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		accountID, err := api.AccountID(svc.STS)
+		if err != nil {
+			return err
+		}
+
+		var bucket, table, ddl string
+		switch source {
+		case "access-logs":
+			serviceStack, err := api.FindServiceStack(svc.Cloudformation, cluster, service)
+			if err != nil {
+				return err
+			}
+			location, ok := api.GetStackOutputByKey(serviceStack, "AccessLogsPrefix")
+			if !ok {
+				return fmt.Errorf("Service %s doesn't write access logs, create the cluster with --access-logs", service)
+			}
+			bucket = strings.SplitN(strings.TrimPrefix(location, "s3://"), "/", 2)[0]
+			table = athenaName(service, "access_logs")
+			ddl = fmt.Sprintf(accessLogsTable, table, location)
+
+		case "flow-logs":
+			outputs, err := api.StackOutputs(svc.Cloudformation, cluster+"-network")
+			if err != nil {
+				return err
+			}
+			if bucket = outputs["FlowLogBucket"]; bucket == "" {
+				return fmt.Errorf("Cluster %s doesn't write flow logs to s3, create it with --flow-logs s3", cluster)
+			}
+			table = "flow_logs"
+			ddl = fmt.Sprintf(flowLogsTable, table,
+				fmt.Sprintf("s3://%s/AWSLogs/%s/vpcflowlogs/%s/", bucket, accountID, resolveRegion(cfg)))
+		}
+
+		database := athenaName("ecsy", cluster)
+		results := fmt.Sprintf("s3://%s/athena-results/", bucket)
+
+		log.Printf("Setting up table %s.%s", database, table)
+		if _, err = api.RunQuery(commandContext(), svc.Athena, "CREATE DATABASE IF NOT EXISTS "+database, "", results); err != nil {
+			return err
+		}
+		if _, err = api.RunQuery(commandContext(), svc.Athena, ddl, database, results); err != nil {
+			return err
+		}
+
+		log.Printf("Running query")
+		rows, err := api.RunQuery(commandContext(), svc.Athena, strings.Replace(query, "{table}", table, -1), database, results)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		w.Flush()
+
+		if len(rows) > 0 {
+			log.Printf("%d rows", len(rows)-1)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// quotaFlags configure the service quota checks made before creating stacks
+type quotaFlags struct {
+	RequestIncrease bool
+}
+
+func (f *quotaFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("request-quota-increase", "Request increases of service quotas that are too low for what would be created").
+		BoolVar(&f.RequestIncrease)
+}
+
+// check fails before anything is created if it would exceed any of the quotas, requesting
+// increases to what's needed if asked to. Quotas are only warned about if they can't be
+// looked up, so that missing permissions don't stop anything being created.
+func (f quotaFlags) check(svc api.Services, quotas []api.Quota) error {
+	if len(quotas) == 0 {
+		return nil
+	}
+	exceeded, err := api.CheckQuotas(svc.Quotas, quotas)
+	if err != nil {
+		log.Printf("Not checking service quotas: %v", err)
+		return nil
+	}
+
+	names := []string{}
+	for _, q := range exceeded {
+		log.Printf("Service quota %s is too low", q)
+		names = append(names, q.Name)
+		if !f.RequestIncrease {
+			continue
+		}
+		id, err := svc.Quotas.RequestServiceQuotaIncrease(q.ServiceCode, q.QuotaCode, q.Used+q.Needed)
+		if err != nil {
+			return fmt.Errorf("Failed to request an increase of %s: %v", q.Name, err)
+		}
+		log.Printf("Requested an increase of %s to %g, request id %s", q.Name, q.Used+q.Needed, id)
+	}
+
+	if len(exceeded) == 0 {
+		return nil
+	} else if f.RequestIncrease {
+		return fmt.Errorf("Service quotas are too low for %s, try again once the increases are approved", strings.Join(names, ", "))
+	}
+	return fmt.Errorf("Service quotas are too low for %s, use --request-quota-increase to request increases", strings.Join(names, ", "))
+}
+
+// clusterQuotas are the quotas that creating the network stack of a cluster uses, a VPC and
+// an elastic ip for its NAT gateway, or none if the cluster already has a network stack
+func clusterQuotas(svc api.Services, cluster string) ([]api.Quota, error) {
+	network, err := api.LookupNetworkStack(svc.Cloudformation, cluster)
+	if err != nil {
+		return nil, err
+	} else if network != nil {
+		return nil, nil
+	}
+
+	quotas := []api.Quota{}
+	if vpcs, err := svc.EC2.DescribeVpcs(); err != nil {
+		log.Printf("Not checking the VPC quota: %v", err)
+	} else {
+		q := api.VPCsQuota
+		q.Used, q.Needed = float64(len(vpcs)), 1
+		quotas = append(quotas, q)
+	}
+	if addresses, err := svc.EC2.DescribeAddresses(); err != nil {
+		log.Printf("Not checking the elastic ip quota: %v", err)
+	} else {
+		q := api.ElasticIPsQuota
+		q.Used, q.Needed = float64(len(addresses)), 1
+		quotas = append(quotas, q)
+	}
+	return quotas, nil
+}
+
+// serviceQuotas are the quotas that a service uses, the tasks it runs and a load balancer
+// if one is being created for it
+func serviceQuotas(svc api.Services, loadBalancer bool, count int) []api.Quota {
+	tasks := api.TasksQuota
+	tasks.Needed = float64(count)
+	quotas := []api.Quota{tasks}
+
+	if !loadBalancer {
+		return quotas
+	}
+	if names, err := svc.ELB.DescribeLoadBalancers(); err != nil {
+		log.Printf("Not checking the load balancer quota: %v", err)
+	} else {
+		q := api.LoadBalancerQuota
+		q.Used, q.Needed = float64(len(names)), 1
+		quotas = append(quotas, q)
+	}
+	return quotas
+}
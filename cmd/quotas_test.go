@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+)
+
+func TestClusterQuotas(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	fake.EC2.AddVpc("vpc-1234")
+
+	quotas, err := clusterQuotas(svc, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quotas) != 2 || quotas[0].Name != api.VPCsQuota.Name || quotas[0].Used != 1 || quotas[1].Name != api.ElasticIPsQuota.Name {
+		t.Fatalf("Expected the VPC and elastic ip quotas of a new network stack, got %+v", quotas)
+	}
+
+	if err = api.CreateStack(svc.Cloudformation, "example-network", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if quotas, err = clusterQuotas(svc, "example"); err != nil || len(quotas) != 0 {
+		t.Fatalf("Expected no quotas for a cluster that has a network stack, got %+v, %v", quotas, err)
+	}
+
+	svc.Cloudformation = &throttledStacks{svc.Cloudformation}
+	if quotas, err = clusterQuotas(svc, "other"); err == nil {
+		t.Fatalf("Expected a failed lookup of the network stack to fail, got %+v", quotas)
+	}
+}
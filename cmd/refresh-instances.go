@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const instanceRefreshPollInterval = 15 * time.Second
+
+func ConfigureRefreshInstances(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster string
+	var minHealthy int
+	var checkpoints []int
+	var warmup, checkpointDelay time.Duration
+
+	cmd := app.Command("refresh-instances", "Replace a cluster's instances with an instance refresh, such as after changing their AMI or launch template")
+	cmd.Flag("cluster", "The ECS cluster to refresh the instances of").
+		StringVar(&cluster)
+
+	cmd.Flag("min-healthy", "The percentage of instances that must stay in service during the refresh").
+		Default("90").
+		IntVar(&minHealthy)
+
+	cmd.Flag("warmup", "How long a new instance takes to be ready to run tasks").
+		Default("5m").
+		DurationVar(&warmup)
+
+	cmd.Flag("checkpoint", "A percentage of instances replaced at which to pause the refresh (repeatable)").
+		IntsVar(&checkpoints)
+
+	cmd.Flag("checkpoint-delay", "How long to pause at each checkpoint").
+		Default("10m").
+		DurationVar(&checkpointDelay)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if minHealthy < 0 || minHealthy > 100 {
+			return fmt.Errorf("--min-healthy must be a percentage between 0 and 100")
+		}
+		for idx, p := range checkpoints {
+			if p < 1 || p > 100 || (idx > 0 && p <= checkpoints[idx-1]) {
+				return fmt.Errorf("Checkpoints must be increasing percentages between 1 and 100")
+			}
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		} else if stack == nil {
+			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+		}
+
+		group, err := clusterAutoScalingGroup(svc, *stack.StackName)
+		if err != nil {
+			return err
+		}
+
+		timer := time.Now()
+		log.Printf("Refreshing the instances of %s, keeping %d%% in service", group, minHealthy)
+
+		id, err := svc.Autoscaling.StartInstanceRefresh(group, api.InstanceRefreshPreferences{
+			MinHealthyPercentage:  minHealthy,
+			InstanceWarmup:        int(warmup.Seconds()),
+			CheckpointPercentages: checkpoints,
+			CheckpointDelay:       int(checkpointDelay.Seconds()),
+		})
+		if err != nil {
+			return err
+		}
+
+		var last api.InstanceRefresh
+		for {
+			if err = api.Sleep(commandContext(), instanceRefreshPollInterval, "instance refresh "+id, "it was "+last.Status); err != nil {
+				return err
+			}
+
+			refresh, err := svc.Autoscaling.DescribeInstanceRefresh(group, id)
+			if err != nil {
+				return err
+			}
+			if refresh.Status != last.Status || refresh.PercentageComplete != last.PercentageComplete {
+				log.Printf("%s: %d%% complete, %d instances to replace %s",
+					refresh.Status, refresh.PercentageComplete, refresh.InstancesToUpdate, refresh.StatusReason)
+			}
+			last = refresh
+
+			switch refresh.Status {
+			case api.InstanceRefreshSuccessful:
+				log.Printf("Refreshed instances in %s", time.Now().Sub(timer).String())
+				return nil
+			case api.InstanceRefreshFailed, api.InstanceRefreshCancelled:
+				return fmt.Errorf("Instance refresh %s %s: %s", id, refresh.Status, refresh.StatusReason)
+			}
+		}
+	})
+}
+
+// clusterAutoScalingGroup returns the name of the autoscaling group of a cluster stack
+func clusterAutoScalingGroup(svc api.Services, stackName string) (string, error) {
+	resources, err := api.StackResources(svc.Cloudformation, stackName)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range resources {
+		if aws.StringValue(r.LogicalResourceId) == "ECSAutoScalingGroup" {
+			return aws.StringValue(r.PhysicalResourceId), nil
+		}
+	}
+	return "", fmt.Errorf("Stack %s has no autoscaling group", stackName)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureRegisterTaskDefinition(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, file string
+
+	cmd := app.Command("register-taskdef", "Register a task definition from raw ECS json, for deploying with `deploy --task-definition`")
+	cmd.Flag("file", "A json task definition, as `aws ecs register-task-definition --cli-input-json` takes or describe-task-definition outputs").
+		Required().
+		ExistingFileVar(&file)
+
+	cmd.Flag("cluster", "A cluster whose stack outputs fill in {{ .Outputs.Name }} placeholders in the file").
+		StringVar(&cluster)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		// the cluster is optional here, so it's never prompted for
+		data := env.templateData()
+		if data.Cluster = cluster; data.Cluster == "" {
+			data.Cluster = cfg.Cluster
+		}
+		if data.Cluster != "" {
+			if data.Outputs, err = clusterAndServiceOutputs(svc, data.Cluster, nil); err != nil {
+				return err
+			}
+		}
+
+		body, err := renderTaskDefinitionFile(file, data)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Registering a task definition from %s", file)
+		resp, err := svc.TaskDefinitions.RegisterRawTaskDefinition(body)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Registered task definition %s:%d", *resp.TaskDefinition.Family, *resp.TaskDefinition.Revision)
+		auditResource(*resp.TaskDefinition.TaskDefinitionArn)
+		fmt.Println(*resp.TaskDefinition.TaskDefinitionArn)
+		return nil
+	})
+}
+
+// renderTaskDefinitionFile reads a raw task definition, resolving any placeholders in it
+func renderTaskDefinitionFile(path string, data config.TemplateData) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Render(path, b, data)
+}
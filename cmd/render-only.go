@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// renderFlags write what a command would create or update to a directory instead, so that
+// infrastructure changes can be reviewed. Rendering doesn't call AWS, so it works from the
+// config and flags alone: the outputs of other stacks are placeholders and parameter store
+// references are left as they are.
+type renderFlags struct {
+	Dir string
+}
+
+func (f *renderFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("render-only", "Write the resolved cloudformation templates, parameters and task definitions to a directory rather than changing anything").
+		PlaceHolder("DIR").
+		StringVar(&f.Dir)
+}
+
+func (f renderFlags) enabled() bool {
+	return f.Dir != ""
+}
+
+// renderedParameter is a stack parameter in the format `aws cloudformation` takes
+type renderedParameter struct {
+	ParameterKey   string
+	ParameterValue string
+}
+
+// stack writes a stack's template and parameters as <stack>.yml and <stack>.parameters.json
+func (f renderFlags) stack(stackName, body string, ctx api.StackOptions) error {
+	if err := api.LintTemplate(body); err != nil {
+		return err
+	}
+	if err := f.write(stackName+".yml", []byte(body)); err != nil {
+		return err
+	}
+
+	keys := []string{}
+	for k := range ctx.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := []renderedParameter{}
+	for _, k := range keys {
+		params = append(params, renderedParameter{k, ctx.Params[k]})
+	}
+	// placeholders are written as <VpcId of stack> rather than escaped
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(params); err != nil {
+		return err
+	}
+	// parameters can hold secrets, like the docker hub password of a cluster
+	return f.writeMode(stackName+".parameters.json", b.Bytes(), 0600)
+}
+
+// taskDefinition writes the json a task definition would be registered with as
+// <family>.taskdef.json
+func (f renderFlags) taskDefinition(input *ecs.RegisterTaskDefinitionInput, names api.PortNames, fields api.ContainerFields) error {
+	b, err := api.TaskDefinitionJSON(input, names, fields)
+	if err != nil {
+		return err
+	}
+	return f.write(aws.StringValue(input.Family)+".taskdef.json", b)
+}
+
+// rawTaskDefinition writes the json of a task definition file that would be registered
+func (f renderFlags) rawTaskDefinition(family string, body []byte) error {
+	b, err := api.RawTaskDefinitionJSON(body)
+	if err != nil {
+		return err
+	}
+	return f.write(family+".taskdef.json", b)
+}
+
+func (f renderFlags) write(name string, b []byte) error {
+	return f.writeMode(name, b, 0644)
+}
+
+func (f renderFlags) writeMode(name string, b []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(f.Dir, name)
+	log.Printf("Rendering %s", path)
+	if !bytes.HasSuffix(b, []byte("\n")) {
+		b = append(b, '\n')
+	}
+	// the mode of a file that's rendered again is kept by WriteFile, so it's set explicitly
+	if err := ioutil.WriteFile(path, b, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// renderedValue stands in for a value that is only known once something that is rendered
+// has been created, such as the outputs of a stack
+func renderedValue(what, of string) string {
+	return fmt.Sprintf("<%s of %s>", what, of)
+}
+
+// renderedOutputs stands in for the outputs of a stack created from a template
+func renderedOutputs(stackName, body string) map[string]string {
+	outputs := map[string]string{}
+	for _, name := range templates.OutputNames(body) {
+		outputs[name] = renderedValue(name, stackName)
+	}
+	return outputs
+}
+
+// renderedNetwork stands in for the outputs of a cluster's network stack
+func renderedNetwork(cluster string) api.NetworkOutputs {
+	stackName := cluster + "-network"
+	outputs := renderedOutputs(stackName, templates.NetworkStack())
+	return api.NetworkOutputs{
+		StackName:      stackName,
+		VpcId:          outputs["VpcId"],
+		Subnet0Public:  outputs["Subnet0Public"],
+		Subnet1Public:  outputs["Subnet1Public"],
+		Subnet2Private: outputs["Subnet2Private"],
+		Subnet3Private: outputs["Subnet3Private"],
+	}
+}
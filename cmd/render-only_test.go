@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// TestRenderOnlyIsOffline renders with services that have no clients, so that any call to
+// AWS fails the test
+func TestRenderOnlyIsOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy-render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "rendered")
+	config := filepath.Join(dir, "ecsy.yml")
+	compose := "../examples/helloworld/docker-compose.yml"
+
+	for _, args := range [][]string{
+		{"create-cluster", "--cluster", "example", "--docker-password", "hunter2"},
+		{"create-service", "--cluster", "example", "-p", "helloworld", "-f", compose, "--service-connect"},
+		{"deploy", "--cluster", "example", "-p", "helloworld", "-f", compose},
+	} {
+		app := kingpin.New("ecsy", "")
+		ConfigureCreateCluster(app, api.Services{})
+		ConfigureCreateService(app, api.Services{})
+		ConfigureDeploy(app, api.Services{})
+		if _, err = app.Parse(append(args, "--config", config, "--render-only", out)); err != nil {
+			t.Fatalf("Failed to render %s: %v", args[0], err)
+		}
+	}
+
+	for name, mode := range map[string]os.FileMode{
+		"example-network.yml":                            0644,
+		"ecs-example-cluster.yml":                        0644,
+		"ecs-example-cluster.parameters.json":            0600,
+		"ecs-example-example-namespace.yml":              0644,
+		"ecs-example-helloworld-service.parameters.json": 0600,
+		"helloworld.taskdef.json":                        0644,
+	} {
+		info, err := os.Stat(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("Expected %s to be written with %v, got %v", name, mode, info.Mode().Perm())
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(out, "ecs-example-helloworld-service.parameters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), renderedValue("VpcId", "example-network")) {
+		t.Fatalf("Expected the network's outputs to be placeholders, got %s", b)
+	}
+}
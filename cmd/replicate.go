@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureReplicate(app *kingpin.Application, svc api.Services) {
+	var cluster, to string
+	var replacements map[string]string
+
+	cmd := app.Command("replicate", "Create an equivalent cluster and services in another region")
+	cmd.Flag("cluster", "The name of the ECS cluster to replicate").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Flag("to", "The region to replicate the cluster to").
+		Required().
+		StringVar(&to)
+
+	cmd.Flag("map", "Replace a value from the source region with one for the target, in the form old=new").
+		StringMapVar(&replacements)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		from := os.Getenv("AWS_REGION")
+		if from == to {
+			return fmt.Errorf("Cluster %s is already in %s", cluster, to)
+		}
+
+		log.Printf("Exporting cluster %s from %s", cluster, from)
+		backup, err := api.ExportCluster(svc.Cloudformation, svc.ECS, cluster, from)
+		if err != nil {
+			return err
+		}
+
+		target, err := api.RegionServices(to)
+		if err != nil {
+			return err
+		}
+
+		r := api.Replacements{}
+		for k, v := range replacements {
+			r[k] = v
+		}
+
+		unmapped, err := translateForRegion(svc, target, backup, to, r)
+		if err != nil {
+			return err
+		}
+
+		timer := time.Now()
+		warnings, err := restoreCluster(target, backup, to, r)
+		unmapped = append(unmapped, warnings...)
+
+		if len(unmapped) > 0 {
+			log.Printf("Couldn't map %d values to %s:", len(unmapped), to)
+			for _, w := range unmapped {
+				log.Printf("  %s", w)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Cluster %s replicated to %s in %s", cluster, to, time.Now().Sub(timer).String())
+		return nil
+	})
+}
+
+// translateForRegion adds replacements for the region specific values in a backup that
+// have known equivalents in another region, failing if a stack can't be created there
+func translateForRegion(source, target api.Services, backup *api.ClusterBackup, to string, r api.Replacements) ([]string, error) {
+	unmapped := []string{}
+
+	for _, stack := range backup.Stacks {
+		if !api.TemplateHasAMI(stack.Template, to) {
+			return unmapped, fmt.Errorf("Stack %s has no AMI for %s", stack.Name, to)
+		}
+
+		for k, v := range stack.Parameters {
+			if _, ok := r[v]; ok || !strings.HasPrefix(v, "arn:aws:acm:"+backup.Region+":") {
+				continue
+			}
+			arn, ok, err := api.FindMatchingCertificate(source.ACM, target.ACM, v)
+			if err != nil {
+				return unmapped, err
+			}
+			if !ok {
+				unmapped = append(unmapped, fmt.Sprintf("No certificate in %s matches %s (%s of %s)", to, v, k, stack.Name))
+				continue
+			}
+			log.Printf("Mapped certificate %s to %s", v, arn)
+			r[v] = arn
+		}
+	}
+
+	for _, td := range backup.TaskDefinitions {
+		for _, def := range td.ContainerDefinitions {
+			image := aws.StringValue(def.Image)
+			if translated, ok := api.TranslateECRImage(image, backup.Region, to); ok {
+				if _, exists := r[image]; !exists {
+					log.Printf("Mapped image %s to %s, it must be replicated to %s", image, translated, to)
+					r[image] = translated
+				}
+			}
+		}
+	}
+
+	return unmapped, nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+)
+
+// serviceStackEnvironment are service stack outputs that are passed to containers
+var serviceStackEnvironment = []struct {
+	Output, Name string
+}{
+	{"AssetsBucket", "ASSETS_BUCKET"},
+	{"AssetsURL", "ASSETS_URL"},
+}
+
+// linkedResource is a kind of companion stack in a cluster that services can be linked to,
+// with the stack outputs passed to containers prefixed with the resource name
+type linkedResource struct {
+	Output      string
+	StackName   func(cluster, name string) string
+	Environment []struct {
+		Output, Suffix string
+	}
+}
+
+var linkedResources = []linkedResource{
+	{
+		Output:    "Databases",
+		StackName: dbStackName,
+		Environment: []struct{ Output, Suffix string }{
+			{"Host", "HOST"},
+			{"Port", "PORT"},
+			{"DatabaseName", "NAME"},
+			{"SecretArn", "SECRET_ARN"},
+		},
+	},
+	{
+		Output:    "Caches",
+		StackName: cacheStackName,
+		Environment: []struct{ Output, Suffix string }{
+			{"Host", "HOST"},
+			{"Port", "PORT"},
+			{"ReaderHost", "READER_HOST"},
+			{"URL", "URL"},
+		},
+	},
+}
+
+// serviceResources are the task role and environment for the resources a service stack
+// provides, or the cluster resources it's linked to
+type serviceResources struct {
+	TaskRoleArn string
+	Environment []*ecs.KeyValuePair
+}
+
+func findServiceResources(svc api.Services, cluster string, outputs map[string]string) (serviceResources, error) {
+	r := serviceResources{
+		TaskRoleArn: outputs["TaskRoleArn"],
+	}
+
+	for _, env := range serviceStackEnvironment {
+		if value, ok := outputs[env.Output]; ok {
+			r.add(env.Name, value)
+		}
+	}
+
+	for _, name := range splitList(outputs["Queues"]) {
+		r.add(envPrefix(name)+"_QUEUE_URL", outputs[templates.QueueOutput(name)])
+	}
+	for _, name := range splitList(outputs["Topics"]) {
+		r.add(envPrefix(name)+"_TOPIC_ARN", outputs[templates.TopicOutput(name)])
+	}
+
+	for _, linked := range linkedResources {
+		for _, name := range splitList(outputs[linked.Output]) {
+			linkedOutputs, err := api.StackOutputs(svc.Cloudformation, linked.StackName(cluster, name))
+			if err != nil {
+				return r, err
+			}
+			for _, env := range linked.Environment {
+				r.add(envPrefix(name)+"_"+env.Suffix, linkedOutputs[env.Output])
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r *serviceResources) add(name, value string) {
+	r.Environment = append(r.Environment, &ecs.KeyValuePair{
+		Name:  aws.String(name),
+		Value: aws.String(value),
+	})
+}
+
+// apply sets the task role and environment on a task definition, without overriding
+// anything already set in it, returning whether anything changed
+func (r serviceResources) apply(input *ecs.RegisterTaskDefinitionInput) bool {
+	var changed bool
+
+	if r.TaskRoleArn != "" && input.TaskRoleArn == nil {
+		input.TaskRoleArn = aws.String(r.TaskRoleArn)
+		changed = true
+	}
+
+	for _, def := range input.ContainerDefinitions {
+		for _, kv := range r.Environment {
+			if !hasEnvironment(def, *kv.Name) {
+				def.Environment = append(def.Environment, kv)
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+func hasEnvironment(def *ecs.ContainerDefinition, key string) bool {
+	for _, kv := range def.Environment {
+		if aws.StringValue(kv.Name) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// envPrefix turns a resource name like main-db into MAIN_DB
+func envPrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
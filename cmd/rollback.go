@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const alarmPollInterval = 15 * time.Second
+
+// rollbackFlags are the alarms a deploy watches, rolling back if any of them fire
+type rollbackFlags struct {
+	Alarms []string
+	Watch  time.Duration
+}
+
+func (f *rollbackFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("rollback-on-alarm", "A cloudwatch alarm that rolls the service back to its previous task definition if it fires during the deploy (repeatable)").
+		StringsVar(&f.Alarms)
+
+	cmd.Flag("alarm-watch", "How long to keep watching alarms after the service reaches a steady state").
+		Default("5m").
+		DurationVar(&f.Watch)
+}
+
+// errAlarmFired is returned when a watched alarm fires during a deploy
+type errAlarmFired struct {
+	Alarm api.Alarm
+}
+
+func (e *errAlarmFired) Error() string {
+	return fmt.Sprintf("Alarm %s fired: %s", e.Alarm.Name, e.Alarm.Reason)
+}
+
+func (e *errAlarmFired) FailureKind() failure.Kind {
+	return failure.DeployHealth
+}
+
+// errRolledBack is returned when a deploy failed and the service was rolled back to the
+// task definition it ran before
+type errRolledBack struct {
+	Cause    error
+	Previous string
+}
+
+func (e *errRolledBack) Error() string {
+	return fmt.Sprintf("%v, rolled back to %s", e.Cause, e.Previous)
+}
+
+func (e *errRolledBack) Unwrap() error {
+	return e.Cause
+}
+
+func (e *errRolledBack) FailureKind() failure.Kind {
+	return failure.DeployHealth
+}
+
+// shouldRollback returns whether a deploy failed in a way that rolling back fixes, rather
+// than failing to roll out at all
+func shouldRollback(err error) bool {
+	switch err.(type) {
+	case *errAlarmFired, *errSmokeTestFailed:
+		return true
+	}
+	return false
+}
+
+// alarmWatcher checks alarms at most every alarmPollInterval, ignoring any that were
+// already firing when the deploy started
+type alarmWatcher struct {
+	svc       api.Services
+	alarms    []string
+	ignored   map[string]bool
+	lastCheck time.Time
+}
+
+func newAlarmWatcher(svc api.Services, names []string) (*alarmWatcher, error) {
+	w := &alarmWatcher{svc: svc, alarms: names, ignored: map[string]bool{}}
+	if len(names) == 0 {
+		return w, nil
+	}
+
+	alarms, err := svc.CloudWatch.DescribeAlarms(names)
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]bool{}
+	for _, a := range alarms {
+		found[a.Name] = true
+		if a.State == api.AlarmStateAlarm {
+			log.Printf("Warning: alarm %s is already firing, it won't trigger a rollback", a.Name)
+			w.ignored[a.Name] = true
+		}
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("No alarm named %q exists", name)
+		}
+	}
+
+	w.lastCheck = time.Now()
+	return w, nil
+}
+
+// check returns an errAlarmFired if one of the alarms has fired since the last check
+func (w *alarmWatcher) check() error {
+	if len(w.alarms) == 0 || time.Now().Sub(w.lastCheck) < alarmPollInterval {
+		return nil
+	}
+	w.lastCheck = time.Now()
+
+	alarms, err := w.svc.CloudWatch.DescribeAlarms(w.alarms)
+	if err != nil {
+		return err
+	}
+	for _, a := range alarms {
+		if a.State == api.AlarmStateAlarm && !w.ignored[a.Name] {
+			return &errAlarmFired{a}
+		} else if a.State != api.AlarmStateAlarm {
+			// an alarm that recovers and fires again is caused by the deploy
+			delete(w.ignored, a.Name)
+		}
+	}
+	return nil
+}
+
+// watch checks the alarms until a duration has passed or ctx is done
+func (w *alarmWatcher) watch(ctx context.Context, d time.Duration) error {
+	if len(w.alarms) == 0 || d <= 0 {
+		return nil
+	}
+	log.Printf("Watching alarms for %s", d)
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		if err := w.check(); err != nil {
+			return err
+		}
+		if err := api.Sleep(ctx, time.Second, "alarms", "none had fired"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackService reverts a service to a previous task definition after an alarm fired,
+// under the lock of the service's task family
+func rollbackService(ctx context.Context, svc api.Services, cluster, family, service, previous string, cause error) error {
+	log.Printf("%v, rolling back to %s", cause, previous)
+
+	err := withServiceLock(ctx, svc, cluster, family, false, func(ctx context.Context) error {
+		_, err := svc.ECS.UpdateService(&ecs.UpdateServiceInput{
+			Service:        aws.String(service),
+			Cluster:        aws.String(cluster),
+			TaskDefinition: aws.String(previous),
+		})
+		if err != nil {
+			return err
+		}
+		return api.PollUntilTaskDeployed(ctx, svc.ECS, cluster, service, previous, serviceEventPrinter(cluster, service))
+	})
+	if err != nil {
+		return failure.Errorf(failure.DeployHealth, "%v, and failed to roll back: %v", cause, err)
+	}
+	return &errRolledBack{Cause: cause, Previous: previous}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+	"github.com/lox/ecsy/failure"
+)
+
+func TestAlarmWatcherIgnoresAlarmsAlreadyFiring(t *testing.T) {
+	fake := apitest.New()
+	fake.CloudWatch.SetAlarm(api.Alarm{Name: "errors", State: "OK"})
+	fake.CloudWatch.SetAlarm(api.Alarm{Name: "latency", State: api.AlarmStateAlarm})
+
+	w, err := newAlarmWatcher(fake.Services(), []string{"errors", "latency"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.lastCheck = time.Time{}
+	if err := w.check(); err != nil {
+		t.Fatalf("Expected an alarm that was already firing to be ignored, got %v", err)
+	}
+
+	// an ignored alarm that recovers and fires again is caused by the deploy
+	fake.CloudWatch.SetAlarm(api.Alarm{Name: "latency", State: "OK"})
+	w.lastCheck = time.Time{}
+	if err := w.check(); err != nil {
+		t.Fatal(err)
+	}
+	fake.CloudWatch.SetAlarm(api.Alarm{Name: "latency", State: api.AlarmStateAlarm, Reason: "p99 too high"})
+	w.lastCheck = time.Time{}
+	var fired *errAlarmFired
+	if err := w.check(); !errors.As(err, &fired) || fired.Alarm.Name != "latency" {
+		t.Fatalf("Expected the latency alarm to fire, got %v", err)
+	}
+}
+
+func TestAlarmWatcherChecksAtMostEveryInterval(t *testing.T) {
+	fake := apitest.New()
+	fake.CloudWatch.SetAlarm(api.Alarm{Name: "errors", State: "OK"})
+
+	w, err := newAlarmWatcher(fake.Services(), []string{"errors"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.CloudWatch.SetAlarm(api.Alarm{Name: "errors", State: api.AlarmStateAlarm})
+	if err := w.check(); err != nil {
+		t.Fatalf("Expected no check within the poll interval, got %v", err)
+	}
+	w.lastCheck = time.Now().Add(-alarmPollInterval)
+	if err := w.check(); err == nil {
+		t.Fatalf("Expected the errors alarm to fire")
+	}
+}
+
+func TestAlarmWatcherRequiresAlarmsToExist(t *testing.T) {
+	fake := apitest.New()
+	if _, err := newAlarmWatcher(fake.Services(), []string{"missing"}); err == nil {
+		t.Fatalf("Expected an error for an alarm that doesn't exist")
+	}
+}
+
+func TestRollbackServiceReturnsRolledBack(t *testing.T) {
+	fake := apitest.New()
+	svc, _ := lockedServices(t, fake)
+	fake.ECS.AddService("example", "app", "app:2", 1)
+
+	err := rollbackService(context.Background(), svc, "example", "app", "app", "app:1", &errAlarmFired{api.Alarm{Name: "errors"}})
+	var rolledBack *errRolledBack
+	if !errors.As(err, &rolledBack) || rolledBack.Previous != "app:1" {
+		t.Fatalf("Expected the service to be rolled back to app:1, got %v", err)
+	}
+	if kind := failure.KindOf(err); kind != failure.DeployHealth {
+		t.Fatalf("Expected a rollback to be a deploy health failure, got %s", kind)
+	}
+}
+
+func TestRollbackServiceTakesTheLock(t *testing.T) {
+	fake := apitest.New()
+	svc, table := lockedServices(t, fake)
+	fake.ECS.AddService("example", "app", "app:2", 1)
+	table.owners["example/app"] = "someone else"
+
+	err := rollbackService(context.Background(), svc, "example", "app", "app", "app:1", &errAlarmFired{api.Alarm{Name: "errors"}})
+	if err == nil || !strings.Contains(err.Error(), "failed to roll back: example/app is locked by someone else") {
+		t.Fatalf("Expected the rollback to be blocked by the lock, got %v", err)
+	}
+}
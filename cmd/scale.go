@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureScale(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, service string
+	var count int64
+	var forceUnlock bool
+
+	cmd := app.Command("scale", "Change the number of tasks a service runs")
+	cmd.Flag("cluster", "The name of the ECS cluster the service is in").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service or compose project to scale").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	env.configure(cmd)
+
+	cmd.Flag("force-unlock", "Remove an existing lock on the service before scaling").
+		BoolVar(&forceUnlock)
+
+	cmd.Arg("count", "The desired number of tasks").
+		Required().
+		Int64Var(&count)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		serviceName, err := resolveServiceName(svc, cluster, service)
+		if err != nil {
+			return err
+		}
+		family, err := resolveServiceFamily(svc, cluster, serviceName)
+		if err != nil {
+			return err
+		}
+
+		timer := time.Now()
+		log.Printf("Scaling service %s on %s to %d tasks", serviceName, cluster, count)
+
+		err = withServiceLock(commandContext(), svc, cluster, family, forceUnlock, func(ctx context.Context) error {
+			_, err := svc.ECS.UpdateService(&ecs.UpdateServiceInput{
+				Service:      aws.String(serviceName),
+				Cluster:      aws.String(cluster),
+				DesiredCount: aws.Int64(count),
+			})
+			if err != nil {
+				return err
+			}
+
+			var printer = func(e *ecs.ServiceEvent) {
+				log.Println(*e.Message)
+			}
+
+			log.Printf("Waiting for service to reach a steady state.")
+			return api.PollUntilServiceStable(ctx, svc.ECS, cluster, serviceName, printer)
+		})
+		if err != nil {
+			return err
+		}
+
+		notifiersFromConfig(svc, cfg).Notify(notify.Event{
+			Type:         notify.ServiceScaled,
+			Cluster:      cluster,
+			Service:      family,
+			DesiredCount: aws.Int64(count),
+			Duration:     time.Now().Sub(timer),
+		})
+
+		log.Printf("Scaled in %s", time.Now().Sub(timer).String())
+		return nil
+	})
+}
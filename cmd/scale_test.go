@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/api/apitest"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// TestScaleNotifiesByFamily scales a service whose name isn't its task family, like those
+// created by stacks, and expects the notification to name the family as deploys do
+func TestScaleNotifiesByFamily(t *testing.T) {
+	fake := apitest.New()
+	svc := fake.Services()
+	fake.CloudFormation.SetOutputs("ecs-example-cluster", map[string]string{
+		"StackType":  "ecs-former::ecs-stack",
+		"ECSCluster": "example",
+	})
+	if err := api.CreateStack(svc.Cloudformation, "ecs-example-cluster", testTemplate, api.StackOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	fake.ECS.AddService("example", "example-web-Service-1ABC", "arn:aws:ecs:us-east-1:123456789012:task-definition/web:3", 2)
+
+	buf := withEvents(t)
+	app := kingpin.New("ecsy", "")
+	ConfigureScale(app, svc)
+	args := []string{"scale", "--cluster", "example", "--service", "example-web-Service-1ABC", "--config", filepath.Join(t.TempDir(), "ecsy.yml"), "4"}
+	if _, err := app.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	var scaled map[string]interface{}
+	for _, e := range decodeEvents(t, buf) {
+		if e["type"] == "service_scaled" {
+			scaled = e
+		}
+	}
+	if scaled == nil {
+		t.Fatalf("Expected a service_scaled event, got %s", buf.String())
+	}
+	if scaled["service"] != "web" || scaled["desired_count"] != float64(4) {
+		t.Errorf("Expected web to be scaled to 4, got %v", scaled)
+	}
+}
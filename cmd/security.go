@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// ingressFlags are the sources and ports allowed into a cluster or service security group
+type ingressFlags struct {
+	CIDRs          []string
+	SecurityGroups []string
+	Ports          []string
+}
+
+func (f *ingressFlags) configure(cmd *kingpin.CmdClause, what string) {
+	cmd.Flag("allow-cidr", fmt.Sprintf("A CIDR allowed to access the %s, replaces the default ingress (repeatable)", what)).
+		StringsVar(&f.CIDRs)
+
+	cmd.Flag("allow-sg", fmt.Sprintf("A security group allowed to access the %s, replaces the default ingress (repeatable)", what)).
+		StringsVar(&f.SecurityGroups)
+
+	cmd.Flag("service-port", "A port or range like 8000-8100 to allow ingress on (repeatable)").
+		StringsVar(&f.Ports)
+}
+
+// restricted is whether the default ingress in the template should be replaced
+func (f *ingressFlags) restricted() bool {
+	return len(f.CIDRs) > 0 || len(f.SecurityGroups) > 0 || len(f.Ports) > 0
+}
+
+// rules returns an ingress rule for each source and port, falling back to the provided
+// defaults when either sources or ports aren't given
+func (f *ingressFlags) rules(defaultCIDR string, defaultPorts ...string) ([]templates.IngressRule, error) {
+	if !f.restricted() {
+		return nil, nil
+	}
+
+	cidrs := f.CIDRs
+	if len(cidrs) == 0 && len(f.SecurityGroups) == 0 {
+		cidrs = []string{defaultCIDR}
+	}
+
+	ports := f.Ports
+	if len(ports) == 0 {
+		ports = defaultPorts
+	}
+
+	rules := []templates.IngressRule{}
+	for _, port := range ports {
+		from, to, err := parsePortRange(port)
+		if err != nil {
+			return nil, err
+		}
+		for _, cidr := range cidrs {
+			rules = append(rules, templates.IngressRule{FromPort: from, ToPort: to, CidrIP: cidr})
+		}
+		for _, sg := range f.SecurityGroups {
+			rules = append(rules, templates.IngressRule{FromPort: from, ToPort: to, SourceGroupID: sg})
+		}
+	}
+	return rules, nil
+}
+
+func parsePortRange(s string) (int, int, error) {
+	pieces := strings.SplitN(s, "-", 2)
+	from, err := strconv.Atoi(pieces[0])
+	if err != nil || from < 1 || from > 65535 {
+		return 0, 0, fmt.Errorf("Invalid port %q", s)
+	}
+	if len(pieces) == 1 {
+		return from, from, nil
+	}
+	to, err := strconv.Atoi(pieces[1])
+	if err != nil || to < from || to > 65535 {
+		return 0, 0, fmt.Errorf("Invalid port range %q", s)
+	}
+	return from, to, nil
+}
+
+func ConfigureShowSecurity(app *kingpin.Application, svc api.Services) {
+	var cluster string
+
+	cmd := app.Command("show-security", "List the effective ingress rules for a cluster and its services")
+	cmd.Flag("cluster", "The name of the ECS cluster").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+
+		for _, stack := range stacks {
+			resources, err := api.StackResources(svc.Cloudformation, *stack.StackName)
+			if err != nil {
+				return err
+			}
+
+			ids := []string{}
+			for _, r := range resources {
+				if *r.ResourceType == "AWS::EC2::SecurityGroup" && r.PhysicalResourceId != nil {
+					ids = append(ids, *r.PhysicalResourceId)
+				}
+			}
+			if len(ids) == 0 {
+				continue
+			}
+
+			groups, err := svc.EC2.DescribeSecurityGroups(ids)
+			if err != nil {
+				return err
+			}
+
+			for _, g := range groups {
+				log.Printf("%s %s (%s)", *stack.StackName, g.ID, g.Description)
+				if len(g.Ingress) == 0 {
+					log.Printf("  no ingress")
+				}
+				for _, r := range g.Ingress {
+					log.Printf("  %s", formatSecurityGroupRule(r))
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func formatSecurityGroupRule(r api.SecurityGroupRule) string {
+	source := r.CidrIP
+	if r.SourceGroupID != "" {
+		source = r.SourceGroupID
+	}
+	switch {
+	case r.Protocol == "-1":
+		return fmt.Sprintf("all traffic from %s", source)
+	case r.FromPort == r.ToPort:
+		return fmt.Sprintf("%s %d from %s", r.Protocol, r.FromPort, source)
+	default:
+		return fmt.Sprintf("%s %d-%d from %s", r.Protocol, r.FromPort, r.ToPort, source)
+	}
+}
@@ -0,0 +1,547 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/notify"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+const (
+	serveDeployRunning   = "running"
+	serveDeploySucceeded = "succeeded"
+	serveDeployFailed    = "failed"
+
+	// serveMaxDeploys is how many deploys are kept to be looked up, the oldest finished
+	// deploys are forgotten after that
+	serveMaxDeploys = 500
+
+	// maxServeRequestBytes is the largest deploy request body that's read
+	maxServeRequestBytes = 1 << 20
+)
+
+// serveDeploy is a deploy or rollback requested from ecsy serve
+type serveDeploy struct {
+	ID             string            `json:"id"`
+	Cluster        string            `json:"cluster"`
+	Service        string            `json:"service"`
+	Rollback       bool              `json:"rollback,omitempty"`
+	RequestedBy    string            `json:"requested_by,omitempty"`
+	State          string            `json:"state"`
+	TaskDefinition string            `json:"task_definition,omitempty"`
+	Previous       string            `json:"previous_task_definition,omitempty"`
+	Images         map[string]string `json:"images,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	StartedAt      time.Time         `json:"started_at"`
+	FinishedAt     *time.Time        `json:"finished_at,omitempty"`
+}
+
+// serveDeployRequest is the body of a deploy or rollback, which are all optional. A deploy
+// without images or a tag redeploys the current task definition, and a rollback without a
+// task definition goes back to the one before the last deploy.
+type serveDeployRequest struct {
+	Images         map[string]string `json:"images"`
+	Tag            string            `json:"tag"`
+	TaskDefinition string            `json:"task_definition"`
+	RequestedBy    string            `json:"requested_by"`
+}
+
+type serveServiceStatus struct {
+	Cluster        string                `json:"cluster"`
+	Service        string                `json:"service"`
+	Status         string                `json:"status"`
+	TaskDefinition string                `json:"task_definition"`
+	DesiredCount   int64                 `json:"desired_count"`
+	RunningCount   int64                 `json:"running_count"`
+	Deployments    []serveDeploymentInfo `json:"deployments"`
+	LastDeploy     *serveDeploy          `json:"last_deploy,omitempty"`
+}
+
+type serveDeploymentInfo struct {
+	Status         string `json:"status"`
+	TaskDefinition string `json:"task_definition"`
+	DesiredCount   int64  `json:"desired_count"`
+	RunningCount   int64  `json:"running_count"`
+}
+
+// server is an http api for deploying, rolling back and checking on services, so that
+// chatops bots and CI systems don't each need aws credentials
+type server struct {
+	svc      api.Services
+	cfg      *config.Config
+	token    string
+	clusters []string
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	deploys map[string]*serveDeploy
+	order   []string
+	last    map[string]*serveDeploy
+	next    int
+}
+
+func ConfigureServe(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var listen, token, tlsCert, tlsKey string
+	var clusters []string
+	var timeout time.Duration
+
+	cmd := app.Command("serve", "Run an http api for deploying and rolling back services, for chatops and CI")
+	cmd.Flag("listen", "The address to listen on, only localhost by default. Use --tls-cert to listen on other interfaces").
+		Default("localhost:8080").
+		StringVar(&listen)
+
+	cmd.Flag("tls-cert", "A certificate file to serve https with, so that tokens aren't sent in the clear").
+		StringVar(&tlsCert)
+
+	cmd.Flag("tls-key", "The private key file of --tls-cert").
+		StringVar(&tlsKey)
+
+	cmd.Flag("token", "The bearer token that requests must have, set it with ECSY_SERVE_TOKEN rather than on the command line").
+		Envar("ECSY_SERVE_TOKEN").
+		Required().
+		StringVar(&token)
+
+	cmd.Flag("cluster", "A cluster that services can be deployed to, any cluster by default (repeatable)").
+		StringsVar(&clusters)
+
+	cmd.Flag("deploy-timeout", "How long a deploy can take before it fails").
+		Default("30m").
+		DurationVar(&timeout)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if token == "" {
+			return fmt.Errorf("A --token or ECSY_SERVE_TOKEN is required")
+		}
+		if (tlsCert == "") != (tlsKey == "") {
+			return fmt.Errorf("--tls-cert and --tls-key must be set together")
+		}
+		if tlsCert == "" && !isLocalAddress(listen) {
+			return fmt.Errorf("Listening on %s needs --tls-cert and --tls-key, so that tokens aren't sent in the clear", listen)
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		s := &server{
+			svc:      svc,
+			cfg:      cfg,
+			token:    token,
+			clusters: clusters,
+			timeout:  timeout,
+			deploys:  map[string]*serveDeploy{},
+			last:     map[string]*serveDeploy{},
+		}
+		httpServer := &http.Server{
+			Addr:              listen,
+			Handler:           s,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(stop)
+
+		errs := make(chan error, 1)
+		go func() {
+			if tlsCert != "" {
+				errs <- httpServer.ListenAndServeTLS(tlsCert, tlsKey)
+				return
+			}
+			errs <- httpServer.ListenAndServe()
+		}()
+		log.Printf("Listening on %s", listen)
+
+		select {
+		case err = <-errs:
+			return err
+		case <-stop:
+		}
+
+		// running deploys are finished so that services aren't left locked
+		log.Printf("Shutting down, waiting for running deploys to finish")
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err = httpServer.Shutdown(ctx); err != nil {
+			return err
+		}
+		s.wg.Wait()
+		return nil
+	})
+}
+
+// ServeHTTP routes
+//
+//	GET  /healthz
+//	GET  /deploys/<id>
+//	GET  /clusters/<cluster>/services/<service>
+//	POST /clusters/<cluster>/services/<service>/deploy
+//	POST /clusters/<cluster>/services/<service>/rollback
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		fmt.Fprintln(w, "ok")
+		return
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") || s.token == "" ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(s.token)) != 1 {
+		serveError(w, http.StatusUnauthorized, "A valid bearer token is required")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "deploys" && r.Method == http.MethodGet:
+		s.mu.Lock()
+		d, ok := s.deploys[parts[1]]
+		s.mu.Unlock()
+		if !ok {
+			serveError(w, http.StatusNotFound, "No deploy %s", parts[1])
+			return
+		}
+		s.writeDeploy(w, http.StatusOK, d)
+	case len(parts) >= 4 && parts[0] == "clusters" && parts[2] == "services":
+		cluster, service := parts[1], parts[3]
+		if len(s.clusters) > 0 && !containsString(s.clusters, cluster) {
+			serveError(w, http.StatusForbidden, "Deploys to cluster %s aren't allowed", cluster)
+			return
+		}
+		switch {
+		case len(parts) == 4 && r.Method == http.MethodGet:
+			s.status(w, cluster, service)
+		case len(parts) == 5 && r.Method == http.MethodPost && (parts[4] == "deploy" || parts[4] == "rollback"):
+			s.deploy(w, r, cluster, service, parts[4] == "rollback")
+		default:
+			serveError(w, http.StatusNotFound, "No route for %s %s", r.Method, r.URL.Path)
+		}
+	default:
+		serveError(w, http.StatusNotFound, "No route for %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func (s *server) status(w http.ResponseWriter, cluster, service string) {
+	stack, err := api.FindServiceStack(s.svc.Cloudformation, cluster, service)
+	if err != nil {
+		serveError(w, http.StatusNotFound, "%v", err)
+		return
+	}
+	outputs := api.StackOutputMap(stack)
+
+	ecsService, err := api.DescribeService(s.svc.ECS, outputs["ECSCluster"], outputs["ECSService"])
+	if err != nil {
+		serveError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+
+	status := serveServiceStatus{
+		Cluster:        cluster,
+		Service:        service,
+		Status:         aws.StringValue(ecsService.Status),
+		TaskDefinition: aws.StringValue(ecsService.TaskDefinition),
+		DesiredCount:   aws.Int64Value(ecsService.DesiredCount),
+		RunningCount:   aws.Int64Value(ecsService.RunningCount),
+		Deployments:    []serveDeploymentInfo{},
+	}
+	for _, d := range ecsService.Deployments {
+		status.Deployments = append(status.Deployments, serveDeploymentInfo{
+			Status:         aws.StringValue(d.Status),
+			TaskDefinition: aws.StringValue(d.TaskDefinition),
+			DesiredCount:   aws.Int64Value(d.DesiredCount),
+			RunningCount:   aws.Int64Value(d.RunningCount),
+		})
+	}
+
+	s.mu.Lock()
+	if last, ok := s.last[cluster+"/"+service]; ok {
+		copied := *last
+		status.LastDeploy = &copied
+	}
+	s.mu.Unlock()
+	serveJSON(w, http.StatusOK, status)
+}
+
+// deploy starts a deploy or rollback that runs in the background, responding with where
+// its progress can be followed
+func (s *server) deploy(w http.ResponseWriter, r *http.Request, cluster, service string, rollback bool) {
+	var req serveDeployRequest
+	if r.ContentLength != 0 {
+		body := http.MaxBytesReader(w, r.Body, maxServeRequestBytes)
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			serveError(w, http.StatusBadRequest, "Failed to parse request: %v", err)
+			return
+		}
+	}
+	if rollback && (len(req.Images) > 0 || req.Tag != "") {
+		serveError(w, http.StatusBadRequest, "A rollback can only set a task definition")
+		return
+	}
+
+	key := cluster + "/" + service
+	s.mu.Lock()
+	if last, ok := s.last[key]; ok && last.State == serveDeployRunning {
+		copied := *last
+		s.mu.Unlock()
+		s.writeDeploy(w, http.StatusConflict, &copied)
+		return
+	}
+
+	opts := api.DeployOptions{
+		Cluster:        cluster,
+		Service:        service,
+		TaskDefinition: req.TaskDefinition,
+		Images:         req.Images,
+		ImageTag:       req.Tag,
+	}
+	if rollback && opts.TaskDefinition == "" {
+		if last, ok := s.last[key]; ok && last.Previous != "" {
+			opts.TaskDefinition = last.Previous
+		}
+	}
+
+	s.next++
+	d := &serveDeploy{
+		ID:          strconv.Itoa(s.next),
+		Cluster:     cluster,
+		Service:     service,
+		Rollback:    rollback,
+		RequestedBy: req.RequestedBy,
+		State:       serveDeployRunning,
+		Images:      req.Images,
+		StartedAt:   time.Now(),
+	}
+	s.deploys[d.ID] = d
+	s.order = append(s.order, d.ID)
+	s.last[key] = d
+	s.forgetDeploys()
+	copied := *d
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+		s.run(d, opts)
+	}()
+	w.Header().Set("Location", "/deploys/"+d.ID)
+	s.writeDeploy(w, http.StatusAccepted, &copied)
+}
+
+// forgetDeploys drops the oldest finished deploys once there are more than serveMaxDeploys,
+// the last deploy of each service is still shown in its status
+func (s *server) forgetDeploys() {
+	excess := len(s.order) - serveMaxDeploys
+	kept := []string{}
+	for _, id := range s.order {
+		if excess > 0 && s.deploys[id].State != serveDeployRunning {
+			delete(s.deploys, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.order = kept
+}
+
+// run deploys a service whilst holding its lock, notifying and auditing like `ecsy deploy`
+func (s *server) run(d *serveDeploy, opts api.DeployOptions) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	prefix := fmt.Sprintf("[deploy %s] %s/%s:", d.ID, d.Cluster, d.Service)
+	opts.OnEvent = func(e *ecs.ServiceEvent) {
+		log.Printf("%s %s", prefix, aws.StringValue(e.Message))
+	}
+
+	notifiers := notifiersFromConfig(s.svc, s.cfg)
+	notifiers.Notify(notify.Event{
+		Type:    notify.DeployStarted,
+		Cluster: d.Cluster,
+		Service: d.Service,
+		Images:  d.Images,
+	})
+	log.Printf("%s Deploying for %s", prefix, requester(d.RequestedBy))
+
+	timer := time.Now()
+	var previous string
+	var result *api.DeployResult
+	err := withServiceLock(ctx, s.svc, d.Cluster, d.Service, false, func(ctx context.Context) (err error) {
+		if previous, err = currentTaskDefinition(s.svc, d.Cluster, d.Service); err != nil {
+			return err
+		}
+		if d.Rollback && opts.TaskDefinition == "" {
+			if opts.TaskDefinition, err = previousRevision(previous); err != nil {
+				return err
+			}
+		}
+		result, err = api.Deploy(ctx, s.svc, opts)
+		return err
+	})
+
+	event := notify.Event{
+		Cluster:  d.Cluster,
+		Service:  d.Service,
+		Images:   d.Images,
+		Duration: time.Now().Sub(timer),
+	}
+	if err != nil {
+		event.Type = notify.DeployFailed
+		event.Error = err.Error()
+		log.Printf("%s Failed: %v", prefix, err)
+	} else {
+		event.Type = notify.DeploySucceeded
+		if d.Rollback {
+			event.Type = notify.DeployRolledBack
+		}
+		event.Images = result.Images
+		event.TaskDefinition = result.TaskDefinitionArn
+		event.URL = result.URL
+		log.Printf("%s Deployed %s in %s", prefix, result.TaskDefinitionArn, event.Duration)
+	}
+	notifiers.Notify(event)
+	s.audit(d, result, err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	d.FinishedAt = &finished
+	d.Previous = previous
+	if err != nil {
+		d.State = serveDeployFailed
+		d.Error = err.Error()
+		return
+	}
+	d.State = serveDeploySucceeded
+	d.TaskDefinition = result.TaskDefinitionArn
+	d.Images = result.Images
+}
+
+// audit records a deploy in the audit log, with whoever the request said it was for
+func (s *server) audit(d *serveDeploy, result *api.DeployResult, err error) {
+	command := "serve deploy"
+	if d.Rollback {
+		command = "serve rollback"
+	}
+	args := []string{"--cluster", d.Cluster, d.Service}
+	names := []string{}
+	for name := range d.Images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, name+"="+d.Images[name])
+	}
+
+	r := api.AuditRecord{
+		Cluster:    d.Cluster,
+		RecordedAt: time.Now(),
+		Owner:      lockOwner(),
+		Command:    command,
+		Args:       args,
+	}
+	if d.RequestedBy != "" {
+		r.Owner = d.RequestedBy + " via " + r.Owner
+	}
+	if result != nil {
+		r.Resources = []string{result.TaskDefinitionArn}
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if r.Caller, err = api.CallerArn(s.svc.STS); err != nil {
+		log.Printf("Not writing an audit record: %v", err)
+		return
+	}
+	if err = api.WriteAuditRecord(s.svc.DynamoDB, r); err != nil && err != api.ErrTableNotFound {
+		log.Printf("Failed to write an audit record: %v", err)
+	}
+}
+
+func (s *server) writeDeploy(w http.ResponseWriter, code int, d *serveDeploy) {
+	s.mu.Lock()
+	copied := *d
+	s.mu.Unlock()
+	serveJSON(w, code, copied)
+}
+
+// currentTaskDefinition returns the arn of the task definition a service runs
+func currentTaskDefinition(svc api.Services, cluster, service string) (string, error) {
+	stack, err := api.FindServiceStack(svc.Cloudformation, cluster, service)
+	if err != nil {
+		return "", err
+	}
+	outputs := api.StackOutputMap(stack)
+	current, err := api.DescribeService(svc.ECS, outputs["ECSCluster"], outputs["ECSService"])
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(current.TaskDefinition), nil
+}
+
+// previousRevision returns the family:revision before a task definition arn
+func previousRevision(arn string) (string, error) {
+	name := arn[strings.LastIndex(arn, "/")+1:]
+	idx := strings.LastIndex(name, ":")
+	if idx == -1 {
+		return "", fmt.Errorf("Task definition %s has no revision", arn)
+	}
+	revision, err := strconv.Atoi(name[idx+1:])
+	if err != nil || revision <= 1 {
+		return "", fmt.Errorf("Task definition %s has no previous revision", arn)
+	}
+	return fmt.Sprintf("%s:%d", name[:idx], revision-1), nil
+}
+
+// isLocalAddress returns whether a listen address is only reachable from this host
+func isLocalAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func requester(requestedBy string) string {
+	if requestedBy == "" {
+		return "an unnamed requester"
+	}
+	return requestedBy
+}
+
+func serveJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func serveError(w http.ResponseWriter, code int, format string, v ...interface{}) {
+	serveJSON(w, code, map[string]string{"error": fmt.Sprintf(format, v...)})
+}
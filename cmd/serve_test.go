@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func testServer() *server {
+	return &server{
+		token:   "secret",
+		deploys: map[string]*serveDeploy{},
+		last:    map[string]*serveDeploy{},
+	}
+}
+
+func TestServeRequiresBearerToken(t *testing.T) {
+	for header, expected := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"secret":        http.StatusUnauthorized,
+		"Basic secret":  http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer ":       http.StatusUnauthorized,
+		"Bearer secret": http.StatusNotFound,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/deploys/1", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		testServer().ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Fatalf("Expected %d with authorization %q, got %d", expected, header, w.Code)
+		}
+	}
+}
+
+func TestServeRejectsEveryTokenWithoutOne(t *testing.T) {
+	s := testServer()
+	s.token = ""
+
+	r := httptest.NewRequest(http.MethodGet, "/deploys/1", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected an empty token to be refused, got %d", w.Code)
+	}
+}
+
+func TestServeConflictsWithRunningDeploy(t *testing.T) {
+	s := testServer()
+	s.last["default/app"] = &serveDeploy{ID: "1", Cluster: "default", Service: "app", State: serveDeployRunning}
+
+	r := httptest.NewRequest(http.MethodPost, "/clusters/default/services/app/deploy", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected a deploy whilst one is running to conflict, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestServeForgetsOldestFinishedDeploys(t *testing.T) {
+	s := testServer()
+	for i := 1; i <= serveMaxDeploys+2; i++ {
+		id := strconv.Itoa(i)
+		state := serveDeploySucceeded
+		if i == 1 {
+			state = serveDeployRunning
+		}
+		s.deploys[id] = &serveDeploy{ID: id, State: state}
+		s.order = append(s.order, id)
+	}
+	s.forgetDeploys()
+
+	if len(s.deploys) != serveMaxDeploys || len(s.order) != serveMaxDeploys {
+		t.Fatalf("Expected %d deploys, got %d", serveMaxDeploys, len(s.deploys))
+	}
+	for id, expected := range map[string]bool{"1": true, "2": false, "3": false, "4": true} {
+		if _, ok := s.deploys[id]; ok != expected {
+			t.Fatalf("Expected deploy %s to be kept: %v", id, expected)
+		}
+	}
+}
+
+func TestPreviousRevision(t *testing.T) {
+	for arn, expected := range map[string]string{
+		"arn:aws:ecs:us-east-1:123456789012:task-definition/app:3": "app:2",
+		"app:10": "app:9",
+		"app:1":  "",
+		"app":    "",
+		"app:x":  "",
+	} {
+		previous, err := previousRevision(arn)
+		if expected == "" {
+			if err == nil {
+				t.Fatalf("Expected no previous revision of %s, got %s", arn, previous)
+			}
+			continue
+		}
+		if err != nil || previous != expected {
+			t.Fatalf("Expected %s before %s, got %q (%v)", expected, arn, previous, err)
+		}
+	}
+}
+
+func TestIsLocalAddress(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"localhost:8080": true,
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.1:8080":  false,
+	} {
+		if local := isLocalAddress(addr); local != expected {
+			t.Fatalf("Expected %s local to be %v", addr, expected)
+		}
+	}
+}
+
+func TestServeRequiresTLSOffLocalhost(t *testing.T) {
+	for _, listen := range []string{":8080", "0.0.0.0:8080", "10.0.0.5:8080"} {
+		app := kingpin.New("ecsy", "")
+		ConfigureServe(app, api.Services{})
+		_, err := app.Parse([]string{"serve", "--token", "secret", "--listen", listen})
+		if err == nil || !strings.Contains(err.Error(), "needs --tls-cert") {
+			t.Errorf("Expected listening on %s without tls to fail, got %v", listen, err)
+		}
+	}
+}
+
+func TestServeLimitsRequestBodies(t *testing.T) {
+	body := `{"tag":"` + strings.Repeat("a", maxServeRequestBytes) + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/clusters/default/services/app/deploy", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	testServer().ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+		t.Fatalf("Expected an oversized request to be refused, got %d: %s", w.Code, w.Body)
+	}
+}
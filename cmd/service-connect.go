@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+)
+
+func namespaceStackName(cluster, namespace string) string {
+	return fmt.Sprintf("ecs-%s-%s-namespace", cluster, namespace)
+}
+
+// ensureNamespace returns the arn of a cluster's service connect namespace, creating a stack
+// for it the first time a service uses it. When rendering, the stack is rendered as it would
+// be created and the arn is a placeholder.
+func ensureNamespace(svc api.Services, cluster, namespace string, render renderFlags) (string, error) {
+	stackName := namespaceStackName(cluster, namespace)
+	ctx := api.StackOptions{
+		Params: map[string]string{
+			"ECSCluster": cluster,
+			"Namespace":  namespace,
+		},
+	}
+	if render.enabled() {
+		return renderedValue("NamespaceArn", stackName), render.stack(stackName, templates.EcsNamespace(), ctx)
+	}
+
+	existing, err := api.FindStacksByName(svc.Cloudformation, stackName)
+	if err != nil {
+		return "", err
+	}
+	if len(existing) > 0 && api.IsStackInProgress(existing[0]) {
+		log.Printf("Namespace stack %s is already %s, resuming", stackName, *existing[0].StackStatus)
+		if err := waitForStack(svc, stackName); err != nil {
+			return "", err
+		}
+		if existing, err = api.FindStacksByName(svc.Cloudformation, stackName); err != nil {
+			return "", err
+		}
+	}
+	if len(existing) > 0 {
+		if arn, ok := api.GetStackOutputByKey(existing[0], "NamespaceArn"); ok {
+			return arn, nil
+		}
+		return "", fmt.Errorf("Stack %s has no NamespaceArn output", stackName)
+	}
+
+	timer := time.Now()
+	log.Printf("Creating namespace cloudformation stack %s", stackName)
+
+	err = api.CreateStack(svc.Cloudformation, stackName, templates.EcsNamespace(), ctx)
+	if err != nil {
+		return "", err
+	}
+
+	err = waitForStack(svc, stackName)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Stack %s finished in %s", stackName, time.Now().Sub(timer).String())
+
+	outputs, err := api.StackOutputs(svc.Cloudformation, stackName)
+	if err != nil {
+		return "", err
+	}
+	return outputs["NamespaceArn"], nil
+}
+
+// serviceConnectPort picks the port mapping other services connect to, preferring one
+// mapped to a host port like the load balancer uses. Services without one are only clients.
+func serviceConnectPort(defs []*ecs.ContainerDefinition) (string, int64, bool) {
+	var container string
+	var port int64
+	for _, def := range defs {
+		for _, mapping := range def.PortMappings {
+			if mapping.ContainerPort == nil {
+				continue
+			}
+			if aws.Int64Value(mapping.HostPort) != 0 {
+				return *def.Name, *mapping.ContainerPort, true
+			}
+			if container == "" {
+				container, port = *def.Name, *mapping.ContainerPort
+			}
+		}
+	}
+	return container, port, container != ""
+}
+
+// servicePortNames returns the port names that a service stack connects through, naming
+// whichever container maps the stack's service connect port
+func servicePortNames(stack *cloudformation.Stack, defs []*ecs.ContainerDefinition) api.PortNames {
+	name, _ := api.GetStackParameterByKey(stack, "ServiceConnectPortName")
+	param, _ := api.GetStackParameterByKey(stack, "ServiceConnectPort")
+	port, err := strconv.ParseInt(param, 10, 64)
+	if name == "" || err != nil {
+		return nil
+	}
+
+	for _, def := range defs {
+		for _, mapping := range def.PortMappings {
+			if aws.Int64Value(mapping.ContainerPort) == port {
+				return api.PortNames{*def.Name: {port: name}}
+			}
+		}
+	}
+	log.Printf("No container maps port %d for service connect, other services won't reach this one", port)
+	return nil
+}
+
+// registerTaskDefinition registers a task definition, naming port mappings for service
+// connect and adding the fields of containers that the vendored sdk predates when there
+// are any
+func registerTaskDefinition(svc api.Services, input *ecs.RegisterTaskDefinitionInput, names api.PortNames, fields api.ContainerFields) (*ecs.RegisterTaskDefinitionOutput, error) {
+	if len(names) == 0 && len(fields) == 0 {
+		return svc.ECS.RegisterTaskDefinition(input)
+	}
+	return svc.TaskDefinitions.RegisterTaskDefinition(input, names, fields)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureSetLogRetention(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, service string
+	var days int
+
+	cmd := app.Command("set-log-retention", "Change how many days the logs of a cluster or a service are kept for")
+	cmd.Flag("cluster", "The ECS cluster the logs belong to").
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The service to change the retention of, rather than the cluster's log group").
+		Short('s').
+		StringVar(&service)
+
+	env.configure(cmd)
+
+	cmd.Arg("days", "The number of days to keep logs for").
+		Required().
+		IntVar(&days)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if err := api.ValidateLogRetention(days); err != nil {
+			return err
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		var stack *cloudformation.Stack
+		if service != "" {
+			if stack, err = api.FindServiceStack(svc.Cloudformation, cluster, service); err != nil {
+				return err
+			}
+		} else {
+			if stack, err = api.FindClusterStack(svc.Cloudformation, cluster); err != nil {
+				return err
+			} else if stack == nil {
+				return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+			}
+		}
+
+		// the log groups are managed by the stacks, so changing them directly would be reverted
+		if _, ok := api.GetStackParameterByKey(stack, "LogRetentionDays"); !ok {
+			return fmt.Errorf("Stack %s was created before log retention could be set, use `upgrade` first", *stack.StackName)
+		}
+
+		timer := time.Now()
+		log.Printf("Keeping the logs of %s for %d days", *stack.StackName, days)
+
+		err = api.UpdateStackParameters(svc.Cloudformation, stack, map[string]string{
+			"LogRetentionDays": strconv.Itoa(days),
+		})
+		if err == api.ErrNoStackUpdates {
+			log.Printf("Logs are already kept for %d days", days)
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		err = waitForStack(svc, *stack.StackName)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Updated %s in %s", *stack.StackName, time.Now().Sub(timer).String())
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/failure"
+)
+
+// smokeTestRetryInterval is how long to wait between requests to a path that hasn't passed
+var smokeTestRetryInterval = 5 * time.Second
+
+// errSmokeTestFailed is returned when a smoke test fails after a deploy
+type errSmokeTestFailed struct {
+	Test config.SmokeTest
+	Err  error
+}
+
+func (e *errSmokeTestFailed) Error() string {
+	return fmt.Sprintf("Smoke test %s failed: %v", e.Test, e.Err)
+}
+
+func (e *errSmokeTestFailed) FailureKind() failure.Kind {
+	return failure.DeployHealth
+}
+
+// runSmokeTests checks a deployed service with each of the smoke tests in turn, requests
+// are made to its load balancer and tasks are run like hooks
+func runSmokeTests(svc api.Services, tests []config.SmokeTest, url string, base onceOffTask) error {
+	for _, t := range tests {
+		log.Printf("Running smoke test %s", t)
+
+		var err error
+		if t.Path != "" {
+			err = requestSmokeTest(t, url)
+		} else {
+			task := base
+			task.Service = t.Service
+			task.Commands = t.Command
+
+			var exitCode int
+			if exitCode, err = runOnceOffTask(svc, task); err == nil && exitCode != 0 {
+				err = fmt.Errorf("exit code %d", exitCode)
+			}
+		}
+		if err != nil {
+			return &errSmokeTestFailed{t, err}
+		}
+		log.Printf("Smoke test %s passed", t)
+	}
+	return nil
+}
+
+// hasSmokeTestTasks returns whether any smoke tests run a compose service
+func hasSmokeTestTasks(cfg *config.Config) bool {
+	for _, t := range cfg.SmokeTests {
+		if t.Service != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// requestSmokeTest requests a path until it responds with the expected status and body,
+// giving up after the test's timeout
+func requestSmokeTest(t config.SmokeTest, url string) error {
+	if url == "" {
+		return fmt.Errorf("the service has no load balancer to request %s from", t.Path)
+	}
+	timeout, _ := t.TimeoutDuration()
+	body := regexp.MustCompile(t.Body)
+	target := strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(t.Path, "/")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := checkSmokeResponse(client, target, t.ExpectedStatus(), body)
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		if sleepErr := api.Sleep(commandContext(), smokeTestRetryInterval, target, err.Error()); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
+
+func checkSmokeResponse(client *http.Client, url string, status int, body *regexp.Regexp) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != status {
+		return fmt.Errorf("%s responded with %s, expected %d", url, resp.Status, status)
+	}
+	if !body.Match(b) {
+		return fmt.Errorf("%s responded with a body that doesn't match %q", url, body)
+	}
+	return nil
+}
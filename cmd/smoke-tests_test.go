@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lox/ecsy/config"
+)
+
+func TestRequestSmokeTest(t *testing.T) {
+	defer func(interval time.Duration) { smokeTestRetryInterval = interval }(smokeTestRetryInterval)
+	smokeTestRetryInterval = 10 * time.Millisecond
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			fmt.Fprint(w, `{"status":"ok"}`)
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		case "/warming":
+			// fails the first request, as a task that's still starting would
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "ready")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		test config.SmokeTest
+		url  string
+		err  string
+	}{
+		{test: config.SmokeTest{Path: "/health"}, url: server.URL},
+		{test: config.SmokeTest{Path: "health", Body: `"status":"ok"`}, url: server.URL + "/"},
+		{test: config.SmokeTest{Path: "/created", Status: 201}, url: server.URL},
+		{test: config.SmokeTest{Path: "/warming", Body: "^ready$", Timeout: "1s"}, url: server.URL},
+		{test: config.SmokeTest{Path: "/missing", Timeout: "30ms"}, url: server.URL, err: "404 Not Found, expected 200"},
+		{test: config.SmokeTest{Path: "/health", Body: "degraded", Timeout: "30ms"}, url: server.URL, err: `doesn't match "degraded"`},
+		{test: config.SmokeTest{Path: "/health"}, url: "", err: "no load balancer"},
+	} {
+		err := requestSmokeTest(tc.test, tc.url)
+		if tc.err == "" && err != nil {
+			t.Errorf("Expected %s to pass, got %v", tc.test, err)
+		} else if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("Expected %s to fail with %q, got %v", tc.test, tc.err, err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected a failing request to be retried once, got %d requests", requests)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureSSH(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, user, instanceID string
+
+	cmd := app.Command("ssh", "Open a shell on one of a cluster's instances, via Session Manager or EC2 Instance Connect if the cluster uses them")
+	cmd.Flag("cluster", "The ECS cluster the instance belongs to").
+		StringVar(&cluster)
+
+	cmd.Flag("user", "The user to ssh in as, for clusters that don't use Session Manager").
+		Default("ec2-user").
+		StringVar(&user)
+
+	env.configure(cmd)
+
+	cmd.Arg("instance", "The id of the instance, defaults to the first in the cluster").
+		StringVar(&instanceID)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stack, err := api.FindClusterStack(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		} else if stack == nil {
+			return fmt.Errorf("No cluster exists for %q. Use `create-cluster`", cluster)
+		}
+
+		if instanceID == "" {
+			instances, err := api.ListContainerInstances(svc.ECS, cluster)
+			if err != nil {
+				return err
+			}
+			for _, i := range instances {
+				if aws.StringValue(i.Status) == "ACTIVE" {
+					instanceID = aws.StringValue(i.Ec2InstanceId)
+					break
+				}
+			}
+			if instanceID == "" {
+				return fmt.Errorf("Cluster %s has no active instances", cluster)
+			}
+		}
+
+		instanceConnect, _ := api.GetStackParameterByKey(stack, "InstanceConnect")
+
+		var command *exec.Cmd
+		if instanceConnect != "true" && usesSessionManager(stack) {
+			log.Printf("Starting a session on %s", instanceID)
+			command = exec.Command("aws", "ssm", "start-session", "--target", instanceID, "--region", resolveRegion(cfg))
+		} else {
+			instances, err := svc.EC2.DescribeInstances([]string{instanceID})
+			if err != nil {
+				return err
+			} else if len(instances) == 0 || instances[0].PrivateIP == "" {
+				return fmt.Errorf("Instance %s has no address to ssh to", instanceID)
+			}
+			instance := instances[0]
+
+			args := []string{}
+			if instanceConnect == "true" {
+				dir, err := ioutil.TempDir("", "ecsy-ssh")
+				if err != nil {
+					return err
+				}
+				defer os.RemoveAll(dir)
+
+				key, err := pushInstanceConnectKey(svc, instance, user, dir)
+				if err != nil {
+					return err
+				}
+				args = append(args, "-i", key, "-o", "IdentitiesOnly=yes")
+			}
+
+			log.Printf("Connecting to %s at %s", instanceID, instance.PrivateIP)
+			command = exec.Command("ssh", append(args, user+"@"+instance.PrivateIP)...)
+		}
+
+		command.Stdin = os.Stdin
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+		return command.Run()
+	})
+}
+
+// usesSessionManager returns whether a cluster's instances are accessed via Session Manager
+// rather than an ssh keypair
+func usesSessionManager(stack *cloudformation.Stack) bool {
+	for _, key := range []string{"SessionManager", "Hardened"} {
+		if v, _ := api.GetStackParameterByKey(stack, key); v == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// pushInstanceConnectKey generates a temporary key in a directory and pushes it to an instance
+// with EC2 Instance Connect, returning the path to the private key
+func pushInstanceConnectKey(svc api.Services, instance api.Instance, user, dir string) (string, error) {
+	key := filepath.Join(dir, "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("Failed to generate a key: %v: %s", err, out)
+	}
+	publicKey, err := ioutil.ReadFile(key + ".pub")
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Pushing a temporary key for %s to %s", user, instance.ID)
+	err = svc.InstanceConnect.SendSSHPublicKey(instance.ID, user, instance.AvailabilityZone, string(publicKey))
+	if err != nil {
+		return "", fmt.Errorf("Failed to push a key to %s: %v", instance.ID, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"log"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func configureAllowReplacement(cmd *kingpin.CmdClause, allowReplacement *bool) {
+	cmd.Flag("allow-replacement", "Allow the update to replace or delete databases, caches, buckets and load balancers, which the stack policy otherwise prevents").
+		BoolVar(allowReplacement)
+}
+
+// checkProtectedChanges fails if a change set would replace or remove protected resources,
+// unless replacements are allowed in which case the stack policy is lifted until the update
+// sets it again
+func checkProtectedChanges(svc api.Services, cs *api.ChangeSet, allowReplacement bool) error {
+	changes := api.ProtectedChanges(cs)
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, change := range changes {
+		log.Printf("  protected: %s", api.FormatResourceChange(change))
+	}
+	if !allowReplacement {
+		return failure.Errorf(failure.Validation, "%s would replace or delete %d protected resources, rerun with --allow-replacement to allow it",
+			cs.StackName, len(changes))
+	}
+	log.Printf("Allowing %s to replace or delete %d protected resources", cs.StackName, len(changes))
+	return api.AllowReplacements(svc.Cloudformation, cs.StackName)
+}
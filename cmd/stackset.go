@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// stack set templates for org-wide base infrastructure
+var stackSetTemplates = map[string]func() string{
+	"network":   templates.NetworkStack,
+	"iam-roles": templates.IAMRoles,
+}
+
+func ConfigureStackSet(app *kingpin.Application, svc api.Services) {
+	var name, template string
+	var targets api.StackSetTargets
+	var params map[string]string
+
+	cmd := app.Command("stackset", "Deploy base infrastructure to many accounts with a CloudFormation stack set")
+	cmd.Flag("name", "The name of the stack set").
+		Required().
+		StringVar(&name)
+
+	cmd.Flag("template", "The template to deploy, either network or iam-roles").
+		Required().
+		EnumVar(&template, "network", "iam-roles")
+
+	cmd.Flag("account", "An account to deploy stack instances to").
+		StringsVar(&targets.Accounts)
+
+	cmd.Flag("ou", "An organizational unit to deploy stack instances to, uses service managed permissions").
+		StringsVar(&targets.OrganizationalUnits)
+
+	cmd.Flag("region", "A region to deploy stack instances to").
+		Required().
+		StringsVar(&targets.Regions)
+
+	cmd.Flag("param", "A template parameter in the form key=value").
+		StringMapVar(&params)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if len(targets.Accounts) == 0 && len(targets.OrganizationalUnits) == 0 {
+			return fmt.Errorf("Either --account or --ou is required")
+		} else if len(targets.Accounts) > 0 && len(targets.OrganizationalUnits) > 0 {
+			return fmt.Errorf("Stack sets deploy to either accounts or organizational units, not both")
+		}
+
+		input := &api.StackSetInput{
+			Name:           name,
+			TemplateBody:   stackSetTemplates[template](),
+			Parameters:     params,
+			ServiceManaged: len(targets.OrganizationalUnits) > 0,
+		}
+
+		timer := time.Now()
+		_, err := svc.StackSets.DescribeStackSet(name)
+		if err == api.ErrStackSetNotFound {
+			log.Printf("Creating stack set %s", name)
+			if err = svc.StackSets.CreateStackSet(input); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		} else {
+			log.Printf("Updating stack set %s", name)
+			opID, err := svc.StackSets.UpdateStackSet(input)
+			if err != nil {
+				return err
+			}
+			if err = api.PollStackSetOperation(commandContext(), svc.StackSets, name, opID); err != nil {
+				return err
+			}
+		}
+
+		existing, err := svc.StackSets.ListStackInstances(name)
+		if err != nil {
+			return err
+		}
+
+		if missing := api.MissingStackInstances(existing, targets); len(missing.Regions) > 0 {
+			log.Printf("Creating stack instances in %v", missing.Regions)
+			opID, err := svc.StackSets.CreateStackInstances(name, missing)
+			if err != nil {
+				return err
+			}
+			if err = api.PollStackSetOperation(commandContext(), svc.StackSets, name, opID); err != nil {
+				printStackInstances(svc, name)
+				return err
+			}
+		}
+
+		if err = printStackInstances(svc, name); err != nil {
+			return err
+		}
+
+		log.Printf("Stack set %s deployed in %s", name, time.Now().Sub(timer).String())
+		return nil
+	})
+
+	statusCmd := app.Command("stackset-status", "Show the status of a stack set's instances across accounts")
+	statusCmd.Flag("name", "The name of the stack set").
+		Required().
+		StringVar(&name)
+
+	statusCmd.Action(func(c *kingpin.ParseContext) error {
+		return printStackInstances(svc, name)
+	})
+}
+
+func printStackInstances(svc api.Services, name string) error {
+	instances, err := svc.StackSets.ListStackInstances(name)
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ACCOUNT\tREGION\tSTATUS\tDETAIL\tREASON")
+	for _, i := range instances {
+		counts[i.Status]++
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", i.Account, i.Region, i.Status, i.DetailedStatus, i.StatusReason)
+	}
+	w.Flush()
+
+	log.Printf("%d stack instances: %d current, %d outdated, %d inoperable",
+		len(instances), counts["CURRENT"], counts["OUTDATED"], counts["INOPERABLE"])
+	return nil
+}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// maxWebhookBytes is the largest webhook body that's read, GitHub caps payloads at 25MB
+const maxWebhookBytes = 25 << 20
+
+// syncFlags select the git repo of ecsy.yml manifests that services are synced from
+type syncFlags struct {
+	Repo     string
+	Branch   string
+	Path     string
+	Manifest string
+	Dir      string
+	Cluster  string
+	Env      string
+	Parallel int
+}
+
+func (f *syncFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("repo", "The git repo with the ecsy.yml of the services to sync").
+		Required().
+		StringVar(&f.Repo)
+
+	cmd.Flag("branch", "The branch of the repo to sync").
+		Default("main").
+		StringVar(&f.Branch)
+
+	cmd.Flag("path", "The directory in the repo with the ecsy.yml").
+		Default(".").
+		StringVar(&f.Path)
+
+	cmd.Flag("manifest", "A yaml file of services to the images to deploy to them, relative to --path, if it exists").
+		Default("manifest.yml").
+		StringVar(&f.Manifest)
+
+	cmd.Flag("dir", "Where the repo is checked out and the sync state is kept, ~/.ecsy/sync/<repo> by default").
+		StringVar(&f.Dir)
+
+	cmd.Flag("cluster", "The ECS cluster to sync services to, if it isn't set in the ecsy.yml").
+		StringVar(&f.Cluster)
+
+	cmd.Flag("env", "The environment from the ecsy.yml to use").
+		Envar("ECSY_ENV").
+		StringVar(&f.Env)
+
+	cmd.Flag("parallel", "The maximum number of services to deploy at once").
+		Default("4").
+		IntVar(&f.Parallel)
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func (f *syncFlags) dir() string {
+	if f.Dir != "" {
+		return f.Dir
+	}
+	home, _ := os.UserHomeDir()
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.TrimSuffix(f.Repo, ".git"), "-"), "-")
+	return filepath.Join(home, ".ecsy", "sync", slug)
+}
+
+func (f *syncFlags) statePath() string {
+	return filepath.Join(f.dir(), "state.json")
+}
+
+// syncState is the outcome of the last sync, for `sync status`
+type syncState struct {
+	Repo     string    `json:"repo"`
+	Branch   string    `json:"branch"`
+	Path     string    `json:"path"`
+	Cluster  string    `json:"cluster"`
+	Commit   string    `json:"commit"`
+	SyncedAt time.Time `json:"synced_at"`
+	// Deployed is the changes to each service that was deployed, once they all were
+	Deployed map[string][]string `json:"deployed,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+func (f *syncFlags) readState() (*syncState, error) {
+	b, err := ioutil.ReadFile(f.statePath())
+	if err != nil {
+		return nil, err
+	}
+	var state syncState
+	if err = json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", f.statePath(), err)
+	}
+	return &state, nil
+}
+
+func (f *syncFlags) writeState(state syncState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.statePath(), b, 0600)
+}
+
+// checkout clones the branch into dir, or fetches it if it's already cloned, returning the
+// commit that's checked out. The checkout is ecsy's own, so local changes are discarded.
+// The repo and branch come after --, so that they can't be taken as options to git.
+func (f *syncFlags) checkout(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+			return "", err
+		}
+		if _, err = runGit("clone", "--quiet", "--single-branch", "--branch="+f.Branch, "--", f.Repo, dir); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err = runGit("-C", dir, "fetch", "--quiet", "--", "origin", f.Branch); err != nil {
+			return "", err
+		}
+		if _, err = runGit("-C", dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return runGit("-C", dir, "rev-parse", "HEAD")
+}
+
+func runGit(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// plan loads the ecsy.yml of a checkout and plans a deploy of each of its services
+func (f *syncFlags) plan(svc api.Services, dir string) (string, []servicePlan, api.Services, *config.Config, error) {
+	env := environmentFlags{
+		ConfigFile: filepath.Join(dir, f.Path, config.DefaultFile),
+		Env:        f.Env,
+	}
+	// errors name the file in the repo, rather than in the checkout
+	name := filepath.Join(f.Path, config.DefaultFile)
+	if _, err := os.Stat(env.ConfigFile); err != nil {
+		return "", nil, svc, nil, fmt.Errorf("No %s in %s", name, f.Repo)
+	}
+	cfg, svc, err := env.load(svc)
+	if err != nil {
+		return "", nil, svc, nil, err
+	}
+
+	cluster := f.Cluster
+	if cluster == "" {
+		cluster = cfg.Cluster
+	}
+	if cluster == "" {
+		return "", nil, svc, nil, fmt.Errorf("A cluster is required, either with --cluster or in %s", name)
+	}
+
+	order, err := cfg.DeployOrder()
+	if err != nil {
+		return "", nil, svc, nil, err
+	}
+	if len(order) == 0 {
+		return "", nil, svc, nil, fmt.Errorf("No services are defined in %s", name)
+	}
+
+	manifest := filepath.Join(dir, f.Path, f.Manifest)
+	if _, err := os.Stat(manifest); os.IsNotExist(err) {
+		manifest = ""
+	}
+	images, err := loadManifest(manifest)
+	if err != nil {
+		return "", nil, svc, nil, err
+	}
+
+	plans, err := planServices(svc, cfg, order, images, allDeployOptions{
+		Cluster:    cluster,
+		ConfigFile: env.ConfigFile,
+		Env:        f.Env,
+		Template:   env.templateData(),
+		Region:     resolveRegion(cfg),
+	})
+	return cluster, plans, svc, cfg, err
+}
+
+// syncOnce converges the services of the cluster to the head of the branch
+func (f *syncFlags) syncOnce(svc api.Services) syncState {
+	state := syncState{Repo: f.Repo, Branch: f.Branch, Path: f.Path, SyncedAt: time.Now()}
+
+	err := func() error {
+		commit, err := f.checkout(filepath.Join(f.dir(), "checkout"))
+		if err != nil {
+			return err
+		}
+		state.Commit = commit
+
+		cluster, plans, svc, cfg, err := f.plan(svc, filepath.Join(f.dir(), "checkout"))
+		if err != nil {
+			return err
+		}
+		state.Cluster = cluster
+
+		drifted := map[string][]string{}
+		for _, p := range plans {
+			if len(p.Changes) > 0 {
+				drifted[p.Name] = p.Changes
+				log.Printf("%s has drifted from %s:", p.Name, shortCommit(commit))
+				for _, change := range p.Changes {
+					log.Printf("  %s", change)
+				}
+			}
+		}
+		if len(drifted) == 0 {
+			log.Printf("Services on %s are in sync with %s", cluster, shortCommit(commit))
+			return nil
+		}
+		if err = applyPlans(svc, notifiersFromConfig(svc, cfg), false, plans, len(drifted), f.Parallel); err != nil {
+			return err
+		}
+		state.Deployed = drifted
+		return nil
+	}()
+	if err != nil {
+		state.Error = err.Error()
+	}
+	return state
+}
+
+func ConfigureSync(app *kingpin.Application, svc api.Services) {
+	sync := app.Command("sync", "Converge services to the ecsy.yml in a git repo, gitops style")
+
+	configureSyncRun(sync, svc)
+	configureSyncStatus(sync, svc)
+}
+
+func configureSyncRun(sync *kingpin.CmdClause, svc api.Services) {
+	var f syncFlags
+	var interval time.Duration
+	var once bool
+	var listen, secret string
+
+	cmd := sync.Command("run", "Poll the repo and deploy the services that have drifted from it").Default()
+	f.configure(cmd)
+
+	cmd.Flag("interval", "How often to poll the repo").
+		Default("1m").
+		DurationVar(&interval)
+
+	cmd.Flag("once", "Sync once and exit, failing if the sync fails").
+		BoolVar(&once)
+
+	cmd.Flag("listen", "An address to receive push webhooks on at /webhook, which sync straight away, needs --webhook-secret").
+		StringVar(&listen)
+
+	cmd.Flag("webhook-secret", "The secret that webhooks are signed with, in X-Hub-Signature-256").
+		Envar("ECSY_SYNC_WEBHOOK_SECRET").
+		StringVar(&secret)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		// anyone who can reach the listener could otherwise trigger deploys
+		if listen != "" && secret == "" {
+			return fmt.Errorf("--listen needs --webhook-secret or $ECSY_SYNC_WEBHOOK_SECRET to check webhooks with")
+		}
+		if err := os.MkdirAll(f.dir(), 0700); err != nil {
+			return err
+		}
+
+		trigger := make(chan struct{}, 1)
+		if listen != "" {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+				if err := verifyWebhook(w, r, secret); err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				select {
+				case trigger <- struct{}{}:
+				default:
+				}
+				w.WriteHeader(http.StatusAccepted)
+			})
+			go func() {
+				log.Printf("Listening for webhooks on %s", listen)
+				if err := http.ListenAndServe(listen, mux); err != nil {
+					log.Printf("Failed to listen for webhooks: %v", err)
+				}
+			}()
+		}
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(stop)
+
+		for {
+			log.Printf("Syncing %s %s", f.Repo, f.Branch)
+			state := f.syncOnce(svc)
+			if err := f.writeState(state); err != nil {
+				log.Printf("Failed to write sync state: %v", err)
+			}
+			if once {
+				if state.Error != "" {
+					return fmt.Errorf("Sync failed: %s", state.Error)
+				}
+				return nil
+			}
+			if state.Error != "" {
+				log.Printf("Sync failed: %s", state.Error)
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-trigger:
+				log.Printf("Received a webhook")
+			case <-stop:
+				return nil
+			}
+		}
+	})
+}
+
+// verifyWebhook checks the signature of a webhook from a git host against the secret,
+// reading at most maxWebhookBytes of its body
+func verifyWebhook(w http.ResponseWriter, r *http.Request, secret string) error {
+	if r.Method != http.MethodPost {
+		return fmt.Errorf("Webhooks must be POSTed")
+	}
+	if secret == "" {
+		return fmt.Errorf("No webhook secret is configured")
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(r.Header.Get("X-Hub-Signature-256")), []byte(expected)) {
+		return fmt.Errorf("Webhook signature doesn't match")
+	}
+	return nil
+}
+
+func configureSyncStatus(sync *kingpin.CmdClause, svc api.Services) {
+	var f syncFlags
+
+	cmd := sync.Command("status", "Show the last sync and which services have drifted from the repo")
+	f.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		state, err := f.readState()
+		switch {
+		case os.IsNotExist(err):
+			log.Printf("No sync state in %s, it hasn't been synced from here", f.dir())
+		case err != nil:
+			return err
+		case state.Error != "":
+			log.Printf("Last sync of %s at %s failed: %s", shortCommit(state.Commit),
+				state.SyncedAt.Format("2006-01-02 15:04:05 MST"), state.Error)
+		default:
+			log.Printf("Last synced %s to %s at %s, deployed %d services", shortCommit(state.Commit),
+				state.Cluster, state.SyncedAt.Format("2006-01-02 15:04:05 MST"), len(state.Deployed))
+		}
+
+		// drift is planned from a fresh clone, so a running sync's checkout isn't touched
+		dir, err := ioutil.TempDir("", "ecsy-sync")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		commit, err := f.checkout(filepath.Join(dir, "checkout"))
+		if err != nil {
+			return err
+		}
+		if state != nil && state.Commit != commit {
+			log.Printf("%s is at %s, which hasn't been synced yet", f.Branch, shortCommit(commit))
+		}
+
+		cluster, plans, _, _, err := f.plan(svc, filepath.Join(dir, "checkout"))
+		if err != nil {
+			return err
+		}
+
+		drifted := 0
+		for _, p := range plans {
+			if len(p.Changes) == 0 {
+				log.Printf("%s: in sync", p.Name)
+				continue
+			}
+			drifted++
+			log.Printf("%s: %d changes", p.Name, len(p.Changes))
+			for _, change := range p.Changes {
+				log.Printf("  %s", change)
+			}
+		}
+		log.Printf("%d of %d services on %s have drifted from %s", drifted, len(plans), cluster, shortCommit(commit))
+		return nil
+	})
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
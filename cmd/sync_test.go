@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	body := `{"ref":"refs/heads/main"}`
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for _, tc := range []struct {
+		name, method, secret, signature string
+		valid                           bool
+	}{
+		{"signed", "POST", "s3cret", signature, true},
+		{"unsigned", "POST", "s3cret", "", false},
+		{"wrong secret", "POST", "other", signature, false},
+		{"no secret", "POST", "", signature, false},
+		{"get", "GET", "s3cret", signature, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/webhook", strings.NewReader(body))
+			if tc.signature != "" {
+				r.Header.Set("X-Hub-Signature-256", tc.signature)
+			}
+			if err := verifyWebhook(httptest.NewRecorder(), r, tc.secret); (err == nil) != tc.valid {
+				t.Fatalf("Expected valid to be %v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookLimitsBodies(t *testing.T) {
+	r := httptest.NewRequest("POST", "/webhook", strings.NewReader(strings.Repeat("a", maxWebhookBytes+1)))
+	r.Header.Set("X-Hub-Signature-256", "sha256=")
+	if err := verifyWebhook(httptest.NewRecorder(), r, "s3cret"); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Expected a body over %d bytes to be refused, got %v", maxWebhookBytes, err)
+	}
+}
+
+func TestSyncCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir, err := ioutil.TempDir("", "ecsy-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "repo")
+	commit := func(content string) string {
+		if err := ioutil.WriteFile(filepath.Join(repo, "ecsy.yml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{
+			{"add", "ecsy.yml"},
+			{"-c", "user.name=ecsy", "-c", "user.email=ecsy@example.com", "commit", "-q", "-m", content},
+		} {
+			if _, err := runGit(append([]string{"-C", repo}, args...)...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		head, err := runGit("-C", repo, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head
+	}
+	if _, err = runGit("init", "-q", repo); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = runGit("-C", repo, "symbolic-ref", "HEAD", "refs/heads/main"); err != nil {
+		t.Fatal(err)
+	}
+
+	f := syncFlags{Repo: repo, Branch: "main"}
+	checkout := filepath.Join(dir, "sync", "checkout")
+	first := commit("cluster: staging\n")
+	if head, err := f.checkout(checkout); err != nil || head != first {
+		t.Fatalf("Expected the clone to be at %s, got %s, %v", first, head, err)
+	}
+
+	// local changes to the checkout are discarded when it's next synced
+	if err = ioutil.WriteFile(filepath.Join(checkout, "ecsy.yml"), []byte("cluster: changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second := commit("cluster: production\n")
+	if head, err := f.checkout(checkout); err != nil || head != second {
+		t.Fatalf("Expected the checkout to be fetched to %s, got %s, %v", second, head, err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(checkout, "ecsy.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "cluster: production\n" {
+		t.Fatalf("Expected the checkout to match the branch, got %q", b)
+	}
+}
+
+func TestSyncCheckoutTakesNoOptions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	dir := t.TempDir()
+	pwned := filepath.Join(dir, "pwned")
+	option := "--upload-pack=touch " + pwned
+
+	f := syncFlags{Repo: option, Branch: "main"}
+	if _, err := f.checkout(filepath.Join(dir, "clone")); err == nil {
+		t.Fatalf("Expected a repo that looks like an option to fail to clone")
+	}
+
+	checkout := filepath.Join(dir, "fetch")
+	if _, err := runGit("init", "-q", checkout); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit("-C", checkout, "remote", "add", "origin", checkout); err != nil {
+		t.Fatal(err)
+	}
+	f = syncFlags{Repo: checkout, Branch: option}
+	if _, err := f.checkout(checkout); err == nil {
+		t.Fatalf("Expected a branch that looks like an option to fail to fetch")
+	}
+
+	if _, err := os.Stat(pwned); !os.IsNotExist(err) {
+		t.Fatalf("Expected the repo and branch not to be taken as options to git")
+	}
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	logs "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/compose"
+	"golang.org/x/net/context"
+)
+
+// onceOffTask is a single compose service run as a task until it exits
+type onceOffTask struct {
+	Cluster      string
+	ProjectName  string
+	Family       string
+	Service      string
+	ComposeFiles []string
+	Commands     []string
+	Images       map[string]string
+}
+
+func (t onceOffTask) taskName() string {
+	if t.Family != "" {
+		return t.Family
+	}
+	return fmt.Sprintf("%s_%s_run", t.ProjectName, t.Service)
+}
+
+// registerOnceOffTask registers a task definition for the service, returning it and the log
+// group its containers log to
+func registerOnceOffTask(svc api.Services, t onceOffTask) (*ecs.TaskDefinition, string, error) {
+	taskName := t.taskName()
+
+	clusterStack, err := api.FindClusterStack(svc.Cloudformation, t.Cluster)
+	if err != nil {
+		return nil, "", err
+	} else if clusterStack == nil {
+		return nil, "", fmt.Errorf("No cluster exists for %q. Use `create-cluster`", t.Cluster)
+	}
+
+	log.Printf("Generating task definition from %v", t.ComposeFiles)
+	transformer := compose.Transformer{
+		ComposeFiles: t.ComposeFiles,
+		ProjectName:  taskName,
+		Services:     []string{t.Service},
+	}
+
+	taskDefinitionInput, containerFields, err := transformer.Transform()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if image, ok := t.Images[t.Service]; ok {
+		err = api.UpdateContainerImages(taskDefinitionInput.ContainerDefinitions, map[string]string{
+			t.Service: image,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	logGroup, exists := api.GetStackOutputByKey(clusterStack, "LogGroupName")
+	if !exists {
+		return nil, "", fmt.Errorf("Expected to find a LogGroupName in stack output")
+	}
+
+	log.Printf("Setting tasks to use log group %s", logGroup)
+	for _, def := range taskDefinitionInput.ContainerDefinitions {
+		if def.LogConfiguration == nil {
+			def.LogConfiguration = &ecs.LogConfiguration{
+				LogDriver: aws.String("awslogs"),
+				Options: map[string]*string{
+					"awslogs-group":         aws.String(logGroup),
+					"awslogs-region":        aws.String(os.Getenv("AWS_REGION")),
+					"awslogs-stream-prefix": aws.String(taskName),
+				},
+			}
+		}
+	}
+
+	log.Printf("Registering a task for %s", taskName)
+	resp, err := registerTaskDefinition(svc, taskDefinitionInput, nil, containerFields)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.TaskDefinition, logGroup, nil
+}
+
+// runOnceOffTask registers and runs a task, following its logs until it stops and
+// returns the first non-zero exit code of its containers
+func runOnceOffTask(svc api.Services, t onceOffTask) (int, error) {
+	taskName := t.taskName()
+	log.Printf("Creating task %s on %s", taskName, t.Cluster)
+
+	taskDef, logGroup, err := registerOnceOffTask(svc, t)
+	if err != nil {
+		return 0, err
+	}
+
+	taskDefinition := fmt.Sprintf("%s:%d",
+		*taskDef.Family, *taskDef.Revision)
+
+	runTaskInput := &ecs.RunTaskInput{
+		TaskDefinition: aws.String(taskDefinition),
+		Cluster:        aws.String(t.Cluster),
+		Count:          aws.Int64(1),
+		Overrides: &ecs.TaskOverride{
+			ContainerOverrides: []*ecs.ContainerOverride{},
+		},
+	}
+
+	if len(t.Commands) > 0 {
+		cmds := []*string{}
+
+		for _, command := range t.Commands {
+			cmds = append(cmds, aws.String(command))
+		}
+
+		runTaskInput.Overrides.ContainerOverrides = append(
+			runTaskInput.Overrides.ContainerOverrides,
+			&ecs.ContainerOverride{
+				Command: cmds,
+				Name:    aws.String(t.Service),
+			},
+		)
+	}
+
+	log.Printf("Running task %s", taskDefinition)
+	runResp, err := svc.ECS.RunTask(runTaskInput)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(runResp.Failures) > 0 {
+		return 0, fmt.Errorf("Failed to run task: %s", *runResp.Failures[0].Reason)
+	} else if len(runResp.Tasks) == 0 {
+		return 0, errors.New("No tasks were started")
+	}
+
+	taskARNs := []*string{}
+	for _, t := range runResp.Tasks {
+		taskARNs = append(taskARNs, t.TaskArn)
+	}
+
+	ctx, cancel := context.WithCancel(commandContext())
+	defer cancel()
+
+	var exitCode int
+	var waitErr error
+
+	go func() {
+		defer cancel()
+
+		waitErr = svc.ECS.WaitUntilTasksStopped(&ecs.DescribeTasksInput{
+			Cluster: aws.String(t.Cluster),
+			Tasks:   taskARNs,
+		})
+		if waitErr != nil {
+			return
+		}
+
+		output, err := svc.ECS.DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String(t.Cluster),
+			Tasks:   taskARNs,
+		})
+		if err != nil {
+			waitErr = err
+			return
+		}
+
+		for _, task := range output.Tasks {
+			for _, container := range task.Containers {
+				if container.ExitCode == nil {
+					exitCode = 1
+					log.Printf("Container %s exited without an exit code: %s",
+						*container.Name, aws.StringValue(container.Reason))
+				} else if *container.ExitCode != 0 {
+					exitCode = int(*container.ExitCode)
+					log.Printf("Container %s exited with %d", *container.Name, exitCode)
+				}
+			}
+		}
+
+		// FIX: gross, but logs lag behind
+		time.Sleep(time.Second * 5)
+	}()
+
+	containerID := path.Base(*runResp.Tasks[0].TaskArn)
+	prefix := fmt.Sprintf("%s/%s/%s", taskName, t.Service, containerID)
+
+	log.Printf("Following logs for %s", prefix)
+
+	w := &logWatcher{
+		LogGroup:  logGroup,
+		LogPrefix: prefix,
+		services:  svc,
+		Printer: func(ev *logs.FilteredLogEvent) {
+			fmt.Println(*ev.Message)
+		},
+	}
+
+	err = w.Watch(ctx)
+	if ctxErr := commandContext().Err(); ctxErr != nil {
+		return 0, &api.WaitError{Err: ctxErr, What: "task " + *runResp.Tasks[0].TaskArn, State: "it was still running"}
+	} else if err != nil && err != context.Canceled {
+		return 0, err
+	}
+
+	return exitCode, waitErr
+}
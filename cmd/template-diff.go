@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// networkStackType identifies network stacks, which have no StackType output
+const networkStackType = "network"
+
+// embeddedTemplates are the templates that stacks of each type were created from
+var embeddedTemplates = map[string]func() string{
+	networkStackType:                   templates.NetworkStack,
+	"ecs-former::ecs-stack":            templates.EcsStack,
+	"ecs-former::ecs-service":          templates.EcsService,
+	"ecs-former::ecs-prometheus-agent": templates.PrometheusAgent,
+	"ecs-former::ecs-db":               templates.EcsDatabase,
+	"ecs-former::ecs-cache":            templates.EcsCache,
+	"ecs-former::ecs-budget":           templates.EcsBudget,
+	"ecs-former::ecs-namespace":        templates.EcsNamespace,
+	"ecs-former::ecs-autodeploy":       templates.EcsAutodeploy,
+	"ecs-former::iam-roles":            templates.IAMRoles,
+}
+
+func ConfigureTemplateDiff(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, stackName, templateFile string
+
+	cmd := app.Command("template-diff", "Show how the templates in this version of ecsy differ from a cluster's deployed stacks")
+	cmd.Flag("cluster", "The ECS cluster to compare").
+		StringVar(&cluster)
+
+	cmd.Flag("stack", "Only compare a single stack").
+		StringVar(&stackName)
+
+	cmd.Flag("template-file", "Compare the stack with this template instead of the embedded one, requires --stack").
+		ExistingFileVar(&templateFile)
+
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		if templateFile != "" && stackName == "" {
+			return fmt.Errorf("A --stack is required with --template-file")
+		}
+
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+
+		compared, removed := 0, false
+		for _, stack := range stacks {
+			name := aws.StringValue(stack.StackName)
+			if stackName != "" && name != stackName {
+				continue
+			}
+
+			var proposed string
+			if templateFile != "" {
+				b, err := ioutil.ReadFile(templateFile)
+				if err != nil {
+					return err
+				}
+				proposed = string(b)
+				if err := api.LintTemplate(proposed); err != nil {
+					return err
+				}
+			} else if tpl, ok := embeddedTemplates[stackType(stack)]; ok {
+				proposed = tpl()
+			} else {
+				log.Printf("Skipping %s, it isn't created from an embedded template", name)
+				continue
+			}
+
+			deployed, err := api.DeployedTemplate(svc.Cloudformation, name)
+			if err != nil {
+				return err
+			}
+
+			changes, err := api.DiffTemplates(deployed, proposed)
+			if err != nil {
+				return fmt.Errorf("Failed to compare %s: %v", name, err)
+			}
+			compared++
+
+			if len(changes) == 0 {
+				log.Printf("%s: no changes", name)
+				continue
+			}
+			log.Printf("%s: %d resources would change", name, len(changes))
+			for _, change := range changes {
+				removed = removed || change.Action == api.ResourceRemoved
+				line := fmt.Sprintf("  %-6s %s (%s)", change.Action, change.LogicalID, change.Type)
+				if len(change.Properties) > 0 {
+					line += ": " + strings.Join(change.Properties, ", ")
+				}
+				log.Println(line)
+			}
+		}
+
+		if stackName != "" && compared == 0 {
+			return fmt.Errorf("No stack %s found in cluster %s", stackName, cluster)
+		}
+
+		if removed {
+			log.Printf("Resources added by options like --vpc-endpoints or --allow-cidr show up as removed")
+		}
+		return nil
+	})
+}
+
+// stackType returns the type of template a stack was created from
+func stackType(stack *cloudformation.Stack) string {
+	if t, ok := api.GetStackOutputByKey(stack, "StackType"); ok {
+		return t
+	}
+	if strings.HasSuffix(aws.StringValue(stack.StackName), "-network") {
+		return networkStackType
+	}
+	return ""
+}
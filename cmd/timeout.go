@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	// waitTimeout bounds how long a command waits for stacks, deploys and tasks, waits are
+	// unbounded when it's zero
+	waitTimeout time.Duration
+
+	commandCtx    = context.Background()
+	cancelCommand = func() {}
+)
+
+// ConfigureTimeout adds the global --timeout flag, which every wait of a command is bounded by
+func ConfigureTimeout(app *kingpin.Application) {
+	app.Flag("timeout", "How long to wait for stacks, deploys and tasks before giving up, like 20m. Unbounded by default").
+		DurationVar(&waitTimeout)
+
+	app.PreAction(func(c *kingpin.ParseContext) error {
+		if waitTimeout > 0 {
+			commandCtx, cancelCommand = context.WithTimeout(context.Background(), waitTimeout)
+		}
+		return nil
+	})
+}
+
+// commandContext returns the context of the command being run, which ends once its
+// --timeout has passed
+func commandContext() context.Context {
+	return commandCtx
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureTop(app *kingpin.Application, svc api.Services) {
+	var cluster string
+	var interval time.Duration
+	var once bool
+
+	cmd := app.Command("top", "Show cpu and memory utilization for services and tasks")
+	cmd.Flag("cluster", "The name of the ECS cluster to show").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Flag("interval", "How often to refresh the display").
+		Default("5s").
+		DurationVar(&interval)
+
+	cmd.Flag("once", "Print utilization once and exit").
+		BoolVar(&once)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		for {
+			services, err := api.ListServices(svc.ECS, cluster)
+			if err != nil {
+				return err
+			}
+
+			// container insights is optional, tasks without it are shown without usage
+			usage, _ := api.FindTaskUtilization(svc.Logs, cluster, time.Now().Add(-2*time.Minute))
+
+			if !once {
+				fmt.Print("\033[H\033[2J")
+			}
+			fmt.Printf("Cluster %s at %s\n\n", cluster, time.Now().Format(time.Stamp))
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "SERVICE / TASK\tSTATUS\tDESIRED\tRUNNING\tPENDING\tCPU\tMEMORY")
+
+			for _, service := range services {
+				dimensions := map[string]string{
+					"ClusterName": cluster,
+					"ServiceName": *service.ServiceName,
+				}
+				cpu, cpuErr := api.LatestAverage(svc.CloudWatch, "AWS/ECS", "CPUUtilization", dimensions)
+				mem, memErr := api.LatestAverage(svc.CloudWatch, "AWS/ECS", "MemoryUtilization", dimensions)
+
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+					*service.ServiceName,
+					*service.Status,
+					*service.DesiredCount,
+					*service.RunningCount,
+					*service.PendingCount,
+					formatPercent(cpu, cpuErr),
+					formatPercent(mem, memErr),
+				)
+
+				tasks, err := api.ListServiceTasks(svc.ECS, cluster, *service.ServiceName, ecs.DesiredStatusRunning)
+				if err != nil {
+					return err
+				}
+
+				for _, task := range tasks {
+					taskID := path.Base(*task.TaskArn)
+					cpu, mem := "-", "-"
+					if u, ok := usage[taskID]; ok {
+						cpu = fmt.Sprintf("%.0f/%.0f", u.CpuUtilized, u.CpuReserved)
+						mem = fmt.Sprintf("%.0f/%.0fMB", u.MemoryUtilized, u.MemoryReserved)
+					}
+					fmt.Fprintf(w, "  %s\t%s\t\t\t\t%s\t%s\n", taskID, *task.LastStatus, cpu, mem)
+				}
+			}
+
+			if err = w.Flush(); err != nil {
+				return err
+			}
+
+			if once {
+				return nil
+			}
+			time.Sleep(interval)
+		}
+	})
+}
+
+func formatPercent(f float64, err error) string {
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", f)
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// templateMigration describes what changed in a version of a stack type's template, and
+// any parameters that need new values when stacks are upgraded to it
+type templateMigration struct {
+	StackType   string
+	Version     int
+	Description string
+	Params      func(previous map[string]string) map[string]string
+}
+
+var templateMigrations = []templateMigration{
+	{StackType: networkStackType, Version: 2,
+		Description: "Adds optional VPC endpoints and flow logs"},
+	{StackType: "ecs-former::ecs-stack", Version: 2,
+		Description: "Requires IMDSv2 on instances, and adds hardening, docker bridge and ingress options"},
+	{StackType: "ecs-former::ecs-service", Version: 2,
+		Description: "Adds task roles, assets buckets, queues, topics, linked databases and caches, and workers without a load balancer"},
+	{StackType: "ecs-former::ecs-stack", Version: 3,
+		Description: "Makes the retention of the cluster's log group configurable"},
+	{StackType: "ecs-former::ecs-service", Version: 3,
+		Description: "Creates a log group for the service with a retention period and optional KMS key"},
+	{StackType: "ecs-former::ecs-stack", Version: 4,
+		Description: "Adds an optional bucket for load balancer access logs"},
+	{StackType: "ecs-former::ecs-service", Version: 4,
+		Description: "Writes load balancer access logs to the cluster's bucket if it has one"},
+	{StackType: "ecs-former::ecs-stack", Version: 5,
+		Description: "Adds access to instances via SSM Session Manager without an ssh keypair"},
+	{StackType: "ecs-former::ecs-stack", Version: 6,
+		Description: "Syncs authorized_keys every 5 minutes so revoked keys are removed, and installs the SSM agent for `keys sync`"},
+	{StackType: "ecs-former::ecs-stack", Version: 7,
+		Description: "Adds optional ssh access with EC2 Instance Connect"},
+	{StackType: "ecs-former::ecs-stack", Version: 8,
+		Description: "Adds optional scheduled downtime, scaling instances to zero and back up"},
+	{StackType: "ecs-former::ecs-service", Version: 5,
+		Description: "Scales the service to zero during the cluster's scheduled downtime"},
+	{StackType: "ecs-former::ecs-stack", Version: 9,
+		Description: "Gives instances an ecsy.instance-group attribute, for placement on instance groups"},
+	{StackType: "ecs-former::ecs-stack", Version: 10,
+		Description: "Adds custom ECS attributes for the instances of the main group"},
+	{StackType: "ecs-former::ecs-stack", Version: 11,
+		Description: "Allows current generation instance types, which ecsy checks are offered in the region"},
+	{StackType: "ecs-former::ecs-stack", Version: 12,
+		Description: "Tags instances and their volumes with ecsy:cluster for cost allocation"},
+	{StackType: "ecs-former::ecs-service", Version: 6,
+		Description: "Adds an optional placement constraint for the service's tasks"},
+	{StackType: "ecs-former::ecs-service", Version: 7,
+		Description: "Adds optional ECS service connect through a cloud map namespace"},
+	{StackType: "ecs-former::ecs-service", Version: 8,
+		Description: "Tags the service, its tasks and load balancers with ecsy:cluster and ecsy:service for cost allocation"},
+	{StackType: "ecs-former::ecs-db", Version: 2,
+		Description: "Records the template version"},
+	{StackType: "ecs-former::ecs-cache", Version: 2,
+		Description: "Records the template version"},
+	{StackType: "ecs-former::ecs-prometheus-agent", Version: 2,
+		Description: "Records the template version"},
+	{StackType: "ecs-former::iam-roles", Version: 2,
+		Description: "Records the template version"},
+}
+
+// migrationsBetween returns the migrations a stack goes through from one version to another
+func migrationsBetween(stackType string, from, to int) []templateMigration {
+	migrations := []templateMigration{}
+	for _, m := range templateMigrations {
+		if m.StackType == stackType && m.Version > from && m.Version <= to {
+			migrations = append(migrations, m)
+		}
+	}
+	return migrations
+}
+
+func ConfigureUpgrade(app *kingpin.Application, svc api.Services) {
+	var env environmentFlags
+	var cluster, stackName string
+	var opts upgradeOptions
+	var approval approvalFlags
+
+	cmd := app.Command("upgrade", "Upgrade a cluster's stacks to the templates in this version of ecsy, previewing the changes first")
+	cmd.Flag("cluster", "The ECS cluster to upgrade").
+		StringVar(&cluster)
+
+	cmd.Flag("stack", "Only upgrade a single stack").
+		StringVar(&stackName)
+
+	cmd.Flag("apply", "Apply the upgrades rather than only previewing them").
+		BoolVar(&opts.Apply)
+
+	configureAllowReplacement(cmd, &opts.AllowReplacement)
+	approval.configure(cmd)
+	env.configure(cmd)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		cfg, svc, err := env.load(svc)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := resolveCluster(svc, cluster, cfg)
+		if err != nil {
+			return err
+		}
+
+		opts.RequireApproval = approval.required(cfg)
+
+		stacks, err := api.FindAllStacksForCluster(svc.Cloudformation, cluster)
+		if err != nil {
+			return err
+		}
+
+		pending := 0
+		for _, stack := range stacks {
+			if stackName != "" && aws.StringValue(stack.StackName) != stackName {
+				continue
+			}
+			upgraded, err := upgradeStack(svc, stack, opts)
+			if err != nil {
+				return err
+			}
+			if upgraded {
+				pending++
+			}
+		}
+
+		if !opts.Apply && pending > 0 {
+			log.Printf("%d stacks can be upgraded, run again with --apply to upgrade them", pending)
+		}
+		return nil
+	})
+}
+
+type upgradeOptions struct {
+	Apply            bool
+	AllowReplacement bool
+	RequireApproval  bool
+}
+
+// upgradeStack previews the change set for upgrading a stack to its embedded template,
+// executing it if apply is set. It returns whether the stack had changes.
+func upgradeStack(svc api.Services, stack *cloudformation.Stack, opts upgradeOptions) (bool, error) {
+	name := aws.StringValue(stack.StackName)
+	st := stackType(stack)
+	tpl, ok := embeddedTemplates[st]
+	if !ok {
+		log.Printf("Skipping %s, it isn't created from an embedded template", name)
+		return false, nil
+	}
+
+	deployed, err := api.DeployedTemplate(svc.Cloudformation, name)
+	if err != nil {
+		return false, err
+	}
+
+	from, to := templates.TemplateVersion(deployed), templates.TemplateVersion(tpl())
+	if from > to {
+		log.Printf("Skipping %s, it was deployed with version %d of its template which is newer than this ecsy's %d", name, from, to)
+		return false, nil
+	}
+
+	previous := map[string]string{}
+	for _, p := range stack.Parameters {
+		previous[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+	}
+
+	overrides := map[string]string{}
+	for _, m := range migrationsBetween(st, from, to) {
+		log.Printf("%s: migrating to template version %d: %s", name, m.Version, m.Description)
+		if m.Params != nil {
+			for k, v := range m.Params(previous) {
+				overrides[k] = v
+			}
+		}
+	}
+
+	body, carried := templates.CarryOver(deployed, tpl())
+	if len(carried) > 0 {
+		log.Printf("%s: keeping %d parameters, resources and outputs added when it was created", name, len(carried))
+	}
+
+	cs, err := api.PreviewStackUpdate(commandContext(), svc.Cloudformation, stack, body, overrides)
+	if err != nil {
+		return false, err
+	}
+	if cs == nil {
+		log.Printf("%s: up to date", name)
+		return false, nil
+	}
+
+	log.Printf("%s: %d resources would change", name, len(cs.Changes))
+	for _, change := range cs.Changes {
+		log.Printf("  %s", api.FormatResourceChange(change))
+	}
+
+	if !opts.Apply {
+		return true, api.DeleteChangeSet(svc.Cloudformation, cs)
+	}
+
+	if opts.RequireApproval {
+		if err = approve("upgrading " + name); err != nil {
+			api.DeleteChangeSet(svc.Cloudformation, cs)
+			return true, err
+		}
+	}
+	if err = checkProtectedChanges(svc, cs, opts.AllowReplacement); err != nil {
+		api.DeleteChangeSet(svc.Cloudformation, cs)
+		return true, err
+	}
+
+	log.Printf("Upgrading %s", name)
+	if err = api.ExecuteChangeSet(svc.Cloudformation, cs); err != nil {
+		return true, err
+	}
+	err = waitForStack(svc, name)
+
+	// change sets don't set stack policies, so stacks get one for their upgraded template,
+	// which also protects resources again after replacements were allowed
+	if policyErr := api.SetStackPolicy(svc.Cloudformation, name, api.StackPolicy(body)); err == nil {
+		err = policyErr
+	}
+	if err != nil {
+		return true, fmt.Errorf("Failed to upgrade %s: %v", name, err)
+	}
+	return true, nil
+}
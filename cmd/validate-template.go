@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"log"
+	"sort"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/failure"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureValidateTemplate(app *kingpin.Application, svc api.Services) {
+	var files []string
+
+	cmd := app.Command("validate-template", "Lint cloudformation templates, or the embedded ones, against a subset of the cfn-lint rules")
+	cmd.Arg("files", "The templates to lint, defaults to every template embedded in ecsy").
+		ExistingFilesVar(&files)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		bodies := map[string]string{}
+		for _, file := range files {
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			bodies[file] = string(b)
+		}
+		if len(files) == 0 {
+			for st, tpl := range embeddedTemplates {
+				bodies[st] = tpl()
+			}
+		}
+
+		names := []string{}
+		for name := range bodies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		errors := 0
+		for _, name := range names {
+			issues := templates.Lint(bodies[name])
+			for _, issue := range issues {
+				log.Printf("%s: %s", name, issue)
+			}
+			errors += len(templates.LintErrors(issues))
+			if len(issues) == 0 {
+				log.Printf("%s: no issues", name)
+			}
+		}
+
+		if errors > 0 {
+			return failure.Errorf(failure.Validation, "Found %d errors in %d templates", errors, len(names))
+		}
+		return nil
+	})
+}
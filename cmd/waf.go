@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// wafFlags are the Web ACL that a service's load balancer is served behind, via a CloudFront
+// distribution as WAF can't be associated with classic load balancers
+type wafFlags struct {
+	WebACLArn string
+	Rules     string
+}
+
+func (f *wafFlags) configure(cmd *kingpin.CmdClause) {
+	cmd.Flag("waf-web-acl-arn", "A WAFv2 Web ACL with CLOUDFRONT scope to serve the load balancer behind, from a CloudFront distribution").
+		StringVar(&f.WebACLArn)
+
+	cmd.Flag("waf", "Create a Web ACL with the AWS managed common rule set to serve the load balancer behind, only in us-east-1").
+		Default("none").
+		EnumVar(&f.Rules, "none", "managed-rules")
+}
+
+func (f *wafFlags) isSet() bool {
+	return f.WebACLArn != "" || f.Rules != "none"
+}
+
+// params returns the service stack parameters for the Web ACL. CloudFront only connects to
+// load balancers on port 80 or 1024 and above, and Web ACLs for CloudFront can only be
+// created in us-east-1, so a region that isn't known isn't checked.
+func (f *wafFlags) params(region, elbPort string) (map[string]string, error) {
+	params := map[string]string{
+		"WAFWebACLArn":    f.WebACLArn,
+		"WAFManagedRules": strconv.FormatBool(f.Rules == "managed-rules"),
+	}
+	if !f.isSet() {
+		return params, nil
+	}
+
+	if f.WebACLArn != "" && f.Rules != "none" {
+		return nil, failure.Errorf(failure.Validation, "Use either --waf-web-acl-arn or --waf, not both")
+	}
+	if f.WebACLArn != "" && (!strings.HasPrefix(f.WebACLArn, "arn:aws:wafv2:us-east-1:") || !strings.Contains(f.WebACLArn, ":global/webacl/")) {
+		return nil, failure.Errorf(failure.Validation, "%q isn't the arn of a WAFv2 Web ACL with CLOUDFRONT scope, like arn:aws:wafv2:us-east-1:123456789012:global/webacl/name/id", f.WebACLArn)
+	}
+	if f.Rules != "none" && region != "" && region != "us-east-1" {
+		return nil, failure.Errorf(failure.Validation, "Web ACLs for CloudFront can only be created in us-east-1, create one there and use --waf-web-acl-arn")
+	}
+	if port, err := strconv.Atoi(elbPort); err != nil || (port != 80 && port < 1024) {
+		return nil, failure.Errorf(failure.Validation, "CloudFront can't connect to the load balancer on port %s, only 80 or 1024 and above", elbPort)
+	}
+	return params, nil
+}
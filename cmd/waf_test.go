@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lox/ecsy/failure"
+)
+
+func TestWAFParams(t *testing.T) {
+	const arn = "arn:aws:wafv2:us-east-1:123456789012:global/webacl/example/a1b2c3"
+	for _, tc := range []struct {
+		flags  wafFlags
+		region string
+		port   string
+		params map[string]string
+	}{
+		{flags: wafFlags{Rules: "none"}, region: "eu-west-1", port: "22", params: map[string]string{"WAFWebACLArn": "", "WAFManagedRules": "false"}},
+		{flags: wafFlags{WebACLArn: arn, Rules: "none"}, region: "eu-west-1", port: "80", params: map[string]string{"WAFWebACLArn": arn, "WAFManagedRules": "false"}},
+		{flags: wafFlags{Rules: "managed-rules"}, region: "us-east-1", port: "8080", params: map[string]string{"WAFWebACLArn": "", "WAFManagedRules": "true"}},
+		{flags: wafFlags{Rules: "managed-rules"}, region: "", port: "8080", params: map[string]string{"WAFWebACLArn": "", "WAFManagedRules": "true"}},
+		{flags: wafFlags{Rules: "managed-rules"}, region: "eu-west-1", port: "8080"},
+		{flags: wafFlags{WebACLArn: arn, Rules: "managed-rules"}, region: "us-east-1", port: "80"},
+		{flags: wafFlags{WebACLArn: "arn:aws:wafv2:eu-west-1:123456789012:regional/webacl/example/a1b2c3", Rules: "none"}, region: "eu-west-1", port: "80"},
+		{flags: wafFlags{WebACLArn: arn, Rules: "none"}, region: "us-east-1", port: "443"},
+	} {
+		params, err := tc.flags.params(tc.region, tc.port)
+		if tc.params == nil {
+			if failure.KindOf(err) != failure.Validation {
+				t.Errorf("Expected %+v in %q on port %s to be rejected, got %v, %v", tc.flags, tc.region, tc.port, params, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(params, tc.params) {
+			t.Errorf("Expected %+v in %q on port %s to be %v, got %v, %v", tc.flags, tc.region, tc.port, tc.params, params, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/lox/ecsy/api"
+	"golang.org/x/net/context"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureWatch(app *kingpin.Application, svc api.Services) {
+	var cluster, service string
+	var maxFailures int
+
+	cmd := app.Command("watch", "Watch a service rollout until it reaches a steady state")
+	cmd.Flag("cluster", "The name of the ECS cluster the service is in").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service or compose project to watch").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	cmd.Flag("max-failures", "The number of stopped tasks after which the rollout is considered failed").
+		Default("3").
+		IntVar(&maxFailures)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		serviceName, err := resolveServiceName(svc, cluster, service)
+		if err != nil {
+			return err
+		}
+
+		w := &serviceWatcher{
+			Cluster:     cluster,
+			Service:     serviceName,
+			MaxFailures: maxFailures,
+			services:    svc,
+		}
+
+		log.Printf("Watching service %s on %s", serviceName, cluster)
+		return w.Watch(context.Background())
+	})
+}
+
+// resolveServiceName finds the ECS service for an ecsy service stack, falling back to
+// treating the name as an ECS service name
+func resolveServiceName(svc api.Services, cluster, name string) (string, error) {
+	stack, err := api.FindServiceStack(svc.Cloudformation, cluster, name)
+	if err == nil {
+		if serviceName, ok := api.StackOutputMap(stack)["ECSService"]; ok {
+			return serviceName, nil
+		}
+	}
+
+	service, err := api.DescribeService(svc.ECS, cluster, name)
+	if err != nil {
+		return "", err
+	}
+	return *service.ServiceName, nil
+}
+
+// resolveServiceFamily returns the task family a service runs, which is what deploys lock
+// and notify about it by
+func resolveServiceFamily(svc api.Services, cluster, serviceName string) (string, error) {
+	service, err := api.DescribeService(svc.ECS, cluster, serviceName)
+	if err != nil {
+		return "", err
+	}
+	return taskFamily(aws.StringValue(service.TaskDefinition)), nil
+}
+
+// taskFamily returns the family of a task definition arn or family:revision
+func taskFamily(taskDefinition string) string {
+	family := taskDefinition[strings.LastIndex(taskDefinition, "/")+1:]
+	if i := strings.LastIndex(family, ":"); i >= 0 {
+		family = family[:i]
+	}
+	return family
+}
+
+type serviceWatcher struct {
+	Cluster, Service string
+	MaxFailures      int
+	services         api.Services
+	initialized      bool
+	lastEvent        time.Time
+	deployments      map[string]string
+	tasks            map[string]string
+	health           map[string]string
+	failures         int
+}
+
+func (sw *serviceWatcher) Watch(ctx context.Context) error {
+	for {
+		done, err := sw.poll()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-time.After(api.ECS_POLL_INTERVAL * 5):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (sw *serviceWatcher) poll() (bool, error) {
+	service, err := api.DescribeService(sw.services.ECS, sw.Cluster, sw.Service)
+	if err != nil {
+		return false, err
+	}
+
+	if !sw.initialized {
+		sw.lastEvent = time.Now().Add(-1 * time.Minute)
+		sw.deployments = map[string]string{}
+		sw.tasks = map[string]string{}
+		sw.health = map[string]string{}
+	}
+
+	for i := len(service.Events) - 1; i >= 0; i-- {
+		event := service.Events[i]
+		if event.CreatedAt.After(sw.lastEvent) {
+			log.Printf("Event: %s", *event.Message)
+			sw.lastEvent = *event.CreatedAt
+		}
+	}
+
+	var primary *ecs.Deployment
+	for _, d := range service.Deployments {
+		if *d.Status == "PRIMARY" {
+			primary = d
+		}
+		summary := fmt.Sprintf("%s %s desired=%d running=%d pending=%d",
+			*d.Status, path.Base(*d.TaskDefinition), *d.DesiredCount, *d.RunningCount, *d.PendingCount)
+		if sw.deployments[*d.Id] != summary {
+			log.Printf("Deployment: %s", summary)
+			sw.deployments[*d.Id] = summary
+		}
+	}
+
+	if err = sw.pollTasks(primary); err != nil {
+		return false, err
+	}
+
+	for _, lb := range service.LoadBalancers {
+		if err = sw.pollHealth(lb); err != nil {
+			return false, err
+		}
+	}
+
+	sw.initialized = true
+
+	if sw.MaxFailures > 0 && sw.failures >= sw.MaxFailures {
+		return true, fmt.Errorf("Rollout failed, %d tasks stopped", sw.failures)
+	}
+
+	if len(service.Deployments) == 1 && *service.RunningCount == *service.DesiredCount && *service.PendingCount == 0 {
+		log.Printf("Service %s reached a steady state with %d running tasks", sw.Service, *service.RunningCount)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (sw *serviceWatcher) pollTasks(primary *ecs.Deployment) error {
+	for _, status := range []string{ecs.DesiredStatusRunning, ecs.DesiredStatusStopped} {
+		tasks, err := api.ListServiceTasks(sw.services.ECS, sw.Cluster, sw.Service, status)
+		if err != nil {
+			return err
+		}
+
+		for _, task := range tasks {
+			if sw.tasks[*task.TaskArn] == *task.LastStatus {
+				continue
+			}
+			sw.tasks[*task.TaskArn] = *task.LastStatus
+
+			// only report tasks that change after the watch started
+			if !sw.initialized {
+				continue
+			}
+
+			taskID := path.Base(*task.TaskArn)
+			if *task.LastStatus != ecs.DesiredStatusStopped {
+				log.Printf("Task %s is %s", taskID, *task.LastStatus)
+				continue
+			}
+
+			log.Printf("Task %s stopped: %s", taskID, formatStoppedReason(task))
+			if primary != nil && *task.TaskDefinitionArn == *primary.TaskDefinition {
+				sw.failures++
+			}
+		}
+	}
+	return nil
+}
+
+func (sw *serviceWatcher) pollHealth(lb *ecs.LoadBalancer) error {
+	var health []api.TargetHealth
+	var err error
+
+	if lb.TargetGroupArn != nil {
+		health, err = sw.services.ELB.DescribeTargetHealth(*lb.TargetGroupArn)
+	} else if lb.LoadBalancerName != nil {
+		health, err = sw.services.ELB.DescribeInstanceHealth(*lb.LoadBalancerName)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, h := range health {
+		key := h.ID + ":" + h.Port
+		if sw.health[key] == h.State {
+			continue
+		}
+		sw.health[key] = h.State
+
+		descr := ""
+		if h.Description != "" && h.Description != "N/A" {
+			descr = fmt.Sprintf(" => %q", h.Description)
+		}
+		log.Printf("Target %s is %s%s", strings.TrimSuffix(key, ":"), h.State, descr)
+	}
+	return nil
+}
+
+func formatStoppedReason(task *ecs.Task) string {
+	reasons := []string{}
+	if task.StoppedReason != nil {
+		reasons = append(reasons, *task.StoppedReason)
+	}
+	for _, container := range task.Containers {
+		if container.ExitCode != nil && *container.ExitCode != 0 {
+			reasons = append(reasons, fmt.Sprintf("%s exited with %d", *container.Name, *container.ExitCode))
+		}
+		if container.Reason != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", *container.Name, *container.Reason))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
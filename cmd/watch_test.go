@@ -0,0 +1,16 @@
+package cmd
+
+import "testing"
+
+func TestTaskFamily(t *testing.T) {
+	for taskDefinition, expected := range map[string]string{
+		"arn:aws:ecs:us-east-1:123456789012:task-definition/web:12": "web",
+		"web:3": "web",
+		"web":   "web",
+		"":      "",
+	} {
+		if family := taskFamily(taskDefinition); family != expected {
+			t.Errorf("Expected the family of %q to be %q, got %q", taskDefinition, expected, family)
+		}
+	}
+}
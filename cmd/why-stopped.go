@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/fatih/color"
+	"github.com/lox/ecsy/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func ConfigureWhyStopped(app *kingpin.Application, svc api.Services) {
+	var cluster, service string
+	var limit int
+	var lines int64
+
+	cmd := app.Command("why-stopped", "Show why recently stopped tasks for a service stopped")
+	cmd.Flag("cluster", "The name of the ECS cluster the service is in").
+		Required().
+		StringVar(&cluster)
+
+	cmd.Flag("service", "The name of the service or compose project").
+		Short('s').
+		Default(currentDirName()).
+		StringVar(&service)
+
+	cmd.Flag("limit", "The number of stopped tasks to show").
+		Default("5").
+		IntVar(&limit)
+
+	cmd.Flag("lines", "The number of log lines to show for each container").
+		Default("10").
+		Int64Var(&lines)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		serviceName, err := resolveServiceName(svc, cluster, service)
+		if err != nil {
+			return err
+		}
+
+		tasks, err := api.ListServiceTasks(svc.ECS, cluster, serviceName, ecs.DesiredStatusStopped)
+		if err != nil {
+			return err
+		}
+
+		if len(tasks) == 0 {
+			fmt.Printf("No recently stopped tasks for %s\n", serviceName)
+			return nil
+		}
+
+		sort.Sort(byStoppedAt(tasks))
+		if len(tasks) > limit {
+			tasks = tasks[:limit]
+		}
+
+		taskDefs := map[string]*ecs.TaskDefinition{}
+
+		for _, task := range tasks {
+			d := api.DiagnoseStoppedTask(task)
+
+			stoppedAt := "unknown"
+			if task.StoppedAt != nil {
+				stoppedAt = task.StoppedAt.Local().Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%s %s stopped at %s (%s)\n",
+				color.RedString("Task"), path.Base(*task.TaskArn), stoppedAt, d.StopCode)
+			fmt.Printf("  Reason: %s\n", aws.StringValue(task.StoppedReason))
+			for _, cause := range d.Causes {
+				fmt.Printf("  %s %s\n", color.YellowString("=>"), cause)
+			}
+
+			taskDef, ok := taskDefs[*task.TaskDefinitionArn]
+			if !ok {
+				resp, err := svc.ECS.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+					TaskDefinition: task.TaskDefinitionArn,
+				})
+				if err != nil {
+					return err
+				}
+				taskDef = resp.TaskDefinition
+				taskDefs[*task.TaskDefinitionArn] = taskDef
+			}
+
+			for _, container := range d.Containers {
+				exitCode := "-"
+				if container.ExitCode != nil {
+					exitCode = fmt.Sprintf("%d", *container.ExitCode)
+				}
+				fmt.Printf("  Container %s exit=%s %s\n", container.Name, exitCode, container.Reason)
+
+				group, stream, ok := api.TaskLogStream(taskDef, task, container.Name)
+				if !ok {
+					continue
+				}
+
+				messages, err := api.TailLogStream(svc.Logs, group, stream, lines)
+				if err != nil {
+					fmt.Printf("    Failed to read logs from %s: %v\n", stream, err)
+					continue
+				}
+				for _, message := range messages {
+					fmt.Printf("    | %s\n", message)
+				}
+			}
+			fmt.Println()
+		}
+
+		return nil
+	})
+}
+
+type byStoppedAt []*ecs.Task
+
+func (b byStoppedAt) Len() int      { return len(b) }
+func (b byStoppedAt) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byStoppedAt) Less(i, j int) bool {
+	if b[i].StoppedAt == nil || b[j].StoppedAt == nil {
+		return b[j].StoppedAt == nil
+	}
+	return b[i].StoppedAt.After(*b[j].StoppedAt)
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/lox/ecsy/api"
+	"github.com/lox/ecsy/config"
+	"github.com/lox/ecsy/templates"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func workflowsStackName(cluster, projectName string) string {
+	return fmt.Sprintf("ecs-%s-%s-workflows", cluster, projectName)
+}
+
+func ConfigureWorkflow(app *kingpin.Application, svc api.Services) {
+	workflow := app.Command("workflow", "Manage workflows of tasks defined in config")
+
+	configureWorkflowDeploy(workflow, svc)
+	configureWorkflowRun(workflow, svc)
+	configureWorkflowHistory(workflow, svc)
+}
+
+func configureWorkflowDeploy(workflow *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+	var images string
+
+	cmd := workflow.Command("deploy", "Register tasks for workflow steps and create or update their state machines")
+	f.configure(cmd)
+
+	cmd.Flag("file", "The docker-compose file to use").
+		Short('f').
+		Default("docker-compose.yml").
+		ExistingFilesVar(&f.ComposeFiles)
+
+	cmd.Arg("imagetags", "Tags in the form image=tag to apply to the tasks").
+		StringVar(&images)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		imageMap, err := parseImageMap(images)
+		if err != nil {
+			return err
+		}
+
+		cfg, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+		if len(cfg.Workflows) == 0 {
+			return fmt.Errorf("No workflows are defined in %s", f.env.ConfigFile)
+		}
+
+		// steps that run the same service share a task definition
+		taskDefinitions := map[string]string{}
+		stepFor := func(s config.WorkflowStep) (templates.WorkflowStep, error) {
+			if _, ok := taskDefinitions[s.Service]; !ok {
+				taskDef, _, err := registerOnceOffTask(svc, onceOffTask{
+					Cluster:      cluster,
+					ProjectName:  f.ProjectName,
+					Family:       fmt.Sprintf("%s_%s_workflow", f.ProjectName, s.Service),
+					Service:      s.Service,
+					ComposeFiles: f.ComposeFiles,
+					Images:       imageMap,
+				})
+				if err != nil {
+					return templates.WorkflowStep{}, err
+				}
+				log.Printf("Registered task definition %s:%d for service %s", *taskDef.Family, *taskDef.Revision, s.Service)
+				taskDefinitions[s.Service] = *taskDef.TaskDefinitionArn
+			}
+
+			timeout, _ := s.TimeoutDuration()
+			return templates.WorkflowStep{
+				Name:           s.Name,
+				TaskDefinition: taskDefinitions[s.Service],
+				Container:      s.Service,
+				Command:        s.Command,
+				Timeout:        timeout,
+				Retries:        s.Retries,
+			}, nil
+		}
+
+		workflows := []templates.Workflow{}
+		for _, name := range sortedWorkflowNames(cfg.Workflows) {
+			w := cfg.Workflows[name]
+			workflow := templates.Workflow{Name: name, Schedule: w.Schedule}
+
+			for _, s := range w.Steps {
+				step, err := stepFor(s)
+				if err != nil {
+					return err
+				}
+				workflow.Steps = append(workflow.Steps, step)
+			}
+			if w.OnFailure != nil {
+				step, err := stepFor(*w.OnFailure)
+				if err != nil {
+					return err
+				}
+				workflow.OnFailure = &step
+			}
+			workflows = append(workflows, workflow)
+		}
+
+		tpl, err := templates.WorkflowsStack(cluster, workflows)
+		if err != nil {
+			return err
+		}
+
+		timer := time.Now()
+		if err = deployStateMachinesStack(svc, workflowsStackName(cluster, f.ProjectName), tpl, "workflows"); err != nil {
+			return err
+		}
+
+		log.Printf("Deployed %d workflows in %s", len(workflows), time.Now().Sub(timer).String())
+		return nil
+	})
+}
+
+func configureWorkflowRun(workflow *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+	var name string
+	var noWait bool
+
+	cmd := workflow.Command("run", "Start a deployed workflow, waiting for all of its steps to finish")
+	f.configure(cmd)
+
+	cmd.Flag("no-wait", "Don't wait for the workflow to finish").
+		BoolVar(&noWait)
+
+	cmd.Arg("workflow", "The name of the workflow to run").
+		Required().
+		StringVar(&name)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		_, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		arn, err := findStateMachine(svc, workflowsStackName(cluster, f.ProjectName),
+			templates.WorkflowStateMachineOutput(name), "workflow", name, f.ProjectName)
+		if err != nil {
+			return err
+		}
+
+		return runStateMachine(svc, arn, "workflow", name, !noWait)
+	})
+}
+
+func configureWorkflowHistory(workflow *kingpin.CmdClause, svc api.Services) {
+	var f jobsFlags
+	var name string
+	var count int
+
+	cmd := workflow.Command("history", "Show recent runs of a workflow")
+	f.configure(cmd)
+
+	cmd.Flag("count", "The number of runs to show").
+		Default("10").
+		IntVar(&count)
+
+	cmd.Arg("workflow", "The name of the workflow").
+		Required().
+		StringVar(&name)
+
+	cmd.Action(func(c *kingpin.ParseContext) error {
+		_, svc, cluster, err := f.load(svc)
+		if err != nil {
+			return err
+		}
+
+		arn, err := findStateMachine(svc, workflowsStackName(cluster, f.ProjectName),
+			templates.WorkflowStateMachineOutput(name), "workflow", name, f.ProjectName)
+		if err != nil {
+			return err
+		}
+
+		executions, err := svc.StepFunctions.ListExecutions(arn, count)
+		if err != nil {
+			return err
+		}
+
+		printExecutions(executions)
+		return nil
+	})
+}
+
+func sortedWorkflowNames(workflows map[string]config.Workflow) []string {
+	names := []string{}
+	for name := range workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,171 @@
+package compose
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// extraKeys are service keys that the vendored libcompose predates, which are removed from
+// compose files before it parses them and set on container definitions as raw fields
+var extraKeys = []string{"sysctls", "init"}
+
+// serviceExtras are the values of the extra keys of each service, in the order of the
+// compose files that set them
+type serviceExtras map[string]map[string][]interface{}
+
+// readComposeFiles reads compose files, taking out the extra keys of their services
+func readComposeFiles(files []string) ([][]byte, serviceExtras, error) {
+	all := [][]byte{}
+	extras := serviceExtras{}
+	for _, file := range files {
+		var b []byte
+		var err error
+		if file == "-" {
+			b, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			b, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if b, err = extras.take(file, b); err != nil {
+			return nil, nil, err
+		}
+		all = append(all, b)
+	}
+	return all, extras, nil
+}
+
+// take removes the extra keys from the services of a compose file, returning the file
+// unchanged if it has none
+func (e serviceExtras) take(file string, b []byte) ([]byte, error) {
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", file, err)
+	}
+
+	// version 1 files have their services at the top level
+	services := doc
+	if _, ok := doc["version"]; ok {
+		services, _ = doc["services"].(map[interface{}]interface{})
+	}
+
+	taken := false
+	for name, s := range services {
+		service, ok := s.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range extraKeys {
+			value, ok := service[key]
+			if !ok {
+				continue
+			}
+			serviceName := fmt.Sprint(name)
+			if e[serviceName] == nil {
+				e[serviceName] = map[string][]interface{}{}
+			}
+			e[serviceName][key] = append(e[serviceName][key], value)
+			delete(service, key)
+			taken = true
+		}
+	}
+	if !taken {
+		return b, nil
+	}
+	return yaml.Marshal(doc)
+}
+
+// containerFields returns the raw container definition fields of a service's extra keys and
+// the capabilities that it adds and drops, which are set via linuxParameters
+func (e serviceExtras) containerFields(service string, capAdd, capDrop []string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+
+	if values := e[service]["sysctls"]; len(values) > 0 {
+		sysctls, err := parseSysctls(values)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sysctls for %s: %v", service, err)
+		}
+		fields["systemControls"] = systemControls(sysctls)
+	}
+
+	linux := map[string]interface{}{}
+	if len(capAdd) > 0 || len(capDrop) > 0 {
+		capabilities := map[string]interface{}{}
+		if len(capAdd) > 0 {
+			capabilities["add"] = capabilityNames(capAdd)
+		}
+		if len(capDrop) > 0 {
+			capabilities["drop"] = capabilityNames(capDrop)
+		}
+		linux["capabilities"] = capabilities
+	}
+	// a later compose file's init overrides an earlier one's
+	if values := e[service]["init"]; len(values) > 0 {
+		init, ok := values[len(values)-1].(bool)
+		if !ok {
+			return nil, fmt.Errorf("Invalid init for %s: expected true or false, got %v", service, values[len(values)-1])
+		}
+		linux["initProcessEnabled"] = init
+	}
+	if len(linux) > 0 {
+		fields["linuxParameters"] = linux
+	}
+
+	return fields, nil
+}
+
+// capabilityNames returns linux capabilities as ECS names them, without the CAP_ prefix
+// that compose allows
+func capabilityNames(caps []string) []interface{} {
+	names := []interface{}{}
+	for _, c := range caps {
+		names = append(names, strings.TrimPrefix(strings.ToUpper(c), "CAP_"))
+	}
+	return names
+}
+
+// parseSysctls merges sysctls from each compose file, which can be a map or a list of
+// name=value pairs like in docker-compose
+func parseSysctls(values []interface{}) (map[string]string, error) {
+	sysctls := map[string]string{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case map[interface{}]interface{}:
+			for name, val := range v {
+				sysctls[fmt.Sprint(name)] = fmt.Sprint(val)
+			}
+		case []interface{}:
+			for _, item := range v {
+				pieces := strings.SplitN(fmt.Sprint(item), "=", 2)
+				if len(pieces) != 2 {
+					return nil, fmt.Errorf("expected name=value, got %q", item)
+				}
+				sysctls[pieces[0]] = pieces[1]
+			}
+		default:
+			return nil, fmt.Errorf("expected a map or a list, got %v", value)
+		}
+	}
+	return sysctls, nil
+}
+
+// systemControls returns sysctls in the form ECS takes them, sorted by name
+func systemControls(sysctls map[string]string) []interface{} {
+	names := []string{}
+	for name := range sysctls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	controls := []interface{}{}
+	for _, name := range names {
+		controls = append(controls, map[string]interface{}{"namespace": name, "value": sysctls[name]})
+	}
+	return controls
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/docker/libcompose/docker"
 	"github.com/docker/libcompose/docker/ctx"
 	"github.com/docker/libcompose/project"
+	"github.com/lox/ecsy/api"
 )
 
 type Transformer struct {
@@ -22,15 +23,24 @@ type Transformer struct {
 	EnvironmentLookup config.EnvironmentLookup
 }
 
-func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
+// Transform returns a task definition for the services of the compose files, along with the
+// fields of its containers that the vendored sdk predates
+func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, api.ContainerFields, error) {
 	task := ecs.RegisterTaskDefinitionInput{
 		Family:               aws.String(t.ProjectName),
 		ContainerDefinitions: []*ecs.ContainerDefinition{},
 		Volumes:              []*ecs.Volume{},
 	}
+	fields := api.ContainerFields{}
+
+	composeBytes, extras, err := readComposeFiles(t.ComposeFiles)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	projectCtx := project.Context{
 		ComposeFiles: t.ComposeFiles,
+		ComposeBytes: composeBytes,
 		ProjectName:  t.ProjectName,
 	}
 
@@ -40,7 +50,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 
 	p, err := docker.NewProject(&ctx.Context{Context: projectCtx}, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, name := range p.(*project.Project).ServiceConfigs.Keys() {
@@ -83,6 +93,14 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			def.Privileged = aws.Bool(config.Privileged)
 		}
 
+		if config.ReadOnly {
+			def.ReadonlyRootFilesystem = aws.Bool(config.ReadOnly)
+		}
+
+		if config.User != "" {
+			def.User = aws.String(config.User)
+		}
+
 		if slice := []string(config.DNS); len(slice) > 0 {
 			for _, dns := range slice {
 				def.DnsServers = append(def.DnsServers, aws.String(dns))
@@ -130,7 +148,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 				if len(parts) > 0 {
 					portInt, err := strconv.ParseInt(parts[0], 10, 64)
 					if err != nil {
-						return nil, err
+						return nil, nil, err
 					}
 					mapping.ContainerPort = aws.Int64(portInt)
 				}
@@ -139,7 +157,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 					hostParts := strings.Split(parts[1], "/")
 					portInt, err := strconv.ParseInt(hostParts[0], 10, 64)
 					if err != nil {
-						return nil, err
+						return nil, nil, err
 					}
 					mapping.HostPort = aws.Int64(portInt)
 
@@ -150,7 +168,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 				}
 
 				if len(parts) == 0 || len(parts) > 2 {
-					return nil, errors.New("Unsupported port mapping " + val)
+					return nil, nil, errors.New("Unsupported port mapping " + val)
 				}
 
 				def.PortMappings = append(def.PortMappings, mapping)
@@ -206,6 +224,17 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			}
 		}
 
+		if ulimits := config.Ulimits.Elements; len(ulimits) > 0 {
+			def.Ulimits = []*ecs.Ulimit{}
+			for _, ulimit := range ulimits {
+				def.Ulimits = append(def.Ulimits, &ecs.Ulimit{
+					Name:      aws.String(ulimit.Name),
+					SoftLimit: aws.Int64(ulimit.Soft),
+					HardLimit: aws.Int64(ulimit.Hard),
+				})
+			}
+		}
+
 		if config.Logging.Driver != "" {
 			def.LogConfiguration = &ecs.LogConfiguration{
 				LogDriver: aws.String(config.Logging.Driver),
@@ -224,8 +253,6 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			Key   string
 			Value []string
 		}{
-			{"CapAdd", config.CapAdd},
-			{"CapDrop", config.CapDrop},
 			{"Devices", config.Devices},
 			{"EnvFile", config.EnvFile},
 			{"SecurityOpt", config.SecurityOpt},
@@ -237,7 +264,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			{"Tmpfs", config.Tmpfs},
 		} {
 			if len(i.Value) > 0 {
-				return nil, fmt.Errorf("%s directive not supported", i.Key)
+				return nil, nil, fmt.Errorf("%s directive not supported", i.Key)
 			}
 		}
 
@@ -255,7 +282,6 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			{"Uts", config.Uts},
 			{"Ipc", config.Ipc},
 			{"Restart", config.Restart},
-			{"User", config.User},
 			{"StopSignal", config.StopSignal},
 			{"MacAddress", config.MacAddress},
 			{"Isolation", config.Isolation},
@@ -263,7 +289,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			{"CgroupParent", config.CgroupParent},
 		} {
 			if i.Value != "" {
-				return nil, fmt.Errorf("%s directive not supported", i.Key)
+				return nil, nil, fmt.Errorf("%s directive not supported", i.Key)
 			}
 		}
 
@@ -279,7 +305,7 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			{"ShmSize", int(config.ShmSize)},
 		} {
 			if i.Value != 0 {
-				return nil, fmt.Errorf("%s directive not supported", i.Key)
+				return nil, nil, fmt.Errorf("%s directive not supported", i.Key)
 			}
 		}
 
@@ -287,27 +313,30 @@ func (t *Transformer) Transform() (*ecs.RegisterTaskDefinitionInput, error) {
 			Key   string
 			Value bool
 		}{
-			{"ReadOnly", config.ReadOnly},
 			{"StdinOpen", config.StdinOpen},
 			{"Tty", config.Tty},
 		} {
 			if i.Value {
-				return nil, fmt.Errorf("%s directive not supported", i.Key)
+				return nil, nil, fmt.Errorf("%s directive not supported", i.Key)
 			}
 		}
 
 		if config.Labels != nil {
-			return nil, fmt.Errorf("Labels directive not supported")
+			return nil, nil, fmt.Errorf("Labels directive not supported")
 		}
 
-		if len(config.Ulimits.Elements) > 0 {
-			return nil, fmt.Errorf("Ulimits directive not supported")
+		containerFields, err := extras.containerFields(name, config.CapAdd, config.CapDrop)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(containerFields) > 0 {
+			fields[name] = containerFields
 		}
 
 		task.ContainerDefinitions = append(task.ContainerDefinitions, &def)
 	}
 
-	return &task, nil
+	return &task, fields, nil
 }
 
 func isServiceIncluded(name string, included []string) bool {
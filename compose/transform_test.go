@@ -1,6 +1,14 @@
 package compose
 
-import "testing"
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lox/ecsy/api"
+)
 
 func TestTransformHelloWorld(t *testing.T) {
 	trf := Transformer{
@@ -8,7 +16,7 @@ func TestTransformHelloWorld(t *testing.T) {
 		ProjectName:  "helloworld",
 	}
 
-	_, err := trf.Transform()
+	_, _, err := trf.Transform()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -24,8 +32,129 @@ func TestTransformComplex(t *testing.T) {
 		},
 	}
 
-	_, err := trf.Transform()
+	task, _, err := trf.Transform()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, def := range task.ContainerDefinitions {
+		switch *def.Name {
+		case "rabbitmq":
+			if len(def.Ulimits) != 1 || *def.Ulimits[0].Name != "nofile" || *def.Ulimits[0].SoftLimit != 65536 {
+				t.Fatalf("Unexpected ulimits %v", def.Ulimits)
+			}
+		case "user":
+			if def.ReadonlyRootFilesystem == nil || !*def.ReadonlyRootFilesystem || def.User == nil || *def.User != "nobody" {
+				t.Fatalf("Expected a read-only root filesystem as nobody, got %v", def)
+			}
+		}
+	}
+}
+
+func writeComposeFile(t *testing.T, name, body string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTransformSysctls(t *testing.T) {
+	base := writeComposeFile(t, "docker-compose.yml", `version: '2'
+services:
+  haproxy:
+    image: haproxy:2.8
+    sysctls:
+      net.core.somaxconn: 1024
+      net.ipv4.tcp_syncookies: 0
+  web:
+    image: nginx
+`)
+	override := writeComposeFile(t, "docker-compose.override.yml", `version: '2'
+services:
+  haproxy:
+    sysctls:
+      - net.ipv4.tcp_syncookies=1
+      - net.ipv4.ip_local_port_range=1024 65000
+`)
+
+	trf := Transformer{ComposeFiles: []string{base, override}, ProjectName: "proxy"}
+	task, fields, err := trf.Transform()
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(task.ContainerDefinitions) != 2 {
+		t.Fatalf("Expected both services, got %v", task.ContainerDefinitions)
+	}
+
+	expected := api.ContainerFields{"haproxy": {"systemControls": []interface{}{
+		map[string]interface{}{"namespace": "net.core.somaxconn", "value": "1024"},
+		map[string]interface{}{"namespace": "net.ipv4.ip_local_port_range", "value": "1024 65000"},
+		map[string]interface{}{"namespace": "net.ipv4.tcp_syncookies", "value": "1"},
+	}}}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("Expected %v, got %v", expected, fields)
+	}
+}
+
+func TestTransformInvalidSysctls(t *testing.T) {
+	for _, sysctls := range []string{`"net.core.somaxconn"`, `[net.core.somaxconn]`} {
+		file := writeComposeFile(t, "docker-compose.yml", "version: '2'\nservices:\n  web:\n    image: nginx\n    sysctls: "+sysctls+"\n")
+		trf := Transformer{ComposeFiles: []string{file}, ProjectName: "web"}
+		if _, _, err := trf.Transform(); err == nil || !strings.Contains(err.Error(), "Invalid sysctls for web") {
+			t.Errorf("Expected sysctls %s to be invalid, got %v", sysctls, err)
+		}
+	}
+}
+
+func TestTransformLinuxParameters(t *testing.T) {
+	base := writeComposeFile(t, "docker-compose.yml", `version: '2'
+services:
+  proxy:
+    image: haproxy:2.8
+    cap_add:
+      - NET_BIND_SERVICE
+      - cap_net_admin
+    cap_drop:
+      - ALL
+    init: false
+  worker:
+    image: worker
+    init: true
+  web:
+    image: nginx
+`)
+	override := writeComposeFile(t, "docker-compose.override.yml", `version: '2'
+services:
+  proxy:
+    init: true
+`)
+
+	trf := Transformer{ComposeFiles: []string{base, override}, ProjectName: "proxy"}
+	_, fields, err := trf.Transform()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := api.ContainerFields{
+		"proxy": {"linuxParameters": map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"add":  []interface{}{"NET_BIND_SERVICE", "NET_ADMIN"},
+				"drop": []interface{}{"ALL"},
+			},
+			"initProcessEnabled": true,
+		}},
+		"worker": {"linuxParameters": map[string]interface{}{"initProcessEnabled": true}},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("Expected %v, got %v", expected, fields)
+	}
+}
+
+func TestTransformInvalidInit(t *testing.T) {
+	file := writeComposeFile(t, "docker-compose.yml", "version: '2'\nservices:\n  web:\n    image: nginx\n    init: /sbin/tini\n")
+	trf := Transformer{ComposeFiles: []string{file}, ProjectName: "web"}
+	if _, _, err := trf.Transform(); err == nil || !strings.Contains(err.Error(), "Invalid init for web") {
+		t.Fatalf("Expected a path for init to be invalid, got %v", err)
+	}
 }
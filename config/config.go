@@ -0,0 +1,436 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/lox/ecsy/failure"
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultFile = "ecsy.yml"
+
+// Service types, a web service is behind a load balancer and a worker isn't
+const (
+	ServiceTypeWeb    = "web"
+	ServiceTypeWorker = "worker"
+)
+
+// Config is the optional ecsy.yml file that lives alongside docker-compose files
+type Config struct {
+	Settings       `yaml:",inline"`
+	Type           string                   `yaml:"type"`
+	Hooks          Hooks                    `yaml:"hooks"`
+	SmokeTests     []SmokeTest              `yaml:"smoke_tests"`
+	Notifications  Notifications            `yaml:"notifications"`
+	Resources      Resources                `yaml:"resources"`
+	Jobs           map[string]Job           `yaml:"jobs"`
+	Workflows      map[string]Workflow      `yaml:"workflows"`
+	Services       map[string]Service       `yaml:"services"`
+	InstanceGroups map[string]InstanceGroup `yaml:"instance_groups"`
+	Environments   map[string]Environment   `yaml:"environments"`
+}
+
+// Resources are created alongside a service, with access granted to its tasks
+type Resources struct {
+	Queues []string `yaml:"queues"`
+	Topics []string `yaml:"topics"`
+}
+
+// Service is one of several services deployed together with `deploy --all`, from a
+// directory with its own compose files and ecsy.yml
+type Service struct {
+	Path      string   `yaml:"path"`
+	DependsOn []string `yaml:"depends_on"`
+	HealthURL string   `yaml:"health_url"`
+}
+
+// InstanceGroup is an extra autoscaling group of instances in a cluster, with its own
+// instance type, size and ECS attributes that placement constraints can target
+type InstanceGroup struct {
+	Type       string            `yaml:"type"`
+	Count      int               `yaml:"count"`
+	Min        int               `yaml:"min"`
+	Max        int               `yaml:"max"`
+	AMI        string            `yaml:"ami"`
+	GPU        bool              `yaml:"gpu"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+var (
+	instanceGroupName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	attributeName     = regexp.MustCompile(`^[A-Za-z0-9_./-]{1,128}$`)
+	attributeValue    = regexp.MustCompile(`^[A-Za-z0-9_.@/: -]{1,128}$`)
+)
+
+func (g InstanceGroup) validate() error {
+	if g.Type == "" {
+		return fmt.Errorf("needs an instance type")
+	}
+	if g.Min < 0 || g.Count < g.Min || g.Max < g.Count || g.Max == 0 {
+		return fmt.Errorf("needs 0 <= min <= count <= max, and a max of at least 1")
+	}
+	for k, v := range g.Attributes {
+		if k == "ecsy.instance-group" {
+			return fmt.Errorf("can't override the ecsy.instance-group attribute")
+		}
+		if err := ValidateAttribute(k, v); err != nil {
+			return fmt.Errorf("has an %v", err)
+		}
+	}
+	return nil
+}
+
+// ValidateAttribute checks an ECS container instance attribute has a name and value that
+// ECS accepts
+func ValidateAttribute(name, value string) error {
+	if !attributeName.MatchString(name) || !attributeValue.MatchString(value) {
+		return fmt.Errorf("invalid attribute %s=%s", name, value)
+	}
+	return nil
+}
+
+// Settings are the values that an environment can override
+type Settings struct {
+	Cluster   string `yaml:"cluster"`
+	Region    string `yaml:"region"`
+	AccountID string `yaml:"account_id"`
+	RoleArn   string `yaml:"role_arn"`
+	MFASerial string `yaml:"mfa_serial"`
+	Count     int    `yaml:"count"`
+	// Protect turns on termination protection for cluster and network stacks, it defaults
+	// to on for environments with prod in their name
+	Protect *bool `yaml:"protect"`
+	// RequireApproval makes deploys and upgrades wait for what they change to be approved
+	RequireApproval bool `yaml:"require_approval"`
+}
+
+// Environment is an overlay applied to the config for a particular environment, such
+// as staging or production
+type Environment struct {
+	Settings      `yaml:",inline"`
+	Notifications *Notifications `yaml:"notifications"`
+}
+
+// ForEnvironment returns the config with an environment's overrides applied, an empty
+// name returns the config as is
+func (c *Config) ForEnvironment(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+
+	env, ok := c.Environments[name]
+	if !ok {
+		return nil, fmt.Errorf("No environment %q is defined in config", name)
+	}
+
+	resolved := *c
+	if env.Cluster != "" {
+		resolved.Cluster = env.Cluster
+	}
+	if env.Region != "" {
+		resolved.Region = env.Region
+	}
+	if env.AccountID != "" {
+		resolved.AccountID = env.AccountID
+	}
+	if env.RoleArn != "" {
+		resolved.RoleArn = env.RoleArn
+	}
+	if env.MFASerial != "" {
+		resolved.MFASerial = env.MFASerial
+	}
+	if env.Count != 0 {
+		resolved.Count = env.Count
+	}
+	if env.Protect != nil {
+		resolved.Protect = env.Protect
+	}
+	if env.RequireApproval {
+		resolved.RequireApproval = true
+	}
+	if env.Notifications != nil {
+		resolved.Notifications = *env.Notifications
+	}
+	return &resolved, nil
+}
+
+type Hooks struct {
+	PreDeploy  []Hook `yaml:"pre_deploy"`
+	PostDeploy []Hook `yaml:"post_deploy"`
+}
+
+// Hook is a once-off task run from a compose service, such as a database migration
+type Hook struct {
+	Name    string   `yaml:"name"`
+	Service string   `yaml:"service"`
+	Command []string `yaml:"command"`
+}
+
+func (h Hook) String() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Service
+}
+
+// SmokeTest checks a deploy before it's declared a success, either with a request to a path
+// on the service's load balancer or a once-off task run from a compose service. A deploy
+// that fails a smoke test is rolled back.
+type SmokeTest struct {
+	Name    string   `yaml:"name"`
+	Path    string   `yaml:"path"`
+	Status  int      `yaml:"status"`
+	Body    string   `yaml:"body"`
+	Service string   `yaml:"service"`
+	Command []string `yaml:"command"`
+	Timeout string   `yaml:"timeout"`
+}
+
+func (t SmokeTest) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if t.Path != "" {
+		return t.Path
+	}
+	return t.Service
+}
+
+// ExpectedStatus is the status a request must respond with, defaulting to 200
+func (t SmokeTest) ExpectedStatus() int {
+	if t.Status == 0 {
+		return 200
+	}
+	return t.Status
+}
+
+// TimeoutDuration parses how long a request is retried for until it passes, defaulting
+// to a minute
+func (t SmokeTest) TimeoutDuration() (time.Duration, error) {
+	if t.Timeout == "" {
+		return time.Minute, nil
+	}
+	return time.ParseDuration(t.Timeout)
+}
+
+func (t SmokeTest) validate() error {
+	if (t.Path == "") == (t.Service == "") {
+		return fmt.Errorf("needs either a path to request or a service to run")
+	}
+	if t.Service != "" && (t.Status != 0 || t.Body != "") {
+		return fmt.Errorf("checks a status or body, which only requests to a path have")
+	}
+	if t.Status != 0 && (t.Status < 100 || t.Status > 599) {
+		return fmt.Errorf("has an invalid status %d", t.Status)
+	}
+	if _, err := regexp.Compile(t.Body); err != nil {
+		return fmt.Errorf("has an invalid body pattern: %v", err)
+	}
+	if _, err := t.TimeoutDuration(); err != nil {
+		return fmt.Errorf("has an invalid timeout: %v", err)
+	}
+	return nil
+}
+
+// Job is a once-off task run from a compose service on a schedule or on demand
+type Job struct {
+	Service   string   `yaml:"service"`
+	Command   []string `yaml:"command"`
+	Schedule  string   `yaml:"schedule"`
+	Timeout   string   `yaml:"timeout"`
+	Retries   int      `yaml:"retries"`
+	NoOverlap bool     `yaml:"no_overlap"`
+}
+
+// TimeoutDuration parses the timeout of a job, defaulting to an hour
+func (j Job) TimeoutDuration() (time.Duration, error) {
+	return parseTimeout(j.Timeout)
+}
+
+// Workflow is a sequence of once-off tasks run in order by a state machine, with an
+// optional step that is run if any of them fail
+type Workflow struct {
+	Schedule  string         `yaml:"schedule"`
+	Steps     []WorkflowStep `yaml:"steps"`
+	OnFailure *WorkflowStep  `yaml:"on_failure"`
+}
+
+// WorkflowStep is a task run from a compose service as part of a workflow
+type WorkflowStep struct {
+	Name    string   `yaml:"name"`
+	Service string   `yaml:"service"`
+	Command []string `yaml:"command"`
+	Timeout string   `yaml:"timeout"`
+	Retries int      `yaml:"retries"`
+}
+
+// TimeoutDuration parses the timeout of a step, defaulting to an hour
+func (s WorkflowStep) TimeoutDuration() (time.Duration, error) {
+	return parseTimeout(s.Timeout)
+}
+
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+type Notifications struct {
+	Slack   *SlackNotification   `yaml:"slack"`
+	Webhook *WebhookNotification `yaml:"webhook"`
+	SNS     *SNSNotification     `yaml:"sns"`
+}
+
+type SlackNotification struct {
+	WebhookURL string `yaml:"webhook_url"`
+	Channel    string `yaml:"channel"`
+	Username   string `yaml:"username"`
+}
+
+type WebhookNotification struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type SNSNotification struct {
+	TopicArn string `yaml:"topic_arn"`
+}
+
+// Load reads a config file, a missing file results in an empty config
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse parses and validates a config, invalid configs are validation failures
+func Parse(b []byte) (*Config, error) {
+	var c Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, failure.Wrap(failure.Validation, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, failure.Wrap(failure.Validation, err)
+	}
+	return &c, nil
+}
+
+func (c *Config) validate() error {
+	switch c.Type {
+	case "", ServiceTypeWeb, ServiceTypeWorker:
+	default:
+		return fmt.Errorf("Unknown service type %q, expected %s or %s", c.Type, ServiceTypeWeb, ServiceTypeWorker)
+	}
+	for _, t := range c.SmokeTests {
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("Smoke test %q %v", t, err)
+		}
+	}
+	for name, job := range c.Jobs {
+		if job.Service == "" {
+			return fmt.Errorf("Job %q needs a service to run", name)
+		}
+		if _, err := job.TimeoutDuration(); err != nil {
+			return fmt.Errorf("Job %q has an invalid timeout: %v", name, err)
+		}
+	}
+	for name, w := range c.Workflows {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("Workflow %q %v", name, err)
+		}
+	}
+	for name, g := range c.InstanceGroups {
+		if !instanceGroupName.MatchString(name) || name == "default" {
+			return fmt.Errorf("Instance group %q needs a lowercase name other than default", name)
+		}
+		if err := g.validate(); err != nil {
+			return fmt.Errorf("Instance group %q %v", name, err)
+		}
+	}
+	_, err := c.DeployOrder()
+	return err
+}
+
+func (w Workflow) validate() error {
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("needs at least one step")
+	}
+	steps := append([]WorkflowStep{}, w.Steps...)
+	if w.OnFailure != nil {
+		steps = append(steps, *w.OnFailure)
+	}
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if step.Name == "" || step.Service == "" {
+			return fmt.Errorf("has a step without a name or service")
+		}
+		if seen[step.Name] {
+			return fmt.Errorf("has more than one step named %q", step.Name)
+		}
+		seen[step.Name] = true
+		if _, err := step.TimeoutDuration(); err != nil {
+			return fmt.Errorf("step %q has an invalid timeout: %v", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// DeployOrder returns the names of the services in an order where each comes after the
+// services it depends on, sorted by name where the order doesn't matter
+func (c *Config) DeployOrder() ([]string, error) {
+	names := []string{}
+	for name, s := range c.Services {
+		names = append(names, name)
+		for _, dep := range s.DependsOn {
+			if _, ok := c.Services[dep]; !ok {
+				return nil, fmt.Errorf("Service %q depends on unknown service %q", name, dep)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	order := []string{}
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("Service %q has a circular dependency", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		deps := append([]string{}, c.Services[name].DependsOn...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
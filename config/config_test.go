@@ -0,0 +1,218 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadComplexExample(t *testing.T) {
+	cfg, err := Load("../examples/complex/ecsy.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Hooks.PreDeploy) != 1 {
+		t.Fatalf("Expected 1 pre-deploy hook, got %d", len(cfg.Hooks.PreDeploy))
+	}
+
+	hook := cfg.Hooks.PreDeploy[0]
+	if hook.Service != "user" || len(hook.Command) != 3 {
+		t.Fatalf("Unexpected hook %#v", hook)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load("../examples/helloworld/ecsy.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Hooks.PreDeploy) > 0 || len(cfg.Hooks.PostDeploy) > 0 {
+		t.Fatalf("Expected an empty config, got %#v", cfg)
+	}
+}
+
+func TestEnvironmentOverlay(t *testing.T) {
+	cfg, err := Parse([]byte(`
+cluster: staging
+count: 1
+environments:
+  production:
+    cluster: production
+    count: 6
+    role_arn: arn:aws:iam::123456789012:role/deploy
+    require_approval: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prod, err := cfg.ForEnvironment("production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.Cluster != "production" || prod.Count != 6 || prod.RoleArn == "" || !prod.RequireApproval {
+		t.Fatalf("Unexpected production settings %#v", prod.Settings)
+	}
+	if cfg.Cluster != "staging" || cfg.RequireApproval {
+		t.Fatalf("Base config was modified")
+	}
+
+	if _, err = cfg.ForEnvironment("qa"); err == nil {
+		t.Fatalf("Expected an error for an undefined environment")
+	}
+}
+
+func TestUnknownServiceType(t *testing.T) {
+	if _, err := Parse([]byte("type: cron\n")); err == nil {
+		t.Fatalf("Expected an error for an unknown service type")
+	}
+
+	cfg, err := Parse([]byte("type: worker\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Type != ServiceTypeWorker {
+		t.Fatalf("Expected a worker, got %q", cfg.Type)
+	}
+}
+
+func TestWorkflowSteps(t *testing.T) {
+	cfg, err := Parse([]byte(`
+workflows:
+  etl:
+    steps:
+      - {name: extract, service: app, command: [./extract]}
+      - {name: load, service: app, retries: 2, timeout: 10m}
+    on_failure: {name: cleanup, service: app}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etl := cfg.Workflows["etl"]; len(etl.Steps) != 2 || etl.OnFailure.Name != "cleanup" {
+		t.Fatalf("Unexpected workflow %#v", etl)
+	}
+
+	if _, err := Parse([]byte("workflows:\n  etl:\n    steps:\n      - {name: a, service: app}\n      - {name: a, service: app}\n")); err == nil {
+		t.Fatalf("Expected an error for duplicate step names")
+	}
+}
+
+func TestDeployOrder(t *testing.T) {
+	cfg, err := Parse([]byte(`
+services:
+  web: {depends_on: [api]}
+  api: {depends_on: [db-migrate, auth]}
+  auth: {}
+  db-migrate: {}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := cfg.DeployOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"auth", "db-migrate", "api", "web"}) {
+		t.Fatalf("Unexpected deploy order %v", order)
+	}
+
+	if _, err := Parse([]byte("services:\n  a: {depends_on: [b]}\n  b: {depends_on: [a]}\n")); err == nil {
+		t.Fatalf("Expected an error for a circular dependency")
+	}
+}
+
+func TestInstanceGroups(t *testing.T) {
+	cfg, err := Parse([]byte(`
+instance_groups:
+  gpu:
+    type: g4dn.xlarge
+    count: 1
+    max: 2
+    gpu: true
+    attributes: {pool: gpu}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := cfg.InstanceGroups["gpu"]; g.Type != "g4dn.xlarge" || !g.GPU || g.Attributes["pool"] != "gpu" {
+		t.Fatalf("Unexpected instance group %#v", g)
+	}
+
+	for _, bad := range []string{
+		"instance_groups:\n  gpu: {count: 1, max: 1}\n",
+		"instance_groups:\n  gpu: {type: g4dn.xlarge, count: 3, max: 2}\n",
+		"instance_groups:\n  default: {type: m5.large, max: 1}\n",
+		"instance_groups:\n  gpu: {type: g4dn.xlarge, max: 1, attributes: {pool: \"it's\"}}\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestResolveValues(t *testing.T) {
+	cfg, err := Parse([]byte(`
+cluster: ssm:/myapp/cluster
+notifications:
+  slack: {webhook_url: "ssm-secure:/myapp/slack"}
+instance_groups:
+  gpu: {type: g4dn.xlarge, max: 1, attributes: {pool: "ssm:/myapp/pool"}}
+environments:
+  prod: {cluster: "ssm:/myapp/prod"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{
+		"ssm:/myapp/cluster":      "example",
+		"ssm-secure:/myapp/slack": "https://hooks.slack.com/x",
+		"ssm:/myapp/pool":         "gpu",
+	}
+	err = cfg.ResolveValues(func(s string) (string, error) {
+		if v, ok := values[s]; ok {
+			return v, nil
+		}
+		return s, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Cluster != "example" || cfg.Notifications.Slack.WebhookURL != "https://hooks.slack.com/x" || cfg.InstanceGroups["gpu"].Attributes["pool"] != "gpu" {
+		t.Fatalf("Unexpected config %#v", cfg)
+	}
+	if cfg.Environments["prod"].Cluster != "ssm:/myapp/prod" {
+		t.Fatalf("Expected other environments to be left alone, got %q", cfg.Environments["prod"].Cluster)
+	}
+}
+
+func TestSmokeTests(t *testing.T) {
+	cfg, err := Parse([]byte(`
+smoke_tests:
+  - path: /status
+    body: "ok"
+  - name: integration
+    service: app
+    command: [bin/smoke]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.SmokeTests) != 2 || cfg.SmokeTests[0].ExpectedStatus() != 200 || cfg.SmokeTests[1].String() != "integration" {
+		t.Fatalf("Unexpected smoke tests %#v", cfg.SmokeTests)
+	}
+
+	for _, bad := range []string{
+		"smoke_tests:\n  - {status: 200}\n",
+		"smoke_tests:\n  - {path: /, service: app}\n",
+		"smoke_tests:\n  - {service: app, status: 200}\n",
+		"smoke_tests:\n  - {path: /, body: \"(\"}\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+)
+
+// ResolveValues replaces each string value in the config with what resolve returns for it,
+// such as the values of parameter store references. Other environments are left alone, as
+// they may be in accounts that the current credentials can't read.
+func (c *Config) ResolveValues(resolve func(string) (string, error)) error {
+	envs := c.Environments
+	c.Environments = nil
+	defer func() { c.Environments = envs }()
+
+	return resolveValue(reflect.ValueOf(c).Elem(), resolve)
+}
+
+func resolveValue(v reflect.Value, resolve func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.String:
+		resolved, err := resolve(v.String())
+		if err != nil {
+			return err
+		}
+		if v.CanSet() {
+			v.SetString(resolved)
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return resolveValue(v.Elem(), resolve)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := resolveValue(v.Field(i), resolve); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveValue(v.Index(i), resolve); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map values aren't addressable, so are resolved in a copy and set back
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := resolveValue(elem, resolve); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
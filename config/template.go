@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/lox/ecsy/failure"
+)
+
+// TemplateData is what placeholders in config and task definition files are resolved from,
+// like {{ .ImageTag }}, {{ .Vars.name }}, {{ .Env.REGION }} or {{ .Outputs.KmsKeyArn }}
+type TemplateData struct {
+	ImageTag string
+	Cluster  string
+	Vars     map[string]string
+	Env      map[string]string
+	Outputs  map[string]string
+}
+
+// Environ returns the process environment as a map for templates
+func Environ() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		pieces := strings.SplitN(kv, "=", 2)
+		if len(pieces) == 2 {
+			env[pieces[0]] = pieces[1]
+		}
+	}
+	return env
+}
+
+// placeholder matches a template action that refers to the template data, files without
+// one are used as they are, so a literal {{ like a docker --format string keeps working
+var placeholder = regexp.MustCompile(`\{\{[^}]*\.(ImageTag|Cluster|Vars|Env|Outputs)\b`)
+
+// Render resolves the go template placeholders in a file. Unknown variables are errors
+// rather than being rendered empty, so a typo can't deploy a blank value.
+func Render(name string, b []byte, data TemplateData) ([]byte, error) {
+	if !placeholder.Match(b) {
+		return b, nil
+	}
+
+	// values that aren't set are left out, so that using them is an error too
+	values := map[string]interface{}{
+		"Vars":    nonNil(data.Vars),
+		"Env":     nonNil(data.Env),
+		"Outputs": nonNil(data.Outputs),
+	}
+	if data.ImageTag != "" {
+		values["ImageTag"] = data.ImageTag
+	}
+	if data.Cluster != "" {
+		values["Cluster"] = data.Cluster
+	}
+
+	tpl, err := template.New(name).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return nil, failure.Errorf(failure.Validation, "Failed to parse template %s: %v", name, err)
+	}
+
+	var out bytes.Buffer
+	if err = tpl.Execute(&out, values); err != nil {
+		return nil, failure.Errorf(failure.Validation, "Failed to render template %s: %v", name, err)
+	}
+	return out.Bytes(), nil
+}
+
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// LoadTemplate reads a config file like Load, rendering any placeholders in it first
+func LoadTemplate(path string, data TemplateData) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if b, err = Render(path, b, data); err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	b, err := Render("test", []byte(`image: app:{{ .ImageTag }} region: {{ .Env.REGION }} key: {{ .Outputs.KmsKeyArn }}`), TemplateData{
+		ImageTag: "abc123",
+		Env:      map[string]string{"REGION": "us-east-1"},
+		Outputs:  map[string]string{"KmsKeyArn": "arn:aws:kms:key"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "image: app:abc123 region: us-east-1 key: arn:aws:kms:key"; string(b) != expected {
+		t.Fatalf("Expected %q, got %q", expected, b)
+	}
+}
+
+func TestRenderUnknownVariables(t *testing.T) {
+	for _, tpl := range []string{`{{ .ImageTag }}`, `{{ .Env.MISSING }}`, `{{ .Vars.missing }}`, `{{ .Vars }}{{ .Unknown }}`} {
+		_, err := Render("test", []byte(tpl), TemplateData{})
+		if err == nil || !strings.Contains(err.Error(), "Failed to render") {
+			t.Fatalf("Expected %s to fail to render, got %v", tpl, err)
+		}
+	}
+}
+
+func TestRenderWithoutPlaceholders(t *testing.T) {
+	for _, tpl := range []string{
+		`command: docker ps --format '{{.Names}}'`,
+		`command: echo {{ not a template`,
+		`image: app:latest`,
+	} {
+		b, err := Render("test", []byte(tpl), TemplateData{ImageTag: "abc123"})
+		if err != nil {
+			t.Fatalf("Expected %s to be left alone, got %v", tpl, err)
+		}
+		if string(b) != tpl {
+			t.Fatalf("Expected %q to be left alone, got %q", tpl, b)
+		}
+	}
+
+	b, err := Render("test", []byte(`image: app:{{ .ImageTag }} command: docker ps --format '{{ "{{" }}.Names}}'`), TemplateData{ImageTag: "abc123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `image: app:abc123 command: docker ps --format '{{.Names}}'`; string(b) != expected {
+		t.Fatalf("Expected %q, got %q", expected, b)
+	}
+}
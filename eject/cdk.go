@@ -0,0 +1,113 @@
+package eject
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lox/ecsy/templates"
+)
+
+// maskedValue is what cloudformation describes the values of NoEcho parameters as
+const maskedValue = "****"
+
+// CDK generates a typescript cdk app that adopts each stack in place with CfnInclude, so
+// that resources can be moved to higher level constructs incrementally. The values of NoEcho
+// parameters can't be read back, so they become CfnParameters that are given to cdk deploy,
+// which are returned as stack:parameter.
+func CDK(stacks []Stack) (map[string][]byte, []string) {
+	files := map[string][]byte{}
+	secrets := []string{}
+
+	var app bytes.Buffer
+	app.WriteString("import * as cdk from 'aws-cdk-lib';\n")
+	for _, s := range stacks {
+		fmt.Fprintf(&app, "import { %sStack } from '../lib/%s';\n", pascalCase(s.Name), s.Name)
+	}
+	app.WriteString("\nconst app = new cdk.App();\n")
+	for _, s := range stacks {
+		fmt.Fprintf(&app, "new %sStack(app, %q);\n", pascalCase(s.Name), s.Name)
+	}
+	files["bin/app.ts"] = app.Bytes()
+
+	for _, s := range stacks {
+		var b bytes.Buffer
+		b.WriteString("import * as cdk from 'aws-cdk-lib';\n")
+		b.WriteString("import * as cfninc from 'aws-cdk-lib/cloudformation-include';\n")
+		b.WriteString("import { Construct } from 'constructs';\n\n")
+		fmt.Fprintf(&b, "export class %sStack extends cdk.Stack {\n", pascalCase(s.Name))
+		b.WriteString("  constructor(scope: Construct, id: string, props?: cdk.StackProps) {\n")
+		b.WriteString("    super(scope, id, props);\n\n")
+
+		keys := []string{}
+		for k := range s.Parameters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		noEcho := templates.NoEchoParameters(s.Template)
+		masked := map[string]bool{}
+		for _, k := range keys {
+			if noEcho[k] || s.Parameters[k] == maskedValue {
+				masked[k] = true
+				secrets = append(secrets, s.Name+":"+k)
+				fmt.Fprintf(&b, "    const %s = new cdk.CfnParameter(this, %q, { type: 'String', noEcho: true });\n", lowerCamel(k), k)
+			}
+		}
+		if len(masked) > 0 {
+			b.WriteString("\n")
+		}
+
+		b.WriteString("    new cfninc.CfnInclude(this, 'Template', {\n")
+		fmt.Fprintf(&b, "      templateFile: 'templates/%s.yml',\n", s.Name)
+		b.WriteString("      parameters: {\n")
+		for _, k := range keys {
+			if masked[k] {
+				fmt.Fprintf(&b, "        %s: %s.valueAsString,\n", k, lowerCamel(k))
+			} else {
+				fmt.Fprintf(&b, "        %s: %q,\n", k, s.Parameters[k])
+			}
+		}
+
+		b.WriteString("      },\n    });\n  }\n}\n")
+		files["lib/"+s.Name+".ts"] = b.Bytes()
+		files["templates/"+s.Name+".yml"] = []byte(s.Template)
+	}
+
+	return files, secrets
+}
+
+// lowerCamel converts a name like AlertWebhookUrl to alertWebhookUrl, for a variable
+func lowerCamel(s string) string {
+	s = pascalCase(s)
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// CDKSteps describes how to move from the stacks to the generated cdk app
+func CDKSteps(stacks []Stack) []string {
+	steps := []string{
+		"npm install aws-cdk-lib constructs",
+		"cdk diff, which should show no changes to resources",
+	}
+	for _, s := range stacks {
+		noEcho := templates.NoEchoParameters(s.Template)
+		flags := []string{}
+		for k, v := range s.Parameters {
+			if noEcho[k] || v == maskedValue {
+				flags = append(flags, fmt.Sprintf("--parameters %s:%s=...", s.Name, k))
+			}
+		}
+		sort.Strings(flags)
+		if len(flags) == 0 {
+			steps = append(steps, fmt.Sprintf("cdk deploy %s to take over the existing stack", s.Name))
+		} else {
+			steps = append(steps, fmt.Sprintf("cdk deploy %s %s to take over the existing stack, with the values of its NoEcho parameters",
+				s.Name, strings.Join(flags, " ")))
+		}
+	}
+	return steps
+}
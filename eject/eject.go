@@ -0,0 +1,40 @@
+// Package eject generates terraform and cdk code for adopting the resources that ecsy
+// manages, for teams that have outgrown it
+package eject
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Stack is a cloudformation stack created by ecsy and the resources within it
+type Stack struct {
+	Name       string
+	Template   string
+	Parameters map[string]string
+	Resources  []Resource
+}
+
+type Resource struct {
+	LogicalID  string
+	Type       string
+	PhysicalID string
+}
+
+var identifierRegex = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// identifier converts a name into something usable as a code identifier
+func identifier(parts ...string) string {
+	return strings.Trim(identifierRegex.ReplaceAllString(strings.Join(parts, "_"), "_"), "_")
+}
+
+// pascalCase converts a name like ecs-example-cluster to EcsExampleCluster
+func pascalCase(s string) string {
+	var out string
+	for _, p := range identifierRegex.Split(s, -1) {
+		if p != "" {
+			out += strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package eject
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerraformImportsServices(t *testing.T) {
+	files, unsupported := Terraform([]Stack{{
+		Name:       "ecs-example-app-service",
+		Parameters: map[string]string{"ECSCluster": "example"},
+		Resources: []Resource{
+			{LogicalID: "ECSService", Type: "AWS::ECS::Service", PhysicalID: "arn:aws:ecs:us-east-1:123456789012:service/app-ECSService-1"},
+			{LogicalID: "Mystery", Type: "AWS::Custom::Thing", PhysicalID: "x"},
+		},
+	}})
+
+	tf := string(files["ecs-example-app-service.tf"])
+	if !strings.Contains(tf, "to = aws_ecs_service.ecs_example_app_service_ECSService") ||
+		!strings.Contains(tf, `id = "example/app-ECSService-1"`) {
+		t.Fatalf("Unexpected terraform:\n%s", tf)
+	}
+	if len(unsupported) != 1 {
+		t.Fatalf("Expected the custom resource to be unsupported, got %v", unsupported)
+	}
+}
+
+func TestCDKPassesNoEchoParameters(t *testing.T) {
+	files, secrets := CDK([]Stack{{
+		Name:       "ecs-example",
+		Template:   "Parameters:\n  AlertWebhookUrl:\n    Type: String\n    NoEcho: true\n  KeyName:\n    Type: String\n",
+		Parameters: map[string]string{"AlertWebhookUrl": "****", "KeyName": "llamas"},
+	}})
+
+	ts := string(files["lib/ecs-example.ts"])
+	if strings.Contains(ts, "****") || !strings.Contains(ts, "AlertWebhookUrl: alertWebhookUrl.valueAsString") ||
+		!strings.Contains(ts, `KeyName: "llamas"`) {
+		t.Fatalf("Unexpected cdk stack:\n%s", ts)
+	}
+	if len(secrets) != 1 || secrets[0] != "ecs-example:AlertWebhookUrl" {
+		t.Fatalf("Expected the NoEcho parameter to be returned, got %v", secrets)
+	}
+}
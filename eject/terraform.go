@@ -0,0 +1,107 @@
+package eject
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// terraformResource maps a cloudformation resource type to a terraform resource type and
+// a function that derives the terraform import id from the resource
+type terraformResource struct {
+	Type     string
+	ImportID func(s Stack, r Resource) string
+}
+
+func physicalID(s Stack, r Resource) string {
+	return r.PhysicalID
+}
+
+func lastPathSegment(s Stack, r Resource) string {
+	return r.PhysicalID[strings.LastIndex(r.PhysicalID, "/")+1:]
+}
+
+var terraformResources = map[string]terraformResource{
+	"AWS::EC2::VPC":                             {"aws_vpc", physicalID},
+	"AWS::EC2::Subnet":                          {"aws_subnet", physicalID},
+	"AWS::EC2::InternetGateway":                 {"aws_internet_gateway", physicalID},
+	"AWS::EC2::RouteTable":                      {"aws_route_table", physicalID},
+	"AWS::EC2::NatGateway":                      {"aws_nat_gateway", physicalID},
+	"AWS::EC2::SecurityGroup":                   {"aws_security_group", physicalID},
+	"AWS::ECS::Cluster":                         {"aws_ecs_cluster", physicalID},
+	"AWS::ECS::TaskDefinition":                  {"aws_ecs_task_definition", physicalID},
+	"AWS::ElasticLoadBalancing::LoadBalancer":   {"aws_elb", physicalID},
+	"AWS::ElasticLoadBalancingV2::LoadBalancer": {"aws_lb", physicalID},
+	"AWS::ElasticLoadBalancingV2::TargetGroup":  {"aws_lb_target_group", physicalID},
+	"AWS::ElasticLoadBalancingV2::Listener":     {"aws_lb_listener", physicalID},
+	"AWS::IAM::Role":                            {"aws_iam_role", physicalID},
+	"AWS::IAM::InstanceProfile":                 {"aws_iam_instance_profile", physicalID},
+	"AWS::AutoScaling::AutoScalingGroup":        {"aws_autoscaling_group", physicalID},
+	"AWS::AutoScaling::LaunchConfiguration":     {"aws_launch_configuration", physicalID},
+	"AWS::EC2::LaunchTemplate":                  {"aws_launch_template", physicalID},
+	"AWS::Logs::LogGroup":                       {"aws_cloudwatch_log_group", physicalID},
+	"AWS::DynamoDB::Table":                      {"aws_dynamodb_table", physicalID},
+	"AWS::EC2::VPCEndpoint":                     {"aws_vpc_endpoint", physicalID},
+	"AWS::EC2::FlowLog":                         {"aws_flow_log", physicalID},
+	"AWS::S3::Bucket":                           {"aws_s3_bucket", physicalID},
+	"AWS::CloudFront::Distribution":             {"aws_cloudfront_distribution", physicalID},
+	"AWS::IAM::ManagedPolicy":                   {"aws_iam_policy", physicalID},
+	"AWS::RDS::DBInstance":                      {"aws_db_instance", physicalID},
+	"AWS::RDS::DBCluster":                       {"aws_rds_cluster", physicalID},
+	"AWS::RDS::DBSubnetGroup":                   {"aws_db_subnet_group", physicalID},
+	"AWS::SecretsManager::Secret":               {"aws_secretsmanager_secret", physicalID},
+	"AWS::ElastiCache::ReplicationGroup":        {"aws_elasticache_replication_group", physicalID},
+	"AWS::ElastiCache::SubnetGroup":             {"aws_elasticache_subnet_group", physicalID},
+	"AWS::SQS::Queue":                           {"aws_sqs_queue", physicalID},
+	"AWS::SNS::Topic":                           {"aws_sns_topic", physicalID},
+	"AWS::StepFunctions::StateMachine":          {"aws_sfn_state_machine", physicalID},
+	"AWS::Events::Rule":                         {"aws_cloudwatch_event_rule", physicalID},
+	"AWS::CloudWatch::Alarm":                    {"aws_cloudwatch_metric_alarm", physicalID},
+	"AWS::CloudWatch::Dashboard":                {"aws_cloudwatch_dashboard", physicalID},
+	"AWS::AutoScaling::ScheduledAction":         {"aws_autoscaling_schedule", physicalID},
+	"AWS::Budgets::Budget":                      {"aws_budgets_budget", physicalID},
+	"AWS::ServiceDiscovery::HttpNamespace":      {"aws_service_discovery_http_namespace", physicalID},
+	"AWS::ECS::Service": {"aws_ecs_service", func(s Stack, r Resource) string {
+		return s.Parameters["ECSCluster"] + "/" + lastPathSegment(s, r)
+	}},
+}
+
+// Terraform generates a file of terraform import blocks per stack, from which terraform can
+// generate the resource configuration. Resources that can't be imported are returned.
+func Terraform(stacks []Stack) (map[string][]byte, []string) {
+	files := map[string][]byte{}
+	unsupported := []string{}
+
+	for _, s := range stacks {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "# Resources managed by cloudformation stack %s\n", s.Name)
+
+		for _, r := range s.Resources {
+			tr, ok := terraformResources[r.Type]
+			if !ok || r.PhysicalID == "" {
+				unsupported = append(unsupported, fmt.Sprintf("%s %s (%s)", s.Name, r.LogicalID, r.Type))
+				continue
+			}
+			fmt.Fprintf(&b, "\nimport {\n  to = %s.%s\n  id = %q\n}\n",
+				tr.Type, identifier(s.Name, r.LogicalID), tr.ImportID(s, r))
+		}
+
+		files[s.Name+".tf"] = b.Bytes()
+	}
+
+	return files, unsupported
+}
+
+// TerraformSteps describes how to move from the stacks to terraform
+func TerraformSteps(stacks []Stack) []string {
+	steps := []string{
+		"terraform init",
+		"terraform plan -generate-config-out=generated.tf",
+		"review generated.tf, then run terraform apply to import the resources",
+	}
+	for _, s := range stacks {
+		steps = append(steps, fmt.Sprintf(
+			"set DeletionPolicy: Retain on every resource in %s, update the stack and then delete it", s.Name))
+	}
+	return steps
+}
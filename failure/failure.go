@@ -0,0 +1,137 @@
+// Package failure classifies the errors that ecsy fails with, so that the exit code of a
+// command tells scripts and CI pipelines what kind of failure happened.
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Kind is a class of failure, each with its own exit code
+type Kind int
+
+const (
+	// Unknown is any failure that isn't classified
+	Unknown Kind = iota
+	// Validation is invalid flags, arguments or config
+	Validation
+	// StackRollback is a cloudformation stack that failed and was rolled back
+	StackRollback
+	// Timeout is something that didn't finish in the time it was given
+	Timeout
+	// Auth is missing, expired or insufficient credentials
+	Auth
+	// DeployHealth is a deploy that rolled out, but failed its alarms, smoke tests or
+	// health checks
+	DeployHealth
+)
+
+var kindNames = map[Kind]string{
+	Unknown:       "unknown",
+	Validation:    "validation",
+	StackRollback: "stack-rollback",
+	Timeout:       "timeout",
+	Auth:          "auth",
+	DeployHealth:  "deploy-health",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// ExitCode is what ecsy exits with for a kind of failure, 1 for unknown failures and
+// counting up from 2 for the rest, which scripts can rely on
+func (k Kind) ExitCode() int {
+	return int(k) + 1
+}
+
+// Kinds returns each kind of failure in order of exit code
+func Kinds() []Kind {
+	return []Kind{Unknown, Validation, StackRollback, Timeout, Auth, DeployHealth}
+}
+
+// Error is an error of a known kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap classifies an error as a kind of failure, returning nil for a nil error
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Errorf returns a formatted error of a kind of failure
+func Errorf(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Classifier is implemented by errors that know what kind of failure they are
+type Classifier interface {
+	FailureKind() Kind
+}
+
+// authErrorCodes are the codes of aws errors caused by credentials
+var authErrorCodes = map[string]bool{
+	"NoCredentialProviders":       true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"InvalidClientTokenId":        true,
+	"UnrecognizedClientException": true,
+	"SignatureDoesNotMatch":       true,
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"AuthFailure":                 true,
+	"UnauthorizedOperation":       true,
+}
+
+// KindOf returns the kind of failure an error is. Errors that aren't classified are
+// recognised from aws credential errors and timeouts, or are Unknown.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	var c Classifier
+	if errors.As(err, &c) {
+		return c.FailureKind()
+	}
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		if authErrorCodes[aerr.Code()] {
+			return Auth
+		}
+		// the sdk's errors don't unwrap, so the errors they were caused by are checked
+		if aerr.OrigErr() != nil {
+			return KindOf(aerr.OrigErr())
+		}
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout()) {
+		return Timeout
+	}
+	return Unknown
+}
+
+// ExitCode returns what ecsy exits with for an error, 0 for none
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return KindOf(err).ExitCode()
+}
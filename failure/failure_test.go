@@ -0,0 +1,46 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+type healthError struct{}
+
+func (healthError) Error() string     { return "unhealthy" }
+func (healthError) FailureKind() Kind { return DeployHealth }
+
+func TestKindOf(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		kind Kind
+	}{
+		{errors.New("llamas"), Unknown},
+		{Errorf(Validation, "bad flag"), Validation},
+		{fmt.Errorf("creating stack: %w", Wrap(StackRollback, errors.New("failed"))), StackRollback},
+		{healthError{}, DeployHealth},
+		{awserr.New("ExpiredToken", "token expired", nil), Auth},
+		{awserr.New("RequestError", "send request failed", context.DeadlineExceeded), Timeout},
+		{awserr.New("ValidationError", "stack does not exist", nil), Unknown},
+	} {
+		if kind := KindOf(tc.err); kind != tc.kind {
+			t.Errorf("Expected %v to be %s, got %s", tc.err, tc.kind, kind)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Fatalf("Expected no error to exit with 0, got %d", code)
+	}
+	if code := ExitCode(errors.New("llamas")); code != 1 {
+		t.Fatalf("Expected unknown errors to exit with 1, got %d", code)
+	}
+	if code := ExitCode(Wrap(DeployHealth, errors.New("unhealthy"))); code != 6 {
+		t.Fatalf("Expected deploy health failures to exit with 6, got %d", code)
+	}
+}
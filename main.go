@@ -5,6 +5,7 @@ import (
 
 	"github.com/lox/ecsy/api"
 	"github.com/lox/ecsy/cmd"
+	"github.com/lox/ecsy/failure"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -21,18 +22,106 @@ func run(args []string, exit func(code int)) {
 		`A tool for managing and deploying ECS clusters`)
 
 	app.Version(Version)
+	if Version != "" {
+		api.ToolVersion = Version
+	}
 	app.Writer(os.Stdout)
 	app.DefaultEnvars()
 	app.Terminate(exit)
 
 	cmd.ConfigureCreateCluster(app, api.DefaultServices)
 	cmd.ConfigureDeleteCluster(app, api.DefaultServices)
+	cmd.ConfigureUnprotect(app, api.DefaultServices)
 	cmd.ConfigureCreateService(app, api.DefaultServices)
 	cmd.ConfigurePollStack(app, api.DefaultServices)
 	cmd.ConfigureDeploy(app, api.DefaultServices)
 	cmd.ConfigureDumpTaskDefinition(app, api.DefaultServices)
 	cmd.ConfigureLogs(app, api.DefaultServices)
 	cmd.ConfigureRunTask(app, api.DefaultServices)
+	cmd.ConfigurePrometheusConfig(app, api.DefaultServices)
+	cmd.ConfigureTop(app, api.DefaultServices)
+	cmd.ConfigureWatch(app, api.DefaultServices)
+	cmd.ConfigureWhyStopped(app, api.DefaultServices)
+	cmd.ConfigureScale(app, api.DefaultServices)
+	cmd.ConfigureExport(app, api.DefaultServices)
+	cmd.ConfigureImport(app, api.DefaultServices)
+	cmd.ConfigureReplicate(app, api.DefaultServices)
+	cmd.ConfigureStackSet(app, api.DefaultServices)
+	cmd.ConfigureEject(app, api.DefaultServices)
+	cmd.ConfigureBootstrap(app, api.DefaultServices)
+	cmd.ConfigureIAMPolicy(app, api.DefaultServices)
+	cmd.ConfigureShowSecurity(app, api.DefaultServices)
+	cmd.ConfigureCreateDB(app, api.DefaultServices)
+	cmd.ConfigureCreateCache(app, api.DefaultServices)
+	cmd.ConfigureJobs(app, api.DefaultServices)
+	cmd.ConfigureWorkflow(app, api.DefaultServices)
+	cmd.ConfigureDiff(app, api.DefaultServices)
+	cmd.ConfigureTemplateDiff(app, api.DefaultServices)
+	cmd.ConfigureValidateTemplate(app, api.DefaultServices)
+	cmd.ConfigureUpgrade(app, api.DefaultServices)
+	cmd.ConfigureInfo(app, api.DefaultServices)
+	cmd.ConfigureSetLogRetention(app, api.DefaultServices)
+	cmd.ConfigureQuery(app, api.DefaultServices)
+	cmd.ConfigureSSH(app, api.DefaultServices)
+	cmd.ConfigureKeys(app, api.DefaultServices)
+	cmd.ConfigureBudget(app, api.DefaultServices)
+	cmd.ConfigureRefreshInstances(app, api.DefaultServices)
+	cmd.ConfigureMaintenance(app, api.DefaultServices)
+	cmd.ConfigureAgentStatus(app, api.DefaultServices)
+	cmd.ConfigureAgentUpdate(app, api.DefaultServices)
+	cmd.ConfigureCordon(app, api.DefaultServices)
+	cmd.ConfigureUncordon(app, api.DefaultServices)
+	cmd.ConfigureBalance(app, api.DefaultServices)
+	cmd.ConfigureRebalance(app, api.DefaultServices)
+	cmd.ConfigureOverview(app, api.DefaultServices)
+	cmd.ConfigureServe(app, api.DefaultServices)
+	cmd.ConfigureSync(app, api.DefaultServices)
+	cmd.ConfigureAutodeploy(app, api.DefaultServices)
+	cmd.ConfigureCI(app, api.DefaultServices)
+	cmd.ConfigureRegisterTaskDefinition(app, api.DefaultServices)
+	cmd.ConfigureGetURL(app, api.DefaultServices)
+	cmd.ConfigurePortForward(app, api.DefaultServices)
+	cmd.ConfigureLocal(app, api.DefaultServices)
+	cmd.ConfigureTimeout(app)
+	cmd.ConfigureLogin(app)
+	cmd.ConfigureConsole(app, api.DefaultServices)
+	cmd.ConfigureDoctor(app, api.DefaultServices)
+	cmd.ConfigureAudit(app, api.DefaultServices)
+	cmd.ConfigureHistory(app, api.DefaultServices)
+	cmd.ConfigureCost(app, api.DefaultServices)
+	cmd.ConfigurePrompts(app)
+	cmd.ConfigureEvents(app)
+	cmd.ConfigurePlugins(app)
 
-	kingpin.MustParse(app.Parse(args))
+	// commands ecsy doesn't have are run as ecsy-<command> plugins from the PATH
+	if ran, code := cmd.RunPlugin(app, args, api.DefaultServices); ran {
+		exit(code)
+		return
+	}
+
+	// required values that are missing are asked for when run from a terminal
+	args = cmd.PromptRequired(app, args, api.DefaultServices)
+
+	// flags and arguments that can't be parsed are checked before any command runs, so
+	// that they exit as validation failures
+	c, err := app.ParseContext(args)
+	if err != nil {
+		app.Errorf("%s, try --help", err)
+		exit(failure.Validation.ExitCode())
+		return
+	}
+
+	if c.SelectedCommand != nil {
+		api.ToolCommand = c.SelectedCommand.FullCommand()
+	}
+
+	_, err = app.Parse(args)
+	if c.SelectedCommand != nil {
+		cmd.RecordAudit(c.SelectedCommand.FullCommand(), args, api.DefaultServices, err)
+	}
+	cmd.FinishEvents(err)
+	if err != nil {
+		app.Errorf("%s", err)
+		exit(failure.ExitCode(err))
+	}
 }
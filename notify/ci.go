@@ -0,0 +1,232 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Annotation styles, for how prominently a CI build shows an annotation
+const (
+	StyleSuccess = "success"
+	StyleInfo    = "info"
+	StyleWarning = "warning"
+	StyleError   = "error"
+)
+
+// CI is the build that ecsy is running in, which shows events as annotations and keeps
+// metadata about them for later steps
+type CI interface {
+	Notifier
+	Annotate(context, style, markdown string) error
+	SetMetadata(key, value string) error
+}
+
+// DetectCI returns the CI build that ecsy is running in, or nil outside of a supported CI or
+// when ECSY_CI_ANNOTATIONS is false
+func DetectCI() CI {
+	if os.Getenv("ECSY_CI_ANNOTATIONS") == "false" {
+		return nil
+	}
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return &GitHubActions{
+			SummaryFile: os.Getenv("GITHUB_STEP_SUMMARY"),
+			OutputFile:  os.Getenv("GITHUB_OUTPUT"),
+			Commands:    os.Stdout,
+		}
+	case os.Getenv("BUILDKITE") == "true":
+		return &Buildkite{Agent: "buildkite-agent"}
+	}
+	return nil
+}
+
+// GitHubActions writes events to the job summary, fails with ::error workflow commands and
+// sets step outputs
+type GitHubActions struct {
+	SummaryFile string
+	OutputFile  string
+	Commands    io.Writer
+}
+
+func (g *GitHubActions) Notify(e Event) error {
+	if e.Type == DeployStarted {
+		return nil
+	}
+	style := eventStyle(e)
+	if err := g.Annotate(annotationContext(e), style, eventMarkdown(e)); err != nil {
+		return err
+	}
+	return setEventMetadata(g, e)
+}
+
+func (g *GitHubActions) Annotate(context, style, markdown string) error {
+	if style == StyleError || style == StyleWarning {
+		title, message := markdown, ""
+		if idx := strings.Index(markdown, "\n"); idx != -1 {
+			title, message = markdown[:idx], strings.TrimSpace(markdown[idx+1:])
+		}
+		fmt.Fprintf(g.Commands, "::%s title=%s::%s\n", style, escapeCommand(plainText(title)), escapeCommand(plainText(message)))
+	}
+	if g.SummaryFile == "" {
+		return nil
+	}
+	return appendFile(g.SummaryFile, markdown+"\n")
+}
+
+func (g *GitHubActions) SetMetadata(key, value string) error {
+	if g.OutputFile == "" {
+		return nil
+	}
+	return appendFile(g.OutputFile, fmt.Sprintf("%s=%s\n", key, value))
+}
+
+// escapeCommand escapes a value in a workflow command, which ends at a newline
+func escapeCommand(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// plainText drops the markdown emphasis that workflow commands would show literally
+func plainText(s string) string {
+	return strings.NewReplacer("**", "", "`", "").Replace(s)
+}
+
+func appendFile(path, s string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Buildkite annotates the build and sets meta-data with the buildkite-agent
+type Buildkite struct {
+	Agent string
+}
+
+func (b *Buildkite) Notify(e Event) error {
+	if e.Type == DeployStarted {
+		return nil
+	}
+	if err := b.Annotate(annotationContext(e), eventStyle(e), eventMarkdown(e)); err != nil {
+		return err
+	}
+	return setEventMetadata(b, e)
+}
+
+func (b *Buildkite) Annotate(context, style, markdown string) error {
+	cmd := exec.Command(b.Agent, "annotate", "--style", style, "--context", context)
+	cmd.Stdin = strings.NewReader(markdown)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("buildkite-agent annotate failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (b *Buildkite) SetMetadata(key, value string) error {
+	if out, err := exec.Command(b.Agent, "meta-data", "set", key, value).CombinedOutput(); err != nil {
+		return fmt.Errorf("buildkite-agent meta-data set failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// MetadataKey is the key a value about a service is kept under, like ecsy_web_url
+func MetadataKey(service, name string) string {
+	key := "ecsy"
+	if service != "" {
+		key += "_" + strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return '_'
+		}, service)
+	}
+	return key + "_" + name
+}
+
+// setEventMetadata keeps the task definition and url of a deploy for later steps
+func setEventMetadata(ci CI, e Event) error {
+	if e.TaskDefinition != "" {
+		if err := ci.SetMetadata(MetadataKey(e.Service, "task_definition"), e.TaskDefinition); err != nil {
+			return err
+		}
+	}
+	if e.URL != "" {
+		return ci.SetMetadata(MetadataKey(e.Service, "url"), e.URL)
+	}
+	return nil
+}
+
+// annotationContext groups annotations about the same service, so that a later one
+// replaces an earlier one where the CI supports it
+func annotationContext(e Event) string {
+	if e.Service == "" {
+		return "ecsy-" + e.Cluster
+	}
+	return "ecsy-" + e.Cluster + "-" + e.Service
+}
+
+func eventStyle(e Event) string {
+	switch {
+	case e.Type == DeployFailed:
+		return StyleError
+	case e.Type == DeployRolledBack || e.Error != "":
+		return StyleWarning
+	case e.Type == OverviewReported || e.Type == ServiceScaled:
+		return StyleInfo
+	}
+	return StyleSuccess
+}
+
+// eventMarkdown renders an event with a heading line, followed by its images, task
+// definition, url and error
+func eventMarkdown(e Event) string {
+	var b strings.Builder
+	switch e.Type {
+	case DeploySucceeded:
+		fmt.Fprintf(&b, "**Deployed `%s` to `%s`** in %s\n", e.Service, e.Cluster, e.Duration)
+	case DeployFailed:
+		fmt.Fprintf(&b, "**Failed to deploy `%s` to `%s`** after %s\n", e.Service, e.Cluster, e.Duration)
+	case DeployRolledBack:
+		fmt.Fprintf(&b, "**Rolled back `%s` on `%s`**\n", e.Service, e.Cluster)
+	case ClusterCreated:
+		fmt.Fprintf(&b, "**Created cluster `%s`** in %s\n", e.Cluster, e.Duration)
+	case ServiceCreated:
+		fmt.Fprintf(&b, "**Created service `%s` on `%s`** in %s\n", e.Service, e.Cluster, e.Duration)
+	case ServiceScaled:
+		fmt.Fprintf(&b, "**Scaled `%s` on `%s`** to %d tasks\n", e.Service, e.Cluster, *e.DesiredCount)
+	case OverviewReported:
+		fmt.Fprintf(&b, "**Overview of ecsy clusters**\n\n```\n%s```\n", e.Summary)
+	default:
+		fmt.Fprintf(&b, "**%s** for `%s` on `%s`\n", e.Type, e.Service, e.Cluster)
+	}
+
+	if e.Error != "" {
+		fmt.Fprintf(&b, "\n%s\n", e.Error)
+	}
+	if len(e.Images) > 0 {
+		containers := []string{}
+		for container := range e.Images {
+			containers = append(containers, container)
+		}
+		sort.Strings(containers)
+		b.WriteString("\n| Container | Image |\n| --- | --- |\n")
+		for _, container := range containers {
+			fmt.Fprintf(&b, "| %s | `%s` |\n", container, e.Images[container])
+		}
+	}
+	if e.TaskDefinition != "" {
+		fmt.Fprintf(&b, "\nTask definition `%s`\n", e.TaskDefinition)
+	}
+	if e.URL != "" {
+		fmt.Fprintf(&b, "\n%s\n", e.URL)
+	}
+	return b.String()
+}
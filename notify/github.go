@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultGitHubAPI = "https://api.github.com"
+
+// GitHubDeployment tracks a deploy as a GitHub Deployment, creating it when the
+// deploy starts and updating its status as the rollout progresses
+type GitHubDeployment struct {
+	APIURL      string
+	Token       string
+	Repository  string
+	Ref         string
+	Environment string
+	LogURL      string
+
+	deploymentID int64
+}
+
+func (g *GitHubDeployment) Notify(e Event) error {
+	switch e.Type {
+	case DeployStarted:
+		if err := g.create(e); err != nil {
+			return err
+		}
+		return g.status("in_progress", "", fmt.Sprintf("Deploying %s to %s", e.Service, e.Cluster))
+	case DeploySucceeded:
+		return g.status("success", e.URL, fmt.Sprintf("Deployed in %s", e.Duration))
+	case DeployFailed:
+		return g.status("failure", "", truncate(e.Error, 140))
+	case DeployRolledBack:
+		return g.status("error", "", "Deploy was rolled back")
+	}
+	return nil
+}
+
+func (g *GitHubDeployment) create(e Event) error {
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+
+	err := g.post(fmt.Sprintf("/repos/%s/deployments", g.Repository), map[string]interface{}{
+		"ref":               g.Ref,
+		"environment":       g.Environment,
+		"description":       fmt.Sprintf("ecsy deploy of %s to %s", e.Service, e.Cluster),
+		"auto_merge":        false,
+		"required_contexts": []string{},
+		"payload": map[string]interface{}{
+			"cluster": e.Cluster,
+			"service": e.Service,
+			"images":  e.Images,
+		},
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	g.deploymentID = resp.ID
+	return nil
+}
+
+func (g *GitHubDeployment) status(state, environmentURL, description string) error {
+	if g.deploymentID == 0 {
+		return fmt.Errorf("No GitHub deployment has been created")
+	}
+
+	body := map[string]interface{}{
+		"state":       state,
+		"environment": g.Environment,
+		"description": description,
+	}
+	if environmentURL != "" {
+		body["environment_url"] = withScheme(environmentURL)
+	}
+	if g.LogURL != "" {
+		body["log_url"] = g.LogURL
+	}
+
+	return g.post(fmt.Sprintf("/repos/%s/deployments/%d/statuses", g.Repository, g.deploymentID), body, nil)
+}
+
+func (g *GitHubDeployment) post(path string, in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	apiURL := g.APIURL
+	if apiURL == "" {
+		apiURL = defaultGitHubAPI
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(apiURL, "/")+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "token "+g.Token)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GitHub responded to %s with %s", path, resp.Status)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// withScheme returns a url with an http or https scheme, which GitHub needs to link to it,
+// treating a bare host like a load balancer's dns name as http
+func withScheme(u string) string {
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+	return "http://" + strings.TrimPrefix(u, "//")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
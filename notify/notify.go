@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type EventType string
+
+const (
+	DeployStarted    EventType = "deploy_started"
+	DeploySucceeded  EventType = "deploy_succeeded"
+	DeployFailed     EventType = "deploy_failed"
+	DeployRolledBack EventType = "deploy_rolled_back"
+	ClusterCreated   EventType = "cluster_created"
+	ServiceCreated   EventType = "service_created"
+	ServiceScaled    EventType = "service_scaled"
+	OverviewReported EventType = "overview_reported"
+)
+
+// Event describes something that happened to a service
+type Event struct {
+	Type           EventType         `json:"type"`
+	Cluster        string            `json:"cluster"`
+	Service        string            `json:"service"`
+	Images         map[string]string `json:"images,omitempty"`
+	TaskDefinition string            `json:"task_definition,omitempty"`
+	DesiredCount   *int64            `json:"desired_count,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Duration       time.Duration     `json:"-"`
+	Error          string            `json:"error,omitempty"`
+	Summary        string            `json:"summary,omitempty"`
+	Time           time.Time         `json:"time"`
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	return json.Marshal(struct {
+		event
+		DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	}{event(e), e.Duration.Seconds()})
+}
+
+// Notifier sends events somewhere
+type Notifier interface {
+	Notify(e Event) error
+}
+
+// Notifiers sends an event to many notifiers, a failed notification is logged rather
+// than failing the operation that triggered it
+type Notifiers []Notifier
+
+func (n Notifiers) Notify(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	for _, notifier := range n {
+		if err := notifier.Notify(e); err != nil {
+			log.Printf("Failed to send %s notification: %v", e.Type, err)
+		}
+	}
+}
+
+// Webhook posts events as json to a url
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+}
+
+func (w *Webhook) Notify(e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return postJSON(w.URL, w.Headers, b)
+}
+
+func postJSON(url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s responded with %s", url, resp.Status)
+	}
+	return nil
+}
+
+// Publisher is the subset of the sns api used to publish events
+type Publisher interface {
+	Publish(topicArn, subject, message string, attributes map[string]string) error
+}
+
+// SNS publishes events as json to an sns topic, with the event type as a message
+// attribute so that subscribers can filter on it
+type SNS struct {
+	TopicArn  string
+	Publisher Publisher
+}
+
+func (s *SNS) Notify(e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("ecsy %s %s", e.Type, e.Cluster)
+	attributes := map[string]string{
+		"event_type": string(e.Type),
+		"cluster":    e.Cluster,
+	}
+	if e.Service != "" {
+		attributes["service"] = e.Service
+	}
+
+	return s.Publisher.Publish(s.TopicArn, subject, string(b), attributes)
+}
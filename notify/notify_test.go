@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookPostsEvent(t *testing.T) {
+	var received map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &received); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	defer server.Close()
+
+	n := Notifiers{&Webhook{URL: server.URL}}
+	n.Notify(Event{
+		Type:     DeploySucceeded,
+		Cluster:  "example",
+		Service:  "app",
+		Duration: 90 * time.Second,
+	})
+
+	if received["type"] != "deploy_succeeded" {
+		t.Fatalf("Unexpected event type %v", received["type"])
+	}
+	if received["duration_seconds"] != float64(90) {
+		t.Fatalf("Unexpected duration %v", received["duration_seconds"])
+	}
+}
+
+func TestSlackMessageIncludesImages(t *testing.T) {
+	s := &Slack{}
+	msg := s.message(Event{
+		Type:    DeployStarted,
+		Cluster: "example",
+		Service: "app",
+		Images:  map[string]string{"app": "example/app:v2"},
+	})
+
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Fields[0].Value != "app: `example/app:v2`" {
+		t.Fatalf("Unexpected message %#v", msg)
+	}
+}
+
+func TestSlackMessageIncludesOverview(t *testing.T) {
+	s := &Slack{}
+	msg := s.message(Event{
+		Type:    OverviewReported,
+		Summary: "CLUSTER  INSTANCES\nexample  2/3\n",
+		Error:   "example has 1 inactive instances",
+	})
+
+	if msg.Text != "Overview of ecsy clusters\n```\nCLUSTER  INSTANCES\nexample  2/3\n```" {
+		t.Fatalf("Unexpected text %q", msg.Text)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Color != "warning" {
+		t.Fatalf("Expected problems to be attached as a warning, got %#v", msg.Attachments)
+	}
+}
+
+func TestGitHubDeploymentStatuses(t *testing.T) {
+	var states, urls []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		switch r.URL.Path {
+		case "/repos/lox/ecsy/deployments":
+			w.Write([]byte(`{"id": 42}`))
+		case "/repos/lox/ecsy/deployments/42/statuses":
+			states = append(states, body["state"].(string))
+			if u, ok := body["environment_url"].(string); ok {
+				urls = append(urls, u)
+			}
+		default:
+			t.Fatalf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	g := &GitHubDeployment{APIURL: server.URL, Repository: "lox/ecsy", Ref: "abc123"}
+	n := Notifiers{g}
+	n.Notify(Event{Type: DeployStarted, Cluster: "example", Service: "app"})
+	n.Notify(Event{Type: DeploySucceeded, Cluster: "example", Service: "app", URL: "https://example.org:443"})
+	n.Notify(Event{Type: DeploySucceeded, Cluster: "example", Service: "app", URL: "example-1234.us-east-1.elb.amazonaws.com"})
+
+	if len(states) != 3 || states[0] != "in_progress" || states[1] != "success" {
+		t.Fatalf("Unexpected statuses %v", states)
+	}
+	if len(urls) != 2 || urls[0] != "https://example.org:443" || urls[1] != "http://example-1234.us-east-1.elb.amazonaws.com" {
+		t.Fatalf("Expected environment urls with a scheme, got %v", urls)
+	}
+}
+
+func TestGitHubActionsSummaryAndOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsy-notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var commands bytes.Buffer
+	g := &GitHubActions{
+		SummaryFile: filepath.Join(dir, "summary"),
+		OutputFile:  filepath.Join(dir, "output"),
+		Commands:    &commands,
+	}
+	n := Notifiers{g}
+	n.Notify(Event{
+		Type:           DeploySucceeded,
+		Cluster:        "example",
+		Service:        "app",
+		Images:         map[string]string{"app": "example/app:v2"},
+		TaskDefinition: "app:2",
+	})
+	n.Notify(Event{Type: DeployFailed, Cluster: "example", Service: "app", Error: "Tasks failed to start"})
+
+	summary, _ := ioutil.ReadFile(g.SummaryFile)
+	if !strings.Contains(string(summary), "**Deployed `app` to `example`**") || !strings.Contains(string(summary), "| app | `example/app:v2` |") {
+		t.Fatalf("Unexpected summary %q", summary)
+	}
+	if output, _ := ioutil.ReadFile(g.OutputFile); string(output) != "ecsy_app_task_definition=app:2\n" {
+		t.Fatalf("Unexpected outputs %q", output)
+	}
+	if !strings.HasPrefix(commands.String(), "::error title=Failed to deploy app to example after 0s::Tasks failed to start") {
+		t.Fatalf("Unexpected workflow commands %q", commands.String())
+	}
+}
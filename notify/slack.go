@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Slack posts events to a slack incoming webhook
+type Slack struct {
+	WebhookURL string
+	Channel    string
+	Username   string
+}
+
+type slackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Fields []slackField `json:"fields"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func (s *Slack) Notify(e Event) error {
+	b, err := json.Marshal(s.message(e))
+	if err != nil {
+		return err
+	}
+	return postJSON(s.WebhookURL, nil, b)
+}
+
+func (s *Slack) message(e Event) slackMessage {
+	msg := slackMessage{
+		Channel:  s.Channel,
+		Username: s.Username,
+	}
+
+	var color string
+	switch e.Type {
+	case DeployStarted:
+		msg.Text = fmt.Sprintf("Deploying *%s* to *%s*", e.Service, e.Cluster)
+		color = "#439fe0"
+	case DeploySucceeded:
+		msg.Text = fmt.Sprintf("Deployed *%s* to *%s* in %s", e.Service, e.Cluster, e.Duration)
+		color = "good"
+	case DeployFailed:
+		msg.Text = fmt.Sprintf("Failed to deploy *%s* to *%s* after %s", e.Service, e.Cluster, e.Duration)
+		color = "danger"
+	case ClusterCreated:
+		msg.Text = fmt.Sprintf("Created cluster *%s* in %s", e.Cluster, e.Duration)
+		color = "good"
+	case ServiceCreated:
+		msg.Text = fmt.Sprintf("Created service *%s* on *%s* in %s", e.Service, e.Cluster, e.Duration)
+		color = "good"
+	case ServiceScaled:
+		msg.Text = fmt.Sprintf("Scaled *%s* on *%s* to %d tasks", e.Service, e.Cluster, *e.DesiredCount)
+		color = "#439fe0"
+	case DeployRolledBack:
+		msg.Text = fmt.Sprintf("Rolled back *%s* on *%s*", e.Service, e.Cluster)
+		color = "warning"
+	case OverviewReported:
+		msg.Text = "Overview of ecsy clusters\n```\n" + e.Summary + "```"
+		color = "good"
+		if e.Error != "" {
+			color = "warning"
+		}
+	default:
+		msg.Text = fmt.Sprintf("%s for *%s* on *%s*", e.Type, e.Service, e.Cluster)
+		color = "#cccccc"
+	}
+
+	fields := []slackField{}
+	if len(e.Images) > 0 {
+		images := []string{}
+		for container, image := range e.Images {
+			images = append(images, fmt.Sprintf("%s: `%s`", container, image))
+		}
+		sort.Strings(images)
+		fields = append(fields, slackField{Title: "Images", Value: strings.Join(images, "\n")})
+	}
+	if e.TaskDefinition != "" {
+		fields = append(fields, slackField{Title: "Task Definition", Value: e.TaskDefinition, Short: true})
+	}
+	if e.Error != "" {
+		fields = append(fields, slackField{Title: "Error", Value: e.Error})
+	}
+
+	if len(fields) > 0 {
+		msg.Attachments = []slackAttachment{{Color: color, Fields: fields}}
+	}
+	return msg
+}
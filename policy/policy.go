@@ -0,0 +1,417 @@
+// Package policy generates least privilege IAM policies for running ecsy commands
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Document struct {
+	Version   string      `json:"Version"`
+	Statement []Statement `json:"Statement"`
+}
+
+type Statement struct {
+	Sid      string   `json:"Sid,omitempty"`
+	Effect   string   `json:"Effect"`
+	Action   []string `json:"Action"`
+	Resource []string `json:"Resource"`
+}
+
+// Scope limits resources to clusters with a name prefix, an empty prefix allows all clusters
+type Scope struct {
+	ClusterPrefix string
+}
+
+// permission is a set of actions on resources derived from a scope
+type permission struct {
+	Actions   []string
+	Resources func(s Scope) []string
+}
+
+func anyResource(s Scope) []string {
+	return []string{"*"}
+}
+
+func stacks(s Scope) []string {
+	return []string{
+		fmt.Sprintf("arn:aws:cloudformation:*:*:stack/ecs-%s*/*", s.ClusterPrefix),
+		fmt.Sprintf("arn:aws:cloudformation:*:*:stack/%s*-network/*", s.ClusterPrefix),
+	}
+}
+
+func clusters(s Scope) []string {
+	return []string{fmt.Sprintf("arn:aws:ecs:*:*:cluster/%s*", s.ClusterPrefix)}
+}
+
+func services(s Scope) []string {
+	return []string{
+		fmt.Sprintf("arn:aws:ecs:*:*:service/%s*/*", s.ClusterPrefix),
+		fmt.Sprintf("arn:aws:ecs:*:*:service/ecs-%s*", s.ClusterPrefix),
+	}
+}
+
+func tasks(s Scope) []string {
+	return []string{fmt.Sprintf("arn:aws:ecs:*:*:task/%s*/*", s.ClusterPrefix), "arn:aws:ecs:*:*:task/*"}
+}
+
+func logGroups(s Scope) []string {
+	return []string{fmt.Sprintf("arn:aws:logs:*:*:log-group:ecs-%s*", s.ClusterPrefix)}
+}
+
+func lockTables(s Scope) []string {
+	return []string{fmt.Sprintf("arn:aws:dynamodb:*:*:table/ecs-%s*", s.ClusterPrefix)}
+}
+
+func auditTable(s Scope) []string {
+	return []string{"arn:aws:dynamodb:*:*:table/ecsy-audit"}
+}
+
+func roles(s Scope) []string {
+	return []string{
+		fmt.Sprintf("arn:aws:iam::*:role/ecs-%s*", s.ClusterPrefix),
+		fmt.Sprintf("arn:aws:iam::*:instance-profile/ecs-%s*", s.ClusterPrefix),
+	}
+}
+
+var (
+	readStacks = permission{[]string{
+		"cloudformation:DescribeStacks",
+		"cloudformation:DescribeStackEvents",
+		"cloudformation:DescribeStackResources",
+		"cloudformation:GetTemplate",
+	}, anyResource}
+
+	changeSets = permission{[]string{
+		"cloudformation:CreateChangeSet", "cloudformation:DescribeChangeSet", "cloudformation:ExecuteChangeSet", "cloudformation:DeleteChangeSet",
+	}, stacks}
+
+	writeStacks = permission{[]string{
+		"cloudformation:CreateStack",
+		"cloudformation:UpdateStack",
+		"cloudformation:DeleteStack",
+		"cloudformation:CancelUpdateStack",
+		"cloudformation:SetStackPolicy",
+	}, stacks}
+
+	terminationProtection = permission{[]string{"cloudformation:UpdateTerminationProtection"}, stacks}
+
+	// stack tags are propagated to the resources that support them
+	tagResources = permission{[]string{
+		"ec2:CreateTags", "autoscaling:CreateOrUpdateTags", "ecs:TagResource", "elasticloadbalancing:AddTags",
+		"iam:TagRole", "iam:TagPolicy", "iam:TagInstanceProfile", "logs:TagLogGroup", "logs:TagResource",
+		"dynamodb:TagResource", "s3:PutBucketTagging", "cloudfront:TagResource", "sqs:TagQueue", "sns:TagResource",
+		"rds:AddTagsToResource", "elasticache:AddTagsToResource", "secretsmanager:TagResource", "states:TagResource", "events:TagResource",
+	}, anyResource}
+
+	// resources that cloudformation creates on the caller's behalf for network and cluster stacks
+	clusterResources = permission{[]string{
+		"ec2:CreateVpc", "ec2:DeleteVpc", "ec2:ModifyVpcAttribute", "ec2:DescribeVpcs",
+		"ec2:CreateSubnet", "ec2:DeleteSubnet", "ec2:ModifySubnetAttribute", "ec2:DescribeSubnets",
+		"ec2:CreateInternetGateway", "ec2:DeleteInternetGateway", "ec2:AttachInternetGateway", "ec2:DetachInternetGateway", "ec2:DescribeInternetGateways",
+		"ec2:CreateRouteTable", "ec2:DeleteRouteTable", "ec2:CreateRoute", "ec2:DeleteRoute", "ec2:AssociateRouteTable", "ec2:DisassociateRouteTable", "ec2:DescribeRouteTables",
+		"ec2:AllocateAddress", "ec2:ReleaseAddress", "ec2:DescribeAddresses",
+		"ec2:CreateNatGateway", "ec2:DeleteNatGateway", "ec2:DescribeNatGateways",
+		"ec2:CreateVpcEndpoint", "ec2:DeleteVpcEndpoints", "ec2:ModifyVpcEndpoint", "ec2:DescribeVpcEndpoints",
+		"ec2:CreateFlowLogs", "ec2:DeleteFlowLogs", "ec2:DescribeFlowLogs",
+		"s3:CreateBucket", "s3:PutEncryptionConfiguration", "s3:PutBucketPublicAccessBlock", "s3:PutLifecycleConfiguration", "s3:GetBucketPolicy", "s3:PutBucketPolicy",
+		"ec2:CreateSecurityGroup", "ec2:DeleteSecurityGroup", "ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress", "ec2:DescribeSecurityGroups",
+		"ec2:DescribeAvailabilityZones", "ec2:DescribeImages", "ec2:DescribeKeyPairs", "ec2:DescribeInstanceTypeOfferings", "ec2:CreateTags", "ec2:DeleteTags",
+		"autoscaling:CreateAutoScalingGroup", "autoscaling:UpdateAutoScalingGroup", "autoscaling:DeleteAutoScalingGroup", "autoscaling:DescribeAutoScalingGroups", "autoscaling:DescribeScalingActivities",
+		"autoscaling:PutScheduledUpdateGroupAction", "autoscaling:DeleteScheduledAction", "autoscaling:DescribeScheduledActions",
+		"ec2:CreateLaunchTemplate", "ec2:DeleteLaunchTemplate", "ec2:CreateLaunchTemplateVersion", "ec2:DescribeLaunchTemplates", "ec2:DescribeLaunchTemplateVersions", "ec2:RunInstances",
+		"kms:DescribeKey", "kms:CreateGrant", "ssm:GetParameters",
+		"logs:CreateLogGroup", "logs:DeleteLogGroup", "logs:PutRetentionPolicy", "logs:AssociateKmsKey", "logs:DescribeLogGroups",
+		"dynamodb:CreateTable", "dynamodb:DeleteTable", "dynamodb:DescribeTable", "dynamodb:UpdateTimeToLive", "dynamodb:DescribeTimeToLive",
+	}, anyResource}
+
+	// resources that cloudformation creates on the caller's behalf for service stacks
+	serviceResources = permission{[]string{
+		"elasticloadbalancing:CreateLoadBalancer", "elasticloadbalancing:DeleteLoadBalancer", "elasticloadbalancing:DescribeLoadBalancers",
+		"elasticloadbalancing:ConfigureHealthCheck", "elasticloadbalancing:ModifyLoadBalancerAttributes", "elasticloadbalancing:CreateLoadBalancerListeners",
+		"elasticloadbalancing:DeleteLoadBalancerListeners", "elasticloadbalancing:ApplySecurityGroupsToLoadBalancer",
+		"ec2:CreateSecurityGroup", "ec2:DeleteSecurityGroup", "ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress", "ec2:DescribeSecurityGroups",
+		"ecs:CreateService", "ecs:DeleteService",
+		"s3:CreateBucket", "s3:PutEncryptionConfiguration", "s3:PutBucketPublicAccessBlock", "s3:GetBucketPolicy", "s3:PutBucketPolicy", "s3:DeleteBucketPolicy",
+		"cloudfront:CreateCloudFrontOriginAccessIdentity", "cloudfront:DeleteCloudFrontOriginAccessIdentity", "cloudfront:GetCloudFrontOriginAccessIdentity",
+		"cloudfront:CreateDistribution", "cloudfront:UpdateDistribution", "cloudfront:DeleteDistribution", "cloudfront:GetDistribution",
+		"sqs:CreateQueue", "sqs:DeleteQueue", "sqs:GetQueueAttributes", "sqs:SetQueueAttributes",
+		"sns:CreateTopic", "sns:DeleteTopic", "sns:GetTopicAttributes", "sns:SetTopicAttributes", "sns:Subscribe", "sns:Unsubscribe", "sns:ListSubscriptionsByTopic",
+		"logs:CreateLogGroup", "logs:DeleteLogGroup", "logs:PutRetentionPolicy", "logs:AssociateKmsKey", "logs:DescribeLogGroups",
+		"kms:DescribeKey",
+		"cloudwatch:PutMetricAlarm", "cloudwatch:DeleteAlarms", "cloudwatch:DescribeAlarms", "cloudwatch:PutDashboard", "cloudwatch:DeleteDashboards", "cloudwatch:GetDashboard",
+		"application-autoscaling:RegisterScalableTarget", "application-autoscaling:DeregisterScalableTarget", "application-autoscaling:DescribeScalableTargets",
+		"application-autoscaling:PutScheduledAction", "application-autoscaling:DeleteScheduledAction", "application-autoscaling:DescribeScheduledActions",
+	}, anyResource}
+
+	manageRoles = permission{[]string{
+		"iam:CreateRole", "iam:DeleteRole", "iam:GetRole", "iam:PutRolePolicy", "iam:DeleteRolePolicy", "iam:AttachRolePolicy", "iam:DetachRolePolicy", "iam:PassRole",
+		"iam:CreateInstanceProfile", "iam:DeleteInstanceProfile", "iam:AddRoleToInstanceProfile", "iam:RemoveRoleFromInstanceProfile",
+	}, roles}
+
+	// resources that cloudformation creates on the caller's behalf for database stacks
+	databaseResources = permission{[]string{
+		"rds:CreateDBInstance", "rds:ModifyDBInstance", "rds:DeleteDBInstance", "rds:DescribeDBInstances",
+		"rds:CreateDBCluster", "rds:ModifyDBCluster", "rds:DeleteDBCluster", "rds:DescribeDBClusters",
+		"rds:CreateDBSubnetGroup", "rds:ModifyDBSubnetGroup", "rds:DeleteDBSubnetGroup", "rds:DescribeDBSubnetGroups", "rds:AddTagsToResource",
+		"secretsmanager:CreateSecret", "secretsmanager:UpdateSecret", "secretsmanager:DeleteSecret", "secretsmanager:DescribeSecret",
+		"secretsmanager:GetSecretValue", "secretsmanager:GetRandomPassword", "secretsmanager:TagResource",
+		"iam:CreatePolicy", "iam:CreatePolicyVersion", "iam:DeletePolicy", "iam:DeletePolicyVersion", "iam:GetPolicy", "iam:ListPolicyVersions",
+		"ec2:CreateSecurityGroup", "ec2:DeleteSecurityGroup", "ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress", "ec2:DescribeSecurityGroups",
+		"kms:DescribeKey", "kms:CreateGrant",
+	}, anyResource}
+
+	// resources that cloudformation creates on the caller's behalf for cache stacks
+	cacheResources = permission{[]string{
+		"elasticache:CreateReplicationGroup", "elasticache:ModifyReplicationGroup", "elasticache:DeleteReplicationGroup", "elasticache:DescribeReplicationGroups",
+		"elasticache:CreateCacheSubnetGroup", "elasticache:ModifyCacheSubnetGroup", "elasticache:DeleteCacheSubnetGroup", "elasticache:DescribeCacheSubnetGroups",
+		"elasticache:DescribeCacheClusters", "elasticache:AddTagsToResource",
+		"ec2:CreateSecurityGroup", "ec2:DeleteSecurityGroup", "ec2:AuthorizeSecurityGroupIngress", "ec2:RevokeSecurityGroupIngress", "ec2:DescribeSecurityGroups",
+		"kms:DescribeKey", "kms:CreateGrant",
+	}, anyResource}
+
+	// task roles created by service stacks are passed to tasks on deploy
+	passRoles = permission{[]string{"iam:PassRole"}, roles}
+
+	createCluster = permission{[]string{"ecs:CreateCluster"}, anyResource}
+	deleteCluster = permission{[]string{"ecs:DeleteCluster"}, clusters}
+
+	registerTasks = permission{[]string{
+		"ecs:RegisterTaskDefinition",
+		"ecs:DescribeTaskDefinition",
+	}, anyResource}
+
+	readServices  = permission{[]string{"ecs:DescribeServices", "ecs:ListServices"}, anyResource}
+	writeServices = permission{[]string{"ecs:UpdateService"}, services}
+
+	readTasks = permission{[]string{"ecs:ListTasks", "ecs:DescribeTasks"}, anyResource}
+	runTasks  = permission{[]string{"ecs:RunTask"}, clusters}
+
+	readLogs = permission{[]string{"logs:FilterLogEvents", "logs:GetLogEvents", "logs:DescribeLogStreams"}, logGroups}
+
+	locks = permission{[]string{"dynamodb:PutItem", "dynamodb:GetItem", "dynamodb:UpdateItem", "dynamodb:DeleteItem"}, lockTables}
+
+	// the audit log is append-only, commands that change something only ever add records to it
+	writeAudit = permission{[]string{"dynamodb:PutItem"}, auditTable}
+	readAudit  = permission{[]string{"dynamodb:Query"}, auditTable}
+
+	// history correlates stack events and deployments with the calls that caused them
+	readTrail = permission{[]string{"cloudtrail:LookupEvents"}, anyResource}
+
+	readCosts = permission{[]string{"ce:GetCostAndUsage"}, anyResource}
+
+	readMetrics = permission{[]string{"cloudwatch:GetMetricStatistics"}, anyResource}
+	readAlarms  = permission{[]string{"cloudwatch:DescribeAlarms"}, anyResource}
+
+	readHealth = permission{[]string{"elasticloadbalancing:DescribeInstanceHealth", "elasticloadbalancing:DescribeTargetHealth"}, anyResource}
+
+	notifications = permission{[]string{"sns:Publish"}, anyResource}
+
+	readImages = permission{[]string{"ecr:DescribeImages", "ecr:BatchGetImage", "ecr:GetDownloadUrlForLayer", "ecr:DescribeImageScanFindings"}, anyResource}
+
+	readSecurityGroups = permission{[]string{"ec2:DescribeSecurityGroups"}, anyResource}
+
+	readInstances = permission{[]string{"ecs:ListContainerInstances", "ecs:DescribeContainerInstances", "ec2:DescribeInstances"}, anyResource}
+
+	startSessions = permission{[]string{"ssm:StartSession", "ssm:TerminateSession", "ssm:ResumeSession"}, anyResource}
+
+	pushKeys = permission{[]string{"ec2-instance-connect:SendSSHPublicKey"}, anyResource}
+
+	budgetResources = permission{[]string{"budgets:ViewBudget", "budgets:ModifyBudget"}, anyResource}
+
+	refreshInstances = permission{[]string{"autoscaling:StartInstanceRefresh", "autoscaling:DescribeInstanceRefreshes"}, anyResource}
+
+	updateAgents = permission{[]string{"ecs:UpdateContainerAgent"}, anyResource}
+
+	cordonInstances = permission{[]string{"ecs:UpdateContainerInstancesState", "ecs:PutAttributes", "ecs:DeleteAttributes"}, anyResource}
+
+	stopTasks = permission{[]string{"ecs:StopTask"}, anyResource}
+
+	// autodeploy stacks have an eventbridge rule invoking a lambda function
+	autodeployResources = permission{[]string{
+		"lambda:CreateFunction", "lambda:UpdateFunctionCode", "lambda:UpdateFunctionConfiguration", "lambda:DeleteFunction",
+		"lambda:GetFunction", "lambda:AddPermission", "lambda:RemovePermission", "lambda:TagResource",
+		"events:PutRule", "events:DeleteRule", "events:DescribeRule", "events:PutTargets", "events:RemoveTargets",
+	}, anyResource}
+
+	// overview reports the age of the AMIs that instances run
+	readAMIs = permission{[]string{"ec2:DescribeImages"}, anyResource}
+
+	// maintenance drains instances with scheduled events and has their group replace them
+	replaceInstances = permission{[]string{
+		"ec2:DescribeInstanceStatus", "ecs:UpdateContainerInstancesState", "autoscaling:TerminateInstanceInAutoScalingGroup",
+	}, anyResource}
+
+	serviceConnect = permission{[]string{
+		"servicediscovery:CreateHttpNamespace", "servicediscovery:DeleteNamespace", "servicediscovery:GetNamespace", "servicediscovery:GetOperation",
+		"servicediscovery:CreateService", "servicediscovery:DeleteService", "servicediscovery:GetService", "servicediscovery:TagResource",
+	}, anyResource}
+
+	// values in config and stack parameters can refer to parameter store parameters
+	readParameters = permission{[]string{"ssm:GetParameter"}, anyResource}
+
+	readTaskDefinitions = permission{[]string{"ecs:DescribeTaskDefinition"}, anyResource}
+	readTaskSecrets     = permission{[]string{"ssm:GetParameter", "secretsmanager:GetSecretValue"}, anyResource}
+
+	runCommands = permission{[]string{"ssm:SendCommand", "ssm:GetCommandInvocation"}, anyResource}
+
+	// resources that cloudformation creates on the caller's behalf for jobs and workflows stacks
+	jobResources = permission{[]string{
+		"states:CreateStateMachine", "states:UpdateStateMachine", "states:DeleteStateMachine", "states:DescribeStateMachine", "states:TagResource",
+		"events:PutRule", "events:DeleteRule", "events:DescribeRule", "events:PutTargets", "events:RemoveTargets",
+	}, anyResource}
+
+	// athena reads logs from and writes results to buckets, creating glue tables over them
+	queryLogs = permission{[]string{
+		"athena:StartQueryExecution", "athena:GetQueryExecution", "athena:GetQueryResults",
+		"glue:CreateDatabase", "glue:GetDatabase", "glue:CreateTable", "glue:GetTable",
+		"s3:GetBucketLocation", "s3:ListBucket", "s3:GetObject", "s3:PutObject",
+	}, anyResource}
+
+	// console sessions use federation tokens for long lived credentials, or an assumed role
+	consoleSessions = permission{[]string{"sts:GetFederationToken", "sts:AssumeRole"}, anyResource}
+
+	// quotas are checked before creating clusters and services, with what uses them
+	checkQuotas = permission{[]string{
+		"servicequotas:GetServiceQuota", "servicequotas:GetAWSDefaultServiceQuota", "servicequotas:RequestServiceQuotaIncrease",
+		"ec2:DescribeVpcs", "ec2:DescribeAddresses", "elasticloadbalancing:DescribeLoadBalancers",
+	}, anyResource}
+
+	// doctor checks the caller's permissions and the prerequisites that bootstrap creates
+	checkSetup = permission{[]string{"iam:SimulatePrincipalPolicy", "iam:GetRole", "logs:DescribeResourcePolicies"}, anyResource}
+
+	runJobs  = permission{[]string{"states:StartExecution"}, anyResource}
+	readJobs = permission{[]string{"states:DescribeExecution", "states:ListExecutions"}, anyResource}
+)
+
+var commands = map[string][]permission{
+	"create-cluster":     {readStacks, writeStacks, tagResources, clusterResources, manageRoles, createCluster, notifications, readParameters, checkQuotas, terminationProtection, writeAudit},
+	"unprotect":          {readStacks, terminationProtection, writeAudit},
+	"delete-cluster":     {readStacks, writeStacks, tagResources, clusterResources, manageRoles, deleteCluster, readParameters, writeAudit},
+	"create-service":     {readStacks, writeStacks, tagResources, serviceResources, manageRoles, registerTasks, readServices, writeServices, notifications, serviceConnect, readParameters, checkQuotas, writeAudit},
+	"deploy":             {readStacks, registerTasks, passRoles, readServices, writeServices, readTasks, runTasks, readLogs, locks, notifications, readImages, readAlarms, readParameters, writeAudit},
+	"register-taskdef":   {registerTasks, passRoles, writeAudit},
+	"get-url":            {readStacks},
+	"scale":              {readStacks, readServices, writeServices, locks, notifications, writeAudit},
+	"run-task":           {readStacks, registerTasks, readTasks, runTasks, readLogs, writeAudit},
+	"logs":               {readStacks, readLogs},
+	"top":                {readServices, readTasks, readMetrics, readLogs},
+	"watch":              {readStacks, readServices, readTasks, readHealth},
+	"why-stopped":        {readStacks, readServices, readTasks, registerTasks, readLogs},
+	"poll-stack":         {readStacks},
+	"export":             {readStacks, registerTasks},
+	"show-security":      {readStacks, readSecurityGroups},
+	"create-db":          {readStacks, writeStacks, tagResources, databaseResources, readParameters, writeAudit},
+	"create-cache":       {readStacks, writeStacks, tagResources, cacheResources, readParameters, writeAudit},
+	"jobs deploy":        {readStacks, writeStacks, tagResources, jobResources, manageRoles, registerTasks, readParameters, writeAudit},
+	"jobs run":           {readStacks, runJobs, readJobs, writeAudit},
+	"jobs list":          {readStacks, readJobs},
+	"jobs history":       {readStacks, readJobs},
+	"upgrade":            {readStacks, writeStacks, tagResources, changeSets, clusterResources, serviceResources, databaseResources, cacheResources, manageRoles, readParameters, writeAudit},
+	"info":               {readStacks},
+	"set-log-retention":  {readStacks, writeStacks, tagResources, clusterResources, serviceResources, readParameters, writeAudit},
+	"query":              {readStacks, queryLogs},
+	"budget":             {readStacks, writeStacks, tagResources, budgetResources, readParameters, writeAudit},
+	"autodeploy enable":  {readStacks, writeStacks, tagResources, autodeployResources, manageRoles, readServices, readTaskDefinitions, readParameters, writeAudit},
+	"autodeploy disable": {readStacks, writeStacks, autodeployResources, manageRoles, readParameters, writeAudit},
+	"autodeploy list":    {readStacks, readParameters},
+	"ci emit-metadata":   {readStacks, readServices, readTaskDefinitions, readParameters},
+	"refresh-instances":  {readStacks, refreshInstances, writeAudit},
+	"maintenance":        {readStacks, readInstances, readTasks, replaceInstances, writeAudit},
+	"agent-status":       {readStacks, readInstances, readParameters},
+	"agent-update":       {readStacks, readInstances, readParameters, updateAgents, replaceInstances, writeAudit},
+	"cordon":             {readInstances, cordonInstances, writeAudit},
+	"uncordon":           {readInstances, cordonInstances, writeAudit},
+	"balance":            {readServices, readTasks, readInstances},
+	"rebalance":          {readServices, readTasks, readInstances, stopTasks, writeAudit},
+	"overview":           {readStacks, readServices, readInstances, readAMIs, notifications},
+	"serve":              {readStacks, registerTasks, passRoles, readServices, writeServices, locks, notifications, writeAudit},
+	"sync run":           {readStacks, registerTasks, passRoles, readServices, writeServices, readTasks, runTasks, readLogs, locks, notifications, readImages, readAlarms, readParameters, writeAudit},
+	"sync status":        {readStacks, readServices, readImages, readParameters},
+	"ssh":                {readStacks, readInstances, startSessions, pushKeys},
+	"port-forward":       {readStacks, readServices, readTasks, readInstances, startSessions},
+	"local generate":     {readStacks, readServices, readTaskDefinitions, readTaskSecrets},
+	"local up":           {readStacks, readServices, readTaskDefinitions, readTaskSecrets},
+	"keys sync":          {readStacks, readInstances, runCommands, writeAudit},
+	"template-diff":      {readStacks},
+	"diff":               {readStacks, registerTasks, readServices},
+	"workflow deploy":    {readStacks, writeStacks, tagResources, jobResources, manageRoles, registerTasks, readParameters, writeAudit},
+	"workflow run":       {readStacks, runJobs, readJobs, writeAudit},
+	"workflow history":   {readStacks, readJobs},
+	"console":            {readStacks, readServices, consoleSessions},
+	"doctor":             {readStacks, checkSetup},
+	"audit list":         {readStacks, readAudit},
+	"history":            {readStacks, readServices, readTrail, readAudit},
+	"cost":               {readStacks, readCosts},
+}
+
+// Commands returns the commands that policies can be generated for
+func Commands() []string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForCommands generates a policy allowing the given commands to run within a scope
+func ForCommands(scope Scope, names ...string) (*Document, error) {
+	// actions keyed by the resources they apply to, so statements can be merged
+	byResources := map[string]map[string]bool{}
+
+	for _, name := range names {
+		perms, ok := commands[name]
+		if !ok {
+			return nil, fmt.Errorf("No policy is known for command %q", name)
+		}
+		for _, p := range perms {
+			key := strings.Join(p.Resources(scope), ",")
+			if byResources[key] == nil {
+				byResources[key] = map[string]bool{}
+			}
+			for _, action := range p.Actions {
+				byResources[key][action] = true
+			}
+		}
+	}
+
+	keys := []string{}
+	for key := range byResources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	doc := &Document{Version: "2012-10-17"}
+	for _, key := range keys {
+		doc.Statement = append(doc.Statement, Statement{
+			Effect:   "Allow",
+			Action:   sortedSet(byResources[key]),
+			Resource: strings.Split(key, ","),
+		})
+	}
+	return doc, nil
+}
+
+// Actions returns every action the given commands use
+func Actions(names ...string) []string {
+	set := map[string]bool{}
+	for _, name := range names {
+		for _, p := range commands[name] {
+			for _, action := range p.Actions {
+				set[action] = true
+			}
+		}
+	}
+	return sortedSet(set)
+}
+
+func sortedSet(set map[string]bool) []string {
+	s := []string{}
+	for k := range set {
+		s = append(s, k)
+	}
+	sort.Strings(s)
+	return s
+}
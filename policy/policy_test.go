@@ -0,0 +1,32 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeployPolicyIsScopedToClusterPrefix(t *testing.T) {
+	doc, err := ForCommands(Scope{ClusterPrefix: "staging"}, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, s := range doc.Statement {
+		for _, action := range s.Action {
+			if action == "ecs:UpdateService" {
+				found = true
+				if !strings.Contains(s.Resource[0], "service/staging*") {
+					t.Fatalf("Expected UpdateService to be scoped, got %v", s.Resource)
+				}
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("Expected deploy to allow ecs:UpdateService")
+	}
+
+	if _, err = ForCommands(Scope{}, "nope"); err == nil {
+		t.Fatalf("Expected an error for an unknown command")
+	}
+}
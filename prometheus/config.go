@@ -0,0 +1,116 @@
+package prometheus
+
+import (
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Target is an ecsy service exposing prometheus metrics on a host port
+type Target struct {
+	Service string
+	Port    int
+	Path    string
+}
+
+type config struct {
+	Global        *globalConfig       `yaml:"global,omitempty"`
+	ScrapeConfigs []scrapeConfig      `yaml:"scrape_configs"`
+	RemoteWrite   []remoteWriteConfig `yaml:"remote_write,omitempty"`
+}
+
+type globalConfig struct {
+	ScrapeInterval string            `yaml:"scrape_interval,omitempty"`
+	ExternalLabels map[string]string `yaml:"external_labels,omitempty"`
+}
+
+type scrapeConfig struct {
+	JobName        string          `yaml:"job_name"`
+	MetricsPath    string          `yaml:"metrics_path,omitempty"`
+	EC2SDConfigs   []ec2SDConfig   `yaml:"ec2_sd_configs,omitempty"`
+	StaticConfigs  []staticConfig  `yaml:"static_configs,omitempty"`
+	RelabelConfigs []relabelConfig `yaml:"relabel_configs,omitempty"`
+}
+
+type ec2SDConfig struct {
+	Region  string      `yaml:"region"`
+	Port    int         `yaml:"port"`
+	Filters []ec2Filter `yaml:"filters"`
+}
+
+type ec2Filter struct {
+	Name   string   `yaml:"name"`
+	Values []string `yaml:"values"`
+}
+
+type staticConfig struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+}
+
+type relabelConfig struct {
+	SourceLabels []string `yaml:"source_labels,omitempty"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+}
+
+type remoteWriteConfig struct {
+	URL string `yaml:"url"`
+}
+
+// EC2ServiceDiscovery returns scrape configs that discover the instances in an ecsy
+// cluster via their ECSCluster tag and scrape each target's host port
+func EC2ServiceDiscovery(cluster, region string, targets []Target) ([]byte, error) {
+	c := config{ScrapeConfigs: []scrapeConfig{}}
+
+	for _, t := range targets {
+		c.ScrapeConfigs = append(c.ScrapeConfigs, scrapeConfig{
+			JobName:     cluster + "/" + t.Service,
+			MetricsPath: t.Path,
+			EC2SDConfigs: []ec2SDConfig{{
+				Region: region,
+				Port:   t.Port,
+				Filters: []ec2Filter{
+					{Name: "tag:ECSCluster", Values: []string{cluster}},
+					{Name: "instance-state-name", Values: []string{"running"}},
+				},
+			}},
+			RelabelConfigs: []relabelConfig{
+				{SourceLabels: []string{"__meta_ec2_instance_id"}, TargetLabel: "instance"},
+				{TargetLabel: "cluster", Replacement: cluster},
+				{TargetLabel: "service", Replacement: t.Service},
+			},
+		})
+	}
+
+	return yaml.Marshal(c)
+}
+
+// AgentConfig returns a config for an agent running on each instance in host networking
+// mode, scraping only the targets on the local instance and pushing to a remote write url
+func AgentConfig(cluster string, targets []Target, remoteWriteURL string) ([]byte, error) {
+	c := config{
+		Global: &globalConfig{
+			ScrapeInterval: "30s",
+			ExternalLabels: map[string]string{"cluster": cluster},
+		},
+		ScrapeConfigs: []scrapeConfig{},
+	}
+
+	for _, t := range targets {
+		c.ScrapeConfigs = append(c.ScrapeConfigs, scrapeConfig{
+			JobName:     t.Service,
+			MetricsPath: t.Path,
+			StaticConfigs: []staticConfig{{
+				Targets: []string{"localhost:" + strconv.Itoa(t.Port)},
+				Labels:  map[string]string{"service": t.Service},
+			}},
+		})
+	}
+
+	if remoteWriteURL != "" {
+		c.RemoteWrite = []remoteWriteConfig{{URL: remoteWriteURL}}
+	}
+
+	return yaml.Marshal(c)
+}
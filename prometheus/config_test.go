@@ -0,0 +1,44 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+)
+
+var testTargets = []Target{
+	{Service: "app", Port: 9100, Path: "/metrics"},
+}
+
+func TestEC2ServiceDiscovery(t *testing.T) {
+	b, err := EC2ServiceDiscovery("example", "us-east-1", testTargets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"job_name: example/app",
+		"port: 9100",
+		"name: tag:ECSCluster",
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Fatalf("Expected config to contain %q, got:\n%s", expected, b)
+		}
+	}
+}
+
+func TestAgentConfig(t *testing.T) {
+	b, err := AgentConfig("example", testTargets, "https://example.org/api/v1/write")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"localhost:9100",
+		"url: https://example.org/api/v1/write",
+		"cluster: example",
+	} {
+		if !strings.Contains(string(b), expected) {
+			t.Fatalf("Expected config to contain %q, got:\n%s", expected, b)
+		}
+	}
+}
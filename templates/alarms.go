@@ -0,0 +1,183 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// AlarmOptions configure the alarms and dashboard rendered into a service stack template
+type AlarmOptions struct {
+	// LoadBalancer is the logical id of the service's load balancer, empty for workers
+	LoadBalancer string
+	// TopicArn is an optional SNS topic that alarms notify when they change state
+	TopicArn string
+	// Integrations are incident management services that alarms are routed to via a topic
+	// subscribed to their endpoint, passed in a parameter as it contains their key
+	Integrations []AlertIntegration
+}
+
+// AlertIntegration is an incident management service such as PagerDuty or Opsgenie
+type AlertIntegration struct {
+	Name string
+}
+
+// Parameter is the stack parameter with the integration's endpoint
+func (i AlertIntegration) Parameter() string {
+	return resourceName("", i.Name) + "Endpoint"
+}
+
+type dimension struct {
+	Name, Value string
+}
+
+type alarm struct {
+	Name, Description, Namespace, Metric, Statistic string
+	Dimensions                                      []dimension
+	Period, EvaluationPeriods                       int
+	Threshold                                       float64
+}
+
+// WithAlarms renders a standard pack of alarms for a service into a service stack template,
+// covering cpu, memory, pending tasks and for services behind a load balancer unhealthy hosts
+// and the rate of 5xx responses, along with a dashboard of the same metrics
+func WithAlarms(tpl string, opts AlarmOptions) string {
+	service := []dimension{
+		{"ClusterName", "!Ref ECSCluster"},
+		{"ServiceName", "!GetAtt ECSService.Name"},
+	}
+	alarms := []alarm{
+		{"HighCPUAlarm", "The service's cpu utilization is over 80%", "AWS/ECS", "CPUUtilization", "Average", service, 300, 3, 80},
+		{"HighMemoryAlarm", "The service's memory utilization is over 80%", "AWS/ECS", "MemoryUtilization", "Average", service, 300, 3, 80},
+		{"PendingTasksAlarm", "The service has had tasks pending for 15 minutes", "ECS/ContainerInsights", "PendingTaskCount", "Minimum", service, 300, 3, 0},
+	}
+	if opts.LoadBalancer != "" {
+		alarms = append(alarms, alarm{"UnhealthyHostsAlarm", "The service's load balancer has unhealthy hosts", "AWS/ELB", "UnHealthyHostCount", "Maximum",
+			[]dimension{{"LoadBalancerName", "!Ref " + opts.LoadBalancer}}, 60, 5, 0})
+	}
+
+	var resources, params bytes.Buffer
+	if len(opts.Integrations) > 0 {
+		fmt.Fprintf(&resources, "    AlertTopic:\n")
+		fmt.Fprintf(&resources, "        Type: AWS::SNS::Topic\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            Subscription:\n")
+		for _, i := range opts.Integrations {
+			fmt.Fprintf(&params, "    %s:\n", i.Parameter())
+			fmt.Fprintf(&params, "        Type: String\n")
+			fmt.Fprintf(&params, "        Description: The endpoint that %s receives alarms at\n", i.Name)
+			fmt.Fprintf(&params, "        NoEcho: true\n\n")
+
+			fmt.Fprintf(&resources, "                - Protocol: https\n")
+			fmt.Fprintf(&resources, "                  Endpoint: !Ref %s\n", i.Parameter())
+		}
+		resources.WriteString("\n")
+	}
+
+	for _, a := range alarms {
+		fmt.Fprintf(&resources, "    %s:\n", a.Name)
+		fmt.Fprintf(&resources, "        Type: AWS::CloudWatch::Alarm\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            AlarmDescription: %s\n", a.Description)
+		fmt.Fprintf(&resources, "            Namespace: %s\n", a.Namespace)
+		fmt.Fprintf(&resources, "            MetricName: %s\n", a.Metric)
+		fmt.Fprintf(&resources, "            Dimensions:\n")
+		for _, d := range a.Dimensions {
+			fmt.Fprintf(&resources, "                - Name: %s\n", d.Name)
+			fmt.Fprintf(&resources, "                  Value: %s\n", d.Value)
+		}
+		fmt.Fprintf(&resources, "            Statistic: %s\n", a.Statistic)
+		fmt.Fprintf(&resources, "            Period: %d\n", a.Period)
+		fmt.Fprintf(&resources, "            EvaluationPeriods: %d\n", a.EvaluationPeriods)
+		fmt.Fprintf(&resources, "            Threshold: %g\n", a.Threshold)
+		fmt.Fprintf(&resources, "            ComparisonOperator: GreaterThanThreshold\n")
+		fmt.Fprintf(&resources, "            TreatMissingData: notBreaching\n")
+		writeAlarmActions(&resources, opts)
+		resources.WriteString("\n")
+	}
+
+	if opts.LoadBalancer != "" {
+		fmt.Fprintf(&resources, "    ErrorRateAlarm:\n")
+		fmt.Fprintf(&resources, "        Type: AWS::CloudWatch::Alarm\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            AlarmDescription: Over 5%% of the service's responses are 5xx errors\n")
+		fmt.Fprintf(&resources, "            Metrics:\n")
+		for _, m := range []struct{ ID, Metric string }{{"errors", "HTTPCode_Backend_5XX"}, {"requests", "RequestCount"}} {
+			fmt.Fprintf(&resources, "                - Id: %s\n", m.ID)
+			fmt.Fprintf(&resources, "                  ReturnData: false\n")
+			fmt.Fprintf(&resources, "                  MetricStat:\n")
+			fmt.Fprintf(&resources, "                      Metric:\n")
+			fmt.Fprintf(&resources, "                          Namespace: AWS/ELB\n")
+			fmt.Fprintf(&resources, "                          MetricName: %s\n", m.Metric)
+			fmt.Fprintf(&resources, "                          Dimensions:\n")
+			fmt.Fprintf(&resources, "                              - Name: LoadBalancerName\n")
+			fmt.Fprintf(&resources, "                                Value: !Ref %s\n", opts.LoadBalancer)
+			fmt.Fprintf(&resources, "                      Period: 300\n")
+			fmt.Fprintf(&resources, "                      Stat: Sum\n")
+		}
+		fmt.Fprintf(&resources, "                - Id: rate\n")
+		fmt.Fprintf(&resources, "                  Label: 5xx rate\n")
+		fmt.Fprintf(&resources, "                  Expression: 100 * FILL(errors, 0) / requests\n")
+		fmt.Fprintf(&resources, "                  ReturnData: true\n")
+		fmt.Fprintf(&resources, "            EvaluationPeriods: 1\n")
+		fmt.Fprintf(&resources, "            Threshold: 5\n")
+		fmt.Fprintf(&resources, "            ComparisonOperator: GreaterThanThreshold\n")
+		fmt.Fprintf(&resources, "            TreatMissingData: notBreaching\n")
+		writeAlarmActions(&resources, opts)
+		resources.WriteString("\n")
+	}
+
+	fmt.Fprintf(&resources, "    Dashboard:\n")
+	fmt.Fprintf(&resources, "        Type: AWS::CloudWatch::Dashboard\n")
+	fmt.Fprintf(&resources, "        Properties:\n")
+	fmt.Fprintf(&resources, "            DashboardName: !Ref 'AWS::StackName'\n")
+	fmt.Fprintf(&resources, "            DashboardBody: !Sub |\n")
+	for _, line := range strings.Split(dashboardBody(opts.LoadBalancer), "\n") {
+		fmt.Fprintf(&resources, "                %s\n", line)
+	}
+	resources.WriteString("\n")
+
+	outputs := "    Dashboard:\n        Value: !Sub \"https://console.aws.amazon.com/cloudwatch/home?region=${AWS::Region}#dashboards:name=${Dashboard}\"\n\n"
+	tpl = strings.Replace(tpl, "\nParameters:\n", "\nParameters:\n"+params.String(), 1)
+	tpl = strings.Replace(tpl, "\nOutputs:\n", "\nOutputs:\n"+outputs, 1)
+	return strings.Replace(tpl, "\nResources:\n", "\nResources:\n"+resources.String(), 1)
+}
+
+func writeAlarmActions(b *bytes.Buffer, opts AlarmOptions) {
+	actions := []string{}
+	if opts.TopicArn != "" {
+		actions = append(actions, fmt.Sprintf("%q", opts.TopicArn))
+	}
+	if len(opts.Integrations) > 0 {
+		actions = append(actions, "!Ref AlertTopic")
+	}
+	if len(actions) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "            AlarmActions: [ %s ]\n", strings.Join(actions, ", "))
+	fmt.Fprintf(b, "            OKActions: [ %s ]\n", strings.Join(actions, ", "))
+}
+
+// dashboardBody is the json of a dashboard of the alarmed metrics, with placeholders for !Sub
+func dashboardBody(loadBalancer string) string {
+	service := `"ClusterName", "${ECSCluster}", "ServiceName", "${ECSService.Name}"`
+	widgets := []string{
+		dashboardWidget("CPU utilization", `["AWS/ECS", "CPUUtilization", `+service+`]`),
+		dashboardWidget("Memory utilization", `["AWS/ECS", "MemoryUtilization", `+service+`]`),
+		dashboardWidget("Tasks", `["ECS/ContainerInsights", "RunningTaskCount", `+service+`], [".", "PendingTaskCount", ".", ".", ".", "."]`),
+	}
+	if loadBalancer != "" {
+		lb := `"LoadBalancerName", "${` + loadBalancer + `}"`
+		widgets = append(widgets,
+			dashboardWidget("Requests", `["AWS/ELB", "RequestCount", `+lb+`, {"stat": "Sum"}], [".", "HTTPCode_Backend_5XX", ".", ".", {"stat": "Sum"}]`),
+			dashboardWidget("Healthy hosts", `["AWS/ELB", "HealthyHostCount", `+lb+`], [".", "UnHealthyHostCount", ".", "."]`),
+			dashboardWidget("Latency", `["AWS/ELB", "Latency", `+lb+`, {"stat": "p99"}]`),
+		)
+	}
+	return "{\n  \"widgets\": [\n" + strings.Join(widgets, ",\n") + "\n  ]\n}"
+}
+
+func dashboardWidget(title, metrics string) string {
+	return fmt.Sprintf(`    {"type": "metric", "width": 12, "height": 6, "properties": {"title": %q, "region": "${AWS::Region}", "view": "timeSeries", "metrics": [%s]}}`,
+		title, metrics)
+}
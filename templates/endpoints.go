@@ -0,0 +1,96 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// VpcEndpoints maps the names accepted for endpoints to the AWS services they need
+var VpcEndpoints = map[string][]string{
+	"ecr":            {"ecr.api", "ecr.dkr", "s3"}, // image layers are served from s3
+	"ecs":            {"ecs", "ecs-agent", "ecs-telemetry"},
+	"logs":           {"logs"},
+	"s3":             {"s3"},
+	"ssm":            {"ssm", "ssmmessages", "ec2messages"},
+	"secretsmanager": {"secretsmanager"},
+}
+
+// gateway endpoints are routed via the private route table and are free, the rest are interfaces
+var gatewayEndpoints = map[string]bool{
+	"s3":       true,
+	"dynamodb": true,
+}
+
+// WithVpcEndpoints renders VPC endpoints for the named services into a network stack template
+func WithVpcEndpoints(tpl string, names []string) (string, error) {
+	services := map[string]bool{}
+	for _, name := range names {
+		s, ok := VpcEndpoints[name]
+		if !ok {
+			return "", fmt.Errorf("Unknown vpc endpoint %q", name)
+		}
+		for _, service := range s {
+			services[service] = true
+		}
+	}
+	if len(services) == 0 {
+		return tpl, nil
+	}
+
+	sorted := []string{}
+	for service := range services {
+		sorted = append(sorted, service)
+	}
+	sort.Strings(sorted)
+
+	var b bytes.Buffer
+	var interfaces int
+	for _, service := range sorted {
+		fmt.Fprintf(&b, "    %s:\n", resourceName("VpcEndpoint", service))
+		fmt.Fprintf(&b, "        Type: AWS::EC2::VPCEndpoint\n")
+		fmt.Fprintf(&b, "        Properties:\n")
+		fmt.Fprintf(&b, "            VpcId: !Ref VPC\n")
+		fmt.Fprintf(&b, "            ServiceName: !Sub \"com.amazonaws.${AWS::Region}.%s\"\n", service)
+		if gatewayEndpoints[service] {
+			fmt.Fprintf(&b, "            VpcEndpointType: Gateway\n")
+			fmt.Fprintf(&b, "            RouteTableIds: [ !Ref RoutesPrivate ]\n")
+		} else {
+			interfaces++
+			fmt.Fprintf(&b, "            VpcEndpointType: Interface\n")
+			fmt.Fprintf(&b, "            PrivateDnsEnabled: true\n")
+			fmt.Fprintf(&b, "            SubnetIds: [ !Ref Subnet2Private, !Ref Subnet3Private ]\n")
+			fmt.Fprintf(&b, "            SecurityGroupIds: [ !Ref VpcEndpointSecurityGroup ]\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if interfaces > 0 {
+		b.WriteString(`    VpcEndpointSecurityGroup:
+        Type: AWS::EC2::SecurityGroup
+        Properties:
+            GroupDescription: Allows https from the VPC to interface endpoints
+            VpcId: !Ref VPC
+            SecurityGroupIngress:
+                - IpProtocol: tcp
+                  FromPort: 443
+                  ToPort: 443
+                  CidrIp: !FindInMap [ SubnetConfig, "VPC", "CIDR" ]
+
+`)
+	}
+
+	return strings.Replace(tpl, "\nResources:\n", "\nResources:\n"+b.String(), 1), nil
+}
+
+// resourceName turns a prefix and a name like ecr.api into a resource name like VpcEndpointEcrApi
+func resourceName(prefix, name string) string {
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		prefix += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return prefix
+}
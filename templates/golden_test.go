@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// assertGolden compares a rendered template to testdata/<name>.golden, rewriting it instead
+// when run with -update
+func assertGolden(t *testing.T, name, rendered string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(rendered), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered != string(expected) {
+		t.Fatalf("Rendered %s doesn't match %s, rerun with -update if the change is intended\n%s", name, path, rendered)
+	}
+}
+
+func baseTemplate(t *testing.T) string {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "base.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRenderGolden(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		render func(tpl string) (string, error)
+	}{
+		{"ingress", func(tpl string) (string, error) {
+			return WithIngress(tpl, "SecurityGroup", []IngressRule{
+				{FromPort: 443, ToPort: 443, CidrIP: "10.0.0.0/8"},
+				{FromPort: 8000, ToPort: 8080, SourceGroupID: "sg-1234"},
+			}), nil
+		}},
+		{"messaging", func(tpl string) (string, error) {
+			return WithMessaging(tpl, []string{"jobs"}, []string{"events"}), nil
+		}},
+		{"alarms", func(tpl string) (string, error) {
+			return WithAlarms(tpl, AlarmOptions{
+				LoadBalancer: "LoadBalancer",
+				TopicArn:     "arn:aws:sns:us-east-1:123456789012:alerts",
+				Integrations: []AlertIntegration{{Name: "pagerduty"}},
+			}), nil
+		}},
+		{"instance-groups", func(tpl string) (string, error) {
+			return WithInstanceGroups(tpl, []InstanceGroup{
+				{Name: "gpu", InstanceType: "p3.2xlarge", GPU: true, Desired: 2, Min: 1, Max: 4},
+			})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rendered, err := tc.render(baseTemplate(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, tc.name, rendered)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// IngressRule is an additional ingress rule for a security group in a template
+type IngressRule struct {
+	FromPort      int
+	ToPort        int
+	CidrIP        string
+	SourceGroupID string
+}
+
+func (r IngressRule) String() string {
+	source := r.CidrIP
+	if r.SourceGroupID != "" {
+		source = r.SourceGroupID
+	}
+	if r.FromPort == r.ToPort {
+		return fmt.Sprintf("tcp %d from %s", r.FromPort, source)
+	}
+	return fmt.Sprintf("tcp %d-%d from %s", r.FromPort, r.ToPort, source)
+}
+
+// ClusterStack renders the cluster template with ingress rules for the instances' security
+// group and instance groups
+func ClusterStack(rules []IngressRule, groups []InstanceGroup) (string, error) {
+	return WithInstanceGroups(WithIngress(EcsStack(), "SecurityGroup", rules), groups)
+}
+
+// WithIngress renders the rules as AWS::EC2::SecurityGroupIngress resources for the
+// security group resource named group, adding them to the Resources of the template
+func WithIngress(tpl, group string, rules []IngressRule) string {
+	if len(rules) == 0 {
+		return tpl
+	}
+
+	var b bytes.Buffer
+	for idx, r := range rules {
+		fmt.Fprintf(&b, "    %sIngress%d:\n", group, idx+1)
+		fmt.Fprintf(&b, "        Type: AWS::EC2::SecurityGroupIngress\n")
+		fmt.Fprintf(&b, "        Properties:\n")
+		fmt.Fprintf(&b, "            GroupId: !Ref %s\n", group)
+		fmt.Fprintf(&b, "            IpProtocol: tcp\n")
+		fmt.Fprintf(&b, "            FromPort: %d\n", r.FromPort)
+		fmt.Fprintf(&b, "            ToPort: %d\n", r.ToPort)
+		if r.SourceGroupID != "" {
+			fmt.Fprintf(&b, "            SourceSecurityGroupId: %s\n", r.SourceGroupID)
+		} else {
+			fmt.Fprintf(&b, "            CidrIp: %s\n", r.CidrIP)
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.Replace(tpl, "\nResources:\n", "\nResources:\n"+b.String(), 1)
+}
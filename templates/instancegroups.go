@@ -0,0 +1,165 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// InstanceGroupAttribute is the ECS attribute that every instance has with the name of its
+// group, for placement constraints like "attribute:ecsy.instance-group == gpu"
+const InstanceGroupAttribute = "ecsy.instance-group"
+
+// DefaultInstanceGroup is the group of the instances in the cluster's main autoscaling group
+const DefaultInstanceGroup = "default"
+
+// gpuImage is the latest ECS optimized AMI with GPU support
+const gpuImage = "{{resolve:ssm:/aws/service/ecs/optimized-ami/amazon-linux-2/gpu/recommended/image_id}}"
+
+var regionAMI = regexp.MustCompile(`(?m)^\s+([a-z0-9-]+): \{ AMIID: (ami-[0-9a-f]+) \}`)
+
+// RegionAMI returns the AMI that a cluster stack template's instances use in a region, or
+// false if the template doesn't have one for it
+func RegionAMI(tpl, region string) (string, bool) {
+	for _, m := range regionAMI.FindAllStringSubmatch(tpl, -1) {
+		if m[1] == region {
+			return m[2], true
+		}
+	}
+	return "", false
+}
+
+// InstanceGroup is an extra autoscaling group of instances in a cluster, such as a pool of
+// GPU instances for the tasks that need them
+type InstanceGroup struct {
+	Name         string
+	InstanceType string
+	ImageID      string
+	GPU          bool
+	Desired      int
+	Min          int
+	Max          int
+	Attributes   map[string]string
+}
+
+// InstanceGroupOutput is the stack output with the name of a group's autoscaling group
+func InstanceGroupOutput(name string) string {
+	return resourceName("InstanceGroup", name) + "AutoScalingGroup"
+}
+
+// WithInstanceGroups renders an autoscaling group and launch template for each instance group
+// into a cluster stack template. Instances run the same configuration as the cluster's main
+// group, with the group's instance type and attributes.
+func WithInstanceGroups(tpl string, groups []InstanceGroup) (string, error) {
+	if len(groups) == 0 {
+		return tpl, nil
+	}
+
+	var resources, outputs bytes.Buffer
+	for _, g := range groups {
+		group := resourceName("InstanceGroup", g.Name)
+
+		attributes := map[string]string{InstanceGroupAttribute: g.Name}
+		for k, v := range g.Attributes {
+			attributes[k] = v
+		}
+		attributesJSON, err := json.Marshal(attributes)
+		if err != nil {
+			return "", err
+		}
+
+		image := "!FindInMap [ AWSRegionToAMI, !Ref 'AWS::Region', AMIID ]"
+		if g.ImageID != "" {
+			image = fmt.Sprintf("%q", g.ImageID)
+		} else if g.GPU {
+			image = fmt.Sprintf("%q", gpuImage)
+		}
+
+		fmt.Fprintf(&resources, "    %sLaunchTemplate:\n", group)
+		fmt.Fprintf(&resources, "        Type: AWS::EC2::LaunchTemplate\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            LaunchTemplateData:\n")
+		fmt.Fprintf(&resources, "                SecurityGroupIds: [ !Ref SecurityGroup ]\n")
+		fmt.Fprintf(&resources, "                Monitoring: { Enabled: true }\n")
+		fmt.Fprintf(&resources, "                ImageId: %s\n", image)
+		fmt.Fprintf(&resources, "                InstanceType: %s\n", g.InstanceType)
+		fmt.Fprintf(&resources, "                IamInstanceProfile: { Arn: !GetAtt EC2InstanceProfile.Arn }\n")
+		fmt.Fprintf(&resources, "                KeyName: !If [ HasKeyName, !Ref KeyName, !Ref \"AWS::NoValue\" ]\n")
+		fmt.Fprintf(&resources, "                MetadataOptions:\n")
+		fmt.Fprintf(&resources, "                    HttpEndpoint: enabled\n")
+		fmt.Fprintf(&resources, "                    HttpTokens: required\n")
+		fmt.Fprintf(&resources, "                    HttpPutResponseHopLimit: 1\n")
+		fmt.Fprintf(&resources, "                BlockDeviceMappings:\n")
+		for _, d := range []struct {
+			Device string
+			Size   int
+		}{{"/dev/xvda", 8}, {"/dev/xvdcz", 22}} {
+			fmt.Fprintf(&resources, "                    - DeviceName: %s\n", d.Device)
+			fmt.Fprintf(&resources, "                      Ebs:\n")
+			fmt.Fprintf(&resources, "                          VolumeSize: %d\n", d.Size)
+			fmt.Fprintf(&resources, "                          VolumeType: gp2\n")
+			fmt.Fprintf(&resources, "                          Encrypted: true\n")
+			fmt.Fprintf(&resources, "                          KmsKeyId: !If [ HasKmsKey, !Ref KmsKeyArn, !Ref \"AWS::NoValue\" ]\n")
+		}
+		// instances are configured from the main launch template's metadata, then given
+		// the group's attributes before the agent registers them
+		fmt.Fprintf(&resources, "                UserData:\n")
+		fmt.Fprintf(&resources, "                    'Fn::Base64': !Sub |\n")
+		fmt.Fprintf(&resources, "                        #!/bin/bash -xve\n")
+		fmt.Fprintf(&resources, "                        yum install -y aws-cfn-bootstrap\n")
+		fmt.Fprintf(&resources, "                        /opt/aws/bin/cfn-init -v --stack ${AWS::StackName} --resource LaunchTemplate --region ${AWS::Region}\n")
+		fmt.Fprintf(&resources, "                        sed -i '/^ECS_INSTANCE_ATTRIBUTES=/d' /etc/ecs/ecs.config\n")
+		fmt.Fprintf(&resources, "                        echo 'ECS_INSTANCE_ATTRIBUTES=%s' >> /etc/ecs/ecs.config\n", attributesJSON)
+		if g.GPU {
+			fmt.Fprintf(&resources, "                        echo 'ECS_ENABLE_GPU_SUPPORT=true' >> /etc/ecs/ecs.config\n")
+		}
+		fmt.Fprintf(&resources, "                        /opt/aws/bin/cfn-signal -e $? --stack ${AWS::StackName} --resource %sAutoScalingGroup --region ${AWS::Region}\n", group)
+		resources.WriteString("\n")
+
+		fmt.Fprintf(&resources, "    %sAutoScalingGroup:\n", group)
+		fmt.Fprintf(&resources, "        Type: AWS::AutoScaling::AutoScalingGroup\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            VPCZoneIdentifier:\n")
+		fmt.Fprintf(&resources, "                - !Ref VpcPrivateSubnet1Id\n")
+		fmt.Fprintf(&resources, "                - !Ref VpcPrivateSubnet2Id\n")
+		fmt.Fprintf(&resources, "            LaunchTemplate:\n")
+		fmt.Fprintf(&resources, "                LaunchTemplateId: !Ref %sLaunchTemplate\n", group)
+		fmt.Fprintf(&resources, "                Version: !GetAtt %sLaunchTemplate.LatestVersionNumber\n", group)
+		fmt.Fprintf(&resources, "            MinSize: %d\n", g.Min)
+		fmt.Fprintf(&resources, "            MaxSize: %d\n", g.Max)
+		fmt.Fprintf(&resources, "            DesiredCapacity: %d\n", g.Desired)
+		fmt.Fprintf(&resources, "            Tags:\n")
+		fmt.Fprintf(&resources, "                - { Key: Name, Value: ecs-instance-%s, PropagateAtLaunch: true }\n", g.Name)
+		fmt.Fprintf(&resources, "                - { Key: Role, Value: ecs-instance, PropagateAtLaunch: true }\n")
+		fmt.Fprintf(&resources, "                - { Key: ECSCluster, Value: !Ref ECSCluster, PropagateAtLaunch: true }\n")
+		fmt.Fprintf(&resources, "                - { Key: InstanceGroup, Value: %s, PropagateAtLaunch: true }\n", g.Name)
+		if g.Desired > 0 {
+			fmt.Fprintf(&resources, "        CreationPolicy:\n")
+			fmt.Fprintf(&resources, "            ResourceSignal:\n")
+			fmt.Fprintf(&resources, "                Timeout: PT15M\n")
+			fmt.Fprintf(&resources, "                Count: 1\n")
+		}
+		fmt.Fprintf(&resources, "        UpdatePolicy:\n")
+		fmt.Fprintf(&resources, "            AutoScalingRollingUpdate:\n")
+		fmt.Fprintf(&resources, "                MinInstancesInService: %d\n", minInService(g))
+		fmt.Fprintf(&resources, "                MaxBatchSize: 1\n")
+		fmt.Fprintf(&resources, "                PauseTime: PT5M\n")
+		fmt.Fprintf(&resources, "                WaitOnResourceSignals: true\n\n")
+
+		fmt.Fprintf(&outputs, "    %s:\n", InstanceGroupOutput(g.Name))
+		fmt.Fprintf(&outputs, "        Value: !Ref %sAutoScalingGroup\n\n", group)
+	}
+
+	tpl = strings.Replace(tpl, "\nOutputs:\n", "\nOutputs:\n"+outputs.String(), 1)
+	return strings.Replace(tpl, "\nResources:\n", "\nResources:\n"+resources.String(), 1), nil
+}
+
+// minInService keeps an instance running during rolling updates of groups that have one
+func minInService(g InstanceGroup) int {
+	if g.Desired > 1 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,250 @@
+package templates
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Job is a task run by a step functions state machine in a jobs stack
+type Job struct {
+	Name           string
+	TaskDefinition string
+	Container      string
+	Command        []string
+	Schedule       string
+	Timeout        time.Duration
+	Retries        int
+	NoOverlap      bool
+}
+
+// JobStateMachineOutput is the stack output with the arn of the state machine for a job
+func JobStateMachineOutput(name string) string {
+	return resourceName("Job", name) + "StateMachine"
+}
+
+// object is a json object in a generated template or state machine definition
+type object map[string]interface{}
+
+// JobsStack generates a template with a state machine for each job that runs its task on
+// the cluster, retrying failures and optionally skipping runs while another is in progress.
+// Jobs with a schedule get an event rule that starts them.
+func JobsStack(cluster string, jobs []Job) (string, error) {
+	machines := []stateMachine{}
+	for _, job := range jobs {
+		machines = append(machines, stateMachine{
+			Name:            job.Name,
+			ID:              resourceName("Job", job.Name),
+			Output:          JobStateMachineOutput(job.Name),
+			Definition:      jobDefinition(cluster, job),
+			Schedule:        job.Schedule,
+			TaskDefinitions: []string{job.TaskDefinition},
+		})
+	}
+	return stateMachinesStack(stateMachinesTemplate{
+		Description: "ECS Jobs: scheduled and on demand tasks run by step functions",
+		StackType:   "ecs-former::ecs-jobs",
+		ListOutput:  "Jobs",
+		Cluster:     cluster,
+		Machines:    machines,
+	})
+}
+
+// stateMachine is a state machine in a generated stack, started by an event rule
+// if it has a schedule
+type stateMachine struct {
+	Name            string
+	ID              string
+	Output          string
+	Definition      object
+	Schedule        string
+	TaskDefinitions []string
+}
+
+type stateMachinesTemplate struct {
+	Description string
+	StackType   string
+	ListOutput  string
+	Cluster     string
+	Machines    []stateMachine
+}
+
+func stateMachinesStack(t stateMachinesTemplate) (string, error) {
+	sort.Slice(t.Machines, func(i, j int) bool {
+		return t.Machines[i].Name < t.Machines[j].Name
+	})
+
+	names := []string{}
+	taskDefinitions := []interface{}{}
+	seen := map[string]bool{}
+	scheduled := []interface{}{}
+	resources := object{}
+	outputs := object{
+		"StackType":  object{"Value": t.StackType},
+		"ECSCluster": object{"Value": t.Cluster},
+	}
+
+	for _, m := range t.Machines {
+		definition, err := json.Marshal(m.Definition)
+		if err != nil {
+			return "", err
+		}
+
+		names = append(names, m.Name)
+		for _, arn := range m.TaskDefinitions {
+			if !seen[arn] {
+				seen[arn] = true
+				taskDefinitions = append(taskDefinitions, arn)
+			}
+		}
+
+		resources[m.ID] = object{
+			"Type": "AWS::StepFunctions::StateMachine",
+			"Properties": object{
+				"RoleArn":          object{"Fn::GetAtt": []string{"StateMachineRole", "Arn"}},
+				"DefinitionString": string(definition),
+			},
+		}
+		outputs[m.Output] = object{"Value": object{"Ref": m.ID}}
+
+		if m.Schedule != "" {
+			scheduled = append(scheduled, object{"Ref": m.ID})
+			resources[m.ID+"Schedule"] = object{
+				"Type": "AWS::Events::Rule",
+				"Properties": object{
+					"Description":        "Runs " + m.Name,
+					"ScheduleExpression": m.Schedule,
+					"State":              "ENABLED",
+					"Targets": []object{{
+						"Id":      "state-machine",
+						"Arn":     object{"Ref": m.ID},
+						"RoleArn": object{"Fn::GetAtt": []string{"EventsRole", "Arn"}},
+					}},
+				},
+			}
+		}
+	}
+	outputs[t.ListOutput] = object{"Value": strings.Join(names, ",")}
+
+	resources["StateMachineRole"] = role("states.amazonaws.com", []object{
+		{"Effect": "Allow", "Action": []string{"ecs:RunTask"}, "Resource": taskDefinitions},
+		{"Effect": "Allow", "Action": []string{"ecs:StopTask", "ecs:DescribeTasks", "states:ListExecutions"}, "Resource": "*"},
+		{"Effect": "Allow", "Action": []string{"events:PutTargets", "events:PutRule", "events:DescribeRule"}, "Resource": object{
+			"Fn::Sub": "arn:aws:events:${AWS::Region}:${AWS::AccountId}:rule/StepFunctionsGetEventsForECSTaskRule",
+		}},
+		{"Effect": "Allow", "Action": []string{"iam:PassRole"}, "Resource": "*", "Condition": object{
+			"StringLike": object{"iam:PassedToService": "ecs-tasks.amazonaws.com"},
+		}},
+	})
+
+	if len(scheduled) > 0 {
+		resources["EventsRole"] = role("events.amazonaws.com", []object{
+			{"Effect": "Allow", "Action": []string{"states:StartExecution"}, "Resource": scheduled},
+		})
+	}
+
+	b, err := json.MarshalIndent(object{
+		"AWSTemplateFormatVersion": "2010-09-09",
+		"Description":              t.Description,
+		"Outputs":                  outputs,
+		"Resources":                resources,
+	}, "", "  ")
+	return string(b), err
+}
+
+func jobDefinition(cluster string, job Job) object {
+	states := object{}
+	startAt := "Run"
+
+	if job.NoOverlap {
+		startAt = "CheckRunning"
+		states["CheckRunning"] = object{
+			"Type":     "Task",
+			"Resource": "arn:aws:states:::aws-sdk:sfn:listExecutions",
+			"Parameters": object{
+				"StateMachineArn.$": "$$.StateMachine.Id",
+				"StatusFilter":      "RUNNING",
+				"MaxResults":        2,
+			},
+			"ResultSelector": object{"Running.$": "States.ArrayLength($.Executions)"},
+			"ResultPath":     "$.executions",
+			"Next":           "AlreadyRunning",
+		}
+		states["AlreadyRunning"] = object{
+			"Type": "Choice",
+			"Choices": []object{{
+				"Variable":           "$.executions.Running",
+				"NumericGreaterThan": 1,
+				"Next":               "Skipped",
+			}},
+			"Default": "Run",
+		}
+		states["Skipped"] = object{
+			"Type":    "Succeed",
+			"Comment": "Another run of the job is still in progress",
+		}
+	}
+
+	run := runTaskState(cluster, job.TaskDefinition, job.Container, job.Command, job.Retries)
+	run["End"] = true
+	if job.Timeout > 0 {
+		run["TimeoutSeconds"] = int(job.Timeout.Seconds())
+	}
+	states["Run"] = run
+
+	return object{
+		"Comment": "Runs the " + job.Name + " job",
+		"StartAt": startAt,
+		"States":  states,
+	}
+}
+
+// runTaskState is a state that runs a task on a cluster until it stops, with failures
+// retried with a backoff
+func runTaskState(cluster, taskDefinition, container string, command []string, retries int) object {
+	params := object{
+		"Cluster":        cluster,
+		"TaskDefinition": taskDefinition,
+	}
+	if len(command) > 0 {
+		params["Overrides"] = object{
+			"ContainerOverrides": []object{{"Name": container, "Command": command}},
+		}
+	}
+
+	state := object{
+		"Type":       "Task",
+		"Resource":   "arn:aws:states:::ecs:runTask.sync",
+		"Parameters": params,
+	}
+	if retries > 0 {
+		state["Retry"] = []object{{
+			"ErrorEquals":     []string{"States.TaskFailed"},
+			"MaxAttempts":     retries,
+			"IntervalSeconds": 30,
+			"BackoffRate":     2,
+		}}
+	}
+	return state
+}
+
+func role(service string, statements []object) object {
+	return object{
+		"Type": "AWS::IAM::Role",
+		"Properties": object{
+			"AssumeRolePolicyDocument": object{
+				"Statement": []object{{
+					"Effect":    "Allow",
+					"Principal": object{"Service": []string{service}},
+					"Action":    []string{"sts:AssumeRole"},
+				}},
+			},
+			"Path": "/",
+			"Policies": []object{{
+				"PolicyName":     "ecsy",
+				"PolicyDocument": object{"Statement": statements},
+			}},
+		},
+	}
+}
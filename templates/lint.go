@@ -0,0 +1,364 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Lint levels, errors fail a template and warnings are only reported
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// MaxTemplateBody is the largest template that cloudformation accepts inline, in bytes
+const MaxTemplateBody = 51200
+
+// LintIssue is a problem found in a template, with the id of the cfn-lint rule it
+// corresponds to
+type LintIssue struct {
+	Rule    string
+	Level   string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Rule, i.Level, i.Message)
+}
+
+// LintErrors returns the issues that are errors
+func LintErrors(issues []LintIssue) []LintIssue {
+	errors := []LintIssue{}
+	for _, i := range issues {
+		if i.Level == LintError {
+			errors = append(errors, i)
+		}
+	}
+	return errors
+}
+
+var templateSections = map[string]bool{
+	"AWSTemplateFormatVersion": true, "Description": true, "Metadata": true, "Parameters": true,
+	"Rules": true, "Mappings": true, "Conditions": true, "Transform": true, "Resources": true, "Outputs": true,
+}
+
+var (
+	resourceType = regexp.MustCompile(`^(AWS|Alexa|Custom)::[A-Za-z0-9]+(::[A-Za-z0-9]+)*$`)
+
+	refPattern       = regexp.MustCompile(`(?:!Ref[ \t]+|["']?\bRef["']?[ \t]*:[ \t]*)["']?([A-Za-z0-9:]+)`)
+	getAttPattern    = regexp.MustCompile(`(?:!GetAtt[ \t]+\[?[ \t]*|["']?Fn::GetAtt["']?[ \t]*:[ \t]*\[?[ \t]*)["']?([A-Za-z0-9]+)`)
+	conditionPattern = regexp.MustCompile(`(?:!If[ \t]*\[[ \t]*|["']?Fn::If["']?[ \t]*:[ \t]*\[[ \t]*|!Condition[ \t]+|["']?\bCondition["']?[ \t]*:[ \t]*)["']?([A-Za-z0-9]+)`)
+	mappingPattern   = regexp.MustCompile(`(?:!FindInMap[ \t]*\[[ \t]*|["']?Fn::FindInMap["']?[ \t]*:[ \t]*\[[ \t]*)["']?([A-Za-z0-9]+)`)
+	subPattern       = regexp.MustCompile(`\$\{([A-Za-z0-9_:]+)(\.[A-Za-z0-9.]+)?\}`)
+	keyPattern       = regexp.MustCompile(`([A-Za-z0-9_]+)["']?[ \t]*:`)
+)
+
+type lintParameter struct {
+	Type          string        `yaml:"Type"`
+	Default       interface{}   `yaml:"Default"`
+	AllowedValues []interface{} `yaml:"AllowedValues"`
+}
+
+type lintResource struct {
+	Type      string      `yaml:"Type"`
+	Condition interface{} `yaml:"Condition"`
+	DependsOn interface{} `yaml:"DependsOn"`
+}
+
+type lintOutput struct {
+	Value interface{} `yaml:"Value"`
+}
+
+// Lint checks a yaml or json template against a subset of the cfn-lint rules: its structure,
+// that the parameters, resources, conditions and mappings it refers to exist, and the limits
+// cloudformation has on templates. Rules can be ignored like cfn-lint's, with ignore_checks
+// under cfn-lint config in the template's Metadata.
+func Lint(body string) (issues []LintIssue) {
+	issues = []LintIssue{}
+	add := func(rule, level, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{rule, level, fmt.Sprintf(format, args...)})
+	}
+
+	var sections map[string]interface{}
+	if err := yaml.Unmarshal([]byte(body), &sections); err != nil {
+		add("E0000", LintError, "Failed to parse template: %v", err)
+		return issues
+	}
+	var tpl struct {
+		Metadata struct {
+			CfnLint struct {
+				Config struct {
+					IgnoreChecks []string `yaml:"ignore_checks"`
+				} `yaml:"config"`
+			} `yaml:"cfn-lint"`
+		} `yaml:"Metadata"`
+		Parameters map[string]lintParameter `yaml:"Parameters"`
+		Mappings   map[string]interface{}   `yaml:"Mappings"`
+		Conditions map[string]interface{}   `yaml:"Conditions"`
+		Resources  map[string]lintResource  `yaml:"Resources"`
+		Outputs    map[string]lintOutput    `yaml:"Outputs"`
+	}
+	if err := yaml.Unmarshal([]byte(body), &tpl); err != nil {
+		add("E0000", LintError, "Failed to parse template: %v", err)
+		return issues
+	}
+	defer func() {
+		issues = withoutIgnored(issues, tpl.Metadata.CfnLint.Config.IgnoreChecks)
+	}()
+
+	for _, name := range sortedSections(sections) {
+		if !templateSections[name] {
+			add("E1001", LintError, "Unknown top level section %s", name)
+		}
+	}
+	if len(body) > MaxTemplateBody {
+		add("E1002", LintError, "Template is %d bytes, more than the %d that can be passed inline", len(body), MaxTemplateBody)
+	}
+	if len(tpl.Resources) == 0 {
+		add("E1001", LintError, "Template has no Resources")
+	}
+	for _, limit := range []struct {
+		Rule, What string
+		Count, Max int
+	}{
+		{"E2010", "parameters", len(tpl.Parameters), 200},
+		{"E3010", "resources", len(tpl.Resources), 500},
+		{"E6010", "outputs", len(tpl.Outputs), 200},
+	} {
+		if limit.Count > limit.Max {
+			add(limit.Rule, LintError, "Template has %d %s, more than the limit of %d", limit.Count, limit.What, limit.Max)
+		}
+	}
+
+	// references are found in the text, yaml.v2 doesn't keep the tags of short form functions
+	text := withoutComments(body)
+	refs := matches(refPattern, text)
+	subs := subVariables(text)
+	keys := map[string]bool{}
+	for _, k := range matches(keyPattern, text) {
+		keys[k] = true
+	}
+
+	defined := func(name string) bool {
+		_, param := tpl.Parameters[name]
+		_, resource := tpl.Resources[name]
+		return param || resource || strings.HasPrefix(name, "AWS::")
+	}
+	for _, name := range refs {
+		if !defined(name) {
+			add("E1012", LintError, "Ref to %s, which isn't a parameter or resource", name)
+		}
+	}
+	for _, sub := range subs {
+		// variables can also be defined by the map of a Fn::Sub
+		if name := strings.SplitN(sub, ".", 2)[0]; !defined(name) && !keys[name] {
+			add("E1019", LintError, "Sub of ${%s}, which isn't a parameter, resource or variable", sub)
+		}
+	}
+	for _, name := range matches(getAttPattern, text) {
+		if _, ok := tpl.Resources[name]; !ok {
+			add("E1010", LintError, "GetAtt of %s, which isn't a resource", name)
+		}
+	}
+	for _, name := range matches(conditionPattern, text) {
+		if _, ok := tpl.Conditions[name]; !ok {
+			add("E8002", LintError, "Condition %s isn't defined", name)
+		}
+	}
+	for _, name := range matches(mappingPattern, text) {
+		if _, ok := tpl.Mappings[name]; !ok {
+			add("E7001", LintError, "FindInMap of %s, which isn't a mapping", name)
+		}
+	}
+
+	for _, name := range sortedKeys(tpl.Parameters) {
+		p := tpl.Parameters[name]
+		if p.Type == "" {
+			add("E2001", LintError, "Parameter %s has no Type", name)
+		}
+		if p.Default != nil && len(p.AllowedValues) > 0 && !allowed(p.Default, p.AllowedValues) {
+			add("E2015", LintError, "Default of parameter %s isn't one of its AllowedValues", name)
+		}
+		if !containsName(refs, name) && !containsSub(subs, name) {
+			add("W2001", LintWarning, "Parameter %s isn't used", name)
+		}
+	}
+
+	for _, name := range sortedKeys(tpl.Resources) {
+		r := tpl.Resources[name]
+		if r.Type == "" {
+			add("E3001", LintError, "Resource %s has no Type", name)
+		} else if !resourceType.MatchString(r.Type) {
+			add("E3001", LintError, "Resource %s has an invalid Type %s", name, r.Type)
+		}
+		for _, dep := range stringList(r.DependsOn) {
+			if _, ok := tpl.Resources[dep]; !ok {
+				add("E3005", LintError, "Resource %s depends on %s, which isn't a resource", name, dep)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(tpl.Outputs) {
+		if tpl.Outputs[name].Value == nil {
+			add("E6002", LintError, "Output %s has no Value", name)
+		}
+	}
+	return issues
+}
+
+func withoutIgnored(issues []LintIssue, ignored []string) []LintIssue {
+	kept := []LintIssue{}
+	for _, issue := range issues {
+		if !containsName(ignored, issue.Rule) {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+func withoutComments(body string) string {
+	lines := strings.Split(body, "\n")
+	for idx, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines[idx] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// matches returns the first group of each match of a pattern, without duplicates
+func matches(pattern *regexp.Regexp, text string) []string {
+	seen := map[string]bool{}
+	found := []string{}
+	for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			found = append(found, m[1])
+		}
+	}
+	return found
+}
+
+// subVariables returns the ${} variables in the template, with any attribute they get
+func subVariables(text string) []string {
+	seen := map[string]bool{}
+	found := []string{}
+	for _, m := range subPattern.FindAllStringSubmatch(text, -1) {
+		if v := m[1] + m[2]; !seen[v] {
+			seen[v] = true
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSub(subs []string, name string) bool {
+	for _, s := range subs {
+		if s == name || strings.HasPrefix(s, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func allowed(value interface{}, values []interface{}) bool {
+	for _, v := range values {
+		if fmt.Sprint(v) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList reads a DependsOn, which is a single name or a list of them
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := []string{}
+		for _, item := range v {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list
+	}
+	return nil
+}
+
+func sortedSections(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch m := m.(type) {
+	case map[string]lintParameter:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]lintResource:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]lintOutput:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NoEchoParameters returns the parameters of a template that are NoEcho, which
+// cloudformation shows as **** when stacks are described
+func NoEchoParameters(body string) map[string]bool {
+	var tpl struct {
+		Parameters map[string]struct {
+			NoEcho interface{} `yaml:"NoEcho"`
+		} `yaml:"Parameters"`
+	}
+	noEcho := map[string]bool{}
+	if err := yaml.Unmarshal([]byte(body), &tpl); err != nil {
+		return noEcho
+	}
+	for name, p := range tpl.Parameters {
+		if fmt.Sprint(p.NoEcho) == "true" {
+			noEcho[name] = true
+		}
+	}
+	return noEcho
+}
+
+// OutputNames returns the names of the outputs of a template, sorted
+func OutputNames(body string) []string {
+	var tpl struct {
+		Outputs map[string]interface{} `yaml:"Outputs"`
+	}
+	if err := yaml.Unmarshal([]byte(body), &tpl); err != nil {
+		return nil
+	}
+	names := []string{}
+	for name := range tpl.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
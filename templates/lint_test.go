@@ -0,0 +1,93 @@
+package templates
+
+import "testing"
+
+func TestLintEmbeddedTemplates(t *testing.T) {
+	for name, tpl := range map[string]func() string{
+		"ecs-stack":            EcsStack,
+		"ecs-service":          EcsService,
+		"network-stack":        NetworkStack,
+		"ecs-prometheus-agent": PrometheusAgent,
+		"iam-roles":            IAMRoles,
+		"ecs-db":               EcsDatabase,
+		"ecs-cache":            EcsCache,
+		"ecs-budget":           EcsBudget,
+		"ecs-namespace":        EcsNamespace,
+		"ecs-autodeploy":       EcsAutodeploy,
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, issue := range LintErrors(Lint(tpl())) {
+				t.Error(issue)
+			}
+		})
+	}
+}
+
+func TestLintRenderedTemplates(t *testing.T) {
+	cluster, err := ClusterStack([]IngressRule{
+		{FromPort: 443, ToPort: 443, CidrIP: "10.0.0.0/8"},
+	}, []InstanceGroup{
+		{Name: "gpu", InstanceType: "p3.2xlarge", GPU: true, Desired: 2, Min: 1, Max: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := WithMessaging(EcsService(), []string{"jobs"}, []string{"events"})
+	jobs, err := JobsStack("llamas", []Job{
+		{Name: "reports", TaskDefinition: "arn:aws:ecs:us-east-1:123456789012:task-definition/reports:1", Schedule: "rate(1 day)", NoOverlap: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rendered := range []string{cluster, service, jobs} {
+		for _, issue := range LintErrors(Lint(rendered)) {
+			t.Error(issue)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	for _, tc := range []struct {
+		rule, tpl string
+	}{
+		{"E0000", "Resources: [\n"},
+		{"E1001", "Resource:\n  Queue:\n    Type: AWS::SQS::Queue\n"},
+		{"E3001", "Resources:\n  Queue:\n    Type: SQS::Queue\n"},
+		{"E1012", "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n    Properties:\n      QueueName: !Ref Name\n"},
+		{"E1010", "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\nOutputs:\n  Arn:\n    Value: !GetAtt Queues.Arn\n"},
+		{"E1019", "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n    Properties:\n      QueueName: !Sub ${Name}-queue\n"},
+		{"E8002", "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n    Condition: HasQueue\n"},
+		{"E7001", "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n    Properties:\n      QueueName: !FindInMap [Names, !Ref \"AWS::Region\", Queue]\n"},
+		{"E3005", "Resources:\n  Queue:\n    Type: AWS::SQS::Queue\n    DependsOn: Topic\n"},
+		{"E2015", "Parameters:\n  Size:\n    Type: String\n    Default: huge\n    AllowedValues: [small, large]\nResources:\n  Queue:\n    Type: AWS::SQS::Queue\n    Properties:\n      QueueName: !Ref Size\n"},
+		{"W2001", "Parameters:\n  Name:\n    Type: String\nResources:\n  Queue:\n    Type: AWS::SQS::Queue\n"},
+	} {
+		t.Run(tc.rule, func(t *testing.T) {
+			issues := Lint(tc.tpl)
+			for _, issue := range issues {
+				if issue.Rule == tc.rule {
+					return
+				}
+			}
+			t.Fatalf("Expected %s, got %v", tc.rule, issues)
+		})
+	}
+
+	valid := "Parameters:\n  Name:\n    Type: String\nResources:\n  Queue:\n    Type: AWS::SQS::Queue\n" +
+		"    Properties:\n      QueueName: !Sub\n        - ${Name}-${Suffix}\n        - Suffix: !Ref AWS::Region\n"
+	if issues := Lint(valid); len(issues) > 0 {
+		t.Fatalf("Expected no issues, got %v", issues)
+	}
+}
+
+func TestOutputNames(t *testing.T) {
+	names := OutputNames(NetworkStack())
+	for _, want := range []string{"VpcId", "Subnet0Public", "Subnet3Private"} {
+		if !containsName(names, want) {
+			t.Errorf("Expected output %s, got %v", want, names)
+		}
+	}
+	if names := OutputNames("Resources: [\n"); len(names) > 0 {
+		t.Fatalf("Expected no outputs for an invalid template, got %v", names)
+	}
+}
@@ -0,0 +1,83 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// QueueOutput is the stack output with the url of a queue rendered by WithMessaging
+func QueueOutput(name string) string {
+	return resourceName("Queue", name) + "Url"
+}
+
+// TopicOutput is the stack output with the arn of a topic rendered by WithMessaging
+func TopicOutput(name string) string {
+	return resourceName("Topic", name) + "Arn"
+}
+
+// WithMessaging renders SQS queues with dead letter queues and SNS topics into a service
+// stack template, along with a policy allowing the task role to use them
+func WithMessaging(tpl string, queues, topics []string) string {
+	if len(queues) == 0 && len(topics) == 0 {
+		return tpl
+	}
+
+	var resources, outputs, statements bytes.Buffer
+	for _, name := range queues {
+		queue := resourceName("Queue", name)
+		fmt.Fprintf(&resources, "    %s:\n", queue)
+		fmt.Fprintf(&resources, "        Type: AWS::SQS::Queue\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            SqsManagedSseEnabled: true\n")
+		fmt.Fprintf(&resources, "            RedrivePolicy:\n")
+		fmt.Fprintf(&resources, "                deadLetterTargetArn: !GetAtt %sDeadLetter.Arn\n", queue)
+		fmt.Fprintf(&resources, "                maxReceiveCount: 5\n\n")
+		fmt.Fprintf(&resources, "    %sDeadLetter:\n", queue)
+		fmt.Fprintf(&resources, "        Type: AWS::SQS::Queue\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            SqsManagedSseEnabled: true\n")
+		fmt.Fprintf(&resources, "            MessageRetentionPeriod: 1209600\n\n")
+
+		fmt.Fprintf(&outputs, "    %s:\n", QueueOutput(name))
+		fmt.Fprintf(&outputs, "        Value: !Ref %s\n\n", queue)
+
+		fmt.Fprintf(&statements, "                    - Effect: Allow\n")
+		fmt.Fprintf(&statements, "                      Action:\n")
+		for _, action := range []string{"SendMessage", "ReceiveMessage", "DeleteMessage", "ChangeMessageVisibility", "GetQueueAttributes", "GetQueueUrl"} {
+			fmt.Fprintf(&statements, "                          - sqs:%s\n", action)
+		}
+		fmt.Fprintf(&statements, "                      Resource:\n")
+		fmt.Fprintf(&statements, "                          - !GetAtt %s.Arn\n", queue)
+		fmt.Fprintf(&statements, "                          - !GetAtt %sDeadLetter.Arn\n", queue)
+	}
+
+	for _, name := range topics {
+		topic := resourceName("Topic", name)
+		fmt.Fprintf(&resources, "    %s:\n", topic)
+		fmt.Fprintf(&resources, "        Type: AWS::SNS::Topic\n")
+		fmt.Fprintf(&resources, "        Properties:\n")
+		fmt.Fprintf(&resources, "            KmsMasterKeyId: alias/aws/sns\n\n")
+
+		fmt.Fprintf(&outputs, "    %s:\n", TopicOutput(name))
+		fmt.Fprintf(&outputs, "        Value: !Ref %s\n\n", topic)
+
+		fmt.Fprintf(&statements, "                    - Effect: Allow\n")
+		fmt.Fprintf(&statements, "                      Action:\n")
+		fmt.Fprintf(&statements, "                          - sns:Publish\n")
+		fmt.Fprintf(&statements, "                      Resource: !Ref %s\n", topic)
+	}
+
+	fmt.Fprintf(&resources, "    MessagingPolicy:\n")
+	fmt.Fprintf(&resources, "        Type: AWS::IAM::Policy\n")
+	fmt.Fprintf(&resources, "        Properties:\n")
+	fmt.Fprintf(&resources, "            PolicyName: messaging\n")
+	fmt.Fprintf(&resources, "            Roles: [ !Ref TaskRole ]\n")
+	fmt.Fprintf(&resources, "            PolicyDocument:\n")
+	fmt.Fprintf(&resources, "                Statement:\n")
+	resources.Write(statements.Bytes())
+	resources.WriteString("\n")
+
+	tpl = strings.Replace(tpl, "\nOutputs:\n", "\nOutputs:\n"+outputs.String(), 1)
+	return strings.Replace(tpl, "\nResources:\n", "\nResources:\n"+resources.String(), 1)
+}
@@ -186,111 +186,414 @@ func FSMustString(useLocal bool, name string) string {
 
 var _escData = map[string]*_escFile{
 
+	"/templates/src/ecs-autodeploy.yml": {
+		local:   "templates/src/ecs-autodeploy.yml",
+		size:    10185,
+		modtime: 1791984126,
+		compressed: `
+H4sIAAAAAAAC/8Q6bXPaRrff+RUn2zCBVBCnnelMNZeZUkxuPElsjyHppAzjrqUF9iLtqrtHxtTX//3O
+7koCIQS4ae/DByPtnj3vb3twp9Np9H8bjVmcRBTZO6liil+Y0lwKH179cPb2rHP2c+fs51eNc6YDxRN0
+O8PBCPopypAlkVz74L418JjOmYYk1QsWAkqgAoaDG1AskZqjVGtYcVwAhZhisOBiDkjnBeAINFP3PGCv
+Go1PDGlIkfoNAIBhoNc5mwWDbxuNa6pozJApncENRoMo1ciUewcAGK8T5sMIFRfzYrEkz3jBzEkI3FHA
+Bcs5Aa6Bi0aOfORWn4ucKgFyBpjRyXGjzDQHKD27K0W03mwvGMxSERg8EFABaRJSZI6ZMdXLdzTm0fq5
+zLBAFzQ84KghksESuAakSyZgteCRxowzg6kBAHBTmPCSxuz52i05AS4oOi/RQBXLaLEQZkrGuXzza4rI
+lHgOrSujvwyxUTida0fM+hvggmtIHNoSYQ9eOxDDkFjjokxhRtMIfSCviWNuIAVSLp7nZVf2m0bQsSoJ
+chw7bnC3htARBHbP1Hob0EiiUmF4dLFmFWYdZaPdPXxnbH+UwXJM7yL2XBPusu7i5JXO+bYOhAZzPXHj
+sDcyYn0lvoE0Ur0EJSOWx1OeMbTbCtmMC26OeMaRg0U1ihKqNaA0kVjP7fCBBak58u0ssxzVv833QIrQ
+osiS4XuqC4tv+H9xKREm8GL4Z0ojbZ5u2KzsGh4QAlOYNnI0ue2OYdmycQVHSaPHEO2qv4LtmmrNQrOr
+t3BdKYOg0MM2697OeomCQXyVYpJihm2ENFhaSxfIv9AoZT4QFujOTKqYKd83z7Sog6RRW4Oyw062Yr8+
+k2/Db/YPZ+LtM2WY+oxappPvO/h3mfPVU8khGo0bpmWqgtwY16le3KRRJcf0fxv5/vCeCdS+bwCK/Wsl
+E6aQb5uzElgvRukdkPOs2cjSPEp4+ViW98ktbur1EykhtRxUdJF/nCQ+TIDQle6yQBGYVqBChpRHHbRy
+TYCYCndhU3Lf6qT+TJUiAAC1pzb4rj+P3u/DAQCgmLZBPwEy+jwYDEejeshcLx1hzJjHV1lfNYdthekg
+nZtjj7DiURhQFVbcBZ52EIyQIvNheNn/9ePwvLQ1pmrOUFd10IGL0IdNMFUAAEwahhf/zbCPWPhet5/7
+a+5010zFXNsWcZ/7faTxXUh9fwN21AmdRX2I3NELcS+XrHB+2PqUYmYnSGDrc624CHhCI98UeYG6S2P6
+lxTG4QIZl7Vp/bEkfC7qRvicSjm5bol90f/k+2b3uLRapzEzoNcy4sH6XAZpzARWbWbtvH8LAKADw9mM
+BehDP4rkai/MtipqAAAA8r4bJpkRyvqqceCN5SZANGp/I9puwHyigs5Z6CTuK7HXQ6kSPl1pn9PYtw+J
+BX+T1fKOqe9v+r+NnIv9SjUPSmWm7ALmbEX5jpBjwznRwZA4ZqGT7AQAAPDdbiOiIaDiFcIdAx3IJLvQ
+meRjHfIAqtMMX7LQQRiHkwXad+XgjpmaeF5weuLhGzbnpuw+43Be11zv/5+UOIsAfeKxz/aqmB06UcS8
+Ozl+qgMvLmaNw1yUm7QjwKdrrxTTJhANkX0RfUDSI3CFhDDZ6SArPa5dITbBXkrbGh1nYwd9KUN4de3v
+3yFU9LunCOwuNB/58iT1AADkymfhWBb52XTEJo8cKmh7lFGV7p8NNuMs4VrQWIZ3/nWKF8jiYwrcCgzb
+d+bZv8Dz8tFybNKKFE/5az8IZCrwInzy7Y34jZm3dGgaciTfHlLFPe3fCaiKjryttXMWMWSnqO6fU97L
+x9LN9In8DU9qAAB8BwkX2t6qi4GKLiYqOyOUV9koE1DanWygGfI50+jBQkahnV0uWIZ7c5vfnohEfMng
+D2P/bPkPD6gIQbFAqtAx4zaAOxasm0Ak510YF8jz8ZwdDi5YFEIqkEe7Y0o3HQw9oA6zklEkUzRVHGSK
+EdVYGip4Oe8SaM6GRqrQSJqqTMACjWJJRAOmgSMoigs3jxKwohwN7Ewq4NgtdaMHG/BKY/z/ege8MTo0
+/VWyxoUUP3bf/lDaf09FGDHlAxche+gu3Gv5LsNjJlP04aezMmrTg1cuKjcy69dh6zMU91xJsb83+0IV
+N46vq1sAAIOPn0fj4U11qrA3vQ9vvlwMhqcV+cHV5bh/cVngLsade6E/Xg0+3I7NVc/fM0uqP/Jh+DU3
+58vHDftPb14+bkYeO1YbyHBPffqdJ++40fn/1txjE6kQTHQYk3vwP9qM2aT2wL3fSZQ/7j3KAu1BGN5B
+zwF1g4gzgS0zBSJtb2cxT26kvRfZh+HXEfRgQmZWNJNbcdNMmFe2U/fNmmC4kmr5SYbMvBbJa9PBauId
+zIrkXkZpzLQ5bmPYuNtACo2KcoF2XbE/U66YHsg4ocjveMRNIFqKSWq+YhZLtT5GKuFhzilPgvwxUfJh
+PZBixueponY8YqRNFixmikYjlIrOLaRyYXkdUTRDNpJN/HY/IZttpeuWTdbt+sbF7kPPfXd1EnFskV9I
+e3JWX8f4DIhPgIvyqTekPem8nfoH1VCmp7KjPvHg7UGaimGqMoL1gme5qGUnB54tZ+wBD4jv5k4eMHEP
+PTdwmBC3SKYmELrM5aFaDKni0APS1N1wqcxIrNvc6fDeNDWBJrQy7NQVcoM+W1G20psFR3lCNga0wyky
+bdfSz8tcz0RkN8zuRLfZsm5lv0j0mLifkCwxGlI5QG9id7IsSKbT9oTke2R6yCQY7hI1MXu7uSa3sHSd
+7GVoJ6S8Tqbt6lIt1WBBxZwZ0u9opOubPVN0A+ACMJzsTw3HPLUUR8GEWN8j0zb0etbqpl9xWs2LApkC
+F9AixINgQjLLHaYCALDB7W2JZ0h8D+QXAt8XfuHmjq7ZMtBjlbJDYSok5hgPs5EobtL0pdxuAeUMmtr2
+gLkHlzzFMyy22wfxurBt1MLIlWDKAyFXJog2Ax3fSJ2Fb5eu9K3JwkzjLQ89MKyaVNg1f1oHOOAzZ6BN
+HT5m9O9AUa5N06YYKs5cv2jjNP/1lwppWzzHqO14HZRpRg8iD8O7bpLiLUcWt4o2oFdh0QNzk+g9HnUc
+YvqJD2xNfHgkI+JvSfth+JVMnzwHcmW0XABZnT95x7H3A1v5wj7as5fEB42qJeSqbTAPHxJTF6u78D38
++NPZWfvpBCLFTXz4kChmh849QhEVv0uR3QqJt+yBa9StTNY2XN1AQRr+C3whV+Q4oQ3+fo7dXoJ075FY
+FBURn+odC9X6sB9RJbLkqLLRWiU5vn79uPRNdlpObbJaAheuEeIz94Lh057MuLvSV4fSZdaqdd0VKC8L
+x6pCbzfSdzI5VaJ9Sj45z/97oan9pr2MNDVQneeTVHFvJ7chnRt6u/QNwXqKMy5oFK2P5vNnJoM8ZkN7
+uz8hbD+wde/xaFCeEBW1kVFEM/TAt4FMvMOuLffE/rd4dk0SI1uznNPzV3axKauqcEeTZAY0irYA8pLA
+7Q9N4W1+Y7+lSpyS0m7sfKFIaQZlfvnpFg9CrlrFi/nzlxSsm2LQ7mpUM1t1SPNrpxl3muG4+d5vfvKb
+o25z9jtpn8LE3nQMZCDjmIqw2Ngqh+SkdK3mujhsLnLdMI0T3ZrsBlNtl2n6Db/ab9iYnLZP02/2a/de
+RqgS04N5lT0ELEHrY+7RNGrdHOmlxHcyFeEw3zrS01CtG/83AOImgarJJwAA
+`,
+	},
+
+	"/templates/src/ecs-budget.yml": {
+		local:   "templates/src/ecs-budget.yml",
+		size:    3056,
+		modtime: 1791976963,
+		compressed: `
+H4sIAAAAAAAC/+xUUXObRhB+51esaTJ+ERncp4Q3inGcqYU8AsfTyXg6CzqJmx4cvVvqUTP5753jACEV
+xW1fmofsi9Ddfrvft3u7nuc54WOasaoRSOxGqgrpI1OayzqAyx/9K9/z33n+u0vnmulC8YbsTRyl8FO7
+2TEKAKGSNZViD4XUBHl3DFupgEoGimnZqoJpkFvAGgyyEK0mphaAgini9Q6Q4K3/GrDewJXvv750nCUj
+3CBh4AAAxIXeDyxHfleOc48KK0ZM6d4vSiMb3P43lu0bFkBKite78fBITlayKa+Ody+Da6PE6XBLK/OO
+V5xOwydtlTN1PvxRiYSJALyGh/R6hCx5/RFFyzpdnZYKufg3MlbdLwrwIKyBGTjgZqOY1kDSFnuC3GIr
+KADXteky2fAiVPV/z5gmKZCJMmZbACcNjRS82EPVagIUQj73xdVvsMI/ZY3P+k0hK4Nq2lxwXc6zjGS9
+4SZh3+xb1Cc1ukgkwSe4iH9vUWjztWZbW8gFuC48wZMzQDu9L0GHohzQq5aalnoGKWHxW1emMU7fQ5cV
+2ttKVTEVBObbSnads8+0B1rG4731t6OWYMXm/e2946yHYQsmsNOGho9pENgrPXyMLvdKNmYmhxCDnYY6
+Pu+YwUXa5p3wvffq80HCF/cMytKJVmn2N4eMV+yh5hTAcpVkt3e/nAlxMotTCyvZ1tQXaDq6s9422XQe
+B/thssJQMUAixfOW2KZ75uPSyPdmb3A1aR4Q7hbwXPKihBK7IczZTAIsiP+BJiJqQLsjzKAUaF67iQK8
+hpwLMZ3EwSKp6YaLwxI8tQx3P7O9fS+zDsa8vn2tZio4SHj1lU4mkviWW5L6kVOZtrlZDvksE+/I/xyR
+qU//XKPsIbw74x7JqkHFtaxXDVNIUgXwfh2HWbz+NbsNkzOwrFRMl1JsAnjrv+RjadzH6yhOsvB9POP/
+VeFjfT9s4dO4tBbweYA1B7HxMvxwt4DQbu1gsr/gy8L+c7v5TWTXTxeeXs7XLbH5fGmSnmYbVt4/T/iN
+dvbK/97a/6u1N6t1HIVpFl9/b+831N6/BgBdalq08AsAAA==
+`,
+	},
+
+	"/templates/src/ecs-cache.yml": {
+		local:   "templates/src/ecs-cache.yml",
+		size:    3956,
+		modtime: 1791975606,
+		compressed: `
+H4sIAAAAAAAC/7RXS2/bOBC++1dMhQC92GmSAl1UN8HrtEYa17C8CbBBDww1jolKpEqO0jWC/PcFSUnW
+K85jsbnYJufxzczHmclkMhlF1/EaszxlhOdKZ4yuUBuhZAjvz05OTyYnnycnn9+P/kTDtcjJ38ymMUwZ
+32IITMIsZYaE+w0a81RwZuXgTqsiByGBtgi5FveMEExxK5EMqI1TncbA08IQ6vej0SUSSxixcAQAMONm
+V0GrQZ2NRkumWYaE2ni5q5zPE//V/q13OYYQXcdhOJueheHVchqG86S+b0Wy3iJcLadACrhGi89i5S4U
+IUeV/aUHHzvsp4e8eZGnHUadRMBG6b3PYYdn/7fD2TSe+iJ0/cSkhbx7OneN+jUzZ6yX2naMvNCCdl8s
+Hw5E0hI7WDJTipYUUxugEoyQhpjkaIC2jIAzCYxzNKYXs7wTEl8br48PnW5DasOKlELQmAhTH0dpqn5j
+csXSAk0IN/56DPcs/Yk7+NHEUfH7FXC+u0+WwgTWNSa494bGkHhQBki50O0rMtTHHAQex0Il6Jy+MiNS
+JQi0y7Gqgk+RPTZ9b+7ymD4eZ4Jrtfdsum4XRXaL+oBbd299Ok9jyJS2j5dJUBKBJYmpepGB34K2wApS
+GSPBYcNEqu5R9+Gd1keXQrrCtc7YP+XZJ498rZk0gmaS653H9orkrfBXITTC+lvs3iRXUiK3d3XNPF37
+Jduw1GDwNNEC0gUG40qwotpFZi5wF+m30iyCi8sYLHdJAfqgwfZrYAQaDflE2yeILLG1ia5jyJhkd5hY
+PTNMv6mSiXCBe2BfmVmVpdsjfbdQBDfwbvarYKmx31a48dwZwyn8qIL8yswTT2rYQkt4DEHQMnWgwh07
+PclxWYaGNV+A5xDVZdqj+V5QXlCZjZgY/9l+qiUtA+RmslE6Qx2G9rsjUPB0ky/1fCLq+xKvMtSX/IIU
+EcFqP+Zduz5eapExvZvJZKmEpOMoSTQa400tlf4Ppqy2t7NClqB+HTCvM4zrr9W3vqG4uK3P7N8EgqOH
+mG8xw8fww4ejh5fG/hgePbwouMeg49B7C+HdfAM3gzQc+2Fiyk/LkRUaVWhetVK3jvnl4MDc3W9uYdgQ
+rmWXWuWoSTQbdK9F2JRBsOwveEcPe1J1gvTO5knHro/f8XFg7Xqp6Nk8GTWS8Ib149kEOKn2omW7sOku
+Iaq/fjTN+N21jqITYgvSXN5ZVg3la54vtSLFVRoC8bwnAXCuVeZeIXz6+MfnAYG1OngdO2q18ST7xtHO
+XflW28x/nn9djWdr0FUYIOXRg/PjWuaCZfgIZUts2ilXweY8GLiv/wWpX2VndAyNE3cWOAwL5TqMnQdN
+4y74evnaTzX7syW4KDInW74n05A1LcGoWnTOyz1nJtltikkDeTVex2BH1BjcntABdlmkJKK/36bc7T42
+9yXg7tUBxidum2loNW87HiNaoWl0yBq3BdmS7DXTfYiDg7yl7IfzvJkPfzTuDe/h2v87ADsBycl0DwAA
+`,
+	},
+
+	"/templates/src/ecs-db.yml": {
+		local:   "templates/src/ecs-db.yml",
+		size:    6646,
+		modtime: 1791975606,
+		compressed: `
+H4sIAAAAAAAC/+xYW2/bthd/16c4EQrkxc4/df4bUD3Nsd3M6JIaUZYC6/JAS8e2UIlUSSqdF+S7DyRF
+WjdfNxQYML0kEs/9/M6F7vf73vBT+IBZnhKJ7xnPiHxELhJGAzgfXL697F++61++O/fGKCKe5NKcTEYh
+jIkkcyIwAELhfhxCQoUkNEJgHIYFZ5xAlBZCIoeEglwh5Dx5JhJBFHOKUgBbKF4lrCQ897xblCQmkgQe
+AMAkEmtrnjNs4HkzwkmGErkwdI95NI3Nv+p5WOcYwPBTGAST0SAIHmejIJjG7rzmzcMK4XE2Askg4qjs
+U7bGpXuQUM+qmBn7Q23+210KDcl2ncNGLGDBeE1tt87Bd9A5GYUjk42mqlDyhC63B7GSyEYIhdLlxIcY
+FTyR6xvOinyHPzWynekTJSksFS2wBcjSHgtKAXJFJESEAokiFKLLc7pMKB7rtfMSNXuFcEGKVAaQMyGX
+HIU7GaYp+4bxI0kLFAF8dhQ9yNbia9oDosunb79XPmkCeKoabOviCLs/6r8khb52wVgOz0ZQD2JjugDJ
+dJhU9QnZ9sz3jR3TMsijlAhxbPxshiBS3DZ3lfIrE9hWH88v5NVFhnFSZJ5nIxsRiXEoGSfLVi7vimyO
+fAeODBskFG6udX1QRvsm9GWUOuwYXBrttiPekexoFFGSYct315HaOkmeG6W3RSqT4W/H6LsvKBBQYY3n
+6038EwqEMrlCDuSZJCmZJ6mqqT8ZxYNj4S9IKtDfjnVf8gL9niW0UP6QiQ+4HvJTYTyED7chfME1SAZI
+I77OpUsnobEOrMCIo4RviVxpt5HEKubDTyFkhJIlxkqC6Ab6iNE4UfpKhE+FGXIbg88+cvgMZ5OvBUmF
++u8eF2WNdpQ0PPX20Ja1bkM0FbbOKirvmFTczjhn1oZrVqo80FBr4X77qr5YdT8TsaUrWVO7RD7azuP7
+NVEGFftkOOxs+D8WMi9k6XIoSfRFo8kJ0ngMwMdI9BeMZ8iDQP0fz/3tU7BkMna789JYJmSbcrqAzy4j
+PTi7QTmUEsbXJe/FhMY5S6i8GMYxRyGqNDbbLSIboBnj23XO3Ezxf/j/1UBV3NXV5Y+u4LqbVdXFKoXh
+CXX91Iq0xnBtCMperMfsjKVJtN7KUiXyvHsUrOCRher42qw02/eE+3EYBDUyRzXjLEcukyruW0Jr3eQs
+LObgz9o76puXTbZf/Zo0I2saN5Sop2887FgbDyUdTGPPBuKElWlvKNoh0N1aNBcn1rkyVSWZ9dt50XCx
+ZtWUKlR2xmuazziTLGJpADLKWxQA7znLNOo7YK5Q3gOFcXjqYH1gJzKGGpJ1H+JNF6iHfJMujrIzT+ZI
+3Op5w+37fti2kTriGCOVieqEajy/eTEKVLdTNduAqumS09iGwLXXXquL6ndfS7tjulz9RmhukCJXQNXW
+mxndTmn11N7iAjh/8QuBXG08vmnBa//1vMXsVGh2NQYgJ0J8Y7xdQLPy4BekS7kK4GrQIpn8EaVFjKMV
+4STSF0c493/63+/nNmft4dpuNJbGkbi5G1TGc2V/SFEdmv4WQEhJLlZsf7bLm0h1RHacuwuxS2hjmnZN
+2P25HV/X93nDUvtWo28t3WVrb3xu6NBzp2PQVKluiWq5v5Zgsbh/eeEoWPqMgdnnRFbW0psXW3mvQQ2Y
+Fm6vr/4W+RZAp+uw2GzqqM2bqtOdU0s9j7NRranotXkzYDcHjbzZm4ChLd/qjdhkYmLWY4wDkLzAf7ZL
+lNW05SeEajGVJN21ZFamf3slVXes/7DehfU8akzWQ9H+/bH89wdEA9SnYnZjkZ7/iwS5C3SzqE5p6Iel
+sbbqDKUk0SpDeszS80D4ssq6NzCGzW1fVnmNxgjdJL1y+6pFyL3bOMBThxzjQ1OS39HG/MZnK9bhqHrV
+6QzSdHgbBCZEsSE7ZSu8R2J+7IiOWw+NxjGLinoWN/VGJHYfqacPk8UCI1neITppVAxk7TeBLjmNJnSD
+0mRZV+UWTntx3AWMjYaz6cLbrt91iR00h/h6mL9G3pdMBGPUnWwHbcNL1752GNrR17y/BgAzULqm9hkA
+AA==
+`,
+	},
+
+	"/templates/src/ecs-namespace.yml": {
+		local:   "templates/src/ecs-namespace.yml",
+		size:    844,
+		modtime: 1791977384,
+		compressed: `
+H4sIAAAAAAAC/5SSP2/bMBDFd36Ki1HAkwunW7gJTtouTYPQSOYrcwqJSiRxPCYwin73QtY/K4aHchAk
+8r1H/h5VPZs9talBoa+RW5Qn4uxj0LD+sr3ebrY3m+3NWt1StuyT9Ct3OwP32FJOaEkDgm1ieYEWE4Rx
+GsShQCZ+85Yy+AAYoDPapmQhBia0Do6PKI4YxHEsrw7evbjRCDaGQFbWSv0gwRcU1AoA4M7mw3jw6cjX
+Sj0gY0tCnAfdzuz6DfvvbuwPiTQYYR9ep8kF4d7R4qzvLmaaYUru8GiGVceYuZL/3KrLgVh/zPxZJBUZ
+QIyg/X2Mm3KesCmkYUU2b+rILbHW3fsUsVIXOxi8V49Un6xf5DiV31+grjicG76RVCLwXSRNws8VB6Ue
+KcfClga+heJjgdWz0dr09d/6bOMb8UHrhWeyPHBMxOLH5HF0yjOEU8HiXq5M+QUrs/wN59uBOjJ8+jNX
+93el/g0AAZ1klEwDAAA=
+`,
+	},
+
+	"/templates/src/ecs-prometheus-agent.yml": {
+		local:   "templates/src/ecs-prometheus-agent.yml",
+		size:    2075,
+		modtime: 1791975606,
+		compressed: `
+H4sIAAAAAAAC/6RU3W7bRhO951NMiCC6+VbWZzQFskAKCLScBrAswVSTi6IoxssRuTB3l9gd2hGKvnvB
+H4mSTKEJulfSzjlnfrhzhBDR/Gu6IVOVyHTrvEH+Qj5oZyVMrmf/n4nZBzH7MIluKCivK+4iiySFtXeG
+uKA6wDwnyxLmkCEZZyGQf9aKgAtkCMpjRWF/GcBZIFQFaBsYraJJFC2JMUNGGQEALFTY7Ws6VHMdRWv0
+aIjJhx6XpElZBybf/W/OZleRhJS9tvnh8qT4TUENE1RHBXbgawtcEGDTBzgbtcy2q88Gc/pR+WoYjXKm
+QtaPJYFupPbptO2zGZfRkdIW65Jlq3A1yMjn6+lP76ezrrBh8omzW53/p/IagdpjEz2agQ4QGD1TBi+a
+iy7vncs/eVdX92h+eCSqdHX2gqwKKF0OeaMD7CCQzfqcpcsDsIuiVc1Vzf1HThnVU5vjIP4Fy5okxKSC
+2DpvyEvZ/B76Eq1iHF18Jr3EmwfaHsUP+LR7qxfxfTyKHii42ivqi91geLqhrba67fxsRvOvqZSLJJXy
+FHeArb2ryLPey+3PLRpd7iS8SetHiN/+NVT890jTx8x74hfnn5YuIwmFC3wSTZxl1Jb8UMtZ5uYIaD84
+nGd6BQTotqUb07A+I8AlGed3Eq7f/zwSXYRAljWWEtjXY/yFZb9bO21Zwu8QXz1qexWK+H8QCxXDHyOM
+xBmDNpMjoa7JCanCQfx2/bBaLja/Ln5L/0xW97efP8XwC1yxqY72cbozJbx7B/SNFLTJhxgI0W3VdKtL
++jjGFIIsPpYktoRce/rYLYAQgZ3HnKbt/2mFXHT89v9kdA7P2jtrGv+92Fn3+V71dQF/+tjPvWaEdOfy
+5NhGLhVy5/Ibr5/JS8CX0Gz7BeCquvASh9MLiNZH+lKP7ek7qJ7yptiOO2l386G9mnwHObAnNKLytNXf
+RlbjopGcO0EP+FcL2DvYK8s6BqWqoKwutc1T9siU7yTczBfL1f0J7MylOsnTy+ifAQD8goWMGwgAAA==
+`,
+	},
+
 	"/templates/src/ecs-service.yml": {
 		local:   "templates/src/ecs-service.yml",
-		size:    5086,
-		modtime: 1480643635,
+		size:    22121,
+		modtime: 1791986380,
 		compressed: `
-H4sIAAAJbogA/+xXS2/bOBC+61dMjQIFijqvdhe7ujlOujXgzRqRkx6KHmiaiojKpJakGqRF//sOJUoi
-JTkvdG99AQln+H0czseZ0XQ6jWYfkzXbFTkx7L1UO2KumdJcihhenRwdH02P/sR/r6Izpqnihakt5/ME
-Eqa+cspimDU/vgECa6K/wBlLueDWF4jY4n84X56+iqIVUWTHDBLEEcB1QRdb+wPA+q6wQB+TOD6fn8Tx
-9Woex4ttZQuY1xkDvmXC8JQzBTIFdAUjQZUCuIhq2FW5yTlNyo1g5ngfR23eT5NypQ0UFRToyhkZwKDJ
-cuqCUXuILdxyk9XBjNCfPJdeMyrx7p7Mj6mZ56XGS/Z5E6O4uBlnssmk9RZ7k8QYQrOKR9d5xVWHnDBa
-Km7u/lKyLPbEFbiMhjezwVVOcGO9kIsYoCgTQinTujoRF9oQgb9aaquq92TH87vHBpVW3iBQb1YlNhpj
-pYk3WGr2f2J24n8sbiho41ISYtnMOJK5FIZwwdQFHuSxHLTZ5KdY+lkOoFdSGR/6otxtmBqHLtAXZK3M
-gEYWTLgtKSlzE8MfR5WQlqfPxc8l2cKG5FYZ93J8YCQ32Txj9MuVyh97S1eXSwuacQO3GROwlegKWYVF
-LZaGVMldnaPlaUh8aHmTZDlnymaTYkUNH/8+5pnoSQBfAuIA7YBc+iGVapx8MokizN62EouuWa80+2BM
-seT4tEVTDwBenP9bklzDJ3hxydLBid8gFnyOfAQ9gLiQxu5/DJLF+qc0RWmqYyUovS/VfVRY1yQv8Wom
-jOopBrdDjtj+7EQ5GStpbk/F2dmc5xIVcuoEErovUvjkzg8w6Yc2edPaXmBthklmrfHh4cvvH9brVYB7
-cHaR2Mf3I3753Yn5x+j+dvuDu93mz22hrZvrWLxJ9177Ncz37GxRdMm0LBUW07h+fk+t45XHSuFjw/Qy
-3cgAKqOnZUCRh6XdChYJbY3ElyOMlTfG0ABUbQvciese5iwB/0LcKGwMLS9MYVHgeYykMo/B0KK1ALzH
-F1rVF3dj9RV7Dmt5r3nOt2pRxHB0UP09PHoi67t3b0fIwtUhh61ZPaEME5MTbTjtfLCcxLG/pdrR1oG4
-XwL25LGeRYLrbRLSG6Ue8DhpPYL0DZH7GhzYe7OGszdxBIB+/PcmduFGCs8paHiea5dlmxRn8HpKx78m
-6oZZuOrRW2981gHsDywBQTdqX3sDebfOUN6ZzLcxnLS2K5ENrMedGBcCi95Xgkd82y2u+Y7JEo/zm1vC
-kwhGrRjOFJ4IBbOSmK67LoBzQTY5Q2yjSjYE+r2V5khp/Rna1L/E+TPEOU4WVp1ncySeZTDhjDf/X2+m
-38aDDpvYDlv3cXfdPdk3E89gzKnN2HS5Ytu5LAUSHjcS9AQQyDD8Zuil3q75vSn4CLhfJj6hg7Zj1th4
-VQMNCom37i/jJFQzXMp8OPvYxajRUvDJ1c0+3WKYjAqwn5DF7O84blGH2ZhpXe6qrbUUziTF34Xp7hjn
-WsPCJXvx52mKUkKWPJe3nsWScCySBcnjYBkgEI3/Zwo4GR+QHfkmBbnVB1TuAp8Z7b46/V0aq2cXgDOv
-iMnsN4v7zUblT3a4rY60zqo3kvtFYs9d7L2Ph+5kfxQu/rrJ2K/ATdtk6vlzw14/ZYtiN1aZqimJ2s5v
-g271KLDLPtRHbrKHoejJQydHj1lpMqn4NzY2Dg92NWM+fk+9nkT/BQAA//9COchP3hMAAA==
+H4sIAAAAAAAC/+w8bW8bOc7f8yvYXIECi3Hz1vba+fAAru1sg02TXJw2H4oCJ8/Qti5jaVbSNJsN8t8f
+SJo3zWjGL93u7YNnfcCea1EkRVEkRVIZDAZ7w9vpDa7ShCg85WJF1GcUknIWwovjw6PDweG7weG7F3tj
+lJGgqTIj/7MHADAZTWGK4huNMIRh8RUIi4HADZF3MMY5ZVTP2dv7iIrERJHQzo3kQ0G2JPh2b++KCLJC
+hUJauM9pdBbbr/pz85BqWrfTMJyMjsPw89UoDM/ictzh8maJQGNkis4pCuBz+Hw1AsVBZAwo2ysIXGWz
+hEbTbMZQHfVRsyD9BOdUSAWpQQnSTADKQC3RUJcpRpqdGO6pWtrleRk5/l5GJEacxbtwMhlNR0kmFYom
+B1MlKFt009QaEdmpoDgQpUi0NBRlrhyKlzSmGGWCqoefBc/SnrU6YJ1LHoLMAWGhIUEtiYKIMCBRhFIa
+3iiTirAIpWVC6+gpWdHkYduFzs0sYGSFwOdmhUorPGWQSfyzsFfHa1sK7rFQ+da5OEHxityIM0UoQ3FB
+VrgttaiYXFcK7uhFwkkMM5Lo3RE1THOSJSqE/f0GG1dcqCYbF9lqhqKbjZQLBdyeAIclniJr03x7mKvq
++fvvpeasbj3FD0gStRwtMbr7JJJtpf3p+lyTWFIF90tkEHPKFrA0OCONU8Jc8JXd9fP3bTYOLBfT6fkI
+hdaSiCg8i7fhY8gaKkaYxgdRhTBXL5hz0c1Kse9jlFRgPOIZ23ojmBk3ek7kncwdQJvWkSV1lZAIV8jU
+iDOpBKFMbbPyS/P/JIEBDEtb+GuG4gESwhYZWSDgb6lAKc0Z01aqNEuwyqSCFVHRsvBTtUPyQtoVdIsp
+d8EjzhhGSh9VmZIId+TfHN2EZzGsSAqswAaKQ2QpNEw7cLVEUfwgQS0FzxbLTfnVp2xb89JgVzMZ26O3
+ImmqFd9IuMFYyT4P7NA9legshkrgLHkAAlFCkalt1rCtgla2yNoMa5DbS+k2GGN+zxRd4TRaYpwluLvC
+Cs7q6qkPpzEiioOMSILNHf8dBQ8qe5IrfLe0PqX/BT5nJLqDLN2Gz4+oBI2kbzu3UMcll8puYSr4CtUS
+MwkrixqIMJaAS4yBs/WsELXc1hWkRC13IX2QA1oGrlHqf6kzttDy3oaJa1zxb3YzYou8CEipRVbEAY6H
+DPKzlyT8Xh9h/C1NaESV/i2OMQaRJegzg3OSSNwvB4YaAcafSZKhDOEL7CuR4X5QAMJXu8ChlKjk+yy6
+w612eySQKATCQJ7AzEwv3Vlls4nBbu2Q9UARYSCQxOaqdC+owj96LaPxxTYL0TbM7lLObL6Yb5QAgZH2
+AaeCMwUx1aowy/S0P45nHXde8YRGD0PB5I7nbcRXKwISUyKIQu2zGFkYC6p1B2U79tSbAYIn2BN3EEVm
+ROIfxZRxooWFL6KDuCDiGK1MYo+nH5Fo+WO5igyFLVj6V4bZj2HpV4MZBLIYBcZAmdlBKkHlKYRurm54
+SqMfwpUymHfj6pyT+H1u7bbh7SpzY64ZLqlJtzTtZxnUUKVNENxzcYfCGqH/ZFLBHWIqgao8pgSRMeaN
+MeyZ3fpMn/PFNSp9BeBsTB7k7lF7TB7M4dUcN0xrwhdSW9w21yeH3RwfBXASwOsA/hnA0asATg4DeHMY
+wLvDAI6O9X9e6/+8PQzg5M3rAF4dHgbw+pWecHIUwNHhuzd6+PifARwfvTsO4Pj1a/393fFxACfHb9+a
+eSc1Sfyykr+gNm47Bzy/fJzCHT6A4oAsEg+p8olCZ3K6lW5oEiHnfLGDq2tdC6yRaDi91j3XOLciA2NY
+VLybwdvh6S3OhqPz75HU7fD02zHc4gyGo3MjERidX34an15fXtyAjHiKJkosXZ7LtD1RvTx+tJ7lWgch
+u4QLLnOaheHttPRXEV+tODMhDkhUO/G6gx8ecRabtE++pA9E+o3Us8mvGUkkfIFn1zh3LFmQIy40/wOR
+vbfpZxdcwZcmRs+UAPb34WsNrXvpWoex41q8Bqsb+z8bsljjK+XUnhBsRL645RbUa/S7r3F+xE341oLK
+wIqiXIfMDcK8qK65w9OlaAmkHkkHjbE6M82xjyglWehYv6LYjCf8bFuoNrsNt9+xZgPVmlxy07/YgnRj
+mQZnfVfbMWTHdhaALYaa0Z5/voVqTfbfbhpz3Y1rHWPPrcJ7IBr776MxGl9UBAoStvLRumA2EDQuoq2Y
+45PED0ql51QqZCjW8OparuaZbSQ/XanmhORulPxGooOgo5aenIQfWw20pQ9lPLIOST1waWtVGUysQ9MM
+O1qo/G5/Z0nW0Xmk2OnAt1eSBqq2Wlty/VbEZbc1Vieg0V5mKs1UzvZUkejOBB4lCePkQ9jHSA7mXKxQ
+hKH+nkeK+90lvnymWVk1XsL7o4GS3bAprRbeszl8KX8sPvvNo7QftGCeTbMZ7C81VHhw8Pzxw83NlcPO
+y/HFVLvUp/D5Y16teerFU6JZi8VB8rVWu7Rl7y7h5ePdhcA6fDXuyyhPWJxyJ2xyZd6OLlpE3HVX5PRK
+29OfyjvqwtRc3ZR8nfEConm9uBI4p791MVzB+RmVJ4bNptl40r/pqrBRerNRB8PbqQYoRoZRpAtEZ/HT
+ASZEKhrpeNmGy5QtCrBrXFDOng76Ur0uyzUQryDq4905291wErWs1EhHYI6VdHEWIC2EP6MaKlWieDkU
+rDPB5aIsAbxMVqPerJSLyo568eRD3jySi8SOepHkQ960T0NOZtR/GO1QT164oc01GC/COsA1Sp6Jwi7Y
+kU/X5/24R+OL7hNtLaIFHNeSsy/HfEUoM8ekurn20Lodnq6jcjs87SFRrC0X+uT8/S4NHts4livBUx0y
+1ZXFfAym2tUbQpi6zSFzLjSHQBnMTWKbG7PtojEtMZBvo/mHO+4wXsSu2s+1PM/ADXA94wM4S68EVzzi
+SQgqSlsw+nMq+MrYqdzRWC/lBb3hGwKOaCzO0hAOX5r/HRx6oLbl7tWrk16musbX8zKwC9o36nPBjabm
++t30536Ns36hgtO3vNCrXjUNbFws1mqg7c9q/Fhjv910tiHkcQPSUcFues3D2Ak3mnbDFav3kqlLcCPF
+O8t7HmrATnOPZ0qlgXqrHYBax0ybuxsiFqgKe6bnhs8fHWJPzx/dnpun/RYWC/BwsxQolzyJQzhuwXxi
+yxbUUVuJz5hC8Y0kTpq65JaukGcqhNfOUB6g6Wy6TolRtrDJmvZyJ4zMEoxD0JeRbvRvXNJluLWoUHfY
+Mn8MV433MwAwWVFVieC1B2J6Yv2lCT39t8ieWXn4aee9cGPGFxtaFFd/Fl6dfwR9iTZXrYcwz4jvBx33
+KHhah6G4oQUdlwN4quzc9McaOvm3pfurWrouJvwu9btpT33nrNkY6M9g/W2g/zbQ/y8N9D/0VQsiwl4o
+mJmWGh5R0z9gCn5RQqSkkVvLkwFIXisNpoIrjJR02m9y7PUmHOcG064QUiYVktitrnr9hSmbhqGF6L4j
+1tORa51Drh3mVLeSN4N7Mne3caprs2GtWuuM5tXNYeT2uzs1zhAe3V38TCWd0YSqhxFnc7poz5uSVZpg
+fI2/ZiiV7D8aZitudXtunp3pB7dAW0qhkZWudNHiGd5O63swMtVi/XWKfmtOuXZyIfjudZffUAgaY5dQ
+823kDFuStZ+pIsrUarvm1ng1fnbtBLNryGIuyvX2QO4kk/VKsYNq7KQgbTXpXUpxjOtZGO9hrkxGGI59
+LXvduZ+us1xH0yW2NYeHr8zGdx2Fopnpdnjadu7WLp3FeR3BUzhpl36KrGf5q057wtcW7ktBF5RJvxoM
+QBPVVnXQep7ifqp0WMFkM5quWOosYbggPgjPAuxnlEnFV3Yx/Ypd3CI2zBDVpVREh0UAorODA92t25qZ
+22uT2X2PS/KNcuFnyIZ/Fv9mwv5M8R5FkxeBMRUYqYHiA82X9M7Nm2E+olry2HTD/Dy5CeDDZDgO4PLq
+5uzyYhrA1aebAK6GN6MPAVxdTm8CGE/OJzeTDumbVXpxdk3gq9TmCzsNwykX90TEZd9O5978S78usY1H
+PejyEDlGYVuAftrv1CTNHr+jfTRrDIZAksQtGXmNkqkVhwXIWoPjlJ82CBXLbsMz229YlqicNkRniq1u
+O0alrHkHvhq4JxAtioJFD05cNOV4ZTBMdSc70dwMM8WnEUnMvVx/0bbTHoWuUkyj3Wf95dwGrNU7IMDI
+lUBBd0xXyOwjXIxkEemG9WdXDWHbZP9ZXJjzfMrB88cqpH6y/8rZeJlXChzXR9mIpCQyEYqtKHWR/Eh+
+2xS03Asb13ijqQaM1bI4l7Av0snhQ3/rlXdGfU8tmRAe3TUfBu7CDr3XEC+zWboZq+6Tm10ZbUk8WLMj
+xW3IVy13SkD6SFuAWidjiiyWlyyEje1F0c/QamBwnVLF4Dot8vQhyp4btwfcA/gFHvPVr1A3OF/OA5iU
+75jCzg5IeIKvO9yM6wFEH/PevGAFMWi8/21kkvRvHV6u/lx3s/yTy3Q9nPL0iliUrWiq9nv9551E+I/m
+48H8DX31HlKgfeVu3gwiEFW21RcvuQmzL/l81jnPG9mILRPEnr7OnXJn7ZDcqXmDvuZYn53IJeDnrpPH
+nrhSa1b56rOvWbYnDhlTGfFvKB5qSGodNd0TR+Zh5zChbvtDF6djJrelYD813d9CMOvUcjMo07Fbj25a
+Z6Qyzxq2K8Rxw3XnLw9U8qh+bBwg+8Jkgc03CxGXyrzysxERKLKQganN/1sn3Oz4YDB7KLrX/r3Xvm5O
+RsWN2aQB22qvHQZZEIV2fDq5/nw2mvyVE4junnjd5tnwYxg6rT7f0TAxlDJbGVr2KjXmUebP0axJ3wxg
+Mp9jpEJ7y9rrOBCCsoimJOk7dK2YoftkYiRfkhX5nTNyL19GfNUzpz/TVccqlQwrwbgapdu64MD9rdVm
+X6GyUrXWo9aL6UvXrZH/RruwzV5sKxVfX11oW21m+NPuCAQutFMXRcFK6maS3qhkQ9TXTcS3VC23RRwd
+b7fG6DgcZmrJBf0dfV1CvTiKu1Wo7+hu699OpqDVFPh/0QzAF3NyjCdxD3tnFiNXah04ume56dB8xzl3
+KbVXyZUXbTxkmaYJVZpKUMSj7lMa+LqJVy3tR09Nz9dq6IZSlZ2xD7kHMz/05rZmQ2uznb3Z3OIYS3wS
+6oj/ffdSfIenyKP6+i9N/+t/bTE/o7qc/ccXvXdMuMq2nDDGBBVuNKcmsCIt3yGwp4Of9rfpx/Ph8Rqw
+6UkYNjZ3gx5bs0bKWZH9vUZ9rVxr5CyWiX1D6901bXJQTGmMFZR7O+swer6J7x+KV5l9Rm46GSYLXadb
+rkIYTqbHr9+4xsE0xtgK+/uER3dr+DEwxaSkK8lcgyqE6IU7WzAucC264nWWBbRyLmBrCmHz/HYtZ+Zv
+RKmHtUWs6rtv+sbt0925oz70XcWUtQUta4M9x6HZGeI9DBbou9dmkfU1o2/jFP48zz8ijDMakeSTRNE0
+575tejk9ceZ4Ws3cwGADU7yldaxt9B9do918w/+ESm2h2M2JG1RS7dRNSqh9/ts+4SFJNaHrqnSyaUHU
+a5dyCXAzNrB/x2BA89ED88fRTPdN+QbDh8XTq7Z7cbRHft9TFf1h9UjzQnfvfwcAwua2O2lWAAA=
 `,
 	},
 
 	"/templates/src/ecs-stack.yml": {
 		local:   "templates/src/ecs-stack.yml",
-		size:    9456,
-		modtime: 1480647478,
+		size:    22366,
+		modtime: 1791980845,
+		compressed: `
+H4sIAAAAAAAC/9w8f3fbuJH/+1NMuHlVu0+UKOo3r947RVY2erETn+Uk3du2XoiEJNYkwAKgbcXr734P
+ACmRFCnJSXZ7PeVtViHnNwaDwWAg0zRPRp9m1ziMAiTwa8pCJD5ixn1KHKjZVssyraFpDWsnZ5i7zI+E
+fjMZz2AcxFxg5gCKBeUuCnyyBJ9wgYiLOSDiASKQgWzUTk4usEAeEsg5AQCYuHydMt+wbdknAADfAach
+hggxFGKBGQfEMFASrOEWRwIoAbHCwAVyb2FBGVCxwgxcGoaIeBwEBYaRdwIA4C6IGfhEaK4AAC4lC3+5
+/TcAgL8klOEbd4XdW+7Az/DJtqwW/O3k5HIjhMb4GLlTb4t8vY6wA6NPM8eZjG3H+Xg5dpypt3mfs931
+CoPvYSL8hY8Z0AV8vBwraWMCPjlJGVwy/w4JPIvnBIvWPnYaZD/Hhc+4gEjTBK4wwE9MGGFXCuPBvS9W
+WrlyMeyvFYNjlxLv2XK8xet3KMROOeH36v8oAFPz4Cu4xesI+Qxijj0QFJDrYs4VF+zyrZcWlJkJ5pNl
+hssCxYFwwDC0HKNYrCjzP2PvA8eMf2DBYZFGBGIWgKDg0XsSUOQpCdGG1M0tXnNYMBoCvsNsDV0IfRKL
+Z0s3TbRS0NVjINYRBrrYGAEElXZSc0ga6LnGEXYj9F1GtRAX6GHmf97HP0QPfhiHQOJwrifAhiMICgGK
+ibsq8H6nYHd59zTTM8x9hr0xipDri/Ue5p6GBDcBBbQQmD2PaTvR1CeHNPVJ/K00bSWaUvcWszfxXDog
+yc2JilHKyPQTjdWUUIhpANUU4U0832VaqxW4TkLkB89miSUWIM9jmPMv4nuJOL+nzHs26yhB3MP1HZ24
+K+qAYDGuFmUynqUL3jNkkF6gjb1Q7OVq6Goymuw5XfKIxuIasSUWzyGdhpgGBAkN6dzCJ0g+B7yECEWY
+CSZtj4kXUZ+IRnX0OEMCeXQ5ivy3eP1lgqgJpsnA6HIqozAIClHMV+Bpw+M7TIQMwIKmoHtkehvyt3g9
+YuTL5BnB24tZKgUmLltHAu5oEIeY15XZVJYi0DzAXC86coJi5AFdyJUNQkTQEnuSBt8j5zldXmEh13RK
+ztCaO4cmddFFNgHCQ2sVG24xjpTHJN5S41reBS2LDZ3Ns1EQ0HvsfURBjFUO06pDuw7dOvTr0OrUoW3V
+oWfVYWjVoWXLv7ryr4FVh3avW4eOZdWh25EI7VYdWtawJ1/b/TrYraFdB7vbld+Htl2Htj0YKLy2zJIA
+AN4g5mGCnzVRR1EUrGGVYIKn1eJ1YJgL5rsim1iCT5YqjMiRi7l8NZtdAEMq+xMrRNTyKhczveiXjNoC
+BRwb1TYzZCgw6ilgqtsMc5mhXiifeFYYGClJMtH/zkdK7IQkJDRzaiCSTWW+nRppmjCmhGD3WTFHoQYB
+TMb2hgwkdICSrYJ14FSnOYnmHJYMEZHmdtPRxbdT6IzeE+GHeOausBcH+EuCBZgwApdRAvghYsmoSCe6
+X2ECHLM7X46b3OZgEBQ+Y0brcoKu1S4DfKEh5ZMaw+AyjAT2qkPGh+h3FXkud0lx9FUip3a+wm7MGCbu
+l4otQ15M/ActPU9sAD6BD9dj6fwin4jmzQ6UHDLrv0rCxMp7JUznzUgI5s9lmv/chEImEWiDnaYWW1kQ
+h39wSurKGaIAuTjERMgtL5fZgFx/BQWhMo6ShOfRwC5fN1J65pLRODIcI4nLxlOanPlcLpw6o3rFfG/5
+LGsn6Er2JDmYKyJAsLin7LYQUATitxzCmAuIOYYV5SKFLN8VfWE0uUoWnaleZ56j0hUO6V2ikRZjs1qp
+7Z188fFyXNdVDCQFkusXfogC3/WFfOZ52AMWB/gbrlt69ZGZ5nOUGatwAAjmsXuLhXb6JKqA2sbOUSDH
+hnG4Z74EVXx0niLot5F/TInnS4ES2d8grpNCZ0PmxTsq4Gd4MflnjAIuv13hxTZ1rINhwN82ix/fzVAK
+mClAPZEnRf3AMa9KAV68ZxJ9I22GT71IP09jy2WbQvF9dYZydXcxCnrrlZvPeDhaYiL2iL6rZz37ulS8
+jOzVq3G55LurSl5yOeLF8s+LEfHg5wqCCXRKpb4B26fill82niUxqtpXSmJg0W3eUVnIKgaT5zhMISKV
+e0zJHC+6yAYkK+L7WESxSLBmspiaLx+p2emAXBPMBWUhZo4jv6u6q1G9N07wFOft+zSRdmPmi/WPcmUp
+R8mBbDZZ6l95VyjwSYFSFPf2Wg5QNc4GpHLHmYXevC5G1lcqSG6RNgPr5EenlGqRzMnJyXeAws/ERKFv
+2lar17CGDWSiEH2mxJTmpzLPkTPw5DuYYQwrISKn2fSoyxvonjc0aMOlYXOkvk7Gs6Yss3PR9PAdDmiE
+2TL2PdzUZagblxKBfILZTbroNlYiDE4uUBT5JPWr0afZFV76lFzT0cV0q2/MTYy4MFsOPMLoYjo9c0AK
+3xranX7fwvC0A2oXQOdeG/c63iAPeo9LqPYXltWZtxYloEWq3R72OsN2LwOK43Kq7qDd7nvzeR7UxUQw
+FOxAe17LxvO5nYFGkUkoE6tSS7iDudXqoWEentO4Ar7n2fagY+Eq+KKi/XbH6nstC55OkvMTmSsi16Ux
+Efzwii0zlAQxdShe4VE4QFz4riSpKfpkmTqXGyDOfbeJiZxUpuZgSg7anQAAJuevRlquqcerfCgFcMBo
+2f12qzW025bdMio9KYNgtdu9fn847Ngdy6j0pyyC3e+0O/2OPRyUIuxw6A/7g3572Om1hp0sgosKDpPB
+GQ66vV6vZw3tbsuo9Mes3t1ep2f1WvbA6pUh7EjV69r9VqtrdTqtUoT2jt7WcDjsdbr9Xq9v7PP7LE63
+0+v3BrY1tAcFHOX9uxiDYX9g28Ne3+rZxv75ksHqDux2a9DtWYNepxJr1wSW1W93uv3uYNA39s+1rKVb
+nX6/02q3Ol3L2D/jsk4waNt2t90a2r1eGdYun35rIEfHHrT7OdtxVOH6Xatvd1pde9BtKXVOrjCnMXPT
+XeNkbKcbyktGF36ASw/opqMLxykAbuAumVwMhJ/S3DxHYuVAM/fsigY6V1cL13R0IR9skkz9z2oJ5NuD
+bEecxyGWoJc08N31GXXjMJeypp+ZQAKXvwIAMGGyWMgKl95plMJIMXzi+hEKnAoAAICZ3vdIxbFrJ7FQ
+RkWXhvC3CsSRqxMALrizVeqgiXVG6mnlR4zwXcFMQIw46J47Pgod9SVS4M1kg2YyGuDNym+P07U90WNB
+2WRs70gDAGDCi+kCft7ZMNTBqOKp2cxmF4nk2wIjk7s55SmGcoJ3VKU9RsZhlJr+bhFk6zTaEIe9VYGp
+RG9TYymgbsF+Y59KRr7iLQCACW5AY+8eCXflXMbiAssUX57BHEZaqDYNyUBv2OdY5e1pZNhLALv2Busa
+ZfLR/Cel5YDxvfEbG8KQyYGjyw0VzFJQBXkZi3O6nKiDpMPQqbLndDkTDKPwCJVTR1cEvpd/jJIgWDZ1
+MkFxszVKtySlHq52bE4KctDJN6dMU33OlO5h8sdPOZTctknD1xRr5TTyaS0Hr7c4cvHRgWBTcanvFFb2
+zOzNtqpU67M1QSE9e+U4Cuag2q/8QHYXXVAPO3A5+unmcnJ1czX57w+T2XV+7UjLomd44ZNs4Sg/UI9b
+SG0XKa/ScfM8qZBlFmnQnSiysBGi51F9i9ea3pvR7E2B5LUf4mt67t/hmW6AcVH5pCkQnzxEPsN8JHYA
+J0RXLfJH2gAAs9nkAJPZbFKNngBoVd5ezHZevr2YXSC50f8GLlS9uc540qztOPr18VvvMxxg+V4vBbJs
+KxfHw06o2Ez0MXK57TC7w2zme3gLNVaNZjGrMDcAgFmK+GqdFk735SWzyShYUuaLVejAaDKzu738chfP
+A9/VRngVUPf2gDwKJkUKeIUPZKBSI5bCTVVH3UFyaYFLA2o7l8Ge+wvsrt0AH1DiKi6Nz4mtBRIxd9JJ
+UgoFenKhbKQdWuVumRhgv3MWEpGDLrrfB6vKRr97nnNU7ixNAS9mmY6bykwgl2G+fNxshJ4cRqkwDhIY
+bXdOL177xJuSCxTBz/mCQy7o6GKWUd+iHk7n2zIFeT//B3bFwVxC6g3Gy8fiWDVGjDw1v2+OPsn8gDdf
+Pipxtho3v98WV0exoDPdYFudSWSAHKeIcdCxPl6O/4cSPN00plamNyWtqceC2gXQc1V5TBuAdznm36tR
+VelO7vEO1qaR+MWPWIyEKMA3zlWhKoEqdOZApq9PM0v+lYdAD1kI9LADUWxITE4L8k/zWQBaVmQq8rAL
+9IlGUjSWBeC0SFtXQ4qWSOCR0JrqyAlP1dRkfvrtqG3L+/WKsv8XkVXH0E7S/2R8DWm1t9gu+CdlU3Xm
+L0lZHJPZGY2FA5fXre7FzuuxnK6yOxMAAFQDgocELuOUmZNXVOWzGnaX54VP0h0sn5JN7aG1C4geXskd
+pPbG3feXKOZYaiDFL5H+E/LFe5I3QbrwngAApId4XnpKdzj0bFB0vKxY84qnhIerQoWIltnOlETIwhBv
+ukGqjhzLA4BVPuut/VPdKphOt6T8vzBcsbvmXxQ3T/YtHsVrCRWrRZW58uBnm6sq+Tw/czg59bb10NyL
+kkTighJfUNlz4cBjfptWEgmnIVrinVwmfwJXz+7o9fNaXR8LlfDP3RBIqhWZR7vwKCxWl+Uml2UW190C
+tExtSrRJD/Eze8L0oD5/bF++JdwxZXKRSDdtVWT7b4SIJknrswN4T8YvIa/pLSbcAYb/GftsD+BlLK4w
+jyjh+A2Nzv3QF2XRV+2SzrAM3vlD1OLHBA2l7SPPZ5sPd15VJXAy5/vS7Y+qv1nPs8FBOO0Jy8jeA5ns
+SSsrApkx/tra0fHWcT9/vXls+9/dPt+l7eyAGCY1AQItl9iD+RrECudu5qk2PtWXm28rdylXDfluEHuq
+dy3czYHQMlc1qnTjNJPQVtOilYJW5btfnAaWBBzZm1QevwEAaq+J47xCHPc6tWSb9mulON+9aM590pwj
+vgLz4a56iNdxqHsWgwDMNaB7bsq7h3NKhey9jCoRmzQSTXTPFR+JIquXYN6BqTtt4OVjvmj7BKbJEnMX
+Fi31Rq4EKZJeF56OZ85VJggmhpf/eZwEJanEQTHyd0HTjwIdy3OO15tzDmdK/JJCRdklzvQjV6I9DtbE
+wm1il8v/GvvoZHjJCvtRlQwAc48zZT+T8exmfP5hdj25On35uHXnp6OxJ+9+nL6b3Iw+XL+5uf7pcnKq
+e2m/CP9sdD06fTTS1g+fePihoek1fNq8azUN59FIL5AZjvHycec+2pNRN9JLV3mI9A6XhFA3wvKv1dWy
+J+PpeNVHn2YfL8c3r87fj9/eTC/OZqcHwm8e/Ww6G706n9xcXk0/Ts8nP07OTl8+Jg19smzhB3iJvePF
+mb6bXY/ejSc3o+vrq+mrD9eT2enLx91276cjvGdHjHTFyLYJ7rbL7iMaqsMTw7KsnmXtL6XRe4KZA7Lm
+thdOLSkH4JorGuImdm1T+k0zcSd38ZzpBj8cNNn/ba9NDJpa4RijHgWrx7RmWR3LqlUPQcxZM6AuClSA
+52vimtvrx6a82/acwTgc2XLLJY4PwwPD6p4C36hd4zs3pNXFIbHC6jIeJDcwYtnozOlRLEJ6JxMhBOpG
+4AozDAzf0VvMwRdASXL9Oq2FHaQpy4hgflbl3Z2O6CcD/vAHwA++KNQLyj5So9PiTOF81SzY4Ag1NxcH
+k3tjNZ6oW7iyAveIJzeQk0tZR+q7AOOlug6ZMDDg11/BjZKnxs5b+weVspM4CCSkoLG7KgIdZh1Gpy//
+GN4KHEZwhKEaf1GfPx0mzFAENRZqvUQYGTWY/GV6fRDRX8ALcGMWyPzIDzAR8suK3puYMcrANBfIly9D
+9GDKqgm0rUpP+UHzbiywkMNh/IccKnJQBgCAgC6XmIEpoGxey7XBD/RvICjiUCpBXbmNnBxqb6CqO0J5
+pXGUEBn7bXQ4ClHNj9ZB0IV/EMRFYsf18gKlVjZOfhN13FVIPehZ1rFc3BW9J5uA5xyLFt4lkOmMO/la
+F5FPsQcv/7hkOALThdrfOV+Zf/3179j1ODJrKSc1f1mM/3SEa2zTjf7vmm7IvF5e2mt4X7PQHV7jvm92
+4fvkj5TomFX22Pys0/l9DJb+Vk61UZT3Z9WQrnrQiIqqk0yj7YisaMyCdTFUy2XyGNtV8kz22+a2oU61
+mx8p5DfdzmUSn6PgZdD6858n71/DD9pOfM31ZrT5jG3c+8vr6ft3s1PDlIqbHvPvMDtF91yaAfRDGglI
+niS78tP8rrwETrmP3pamTWRP8PJRX3NK6q5Px8X5yfvXxxkQ/uv6/dl7R2drGH7RZoCI/wJU/VbNCsOC
+pjcn5/ESfA4L/yF7R2s/A3NztWHpi1U8VzcaZPEjc7sGyY7Xps95jHmzPRgeRXoj6lHQ6U3KBItheZvi
+CEfMWT/dFZbdWauDYZr6Ru0poURtFDe9VWUfzkOtuHNyKAt0qpuEn7Un3czCb7+nKFTi0jHn7Xzztkoj
+0x9m4ua9Tzx6z5uz2YVSKL3VEvgkfrhBodfrpH6yMVeDReFBcbhATEARNV1QDwQ3eQvbLf5aw56xUT0n
+8jvUtgWI5GcanmpwCjXJtGYcNzYFS0qDFaWqlj/9bZpnym0SKXr+x3GejhX4N9qlqkVKYqRTNiZgevDX
+g4gAAKYpywynRmoP42g8hpXvnKLgHq350WjyfrxkCb+k3345GlcX7k+bd4g1WZwq3ODUvXWaIoyyD44k
+ugx8D7MAzXkzNcGRmDtuAH/4obgFTEk2ZKG9EdBltUMmPzr0Rf6Y+4mkf3N3/EK3Ui7leUn4+h2cMYJW
+3260+o2O3Wg5g5bdVX81Yy86lgSG2vXox9lpcobk5ArttWdQGV1Ob95Ofjrd8YRjadxB+ZwqefgMkhGj
+btNpStMm3xl9Brqr8ryUAF/z5oInD48nlEyrRANz4yG7czWBLEzViivhO78xmQU72MuhoHI/aiF/OiVd
+EIEnxPQ+Kd8OqX7gc9PFWOhbzPd/JFf7ZUpU0syWvf5f1SeZP+YtgTJhGl0yKqhLAweEW36O+JrR8JIy
++RsurfJq8DVN3ve63Xa3HGbse2waOdCyGupPs9UrGaIZDhZXeIFLf2THqBiwxAxGphM/wsTj74kDRg7S
+OG5sN2NU7hcAsNdw1QbbZ6iZbpbL9wCVyfG/AwCU8dLsXlcAAA==
+`,
+	},
+
+	"/templates/src/iam-roles.yml": {
+		local:   "templates/src/iam-roles.yml",
+		size:    2405,
+		modtime: 1791984828,
 		compressed: `
-H4sIAAAJbogA/8xa+2/btvb/3X8F5xUzMFSW7bh5CMu+X89xNmNLYsRuh90HUpqibaISKZBUHs3N/34P
-ScnWy0naXuDedU2lww/Pi4fnHFLxPK81+nO+oHESYU3PhYyx/kClYoIHqDPo9Xte7wT+77TOqCKSJdqN
-TMZzNI5SpakMEE61UARHjK8R40pjTqhCmIfwFxWQ3U6rNcMSxxReVNBC6ENCpqF5QGjxkNAAgTJBMBkP
-guDDbBwE09COlWQvNhSxkHLNVoxKJFYIoEgLJFMO4luO7UyyW7Boni451f19QtzwfjkrJpVGieOFlEWD
-DKRhTCWUGBVCdMf0xpnSJHzwtcIVJQJ8+GXSf6cPl+DgYA9LtUGf6EOCmUSpgsngNkxgtZRlSonaLWCj
-zsB+BpPtg5FjRI5SvRGSfabhewXr+l5GDdKv7L84Qh4acZTKyIhOqGQiZBA60QMKxR2PBA6tknjL8wb0
-VWglRVxQaK4lBFsmZYXTSAeo3TbKTDP1La7ZCRqGTNjklhpNwBloJaT1QpMHmgTqQTdmRApLHUWRuKPh
-BxylVDnBCGwFDMdcFN93czKCisH+EoKGLI2LlAjLNd0S4oMqBCg1yH2NMqiShtVZw9qsYdOsYZ3U71Vo
-pMqc1JiTOnNSZw6k4yqpai6pmUvq5gKpxvugxltWecsab1nnLeu8ZZ03G1RZAaXKCkhVVkDKWQHtAt/P
-YWfsie4Y37M4jRFP46VLj7uUDIEe4ZSTTSGyLy2uHNmHRgxwZpKGY5xgwvTDHnGhQyGSwRBeQXJ/nZgD
-aw3jz1nDwI5vtKZvrRHkE5W/pUuTo/g2Qzbs7oIGf4nU5kk7AQmXeR0nBKzKYjqdkpxJjFn0aiHUoBEO
-Q2ly8ZdJmmGl7oQMXy0sySbskXMpJmQjIL/JlDYJhoqel/5XSDSr6Ny3ssJMP0DcdMPsD7FWiUj1wkS3
-fg3DvJJ0UZTNNUGoGceGjuga7IPCoqXxKOVhIhjX3aZacYY1DsV6lDCoZl8m2oa+m45Gs6kpqragpVC7
-QudOegs9iqmnQM+gDVpcpTpJta0Yc43Jp13dsqUEQFCUPShNMbg7MM/KwNpNC5HN+O6argpjBjmnJJWw
-O3+VIk3q4NJwtir2eddKVHjngFar9T3C8Wfu4Zh50C8ednsnXQxv+LPgntFXgNNiU8kBOacUbbROAt8H
-L6kuvoO/FtolIvZH9hHY+6YbVdoPwYmRgNVcp9Dy+W6730BbpDHjVN7kyaC70XHUusBJAutmnQktyzVd
-w2otxOhi6mxIlUex0l4/QI8IqNMz6F1B7f7JYHh01KPoqQQbVGDL8IAeDsPjHeyONnA7WvV6w2V/VYFV
-ub07pOHw5OAwg9G0mRs5Pjg4CpfLHYxAWEkc1ZBh2B/Q5XKQIXHicSFhxzVZTI6XsFT4ZIdVsI+asYfh
-YHA83HqnhK0adXQw7B2F/R5gW9cUcJK4bgiax7wzm0mxYhGt9cTT0QX8KIMsBp7Nfma7vmqG9SZAfvZ2
-LSIYQn93wQlsDAH903aD7qVZlhnZI2CkVBpTA5iJiJEHyJDwznU+bjerpmWSqdST1YoS2Nq2HSyMGCGM
-E5bgKCiRzd6Ut4xQYwAlg2w7mI0BOwKsKEJHxOUhBQljp2KjUy4wx2saOvVHkqudWA9hyQOQEDAcB/Yh
-sTBfOVU8CVy3m3EwzrdbpikkI6Bayc7FVsjWe1U3OxX2raQdtGlm27oXJuSA/4D/M99VnA8tYCTS8A5r
-sglmqb6gkPqJKQzNwJU9IRtGrjAsqc3aeazXJsGKbpELvFYlQD4LkvyP7W80o22KYTCWFNxSYuaG7SjY
-B3l7YutSMyJXFWBQAymO9yqch5Cd9qP50y5tx0K0FbdlVrby8lGLGNMO2J+uupjRetBcU22O/4JP+Rl+
-gK3fH2YjpbrlBHcsX7tKhpp3MHBoFXN3Z9GsSQFQenlOsQ+z8d8Ep9Pt7UTNDQ03Ey9BBlvIH7b8wXZc
-sXUqbRgWDG0YzXNB1mI7XPa2zRP3xTH3lo1VTwAOU6FmWBPcRWsfzU0E9OOg39u8eTDdQF6x31r34TWY
-OdJOdddxZmWmxMVEzldxsfsBHOGyyC5+XBjP2ZoX8/GCxRRKW4Bmi/67iy15LFJujxHm5X0CvRwt8ytE
-B2hq/nGoHWfweZ7d1JRvM35/B8D3v5gc5NZiR59hOH4YvYxSBZ3+xExf8bIhKmvZW42R8nyA7wuextIo
-CDM+SJfghGkycoeWAK1AhTx0Sh3lrjiXyNvilrvmQnCmhWm9C4cPGI6hkk1DCL9zxsMphzYP+JUbvLfF
-ze7onbeuM6mJcT5wWalAylE4rnYqeddbbWGyGfmVm4PlF2NuzJw2TTXZhULnnAfBL1hBK9mBKbDH0b8K
-Sfb77/wl4/4Sw0HCu78tVpQHOAfbwI8i5MFB+055ZMW9pRBaQUeYFKA+NN0+ACwvA2LgWeTdIs8dH9Cb
-x3JafIIRmUVTU/TYYePVfKbz8dNzMpWNSuRR9Ob/Xie4IS8/KxiqNQ4L3rWAsanT59s6DS0lKzQJxFpV
-rJ9mJVW5oPpUEx/yjPnbrc/I+Jgi1LCC7j8w5Wb8x/v5YnJ9+uZxdxp7akROLn+dXk5uRu8Xv90s/ppN
-Tt0Z8kXs2WgxOn1smyOVgjMVbA9633Vzu0z4t32/HTy28xuMdtB+81i7CHlqv23nNwJlRH6xYBD2gqI8
-bG84ntpPVZNiEZoOodfrHfZ61VZE3HHzyUBC0FZG1rYM10f8jYgprMTAM3b4mXlk9cKS/Fxz3v+anzJX
-5JY1u2PPqPNxB06avV6nHrtEwol6A5sqevArV+lfGsilhETT2rg5pyPvMwKL6x8Cntrohx8QvYfk06vN
-JOYrAOQF2H8cWKz2svgZ+TpOqobU+MW3jbhaACm1eZEX2YCHEcTvV86Gtd0uXfAVPHZ76Ojb9xAcJGPM
-w8rSryBQNt5ODatbQ3jYucGLcdUqTczqlLc7XXn2pLCP+8vBV3cy1uinnyZX5yY+jHLqQblc7e/Jnlez
-xfTqcn7a9owyXghdNpWnULKMasgRoYihjJIVntNy4WnAWa+7LJ8fQJ6qawa5++q8bh/6/8XV2RWsGI3F
-LUUfs7vDRH1Ewn6aMh8hhTkFmo+ry3SNmIKKdU/DqisNMw/lGW7N9CZd2ks1U5IL13HQS3HtM6VSqvyD
-45Mam60KtZHseiBHSGo+15XXPb+SrWpnskS2ym2XMTjqvHks3/4+dape+4L4aExOfqqkReQqp9DJhOgf
-NSAcNjyT5E/buQXtPShoVjSW+hRHd3D23APaCKXtpffH/OnjHuStiFIQ699i6YN2maJd6LE/BTabFQiN
-LNYRC6mM8FL52/vwJlzN2eiHn6uJKWfQNT1lF97Ki5vdYr9ybUu36//lpX3VotkFC0O3Q751YRPUPxp0
-+0fdIfwMjvuDd/aHn4ZJ8wSKOovRr/PT7NtIUGoaO3vnjGbTm98nf53W/N08A0pkY6Q1EPcySKQgfuAb
-B2TPUuwFE5scczikaH+lMuK+aVmYZbp42xWpx2v+WaUYrg2fOmq/eFH63GEQ9WOuHSt9/DEfrvIDoPnd
-DMvCVdz8+sf+Nsv2Fmd7b1OSN+Vre1wu3HJME5CvBRERnHtJ8Rx3LkU8E9J8eusX27yFyKiH794dvCuO
-jFkop9AC9Htd+8fvH9a8MqfRCnSkkoIpRRe19/go09lt4TOaUGgmrsAl7RKq/Zwrt46pOx/tcUCT8c2G
-z90tSEnlRnn/DgAA//9prxJE8CQAAA==
+H4sIAAAAAAAC/5RVwW7jNhC96ytm3QAGglWb5ra8CUk2CNC0RhzsnsfUyCJKcQzOaF0jyL8XlGzHTrwr
+2UfOe8P33oypPM+z4vv8mZqVR6WvHBvUbxTFcTAwvb768yq/+pJffZlmtyQ2upX2lbubOTwUj/DEnsRA
+AZE9gdaoQFY2YDEAirQNQRW5AQRLQSN6QGu5DQrKUNLK82aaZY+kWKKiyQAA7qxsdor2Wq6zbIYRG1KK
+0uOeYytKZdE3fCj7066yWZGBuUYXlvvDIwPPNb0pSaqdAHrPaypBeSdda9qK7PxlWd8nHSTjf2ND514a
+sCHgqmvdZ8ZgI6HSZ5C2qtx/VMLaad0jaOk4JdljE1kAI8HS8wJ9JzXsjBxcWmHr1XSjyHsDWfZPq6tW
+t9nNFe2/neI96xv6lgxMyEpecWwoGuOwydPNMnnvvYjhA/XTPWmheoD6vYghy55IuI2Wtne/ld+HV3yf
+G/NQPBqTqvviLPKKorpdg93vN0CQZASEtFu53isog9APSuvWJyjA1UFSn2FdO1snylRB6pQoduke9d/P
+GD7N2wVMLl6OZ/+aX7x0kp+6S14nR+yi26GEnbF3dnPLtm0o6LGH7TCUTpcAAHK4qyqyaqBIG3oSk0Jy
+wboVevMTAAB0AW/NYAwG15JGbMzFy/s/06uJzDr5Sa/Cdhv9i5tyEBXzlsERdIZaG/jj+CyF9GHEAAB5
+X9v0ozjc6RMpDEQ9Iu7xkY9LAiAH67ktq+51TejLAThZGYG5HsZ4FHXWM5YL9BisC8tBErbKYtGPwXpe
+DistNwEbLhfmckxKa1Rbm3vSR9LobBqWSyZkgCxBzKxdeCf1kEHbmP5dXtBNelcqZ1FpBOsvJ3rAGJKU
+/lr3pB/W/zRyhiIjoTfdB2Mk+JY8jQbPWn17sc5qPprSi38IohgszSJX7gwf5/OKskz6nvl86hM1/KNz
+9zVyM56/++AZmF5Os/8HACA9dRplCQAA
 `,
 	},
 
 	"/templates/src/network-stack.yml": {
 		local:   "templates/src/network-stack.yml",
-		size:    3730,
-		modtime: 1480643528,
+		size:    7585,
+		modtime: 1791980056,
 		compressed: `
-H4sIAAAJbogA/7xWXW/aMBR951fcokl5gS4f3aTljQHt0LYWAeqkTnswwW2tBieKnXao4r/vxglJHAhf
-XWkrpfgeH597fO8l7Xa70fk1ntB56BNJL4NoTuQtjQQLuAuGbVpm2/yCf0ajR4UXsVCmkX53DLfDLlxT
-+RJETy7gA8J46jOvlf4fsWdkBBFPOZWiBcSLAiFUrHMnzo1G4ycJQ8YfhNsAGCtYN+D37CH5DAm7C6/Q
-HfRGLljmufr9aH2GpQqnG8wKxEaIfaFBrArEWYfYFcjFOsSpQD6tII2bWIaxVEncht5glqknfkxdOBvR
-+yQTtdb/GwaRTOMA12SeAJAemh9e8RJcdyyJ95SsL9uKqtnInTGHytt0s34VGSBzH42tnq8xbFdirCvR
-dhuFImuXImuXIutNiqw1RfYwrbl6SfaqLGs1rTiOFLXaXlLl7FTl7FTlvE2VU6gaURHEkUfTesUWUzST
-RYgkanO/a7vuqmKHURDSSLIUnvx02Sz66gcedvzZJeOzAccuht9a/7agiQRNfCQN04Q/2d4BF5Jwj04o
-x8fChRm9J7Evs3Cfk6lPe1yM41BlCDKKaTX4LRCSY25CC0/IQy4RoA3fKdInFuRruq1N3SjVaFfo0AtZ
-bHZkwCWNMMMMVHUHXpclio5E4sc55bLW3jVkVh0h5TNxg7VRc1BupSYHp06aV3nXah4VQwiX+4NhWZNR
-iMKIUXNaL5gThpqeQy/huCZyg1clqgJQx9jxsYZI0gVKYFa9KOG8HFkaGTytrjwXfaBtnpFVz1NIjRys
-4XTvILzhP0jMvUetvDrPhPlkynwmF3cBVw1HfepJrHyzBWdXVOJ3GhhGXut79kkmfL1XNlzdhrF7siSt
-tydpHZCkPskPyvKdr8o+IAvnfbL4D3fh7JXFKIglFdvqTSEmyWSuyaOedZs1x9D20u+SbO4ohi3kdYOO
-Csm4mj4lP1fvnmaG2j50C/E5oGykPkCP1Fl/Ruk9Yd90CjU5WbFUmq7pCduKuZDVESLwmDq5JoWtI31v
-J7PRcgJp1nHS7BNIs/Vb31kcxYA6gTjnQHH/AgAA//9NTWifkg4AAA==
+H4sIAAAAAAAC/8RZ3W7bPBK991NMvAv4xk79830FqjvXcVtjk1SIggRoECwYamwTkUgtSSX1Fn33BUnJ
++rFsOWmQVS/qiMPDM4ecGYocDAad6W1wjXESEY1fhIyJvkGpmOAe9MbD0XAw/DQYfup1zlBRyRLtWuaz
+AG78GVyifhby0QP9LCBJHyJG++63ZE9EI6j0gaNWfSBUCqVs2/SHOu11OheoSUg08ToAAHOqNjmPLYOx
+bfoHKBEjJESSGDVKBUQiCB5t4BETDYKDXiMoTegjLIUEodcogYo4JjxUoAVIJKHFoks+iBjXblD7RvAl
+WxV/m4etuJD4b7pG+qg8uIPb8XA4gvtOx9+ScD1uEjrnYSIY16rAuN4k6EGgJeOr7cuKgt/t/ySCAVyv
+0WqJOQ5I5CFKDIFxLUCvmQKdSdN3Lhsv0yQkGlUJf0nSSHvQ7Xbsyy+ReD4Xqxfxul2jRNACQozYE0rL
+bBmJZ4jESvWBLYFoIFG0OywXHLdvp1EknjG8IVGKVkHT2gcaiTR8Jpqu+6AmRtAy0QXXKJ9IVCd8mcYP
+KJsJG/Vi8pPFaQws6w+Mg0IqzORb7hKpkKFbN2S1krgiGkMQTyh3/fg4HO534+OwbwwM85ngITMkMoG/
+EbWr+Mml0HAHJ/P/pCRS5tcVLrcO960scF8X4lrMjFC3RqgS1j6QQtVdoGByBICbiguSJIzn7AMbubNa
+dNz4Mw9+wWxxduXBaHhq/30YfYTfWxPXcVgzG58OP4z/2jEb1cwmzWbjmtlfzWaTmtnfuVnne6qTVBdB
+uwhLPpnZ9ZwmN/5s+37+MxFSVzPDJYmNaZA+QPefv6a3gecFJu+Y978HFjiLvkwG3yZFr3nx9jKjLHWe
+9ho5VZCOZdfbZVfB6ZVZjo5hOTqG5eiNWI4aWI59V1QO0xzntecgzxzrj4nmQBWmk6OYTo5iOnkrppMq
+0yz6v0qRJgXYNql5jcmokWcZqYL9OaWPqFvAg8khUAfR6VyhEqmkmEewP6sXCev0fDb2vHIM+1IkKDVD
+VRVsxkL5ORL00YOTL4yHC35BErirZL0+dG/8WbcPXZNSunBfQVhwpQmneI2ccLrxIHQFpGI05+QhwjOu
+gjSxswZapths8k0ozUmMqsHomqxqDphnAP/CjWcnf6etqma3uiSyFPWVaHwmm/1K2oLMUWeGTarCr98V
+sKnWhK5j5Ho/7I0/27EuRUqCPFTfuQcHhq1NRYXmIsy8rvcvcn+R7G3jfOHX2fYKuvOF32vlcCZiwrgH
+Twl1mJdE79G3BF0YtY9g9iKUmPBZhEWszxf+abnld6/Sya3nrcfVUnKoTtXnzJm1krwgiUNaJN/5OUk5
+XTcs5+kTYRF5YBHTmx+C29SFEVINdzDsw8lX1NMfCno9uH9N1GYu7YvcxiWwpxD+32QYvZUMo1fIsFtp
+X6nDu0z0+BUeTt7Dwzebw8mLPLwSqUbVtpKt1bWpPa0+HhqlTcY/HSb7LMuypEVrGaw9VaPSjNt8WZqF
+/FtmWLE9pqQULm7NylNQLwdv4sP+MWu7xZc6XPDcAhevKhXDjdcWQAXRqVKCMsui1b2DZevF2meJ8N0J
+j/6M8PjdCY+bVk/rUiun1XenPHk95eYvoBJje5Tj5SYv/kba50cOePgswRxcDcyhW7fqGmrkdqvHz8hG
+efBpWPHmSkTNyWUxvTDJJcI382OqVBqjgfRFxOjmTNC0uvHPn0ATjc1N5hnAfLlEqj133tZoY2gwTllS
+Ph7cfQKUT4yiB3dmJ16IeEpi8l/BybM6pSKuVdHimVKnxx10lVZe4WG98PpErz34UH1nVNhRyTnoFHIz
+viXVQKJNyaP0fImqFb8P2jhMw9ubSSQaz8Uq0BJJfGw3P9XnYjV/Qq7VsX3cwclDMdjhnvk5ged2XlpX
+ovx0KvnBE4pSvAQTz3PNR59fnGGEptlNomdilbD2vOZGmXMqN0nzNJhVjTJgIRZWbouYSrJ/5gaNHT9v
+8qPuQ3EUzKfRSkim17EH03kw/vtjdbHbcjWlFJWyG4oWPtYm7xQ1nXHUrHIRG+0W9oKmFe4KlZaMamfo
+dG6yPWdLpBsaYYsTV2nUFN6Z1proVHnZgU64R9z5z4RJciCB76+dmUHTcizdPbRvHbMQqey1mwzc+PXW
+a0mWS0Yzcufnte/en9PsfsV6mN3nVO8b8tf1oljapjr0k8US7iqhZq4qsvuOgb3wsHkU7g9ANcPUsoNb
+GiY99PcmjtooZ+6CzED6KGOmzJXlVDaMV5TUHXBTWrJRi2O6S2GP7kzJ+d8ARSixVqEdAAA=
 `,
 	},
 
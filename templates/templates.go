@@ -51,3 +51,59 @@ func NetworkStack() string {
 	}
 	return string(b)
 }
+
+func PrometheusAgent() string {
+	b, err := readTemplateBytes("/templates/src/ecs-prometheus-agent.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func IAMRoles() string {
+	b, err := readTemplateBytes("/templates/src/iam-roles.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func EcsDatabase() string {
+	b, err := readTemplateBytes("/templates/src/ecs-db.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func EcsCache() string {
+	b, err := readTemplateBytes("/templates/src/ecs-cache.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func EcsBudget() string {
+	b, err := readTemplateBytes("/templates/src/ecs-budget.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func EcsNamespace() string {
+	b, err := readTemplateBytes("/templates/src/ecs-namespace.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func EcsAutodeploy() string {
+	b, err := readTemplateBytes("/templates/src/ecs-autodeploy.yml")
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
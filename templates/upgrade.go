@@ -0,0 +1,77 @@
+package templates
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var templateVersion = regexp.MustCompile(`EcsyTemplateVersion"?:\s*"?(\d+)`)
+
+// TemplateVersion reads the schema version from the metadata of a template, templates
+// from before versions were recorded are version 1
+func TemplateVersion(body string) int {
+	if m := templateVersion.FindStringSubmatch(body); m != nil {
+		v, _ := strconv.Atoi(m[1])
+		return v
+	}
+	return 1
+}
+
+// CarryOver copies the parameters, resources and outputs of a deployed template that aren't
+// in a new one into it, such as those added by WithIngress, WithMessaging or WithAlarms, so
+// that upgrading a stack keeps them. It returns the names of what was carried over.
+func CarryOver(deployed, proposed string) (string, []string) {
+	carried := []string{}
+	for _, section := range []string{"Parameters", "Outputs", "Resources"} {
+		existing := sectionEntries(proposed, section)
+		entries := sectionEntries(deployed, section)
+
+		names := []string{}
+		for name := range entries {
+			if _, ok := existing[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		var b bytes.Buffer
+		for _, name := range names {
+			b.WriteString(entries[name])
+			carried = append(carried, name)
+		}
+		header := "\n" + section + ":\n"
+		proposed = strings.Replace(proposed, header, header+b.String(), 1)
+	}
+	return proposed, carried
+}
+
+// sectionEntries splits a top level section of a yaml template into the text of each of
+// its entries, which are indented by four spaces
+func sectionEntries(tpl, section string) map[string]string {
+	entries := map[string]string{}
+	lines := strings.SplitAfter(tpl, "\n")
+
+	inSection, current := false, ""
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case trimmed == section+":":
+			inSection, current = true, ""
+			continue
+		case !inSection:
+			continue
+		case trimmed != "" && !strings.HasPrefix(trimmed, " ") && !strings.HasPrefix(trimmed, "#"):
+			inSection, current = false, ""
+			continue
+		case strings.HasPrefix(trimmed, "    ") && !strings.HasPrefix(trimmed, "     ") && strings.Contains(trimmed, ":"):
+			current = strings.TrimSpace(strings.SplitN(trimmed, ":", 2)[0])
+		}
+		if current != "" {
+			entries[current] += line
+		}
+	}
+	return entries
+}
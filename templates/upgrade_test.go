@@ -0,0 +1,57 @@
+package templates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCarryOver(t *testing.T) {
+	deployed := `Metadata:
+    EcsyTemplateVersion: 1
+
+Outputs:
+    QueueJobsUrl:
+        Value: !Ref QueueJobs
+
+Resources:
+    QueueJobs:
+        Type: AWS::SQS::Queue
+        Properties:
+            VisibilityTimeout: 30
+
+    Service:
+        Type: AWS::ECS::Service
+`
+	proposed := `Metadata:
+    EcsyTemplateVersion: 2
+
+Outputs:
+    ECSService:
+        Value: !Ref Service
+
+Resources:
+    Service:
+        Type: AWS::ECS::Service
+        Properties:
+            DesiredCount: 1
+`
+
+	upgraded, carried := CarryOver(deployed, proposed)
+	if !reflect.DeepEqual(carried, []string{"QueueJobsUrl", "QueueJobs"}) {
+		t.Fatalf("Unexpected carried over entries %v", carried)
+	}
+	if !strings.Contains(upgraded, "\nResources:\n    QueueJobs:\n        Type: AWS::SQS::Queue\n        Properties:\n            VisibilityTimeout: 30\n") {
+		t.Fatalf("Expected the queue to be carried over, got:\n%s", upgraded)
+	}
+	if strings.Count(upgraded, "Type: AWS::ECS::Service") != 1 {
+		t.Fatalf("Expected the service not to be duplicated, got:\n%s", upgraded)
+	}
+
+	if v := TemplateVersion(upgraded); v != 2 {
+		t.Fatalf("Expected version 2, got %d", v)
+	}
+	if v := TemplateVersion("Resources: {}"); v != 1 {
+		t.Fatalf("Expected unversioned templates to be version 1, got %d", v)
+	}
+}
@@ -0,0 +1,112 @@
+package templates
+
+import "time"
+
+// Workflow is a sequence of tasks run in order by a step functions state machine
+type Workflow struct {
+	Name      string
+	Schedule  string
+	Steps     []WorkflowStep
+	OnFailure *WorkflowStep
+}
+
+// WorkflowStep is a task run as one step of a workflow
+type WorkflowStep struct {
+	Name           string
+	TaskDefinition string
+	Container      string
+	Command        []string
+	Timeout        time.Duration
+	Retries        int
+}
+
+// WorkflowStateMachineOutput is the stack output with the arn of the state machine for a workflow
+func WorkflowStateMachineOutput(name string) string {
+	return resourceName("Workflow", name) + "StateMachine"
+}
+
+// WorkflowsStack generates a template with a state machine for each workflow that runs its
+// steps in order on the cluster. A step that still fails after its retries stops the workflow,
+// running the on failure step first if there is one.
+func WorkflowsStack(cluster string, workflows []Workflow) (string, error) {
+	machines := []stateMachine{}
+	for _, w := range workflows {
+		taskDefinitions := []string{}
+		for _, step := range w.steps() {
+			taskDefinitions = append(taskDefinitions, step.TaskDefinition)
+		}
+		machines = append(machines, stateMachine{
+			Name:            w.Name,
+			ID:              resourceName("Workflow", w.Name),
+			Output:          WorkflowStateMachineOutput(w.Name),
+			Definition:      workflowDefinition(cluster, w),
+			Schedule:        w.Schedule,
+			TaskDefinitions: taskDefinitions,
+		})
+	}
+	return stateMachinesStack(stateMachinesTemplate{
+		Description: "ECS Workflows: sequences of tasks run by step functions",
+		StackType:   "ecs-former::ecs-workflows",
+		ListOutput:  "Workflows",
+		Cluster:     cluster,
+		Machines:    machines,
+	})
+}
+
+func (w Workflow) steps() []WorkflowStep {
+	steps := append([]WorkflowStep{}, w.Steps...)
+	if w.OnFailure != nil {
+		steps = append(steps, *w.OnFailure)
+	}
+	return steps
+}
+
+func workflowDefinition(cluster string, w Workflow) object {
+	states := object{
+		"WorkflowFailed": object{
+			"Type":  "Fail",
+			"Error": "WorkflowFailed",
+			"Cause": "A step of the " + w.Name + " workflow failed",
+		},
+	}
+
+	catch := "WorkflowFailed"
+	if w.OnFailure != nil {
+		catch = w.OnFailure.Name
+		handler := workflowStepState(cluster, *w.OnFailure)
+		handler["Next"] = "WorkflowFailed"
+		states[catch] = handler
+	}
+
+	for i, step := range w.Steps {
+		state := workflowStepState(cluster, step)
+		state["Catch"] = []object{{
+			"ErrorEquals": []string{"States.ALL"},
+			"ResultPath":  "$.error",
+			"Next":        catch,
+		}}
+		if i == len(w.Steps)-1 {
+			state["End"] = true
+		} else {
+			state["Next"] = w.Steps[i+1].Name
+		}
+		states[step.Name] = state
+	}
+
+	return object{
+		"Comment": "Runs the " + w.Name + " workflow",
+		"StartAt": w.Steps[0].Name,
+		"States":  states,
+	}
+}
+
+// workflowStepState runs a step, discarding the task result so that the workflow input
+// is passed along to each of the steps
+func workflowStepState(cluster string, step WorkflowStep) object {
+	state := runTaskState(cluster, step.TaskDefinition, step.Container, step.Command, step.Retries)
+	state["ResultPath"] = nil
+	if step.Timeout > 0 {
+		state["TimeoutSeconds"] = int(step.Timeout.Seconds())
+	}
+	return state
+}